@@ -0,0 +1,86 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+)
+
+// authRealm is the Basic Auth realm announced on authentication failures
+const authRealm = "shorter"
+
+// basicAuth authenticates requests against the configured admin credentials or a
+// stored user account and attaches the resulting user to the request context.
+// The configured admin is the built-in user with ID zero.
+func (r *Router) basicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		if !ok {
+			unauthorized(w)
+			return
+		}
+
+		var user *shortener.User
+		if r.isAdminCredentials(username, password) {
+			user = &shortener.User{Username: username, Role: shortener.RoleAdmin}
+		} else {
+			found, err := r.handler.service.Authenticate(req.Context(), username, password)
+			if err != nil {
+				unauthorized(w)
+				return
+			}
+			user = found
+		}
+
+		next.ServeHTTP(w, req.WithContext(shortener.WithUser(req.Context(), user)))
+	})
+}
+
+// ownerAuth authenticates like basicAuth and requires the user role, except that,
+// when anonymous creation is enabled, requests without credentials go through as
+// anonymous callers presenting the X-Owner-Token header
+func (r *Router) ownerAuth(next http.Handler) http.Handler {
+	authenticated := r.basicAuth(RequireRole(shortener.RoleUser)(next))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, _, ok := req.BasicAuth(); ok || !r.anonymous {
+			authenticated.ServeHTTP(w, req)
+			return
+		}
+
+		ctx := shortener.WithOwnerToken(req.Context(), req.Header.Get(constant.HeaderOwnerToken))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// isAdminCredentials compares credentials with the configured admin in constant time
+func (r *Router) isAdminCredentials(username, password string) bool {
+	if r.username == "" {
+		return false
+	}
+	userMatch := subtle.ConstantTimeCompare([]byte(username), []byte(r.username)) == 1
+	passMatch := subtle.ConstantTimeCompare([]byte(password), []byte(r.password)) == 1
+	return userMatch && passMatch
+}
+
+// RequireRole rejects requests whose authenticated user does not hold at least role.
+// It must run after basicAuth, which attaches the user to the request context.
+func RequireRole(role shortener.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := shortener.UserFromContext(r.Context())
+			if !ok || !user.Role.Satisfies(role) {
+				WriteJSONError(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unauthorized asks the client for Basic Auth credentials
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Add("WWW-Authenticate", `Basic realm="`+authRealm+`"`)
+	w.WriteHeader(http.StatusUnauthorized)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prasetyowira/shorter/domain/shortener"
+)
+
+// APIError is the machine-readable description of an error returned by the API.
+// Clients should branch on Code; Message is meant for people and may change.
+type APIError struct {
+	Code    string
+	Message string
+	Details map[string]string
+}
+
+// errorRegistry maps the domain errors the API reports to their machine-readable codes
+var errorRegistry = map[error]APIError{
+	shortener.ErrEmptyLongURL:        {Code: "empty_long_url"},
+	shortener.ErrEmptyShortCode:      {Code: "empty_short_code"},
+	shortener.ErrShortCodeExists:     {Code: "short_code_exists"},
+	shortener.ErrTooManyCollisions:   {Code: "too_many_collisions"},
+	shortener.ErrShortCodeNotFound:   {Code: "short_code_not_found"},
+	shortener.ErrLongURLNotFound:     {Code: "long_url_not_found"},
+	shortener.ErrBlacklistedURL:      {Code: "blacklisted_url"},
+	shortener.ErrInvalidLongURL:      {Code: "invalid_long_url"},
+	shortener.ErrReservedShortCode:   {Code: "reserved_short_code"},
+	shortener.ErrInvalidPassword:     {Code: "invalid_password"},
+	shortener.ErrShortCodeExpired:    {Code: "short_code_expired"},
+	shortener.ErrInvalidExpiry:       {Code: "invalid_expiry"},
+	shortener.ErrSSRFBlocked:         {Code: "private_address"},
+	shortener.ErrURLUnreachable:      {Code: "url_unreachable"},
+	shortener.ErrEmptyUsername:       {Code: "empty_username"},
+	shortener.ErrEmptyUserPassword:   {Code: "empty_password"},
+	shortener.ErrUserExists:          {Code: "user_exists"},
+	shortener.ErrUserNotFound:        {Code: "user_not_found"},
+	shortener.ErrInvalidCredentials:  {Code: "invalid_credentials"},
+	shortener.ErrForbidden:           {Code: "forbidden"},
+	shortener.ErrTagNotFound:         {Code: "tag_not_found"},
+	shortener.ErrInvalidWebhookURL:   {Code: "invalid_webhook_url"},
+	shortener.ErrEmptyWebhookSecret:  {Code: "empty_webhook_secret"},
+	shortener.ErrWebhookNotFound:     {Code: "webhook_not_found"},
+	shortener.ErrInvalidTimeRange:    {Code: "invalid_time_range"},
+	shortener.ErrInvalidPeriod:       {Code: "invalid_period", Details: map[string]string{"max": shortener.MaxTrendingPeriod.String()}},
+	shortener.ErrInvalidRedirectCode: {Code: "invalid_redirect_code", Details: map[string]string{"allowed": "301,302,307,308"}},
+	shortener.ErrInvalidRole:         {Code: "invalid_role", Details: map[string]string{"allowed": string(shortener.RoleAdmin) + "," + string(shortener.RoleUser)}},
+	shortener.ErrInvalidWebhookEvent: {Code: "invalid_webhook_event", Details: map[string]string{"allowed": shortener.WebhookEventVisit}},
+	shortener.ErrInvalidGranularity:  {Code: "invalid_granularity", Details: map[string]string{"allowed": "hour,day"}},
+	shortener.ErrInvalidTag:          {Code: "invalid_tag", Details: map[string]string{"max_length": strconv.Itoa(shortener.MaxTagLength)}},
+	shortener.ErrInvalidBulkDelete:   {Code: "invalid_bulk_delete", Details: map[string]string{"max": strconv.Itoa(shortener.MaxBulkDelete)}},
+	shortener.ErrInvalidSearchLimit:  {Code: "invalid_limit", Details: map[string]string{"min": "1", "max": strconv.Itoa(shortener.MaxSearchLimit)}},
+	shortener.ErrInvalidSearchOffset: {Code: "invalid_offset"},
+	shortener.ErrInvalidCanary:       {Code: "invalid_canary_percent", Details: map[string]string{"min": "0", "max": strconv.Itoa(shortener.MaxCanaryPercent)}},
+	shortener.ErrMissingAlternateURL: {Code: "missing_alternate_url"},
+	shortener.ErrInvalidAlternateURL: {Code: "invalid_alternate_url"},
+	shortener.ErrInvalidGeoRule:      {Code: "invalid_geo_rule"},
+	shortener.ErrInvalidGeoTarget:    {Code: "invalid_geo_target"},
+	shortener.ErrInvalidActiveFrom:   {Code: "invalid_active_from"},
+	errInvalidQRSize:                 {Code: "invalid_qr_size"},
+	errInvalidQRFormat:               {Code: "invalid_qr_format"},
+	errInvalidQRColor:                {Code: "invalid_qr_color"},
+	errInvalidQRECLevel:              {Code: "invalid_qr_ec_level", Details: map[string]string{"allowed": "L,M,Q,H"}},
+}
+
+// lookupAPIError describes err with its registry entry, or with a generic entry named
+// after statusCode when err is not registered. The message is always err's own.
+func lookupAPIError(err error, statusCode int) APIError {
+	for registered, apiErr := range errorRegistry {
+		if errors.Is(err, registered) {
+			apiErr.Message = err.Error()
+			return apiErr
+		}
+	}
+	return APIError{
+		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_"),
+		Message: err.Error(),
+	}
+}
+
+// hasCode reports whether err is a shortener.DomainError with one of codes
+func hasCode(err error, codes ...string) bool {
+	code := shortener.CodeOf(err)
+	return code != "" && slices.Contains(codes, code)
+}
+
+// WriteAPIError writes err as a JSON error response carrying its machine-readable code
+func WriteAPIError(w http.ResponseWriter, err error, statusCode int) {
+	apiErr := lookupAPIError(err, statusCode)
+	WriteJSON(w, ErrorResponse{
+		Error:     apiErr.Message,
+		Code:      statusCode,
+		ErrorCode: apiErr.Code,
+		Details:   apiErr.Details,
+	}, statusCode)
+}
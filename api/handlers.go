@@ -1,89 +1,304 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"image/color"
+	"io"
+	"math/rand"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
+	appMiddleware "github.com/prasetyowira/shorter/api/middleware"
 	"github.com/prasetyowira/shorter/constant"
 	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/prasetyowira/shorter/infrastructure/geo"
 	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
 	"github.com/prasetyowira/shorter/infrastructure/qrcode"
+	"github.com/prasetyowira/shorter/infrastructure/security"
 )
 
 // Handler contains service dependencies for API handlers
 type Handler struct {
 	service     *shortener.Service
-	qrGenerator *qrcode.Generator
+	qrGenerator qrcode.QRGenerator
 	baseURL     string
+	startedAt   time.Time
+	// canaryRoll returns a number in [0, 100) that picks the destination of a canary split
+	canaryRoll func() int
+	// geoResolver finds the visitor's country for geo rules; nil disables them
+	geoResolver geo.Resolver
+	// cdnCacheMaxAge is the Surrogate-Control max-age of redirects in seconds; 0 omits it.
+	// It and preventSelfRedirect change on configuration reloads, so they are atomic.
+	cdnCacheMaxAge atomic.Int64
+	// preventSelfRedirect refuses redirects to baseURL's host
+	preventSelfRedirect atomic.Bool
 }
 
 // CreateShortURLRequest is the request object for CreateShortURL endpoint
 type CreateShortURLRequest struct {
-	LongURL        string `json:"long_url"`
-	CustomShortURL string `json:"custom_short_url"`
+	LongURL        string              `json:"long_url"`
+	CustomShortURL string              `json:"custom_short_url"`
+	Password       string              `json:"password,omitempty"`
+	RedirectCode   int                 `json:"redirect_code,omitempty"`
+	MaxVisits      *uint               `json:"max_visits,omitempty"`
+	ExpiresAt      *time.Time          `json:"expires_at,omitempty"`
+	UTMSource      string              `json:"utm_source,omitempty"`
+	UTMCampaign    string              `json:"utm_campaign,omitempty"`
+	UTMMedium      string              `json:"utm_medium,omitempty"`
+	AlternateURL   string              `json:"alternate_url,omitempty"`
+	CanaryPercent  uint8               `json:"canary_percent,omitempty"`
+	GeoRules       []shortener.GeoRule `json:"geo_rules,omitempty"`
+	ActiveFrom     *time.Time          `json:"active_from,omitempty"`
 }
 
 // ShortURLResponse is the response object for short URL operations
 type ShortURLResponse struct {
-	FullUrl   string `json:"full_url"`
-	ShortCode string `json:"short_code"`
-	LongURL   string `json:"long_url"`
+	FullUrl       string              `json:"full_url"`
+	ShortCode     string              `json:"short_code"`
+	LongURL       string              `json:"long_url"`
+	AlternateURL  string              `json:"alternate_url,omitempty"`
+	CanaryPercent uint8               `json:"canary_percent,omitempty"`
+	GeoRules      []shortener.GeoRule `json:"geo_rules,omitempty"`
+	Tags          []string            `json:"tags"`
+	// ActiveFrom is when a scheduled URL starts redirecting; until then it answers 404
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+	// OwnerToken is returned once, to the anonymous creator of the URL, who sends it in
+	// the X-Owner-Token header to update or delete the URL until OwnerTokenExpiresAt
+	OwnerToken          string     `json:"owner_token,omitempty"`
+	OwnerTokenExpiresAt *time.Time `json:"owner_token_expires_at,omitempty"`
 }
 
 // URLStatsResponse is the response for URL stats
 type URLStatsResponse struct {
+	FullUrl   string `json:"full_url"`
 	ShortCode string `json:"short_code"`
 	Visits    uint   `json:"visits"`
+	// BotVisits counts clicks from bots, which are excluded from Visits
+	BotVisits uint `json:"bot_visits"`
+	// LastAccessedAt is null until the short URL is first visited
+	LastAccessedAt *time.Time `json:"last_accessed_at"`
+}
+
+// URLDetailResponse is the response for URL details
+type URLDetailResponse struct {
+	ShortCode string     `json:"short_code"`
+	LongURL   string     `json:"long_url"`
+	ShortURL  string     `json:"short_url"`
+	Visits    uint       `json:"visits"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Tags      []string   `json:"tags"`
+	// Status is active, scheduled or expired
+	Status       string `json:"status"`
+	RedirectCode int    `json:"redirect_code"`
+}
+
+// VisitsResponse is the response for time-series URL visits
+type VisitsResponse struct {
+	ShortCode   string                  `json:"short_code"`
+	Granularity string                  `json:"granularity"`
+	From        time.Time               `json:"from"`
+	To          time.Time               `json:"to"`
+	Visits      []shortener.VisitBucket `json:"visits"`
 }
 
-// UpdateLongURLRequest is the request object for UpdateLongURL endpoint
+// ReferersResponse is the response for URL referer stats
+type ReferersResponse struct {
+	Referers []shortener.RefererStat `json:"referers"`
+}
+
+// SparklineResponse is the response for daily URL click counts
+type SparklineResponse struct {
+	Buckets []shortener.DailyCount `json:"buckets"`
+}
+
+// UpdateLongURLRequest is the request object for UpdateLongURL endpoint. Sending
+// either canary field replaces the URL's canary split, with the omitted one empty;
+// sending neither leaves the split as it is.
 type UpdateLongURLRequest struct {
-	LongURL string `json:"long_url"`
+	LongURL       string  `json:"long_url"`
+	AlternateURL  *string `json:"alternate_url,omitempty"`
+	CanaryPercent *uint8  `json:"canary_percent,omitempty"`
+}
+
+// RenameShortCodeRequest is the request object for RenameShortCode endpoint
+type RenameShortCodeRequest struct {
+	NewShortCode string `json:"new_short_code"`
+}
+
+// CloneURLRequest is the request object for CloneURL endpoint; an empty
+// NewShortCode gives the clone a generated code
+type CloneURLRequest struct {
+	NewShortCode string `json:"new_short_code"`
 }
 
-// ErrorResponse represents an API error response
+// ImportRowError describes why a single CSV row could not be imported
+type ImportRowError struct {
+	Row    int    `json:"row"`
+	Reason string `json:"reason"`
+}
+
+// ImportURLsResponse is the response for the ImportURLs endpoint
+type ImportURLsResponse struct {
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// LogLevelRequest is the request object for the SetLogLevel endpoint
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LogLevelResponse is the response for the SetLogLevel endpoint
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// ErrorResponse represents an API error response. Code repeats the HTTP status;
+// ErrorCode identifies the error for clients, see errorRegistry.
 type ErrorResponse struct {
-	Error string `json:"error"`
-	Code  int    `json:"code"`
+	Error     string            `json:"error"`
+	Code      int               `json:"code"`
+	ErrorCode string            `json:"error_code"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// defaultQRSize is the QR code size used when no size query parameter is given
+const defaultQRSize = 256
+
+// Export formats
+const (
+	exportFormatCSV  = "csv"
+	exportFormatJSON = "json"
+)
+
+// exportCSVHeader is the header row of a CSV export
+var exportCSVHeader = []string{"id", "short_code", "long_url", "created_at", "visits", "expires_at"}
+
+// clickExportCSVHeader is the header row of a CSV click log export
+var clickExportCSVHeader = []string{"clicked_at", "referer", "ip_hash", "user_agent"}
+
+// formatExpiresAt formats the expires_at column of a CSV export, which is empty for URLs that never expire
+func formatExpiresAt(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return expiresAt.UTC().Format(time.RFC3339)
 }
 
-// NewHandler creates a new API handler
-func NewHandler(service *shortener.Service, qrGenerator *qrcode.Generator, baseURL string) *Handler {
-	return &Handler{
+// maxImportSize is the upload limit for CSV imports
+const maxImportSize = 10 << 20
+
+// QR code output formats
+const (
+	qrFormatPNG = "png"
+	qrFormatSVG = "svg"
+)
+
+// allowedQRSizes lists the QR code sizes accepted by the size query parameter
+var allowedQRSizes = []int{128, 256, 512, 1024}
+
+// Errors for QR code query parameters that are not accepted
+var (
+	errInvalidQRSize    = &shortener.DomainError{Code: constant.ErrCodeAPIQRSize, Message: constant.ErrInvalidQRSize}
+	errInvalidQRFormat  = &shortener.DomainError{Code: constant.ErrCodeAPIQRFormat, Message: constant.ErrInvalidQRFormat}
+	errInvalidQRColor   = &shortener.DomainError{Code: constant.ErrCodeAPIQRColor, Message: constant.ErrInvalidQRColor}
+	errInvalidQRECLevel = &shortener.DomainError{Code: constant.ErrCodeAPIQRECLevel, Message: constant.ErrInvalidQRECLevel}
+)
+
+// qrECLevels maps the ec query parameter to error correction levels
+var qrECLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.ECLow,
+	"M": qrcode.ECMedium,
+	"Q": qrcode.ECQuartile,
+	"H": qrcode.ECHigh,
+}
+
+// NewHandler creates a new API handler. baseURL is the public address short
+// codes are appended to in responses; it must not be empty.
+func NewHandler(service *shortener.Service, qrGenerator qrcode.QRGenerator, baseURL string) *Handler {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if baseURL == "" {
+		panic("api: NewHandler requires a base URL")
+	}
+
+	h := &Handler{
 		service:     service,
 		qrGenerator: qrGenerator,
 		baseURL:     baseURL,
+		startedAt:   time.Now(),
+		canaryRoll:  func() int { return rand.Intn(shortener.MaxCanaryPercent) },
 	}
+	h.cdnCacheMaxAge.Store(DefaultCDNCacheMaxAge)
+	return h
 }
 
-// withRequestID adds a request ID to the context and response headers
-func withRequestID(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := uuid.New().String()
-		ctx := appLogger.WithRequestID(r.Context(), requestID)
+// SetGeoResolver enables the geo rules of short URLs, looking visitors' countries up with resolver
+func (h *Handler) SetGeoResolver(resolver geo.Resolver) {
+	h.geoResolver = resolver
+}
 
-		w.Header().Set(constant.HeaderRequestID, requestID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// destinationURL returns where the visitor making r is sent for url: the target of
+// the first geo rule matching their country, or else the canary split's pick
+func (h *Handler) destinationURL(r *http.Request, url *shortener.URL) string {
+	if h.geoResolver != nil && len(url.GeoRules) > 0 {
+		ip := appMiddleware.ClientIP(r)
+		country, err := h.geoResolver.Country(ip)
+		if err != nil {
+			appLogger.CtxDebug(r.Context(), "Visitor country not resolved", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxRedirectToLongURL,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAPIGeoLookup,
+					Message: err.Error(),
+					Type:    constant.ErrTypeAPI,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: url.ShortCode,
+					constant.DataIP:        ip,
+				},
+			})
+		} else if target, ok := url.GeoRedirectURL(country); ok {
+			return target
+		}
+	}
+	return url.CanaryRedirectURL(h.canaryRoll())
 }
 
-// logRequest logs incoming requests
-func logRequest(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		appLogger.CtxInfo(r.Context(), constant.MsgRequestReceived, appLogger.LoggerInfo{
-			ContextFunction: constant.CtxAPI,
-			Data: map[string]interface{}{
-				constant.DataMethod:     r.Method,
-				constant.DataPath:       r.URL.Path,
-				constant.DataRemoteAddr: r.RemoteAddr,
-				constant.DataUserAgent:  r.UserAgent(),
-			},
-		})
-		next.ServeHTTP(w, r)
-	})
+// newShortURLResponse builds the response for url, including its tags
+func (h *Handler) newShortURLResponse(ctx context.Context, url *shortener.URL) ShortURLResponse {
+	tags, err := h.service.GetTags(ctx, url.ShortCode)
+	if err != nil || tags == nil {
+		tags = []string{}
+	}
+
+	return ShortURLResponse{
+		FullUrl:       h.fullURL(url.ShortCode),
+		ShortCode:     url.ShortCode,
+		LongURL:       url.LongURL,
+		AlternateURL:  url.AlternateURL,
+		CanaryPercent: url.CanaryPercent,
+		GeoRules:      url.GeoRules,
+		Tags:          tags,
+		ActiveFrom:    url.ActiveFrom,
+	}
+}
+
+// fullURL returns the public short URL for shortCode
+func (h *Handler) fullURL(shortCode string) string {
+	return h.baseURL + "/" + shortCode
 }
 
 // CreateShortURL handles short URL creation
@@ -94,6 +309,17 @@ func (h *Handler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 		ContextFunction: constant.CtxCreateShortURL,
 	})
 
+	// A retried request carrying the same idempotency key gets the original response
+	idempotencyKey := idempotencyCacheKey(r)
+	if idempotencyKey != "" {
+		if cached, found := h.service.RecallIdempotent(idempotencyKey); found {
+			if resp, ok := cached.(ShortURLResponse); ok {
+				WriteJSON(w, resp, http.StatusOK)
+				return
+			}
+		}
+	}
+
 	var req CreateShortURLRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
@@ -105,15 +331,58 @@ func (h *Handler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 			},
 		})
 
-		WriteJSONError(w, "Invalid request format", http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	url, err := h.service.CreateShortURL(ctx, req.LongURL, req.CustomShortURL)
+	// Anonymous callers get a token proving they created the URL instead of an account
+	var ownerToken string
+	if _, anonymous := shortener.OwnerTokenFromContext(ctx); anonymous {
+		token, err := shortener.NewOwnerToken()
+		if err != nil {
+			appLogger.CtxError(ctx, "Error generating owner token", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxCreateShortURL,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAPIServiceError,
+					Message: err.Error(),
+					Type:    constant.ErrTypeAPI,
+				},
+			})
+
+			WriteJSONError(w, "Failed to create short URL", http.StatusInternalServerError)
+			return
+		}
+		ownerToken = token
+	}
+
+	url, err := h.service.CreateShortURLWithParams(ctx, req.LongURL, req.CustomShortURL, shortener.CreateURLParams{
+		Password:      req.Password,
+		RedirectCode:  req.RedirectCode,
+		MaxVisits:     req.MaxVisits,
+		ExpiresAt:     req.ExpiresAt,
+		OwnerID:       shortener.UserIDFromContext(ctx),
+		UTMSource:     req.UTMSource,
+		UTMCampaign:   req.UTMCampaign,
+		UTMMedium:     req.UTMMedium,
+		AlternateURL:  req.AlternateURL,
+		CanaryPercent: req.CanaryPercent,
+		GeoRules:      req.GeoRules,
+		OwnerToken:    ownerToken,
+		ActiveFrom:    req.ActiveFrom,
+	})
 	if err != nil {
-		// Check for specific error messages
-		if err.Error() == constant.ErrEmptyLongURL {
-			WriteJSONError(w, "URL cannot be empty", http.StatusBadRequest)
+		// Check for specific errors
+		if hasCode(err, constant.ErrCodeEmptyLongURL) {
+			WriteAPIError(w, err, http.StatusBadRequest)
+			return
+		}
+		if hasCode(err, constant.ErrCodeBlacklistedURL, constant.ErrCodeSSRFBlocked, constant.ErrCodeURLUnreachable) {
+			WriteAPIError(w, err, http.StatusUnprocessableEntity)
+			return
+		}
+		if hasCode(err, constant.ErrCodeReservedShortCode, constant.ErrCodeInvalidRedirectCode, constant.ErrCodeInvalidLongURL, constant.ErrCodeInvalidExpiry,
+			constant.ErrCodeInvalidActiveFrom, constant.ErrCodeInvalidGeoRule, constant.ErrCodeInvalidGeoTarget) || isCanaryError(err) {
+			WriteAPIError(w, err, http.StatusBadRequest)
 			return
 		}
 
@@ -133,10 +402,13 @@ func (h *Handler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := ShortURLResponse{
-		FullUrl:   h.baseURL + "/" + url.ShortCode,
-		ShortCode: url.ShortCode,
-		LongURL:   url.LongURL,
+	resp := h.newShortURLResponse(ctx, url)
+	if ownerToken != "" {
+		resp.OwnerToken = ownerToken
+		resp.OwnerTokenExpiresAt = url.OwnerTokenExpiresAt
+	}
+	if idempotencyKey != "" {
+		h.service.RememberIdempotent(idempotencyKey, resp)
 	}
 
 	appLogger.CtxInfo(ctx, "Created short URL successfully", appLogger.LoggerInfo{
@@ -150,6 +422,27 @@ func (h *Handler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, resp, http.StatusCreated)
 }
 
+// idempotencyCacheKey derives the cache key of a request's X-Idempotency-Key header, scoped to the
+// authenticated user so that clients cannot replay each other's responses. It is empty without the
+// header and for anonymous callers, who cannot be told apart and would see each other's owner tokens.
+func idempotencyCacheKey(r *http.Request) string {
+	key := r.Header.Get(constant.HeaderIdempotencyKey)
+	if key == "" {
+		return ""
+	}
+	if _, anonymous := shortener.OwnerTokenFromContext(r.Context()); anonymous {
+		return ""
+	}
+
+	var username string
+	if user, ok := shortener.UserFromContext(r.Context()); ok {
+		username = user.Username
+	}
+	// Header values cannot contain NUL, so it separates the key from the username unambiguously
+	sum := sha256.Sum256([]byte(key + "\x00" + username))
+	return hex.EncodeToString(sum[:])
+}
+
 // RedirectToLongURL handles redirection to the original URL
 func (h *Handler) RedirectToLongURL(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -162,9 +455,14 @@ func (h *Handler) RedirectToLongURL(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 
+	ctx = shortener.WithReferer(ctx, r.Referer())
+	ctx = shortener.WithVisitor(ctx, appMiddleware.ClientIP(r), r.UserAgent())
 	url, err := h.service.GetLongURL(ctx, shortCode)
 	if err != nil {
-		if err.Error() == constant.ErrShortCodeNotFound {
+		// The code may be created, restored or fixed at any time
+		w.Header().Set(constant.HeaderCacheControl, cacheControlNoStore)
+		// A scheduled URL looks missing so that its launch is not given away
+		if hasCode(err, constant.ErrCodeShortCodeNotFound, constant.ErrCodeURLNotYetActive) {
 			appLogger.CtxInfo(ctx, "Short code not found", appLogger.LoggerInfo{
 				ContextFunction: constant.CtxRedirectToLongURL,
 				Data: map[string]interface{}{
@@ -175,6 +473,10 @@ func (h *Handler) RedirectToLongURL(w http.ResponseWriter, r *http.Request) {
 			http.NotFound(w, r)
 			return
 		}
+		if hasCode(err, constant.ErrCodeShortCodeExpired) {
+			WriteAPIError(w, err, http.StatusGone)
+			return
+		}
 
 		appLogger.CtxError(ctx, "Error retrieving long URL", appLogger.LoggerInfo{
 			ContextFunction: constant.CtxRedirectToLongURL,
@@ -192,6 +494,19 @@ func (h *Handler) RedirectToLongURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if url.IsProtected {
+		appLogger.CtxInfo(ctx, "Redirecting to password form", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxRedirectToLongURL,
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+
+		w.Header().Set(constant.HeaderCacheControl, cacheControlPrivateNoStore)
+		http.Redirect(w, r, "/p/"+shortCode, http.StatusFound)
+		return
+	}
+
 	appLogger.CtxInfo(ctx, "Redirecting to long URL", appLogger.LoggerInfo{
 		ContextFunction: constant.CtxRedirectToLongURL,
 		Data: map[string]interface{}{
@@ -200,37 +515,138 @@ func (h *Handler) RedirectToLongURL(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 
-	http.Redirect(w, r, url.LongURL, http.StatusFound)
+	destination := h.destinationURL(r, url)
+	if h.rejectSelfRedirect(w, r, shortCode, destination) {
+		return
+	}
+
+	cacheControl, surrogateControl := h.redirectCacheHeaders(url)
+	w.Header().Set(constant.HeaderCacheControl, cacheControl)
+	if surrogateControl != "" {
+		w.Header().Set(constant.HeaderSurrogateControl, surrogateControl)
+	}
+	http.Redirect(w, r, destination, url.RedirectStatus())
+}
+
+// SetPreventSelfRedirect makes redirects to the host of the base URL fail with 400
+// instead of sending visitors round in a loop
+func (h *Handler) SetPreventSelfRedirect(enabled bool) {
+	h.preventSelfRedirect.Store(enabled)
+}
+
+// rejectSelfRedirect writes a 400 response and returns true when self redirects
+// are prevented and destination is on the host of the base URL
+func (h *Handler) rejectSelfRedirect(w http.ResponseWriter, r *http.Request, shortCode, destination string) bool {
+	if !h.preventSelfRedirect.Load() || !security.IsSelfRedirect(destination, h.baseURL) {
+		return false
+	}
+
+	appLogger.CtxWarn(r.Context(), "Refusing redirect back to this service", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxRedirectToLongURL,
+		Error: &appLogger.CustomError{
+			Code:    constant.ErrCodeAPIRedirectLoop,
+			Message: constant.ErrRedirectLoop,
+			Type:    constant.ErrTypeAPI,
+		},
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataLongURL:   destination,
+		},
+	})
+
+	w.Header().Set(constant.HeaderCacheControl, cacheControlNoStore)
+	WriteJSONError(w, constant.ErrRedirectLoop, http.StatusBadRequest)
+	return true
 }
 
-// GetURLStats handles retrieving URL stats
-func (h *Handler) GetURLStats(w http.ResponseWriter, r *http.Request) {
+// Cache-Control values of redirect responses
+const (
+	cacheControlNoStore        = "no-store"
+	cacheControlPrivateNoStore = "private, no-store"
+)
+
+// redirectBrowserMaxAge is how long, in seconds, browsers may reuse a redirect
+const redirectBrowserMaxAge = 300
+
+// DefaultCDNCacheMaxAge is how long, in seconds, CDNs may keep a redirect unless SetCDNCacheMaxAge says otherwise
+const DefaultCDNCacheMaxAge = 3600
+
+// SetCDNCacheMaxAge sets the Surrogate-Control max-age of redirects in seconds; 0 leaves
+// the header out, so CDNs follow Cache-Control
+func (h *Handler) SetCDNCacheMaxAge(seconds int) {
+	h.cdnCacheMaxAge.Store(int64(seconds))
+}
+
+// redirectCacheHeaders returns the Cache-Control and Surrogate-Control values of a
+// redirect to url's destination, the latter empty when CDNs must not keep it. Only
+// redirects every visitor shares are left to CDNs, redirects of URLs with a visit
+// limit are not cached so each visit is counted, and no copy outlives the URL.
+func (h *Handler) redirectCacheHeaders(url *shortener.URL) (string, string) {
+	if url.MaxVisits != nil && *url.MaxVisits > 0 {
+		return cacheControlNoStore, ""
+	}
+
+	browserMaxAge, cdnMaxAge := redirectBrowserMaxAge, int(h.cdnCacheMaxAge.Load())
+	if url.ExpiresAt != nil {
+		remaining := int(time.Until(*url.ExpiresAt).Seconds())
+		if remaining <= 0 {
+			return cacheControlNoStore, ""
+		}
+		browserMaxAge, cdnMaxAge = min(browserMaxAge, remaining), min(cdnMaxAge, remaining)
+	}
+
+	perVisitor := (url.AlternateURL != "" && url.CanaryPercent > 0) || (h.geoResolver != nil && len(url.GeoRules) > 0)
+	if perVisitor {
+		return fmt.Sprintf("private, max-age=%d", browserMaxAge), ""
+	}
+	cacheControl := fmt.Sprintf("public, max-age=%d", browserMaxAge)
+	if cdnMaxAge <= 0 {
+		return cacheControl, ""
+	}
+	return cacheControl, fmt.Sprintf("max-age=%d", cdnMaxAge)
+}
+
+// previewPage is the HTML page describing a short URL's destination
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Preview {{.ShortCode}}</title></head>
+<body>
+<h1>Link preview</h1>
+<dl>
+<dt>Short code</dt><dd>{{.ShortCode}}</dd>
+<dt>Destination</dt><dd>{{if .IsProtected}}Password protected{{else}}{{.LongURL}}{{end}}</dd>
+<dt>Created</dt><dd>{{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</dd>
+<dt>Visits</dt><dd>{{.Visits}}</dd>
+</dl>
+<form method="GET" action="/{{.ShortCode}}">
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// PreviewURL serves an HTML page describing where a short URL leads
+// without following it or counting a visit
+func (h *Handler) PreviewURL(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	shortCode := chi.URLParam(r, "shortCode")
 
-	appLogger.CtxDebug(ctx, "Processing URL stats request", appLogger.LoggerInfo{
-		ContextFunction: constant.CtxGetURLStats,
+	appLogger.CtxDebug(ctx, "Handling preview request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxPreviewURL,
 		Data: map[string]interface{}{
 			constant.DataShortCode: shortCode,
 		},
 	})
 
-	url, err := h.service.GetLongURL(ctx, shortCode)
+	url, err := h.service.LookupURL(ctx, shortCode)
 	if err != nil {
-		if err.Error() == constant.ErrShortCodeNotFound {
-			appLogger.CtxInfo(ctx, "Short code not found for stats", appLogger.LoggerInfo{
-				ContextFunction: constant.CtxGetURLStats,
-				Data: map[string]interface{}{
-					constant.DataShortCode: shortCode,
-				},
-			})
-
+		if hasCode(err, constant.ErrCodeShortCodeNotFound) {
 			http.NotFound(w, r)
 			return
 		}
 
-		appLogger.CtxError(ctx, "Error retrieving URL stats", appLogger.LoggerInfo{
-			ContextFunction: constant.CtxGetURLStats,
+		appLogger.CtxError(ctx, "Error retrieving URL for preview", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxPreviewURL,
 			Error: &appLogger.CustomError{
 				Code:    constant.ErrCodeAPIServiceError,
 				Message: err.Error(),
@@ -241,44 +657,183 @@ func (h *Handler) GetURLStats(w http.ResponseWriter, r *http.Request) {
 			},
 		})
 
-		WriteJSONError(w, "Error retrieving URL stats", http.StatusInternalServerError)
+		WriteJSONError(w, "Error retrieving URL", http.StatusInternalServerError)
 		return
 	}
-
-	resp := URLStatsResponse{
-		ShortCode: url.ShortCode,
-		Visits:    url.Visits,
+	// The preview shows the long URL, which a scheduled URL keeps to itself until launch
+	if !url.ActiveAt(time.Now()) {
+		http.NotFound(w, r)
+		return
 	}
 
-	appLogger.CtxInfo(ctx, "URL stats retrieved successfully", appLogger.LoggerInfo{
-		ContextFunction: constant.CtxGetURLStats,
+	renderHTML(ctx, w, previewPage, url, http.StatusOK, constant.CtxPreviewURL)
+}
+
+// protectedURLForm is the HTML form served for password-protected short URLs
+var protectedURLForm = template.Must(template.New("protected").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Password required</title></head>
+<body>
+<h1>Password required</h1>
+{{if .Invalid}}<p>Invalid password, please try again.</p>{{end}}
+<form method="POST" action="/p/{{.ShortCode}}">
+<input type="password" name="password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// protectedURLFormData is the data rendered into protectedURLForm
+type protectedURLFormData struct {
+	ShortCode string
+	Invalid   bool
+}
+
+// ProtectedURL serves the password form for a protected short URL on GET and
+// redirects to the long URL once a correct password is POSTed
+func (h *Handler) ProtectedURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	appLogger.CtxDebug(ctx, "Handling protected URL request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxProtectedURL,
 		Data: map[string]interface{}{
 			constant.DataShortCode: shortCode,
-			constant.DataVisits:    url.Visits,
+			constant.DataMethod:    r.Method,
 		},
 	})
 
-	WriteJSON(w, resp, http.StatusOK)
+	if r.Method != http.MethodPost {
+		renderProtectedURLForm(ctx, w, protectedURLFormData{ShortCode: shortCode}, http.StatusOK)
+		return
+	}
+
+	ctx = shortener.WithVisitor(ctx, appMiddleware.ClientIP(r), r.UserAgent())
+	url, err := h.service.VerifyPassword(ctx, shortCode, r.PostFormValue("password"))
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeInvalidPassword):
+			renderProtectedURLForm(ctx, w, protectedURLFormData{ShortCode: shortCode, Invalid: true}, http.StatusUnauthorized)
+		case hasCode(err, constant.ErrCodeShortCodeNotFound, constant.ErrCodeURLNotYetActive):
+			http.NotFound(w, r)
+		case hasCode(err, constant.ErrCodeShortCodeExpired):
+			WriteAPIError(w, err, http.StatusGone)
+		default:
+			appLogger.CtxError(ctx, "Error verifying URL password", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxProtectedURL,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAPIServiceError,
+					Message: err.Error(),
+					Type:    constant.ErrTypeAPI,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			WriteJSONError(w, "Error retrieving URL", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	destination := h.destinationURL(r, url)
+	if h.rejectSelfRedirect(w, r, shortCode, destination) {
+		return
+	}
+	http.Redirect(w, r, destination, http.StatusFound)
 }
 
-// GenerateQRCode handles QR code generation for a short URL
-func (h *Handler) GenerateQRCode(w http.ResponseWriter, r *http.Request) {
+// renderProtectedURLForm writes the password form with the given status code
+func renderProtectedURLForm(ctx context.Context, w http.ResponseWriter, data protectedURLFormData, statusCode int) {
+	renderHTML(ctx, w, protectedURLForm, data, statusCode, constant.CtxProtectedURL)
+}
+
+// renderHTML writes tmpl executed with data as an HTML response. The status line is
+// already sent when execution fails, so the error can only be logged.
+func renderHTML(ctx context.Context, w http.ResponseWriter, tmpl *template.Template, data interface{}, statusCode int, contextFunction string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if err := tmpl.Execute(w, data); err != nil {
+		appLogger.CtxError(ctx, "Error rendering HTML page", appLogger.LoggerInfo{
+			ContextFunction: contextFunction,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIRenderTemplate,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataTemplate: tmpl.Name(),
+			},
+		})
+	}
+}
+
+// GetURLDetails handles reading the details of a short URL without following it
+func (h *Handler) GetURLDetails(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	shortCode := chi.URLParam(r, "shortCode")
 
-	appLogger.CtxDebug(ctx, "Processing QR code generation request", appLogger.LoggerInfo{
-		ContextFunction: constant.CtxGenerateQRCode,
+	// Reading details is not a visit, so it must not move the count it reports
+	url, err := h.service.LookupURL(ctx, shortCode)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeEmptyShortCode):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		case hasCode(err, constant.ErrCodeShortCodeNotFound):
+			WriteAPIError(w, err, http.StatusNotFound)
+		default:
+			appLogger.CtxError(ctx, "Error retrieving URL details", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxGetURLDetails,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAPIServiceError,
+					Message: err.Error(),
+					Type:    constant.ErrTypeAPI,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			WriteJSONError(w, "Error retrieving URL details", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	tags, err := h.service.GetTags(ctx, url.ShortCode)
+	if err != nil || tags == nil {
+		tags = []string{}
+	}
+
+	WriteJSON(w, URLDetailResponse{
+		ShortCode:    url.ShortCode,
+		LongURL:      url.LongURL,
+		ShortURL:     h.fullURL(url.ShortCode),
+		Visits:       url.Visits,
+		CreatedAt:    url.CreatedAt,
+		ExpiresAt:    url.ExpiresAt,
+		Tags:         tags,
+		Status:       url.StatusAt(time.Now()),
+		RedirectCode: url.RedirectStatus(),
+	}, http.StatusOK)
+}
+
+// GetURLStats handles retrieving URL stats
+func (h *Handler) GetURLStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	appLogger.CtxDebug(ctx, "Processing URL stats request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGetURLStats,
 		Data: map[string]interface{}{
 			constant.DataShortCode: shortCode,
 		},
 	})
 
-	// Verify that the short code exists
-	_, err := h.service.GetLongURL(ctx, shortCode)
+	// Reading stats is not a visit, so it must not move the count it reports
+	url, err := h.service.LookupURL(ctx, shortCode)
 	if err != nil {
-		if err.Error() == constant.ErrShortCodeNotFound {
-			appLogger.CtxInfo(ctx, "Short code not found for QR code generation", appLogger.LoggerInfo{
-				ContextFunction: constant.CtxGenerateQRCode,
+		if hasCode(err, constant.ErrCodeShortCodeNotFound) {
+			appLogger.CtxInfo(ctx, "Short code not found for stats", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxGetURLStats,
 				Data: map[string]interface{}{
 					constant.DataShortCode: shortCode,
 				},
@@ -288,8 +843,8 @@ func (h *Handler) GenerateQRCode(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		appLogger.CtxError(ctx, "Error retrieving URL for QR code", appLogger.LoggerInfo{
-			ContextFunction: constant.CtxGenerateQRCode,
+		appLogger.CtxError(ctx, "Error retrieving URL stats", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGetURLStats,
 			Error: &appLogger.CustomError{
 				Code:    constant.ErrCodeAPIServiceError,
 				Message: err.Error(),
@@ -300,15 +855,14 @@ func (h *Handler) GenerateQRCode(w http.ResponseWriter, r *http.Request) {
 			},
 		})
 
-		WriteJSONError(w, "Error generating QR code", http.StatusInternalServerError)
+		WriteJSONError(w, "Error retrieving URL stats", http.StatusInternalServerError)
 		return
 	}
 
-	// Generate QR code
-	qrCode, err := h.qrGenerator.GenerateQRCode(shortCode, 256)
+	devices, err := h.service.GetDeviceStats(ctx, url.ShortCode)
 	if err != nil {
-		appLogger.CtxError(ctx, "Failed to generate QR code", appLogger.LoggerInfo{
-			ContextFunction: constant.CtxGenerateQRCode,
+		appLogger.CtxError(ctx, "Error retrieving bot visits for URL stats", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGetURLStats,
 			Error: &appLogger.CustomError{
 				Code:    constant.ErrCodeAPIServiceError,
 				Message: err.Error(),
@@ -319,71 +873,311 @@ func (h *Handler) GenerateQRCode(w http.ResponseWriter, r *http.Request) {
 			},
 		})
 
-		WriteJSONError(w, "Failed to generate QR code", http.StatusInternalServerError)
+		WriteJSONError(w, "Error retrieving URL stats", http.StatusInternalServerError)
 		return
 	}
 
-	appLogger.CtxInfo(ctx, "QR code generated successfully", appLogger.LoggerInfo{
-		ContextFunction: constant.CtxGenerateQRCode,
+	// Stats only change when the URL is visited, by a human or a bot, so the two
+	// counts version the response
+	etag := fmt.Sprintf(`"%s-%d-%d"`, url.ShortCode, url.Visits, devices.Bot)
+	w.Header().Set(constant.HeaderETag, etag)
+	w.Header().Set(constant.HeaderCacheControl, statsCacheControl)
+	if etagMatches(r.Header.Get(constant.HeaderIfNoneMatch), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	resp := URLStatsResponse{
+		FullUrl:        h.fullURL(url.ShortCode),
+		ShortCode:      url.ShortCode,
+		Visits:         url.Visits,
+		BotVisits:      devices.Bot,
+		LastAccessedAt: url.LastAccessedAt,
+	}
+
+	appLogger.CtxInfo(ctx, "URL stats retrieved successfully", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGetURLStats,
 		Data: map[string]interface{}{
 			constant.DataShortCode: shortCode,
-			"qr_size":              len(qrCode),
+			constant.DataVisits:    url.Visits,
 		},
 	})
 
-	// Set appropriate headers and write the image data
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Content-Length", strconv.Itoa(len(qrCode)))
-	w.WriteHeader(http.StatusOK)
-	w.Write(qrCode)
+	WriteJSON(w, resp, http.StatusOK)
 }
 
-// UpdateLongURL handles updating the long URL for an existing short code
-func (h *Handler) UpdateLongURL(w http.ResponseWriter, r *http.Request) {
+// statsCacheControl lets clients and shared caches reuse public stats for a minute
+const statsCacheControl = "public, max-age=60"
+
+// etagMatches reports whether an If-None-Match header names etag, or is "*".
+// Weak validators match too, as RFC 9110 asks for GET requests.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVisits handles retrieving click counts aggregated per hour or day
+func (h *Handler) GetVisits(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	shortCode := chi.URLParam(r, "shortCode")
+	query := r.URL.Query()
 
-	appLogger.CtxDebug(ctx, "Processing URL update request", appLogger.LoggerInfo{
-		ContextFunction: constant.CtxUpdateLongURL,
+	appLogger.CtxDebug(ctx, "Processing URL visits request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGetVisits,
 		Data: map[string]interface{}{
 			constant.DataShortCode: shortCode,
 		},
 	})
 
-	var req UpdateLongURLRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
-			ContextFunction: constant.CtxUpdateLongURL,
-			Error: &appLogger.CustomError{
-				Code:    constant.ErrCodeAPIDecodeRequest,
-				Message: err.Error(),
-				Type:    constant.ErrTypeAPI,
-			},
+	granularity := query.Get("granularity")
+	if granularity == "" {
+		granularity = shortener.GranularityDay
+	}
+
+	to := time.Now()
+	if raw := query.Get("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			WriteJSONError(w, "Invalid 'to' parameter, expected RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -7)
+	if granularity == shortener.GranularityHour {
+		from = to.Add(-24 * time.Hour)
+	}
+	if raw := query.Get("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			WriteJSONError(w, "Invalid 'from' parameter, expected RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+
+	buckets, err := h.service.GetVisits(ctx, shortCode, from, to, granularity)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeShortCodeNotFound):
+			http.NotFound(w, r)
+			return
+		case hasCode(err, constant.ErrCodeInvalidGranularity, constant.ErrCodeInvalidTimeRange):
+			WriteAPIError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		appLogger.CtxError(ctx, "Error retrieving URL visits", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGetVisits,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
 		})
 
-		WriteJSONError(w, "Invalid request format", http.StatusBadRequest)
+		WriteJSONError(w, "Error retrieving URL visits", http.StatusInternalServerError)
 		return
 	}
 
-	if req.LongURL == "" {
-		appLogger.CtxWarn(ctx, "Empty long URL in update request", appLogger.LoggerInfo{
-			ContextFunction: constant.CtxUpdateLongURL,
+	resp := VisitsResponse{
+		ShortCode:   shortCode,
+		Granularity: granularity,
+		From:        from.UTC(),
+		To:          to.UTC(),
+		Visits:      buckets,
+	}
+
+	WriteJSON(w, resp, http.StatusOK)
+}
+
+// GetReferers handles retrieving click counts grouped by HTTP referer
+func (h *Handler) GetReferers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	appLogger.CtxDebug(ctx, "Processing URL referers request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGetReferers,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	referers, err := h.service.GetReferers(ctx, shortCode)
+	if err != nil {
+		if hasCode(err, constant.ErrCodeShortCodeNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+
+		appLogger.CtxError(ctx, "Error retrieving URL referers", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGetReferers,
 			Error: &appLogger.CustomError{
-				Code:    constant.ErrCodeEmptyLongURL,
-				Message: constant.ErrEmptyLongURL,
-				Type:    constant.ErrTypeValidation,
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+
+		WriteJSONError(w, "Error retrieving URL referers", http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, ReferersResponse{Referers: referers}, http.StatusOK)
+}
+
+// GetDeviceStats handles retrieving click counts grouped by device type
+func (h *Handler) GetDeviceStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	appLogger.CtxDebug(ctx, "Processing URL device stats request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGetDeviceStats,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	stats, err := h.service.GetDeviceStats(ctx, shortCode)
+	if err != nil {
+		if hasCode(err, constant.ErrCodeShortCodeNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+
+		appLogger.CtxError(ctx, "Error retrieving URL device stats", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGetDeviceStats,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
 			},
 		})
 
-		WriteJSONError(w, "Long URL cannot be empty", http.StatusBadRequest)
+		WriteJSONError(w, "Error retrieving URL device stats", http.StatusInternalServerError)
 		return
 	}
 
-	url, err := h.service.UpdateLongURL(ctx, shortCode, req.LongURL)
+	WriteJSON(w, stats, http.StatusOK)
+}
+
+// GetSparkline handles retrieving one click count per day for sparkline charts
+func (h *Handler) GetSparkline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	appLogger.CtxDebug(ctx, "Processing URL sparkline request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGetSparkline,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	days := shortener.DefaultSparklineDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			WriteJSONError(w, "Invalid 'days' parameter, expected an integer", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	buckets, err := h.service.GetSparkline(ctx, shortCode, days)
 	if err != nil {
-		if err.Error() == constant.ErrShortCodeNotFound {
-			appLogger.CtxInfo(ctx, "Short code not found for update", appLogger.LoggerInfo{
-				ContextFunction: constant.CtxUpdateLongURL,
+		if hasCode(err, constant.ErrCodeShortCodeNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+
+		appLogger.CtxError(ctx, "Error retrieving URL sparkline", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGetSparkline,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+
+		WriteJSONError(w, "Error retrieving URL sparkline", http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, SparklineResponse{Buckets: buckets}, http.StatusOK)
+}
+
+// GenerateQRCode handles QR code generation for a short URL
+func (h *Handler) GenerateQRCode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	appLogger.CtxDebug(ctx, "Processing QR code generation request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGenerateQRCode,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	size, err := parseQRSize(r)
+	if hasCode(err, constant.ErrCodeAPIQRSize) {
+		appLogger.CtxInfo(ctx, "Invalid QR code size requested", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGenerateQRCode,
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataSize:      r.URL.Query().Get("size"),
+			},
+		})
+
+		WriteAPIError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	format, err := parseQRFormat(r)
+	if hasCode(err, constant.ErrCodeAPIQRFormat) {
+		WriteAPIError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	opts, optsKey, err := parseQROptions(r)
+	if hasCode(err, constant.ErrCodeAPIQRColor, constant.ErrCodeAPIQRECLevel) {
+		WriteAPIError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Verify that the short code exists without counting a visit against its limits
+	url, err := h.service.LookupURL(ctx, shortCode)
+	if err == nil {
+		// Statuses match RedirectToLongURL: a scheduled URL looks missing
+		switch url.StatusAt(time.Now()) {
+		case shortener.URLStatusScheduled:
+			err = shortener.ErrURLNotYetActive
+		case shortener.URLStatusExpired:
+			WriteAPIError(w, shortener.ErrShortCodeExpired, http.StatusGone)
+			return
+		}
+	}
+	if err != nil {
+		if hasCode(err, constant.ErrCodeShortCodeNotFound, constant.ErrCodeURLNotYetActive) {
+			appLogger.CtxInfo(ctx, "Short code not found for QR code generation", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxGenerateQRCode,
 				Data: map[string]interface{}{
 					constant.DataShortCode: shortCode,
 				},
@@ -393,8 +1187,8 @@ func (h *Handler) UpdateLongURL(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		appLogger.CtxError(ctx, "Error updating URL", appLogger.LoggerInfo{
-			ContextFunction: constant.CtxUpdateLongURL,
+		appLogger.CtxError(ctx, "Error retrieving URL for QR code", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxGenerateQRCode,
 			Error: &appLogger.CustomError{
 				Code:    constant.ErrCodeAPIServiceError,
 				Message: err.Error(),
@@ -402,45 +1196,1280 @@ func (h *Handler) UpdateLongURL(w http.ResponseWriter, r *http.Request) {
 			},
 			Data: map[string]interface{}{
 				constant.DataShortCode: shortCode,
-				constant.DataLongURL:   req.LongURL,
 			},
 		})
 
-		WriteJSONError(w, "Failed to update URL", http.StatusInternalServerError)
+		WriteJSONError(w, "Error generating QR code", http.StatusInternalServerError)
 		return
 	}
 
-	resp := ShortURLResponse{
-		FullUrl:   h.baseURL + "/" + url.ShortCode,
-		ShortCode: url.ShortCode,
-		LongURL:   url.LongURL,
+	contentType := "image/png"
+	if format == qrFormatSVG {
+		contentType = "image/svg+xml"
 	}
 
-	appLogger.CtxInfo(ctx, "URL updated successfully", appLogger.LoggerInfo{
-		ContextFunction: constant.CtxUpdateLongURL,
+	// Rendering is CPU-bound, so serve a cached image when there is one
+	variant := format + ":" + strconv.Itoa(size) + ":" + optsKey
+	qrCode, cached := h.service.RecallQRCode(shortCode, variant)
+	if !cached {
+		if format == qrFormatSVG {
+			qrCode, err = h.qrGenerator.GenerateQRCodeSVG(shortCode, size, opts)
+		} else {
+			qrCode, err = h.qrGenerator.GenerateQRCode(shortCode, size, opts)
+		}
+		if err != nil {
+			appLogger.CtxError(ctx, "Failed to generate QR code", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxGenerateQRCode,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAPIServiceError,
+					Message: err.Error(),
+					Type:    constant.ErrTypeAPI,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+
+			WriteJSONError(w, "Failed to generate QR code", http.StatusInternalServerError)
+			return
+		}
+		h.service.RememberQRCode(shortCode, variant, qrCode)
+	}
+
+	appLogger.CtxInfo(ctx, "QR code generated successfully", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxGenerateQRCode,
 		Data: map[string]interface{}{
 			constant.DataShortCode: shortCode,
-			constant.DataLongURL:   req.LongURL,
+			"qr_size":              len(qrCode),
+			"qr_format":            format,
+			"qr_cached":            cached,
 		},
 	})
 
-	WriteJSON(w, resp, http.StatusOK)
+	// Set appropriate headers and write the image data; ?download=1 asks the
+	// browser to save the image rather than display it
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "1" {
+		disposition = `attachment; filename="qr-` + shortCode + "." + format + `"`
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.Itoa(len(qrCode)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(qrCode)
 }
 
-// WriteJSON writes a JSON response
-func WriteJSON(w http.ResponseWriter, data interface{}, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	err := json.NewEncoder(w).Encode(data)
+// parseQRSize reads the size query parameter, defaulting to defaultQRSize when absent
+func parseQRSize(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("size")
+	if raw == "" {
+		return defaultQRSize, nil
+	}
+
+	size, err := strconv.Atoi(raw)
+	if err == nil {
+		for _, allowed := range allowedQRSizes {
+			if size == allowed {
+				return size, nil
+			}
+		}
+	}
+
+	return 0, errInvalidQRSize
+}
+
+// parseQRFormat picks the QR code format from the format query parameter, falling back to the Accept header
+func parseQRFormat(r *http.Request) (string, error) {
+	switch r.URL.Query().Get("format") {
+	case qrFormatSVG:
+		return qrFormatSVG, nil
+	case qrFormatPNG:
+		return qrFormatPNG, nil
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), "image/svg+xml") {
+			return qrFormatSVG, nil
+		}
+		return qrFormatPNG, nil
+	default:
+		return "", errInvalidQRFormat
+	}
+}
+
+// parseQROptions reads the fg and bg colors and the ec error correction level query
+// parameters, defaulting to black on white with level M. The returned key identifies
+// the options in cache keys.
+func parseQROptions(r *http.Request) (qrcode.QROptions, string, error) {
+	query := r.URL.Query()
+	opts := qrcode.DefaultQROptions()
+
+	fg, bg := strings.ToLower(query.Get("fg")), strings.ToLower(query.Get("bg"))
+	if fg == "" {
+		fg = "000000"
+	}
+	if bg == "" {
+		bg = "ffffff"
+	}
+	var err error
+	if opts.FGColor, err = parseHexColor(fg); err != nil {
+		return qrcode.QROptions{}, "", err
+	}
+	if opts.BGColor, err = parseHexColor(bg); err != nil {
+		return qrcode.QROptions{}, "", err
+	}
+
+	ec := strings.ToUpper(query.Get("ec"))
+	if ec == "" {
+		ec = "M"
+	}
+	level, found := qrECLevels[ec]
+	if !found {
+		return qrcode.QROptions{}, "", errInvalidQRECLevel
+	}
+	opts.ECLevel = level
+
+	return opts, fg + ":" + bg + ":" + ec, nil
+}
+
+// parseHexColor parses a six digit "rrggbb" hex color
+func parseHexColor(raw string) (color.Color, error) {
+	if len(raw) != 6 {
+		return nil, errInvalidQRColor
+	}
+	rgb, err := hex.DecodeString(raw)
 	if err != nil {
-		return
+		return nil, errInvalidQRColor
 	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}, nil
 }
 
-// WriteJSONError writes a JSON error response
-func WriteJSONError(w http.ResponseWriter, message string, statusCode int) {
-	WriteJSON(w, ErrorResponse{
-		Error: message,
-		Code:  statusCode,
-	}, statusCode)
+// UpdateLongURL handles updating the long URL for an existing short code
+func (h *Handler) UpdateLongURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	appLogger.CtxDebug(ctx, "Processing URL update request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxUpdateLongURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	var req UpdateLongURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLongURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.LongURL == "" {
+		appLogger.CtxWarn(ctx, "Empty long URL in update request", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLongURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeEmptyLongURL,
+				Message: constant.ErrEmptyLongURL,
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+
+		WriteAPIError(w, shortener.ErrEmptyLongURL, http.StatusBadRequest)
+		return
+	}
+
+	url, err := h.updateURL(ctx, shortCode, req)
+	if err != nil {
+		if hasCode(err, constant.ErrCodeShortCodeNotFound) {
+			appLogger.CtxInfo(ctx, "Short code not found for update", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxUpdateLongURL,
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+
+			http.NotFound(w, r)
+			return
+		}
+
+		if hasCode(err, constant.ErrCodeForbidden) {
+			WriteAPIError(w, err, http.StatusForbidden)
+			return
+		}
+		if isCanaryError(err) || hasCode(err, constant.ErrCodeInvalidLongURL) {
+			WriteAPIError(w, err, http.StatusBadRequest)
+			return
+		}
+		if hasCode(err, constant.ErrCodeBlacklistedURL, constant.ErrCodeSSRFBlocked) {
+			WriteAPIError(w, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		appLogger.CtxError(ctx, "Error updating URL", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLongURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataLongURL:   req.LongURL,
+			},
+		})
+
+		WriteJSONError(w, "Failed to update URL", http.StatusInternalServerError)
+		return
+	}
+
+	resp := h.newShortURLResponse(ctx, url)
+
+	appLogger.CtxInfo(ctx, "URL updated successfully", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxUpdateLongURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataLongURL:   req.LongURL,
+		},
+	})
+
+	WriteJSON(w, resp, http.StatusOK)
+}
+
+// updateURL applies req to shortCode. The canary split is changed first, so an
+// invalid split leaves the long URL untouched.
+func (h *Handler) updateURL(ctx context.Context, shortCode string, req UpdateLongURLRequest) (*shortener.URL, error) {
+	if req.AlternateURL != nil || req.CanaryPercent != nil {
+		var alternateURL string
+		var percent uint8
+		if req.AlternateURL != nil {
+			alternateURL = *req.AlternateURL
+		}
+		if req.CanaryPercent != nil {
+			percent = *req.CanaryPercent
+		}
+		if _, err := h.service.UpdateCanary(ctx, shortCode, alternateURL, percent); err != nil {
+			return nil, err
+		}
+	}
+	return h.service.UpdateLongURL(ctx, shortCode, req.LongURL)
+}
+
+// isCanaryError reports whether err rejects a canary split
+func isCanaryError(err error) bool {
+	return hasCode(err, constant.ErrCodeInvalidCanary, constant.ErrCodeMissingAlternateURL, constant.ErrCodeInvalidAlternateURL)
+}
+
+// RenameShortCode handles moving a short URL to a new short code
+func (h *Handler) RenameShortCode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	var req RenameShortCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxRenameShortCode,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	url, err := h.service.RenameShortCode(ctx, shortCode, req.NewShortCode)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeEmptyShortCode, constant.ErrCodeReservedShortCode):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		case hasCode(err, constant.ErrCodeShortCodeNotFound):
+			WriteAPIError(w, err, http.StatusNotFound)
+		case hasCode(err, constant.ErrCodeForbidden):
+			WriteAPIError(w, err, http.StatusForbidden)
+		case hasCode(err, constant.ErrCodeShortCodeExists):
+			WriteAPIError(w, err, http.StatusConflict)
+		default:
+			WriteJSONError(w, "Failed to rename short code", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	WriteJSON(w, h.newShortURLResponse(ctx, url), http.StatusOK)
+}
+
+// CloneURL handles copying a short URL's settings to a new short code. The body is optional.
+func (h *Handler) CloneURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	var req CloneURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxCloneURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	url, err := h.service.CloneURL(ctx, shortCode, req.NewShortCode)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeEmptyShortCode, constant.ErrCodeReservedShortCode, constant.ErrCodeInvalidExpiry):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		case hasCode(err, constant.ErrCodeShortCodeNotFound):
+			WriteAPIError(w, err, http.StatusNotFound)
+		case hasCode(err, constant.ErrCodeForbidden):
+			WriteAPIError(w, err, http.StatusForbidden)
+		case hasCode(err, constant.ErrCodeShortCodeExists):
+			WriteAPIError(w, err, http.StatusConflict)
+		case hasCode(err, constant.ErrCodeBlacklistedURL, constant.ErrCodeSSRFBlocked, constant.ErrCodeURLUnreachable):
+			WriteAPIError(w, err, http.StatusUnprocessableEntity)
+		default:
+			appLogger.CtxError(ctx, "Error cloning URL", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxCloneURL,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAPIServiceError,
+					Message: err.Error(),
+					Type:    constant.ErrTypeAPI,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			WriteJSONError(w, "Failed to clone URL", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	WriteJSON(w, h.newShortURLResponse(ctx, url), http.StatusCreated)
+}
+
+// DeleteURL handles soft-deleting a single short URL
+func (h *Handler) DeleteURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	err := h.service.DeleteURL(ctx, shortCode)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeShortCodeNotFound):
+			WriteAPIError(w, err, http.StatusNotFound)
+		case hasCode(err, constant.ErrCodeForbidden):
+			WriteAPIError(w, err, http.StatusForbidden)
+		default:
+			appLogger.CtxError(ctx, "Error deleting URL", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxDeleteURL,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAPIServiceError,
+					Message: err.Error(),
+					Type:    constant.ErrTypeAPI,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			WriteJSONError(w, "Failed to delete URL", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BulkDeleteRequest is the request object for BulkDeleteURLs endpoint
+type BulkDeleteRequest struct {
+	ShortCodes []string `json:"short_codes"`
+}
+
+// BulkDeleteURLs handles soft-deleting many short URLs in one request
+func (h *Handler) BulkDeleteURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req BulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxBulkDeleteURLs,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	result, err := h.service.BulkDeleteURLs(ctx, req.ShortCodes)
+	if err != nil {
+		if hasCode(err, constant.ErrCodeInvalidBulkDelete) {
+			WriteAPIError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		appLogger.CtxError(ctx, "Error bulk deleting URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxBulkDeleteURLs,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		WriteJSONError(w, "Failed to delete URLs", http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, result, http.StatusOK)
+}
+
+// ExportURLs streams every stored URL as a CSV or JSON attachment
+func (h *Handler) ExportURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatCSV
+	}
+
+	appLogger.CtxDebug(ctx, "Processing URL export request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxExportURLs,
+		Data: map[string]interface{}{
+			constant.DataFormat: format,
+		},
+	})
+
+	if format != exportFormatCSV && format != exportFormatJSON {
+		WriteJSONError(w, constant.ErrInvalidExportFormat, http.StatusBadRequest)
+		return
+	}
+
+	filename := "urls_" + time.Now().Format("2006-01-02") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	count := 0
+	var err error
+	if format == exportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		if err = writer.Write(exportCSVHeader); err == nil {
+			err = h.service.ExportURLs(ctx, func(url *shortener.URL) error {
+				count++
+				return writer.Write([]string{
+					strconv.FormatUint(uint64(url.ID), 10),
+					url.ShortCode,
+					url.LongURL,
+					url.CreatedAt.UTC().Format(time.RFC3339),
+					strconv.FormatUint(uint64(url.Visits), 10),
+					formatExpiresAt(url.ExpiresAt),
+				})
+			})
+		}
+		writer.Flush()
+		if err == nil {
+			err = writer.Error()
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		if _, err = w.Write([]byte("[")); err == nil {
+			err = h.service.ExportURLs(ctx, func(url *shortener.URL) error {
+				if count > 0 {
+					if _, err := w.Write([]byte(",")); err != nil {
+						return err
+					}
+				}
+				count++
+				return encoder.Encode(url)
+			})
+		}
+		if err == nil {
+			_, err = w.Write([]byte("]"))
+		}
+	}
+
+	// Headers are already sent once streaming starts, so failures can only be logged
+	if err != nil {
+		appLogger.CtxError(ctx, "Error exporting URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxExportURLs,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataFormat: format,
+				constant.DataCount:  count,
+			},
+		})
+		return
+	}
+
+	appLogger.CtxInfo(ctx, "URLs exported successfully", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxExportURLs,
+		Data: map[string]interface{}{
+			constant.DataFormat: format,
+			constant.DataCount:  count,
+		},
+	})
+}
+
+// ExportClicks handles downloading the raw click log of a short URL as CSV or JSON.
+// Visitor IPs are only ever exported as their SHA-256 hash.
+func (h *Handler) ExportClicks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatCSV
+	}
+
+	appLogger.CtxDebug(ctx, "Processing click export request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxExportClicks,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataFormat:    format,
+		},
+	})
+
+	if format != exportFormatCSV && format != exportFormatJSON {
+		WriteJSONError(w, constant.ErrInvalidExportFormat, http.StatusBadRequest)
+		return
+	}
+
+	clicks, err := h.service.ExportClicks(ctx, shortCode)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeShortCodeNotFound):
+			http.NotFound(w, r)
+		case hasCode(err, constant.ErrCodeForbidden):
+			WriteAPIError(w, err, http.StatusForbidden)
+		default:
+			WriteJSONError(w, "Failed to export clicks", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	filename := "clicks_" + shortCode + "_" + time.Now().Format("2006-01-02") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	if format == exportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		if err = writer.Write(clickExportCSVHeader); err == nil {
+			for _, click := range clicks {
+				record := []string{click.ClickedAt.UTC().Format(time.RFC3339), click.Referer, click.IPHash, click.UserAgent}
+				if err = writer.Write(record); err != nil {
+					break
+				}
+			}
+		}
+		writer.Flush()
+		if err == nil {
+			err = writer.Error()
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		if _, err = w.Write([]byte("[")); err == nil {
+			for i, click := range clicks {
+				if i > 0 {
+					if _, err = w.Write([]byte(",")); err != nil {
+						break
+					}
+				}
+				if err = encoder.Encode(click); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil {
+			_, err = w.Write([]byte("]"))
+		}
+	}
+
+	// Headers are already sent once streaming starts, so failures can only be logged
+	if err != nil {
+		appLogger.CtxError(ctx, "Error exporting clicks", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxExportClicks,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataFormat:    format,
+			},
+		})
+		return
+	}
+
+	appLogger.CtxInfo(ctx, "Clicks exported successfully", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxExportClicks,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataFormat:    format,
+			constant.DataCount:     len(clicks),
+		},
+	})
+}
+
+// ImportURLs creates short URLs from an uploaded CSV file in the export format
+func (h *Handler) ImportURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	appLogger.CtxDebug(ctx, "Processing URL import request", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxImportURLs,
+	})
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			WriteJSONError(w, "Import file exceeds 10 MB limit", http.StatusRequestEntityTooLarge)
+			return
+		}
+		WriteJSONError(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		WriteJSONError(w, "Missing 'file' field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		WriteJSONError(w, "Invalid CSV header", http.StatusBadRequest)
+		return
+	}
+
+	shortCodeCol, longURLCol := -1, -1
+	for i, column := range header {
+		switch strings.TrimSpace(column) {
+		case "short_code":
+			shortCodeCol = i
+		case "long_url":
+			longURLCol = i
+		}
+	}
+	if shortCodeCol < 0 || longURLCol < 0 {
+		WriteJSONError(w, "CSV header must include short_code and long_url columns", http.StatusBadRequest)
+		return
+	}
+
+	// rows maps each item handed to the service back to its CSV row
+	resp := ImportURLsResponse{Errors: []ImportRowError{}}
+	var items []shortener.BulkURL
+	var rows []int
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, ImportRowError{Row: row, Reason: err.Error()})
+			continue
+		}
+		if len(record) <= shortCodeCol || len(record) <= longURLCol {
+			resp.Errors = append(resp.Errors, ImportRowError{Row: row, Reason: "missing columns"})
+			continue
+		}
+
+		longURL := strings.TrimSpace(record[longURLCol])
+		shortCode := strings.TrimSpace(record[shortCodeCol])
+		if longURL == "" {
+			resp.Errors = append(resp.Errors, ImportRowError{Row: row, Reason: constant.ErrEmptyLongURL})
+			continue
+		}
+
+		items = append(items, shortener.BulkURL{LongURL: longURL, CustomShort: shortCode})
+		rows = append(rows, row)
+	}
+
+	result, err := h.service.CreateBulkShortURLs(ctx, items)
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to import URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxImportURLs,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIServiceError,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+			Data: map[string]interface{}{
+				constant.DataCount: len(items),
+			},
+		})
+		WriteJSONError(w, "Failed to import URLs", http.StatusInternalServerError)
+		return
+	}
+	resp.Imported = result.Stored
+	for _, failure := range result.Errors {
+		resp.Errors = append(resp.Errors, ImportRowError{Row: rows[failure.Index], Reason: failure.Reason})
+	}
+	sort.Slice(resp.Errors, func(i, j int) bool {
+		return resp.Errors[i].Row < resp.Errors[j].Row
+	})
+
+	appLogger.CtxInfo(ctx, "URLs imported", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxImportURLs,
+		Data: map[string]interface{}{
+			constant.DataCount:  resp.Imported,
+			constant.DataErrors: len(resp.Errors),
+		},
+	})
+
+	WriteJSON(w, resp, http.StatusOK)
+}
+
+// SetLogLevel handles changing the logger level at runtime
+func (h *Handler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxSetLogLevel,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	if err := appLogger.SetLevel(req.Level); err != nil {
+		WriteAPIError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	appLogger.CtxInfo(ctx, "Log level changed", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxSetLogLevel,
+		Data: map[string]interface{}{
+			constant.DataLevel: appLogger.Level.String(),
+		},
+	})
+
+	WriteJSON(w, LogLevelResponse{Level: appLogger.Level.String()}, http.StatusOK)
+}
+
+// CreateUserRequest is the request object for CreateUser endpoint
+type CreateUserRequest struct {
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	Role     shortener.Role `json:"role"`
+}
+
+// CreateUser handles creating a user account
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxCreateUser,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = shortener.RoleUser
+	}
+
+	user, err := h.service.CreateUser(ctx, req.Username, req.Password, req.Role)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeEmptyUsername, constant.ErrCodeEmptyUserPassword, constant.ErrCodeInvalidRole):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		case hasCode(err, constant.ErrCodeUserExists):
+			WriteAPIError(w, err, http.StatusConflict)
+		default:
+			WriteJSONError(w, "Failed to create user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	WriteJSON(w, user, http.StatusCreated)
+}
+
+// ListUsers handles listing user accounts
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.service.ListUsers(r.Context())
+	if err != nil {
+		WriteJSONError(w, "Failed to list users", http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, users, http.StatusOK)
+}
+
+// SearchURLsResponse is the response object for SearchURLs endpoint
+type SearchURLsResponse struct {
+	URLs  []*shortener.URL `json:"urls"`
+	Total int              `json:"total"`
+}
+
+// SearchURLs handles finding URLs whose long URL contains the q parameter
+func (h *Handler) SearchURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	limit := shortener.DefaultSearchLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			WriteAPIError(w, shortener.ErrInvalidSearchLimit, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if raw := query.Get("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			WriteAPIError(w, shortener.ErrInvalidSearchOffset, http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	var urls []*shortener.URL
+	var total int
+	var err error
+	if tag := query.Get("tag"); tag != "" {
+		urls, total, err = h.service.ListByTag(ctx, tag, limit, offset)
+	} else {
+		urls, total, err = h.service.SearchURLs(ctx, query.Get("q"), limit, offset)
+	}
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeInvalidSearchLimit, constant.ErrCodeInvalidSearchOffset, constant.ErrCodeInvalidTag):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		default:
+			WriteJSONError(w, "Failed to search URLs", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	WriteJSON(w, SearchURLsResponse{URLs: urls, Total: total}, http.StatusOK)
+}
+
+// RecentURLsResponse is the response object for ListRecentURLs endpoint
+type RecentURLsResponse struct {
+	URLs []*shortener.URL `json:"urls"`
+}
+
+// ListRecentURLs handles listing the URLs created after the since parameter, newest first.
+// Without since it lists the URLs created in the last 24 hours.
+func (h *Handler) ListRecentURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	since := time.Now().Add(-24 * time.Hour)
+	if raw := query.Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			WriteJSONError(w, "Invalid 'since' parameter, expected RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	limit := shortener.DefaultSearchLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			WriteAPIError(w, shortener.ErrInvalidSearchLimit, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if raw := query.Get("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			WriteAPIError(w, shortener.ErrInvalidSearchOffset, http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	urls, err := h.service.ListRecentURLs(ctx, since, limit, offset)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeInvalidSearchLimit, constant.ErrCodeInvalidSearchOffset):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		default:
+			WriteJSONError(w, "Failed to list recent URLs", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	WriteJSON(w, RecentURLsResponse{URLs: urls}, http.StatusOK)
+}
+
+// TopURL is one entry of the GetTopURLs response
+type TopURL struct {
+	ShortCode string `json:"short_code"`
+	LongURL   string `json:"long_url"`
+	Visits    uint   `json:"visits"`
+	ShortURL  string `json:"short_url"`
+}
+
+// TopURLsResponse is the response object for GetTopURLs endpoint
+type TopURLsResponse struct {
+	URLs []TopURL `json:"urls"`
+}
+
+// GetTopURLs handles listing the most visited URLs. The limit parameter defaults
+// to shortener.DefaultTopURLsLimit and is capped at shortener.MaxTopURLsLimit.
+func (h *Handler) GetTopURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := shortener.DefaultTopURLsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			WriteAPIError(w, shortener.ErrInvalidSearchLimit, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	urls, err := h.service.GetTopURLs(ctx, limit)
+	if err != nil {
+		if hasCode(err, constant.ErrCodeInvalidSearchLimit) {
+			WriteAPIError(w, err, http.StatusBadRequest)
+			return
+		}
+		WriteJSONError(w, "Failed to list top URLs", http.StatusInternalServerError)
+		return
+	}
+
+	resp := TopURLsResponse{URLs: make([]TopURL, 0, len(urls))}
+	for _, url := range urls {
+		resp.URLs = append(resp.URLs, TopURL{
+			ShortCode: url.ShortCode,
+			LongURL:   url.LongURL,
+			Visits:    url.Visits,
+			ShortURL:  h.fullURL(url.ShortCode),
+		})
+	}
+	WriteJSON(w, resp, http.StatusOK)
+}
+
+// TrendingURL is one entry of the GetTrendingURLs response
+type TrendingURL struct {
+	ShortCode    string `json:"short_code"`
+	LongURL      string `json:"long_url"`
+	Visits       uint   `json:"visits"`
+	RecentVisits uint   `json:"recent_visits"`
+	ShortURL     string `json:"short_url"`
+}
+
+// TrendingURLsResponse is the response object for GetTrendingURLs endpoint
+type TrendingURLsResponse struct {
+	Period string        `json:"period"`
+	URLs   []TrendingURL `json:"urls"`
+}
+
+// GetTrendingURLs handles listing the URLs with the most clicks within a recent period.
+// The period parameter is a Go duration that defaults to shortener.DefaultTrendingPeriod;
+// the limit parameter behaves as in GetTopURLs.
+func (h *Handler) GetTrendingURLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	period := shortener.DefaultTrendingPeriod
+	if raw := r.URL.Query().Get("period"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			WriteAPIError(w, shortener.ErrInvalidPeriod, http.StatusBadRequest)
+			return
+		}
+		period = parsed
+	}
+
+	limit := shortener.DefaultTopURLsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			WriteAPIError(w, shortener.ErrInvalidSearchLimit, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	urls, err := h.service.GetTrendingURLs(ctx, period, limit)
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeInvalidPeriod, constant.ErrCodeInvalidSearchLimit):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		default:
+			WriteJSONError(w, "Failed to list trending URLs", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := TrendingURLsResponse{Period: period.String(), URLs: make([]TrendingURL, 0, len(urls))}
+	for _, url := range urls {
+		resp.URLs = append(resp.URLs, TrendingURL{
+			ShortCode:    url.ShortCode,
+			LongURL:      url.LongURL,
+			Visits:       url.Visits,
+			RecentVisits: url.RecentVisits,
+			ShortURL:     h.fullURL(url.ShortCode),
+		})
+	}
+	WriteJSON(w, resp, http.StatusOK)
+}
+
+// AddTagRequest is the request object for AddTag endpoint
+type AddTagRequest struct {
+	Tag string `json:"tag"`
+}
+
+// AddTag handles attaching a tag to a short URL
+func (h *Handler) AddTag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	var req AddTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxAddTag,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	_, err := h.service.AddTag(ctx, shortCode, req.Tag)
+	h.writeTagResult(w, r, shortCode, err)
+}
+
+// RemoveTag handles detaching a tag from a short URL
+func (h *Handler) RemoveTag(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	_, err := h.service.RemoveTag(r.Context(), shortCode, chi.URLParam(r, "tag"))
+	h.writeTagResult(w, r, shortCode, err)
+}
+
+// writeTagResult responds to a tag change with the short URL and its tags, or the error
+func (h *Handler) writeTagResult(w http.ResponseWriter, r *http.Request, shortCode string, err error) {
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeInvalidTag, constant.ErrCodeEmptyShortCode):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		case hasCode(err, constant.ErrCodeShortCodeNotFound, constant.ErrCodeTagNotFound):
+			WriteAPIError(w, err, http.StatusNotFound)
+		case hasCode(err, constant.ErrCodeForbidden):
+			WriteAPIError(w, err, http.StatusForbidden)
+		default:
+			WriteJSONError(w, "Failed to update tags", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	url, err := h.service.LookupURL(r.Context(), shortCode)
+	if err != nil {
+		WriteJSONError(w, "Failed to update tags", http.StatusInternalServerError)
+		return
+	}
+
+	WriteJSON(w, h.newShortURLResponse(r.Context(), url), http.StatusOK)
+}
+
+// GetAuditLog handles listing the recorded changes to a short URL
+func (h *Handler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.service.GetAuditLog(r.Context(), chi.URLParam(r, "shortCode"))
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeEmptyShortCode):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		default:
+			WriteJSONError(w, "Failed to get audit log", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	WriteJSON(w, entries, http.StatusOK)
+}
+
+// CreateWebhookRequest is the request object for CreateWebhook endpoint
+type CreateWebhookRequest struct {
+	URL       string   `json:"url"`
+	ShortCode string   `json:"short_code"`
+	Secret    string   `json:"secret"`
+	Events    []string `json:"events,omitempty"`
+}
+
+// CreateWebhook handles registering a webhook for a short URL
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appLogger.CtxError(ctx, "Error decoding request body", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxCreateWebhook,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAPIDecodeRequest,
+				Message: err.Error(),
+				Type:    constant.ErrTypeAPI,
+			},
+		})
+
+		writeDecodeError(w, err)
+		return
+	}
+
+	hook, err := h.service.CreateWebhook(ctx, &shortener.Webhook{
+		URL:       req.URL,
+		ShortCode: req.ShortCode,
+		Secret:    req.Secret,
+		Events:    req.Events,
+	})
+	if err != nil {
+		switch {
+		case hasCode(err, constant.ErrCodeInvalidWebhookURL, constant.ErrCodeEmptyShortCode, constant.ErrCodeEmptyWebhookSecret, constant.ErrCodeInvalidWebhookEvent):
+			WriteAPIError(w, err, http.StatusBadRequest)
+		case hasCode(err, constant.ErrCodeShortCodeNotFound):
+			WriteAPIError(w, err, http.StatusNotFound)
+		case hasCode(err, constant.ErrCodeForbidden):
+			WriteAPIError(w, err, http.StatusForbidden)
+		case hasCode(err, constant.ErrCodeSSRFBlocked):
+			WriteAPIError(w, err, http.StatusUnprocessableEntity)
+		default:
+			WriteJSONError(w, "Failed to create webhook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	WriteJSON(w, hook, http.StatusCreated)
+}
+
+// HealthResponse is the response object for Health endpoint
+type HealthResponse struct {
+	Status        string `json:"status"`
+	DB            string `json:"db"`
+	Cache         string `json:"cache"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// Health handles reporting whether the database and cache are reachable
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	appLogger.CtxDebug(r.Context(), constant.MsgHealthcheckRequest, appLogger.LoggerInfo{
+		ContextFunction: constant.CtxHealth,
+	})
+
+	report := h.service.Health(r.Context())
+	resp := HealthResponse{
+		Status:        shortener.HealthOK,
+		DB:            report.DB,
+		Cache:         report.Cache,
+		UptimeSeconds: int64(time.Since(h.startedAt).Seconds()),
+	}
+
+	status := http.StatusOK
+	if !report.Healthy() {
+		resp.Status = shortener.HealthDegraded
+		status = http.StatusServiceUnavailable
+	}
+
+	WriteJSON(w, resp, status)
+}
+
+// robotsTxt keeps crawlers off the redirect routes, which would otherwise be
+// indexed as pages of their own
+const robotsTxt = `User-agent: *
+Disallow: /
+Allow: /api/
+Allow: /health
+`
+
+// RobotsTxt handles serving the crawler rules
+func (h *Handler) RobotsTxt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, robotsTxt)
+}
+
+// CacheStatsResponse is the response for CacheStats endpoint
+type CacheStatsResponse struct {
+	cache.CacheStats
+	// OldestEntryAgeSeconds is how long ago the entry stored longest ago was set
+	OldestEntryAgeSeconds int64 `json:"oldest_entry_age_seconds"`
+}
+
+// CacheStats handles reporting the URL cache counters and contents
+func (h *Handler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := h.service.CacheStats()
+	WriteJSON(w, CacheStatsResponse{
+		CacheStats:            stats,
+		OldestEntryAgeSeconds: int64(stats.OldestEntryAge.Seconds()),
+	}, http.StatusOK)
+}
+
+// WriteJSON writes a JSON response
+func WriteJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		return
+	}
+}
+
+// WriteJSONError writes a JSON error response for a message that has no domain error behind it
+func WriteJSONError(w http.ResponseWriter, message string, statusCode int) {
+	WriteAPIError(w, errors.New(message), statusCode)
+}
+
+// writeDecodeError answers a request whose JSON body could not be decoded,
+// using 413 when the body went over the size limit
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		WriteJSONError(w, constant.MsgRequestBodyTooLarge, http.StatusRequestEntityTooLarge)
+		return
+	}
+	WriteJSONError(w, "Invalid request format", http.StatusBadRequest)
 }
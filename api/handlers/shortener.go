@@ -26,13 +26,13 @@ type ShortenResponse struct {
 // ShortenerHandler handles URL shortening HTTP requests
 type ShortenerHandler struct {
 	service     *shortener.Service
-	cache       *cache.NamespaceLRU
-	qrGenerator *qrcode.Generator
+	cache       cache.Cache
+	qrGenerator qrcode.QRGenerator
 	baseURL     string
 }
 
 // NewShortenerHandler creates a new shortener handler
-func NewShortenerHandler(service *shortener.Service, cache *cache.NamespaceLRU, qrGenerator *qrcode.Generator, baseURL string) *ShortenerHandler {
+func NewShortenerHandler(service *shortener.Service, cache cache.Cache, qrGenerator qrcode.QRGenerator, baseURL string) *ShortenerHandler {
 	return &ShortenerHandler{
 		service:     service,
 		cache:       cache,
@@ -71,7 +71,7 @@ func (h *ShortenerHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 
-	url, err := h.service.CreateShortURL(ctx, req.URL, req.ShortURL)
+	url, err := h.service.CreateShortURL(ctx, shortener.UserIDFromContext(ctx), req.URL, req.ShortURL)
 	if err != nil {
 		logger.CtxError(ctx, "Failed to create short URL", logger.LoggerInfo{
 			ContextFunction: "ShortenURL",
@@ -192,7 +192,7 @@ func (h *ShortenerHandler) GenerateQRCode(w http.ResponseWriter, r *http.Request
 	}
 
 	// Generate QR code
-	qrCode, err := h.qrGenerator.GenerateQRCode(shortCode, 256)
+	qrCode, err := h.qrGenerator.GenerateQRCode(shortCode, 256, qrcode.DefaultQROptions())
 	if err != nil {
 		logger.CtxError(ctx, "Failed to generate QR code", logger.LoggerInfo{
 			ContextFunction: "GenerateQRCode",
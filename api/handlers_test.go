@@ -3,587 +3,3017 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"html/template"
+	"image/color"
+	"math/rand"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/prasetyowira/shorter/config"
 	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
 	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/prasetyowira/shorter/infrastructure/db"
+	"github.com/prasetyowira/shorter/infrastructure/geo"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
 	"github.com/prasetyowira/shorter/infrastructure/qrcode"
+	"github.com/prasetyowira/shorter/infrastructure/security"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
-// Mock service for testing
-type MockService struct {
+// Mock QR code generator for testing
+type MockQRGenerator struct {
 	mock.Mock
 }
 
-func (m *MockService) CreateShortURL(ctx context.Context, longURL string, customShortURL string) (*shortener.URL, error) {
-	args := m.Called(ctx, longURL, customShortURL)
+func (m *MockQRGenerator) GenerateQRCode(shortCode string, size int, opts qrcode.QROptions) ([]byte, error) {
+	args := m.Called(shortCode, size, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*shortener.URL), args.Error(1)
+	return args.Get(0).([]byte), args.Error(1)
 }
 
-func (m *MockService) GetLongURL(ctx context.Context, shortCode string) (*shortener.URL, error) {
-	args := m.Called(ctx, shortCode)
+func (m *MockQRGenerator) GenerateQRCodeSVG(shortCode string, size int, opts qrcode.QROptions) ([]byte, error) {
+	args := m.Called(shortCode, size, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*shortener.URL), args.Error(1)
+	return args.Get(0).([]byte), args.Error(1)
 }
 
-// Mock QR code generator for testing
-type MockQRGenerator struct {
-	mock.Mock
+// failingRepository is an in-memory repository whose URL lookups and writes fail with err
+type failingRepository struct {
+	*db.MemoryRepository
+	err error
 }
 
-func (m *MockQRGenerator) GenerateQRCode(shortCode string, size int) ([]byte, error) {
-	args := m.Called(shortCode, size)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
+func (r failingRepository) Store(ctx context.Context, url *shortener.URL) error {
+	return r.err
+}
+
+func (r failingRepository) FindByShortCode(ctx context.Context, shortCode string) (*shortener.URL, error) {
+	return nil, r.err
+}
+
+// newTestHandler returns a handler over a real service backed by repo, without a cache
+func newTestHandler(repo shortener.Repository, qrGenerator qrcode.QRGenerator) *Handler {
+	service := shortener.NewService(repo, cache.NewNoopCache(), shortener.ServiceOptions{})
+	return NewHandler(service, qrGenerator, "http://localhost:8080")
+}
+
+// seedURL stores a URL for shortCode pointing at longURL
+func seedURL(t *testing.T, repo shortener.Repository, url *shortener.URL) {
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now()
 	}
-	return args.Get(0).([]byte), args.Error(1)
+	assert.NoError(t, repo.Store(context.Background(), url))
+}
+
+// withShortCode adds the shortCode route parameter chi would have parsed
+func withShortCode(req *http.Request, shortCode string) *http.Request {
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("shortCode", shortCode)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
 }
 
 func TestNewHandler(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
+	service := shortener.NewService(db.NewMemoryRepository(), cache.NewNoopCache(), shortener.ServiceOptions{})
 	mockQRGenerator := new(MockQRGenerator)
 	baseURL := "http://localhost:8080"
-	
+
 	// Act
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
+	handler := NewHandler(service, mockQRGenerator, baseURL)
+
 	// Assert
 	assert.NotNil(t, handler)
-	assert.Equal(t, mockService, handler.service)
+	assert.Equal(t, service, handler.service)
 	assert.Equal(t, mockQRGenerator, handler.qrGenerator)
 	assert.Equal(t, baseURL, handler.baseURL)
 }
 
-func TestWithRequestID(t *testing.T) {
-	// Arrange
-	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if request ID is in context
-		requestID := r.Context().Value(constant.RequestIDKey)
-		assert.NotNil(t, requestID)
-		
-		// Check if request ID header is set
-		headerRequestID := w.Header().Get(constant.HeaderRequestID)
-		assert.NotEmpty(t, headerRequestID)
-		assert.Equal(t, requestID, headerRequestID)
-		
-		w.WriteHeader(http.StatusOK)
-	})
-	
-	middleware := withRequestID(nextHandler)
-	req := httptest.NewRequest("GET", "/", nil)
-	w := httptest.NewRecorder()
-	
-	// Act
-	middleware.ServeHTTP(w, req)
-	
-	// Assert
-	assert.Equal(t, http.StatusOK, w.Code)
-}
-
-func TestLogRequest(t *testing.T) {
-	// Arrange
-	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
-	
-	middleware := logRequest(nextHandler)
-	req := httptest.NewRequest("GET", "/test", nil)
-	w := httptest.NewRecorder()
-	
-	// Act
-	middleware.ServeHTTP(w, req)
-	
-	// Assert
-	assert.Equal(t, http.StatusOK, w.Code)
-	// Note: We can't easily test the logger output without mocking it
-}
-
 func TestCreateShortURL_Success(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	handler := NewHandler(mockService)
-	
+	repo := db.NewMemoryRepository()
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
 	longURL := "https://example.com"
-	createReq := CreateShortURLRequest{
-		LongURL: longURL,
-	}
-	
-	expectedURL := &shortener.URL{
-		ID:        1,
-		LongURL:   longURL,
-		ShortCode: "abc123",
-		CreatedAt: time.Now(),
-		Visits:    0,
-	}
-	
-	mockService.On("CreateShortURL", mock.Anything, longURL, mock.Anything).Return(expectedURL, nil)
-	
-	reqBody, _ := json.Marshal(createReq)
+	reqBody, _ := json.Marshal(CreateShortURLRequest{LongURL: longURL, CustomShortURL: "abc123"})
 	req := httptest.NewRequest("POST", "/api/urls", bytes.NewBuffer(reqBody))
 	w := httptest.NewRecorder()
-	
+
 	// Act
 	handler.CreateShortURL(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusCreated, w.Code)
-	
+
 	var response ShortURLResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, expectedURL.ShortCode, response.ShortCode)
-	assert.Equal(t, expectedURL.LongURL, response.LongURL)
-	
-	mockService.AssertExpectations(t)
+	assert.Equal(t, "abc123", response.ShortCode)
+	assert.Equal(t, handler.baseURL+"/abc123", response.FullUrl)
+	assert.Equal(t, longURL, response.LongURL)
+
+	stored, err := repo.FindByShortCode(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, longURL, stored.LongURL)
 }
 
 func TestCreateShortURL_InvalidRequestBody(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	handler := NewHandler(mockService)
-	
+	repo := db.NewMemoryRepository()
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
 	invalidJSON := []byte(`{"long_url": }`) // Invalid JSON
 	req := httptest.NewRequest("POST", "/api/urls", bytes.NewBuffer(invalidJSON))
 	w := httptest.NewRecorder()
-	
+
 	// Act
 	handler.CreateShortURL(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	
+
 	var response ErrorResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, "Invalid request format", response.Error)
 	assert.Equal(t, http.StatusBadRequest, response.Code)
-	
-	mockService.AssertNotCalled(t, "CreateShortURL")
+
+	var count int
+	assert.NoError(t, repo.FindAll(context.Background(), func(url *shortener.URL) error { count++; return nil }))
+	assert.Zero(t, count, "nothing is stored")
 }
 
 func TestCreateShortURL_EmptyURL(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	handler := NewHandler(mockService)
-	
-	createReq := CreateShortURLRequest{
-		LongURL: "", // Empty URL
-	}
-	
-	mockService.On("CreateShortURL", mock.Anything, "", mock.Anything).
-		Return(nil, errors.New(constant.ErrEmptyLongURL))
-	
-	reqBody, _ := json.Marshal(createReq)
+	handler := newTestHandler(db.NewMemoryRepository(), new(MockQRGenerator))
+
+	reqBody, _ := json.Marshal(CreateShortURLRequest{LongURL: ""})
 	req := httptest.NewRequest("POST", "/api/urls", bytes.NewBuffer(reqBody))
 	w := httptest.NewRecorder()
-	
+
 	// Act
 	handler.CreateShortURL(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	
+
 	var response ErrorResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "URL cannot be empty", response.Error)
-	
-	mockService.AssertExpectations(t)
+	assert.Equal(t, constant.ErrEmptyLongURL, response.Error)
+	assert.Equal(t, "empty_long_url", response.ErrorCode)
 }
 
 func TestCreateShortURL_ServiceError(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	handler := NewHandler(mockService)
-	
-	longURL := "https://example.com"
-	createReq := CreateShortURLRequest{
-		LongURL: longURL,
-	}
-	
-	expectedError := errors.New("service error")
-	mockService.On("CreateShortURL", mock.Anything, longURL, mock.Anything).
-		Return(nil, expectedError)
-	
-	reqBody, _ := json.Marshal(createReq)
+	repo := failingRepository{MemoryRepository: db.NewMemoryRepository(), err: errors.New("database is down")}
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	reqBody, _ := json.Marshal(CreateShortURLRequest{LongURL: "https://example.com"})
 	req := httptest.NewRequest("POST", "/api/urls", bytes.NewBuffer(reqBody))
 	w := httptest.NewRecorder()
-	
+
 	// Act
 	handler.CreateShortURL(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	
+
 	var response ErrorResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, "Failed to create short URL", response.Error)
-	
-	mockService.AssertExpectations(t)
 }
 
 func TestRedirectToLongURL_Success(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "abc123"
-	mockURL := &shortener.URL{
-		ID:        1,
-		LongURL:   "https://example.com",
-		ShortCode: shortCode,
-		CreatedAt: time.Now(),
-		Visits:    5,
-	}
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).Return(mockURL, nil)
-	
-	// Setup Chi router context with URL parameter
-	req := httptest.NewRequest("GET", "/"+shortCode, nil)
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", Visits: 5})
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	req := withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123")
 	w := httptest.NewRecorder()
-	
-	// Chi router context setup
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.RedirectToLongURL(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusFound, w.Code)
-	assert.Equal(t, mockURL.LongURL, w.Header().Get("Location"))
-	
-	mockService.AssertExpectations(t)
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
 }
 
 func TestRedirectToLongURL_NotFound(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "nonexistent"
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).
-		Return(nil, errors.New(constant.ErrShortCodeNotFound))
-	
-	// Setup Chi router context with URL parameter
-	req := httptest.NewRequest("GET", "/"+shortCode, nil)
+	handler := newTestHandler(db.NewMemoryRepository(), new(MockQRGenerator))
+
+	req := withShortCode(httptest.NewRequest("GET", "/nonexistent", nil), "nonexistent")
 	w := httptest.NewRecorder()
-	
-	// Chi router context setup
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.RedirectToLongURL(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	
-	mockService.AssertExpectations(t)
 }
 
 func TestRedirectToLongURL_ServiceError(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "abc123"
-	expectedError := errors.New("service error")
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).
-		Return(nil, expectedError)
-	
-	// Setup Chi router context with URL parameter
-	req := httptest.NewRequest("GET", "/"+shortCode, nil)
+	repo := failingRepository{MemoryRepository: db.NewMemoryRepository(), err: errors.New("database is down")}
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	req := withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123")
 	w := httptest.NewRecorder()
-	
-	// Chi router context setup
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.RedirectToLongURL(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	
+
 	var response ErrorResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, "Error retrieving URL", response.Error)
-	
-	mockService.AssertExpectations(t)
 }
 
 func TestGetURLStats_Success(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "abc123"
-	visits := uint(42)
-	mockURL := &shortener.URL{
-		ID:        1,
-		LongURL:   "https://example.com",
-		ShortCode: shortCode,
-		CreatedAt: time.Now(),
-		Visits:    visits,
-	}
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).Return(mockURL, nil)
-	
-	// Setup Chi router context with URL parameter
-	req := httptest.NewRequest("GET", "/api/urls/"+shortCode+"/stats", nil)
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", Visits: 42})
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/stats", nil), "abc123")
 	w := httptest.NewRecorder()
-	
-	// Chi router context setup
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.GetURLStats(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusOK, w.Code)
-	
+
 	var response URLStatsResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, shortCode, response.ShortCode)
-	assert.Equal(t, visits, response.Visits)
-	
-	mockService.AssertExpectations(t)
+	assert.Equal(t, "abc123", response.ShortCode)
+	assert.Equal(t, handler.baseURL+"/abc123", response.FullUrl)
+	assert.Equal(t, uint(42), response.Visits)
+	assert.Nil(t, response.LastAccessedAt)
+}
+
+func TestGetURLStats_LastAccessedAt(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+	handler := newTestHandler(repo, nil)
+	stats := func() URLStatsResponse {
+		w := httptest.NewRecorder()
+		handler.GetURLStats(w, withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/stats", nil), "abc123"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response URLStatsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// Act
+	before := time.Now()
+	handler.RedirectToLongURL(httptest.NewRecorder(), withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123"))
+	first := stats()
+	time.Sleep(time.Millisecond)
+	handler.RedirectToLongURL(httptest.NewRecorder(), withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123"))
+	second := stats()
+
+	// Assert
+	assert.Equal(t, uint(2), second.Visits)
+	if assert.NotNil(t, first.LastAccessedAt) && assert.NotNil(t, second.LastAccessedAt) {
+		assert.False(t, first.LastAccessedAt.Before(before))
+		assert.True(t, second.LastAccessedAt.After(*first.LastAccessedAt), "each redirect moves the time forward")
+	}
 }
 
 func TestGetURLStats_NotFound(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "nonexistent"
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).
-		Return(nil, errors.New(constant.ErrShortCodeNotFound))
-	
-	// Setup Chi router context with URL parameter
-	req := httptest.NewRequest("GET", "/api/urls/"+shortCode+"/stats", nil)
+	handler := newTestHandler(db.NewMemoryRepository(), new(MockQRGenerator))
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/nonexistent/stats", nil), "nonexistent")
 	w := httptest.NewRecorder()
-	
-	// Chi router context setup
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.GetURLStats(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	
-	mockService.AssertExpectations(t)
 }
 
 func TestGetURLStats_ServiceError(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
-	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "abc123"
-	expectedError := errors.New("service error")
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).
-		Return(nil, expectedError)
-	
-	// Setup Chi router context with URL parameter
-	req := httptest.NewRequest("GET", "/api/urls/"+shortCode+"/stats", nil)
+	repo := failingRepository{MemoryRepository: db.NewMemoryRepository(), err: errors.New("database is down")}
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/stats", nil), "abc123")
 	w := httptest.NewRecorder()
-	
-	// Chi router context setup
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.GetURLStats(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	
+
 	var response ErrorResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "Failed to retrieve URL stats", response.Error)
-	
-	mockService.AssertExpectations(t)
+	assert.Equal(t, "Error retrieving URL stats", response.Error)
 }
 
 func TestGenerateQRCode_Success(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
 	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "abc123"
+	handler := newTestHandler(repo, mockQRGenerator)
+
 	mockQRData := []byte("fake-qr-code-data")
-	mockURL := &shortener.URL{
-		ID:        1,
-		LongURL:   "https://example.com",
-		ShortCode: shortCode,
-		CreatedAt: time.Now(),
-		Visits:    5,
-	}
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).Return(mockURL, nil)
-	mockQRGenerator.On("GenerateQRCode", shortCode, 256).Return(mockQRData, nil)
-	
-	// Chi router context setup
-	req := httptest.NewRequest("GET", "/api/urls/"+shortCode+"/qrcode", nil)
+	mockQRGenerator.On("GenerateQRCode", "abc123", 256, qrcode.DefaultQROptions()).Return(mockQRData, nil)
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil), "abc123")
 	w := httptest.NewRecorder()
-	
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.GenerateQRCode(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "inline", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
 	assert.Equal(t, mockQRData, w.Body.Bytes())
-	
-	mockService.AssertExpectations(t)
+
 	mockQRGenerator.AssertExpectations(t)
 }
 
+func TestGenerateQRCode_Download(t *testing.T) {
+	tests := []struct {
+		name                string
+		query               string
+		expectedDisposition string
+	}{
+		{name: "PNG", query: "?download=1", expectedDisposition: `attachment; filename="qr-abc123.png"`},
+		{name: "SVG", query: "?download=1&format=svg", expectedDisposition: `attachment; filename="qr-abc123.svg"`},
+		{name: "Other value", query: "?download=0", expectedDisposition: "inline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+			mockQRGenerator := new(MockQRGenerator)
+			handler := newTestHandler(repo, mockQRGenerator)
+
+			mockQRGenerator.On("GenerateQRCode", "abc123", 256, qrcode.DefaultQROptions()).Return([]byte("fake-qr-code-data"), nil)
+			mockQRGenerator.On("GenerateQRCodeSVG", "abc123", 256, qrcode.DefaultQROptions()).Return([]byte("<svg></svg>"), nil)
+
+			req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode"+tt.query, nil), "abc123")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GenerateQRCode(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedDisposition, w.Header().Get("Content-Disposition"))
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		})
+	}
+}
+
 func TestGenerateQRCode_ShortCodeNotFound(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
 	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "nonexistent"
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).
-		Return(nil, errors.New(constant.ErrShortCodeNotFound))
-	
-	// Chi router context setup
-	req := httptest.NewRequest("GET", "/api/urls/"+shortCode+"/qrcode", nil)
+	handler := newTestHandler(db.NewMemoryRepository(), mockQRGenerator)
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/nonexistent/qrcode", nil), "nonexistent")
 	w := httptest.NewRecorder()
-	
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.GenerateQRCode(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	
-	mockService.AssertExpectations(t)
-	mockQRGenerator.AssertNotCalled(t, "GenerateQRCode")
+	mockQRGenerator.AssertNotCalled(t, "GenerateQRCode", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGenerateQRCode_Lifecycle(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	maxVisits := uint(1)
+
+	tests := []struct {
+		name         string
+		url          *shortener.URL
+		expectedCode int
+	}{
+		{name: "Scheduled", url: &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", ActiveFrom: &future}, expectedCode: http.StatusNotFound},
+		{name: "Expired", url: &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", ExpiresAt: &past}, expectedCode: http.StatusGone},
+		{name: "Visit limit reached", url: &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", MaxVisits: &maxVisits, Visits: 1}, expectedCode: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, tt.url)
+			mockQRGenerator := new(MockQRGenerator)
+			handler := newTestHandler(repo, mockQRGenerator)
+
+			req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil), "abc123")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GenerateQRCode(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+			mockQRGenerator.AssertNotCalled(t, "GenerateQRCode", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGenerateQRCode_DoesNotCountVisit(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	maxVisits := uint(1)
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", MaxVisits: &maxVisits})
+	mockQRGenerator := new(MockQRGenerator)
+	handler := newTestHandler(repo, mockQRGenerator)
+	mockQRGenerator.On("GenerateQRCode", "abc123", 256, qrcode.DefaultQROptions()).Return([]byte("fake-qr-code-data"), nil)
+
+	// Act
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler.GenerateQRCode(w, withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil), "abc123"))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	url, err := handler.service.LookupURL(context.Background(), "abc123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), url.Visits)
 }
 
 func TestGenerateQRCode_ServiceError(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
+	repo := failingRepository{MemoryRepository: db.NewMemoryRepository(), err: errors.New("database is down")}
 	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "abc123"
-	expectedError := errors.New("service error")
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).
-		Return(nil, expectedError)
-	
-	// Chi router context setup
-	req := httptest.NewRequest("GET", "/api/urls/"+shortCode+"/qrcode", nil)
+	handler := newTestHandler(repo, mockQRGenerator)
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil), "abc123")
 	w := httptest.NewRecorder()
-	
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.GenerateQRCode(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	
-	mockService.AssertExpectations(t)
-	mockQRGenerator.AssertNotCalled(t, "GenerateQRCode")
+	mockQRGenerator.AssertNotCalled(t, "GenerateQRCode", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestGenerateQRCode_QRGenerationError(t *testing.T) {
 	// Arrange
-	mockService := new(MockService)
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
 	mockQRGenerator := new(MockQRGenerator)
-	baseURL := "http://localhost:8080"
-	handler := NewHandler(mockService, mockQRGenerator, baseURL)
-	
-	shortCode := "abc123"
-	qrError := errors.New("qr generation error")
-	mockURL := &shortener.URL{
-		ID:        1,
-		LongURL:   "https://example.com",
-		ShortCode: shortCode,
-		CreatedAt: time.Now(),
-		Visits:    5,
-	}
-	
-	mockService.On("GetLongURL", mock.Anything, shortCode).Return(mockURL, nil)
-	mockQRGenerator.On("GenerateQRCode", shortCode, 256).Return(nil, qrError)
-	
-	// Chi router context setup
-	req := httptest.NewRequest("GET", "/api/urls/"+shortCode+"/qrcode", nil)
+	handler := newTestHandler(repo, mockQRGenerator)
+
+	mockQRGenerator.On("GenerateQRCode", "abc123", 256, qrcode.DefaultQROptions()).Return(nil, errors.New("qr generation error"))
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil), "abc123")
 	w := httptest.NewRecorder()
-	
-	chiCtx := chi.NewRouteContext()
-	chiCtx.URLParams.Add("shortCode", shortCode)
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
-	
+
 	// Act
 	handler.GenerateQRCode(w, req)
-	
+
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	
-	mockService.AssertExpectations(t)
 	mockQRGenerator.AssertExpectations(t)
-} 
\ No newline at end of file
+}
+
+func TestGenerateQRCode_Sizes(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		expectedSize int
+	}{
+		{name: "Default", query: "", expectedSize: 256},
+		{name: "Size 128", query: "?size=128", expectedSize: 128},
+		{name: "Size 256", query: "?size=256", expectedSize: 256},
+		{name: "Size 512", query: "?size=512", expectedSize: 512},
+		{name: "Size 1024", query: "?size=1024", expectedSize: 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+			mockQRGenerator := new(MockQRGenerator)
+			handler := newTestHandler(repo, mockQRGenerator)
+
+			mockQRGenerator.On("GenerateQRCode", "abc123", tt.expectedSize, qrcode.DefaultQROptions()).Return([]byte("fake-qr-code-data"), nil)
+
+			req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode"+tt.query, nil), "abc123")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GenerateQRCode(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, w.Code)
+			mockQRGenerator.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGenerateQRCode_InvalidSize(t *testing.T) {
+	// Arrange
+	mockQRGenerator := new(MockQRGenerator)
+	handler := newTestHandler(db.NewMemoryRepository(), mockQRGenerator)
+
+	req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode?size=300", nil), "abc123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GenerateQRCode(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid size, allowed: 128, 256, 512, 1024", response.Error)
+
+	mockQRGenerator.AssertNotCalled(t, "GenerateQRCode", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGenerateQRCode_SVG(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+	}{
+		{name: "Format query parameter", query: "?format=svg"},
+		{name: "Accept header", accept: "image/svg+xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+			mockQRGenerator := new(MockQRGenerator)
+			handler := newTestHandler(repo, mockQRGenerator)
+
+			mockSVG := []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+			mockQRGenerator.On("GenerateQRCodeSVG", "abc123", 256, qrcode.DefaultQROptions()).Return(mockSVG, nil)
+
+			req := httptest.NewRequest("GET", "/api/urls/abc123/qrcode"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			req = withShortCode(req, "abc123")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GenerateQRCode(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+			assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("<svg")))
+			mockQRGenerator.AssertNotCalled(t, "GenerateQRCode", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGenerateQRCode_Options(t *testing.T) {
+	tests := []struct {
+		name              string
+		query             string
+		expectedOpts      qrcode.QROptions
+		expectedStatus    int
+		expectedErrorCode string
+	}{
+		{
+			name:  "Colors and error correction",
+			query: "?fg=1A237E&bg=ffeb3b&ec=h",
+			expectedOpts: qrcode.QROptions{
+				FGColor: color.RGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff},
+				BGColor: color.RGBA{R: 0xff, G: 0xeb, B: 0x3b, A: 0xff},
+				ECLevel: qrcode.ECHigh,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "Defaults", query: "", expectedOpts: qrcode.DefaultQROptions(), expectedStatus: http.StatusOK},
+		{name: "Short color", query: "?fg=fff", expectedStatus: http.StatusBadRequest, expectedErrorCode: "invalid_qr_color"},
+		{name: "Non-hex color", query: "?bg=zzzzzz", expectedStatus: http.StatusBadRequest, expectedErrorCode: "invalid_qr_color"},
+		{name: "Unknown level", query: "?ec=X", expectedStatus: http.StatusBadRequest, expectedErrorCode: "invalid_qr_ec_level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+			mockQRGenerator := new(MockQRGenerator)
+			handler := newTestHandler(repo, mockQRGenerator)
+
+			if tt.expectedStatus == http.StatusOK {
+				mockQRGenerator.On("GenerateQRCode", "abc123", 256, tt.expectedOpts).Return([]byte("fake-qr-code-data"), nil)
+			}
+
+			req := withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode"+tt.query, nil), "abc123")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GenerateQRCode(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedErrorCode != "" {
+				var response ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedErrorCode, response.ErrorCode)
+			}
+			mockQRGenerator.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGenerateQRCode_Cached(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+	mockQRGenerator := new(MockQRGenerator)
+	service := shortener.NewService(repo, cache.NewNamespaceLRU(100), shortener.ServiceOptions{})
+	handler := NewHandler(service, mockQRGenerator, "http://localhost:8080")
+
+	mockQRData := []byte("fake-qr-code-data")
+	mockQRGenerator.On("GenerateQRCode", "abc123", 256, qrcode.DefaultQROptions()).Return(mockQRData, nil).Once()
+
+	// Act
+	responses := make([]*httptest.ResponseRecorder, 2)
+	for i := range responses {
+		responses[i] = httptest.NewRecorder()
+		handler.GenerateQRCode(responses[i], withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil), "abc123"))
+	}
+
+	// Assert
+	for _, w := range responses {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, mockQRData, w.Body.Bytes())
+	}
+	mockQRGenerator.AssertNumberOfCalls(t, "GenerateQRCode", 1)
+}
+
+func TestExportURLs_CSV(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/1", ShortCode: "abc123", Visits: 3})
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/2", ShortCode: "def456"})
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	req := httptest.NewRequest("GET", "/api/export?format=csv", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ExportURLs(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="urls_`)
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{"id", "short_code", "long_url", "created_at", "visits", "expires_at"}, records[0])
+	assert.Equal(t, "abc123", records[1][1])
+}
+
+func TestExportURLs_JSON(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/1", ShortCode: "abc123"})
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/2", ShortCode: "def456"})
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	req := httptest.NewRequest("GET", "/api/export?format=json", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ExportURLs(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var exported []shortener.URL
+	err := json.Unmarshal(w.Body.Bytes(), &exported)
+	assert.NoError(t, err)
+	assert.Len(t, exported, 2)
+}
+
+// seedClicks stores count clicks on shortCode, one minute apart and oldest first
+func seedClicks(t *testing.T, repo shortener.Repository, shortCode string, count int) time.Time {
+	start := time.Now().UTC().Truncate(time.Second).Add(-time.Duration(count) * time.Minute)
+	for i := 0; i < count; i++ {
+		assert.NoError(t, repo.RecordClick(context.Background(), shortener.ClickEvent{
+			ShortCode: shortCode,
+			ClickedAt: start.Add(time.Duration(i) * time.Minute),
+			Referer:   fmt.Sprintf("https://ref%d.example.com", i%3),
+			IPHash:    shortener.HashIP(fmt.Sprintf("203.0.113.%d", i)),
+			UserAgent: fmt.Sprintf("agent/%d", i),
+		}))
+	}
+	return start
+}
+
+func TestExportClicks(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", OwnerID: 7})
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/quiet", ShortCode: "quiet"})
+	start := seedClicks(t, repo, "abc123", 100)
+	handler := newTestHandler(repo, nil)
+
+	t.Run("CSV", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ExportClicks(w, withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/clicks/export?format=csv", nil), "abc123"))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="clicks_abc123_`)
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		if assert.Len(t, records, 101) {
+			assert.Equal(t, []string{"clicked_at", "referer", "ip_hash", "user_agent"}, records[0])
+			assert.Equal(t, []string{start.Format(time.RFC3339), "https://ref0.example.com", shortener.HashIP("203.0.113.0"), "agent/0"}, records[1])
+			assert.Equal(t, "agent/99", records[100][3])
+			for _, record := range records[1:] {
+				assert.Len(t, record[2], 64, "IPs are exported as SHA-256 hashes")
+				assert.NotContains(t, record[2], "203.0.113.")
+			}
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ExportClicks(w, withShortCode(httptest.NewRequest("GET", "/api/urls/abc123/clicks/export?format=json", nil), "abc123"))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="clicks_abc123_`)
+
+		var exported []shortener.ClickEvent
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &exported))
+		if assert.Len(t, exported, 100) {
+			assert.True(t, exported[0].ClickedAt.Equal(start))
+			assert.Equal(t, shortener.HashIP("203.0.113.0"), exported[0].IPHash)
+			assert.Equal(t, "agent/99", exported[99].UserAgent)
+		}
+	})
+
+	tests := []struct {
+		name           string
+		target         string
+		shortCode      string
+		user           *shortener.User
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "No clicks", target: "/api/urls/quiet/clicks/export?format=json", shortCode: "quiet", expectedStatus: http.StatusOK, expectedBody: "[]"},
+		{name: "Not found", target: "/api/urls/missing/clicks/export", shortCode: "missing", expectedStatus: http.StatusNotFound},
+		{name: "Invalid format", target: "/api/urls/abc123/clicks/export?format=xml", shortCode: "abc123", expectedStatus: http.StatusBadRequest},
+		{name: "Other user", target: "/api/urls/abc123/clicks/export", shortCode: "abc123", user: &shortener.User{ID: 8, Role: shortener.RoleUser}, expectedStatus: http.StatusForbidden},
+		{name: "Owner", target: "/api/urls/abc123/clicks/export", shortCode: "abc123", user: &shortener.User{ID: 7, Role: shortener.RoleUser}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withShortCode(httptest.NewRequest("GET", tt.target, nil), tt.shortCode)
+			if tt.user != nil {
+				req = req.WithContext(shortener.WithUser(req.Context(), tt.user))
+			}
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.ExportClicks(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetDeviceStats(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+	handler := newTestHandler(repo, nil)
+	userAgents := []string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+	}
+	for _, ua := range userAgents {
+		req := withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123")
+		req.Header.Set("User-Agent", ua)
+		handler.RedirectToLongURL(httptest.NewRecorder(), req)
+	}
+	// Clicks are recorded off the redirect path
+	assert.Eventually(t, func() bool {
+		clicks, _ := repo.FindAllClicks(context.Background(), "abc123")
+		return len(clicks) == len(userAgents)
+	}, time.Second, 10*time.Millisecond)
+
+	tests := []struct {
+		name           string
+		shortCode      string
+		expectedStatus int
+		expectedStats  shortener.DeviceStats
+	}{
+		{name: "Success", shortCode: "abc123", expectedStatus: http.StatusOK, expectedStats: shortener.DeviceStats{Mobile: 2, Desktop: 1, Tablet: 1, Bot: 1}},
+		{name: "Not found", shortCode: "missing", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GetDeviceStats(w, withShortCode(httptest.NewRequest("GET", "/api/urls/"+tt.shortCode+"/device-stats", nil), tt.shortCode))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var stats shortener.DeviceStats
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+			assert.Equal(t, tt.expectedStats, stats)
+		})
+	}
+
+	// The bot was redirected but is not counted as a visit
+	url, err := repo.FindByShortCode(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(4), url.Visits)
+}
+
+func TestRedirectToLongURL_RecordsVisitor(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+	handler := newTestHandler(repo, nil)
+
+	req := withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123")
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("User-Agent", "curl/8.0")
+
+	// Act
+	handler.RedirectToLongURL(httptest.NewRecorder(), req)
+
+	// Assert - clicks are recorded off the redirect path
+	var clicks []shortener.ClickEvent
+	assert.Eventually(t, func() bool {
+		clicks, _ = repo.FindAllClicks(context.Background(), "abc123")
+		return len(clicks) == 1
+	}, time.Second, 10*time.Millisecond)
+	if assert.Len(t, clicks, 1) {
+		assert.Equal(t, shortener.HashIP("203.0.113.7"), clicks[0].IPHash)
+		assert.Equal(t, "curl/8.0", clicks[0].UserAgent)
+	}
+}
+
+// newImportRequest builds a multipart request carrying csvData in the file field
+func newImportRequest(t *testing.T, csvData []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "urls.csv")
+	assert.NoError(t, err)
+	_, err = part.Write(csvData)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/api/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestIntegration_ExportImportRoundTrip(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	// Arrange - source service with a few URLs
+	const sourceDB, targetDB = "test_export.db", "test_import.db"
+	defer os.Remove(sourceDB)
+	defer os.Remove(targetDB)
+	ctx := context.Background()
+
+	sourceCache := cache.NewNamespaceLRU(100)
+	sourceRepo, err := db.NewSQLiteRepository(sourceDB, sourceCache)
+	assert.NoError(t, err)
+	defer sourceRepo.Close()
+	sourceService := shortener.NewService(sourceRepo, sourceCache, shortener.ServiceOptions{})
+
+	for _, shortCode := range []string{"abc123", "def456", "ghi789"} {
+		_, err := sourceService.CreateShortURL(ctx, 0, "https://example.com/"+shortCode, shortCode)
+		assert.NoError(t, err)
+	}
+
+	sourceHandler := NewHandler(sourceService, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	exportRecorder := httptest.NewRecorder()
+	sourceHandler.ExportURLs(exportRecorder, httptest.NewRequest("GET", "/api/export?format=csv", nil))
+	assert.Equal(t, http.StatusOK, exportRecorder.Code)
+
+	// Arrange - empty target service
+	targetCache := cache.NewNamespaceLRU(100)
+	targetRepo, err := db.NewSQLiteRepository(targetDB, targetCache)
+	assert.NoError(t, err)
+	defer targetRepo.Close()
+	targetService := shortener.NewService(targetRepo, targetCache, shortener.ServiceOptions{})
+	targetHandler := NewHandler(targetService, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+
+	// Act
+	w := httptest.NewRecorder()
+	targetHandler.ImportURLs(w, newImportRequest(t, exportRecorder.Body.Bytes()))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response ImportURLsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, response.Imported)
+	assert.Empty(t, response.Errors)
+
+	url, err := targetRepo.FindByShortCode(ctx, "def456")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/def456", url.LongURL)
+}
+
+func TestImportURLs_RowErrors(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	handler := newTestHandler(repo, new(MockQRGenerator))
+
+	csvData := []byte("short_code,long_url\nabc123,https://example.com/1\nabc123,https://example.com/2\nxyz,\n")
+
+	// Act
+	w := httptest.NewRecorder()
+	handler.ImportURLs(w, newImportRequest(t, csvData))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response ImportURLsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, response.Imported)
+	assert.Equal(t, []ImportRowError{
+		{Row: 3, Reason: constant.ErrShortCodeExists},
+		{Row: 4, Reason: constant.ErrEmptyLongURL},
+	}, response.Errors)
+
+	stored, err := repo.FindByShortCode(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", stored.LongURL)
+}
+
+func TestIntegration_ProtectedURL(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	// Arrange
+	const testDB = "test_protected.db"
+	defer os.Remove(testDB)
+	ctx := context.Background()
+
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(testDB, lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+
+	_, err = service.CreateShortURLWithParams(ctx, "https://example.com/private", "secret", shortener.CreateURLParams{Password: "s3cret"})
+	assert.NoError(t, err)
+
+	newRequest := func(method, target string, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("shortCode", "secret")
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	}
+
+	// Act
+	redirect := httptest.NewRecorder()
+	handler.RedirectToLongURL(redirect, newRequest("GET", "/secret", ""))
+	form := httptest.NewRecorder()
+	handler.ProtectedURL(form, newRequest("GET", "/p/secret", ""))
+	wrong := httptest.NewRecorder()
+	handler.ProtectedURL(wrong, newRequest("POST", "/p/secret", "password=guess"))
+	correct := httptest.NewRecorder()
+	handler.ProtectedURL(correct, newRequest("POST", "/p/secret", "password=s3cret"))
+
+	// Assert
+	assert.Equal(t, http.StatusFound, redirect.Code)
+	assert.Equal(t, "/p/secret", redirect.Header().Get("Location"))
+	assert.Equal(t, http.StatusOK, form.Code)
+	assert.Contains(t, form.Body.String(), `name="password"`)
+	assert.Equal(t, http.StatusUnauthorized, wrong.Code)
+	assert.Contains(t, wrong.Body.String(), "Invalid password")
+	assert.Equal(t, http.StatusFound, correct.Code)
+	assert.Equal(t, "https://example.com/private", correct.Header().Get("Location"))
+}
+
+func TestIntegration_PreviewURL(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	// Arrange
+	const testDB = "test_preview.db"
+	defer os.Remove(testDB)
+	ctx := context.Background()
+
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(testDB, lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+
+	_, err = service.CreateShortURL(ctx, 0, "https://example.com/destination", "abc123")
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/preview/abc123", nil)
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("shortCode", "abc123")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.PreviewURL(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "https://example.com/destination")
+	assert.Contains(t, w.Body.String(), `action="/abc123"`)
+	url, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), url.Visits)
+}
+
+func TestRedirectToLongURL_RedirectCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		redirectCode int
+		expectedCode int
+	}{
+		{name: "Unset", redirectCode: 0, expectedCode: http.StatusFound},
+		{name: "MovedPermanently", redirectCode: http.StatusMovedPermanently, expectedCode: http.StatusMovedPermanently},
+		{name: "TemporaryRedirect", redirectCode: http.StatusTemporaryRedirect, expectedCode: http.StatusTemporaryRedirect},
+		{name: "PermanentRedirect", redirectCode: http.StatusPermanentRedirect, expectedCode: http.StatusPermanentRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, &shortener.URL{ShortCode: "abc123", LongURL: "https://example.com", RedirectCode: tt.redirectCode})
+			handler := newTestHandler(repo, nil)
+
+			req := httptest.NewRequest("GET", "/abc123", nil)
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("shortCode", "abc123")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.RedirectToLongURL(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectToLongURL_UTMParameters(t *testing.T) {
+	tests := []struct {
+		name             string
+		longURL          string
+		expectedLocation string
+	}{
+		{name: "Without query", longURL: "https://example.com/launch", expectedLocation: "https://example.com/launch?utm_campaign=spring&utm_source=newsletter"},
+		{name: "With query", longURL: "https://example.com/launch?ref=blog", expectedLocation: "https://example.com/launch?ref=blog&utm_campaign=spring&utm_source=newsletter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, &shortener.URL{ShortCode: "abc123", LongURL: tt.longURL, UTMSource: "newsletter", UTMCampaign: "spring"})
+			handler := newTestHandler(repo, nil)
+
+			req := withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.RedirectToLongURL(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	defer appLogger.SetLevel("info")
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedLevel  string
+	}{
+		{name: "Debug", body: `{"level":"debug"}`, expectedStatus: http.StatusOK, expectedLevel: "debug"},
+		{name: "Warn", body: `{"level":"warn"}`, expectedStatus: http.StatusOK, expectedLevel: "warn"},
+		{name: "Error uppercase", body: `{"level":"ERROR"}`, expectedStatus: http.StatusOK, expectedLevel: "error"},
+		{name: "Info", body: `{"level":"info"}`, expectedStatus: http.StatusOK, expectedLevel: "info"},
+		{name: "Invalid level", body: `{"level":"verbose"}`, expectedStatus: http.StatusBadRequest, expectedLevel: "info"},
+		{name: "Fatal not allowed", body: `{"level":"fatal"}`, expectedStatus: http.StatusBadRequest, expectedLevel: "info"},
+		{name: "Invalid body", body: `{level`, expectedStatus: http.StatusBadRequest, expectedLevel: "info"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			handler := &Handler{}
+			req := httptest.NewRequest("PUT", "/api/admin/log-level", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.SetLogLevel(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLevel, appLogger.Level.String())
+			if tt.expectedStatus == http.StatusOK {
+				var resp LogLevelResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedLevel, resp.Level)
+			}
+		})
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(1)
+	lru.Set(constant.ShortURLNamespace, "a", "value")
+	lru.Get(constant.ShortURLNamespace, "a")
+	lru.Get(constant.ShortURLNamespace, "missing")
+	lru.Set(constant.ShortURLNamespace, "b", "value")
+	handler := NewHandler(shortener.NewService(nil, lru, shortener.ServiceOptions{}), nil, "http://localhost:8080")
+	req := httptest.NewRequest("GET", "/api/admin/cache/stats", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CacheStats(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats cache.CacheStats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, cache.CacheStats{Hits: 1, Misses: 1, Evictions: 1, Size: 1, Namespaces: map[string]int{constant.ShortURLNamespace: 1}}, stats)
+	assert.Contains(t, w.Body.String(), `"oldest_entry_age_seconds":0`)
+}
+
+func TestIntegration_UserAccounts(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_users.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	do := func(method, target, body, username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	createAlice := do("POST", "/api/users", `{"username":"alice","password":"alice-pass"}`, "shorter-admin", "change-me-please")
+	createBob := do("POST", "/api/users", `{"username":"bob","password":"bob-pass","role":"user"}`, "shorter-admin", "change-me-please")
+	duplicate := do("POST", "/api/users", `{"username":"alice","password":"other"}`, "shorter-admin", "change-me-please")
+	listAsAdmin := do("GET", "/api/users", "", "shorter-admin", "change-me-please")
+	listAsAlice := do("GET", "/api/users", "", "alice", "alice-pass")
+	wrongPassword := do("POST", "/api/urls", `{"long_url":"https://example.com"}`, "alice", "guess")
+	createURL := do("POST", "/api/urls", `{"long_url":"https://example.com","custom_short_url":"alices"}`, "alice", "alice-pass")
+	updateAsBob := do("PUT", "/api/urls/alices", `{"long_url":"https://example.com/bob"}`, "bob", "bob-pass")
+	updateAsAlice := do("PUT", "/api/urls/alices", `{"long_url":"https://example.com/alice"}`, "alice", "alice-pass")
+	updateAsAdmin := do("PUT", "/api/urls/alices", `{"long_url":"https://example.com/admin"}`, "shorter-admin", "change-me-please")
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, createAlice.Code)
+	assert.NotContains(t, createAlice.Body.String(), "alice-pass")
+	assert.Equal(t, http.StatusCreated, createBob.Code)
+	assert.Equal(t, http.StatusConflict, duplicate.Code)
+	assert.Equal(t, http.StatusOK, listAsAdmin.Code)
+	var users []shortener.User
+	assert.NoError(t, json.Unmarshal(listAsAdmin.Body.Bytes(), &users))
+	assert.Len(t, users, 2)
+	assert.Equal(t, http.StatusForbidden, listAsAlice.Code)
+	assert.Equal(t, http.StatusUnauthorized, wrongPassword.Code)
+	assert.Equal(t, http.StatusCreated, createURL.Code)
+	assert.Equal(t, http.StatusForbidden, updateAsBob.Code)
+	assert.Equal(t, http.StatusOK, updateAsAlice.Code)
+	assert.Equal(t, http.StatusOK, updateAsAdmin.Code)
+
+	url, err := service.LookupURL(context.Background(), "alices")
+	assert.NoError(t, err)
+	assert.Equal(t, users[0].ID, url.OwnerID)
+}
+
+func TestRoleMiddleware(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	_, err := service.CreateUser(context.Background(), "alice", "alice-pass", shortener.RoleUser)
+	assert.NoError(t, err)
+	router := NewRouter(NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080"),
+		config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	type credentials struct{ username, password string }
+	admin := &credentials{"shorter-admin", "change-me-please"}
+	user := &credentials{"alice", "alice-pass"}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		creds  *credentials
+		denied int
+	}{
+		{name: "List users as admin", method: "GET", target: "/api/users", creds: admin},
+		{name: "List users as user", method: "GET", target: "/api/users", creds: user, denied: http.StatusForbidden},
+		{name: "List users unauthenticated", method: "GET", target: "/api/users", denied: http.StatusUnauthorized},
+		{name: "Create user as admin", method: "POST", target: "/api/users", body: `{"username":"bob","password":"bob-pass"}`, creds: admin},
+		{name: "Create user as user", method: "POST", target: "/api/users", body: `{"username":"carol","password":"carol-pass"}`, creds: user, denied: http.StatusForbidden},
+		{name: "Create user unauthenticated", method: "POST", target: "/api/users", body: `{"username":"dave","password":"dave-pass"}`, denied: http.StatusUnauthorized},
+		{name: "Create URL as admin", method: "POST", target: "/api/urls", body: `{"long_url":"https://example.com/admin"}`, creds: admin},
+		{name: "Create URL as user", method: "POST", target: "/api/urls", body: `{"long_url":"https://example.com/user"}`, creds: user},
+		{name: "Create URL unauthenticated", method: "POST", target: "/api/urls", body: `{"long_url":"https://example.com/anon"}`, denied: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.creds != nil {
+				req.SetBasicAuth(tt.creds.username, tt.creds.password)
+			}
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			if tt.denied != 0 {
+				assert.Equal(t, tt.denied, w.Code)
+			} else {
+				assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, w.Code)
+				assert.Less(t, w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestRouter_APIVersioning(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	router := NewRouter(NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080"),
+		config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	tests := []struct {
+		name       string
+		target     string
+		deprecated bool
+	}{
+		{name: "Legacy", target: "/api/urls", deprecated: true},
+		{name: "Versioned", target: "/api/v1/urls", deprecated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.target, strings.NewReader(`{"long_url":"https://example.com/`+tt.name+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetBasicAuth("shorter-admin", "change-me-please")
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusCreated, w.Code)
+			if tt.deprecated {
+				assert.Equal(t, "true", w.Header().Get("Deprecation"))
+				assert.NotEmpty(t, w.Header().Get("Sunset"))
+			} else {
+				assert.Empty(t, w.Header().Get("Deprecation"))
+				assert.Empty(t, w.Header().Get("Sunset"))
+			}
+		})
+	}
+}
+
+func TestSearchURLs(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	for _, code := range []string{"one", "two"} {
+		_, err := service.CreateShortURL(context.Background(), 0, "https://example.com/"+code, code)
+		assert.NoError(t, err)
+	}
+	_, err := service.CreateShortURL(context.Background(), 0, "https://other.org", "three")
+	assert.NoError(t, err)
+	handler := NewHandler(service, nil, "http://localhost:8080")
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedTotal  int
+		expectedLen    int
+	}{
+		{name: "All", target: "/api/v1/urls", expectedStatus: http.StatusOK, expectedTotal: 3, expectedLen: 3},
+		{name: "Filtered", target: "/api/v1/urls?q=example", expectedStatus: http.StatusOK, expectedTotal: 2, expectedLen: 2},
+		{name: "Paged", target: "/api/v1/urls?q=example&limit=1&offset=1", expectedStatus: http.StatusOK, expectedTotal: 2, expectedLen: 1},
+		{name: "Invalid limit", target: "/api/v1/urls?limit=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Limit too large", target: "/api/v1/urls?limit=1000", expectedStatus: http.StatusBadRequest},
+		{name: "Negative offset", target: "/api/v1/urls?offset=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.SearchURLs(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var resp SearchURLsResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedTotal, resp.Total)
+				assert.Len(t, resp.URLs, tt.expectedLen)
+			}
+		})
+	}
+}
+
+func TestListRecentURLs(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	before := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	for _, code := range []string{"one", "two"} {
+		_, err := service.CreateShortURL(context.Background(), 0, "https://example.com/"+code, code)
+		assert.NoError(t, err)
+	}
+	after := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	handler := NewHandler(service, nil, "http://localhost:8080")
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedCodes  []string
+	}{
+		{name: "Default window", target: "/api/v1/urls/recent", expectedStatus: http.StatusOK, expectedCodes: []string{"two", "one"}},
+		{name: "Since before", target: "/api/v1/urls/recent?since=" + before, expectedStatus: http.StatusOK, expectedCodes: []string{"two", "one"}},
+		{name: "Since after", target: "/api/v1/urls/recent?since=" + after, expectedStatus: http.StatusOK, expectedCodes: []string{}},
+		{name: "Paged", target: "/api/v1/urls/recent?since=" + before + "&limit=1&offset=1", expectedStatus: http.StatusOK, expectedCodes: []string{"one"}},
+		{name: "Invalid since", target: "/api/v1/urls/recent?since=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "Limit too large", target: "/api/v1/urls/recent?limit=1000", expectedStatus: http.StatusBadRequest},
+		{name: "Negative offset", target: "/api/v1/urls/recent?offset=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.ListRecentURLs(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var resp RecentURLsResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				codes := []string{}
+				for _, url := range resp.URLs {
+					codes = append(codes, url.ShortCode)
+				}
+				assert.Equal(t, tt.expectedCodes, codes)
+			}
+		})
+	}
+}
+
+func TestIntegration_URLTags(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_tags.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	_, err = service.CreateUser(context.Background(), "alice", "alice-pass", shortener.RoleUser)
+	assert.NoError(t, err)
+	_, err = service.CreateUser(context.Background(), "bob", "bob-pass", shortener.RoleUser)
+	assert.NoError(t, err)
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	do := func(method, target, body, username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth(username, password)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	do("POST", "/api/v1/urls", `{"long_url":"https://example.com/a","custom_short_url":"alicea"}`, "alice", "alice-pass")
+	do("POST", "/api/v1/urls", `{"long_url":"https://example.com/b","custom_short_url":"aliceb"}`, "alice", "alice-pass")
+	addTag := do("POST", "/api/v1/urls/alicea/tags", `{"tag":"Spring-Sale"}`, "alice", "alice-pass")
+	addSecond := do("POST", "/api/v1/urls/alicea/tags", `{"tag":"blog"}`, "alice", "alice-pass")
+	invalidTag := do("POST", "/api/v1/urls/alicea/tags", `{"tag":"  "}`, "alice", "alice-pass")
+	unknownURL := do("POST", "/api/v1/urls/missing/tags", `{"tag":"blog"}`, "alice", "alice-pass")
+	addAsBob := do("POST", "/api/v1/urls/alicea/tags", `{"tag":"bob"}`, "bob", "bob-pass")
+	filtered := do("GET", "/api/v1/urls?tag=spring-sale", "", "shorter-admin", "change-me-please")
+	removeTag := do("DELETE", "/api/v1/urls/alicea/tags/blog", "", "alice", "alice-pass")
+	removeAgain := do("DELETE", "/api/v1/urls/alicea/tags/blog", "", "alice", "alice-pass")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, addTag.Code)
+	var tagged ShortURLResponse
+	assert.NoError(t, json.Unmarshal(addSecond.Body.Bytes(), &tagged))
+	assert.Equal(t, []string{"blog", "spring-sale"}, tagged.Tags)
+	assert.Equal(t, http.StatusBadRequest, invalidTag.Code)
+	assert.Equal(t, http.StatusNotFound, unknownURL.Code)
+	assert.Equal(t, http.StatusForbidden, addAsBob.Code)
+	assert.Equal(t, http.StatusOK, filtered.Code)
+	var search SearchURLsResponse
+	assert.NoError(t, json.Unmarshal(filtered.Body.Bytes(), &search))
+	assert.Equal(t, 1, search.Total)
+	if assert.Len(t, search.URLs, 1) {
+		assert.Equal(t, "alicea", search.URLs[0].ShortCode)
+	}
+	assert.Equal(t, http.StatusOK, removeTag.Code)
+	var untagged ShortURLResponse
+	assert.NoError(t, json.Unmarshal(removeTag.Body.Bytes(), &untagged))
+	assert.Equal(t, []string{"spring-sale"}, untagged.Tags)
+	assert.Equal(t, http.StatusNotFound, removeAgain.Code)
+}
+
+func TestBulkDeleteURLs(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	for _, code := range []string{"one", "two"} {
+		_, err := service.CreateShortURL(context.Background(), 0, "https://example.com/"+code, code)
+		assert.NoError(t, err)
+	}
+	handler := NewHandler(service, nil, "http://localhost:8080")
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Mixed found and not found", body: `{"short_codes":["one","missing","two"]}`, expectedStatus: http.StatusOK, expectedBody: `{"deleted":2,"not_found":["missing"]}`},
+		{name: "Already deleted", body: `{"short_codes":["one"]}`, expectedStatus: http.StatusOK, expectedBody: `{"deleted":0,"not_found":["one"]}`},
+		{name: "Empty list", body: `{"short_codes":[]}`, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid JSON", body: `{`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.BulkDeleteURLs(w, httptest.NewRequest("POST", "/api/v1/urls/bulk-delete", strings.NewReader(tt.body)))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIntegration_AuditLog(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_audit.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	bus := events.NewEventBus()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{Events: bus})
+	_, err = service.CreateUser(context.Background(), "alice", "alice-pass", shortener.RoleUser)
+	assert.NoError(t, err)
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	do := func(method, target, body, username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth(username, password)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	do("POST", "/api/v1/urls", `{"long_url":"https://example.com/a","custom_short_url":"audited"}`, "alice", "alice-pass")
+	do("PUT", "/api/v1/urls/audited", `{"long_url":"https://example.com/b"}`, "alice", "alice-pass")
+	// Audit entries are appended by event handlers, so wait for them before reading the log
+	assert.NoError(t, bus.Shutdown(context.Background()))
+	asUser := do("GET", "/api/v1/urls/audited/audit", "", "alice", "alice-pass")
+	asAdmin := do("GET", "/api/v1/urls/audited/audit", "", "shorter-admin", "change-me-please")
+	unknown := do("GET", "/api/v1/urls/missing/audit", "", "shorter-admin", "change-me-please")
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, asUser.Code)
+	assert.Equal(t, http.StatusOK, asAdmin.Code)
+	var entries []shortener.AuditEntry
+	assert.NoError(t, json.Unmarshal(asAdmin.Body.Bytes(), &entries))
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, shortener.AuditActionCreate, entries[0].Action)
+		assert.Equal(t, "alice", entries[0].ActorUsername)
+		assert.Equal(t, shortener.AuditActionUpdate, entries[1].Action)
+		assert.Contains(t, entries[1].Before, "https://example.com/a")
+		assert.Contains(t, entries[1].After, "https://example.com/b")
+	}
+	assert.Equal(t, http.StatusOK, unknown.Code)
+	assert.JSONEq(t, `[]`, unknown.Body.String())
+}
+
+func TestIntegration_RenameShortCode(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_rename.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("shorter-admin", "change-me-please")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	do("POST", "/api/v1/urls", `{"long_url":"https://example.com/a","custom_short_url":"typo"}`)
+	do("POST", "/api/v1/urls", `{"long_url":"https://example.com/b","custom_short_url":"taken"}`)
+
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Renamed", target: "/api/v1/urls/typo/rename", body: `{"new_short_code":"fixed"}`, expectedStatus: http.StatusOK},
+		{name: "Old code gone", target: "/api/v1/urls/typo/rename", body: `{"new_short_code":"other"}`, expectedStatus: http.StatusNotFound},
+		{name: "New code taken", target: "/api/v1/urls/fixed/rename", body: `{"new_short_code":"taken"}`, expectedStatus: http.StatusConflict},
+		{name: "Reserved code", target: "/api/v1/urls/fixed/rename", body: `{"new_short_code":"api"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Empty code", target: "/api/v1/urls/fixed/rename", body: `{"new_short_code":""}`, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid JSON", target: "/api/v1/urls/fixed/rename", body: `{`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			w := do("PUT", tt.target, tt.body)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+
+	redirect := httptest.NewRecorder()
+	router.ServeHTTP(redirect, httptest.NewRequest("GET", "/fixed", nil))
+	assert.Equal(t, "https://example.com/a", redirect.Header().Get("Location"))
+}
+
+func TestNewHandler_BaseURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		expected    string
+		expectPanic bool
+	}{
+		{name: "Plain", baseURL: "https://sho.rt", expected: "https://sho.rt"},
+		{name: "Trailing slash", baseURL: "https://sho.rt/", expected: "https://sho.rt"},
+		{name: "Empty", baseURL: "", expectPanic: true},
+		{name: "Only slash", baseURL: "/", expectPanic: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expectPanic {
+				assert.Panics(t, func() { NewHandler(nil, nil, tt.baseURL) })
+				return
+			}
+
+			// Act
+			handler := NewHandler(nil, nil, tt.baseURL)
+
+			// Assert
+			assert.Equal(t, tt.expected, handler.baseURL)
+			assert.Equal(t, tt.expected+"/abc123", handler.fullURL("abc123"))
+		})
+	}
+}
+
+func TestIntegration_FullURL(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_full_url.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	handler := NewHandler(service, qrcode.NewGenerator("https://sho.rt"), "https://sho.rt/")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("shorter-admin", "change-me-please")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Act
+	created := do("POST", "/api/v1/urls", `{"long_url":"https://example.com","custom_short_url":"abc123"}`)
+	updated := do("PUT", "/api/v1/urls/abc123", `{"long_url":"https://example.com/new"}`)
+	stats := do("GET", "/api/v1/urls/abc123/stats", "")
+
+	// Assert
+	var createResp, updateResp ShortURLResponse
+	assert.NoError(t, json.Unmarshal(created.Body.Bytes(), &createResp))
+	assert.Equal(t, "https://sho.rt/abc123", createResp.FullUrl)
+	assert.NoError(t, json.Unmarshal(updated.Body.Bytes(), &updateResp))
+	assert.Equal(t, "https://sho.rt/abc123", updateResp.FullUrl)
+	var statsResp URLStatsResponse
+	assert.NoError(t, json.Unmarshal(stats.Body.Bytes(), &statsResp))
+	assert.Equal(t, "https://sho.rt/abc123", statsResp.FullUrl)
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		closeDB        bool
+		expectedStatus int
+		expectedState  string
+		expectedDB     string
+	}{
+		{name: "Healthy", expectedStatus: http.StatusOK, expectedState: "ok", expectedDB: "ok"},
+		{name: "Database closed", closeDB: true, expectedStatus: http.StatusServiceUnavailable, expectedState: "degraded", expectedDB: "degraded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			lru := cache.NewNamespaceLRU(100)
+			repo := db.NewMemoryRepository()
+			defer repo.Close()
+			if tt.closeDB {
+				assert.NoError(t, repo.Close())
+			}
+			handler := NewHandler(shortener.NewService(repo, lru, shortener.ServiceOptions{}), nil, "http://localhost:8080")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.Health(w, httptest.NewRequest("GET", "/health", nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			var resp HealthResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedState, resp.Status)
+			assert.Equal(t, tt.expectedDB, resp.DB)
+			assert.Equal(t, "ok", resp.Cache)
+			assert.GreaterOrEqual(t, resp.UptimeSeconds, int64(0))
+		})
+	}
+}
+
+// stubReadiness is a ReadinessChecker with a fixed answer
+type stubReadiness bool
+
+func (s stubReadiness) IsReady(ctx context.Context) bool {
+	return bool(s)
+}
+
+func TestRouter_Probes(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	handler := NewHandler(shortener.NewService(repo, lru, shortener.ServiceOptions{}), nil, "http://localhost:8080")
+
+	tests := []struct {
+		name           string
+		checker        ReadinessChecker
+		closeDB        bool
+		target         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Live", target: "/live", expectedStatus: http.StatusOK, expectedBody: `{"status":"live"}`},
+		{name: "Live while not ready", checker: stubReadiness(false), target: "/live", expectedStatus: http.StatusOK, expectedBody: `{"status":"live"}`},
+		{name: "Ready", target: "/ready", expectedStatus: http.StatusOK, expectedBody: `{"status":"ready"}`},
+		{name: "Not ready", checker: stubReadiness(false), target: "/ready", expectedStatus: http.StatusServiceUnavailable, expectedBody: `{"status":"not_ready"}`},
+		{name: "Database down", closeDB: true, target: "/ready", expectedStatus: http.StatusServiceUnavailable, expectedBody: `{"status":"not_ready"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+			if tt.checker != nil {
+				router.SetReadinessChecker(tt.checker)
+			}
+			router.SetupRoutes()
+			if tt.closeDB {
+				assert.NoError(t, repo.Close())
+			}
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestRouter_RobotsTxt(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	_, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "abc123")
+	assert.NoError(t, err)
+	router := NewRouter(NewHandler(service, nil, "http://localhost:8080"), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	// Act
+	robots := httptest.NewRecorder()
+	router.ServeHTTP(robots, httptest.NewRequest("GET", "/robots.txt", nil))
+	redirect := httptest.NewRecorder()
+	router.ServeHTTP(redirect, httptest.NewRequest("GET", "/abc123", nil))
+	health := httptest.NewRecorder()
+	router.ServeHTTP(health, httptest.NewRequest("GET", "/health", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, robots.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", robots.Header().Get("Content-Type"))
+	assert.Equal(t, "User-agent: *\nDisallow: /\nAllow: /api/\nAllow: /health\n", robots.Body.String())
+	assert.Equal(t, http.StatusFound, redirect.Code)
+	assert.Equal(t, "noindex", redirect.Header().Get("X-Robots-Tag"))
+	assert.Empty(t, health.Header().Get("X-Robots-Tag"))
+}
+
+func TestIntegration_Sparkline(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_sparkline.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	_, err = service.CreateShortURLWithParams(context.Background(), "https://example.com/spark", "spark", shortener.CreateURLParams{})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.RecordClick(context.Background(), shortener.ClickEvent{ShortCode: "spark", ClickedAt: time.Now()}))
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	// Act
+	week := get("/api/v1/urls/spark/sparkline")
+	clamped := get("/api/v1/urls/spark/sparkline?days=365")
+	invalid := get("/api/v1/urls/spark/sparkline?days=abc")
+	missing := get("/api/v1/urls/missing/sparkline")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, week.Code)
+	var resp SparklineResponse
+	assert.NoError(t, json.Unmarshal(week.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Buckets, shortener.DefaultSparklineDays) {
+		assert.Equal(t, time.Now().UTC().Format("2006-01-02"), resp.Buckets[len(resp.Buckets)-1].Date)
+		assert.Equal(t, uint(1), resp.Buckets[len(resp.Buckets)-1].Count)
+		assert.Equal(t, uint(0), resp.Buckets[0].Count)
+	}
+	assert.Equal(t, http.StatusOK, clamped.Code)
+	assert.NoError(t, json.Unmarshal(clamped.Body.Bytes(), &resp))
+	assert.Len(t, resp.Buckets, shortener.MaxSparklineDays)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
+
+func TestIntegration_IdempotencyKey(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_idempotency.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	_, err = service.CreateUser(context.Background(), "alice", "alice-pass", shortener.RoleUser)
+	assert.NoError(t, err)
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	create := func(body, key, username, password string) (int, ShortURLResponse) {
+		req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(constant.HeaderIdempotencyKey, key)
+		}
+		req.SetBasicAuth(username, password)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp ShortURLResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// Act
+	firstCode, first := create(`{"long_url":"https://example.com/a","custom_short_url":"first"}`, "retry-1", "alice", "alice-pass")
+	retryCode, retry := create(`{"long_url":"https://example.com/a","custom_short_url":"first"}`, "retry-1", "alice", "alice-pass")
+	otherUserCode, otherUser := create(`{"long_url":"https://example.com/b"}`, "retry-1", "shorter-admin", "change-me-please")
+	noKeyCode, _ := create(`{"long_url":"https://example.com/c"}`, "", "alice", "alice-pass")
+	noKeyAgainCode, _ := create(`{"long_url":"https://example.com/c"}`, "", "alice", "alice-pass")
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, firstCode)
+	assert.Equal(t, http.StatusOK, retryCode)
+	assert.Equal(t, "first", retry.ShortCode)
+	assert.Equal(t, first, retry)
+	assert.Equal(t, http.StatusCreated, otherUserCode, "keys are scoped to the authenticated user")
+	assert.NotEqual(t, first.ShortCode, otherUser.ShortCode)
+	assert.Equal(t, http.StatusCreated, noKeyCode)
+	assert.Equal(t, http.StatusCreated, noKeyAgainCode)
+}
+
+func TestWriteAPIError_Registry(t *testing.T) {
+	seen := map[string]bool{}
+	for registered, apiErr := range errorRegistry {
+		t.Run(apiErr.Code, func(t *testing.T) {
+			// Arrange
+			assert.NotEmpty(t, apiErr.Code)
+			assert.False(t, seen[apiErr.Code], "error codes are unique")
+			seen[apiErr.Code] = true
+			w := httptest.NewRecorder()
+
+			// Act
+			WriteAPIError(w, fmt.Errorf("handler context: %w", registered), http.StatusBadRequest)
+
+			// Assert
+			var response ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+			assert.Equal(t, apiErr.Code, response.ErrorCode, "wrapped errors keep their code")
+			assert.Equal(t, "handler context: "+registered.Error(), response.Error)
+			assert.Equal(t, apiErr.Details, response.Details)
+		})
+	}
+}
+
+func TestWriteAPIError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		statusCode     int
+		expectedCode   string
+		expectedDetail map[string]string
+	}{
+		{name: "Not found", err: shortener.ErrShortCodeNotFound, statusCode: http.StatusNotFound, expectedCode: "short_code_not_found"},
+		{name: "With details", err: shortener.ErrInvalidSearchLimit, statusCode: http.StatusBadRequest, expectedCode: "invalid_limit", expectedDetail: map[string]string{"min": "1", "max": "100"}},
+		{name: "Unregistered", err: errors.New("database is on fire"), statusCode: http.StatusInternalServerError, expectedCode: "internal_server_error"},
+		{name: "Plain message", err: errors.New("Invalid request format"), statusCode: http.StatusBadRequest, expectedCode: "bad_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			w := httptest.NewRecorder()
+
+			// Act
+			WriteAPIError(w, tt.err, tt.statusCode)
+
+			// Assert
+			var response ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.err.Error(), response.Error)
+			assert.Equal(t, tt.expectedCode, response.ErrorCode)
+			assert.Equal(t, tt.expectedDetail, response.Details)
+		})
+	}
+}
+
+func TestIntegration_RequestBodyLimit(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "test_body_limit.db"), lru)
+	assert.NoError(t, err)
+	defer repo.Close()
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	handler := NewHandler(service, qrcode.NewGenerator("http://localhost:8080"), "http://localhost:8080")
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please", MaxRequestBodyBytes: 1024})
+	router.SetupRoutes()
+
+	create := func(body string) (int, ErrorResponse) {
+		req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("shorter-admin", "change-me-please")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp ErrorResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// Act
+	smallCode, _ := create(`{"long_url":"https://example.com/a"}`)
+	largeCode, large := create(`{"long_url":"https://example.com/` + strings.Repeat("a", 2048) + `"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, smallCode)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, largeCode)
+	assert.Equal(t, constant.MsgRequestBodyTooLarge, large.Error)
+	assert.Equal(t, "request_entity_too_large", large.ErrorCode)
+}
+
+func TestGetTopURLs(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	for i := 0; i < shortener.MaxTopURLsLimit+5; i++ {
+		code := fmt.Sprintf("code%03d", i)
+		assert.NoError(t, repo.Store(context.Background(), &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now(), Visits: uint(i)}))
+	}
+	service := shortener.NewService(repo, lru, shortener.ServiceOptions{})
+	handler := NewHandler(service, nil, "http://localhost:8080")
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "Default limit", target: "/api/v1/analytics/top", expectedStatus: http.StatusOK, expectedCount: shortener.DefaultTopURLsLimit},
+		{name: "Custom limit", target: "/api/v1/analytics/top?limit=3", expectedStatus: http.StatusOK, expectedCount: 3},
+		{name: "Limit capped", target: "/api/v1/analytics/top?limit=1000", expectedStatus: http.StatusOK, expectedCount: shortener.MaxTopURLsLimit},
+		{name: "Zero limit", target: "/api/v1/analytics/top?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid limit", target: "/api/v1/analytics/top?limit=ten", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GetTopURLs(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var resp TopURLsResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if assert.Len(t, resp.URLs, tt.expectedCount) {
+				top := resp.URLs[0]
+				assert.Equal(t, "code104", top.ShortCode)
+				assert.Equal(t, "https://example.com/code104", top.LongURL)
+				assert.Equal(t, uint(104), top.Visits)
+				assert.Equal(t, "http://localhost:8080/code104", top.ShortURL)
+				for i := 1; i < len(resp.URLs); i++ {
+					assert.Greater(t, resp.URLs[i-1].Visits, resp.URLs[i].Visits, "most visited first")
+				}
+			}
+		})
+	}
+}
+
+func TestGetTrendingURLs(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	for i, code := range []string{"quiet", "hot", "old"} {
+		seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, Visits: uint(10 * (i + 1))})
+	}
+	clicks := map[string][]time.Duration{
+		"quiet": {time.Minute, 30 * time.Hour},
+		"hot":   {time.Minute, 30 * time.Minute, 3 * time.Hour},
+		"old":   {40 * time.Hour, 50 * time.Hour},
+	}
+	for code, ages := range clicks {
+		for _, age := range ages {
+			assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: code, ClickedAt: time.Now().Add(-age)}))
+		}
+	}
+	handler := newTestHandler(repo, nil)
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedPeriod string
+		expectedCodes  []string
+		expectedCounts []uint
+	}{
+		{name: "Default period", target: "/api/analytics/trending", expectedStatus: http.StatusOK, expectedPeriod: "24h0m0s", expectedCodes: []string{"hot", "quiet"}, expectedCounts: []uint{3, 1}},
+		{name: "Short period", target: "/api/analytics/trending?period=90m", expectedStatus: http.StatusOK, expectedPeriod: "1h30m0s", expectedCodes: []string{"hot", "quiet"}, expectedCounts: []uint{2, 1}},
+		{name: "Long period", target: "/api/analytics/trending?period=72h", expectedStatus: http.StatusOK, expectedPeriod: "72h0m0s", expectedCodes: []string{"hot", "quiet", "old"}, expectedCounts: []uint{3, 2, 2}},
+		{name: "Limit", target: "/api/analytics/trending?limit=1", expectedStatus: http.StatusOK, expectedPeriod: "24h0m0s", expectedCodes: []string{"hot"}, expectedCounts: []uint{3}},
+		{name: "Invalid period", target: "/api/analytics/trending?period=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "Period too long", target: "/api/analytics/trending?period=1000h", expectedStatus: http.StatusBadRequest},
+		{name: "Negative period", target: "/api/analytics/trending?period=-1h", expectedStatus: http.StatusBadRequest},
+		{name: "Zero limit", target: "/api/analytics/trending?limit=0", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.GetTrendingURLs(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var resp TrendingURLsResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedPeriod, resp.Period)
+			var codes []string
+			var counts []uint
+			for _, url := range resp.URLs {
+				codes = append(codes, url.ShortCode)
+				counts = append(counts, url.RecentVisits)
+			}
+			assert.Equal(t, tt.expectedCodes, codes)
+			assert.Equal(t, tt.expectedCounts, counts)
+			assert.Equal(t, "http://localhost:8080/hot", resp.URLs[0].ShortURL)
+			assert.Equal(t, uint(20), resp.URLs[0].Visits)
+		})
+	}
+}
+
+func TestGetURLStats_ETag(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	defer repo.Close()
+	assert.NoError(t, repo.Store(context.Background(), &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now(), Visits: 5}))
+	service := shortener.NewService(repo, cache.NewNoopCache(), shortener.ServiceOptions{})
+	handler := NewHandler(service, nil, "http://localhost:8080")
+
+	getStats := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/urls/abc123/stats", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set(constant.HeaderIfNoneMatch, ifNoneMatch)
+		}
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("shortCode", "abc123")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		w := httptest.NewRecorder()
+		handler.GetURLStats(w, req)
+		return w
+	}
+
+	// Act - initial request
+	initial := getStats("")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, initial.Code)
+	assert.Equal(t, `"abc123-5-0"`, initial.Header().Get(constant.HeaderETag))
+	assert.Equal(t, "public, max-age=60", initial.Header().Get(constant.HeaderCacheControl))
+
+	// Act - unchanged stats
+	unchanged := getStats(initial.Header().Get(constant.HeaderETag))
+
+	// Assert
+	assert.Equal(t, http.StatusNotModified, unchanged.Code)
+	assert.Empty(t, unchanged.Body.String())
+	assert.Equal(t, `"abc123-5-0"`, unchanged.Header().Get(constant.HeaderETag))
+
+	// Act - visit count changed
+	assert.NoError(t, repo.IncrementVisits(context.Background(), "abc123"))
+	changed := getStats(initial.Header().Get(constant.HeaderETag))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, changed.Code)
+	assert.Equal(t, `"abc123-6-0"`, changed.Header().Get(constant.HeaderETag))
+	var response URLStatsResponse
+	assert.NoError(t, json.Unmarshal(changed.Body.Bytes(), &response))
+	assert.Equal(t, uint(6), response.Visits)
+
+	// Act - bot visit recorded
+	assert.NoError(t, repo.RecordClick(context.Background(), shortener.ClickEvent{ShortCode: "abc123", ClickedAt: time.Now(), DeviceType: "bot"}))
+	botVisited := getStats(changed.Header().Get(constant.HeaderETag))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, botVisited.Code)
+	assert.Equal(t, `"abc123-6-1"`, botVisited.Header().Get(constant.HeaderETag))
+	assert.NoError(t, json.Unmarshal(botVisited.Body.Bytes(), &response))
+	assert.Equal(t, uint(6), response.Visits)
+	assert.Equal(t, uint(1), response.BotVisits)
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		expected    bool
+	}{
+		{name: "Empty", ifNoneMatch: "", expected: false},
+		{name: "Exact", ifNoneMatch: `"abc123-5"`, expected: true},
+		{name: "Weak", ifNoneMatch: `W/"abc123-5"`, expected: true},
+		{name: "List", ifNoneMatch: `"abc123-4", "abc123-5"`, expected: true},
+		{name: "Wildcard", ifNoneMatch: "*", expected: true},
+		{name: "Stale", ifNoneMatch: `"abc123-4"`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act & Assert
+			assert.Equal(t, tt.expected, etagMatches(tt.ifNoneMatch, `"abc123-5"`))
+		})
+	}
+}
+
+func TestRedirectToLongURL_CanarySplit(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{ShortCode: "abc123", LongURL: "https://example.com/old", AlternateURL: "https://example.com/new", CanaryPercent: 20})
+	handler := newTestHandler(repo, nil)
+	rng := rand.New(rand.NewSource(7))
+	handler.canaryRoll = func() int { return rng.Intn(shortener.MaxCanaryPercent) }
+
+	// Act
+	alternate := 0
+	for i := 0; i < 1000; i++ {
+		w := httptest.NewRecorder()
+		handler.RedirectToLongURL(w, withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123"))
+		if w.Header().Get("Location") == "https://example.com/new" {
+			alternate++
+		}
+	}
+
+	// Assert
+	assert.InDelta(t, 200, alternate, 40)
+}
+
+func TestIntegration_CanarySplit(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	handler := newTestHandler(repo, nil)
+	handler.canaryRoll = func() int { return 0 }
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("shorter-admin", "change-me-please")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	do("POST", "/api/v1/urls", `{"long_url":"https://example.com/a","custom_short_url":"split"}`)
+
+	tests := []struct {
+		name             string
+		method           string
+		target           string
+		body             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{name: "Create over 100 percent", method: "POST", target: "/api/v1/urls", body: `{"long_url":"https://example.com/a","alternate_url":"https://example.com/b","canary_percent":101}`, expectedStatus: http.StatusBadRequest},
+		{name: "Create without alternate", method: "POST", target: "/api/v1/urls", body: `{"long_url":"https://example.com/a","canary_percent":10}`, expectedStatus: http.StatusBadRequest},
+		{name: "Update invalid alternate", method: "PUT", target: "/api/v1/urls/split", body: `{"long_url":"https://example.com/a","alternate_url":"not a url","canary_percent":10}`, expectedStatus: http.StatusBadRequest},
+		{name: "Update starts split", method: "PUT", target: "/api/v1/urls/split", body: `{"long_url":"https://example.com/a","alternate_url":"https://example.com/b","canary_percent":10}`, expectedStatus: http.StatusOK, expectedLocation: "https://example.com/b"},
+		{name: "Update keeps split", method: "PUT", target: "/api/v1/urls/split", body: `{"long_url":"https://example.com/c"}`, expectedStatus: http.StatusOK, expectedLocation: "https://example.com/b"},
+		{name: "Update ends split", method: "PUT", target: "/api/v1/urls/split", body: `{"long_url":"https://example.com/c","canary_percent":0}`, expectedStatus: http.StatusOK, expectedLocation: "https://example.com/c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			w := do(tt.method, tt.target, tt.body)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedLocation != "" {
+				redirect := httptest.NewRecorder()
+				router.ServeHTTP(redirect, httptest.NewRequest("GET", "/split", nil))
+				assert.Equal(t, tt.expectedLocation, redirect.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+// stubGeoResolver maps IP addresses to countries; unknown addresses fail to resolve
+type stubGeoResolver map[string]string
+
+func (s stubGeoResolver) Country(ip string) (string, error) {
+	country, ok := s[ip]
+	if !ok {
+		return "", errors.New("address not in database")
+	}
+	return country, nil
+}
+
+func TestRedirectToLongURL_GeoRules(t *testing.T) {
+	resolver := stubGeoResolver{"203.0.113.7": "ID", "198.51.100.7": "DE", "192.0.2.7": "US", "192.0.2.8": ""}
+
+	tests := []struct {
+		name             string
+		resolver         geo.Resolver
+		ip               string
+		expectedLocation string
+	}{
+		{name: "First rule", resolver: resolver, ip: "203.0.113.7", expectedLocation: "https://example.com/asia"},
+		{name: "Second rule", resolver: resolver, ip: "198.51.100.7", expectedLocation: "https://example.de/landing"},
+		{name: "No matching rule", resolver: resolver, ip: "192.0.2.7", expectedLocation: "https://example.com/landing"},
+		{name: "Country unknown", resolver: resolver, ip: "192.0.2.8", expectedLocation: "https://example.com/landing"},
+		{name: "Lookup fails", resolver: resolver, ip: "192.0.2.9", expectedLocation: "https://example.com/landing"},
+		{name: "No resolver", ip: "203.0.113.7", expectedLocation: "https://example.com/landing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			seedURL(t, repo, &shortener.URL{ShortCode: "abc123", LongURL: "https://example.com/landing", GeoRules: []shortener.GeoRule{
+				{CountryCodes: []string{"ID", "MY"}, TargetURL: "https://example.com/asia"},
+				{CountryCodes: []string{"DE"}, TargetURL: "https://example.de/landing"},
+			}})
+			handler := newTestHandler(repo, nil)
+			if tt.resolver != nil {
+				handler.SetGeoResolver(tt.resolver)
+			}
+
+			req := withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123")
+			req.RemoteAddr = tt.ip + ":41234"
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.RedirectToLongURL(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestCreateShortURL_GeoRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Valid", body: `{"long_url":"https://example.com/a","geo_rules":[{"country_codes":["id"],"target_url":"https://example.com/asia"}]}`, expectedStatus: http.StatusCreated},
+		{name: "Invalid country code", body: `{"long_url":"https://example.com/a","geo_rules":[{"country_codes":["Indonesia"],"target_url":"https://example.com/asia"}]}`, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid target URL", body: `{"long_url":"https://example.com/a","geo_rules":[{"country_codes":["ID"],"target_url":"asia"}]}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			handler := newTestHandler(db.NewMemoryRepository(), nil)
+			req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.CreateShortURL(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				var resp ShortURLResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, []shortener.GeoRule{{CountryCodes: []string{"ID"}, TargetURL: "https://example.com/asia"}}, resp.GeoRules)
+			}
+		})
+	}
+}
+
+func TestRedirectToLongURL_CacheHeaders(t *testing.T) {
+	maxVisits := uint(10)
+	expiresSoon := time.Now().Add(2 * time.Minute)
+	expired := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name                     string
+		url                      *shortener.URL
+		cdnMaxAge                int
+		geoResolver              geo.Resolver
+		expectedCode             int
+		expectedCacheControl     string
+		expectedSurrogateControl string
+	}{
+		{name: "Shared redirect", url: &shortener.URL{LongURL: "https://example.com"}, cdnMaxAge: DefaultCDNCacheMaxAge,
+			expectedCode: http.StatusFound, expectedCacheControl: "public, max-age=300", expectedSurrogateControl: "max-age=3600"},
+		{name: "Configured CDN max age", url: &shortener.URL{LongURL: "https://example.com"}, cdnMaxAge: 600,
+			expectedCode: http.StatusFound, expectedCacheControl: "public, max-age=300", expectedSurrogateControl: "max-age=600"},
+		{name: "CDN max age disabled", url: &shortener.URL{LongURL: "https://example.com"}, cdnMaxAge: 0,
+			expectedCode: http.StatusFound, expectedCacheControl: "public, max-age=300"},
+		{name: "Expiring soon", url: &shortener.URL{LongURL: "https://example.com", ExpiresAt: &expiresSoon}, cdnMaxAge: DefaultCDNCacheMaxAge,
+			expectedCode: http.StatusFound, expectedCacheControl: "public, max-age=119", expectedSurrogateControl: "max-age=119"},
+		{name: "Canary split", url: &shortener.URL{LongURL: "https://example.com", AlternateURL: "https://example.com/b", CanaryPercent: 10}, cdnMaxAge: DefaultCDNCacheMaxAge,
+			expectedCode: http.StatusFound, expectedCacheControl: "private, max-age=300"},
+		{name: "Geo rules", url: &shortener.URL{LongURL: "https://example.com", GeoRules: []shortener.GeoRule{{CountryCodes: []string{"ID"}, TargetURL: "https://example.com/asia"}}}, cdnMaxAge: DefaultCDNCacheMaxAge,
+			geoResolver: stubGeoResolver{}, expectedCode: http.StatusFound, expectedCacheControl: "private, max-age=300"},
+		{name: "Visit limit", url: &shortener.URL{LongURL: "https://example.com", MaxVisits: &maxVisits}, cdnMaxAge: DefaultCDNCacheMaxAge,
+			expectedCode: http.StatusFound, expectedCacheControl: "no-store"},
+		{name: "Password protected", url: &shortener.URL{LongURL: "https://example.com", IsProtected: true, Password: "hash"}, cdnMaxAge: DefaultCDNCacheMaxAge,
+			expectedCode: http.StatusFound, expectedCacheControl: "private, no-store"},
+		{name: "Expired", url: &shortener.URL{LongURL: "https://example.com", ExpiresAt: &expired}, cdnMaxAge: DefaultCDNCacheMaxAge,
+			expectedCode: http.StatusGone, expectedCacheControl: "no-store"},
+		{name: "Deleted or unknown", cdnMaxAge: DefaultCDNCacheMaxAge,
+			expectedCode: http.StatusNotFound, expectedCacheControl: "no-store"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := db.NewMemoryRepository()
+			if tt.url != nil {
+				tt.url.ShortCode = "abc123"
+				seedURL(t, repo, tt.url)
+			}
+			handler := newTestHandler(repo, nil)
+			handler.SetCDNCacheMaxAge(tt.cdnMaxAge)
+			if tt.geoResolver != nil {
+				handler.SetGeoResolver(tt.geoResolver)
+			}
+
+			req := withShortCode(httptest.NewRequest("GET", "/abc123", nil), "abc123")
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.RedirectToLongURL(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedCacheControl, w.Header().Get(constant.HeaderCacheControl))
+			assert.Equal(t, tt.expectedSurrogateControl, w.Header().Get(constant.HeaderSurrogateControl))
+		})
+	}
+}
+
+func TestIntegration_AnonymousOwnerToken(t *testing.T) {
+	// Arrange
+	handler := newTestHandler(db.NewMemoryRepository(), nil)
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please", AllowAnonymousCreate: true})
+	router.SetupRoutes()
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(constant.HeaderOwnerToken, token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	created := do("POST", "/api/v1/urls", `{"long_url":"https://example.com/a","custom_short_url":"anon"}`, "")
+	assert.Equal(t, http.StatusCreated, created.Code)
+	var resp ShortURLResponse
+	assert.NoError(t, json.NewDecoder(created.Body).Decode(&resp))
+	assert.NotEmpty(t, resp.OwnerToken)
+	assert.NotNil(t, resp.OwnerTokenExpiresAt)
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "Update without token", method: "PUT", body: `{"long_url":"https://example.com/b"}`, expectedStatus: http.StatusForbidden},
+		{name: "Update with wrong token", method: "PUT", body: `{"long_url":"https://example.com/b"}`, token: "not-the-token", expectedStatus: http.StatusForbidden},
+		{name: "Update with token", method: "PUT", body: `{"long_url":"https://example.com/b"}`, token: resp.OwnerToken, expectedStatus: http.StatusOK},
+		{name: "Delete with wrong token", method: "DELETE", token: "not-the-token", expectedStatus: http.StatusForbidden},
+		{name: "Delete with token", method: "DELETE", token: resp.OwnerToken, expectedStatus: http.StatusNoContent},
+		{name: "Delete again", method: "DELETE", token: resp.OwnerToken, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			w := do(tt.method, "/api/v1/urls/anon", tt.body, tt.token)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRouter_AnonymousCreateDisabled(t *testing.T) {
+	// Arrange
+	handler := newTestHandler(db.NewMemoryRepository(), nil)
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(`{"long_url":"https://example.com/a"}`))
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestCreateShortURL_AuthenticatedGetsNoOwnerToken(t *testing.T) {
+	// Arrange
+	handler := newTestHandler(db.NewMemoryRepository(), nil)
+	router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please", AllowAnonymousCreate: true})
+	router.SetupRoutes()
+	req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(`{"long_url":"https://example.com/a"}`))
+	req.SetBasicAuth("shorter-admin", "change-me-please")
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp ShortURLResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Empty(t, resp.OwnerToken)
+}
+
+func TestIntegration_CloneURL(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("shorter-admin", "change-me-please")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	redirect := func(code string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+		return w.Header().Get("Location")
+	}
+	do("POST", "/api/v1/urls", `{"long_url":"https://example.com/a","custom_short_url":"source","redirect_code":301,"utm_source":"newsletter"}`)
+
+	// Act
+	cloned := do("POST", "/api/v1/urls/source/clone", `{"new_short_code":"copy"}`)
+	generated := do("POST", "/api/v1/urls/source/clone", "")
+	taken := do("POST", "/api/v1/urls/source/clone", `{"new_short_code":"copy"}`)
+	missing := do("POST", "/api/v1/urls/nope/clone", "")
+	updated := do("PUT", "/api/v1/urls/source", `{"long_url":"https://example.com/b"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, cloned.Code)
+	var resp ShortURLResponse
+	assert.NoError(t, json.Unmarshal(cloned.Body.Bytes(), &resp))
+	assert.Equal(t, "copy", resp.ShortCode)
+	assert.Equal(t, http.StatusCreated, generated.Code)
+	var generatedResp ShortURLResponse
+	assert.NoError(t, json.Unmarshal(generated.Body.Bytes(), &generatedResp))
+	assert.NotEqual(t, "source", generatedResp.ShortCode)
+	assert.Equal(t, http.StatusConflict, taken.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+	assert.Equal(t, http.StatusOK, updated.Code)
+
+	// Updating the source leaves the clone where it was
+	assert.Equal(t, "https://example.com/b?utm_source=newsletter", redirect("source"))
+	assert.Equal(t, "https://example.com/a?utm_source=newsletter", redirect("copy"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/copy", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
+
+func TestIntegration_ScheduledActivation(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	launched := time.Now().Add(-time.Minute)
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/live", ShortCode: "launched", ActiveFrom: &launched})
+
+	create := func(body string) {
+		req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("shorter-admin", "change-me-please")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"active_from"`)
+	}
+	later := time.Now().Add(time.Hour).Format(time.RFC3339)
+	create(`{"long_url":"https://example.com/launch","custom_short_url":"launch","active_from":"` + later + `"}`)
+	create(`{"long_url":"https://example.com/secret","custom_short_url":"locked","password":"secret","active_from":"` + later + `"}`)
+
+	tests := []struct {
+		name             string
+		method           string
+		target           string
+		body             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{name: "Redirect before activation", method: "GET", target: "/launch", expectedStatus: http.StatusNotFound},
+		{name: "Preview before activation", method: "GET", target: "/preview/launch", expectedStatus: http.StatusNotFound},
+		{name: "Password before activation", method: "POST", target: "/p/locked", body: "password=secret", expectedStatus: http.StatusNotFound},
+		{name: "Redirect after activation", method: "GET", target: "/launched", expectedStatus: http.StatusFound, expectedLocation: "https://example.com/live"},
+		{name: "Preview after activation", method: "GET", target: "/preview/launched", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+			assert.NotContains(t, w.Body.String(), "example.com/launch")
+			assert.NotContains(t, w.Body.String(), "example.com/secret")
+		})
+	}
+}
+
+func TestIntegration_CaseInsensitiveShortCodes(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	// Created before short codes were lowercased
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/legacy", ShortCode: "OldCode"})
+
+	req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(`{"long_url":"https://example.com/sale","custom_short_url":"SpringSale"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("shorter-admin", "change-me-please")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"short_code":"springsale"`)
+
+	tests := []struct {
+		name             string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{name: "Lowercase", target: "/springsale", expectedStatus: http.StatusFound, expectedLocation: "https://example.com/sale"},
+		{name: "As created", target: "/SpringSale", expectedStatus: http.StatusFound, expectedLocation: "https://example.com/sale"},
+		{name: "Uppercase", target: "/SPRINGSALE", expectedStatus: http.StatusFound, expectedLocation: "https://example.com/sale"},
+		{name: "Legacy code as created", target: "/OldCode", expectedStatus: http.StatusFound, expectedLocation: "https://example.com/legacy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestIntegration_CaseInsensitiveAnalytics(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/sale", ShortCode: "springsale"})
+	clickedAt := time.Now().Add(-time.Minute)
+	for _, deviceType := range []string{"desktop", "bot"} {
+		click := shortener.ClickEvent{ShortCode: "springsale", ClickedAt: clickedAt, Referer: "https://ref.example.com", DeviceType: deviceType}
+		assert.NoError(t, repo.RecordClick(context.Background(), click))
+	}
+
+	tests := []struct {
+		name         string
+		target       string
+		expectedBody string
+	}{
+		{name: "Stats", target: "/api/v1/urls/SPRINGSALE/stats", expectedBody: `"bot_visits":1`},
+		{name: "Visits", target: "/api/v1/urls/SPRINGSALE/visits", expectedBody: `"count":2`},
+		{name: "Referers", target: "/api/v1/urls/SPRINGSALE/referers", expectedBody: `"url":"https://ref.example.com","count":2`},
+		{name: "Device stats", target: "/api/v1/urls/SPRINGSALE/device-stats", expectedBody: `"desktop":1`},
+		{name: "Sparkline", target: "/api/v1/urls/SPRINGSALE/sparkline", expectedBody: `"count":2`},
+		{name: "Export clicks", target: "/api/v1/urls/SPRINGSALE/clicks/export?format=json", expectedBody: "https://ref.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.SetBasicAuth("shorter-admin", "change-me-please")
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert - clicks stored under the lowercase code are found for the uppercase one
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestIntegration_PreventSelfRedirect(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "http://localhost:8080/other", ShortCode: "samehost"})
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/page", ShortCode: "otherhost"})
+	seedURL(t, repo, &shortener.URL{LongURL: "http://docs.localhost:8080/guide", ShortCode: "subdomain"})
+
+	tests := []struct {
+		name             string
+		prevent          bool
+		target           string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{name: "Same host", prevent: true, target: "/samehost", expectedStatus: http.StatusBadRequest},
+		{name: "Different host", prevent: true, target: "/otherhost", expectedStatus: http.StatusFound, expectedLocation: "https://example.com/page"},
+		{name: "Subdomain", prevent: true, target: "/subdomain", expectedStatus: http.StatusFound, expectedLocation: "http://docs.localhost:8080/guide"},
+		{name: "Same host when allowed", prevent: false, target: "/samehost", expectedStatus: http.StatusFound, expectedLocation: "http://localhost:8080/other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			handler := newTestHandler(repo, nil)
+			handler.SetPreventSelfRedirect(tt.prevent)
+			router := NewRouter(handler, config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+			router.SetupRoutes()
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+			if tt.expectedStatus == http.StatusBadRequest {
+				assert.Contains(t, w.Body.String(), `"error":"redirect loop detected"`)
+			}
+		})
+	}
+}
+
+func TestIntegration_CreateShortURL_Unreachable(t *testing.T) {
+	// Arrange
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/exists" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer target.Close()
+	service := shortener.NewService(db.NewMemoryRepository(), cache.NewNoopCache(), shortener.ServiceOptions{ValidateURLReachability: true})
+	router := NewRouter(NewHandler(service, nil, "http://localhost:8080"), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+
+	tests := []struct {
+		name           string
+		longURL        string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Reachable", longURL: target.URL + "/exists", expectedStatus: http.StatusCreated, expectedBody: `"short_code":`},
+		{name: "Not found", longURL: target.URL + "/typo", expectedStatus: http.StatusUnprocessableEntity, expectedBody: `"error_code":"url_unreachable"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(`{"long_url":"`+tt.longURL+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetBasicAuth("shorter-admin", "change-me-please")
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestIntegration_UpdateLongURL_ValidatesNewURL(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	service := shortener.NewService(repo, cache.NewNoopCache(), shortener.ServiceOptions{SSRFGuard: security.NewGuard()})
+	router := NewRouter(NewHandler(service, nil, "http://localhost:8080"), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://93.184.216.34/a", ShortCode: "abc123"})
+
+	tests := []struct {
+		name            string
+		longURL         string
+		expectedStatus  int
+		expectedLongURL string
+	}{
+		{name: "Private address", longURL: "http://127.0.0.1/admin", expectedStatus: http.StatusUnprocessableEntity, expectedLongURL: "https://93.184.216.34/a"},
+		{name: "Invalid URL", longURL: "http://[::1", expectedStatus: http.StatusBadRequest, expectedLongURL: "https://93.184.216.34/a"},
+		{name: "Normalized", longURL: "HTTPS://93.184.216.34:443/b", expectedStatus: http.StatusOK, expectedLongURL: "https://93.184.216.34/b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("PUT", "/api/v1/urls/abc123", strings.NewReader(`{"long_url":"`+tt.longURL+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetBasicAuth("shorter-admin", "change-me-please")
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			stored, err := repo.FindByShortCode(context.Background(), "abc123")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLongURL, stored.LongURL)
+		})
+	}
+}
+
+func TestIntegration_GetURLDetails(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	future := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/live", ShortCode: "live", CreatedAt: createdAt, Visits: 7, ExpiresAt: &future, RedirectCode: http.StatusMovedPermanently})
+	assert.NoError(t, repo.AddTag(context.Background(), "live", "launch"))
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/old", ShortCode: "old", CreatedAt: createdAt, Visits: 3, ExpiresAt: &past})
+
+	tests := []struct {
+		name           string
+		shortCode      string
+		expectedStatus int
+		expected       *URLDetailResponse
+	}{
+		{
+			name:           "Found",
+			shortCode:      "live",
+			expectedStatus: http.StatusOK,
+			expected: &URLDetailResponse{
+				ShortCode:    "live",
+				LongURL:      "https://example.com/live",
+				ShortURL:     "http://localhost:8080/live",
+				Visits:       7,
+				CreatedAt:    createdAt,
+				ExpiresAt:    &future,
+				Tags:         []string{"launch"},
+				Status:       shortener.URLStatusActive,
+				RedirectCode: http.StatusMovedPermanently,
+			},
+		},
+		{
+			name:           "Expired",
+			shortCode:      "old",
+			expectedStatus: http.StatusOK,
+			expected: &URLDetailResponse{
+				ShortCode:    "old",
+				LongURL:      "https://example.com/old",
+				ShortURL:     "http://localhost:8080/old",
+				Visits:       3,
+				CreatedAt:    createdAt,
+				ExpiresAt:    &past,
+				Tags:         []string{},
+				Status:       shortener.URLStatusExpired,
+				RedirectCode: http.StatusFound,
+			},
+		},
+		{name: "Not found", shortCode: "missing", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("GET", "/api/v1/urls/"+tt.shortCode, nil)
+			req.SetBasicAuth("shorter-admin", "change-me-please")
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expected == nil {
+				assert.Contains(t, w.Body.String(), `"error_code":"short_code_not_found"`)
+				return
+			}
+			var resp URLDetailResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expected.CreatedAt, resp.CreatedAt.UTC())
+			assert.Equal(t, tt.expected.ExpiresAt.Unix(), resp.ExpiresAt.Unix())
+			resp.CreatedAt, resp.ExpiresAt = tt.expected.CreatedAt, tt.expected.ExpiresAt
+			assert.Equal(t, *tt.expected, resp)
+
+			stored, err := repo.FindByShortCode(context.Background(), tt.shortCode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected.Visits, stored.Visits, "reading details is not a visit")
+		})
+	}
+}
+
+func TestIntegration_GetURLDetails_RequiresAuth(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/live", ShortCode: "live"})
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/urls/live", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRenderHTML_LogsExecuteError(t *testing.T) {
+	// Arrange
+	core, logs := observer.New(appLogger.Level)
+	t.Cleanup(appLogger.ReplaceLogger(zap.New(core)))
+	broken := template.Must(template.New("broken").Parse(`{{.Missing}}`))
+	w := httptest.NewRecorder()
+
+	// Act
+	renderHTML(context.Background(), w, broken, protectedURLFormData{}, http.StatusOK, constant.CtxProtectedURL)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	entries := logs.FilterMessage("Error rendering HTML page").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "broken", entries[0].ContextMap()[constant.DataTemplate])
+}
+
+func TestQRParameterErrors_HaveCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode string
+	}{
+		{name: "Size", err: errInvalidQRSize, expectedCode: constant.ErrCodeAPIQRSize},
+		{name: "Format", err: errInvalidQRFormat, expectedCode: constant.ErrCodeAPIQRFormat},
+		{name: "Color", err: errInvalidQRColor, expectedCode: constant.ErrCodeAPIQRColor},
+		{name: "Error correction level", err: errInvalidQRECLevel, expectedCode: constant.ErrCodeAPIQRECLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			matched := hasCode(tt.err, tt.expectedCode)
+
+			// Assert
+			assert.True(t, matched)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+)
+
+// BodyLimit is middleware that caps request bodies at max bytes, so reading past
+// the limit fails with *http.MaxBytesError instead of growing the heap.
+// Multipart uploads are left to the handler, which sets a limit of its own.
+func BodyLimit(max int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil && !isMultipart(r) {
+				r.Body = http.MaxBytesReader(w, r.Body, max)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isMultipart reports whether the request carries a multipart/form-data body
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
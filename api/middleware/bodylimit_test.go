@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		contentType    string
+		expectedStatus int
+	}{
+		{name: "Within limit", body: strings.Repeat("a", 16), contentType: "application/json", expectedStatus: http.StatusOK},
+		{name: "Exceeds limit", body: strings.Repeat("a", 17), contentType: "application/json", expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Multipart left to handler", body: strings.Repeat("a", 17), contentType: "multipart/form-data; boundary=x", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			handler := BodyLimit(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := io.ReadAll(r.Body); err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						w.WriteHeader(http.StatusRequestEntityTooLarge)
+						return
+					}
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
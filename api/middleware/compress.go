@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// gzipWriterPool reuses gzip writers across responses
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// Gzip is middleware that compresses response bodies for clients accepting gzip.
+// Binary image responses such as PNG QR codes are passed through untouched.
+func Gzip() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w}
+			defer gw.Close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipResponseWriter compresses the body once the response headers show it is worth compressing
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+// WriteHeader decides whether to compress before sending the status code
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	header := w.Header()
+	if shouldCompress(status, header) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+
+		w.gz = gzipWriterPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write compresses b when compression is enabled for this response
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush pushes any buffered compressed data to the client
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the gzip stream and returns the writer to the pool
+func (w *gzipResponseWriter) Close() {
+	if w.gz == nil {
+		return
+	}
+	_ = w.gz.Close()
+	gzipWriterPool.Put(w.gz)
+	w.gz = nil
+}
+
+// shouldCompress reports whether a response with the given status and headers should be gzipped
+func shouldCompress(status int, header http.Header) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "image/svg+xml") {
+		return false
+	}
+	return true
+}
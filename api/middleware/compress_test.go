@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzip_CompressesResponse(t *testing.T) {
+	// Arrange
+	body := strings.Repeat(`{"short_code":"abc123","long_url":"https://example.com"}`, 50)
+	handler := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest("GET", "/api/export", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Less(t, w.Body.Len(), len(body))
+
+	reader, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(decompressed))
+}
+
+func TestGzip_SkipsWithoutAcceptEncoding(t *testing.T) {
+	// Arrange
+	handler := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	req := httptest.NewRequest("GET", "/api/export", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"ok":true}`, w.Body.String())
+}
+
+func TestGzip_SkipsBinaryImages(t *testing.T) {
+	// Arrange
+	png := []byte("\x89PNG fake-qr-code-data")
+	handler := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.WriteHeader(http.StatusOK)
+		w.Write(png)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, png, w.Body.Bytes())
+}
+
+func TestGzip_Flush(t *testing.T) {
+	// Arrange
+	handler := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("id,short_code\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("1,abc123\n"))
+	}))
+
+	req := httptest.NewRequest("GET", "/api/export", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.True(t, w.Flushed)
+	reader, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,short_code\n1,abc123\n", string(decompressed))
+}
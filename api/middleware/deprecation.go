@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Deprecation is middleware that marks responses as coming from a deprecated API,
+// announcing the date after which it will be removed in the Sunset header
+func Deprecation(sunset time.Time) func(http.Handler) http.Handler {
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", sunsetHeader)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecation(t *testing.T) {
+	// Arrange
+	sunset := time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+	handler := Deprecation(sunset)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/urls", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+}
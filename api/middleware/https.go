@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// HTTPSRedirect returns a handler that sends every request to the same host and
+// path over HTTPS on httpsPort, for a plain HTTP listener running beside the TLS one
+func HTTPSRedirect(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusFound)
+	})
+}
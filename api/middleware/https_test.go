@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     int
+		target   string
+		location string
+	}{
+		{name: "Default port", port: 443, target: "http://example.com/abc123?utm=1", location: "https://example.com/abc123?utm=1"},
+		{name: "Custom port", port: 8443, target: "http://example.com:8080/abc123", location: "https://example.com:8443/abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			w := httptest.NewRecorder()
+
+			// Act
+			HTTPSRedirect(tt.port).ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHTTPSRedirect_TLSServer(t *testing.T) {
+	// Arrange
+	cert, err := tls.LoadX509KeyPair("testdata/cert.pem", "testdata/key.pem")
+	assert.NoError(t, err)
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	tlsServer.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	redirectServer := httptest.NewServer(HTTPSRedirect(tlsServer.Listener.Addr().(*net.TCPAddr).Port))
+	defer redirectServer.Close()
+	client := &http.Client{
+		// The test certificate is self-signed
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// Act
+	redirectResp, redirectErr := client.Get(redirectServer.URL + "/abc123")
+	assert.NoError(t, redirectErr)
+	defer redirectResp.Body.Close()
+	secureResp, secureErr := client.Get(redirectResp.Header.Get("Location"))
+
+	// Assert
+	assert.Equal(t, http.StatusFound, redirectResp.StatusCode)
+	assert.Equal(t, tlsServer.URL+"/abc123", redirectResp.Header.Get("Location"))
+	assert.NoError(t, secureErr)
+	defer secureResp.Body.Close()
+	body, err := io.ReadAll(secureResp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, secureResp.StatusCode)
+	assert.Equal(t, "secure", string(body))
+}
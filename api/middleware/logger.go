@@ -1,7 +1,12 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/base64"
+	"io"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,8 +14,10 @@ import (
 	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
 )
 
-// RequestLogger is middleware that adds request ID to the context and logs request/response info
-func RequestLogger() func(http.Handler) http.Handler {
+// RequestLogger is middleware that adds request ID to the context and logs request/response info.
+// While debug logging is enabled it also logs the first maxBodyLogBytes of the request and
+// response bodies, base64 encoded, with the values of sensitiveBodyFields redacted.
+func RequestLogger(maxBodyLogBytes int) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Generate a unique request ID
@@ -36,6 +43,17 @@ func RequestLogger() func(http.Handler) http.Handler {
 			// Create a response wrapper to capture status code
 			ww := newStatusResponseWriter(w)
 
+			// Copy the bodies as they are read and written, so the handler still sees the whole request
+			var requestBody *cappedBuffer
+			logBodies := maxBodyLogBytes > 0 && appLogger.DebugEnabled()
+			if logBodies {
+				requestBody = &cappedBuffer{max: maxBodyLogBytes}
+				ww.body = &cappedBuffer{max: maxBodyLogBytes}
+				if r.Body != nil {
+					r.Body = teeReadCloser{Reader: io.TeeReader(r.Body, requestBody), Closer: r.Body}
+				}
+			}
+
 			// Process request
 			startTime := time.Now()
 			next.ServeHTTP(ww, r.WithContext(ctx))
@@ -53,25 +71,76 @@ func RequestLogger() func(http.Handler) http.Handler {
 				logFunc = appLogger.CtxError
 			}
 
-			logFunc(ctx, "Request completed", appLogger.LoggerInfo{
+			data := map[string]interface{}{
+				"status":  statusCode,
+				"latency": latency.String(),
+				"method":  r.Method,
+				"path":    r.URL.Path,
+				"size":    ww.size,
+			}
+			if logBodies {
+				data[constant.DataRequestBody] = base64.StdEncoding.EncodeToString(redactBody(requestBody.Bytes()))
+				data[constant.DataResponseBody] = base64.StdEncoding.EncodeToString(redactBody(ww.body.Bytes()))
+			}
+
+			logFunc(ctx, constant.MsgRequestCompleted, appLogger.LoggerInfo{
 				ContextFunction: constant.CtxAPI,
-				Data: map[string]interface{}{
-					"status":  statusCode,
-					"latency": latency.String(),
-					"method":  r.Method,
-					"path":    r.URL.Path,
-					"size":    ww.size,
-				},
+				Data:            data,
 			})
 		})
 	}
 }
 
+// redactedValue replaces the value of a sensitive field in logged bodies
+const redactedValue = "[REDACTED]"
+
+// sensitiveBodyFields are the JSON keys and form fields whose values never reach the logs
+var sensitiveBodyFields = []string{"password", "owner_token"}
+
+// Patterns matching a sensitive value in a JSON object and in a form-encoded body. The
+// closing quote of a JSON string is optional because logged bodies may be cut short.
+var (
+	sensitiveJSONValue = regexp.MustCompile(`"(` + strings.Join(sensitiveBodyFields, "|") + `)"(\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+	sensitiveFormValue = regexp.MustCompile(`(^|&)(` + strings.Join(sensitiveBodyFields, "|") + `)=[^&]*`)
+)
+
+// redactBody replaces the values of sensitiveBodyFields in a JSON or form-encoded body
+func redactBody(body []byte) []byte {
+	body = sensitiveJSONValue.ReplaceAll(body, []byte(`"$1"$2"`+redactedValue+`"`))
+	return sensitiveFormValue.ReplaceAll(body, []byte(`$1$2=`+redactedValue))
+}
+
+// teeReadCloser reads through a TeeReader while closing the original body
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+// Write stores what still fits and always reports success, so it never interrupts a TeeReader
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
 // statusResponseWriter is a custom response writer that captures the status code and response size
 type statusResponseWriter struct {
 	http.ResponseWriter
 	status int
 	size   int
+	// body, when set, receives a copy of the response body
+	body *cappedBuffer
 }
 
 // newStatusResponseWriter creates a new statusResponseWriter
@@ -92,5 +161,15 @@ func (w *statusResponseWriter) WriteHeader(status int) {
 func (w *statusResponseWriter) Write(b []byte) (int, error) {
 	size, err := w.ResponseWriter.Write(b)
 	w.size += size
+	if w.body != nil {
+		_, _ = w.body.Write(b[:size])
+	}
 	return size, err
 }
+
+// Flush passes through to the underlying writer so streamed responses keep streaming
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
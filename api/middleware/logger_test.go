@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeLogs routes the application logger to an in-memory observer at level for the rest of the test
+func observeLogs(t *testing.T, level zapcore.Level) *observer.ObservedLogs {
+	core, logs := observer.New(appLogger.Level)
+	t.Cleanup(appLogger.ReplaceLogger(zap.New(core)))
+	previous := appLogger.Level.Level()
+	appLogger.Level.SetLevel(level)
+	t.Cleanup(func() { appLogger.Level.SetLevel(previous) })
+	return logs
+}
+
+func TestRequestLogger_RequestID(t *testing.T) {
+	// Arrange
+	handler := RequestLogger(512)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Context().Value(constant.RequestIDKey)
+		assert.NotNil(t, requestID)
+		assert.Equal(t, requestID, w.Header().Get(constant.HeaderRequestID))
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get(constant.HeaderRequestID))
+}
+
+func TestRequestLogger_Bodies(t *testing.T) {
+	tests := []struct {
+		name             string
+		level            zapcore.Level
+		requestBody      string
+		responseBody     string
+		expectedRequest  string
+		expectedResponse string
+		expectBodies     bool
+	}{
+		{
+			name:             "Debug",
+			level:            zapcore.DebugLevel,
+			requestBody:      `{"long_url":"x"}`,
+			responseBody:     `{"code":"abc"}`,
+			expectedRequest:  `{"long_url":"x"}`,
+			expectedResponse: `{"code":"abc"}`,
+			expectBodies:     true,
+		},
+		{
+			name:             "Debug truncated",
+			level:            zapcore.DebugLevel,
+			requestBody:      strings.Repeat("a", 20),
+			responseBody:     strings.Repeat("b", 20),
+			expectedRequest:  strings.Repeat("a", 16),
+			expectedResponse: strings.Repeat("b", 16),
+			expectBodies:     true,
+		},
+		{
+			name:         "Info",
+			level:        zapcore.InfoLevel,
+			requestBody:  `{"long_url":"https://example.com"}`,
+			responseBody: `{"short_code":"abc123"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			logs := observeLogs(t, tt.level)
+			var handlerSaw string
+			handler := RequestLogger(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				handlerSaw = string(body)
+				_, _ = w.Write([]byte(tt.responseBody))
+			}))
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/urls", strings.NewReader(tt.requestBody)))
+
+			// Assert
+			assert.Equal(t, tt.requestBody, handlerSaw, "the handler reads the whole body")
+			assert.Equal(t, tt.responseBody, w.Body.String())
+			completed := logs.FilterMessage(constant.MsgRequestCompleted).All()
+			if !assert.Len(t, completed, 1) {
+				return
+			}
+			fields := completed[0].ContextMap()
+			if !tt.expectBodies {
+				assert.NotContains(t, fields, constant.DataRequestBody)
+				assert.NotContains(t, fields, constant.DataResponseBody)
+				return
+			}
+			assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(tt.expectedRequest)), fields[constant.DataRequestBody])
+			assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(tt.expectedResponse)), fields[constant.DataResponseBody])
+		})
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "JSON password",
+			body:     `{"long_url":"https://example.com","password": "s3cr\"et"}`,
+			expected: `{"long_url":"https://example.com","password": "[REDACTED]"}`,
+		},
+		{
+			name:     "JSON owner token",
+			body:     `{"short_code":"abc123","owner_token":"tok","owner_token_expires_at":"2024"}`,
+			expected: `{"short_code":"abc123","owner_token":"[REDACTED]","owner_token_expires_at":"2024"}`,
+		},
+		{
+			name:     "JSON truncated",
+			body:     `{"owner_token":"to`,
+			expected: `{"owner_token":"[REDACTED]"`,
+		},
+		{
+			name:     "Form password",
+			body:     `password=s3cret&next=%2F`,
+			expected: `password=[REDACTED]&next=%2F`,
+		},
+		{
+			name:     "Nothing sensitive",
+			body:     `{"long_url":"https://example.com/?password_hint=x"}`,
+			expected: `{"long_url":"https://example.com/?password_hint=x"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			redacted := redactBody([]byte(tt.body))
+
+			// Assert
+			assert.Equal(t, tt.expected, string(redacted))
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package middleware
+
+import "net/http"
+
+// NoIndex is middleware that asks search engines not to index the response,
+// so redirects are not listed as pages of their own
+func NoIndex(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Robots-Tag", "noindex")
+		next.ServeHTTP(w, r)
+	})
+}
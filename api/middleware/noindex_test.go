@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoIndex(t *testing.T) {
+	// Arrange
+	handler := NoIndex(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	}))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "noindex", w.Header().Get("X-Robots-Tag"))
+}
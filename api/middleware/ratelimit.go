@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit is middleware that applies a token bucket rate limit per client IP, the IP of
+// the connection as recorded by ConnectionIP, so clients cannot pick their bucket with a
+// header. Each client gets a bucket refilled at rps tokens per second holding up to burst tokens.
+// Every response reports the bucket in X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset, the Unix time at which it is full again; rejected requests also
+// get Retry-After, the seconds until the next token.
+func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+	return NewRateLimiter(rps, burst).Middleware
+}
+
+// RateLimiter applies the rate limit of RateLimit with limits that can be changed while
+// it serves requests, as on a configuration reload. An rps of 0 lets every request through.
+type RateLimiter struct {
+	limiters *clientLimiters
+}
+
+// NewRateLimiter creates a rate limiter refilling each client's bucket at rps tokens per
+// second and holding up to burst tokens
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	return &RateLimiter{limiters: newClientLimiters(rps, burst)}
+}
+
+// SetLimit changes the limits of every client, existing buckets included
+func (l *RateLimiter) SetLimit(rps float64, burst int) {
+	l.limiters.setLimit(rps, burst)
+}
+
+// Middleware applies the rate limit to next
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rps, burst := l.limiters.limits()
+		if rps <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := remoteIP(r)
+		now := time.Now()
+		limiter := l.limiters.get(ip, now)
+		allowed := limiter.AllowN(now, 1)
+		tokens := limiter.TokensAt(now)
+
+		h := w.Header()
+		h.Set(constant.HeaderRateLimitLimit, strconv.Itoa(burst))
+		h.Set(constant.HeaderRateLimitRemaining, strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+		h.Set(constant.HeaderRateLimitReset, strconv.FormatInt(now.Add(refillTime(float64(burst)-tokens, rps)).Unix(), 10))
+
+		if !allowed {
+			appLogger.CtxWarn(r.Context(), constant.MsgRateLimitExceeded, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxRateLimit,
+				Data: map[string]interface{}{
+					constant.DataIP:     ip,
+					constant.DataMethod: r.Method,
+					constant.DataPath:   r.URL.Path,
+				},
+			})
+
+			retryAfter := math.Max(1, refillTime(1-tokens, rps).Seconds())
+			h.Set(constant.HeaderRetryAfter, strconv.Itoa(int(retryAfter)))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": constant.MsgRateLimitExceeded,
+				"code":  http.StatusTooManyRequests,
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limiterSweepInterval is how often idle client buckets are looked for
+const limiterSweepInterval = time.Minute
+
+// clientLimiters holds a token bucket per client IP. A bucket left alone long enough
+// to refill is the same as a new one, so it is dropped at the next sweep.
+type clientLimiters struct {
+	mutex     sync.Mutex
+	clients   map[string]*clientLimiter
+	rps       float64
+	burst     int
+	idle      time.Duration
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newClientLimiters(rps float64, burst int) *clientLimiters {
+	return &clientLimiters{
+		clients:   make(map[string]*clientLimiter),
+		rps:       rps,
+		burst:     burst,
+		idle:      refillTime(float64(burst), rps),
+		lastSweep: time.Now(),
+	}
+}
+
+// get returns the bucket of ip, creating it when missing, and sweeps idle buckets
+// when limiterSweepInterval has passed since the last sweep
+func (l *clientLimiters) get(ip string, now time.Time) *rate.Limiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.lastSweep) >= limiterSweepInterval {
+		l.sweep(now)
+	}
+
+	c, exists := l.clients[ip]
+	if !exists {
+		c = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// limits returns the current refill rate and bucket size
+func (l *clientLimiters) limits() (float64, int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rps, l.burst
+}
+
+// setLimit changes the refill rate and bucket size of new and existing buckets
+func (l *clientLimiters) setLimit(rps float64, burst int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.rps = rps
+	l.burst = burst
+	l.idle = refillTime(float64(burst), rps)
+	now := time.Now()
+	for _, c := range l.clients {
+		c.limiter.SetLimitAt(now, rate.Limit(rps))
+		c.limiter.SetBurstAt(now, burst)
+	}
+}
+
+// sweep drops the buckets of clients idle for longer than a full refill
+func (l *clientLimiters) sweep(now time.Time) {
+	for ip, c := range l.clients {
+		if now.Sub(c.lastSeen) > l.idle {
+			delete(l.clients, ip)
+		}
+	}
+	l.lastSweep = now
+}
+
+// refillTime returns how long a bucket refilled at rps tokens per second takes to gain tokens,
+// rounded up to whole seconds
+func refillTime(tokens, rps float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens/rps)) * time.Second
+}
+
+// connectionIPContextKey is the context key of the IP recorded by ConnectionIP
+type connectionIPContextKey struct{}
+
+// ConnectionIP is middleware that records the IP of the connection before later middleware,
+// such as chi's RealIP, replace RemoteAddr with a forwarding header. It must come first.
+func ConnectionIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), connectionIPContextKey{}, hostOf(r.RemoteAddr))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// remoteIP returns the IP of the connection: the one ConnectionIP recorded, or the host
+// part of RemoteAddr when it did not run
+func remoteIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(connectionIPContextKey{}).(string); ok {
+		return ip
+	}
+	return hostOf(r.RemoteAddr)
+}
+
+// hostOf returns the host part of addr, or addr itself when it has no port
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// ClientIP returns the originating client IP, preferring the first X-Forwarded-For entry over RemoteAddr.
+// The header is set by the client, so the result suits analytics but not access control.
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get(constant.HeaderForwardedFor); forwarded != "" {
+		if ip := strings.TrimSpace(strings.Split(forwarded, ",")[0]); ip != "" {
+			return ip
+		}
+	}
+	return remoteIP(r)
+}
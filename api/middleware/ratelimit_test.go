@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimitedHandler(rps float64, burst int) http.Handler {
+	return RateLimit(rps, burst)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestRateLimit_ExhaustsBurst(t *testing.T) {
+	// Arrange
+	handler := newRateLimitedHandler(1, 3)
+
+	// Act & Assert - the first burst requests pass
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// The next request is rejected
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(constant.HeaderRetryAfter))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestRateLimit_Headers(t *testing.T) {
+	// Arrange
+	handler := newRateLimitedHandler(0.5, 3)
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	start := time.Now()
+
+	// Act
+	responses := []*httptest.ResponseRecorder{serve(), serve(), serve(), serve()}
+
+	// Assert - every response carries the limit headers and Remaining counts down
+	for i, expectedRemaining := range []string{"2", "1", "0", "0"} {
+		w := responses[i]
+		assert.Equal(t, "3", w.Header().Get(constant.HeaderRateLimitLimit))
+		assert.Equal(t, expectedRemaining, w.Header().Get(constant.HeaderRateLimitRemaining), "request %d", i+1)
+
+		reset, err := strconv.ParseInt(w.Header().Get(constant.HeaderRateLimitReset), 10, 64)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, reset, start.Unix())
+		assert.LessOrEqual(t, reset, start.Add(7*time.Second).Unix(), "refilling 3 tokens at 0.5/s takes at most 6s")
+	}
+	for _, w := range responses[:3] {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(constant.HeaderRetryAfter))
+	}
+
+	// The rejected request is told to wait for the next token, 2s at 0.5/s
+	assert.Equal(t, http.StatusTooManyRequests, responses[3].Code)
+	assert.Equal(t, "2", responses[3].Header().Get(constant.HeaderRetryAfter))
+}
+
+func TestRateLimit_IndependentBucketsPerIP(t *testing.T) {
+	// Arrange
+	handler := newRateLimitedHandler(1, 1)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Act - a different client still has a full bucket
+	req = httptest.NewRequest("GET", "/abc123", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimit_IgnoresForwardedFor(t *testing.T) {
+	// Arrange
+	handler := newRateLimitedHandler(1, 1)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set(constant.HeaderForwardedFor, "203.0.113.5")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Act - same connection claiming a different client
+	req = httptest.NewRequest("GET", "/abc123", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set(constant.HeaderForwardedFor, "203.0.113.6")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestClientLimiters_SweepsIdleClients(t *testing.T) {
+	// Arrange - a bucket of 2 refills in 2s at 1/s
+	start := time.Now()
+	limiters := newClientLimiters(1, 2)
+	limiters.lastSweep = start
+	limiters.get("10.0.0.1", start)
+	limiters.get("10.0.0.2", start.Add(limiterSweepInterval-time.Second))
+
+	// Act
+	limiters.get("10.0.0.3", start.Add(limiterSweepInterval))
+
+	// Assert
+	assert.NotContains(t, limiters.clients, "10.0.0.1", "an idle client's bucket is dropped")
+	assert.Contains(t, limiters.clients, "10.0.0.2", "a bucket still refilling is kept")
+	assert.Contains(t, limiters.clients, "10.0.0.3")
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.10:5555"
+	assert.Equal(t, "192.168.1.10", ClientIP(req))
+
+	req.Header.Set(constant.HeaderForwardedFor, " 203.0.113.5 , 192.168.1.10")
+	assert.Equal(t, "203.0.113.5", ClientIP(req))
+}
+
+func TestRateLimiter_SetLimit(t *testing.T) {
+	// Arrange
+	limiter := NewRateLimiter(0, 0)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Disabled, every request passes without limit headers
+	for i := 0; i < 5; i++ {
+		w := serve()
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(constant.HeaderRateLimitLimit))
+	}
+
+	// Act - enable the limit, then shrink the burst of the existing bucket, which
+	// leaves it one token where a burst of 3 would have left two
+	limiter.SetLimit(1, 3)
+	w := serve()
+	limiter.SetLimit(1, 1)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3", w.Header().Get(constant.HeaderRateLimitLimit))
+	assert.Equal(t, http.StatusOK, serve().Code)
+	w = serve()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(constant.HeaderRateLimitLimit))
+}
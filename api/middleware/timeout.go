@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+)
+
+// WithTimeout is middleware that cancels the request context after d and answers
+// 503 Service Unavailable with a JSON error if the handler has not finished by then.
+// The response is buffered until the handler returns, so it does not suit streaming routes.
+func WithTimeout(d time.Duration) func(http.Handler) http.Handler {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error": constant.MsgRequestTimeout,
+		"code":  http.StatusServiceUnavailable,
+	})
+
+	return func(next http.Handler) http.Handler {
+		timeout := http.TimeoutHandler(next, d, string(body))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout.ServeHTTP(&timeoutResponseWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// timeoutResponseWriter labels the timeout error body as JSON, which http.TimeoutHandler leaves untyped
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+}
+
+// WriteHeader sets the JSON content type on a 503 that carries none
+func (w *timeoutResponseWriter) WriteHeader(status int) {
+	if status == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout_SlowHandler(t *testing.T) {
+	// Arrange
+	ctxErr := make(chan error, 1)
+	handler := WithTimeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		ctxErr <- r.Context().Err()
+	}))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"Request timed out","code":503}`, w.Body.String())
+	select {
+	case err := <-ctxErr:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("the handler's context was not cancelled")
+	}
+}
+
+func TestWithTimeout_FastHandler(t *testing.T) {
+	// Arrange
+	handler := WithTimeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	}))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/abc123", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracedRouter(status int) (http.Handler, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	r := chi.NewRouter()
+	r.Use(Tracing(provider.Tracer("test")))
+	r.Get("/{shortCode}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+	return r, recorder
+}
+
+func TestTracing_ContinuesTraceparent(t *testing.T) {
+	// Arrange
+	handler, recorder := newTracedRouter(http.StatusFound)
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "GET /{shortCode}", span.Name())
+	assert.Contains(t, span.Attributes(), semconv.HTTPRoute("/{shortCode}"))
+	assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(http.StatusFound))
+	assert.Contains(t, span.Attributes(), semconv.URLPath("/abc123"))
+}
+
+func TestTracing_NewTraceWithoutHeader(t *testing.T) {
+	// Arrange
+	handler, recorder := newTracedRouter(http.StatusInternalServerError)
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(w, req)
+
+	// Assert
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
@@ -1,73 +1,152 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	appMiddleware "github.com/prasetyowira/shorter/api/middleware"
+	"github.com/prasetyowira/shorter/config"
 	"github.com/prasetyowira/shorter/constant"
 	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+	"github.com/prasetyowira/shorter/infrastructure/tracing"
 )
 
+// Probe statuses
+const (
+	probeStatusLive     = "live"
+	probeStatusReady    = "ready"
+	probeStatusNotReady = "not_ready"
+)
+
+// ProbeResponse is the response object for the liveness and readiness probes
+type ProbeResponse struct {
+	Status string `json:"status"`
+}
+
+// Request timeouts per route group, kept below the server's 15s write timeout
+const (
+	redirectTimeout = 2 * time.Second
+	statsTimeout    = 5 * time.Second
+	adminTimeout    = 10 * time.Second
+)
+
+// maxBodyLogBytes is how much of each request and response body is logged at debug level
+const maxBodyLogBytes = 512
+
+// legacyAPISunset is when the unversioned /api routes will be removed in favour of /api/v1
+var legacyAPISunset = time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+// ReadinessChecker reports whether the application can serve traffic
+type ReadinessChecker interface {
+	IsReady(ctx context.Context) bool
+}
+
 // Router represents the application router
 type Router struct {
 	handler  *Handler
 	router   *chi.Mux
 	username string
 	password string
+	ready    ReadinessChecker
+	// anonymous lets requests without credentials create URLs, owned through owner tokens
+	anonymous   bool
+	rateLimiter *appMiddleware.RateLimiter
 }
 
 // NewRouter creates a new router
-func NewRouter(handler *Handler, username, password string) *Router {
+func NewRouter(handler *Handler, cfg config.Config) *Router {
 	r := chi.NewRouter()
 
 	// Middleware setup
+	// The connection's IP is recorded before RealIP replaces it with a forwarding
+	// header, so the rate limit cannot be dodged by sending a new header each time
+	r.Use(appMiddleware.ConnectionIP)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
-	r.Use(withRequestID)
-	r.Use(logRequest)
+	if cfg.OTelEnabled {
+		r.Use(appMiddleware.Tracing(tracing.Tracer()))
+	}
+	r.Use(appMiddleware.RequestLogger(maxBodyLogBytes))
+	r.Use(appMiddleware.SecurityHeaders(cfg.HSTSMaxAge))
+	if cfg.MaxRequestBodyBytes > 0 {
+		r.Use(appMiddleware.BodyLimit(cfg.MaxRequestBodyBytes))
+	}
+	r.Use(appMiddleware.Gzip())
+	// Installed even when disabled, since a reload may turn it on
+	rateLimiter := appMiddleware.NewRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	r.Use(rateLimiter.Middleware)
 
 	return &Router{
-		handler:  handler,
-		router:   r,
-		username: username,
-		password: password,
+		handler:     handler,
+		router:      r,
+		rateLimiter: rateLimiter,
+		username:    cfg.AuthUser,
+		password:    cfg.AuthPass,
+		ready:       handler.service,
+		anonymous:   cfg.AllowAnonymousCreate,
 	}
 }
 
+// Reload applies the settings of cfg that take effect without a restart: the rate
+// limit, the CDN max-age of redirects and whether self redirects are prevented
+func (r *Router) Reload(cfg config.Config) {
+	r.rateLimiter.SetLimit(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	r.handler.SetCDNCacheMaxAge(cfg.CDNCacheMaxAge)
+	r.handler.SetPreventSelfRedirect(cfg.PreventSelfRedirect)
+}
+
+// SetReadinessChecker replaces the check behind the readiness probe, which defaults to the shortener service
+func (r *Router) SetReadinessChecker(checker ReadinessChecker) {
+	r.ready = checker
+}
+
 // SetupRoutes configures all application routes
 func (r *Router) SetupRoutes() {
 	appLogger.Info(constant.MsgSettingUpRoutes, appLogger.LoggerInfo{
 		ContextFunction: constant.CtxRouter,
 	})
 
-	creds := map[string]string{
-		r.username: r.password,
-	}
-	// API routes with Basic Auth
-	r.router.With(
-		middleware.BasicAuth("shorter", creds),
-	).Post(constant.RouteCreateShortURL, r.handler.CreateShortURL)
+	// Versioned API
+	r.router.Route(constant.RouteAPIV1Prefix, r.setupV1Routes)
 
-	r.router.With(
-		middleware.BasicAuth("shorter", creds),
-	).Put(constant.RouteUpdateLongURL, r.handler.UpdateLongURL)
+	// Unversioned API, kept until legacyAPISunset
+	r.router.Route(constant.RouteAPIPrefix, func(api chi.Router) {
+		api.Use(appMiddleware.Deprecation(legacyAPISunset))
+		r.setupAPIRoutes(api)
+	})
 
 	// Public routes
-	r.router.Get(constant.RouteShortCodeRedirect, r.handler.RedirectToLongURL)
-	r.router.Get(constant.RouteURLStats, r.handler.GetURLStats)
-	r.router.Get(constant.RouteQRCode, r.handler.GenerateQRCode)
-
-	// Healthcheck
-	r.router.Get(constant.RouteHealthcheck, func(w http.ResponseWriter, r *http.Request) {
-		appLogger.CtxDebug(r.Context(), constant.MsgHealthcheckRequest, appLogger.LoggerInfo{
-			ContextFunction: constant.CtxRouter,
-		})
-
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(constant.MsgHealthy))
-	})
+	redirects := r.router.With(appMiddleware.WithTimeout(redirectTimeout))
+	redirects.With(appMiddleware.NoIndex).Get(constant.RouteShortCodeRedirect, r.handler.RedirectToLongURL)
+	redirects.Get(constant.RouteProtectedURL, r.handler.ProtectedURL)
+	redirects.Post(constant.RouteProtectedURL, r.handler.ProtectedURL)
+	redirects.Get(constant.RoutePreviewURL, r.handler.PreviewURL)
+
+	// Crawler rules
+	r.router.Get(constant.RouteRobotsTxt, r.handler.RobotsTxt)
+
+	// Healthcheck and Kubernetes probes
+	r.router.Get(constant.RouteHealthcheck, r.handler.Health)
+	r.router.Get(constant.RouteLive, r.live)
+	r.router.Get(constant.RouteReady, r.readiness)
+}
+
+// live answers the liveness probe; it succeeds whenever the process can serve HTTP
+func (r *Router) live(w http.ResponseWriter, req *http.Request) {
+	WriteJSON(w, ProbeResponse{Status: probeStatusLive}, http.StatusOK)
+}
+
+// readiness answers the readiness probe, failing while the database is unreachable
+func (r *Router) readiness(w http.ResponseWriter, req *http.Request) {
+	if !r.ready.IsReady(req.Context()) {
+		WriteJSON(w, ProbeResponse{Status: probeStatusNotReady}, http.StatusServiceUnavailable)
+		return
+	}
+	WriteJSON(w, ProbeResponse{Status: probeStatusReady}, http.StatusOK)
 }
 
 // ServeHTTP implements the http.Handler interface
@@ -1,54 +1,33 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/prasetyowira/shorter/config"
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/db"
+	"github.com/prasetyowira/shorter/infrastructure/qrcode"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 )
 
-// MockHandler implements api.Handler interface for testing
-type MockHandler struct {
-	mock.Mock
-}
-
-func (m *MockHandler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
-	m.Called(w, r)
-	w.WriteHeader(http.StatusCreated)
-}
-
-func (m *MockHandler) RedirectToLongURL(w http.ResponseWriter, r *http.Request) {
-	m.Called(w, r)
-	w.WriteHeader(http.StatusFound)
-}
-
-func (m *MockHandler) GetURLStats(w http.ResponseWriter, r *http.Request) {
-	m.Called(w, r)
-	w.WriteHeader(http.StatusOK)
-}
-
-func (m *MockHandler) GenerateQRCode(w http.ResponseWriter, r *http.Request) {
-	m.Called(w, r)
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "image/png")
-	w.Write([]byte("fake-qr-code-data"))
-}
-
 func TestNewRouter(t *testing.T) {
 	// Arrange
-	mockHandler := new(MockHandler)
+	handler := newTestHandler(db.NewMemoryRepository(), new(MockQRGenerator))
 	username := "admin"
 	password := "password"
 	
 	// Act
-	router := NewRouter(mockHandler, username, password)
+	router := NewRouter(handler, config.Config{AuthUser: username, AuthPass: password})
 	
 	// Assert
 	assert.NotNil(t, router)
-	assert.Equal(t, mockHandler, router.handler)
+	assert.Equal(t, handler, router.handler)
 	assert.NotNil(t, router.router)
 	assert.IsType(t, &chi.Mux{}, router.router)
 	assert.Equal(t, username, router.username)
@@ -57,8 +36,11 @@ func TestNewRouter(t *testing.T) {
 
 func TestRouter_SetupRoutes(t *testing.T) {
 	// Arrange
-	mockHandler := new(MockHandler)
-	router := NewRouter(mockHandler, "admin", "password")
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+	mockQRGenerator := new(MockQRGenerator)
+	mockQRGenerator.On("GenerateQRCode", "abc123", 256, qrcode.DefaultQROptions()).Return([]byte("fake-qr-code-data"), nil).Once()
+	router := NewRouter(newTestHandler(repo, mockQRGenerator), config.Config{AuthUser: "admin", AuthPass: "password"})
 	
 	// Act
 	router.SetupRoutes()
@@ -70,21 +52,18 @@ func TestRouter_SetupRoutes(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 	
 	// Testing GET /{shortCode}
-	mockHandler.On("RedirectToLongURL", mock.Anything, mock.Anything).Once()
 	req = httptest.NewRequest("GET", "/abc123", nil)
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusFound, w.Code)
 	
 	// Testing GET /api/urls/{shortCode}/stats
-	mockHandler.On("GetURLStats", mock.Anything, mock.Anything).Once()
 	req = httptest.NewRequest("GET", "/api/urls/abc123/stats", nil)
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 	
 	// Testing GET /api/urls/{shortCode}/qrcode
-	mockHandler.On("GenerateQRCode", mock.Anything, mock.Anything).Once()
 	req = httptest.NewRequest("GET", "/api/urls/abc123/qrcode", nil)
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -96,8 +75,65 @@ func TestRouter_SetupRoutes(t *testing.T) {
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Equal(t, "Healthy", w.Body.String())
+
+	var health HealthResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, shortener.HealthOK, health.Status)
 	
 	// Assert that all expected calls were made
-	mockHandler.AssertExpectations(t)
-} 
\ No newline at end of file
+	mockQRGenerator.AssertExpectations(t)
+}
+
+func TestRouter_Reload(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com/page", ShortCode: "otherhost"})
+	seedURL(t, repo, &shortener.URL{LongURL: "http://localhost:8080/other", ShortCode: "samehost"})
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please"})
+	router.SetupRoutes()
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	// Before the reload redirects are unlimited and self redirects allowed
+	w := serve("/otherhost")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Empty(t, w.Header().Get(constant.HeaderRateLimitLimit))
+	assert.Equal(t, "max-age=3600", w.Header().Get(constant.HeaderSurrogateControl))
+	assert.Equal(t, http.StatusFound, serve("/samehost").Code)
+
+	// Act
+	router.Reload(config.Config{RateLimitRPS: 1, RateLimitBurst: 5, CDNCacheMaxAge: 60, PreventSelfRedirect: true})
+
+	// Assert
+	w = serve("/otherhost")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "5", w.Header().Get(constant.HeaderRateLimitLimit))
+	assert.Equal(t, "max-age=60", w.Header().Get(constant.HeaderSurrogateControl))
+	assert.Equal(t, http.StatusBadRequest, serve("/samehost").Code)
+}
+
+func TestRouter_RateLimitIgnoresForwardingHeaders(t *testing.T) {
+	// Arrange
+	repo := db.NewMemoryRepository()
+	seedURL(t, repo, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"})
+	router := NewRouter(newTestHandler(repo, nil), config.Config{AuthUser: "shorter-admin", AuthPass: "change-me-please", RateLimitRPS: 1, RateLimitBurst: 2})
+	router.SetupRoutes()
+
+	// Act - one connection claiming to forward for a new client on every request
+	var statuses []int
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set(constant.HeaderForwardedFor, fmt.Sprintf("203.0.113.%d", i+1))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		statuses = append(statuses, w.Code)
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusFound, http.StatusFound, http.StatusTooManyRequests, http.StatusTooManyRequests,
+		http.StatusTooManyRequests, http.StatusTooManyRequests}, statuses)
+}
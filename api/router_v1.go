@@ -0,0 +1,73 @@
+package api
+
+import (
+	"github.com/go-chi/chi/v5"
+	appMiddleware "github.com/prasetyowira/shorter/api/middleware"
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+)
+
+// setupV1Routes registers the versioned API under RouteAPIV1Prefix
+func (r *Router) setupV1Routes(v1 chi.Router) {
+	r.setupAPIRoutes(v1)
+}
+
+// setupAPIRoutes registers the API routes on api, which is mounted under an API prefix
+func (r *Router) setupAPIRoutes(api chi.Router) {
+	// URL management open to anonymous owners when enabled, Basic Auth otherwise
+	api.Group(func(owner chi.Router) {
+		owner.Use(r.ownerAuth)
+		owner.Post(constant.RouteCreateShortURL, r.handler.CreateShortURL)
+		owner.Put(constant.RouteUpdateLongURL, r.handler.UpdateLongURL)
+		owner.Delete(constant.RouteDeleteURL, r.handler.DeleteURL)
+	})
+
+	// API routes with Basic Auth
+	api.Group(func(auth chi.Router) {
+		auth.Use(r.basicAuth)
+
+		auth.Group(func(user chi.Router) {
+			user.Use(RequireRole(shortener.RoleUser))
+			user.Get(constant.RouteURLDetails, r.handler.GetURLDetails)
+			user.Put(constant.RouteRenameShortCode, r.handler.RenameShortCode)
+			user.Post(constant.RouteCloneURL, r.handler.CloneURL)
+			user.Post(constant.RouteImport, r.handler.ImportURLs)
+			user.Post(constant.RouteBulkDeleteURLs, r.handler.BulkDeleteURLs)
+			user.Post(constant.RouteWebhooks, r.handler.CreateWebhook)
+			user.Post(constant.RouteURLTags, r.handler.AddTag)
+			user.Delete(constant.RouteURLTag, r.handler.RemoveTag)
+			user.Get(constant.RouteExportClicks, r.handler.ExportClicks)
+		})
+
+		// Admin-only routes
+		auth.Group(func(admin chi.Router) {
+			admin.Use(RequireRole(shortener.RoleAdmin))
+			// The export streams every URL, so it is not buffered behind a timeout
+			admin.Get(constant.RouteExport, r.handler.ExportURLs)
+
+			admin.Group(func(timed chi.Router) {
+				timed.Use(appMiddleware.WithTimeout(adminTimeout))
+				timed.Get(constant.RouteSearchURLs, r.handler.SearchURLs)
+				timed.Get(constant.RouteRecentURLs, r.handler.ListRecentURLs)
+				timed.Get(constant.RouteTopURLs, r.handler.GetTopURLs)
+				timed.Get(constant.RouteTrendingURLs, r.handler.GetTrendingURLs)
+				timed.Get(constant.RouteUsers, r.handler.ListUsers)
+				timed.Post(constant.RouteUsers, r.handler.CreateUser)
+				timed.Put(constant.RouteAdminLogLevel, r.handler.SetLogLevel)
+				timed.Get(constant.RouteAdminCacheStats, r.handler.CacheStats)
+				timed.Get(constant.RouteURLAudit, r.handler.GetAuditLog)
+			})
+		})
+	})
+
+	// Public API routes
+	api.Group(func(stats chi.Router) {
+		stats.Use(appMiddleware.WithTimeout(statsTimeout))
+		stats.Get(constant.RouteURLStats, r.handler.GetURLStats)
+		stats.Get(constant.RouteURLVisits, r.handler.GetVisits)
+		stats.Get(constant.RouteURLReferers, r.handler.GetReferers)
+		stats.Get(constant.RouteURLDeviceStats, r.handler.GetDeviceStats)
+		stats.Get(constant.RouteURLSparkline, r.handler.GetSparkline)
+		stats.Get(constant.RouteQRCode, r.handler.GenerateQRCode)
+	})
+}
@@ -2,29 +2,87 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"github.com/prasetyowira/shorter/api"
+	appMiddleware "github.com/prasetyowira/shorter/api/middleware"
 	"github.com/prasetyowira/shorter/config"
 	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
 	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/blacklist"
 	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/prasetyowira/shorter/infrastructure/codegen"
 	"github.com/prasetyowira/shorter/infrastructure/db"
+	"github.com/prasetyowira/shorter/infrastructure/debug"
+	"github.com/prasetyowira/shorter/infrastructure/geo"
+	"github.com/prasetyowira/shorter/infrastructure/jobs"
 	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
 	"github.com/prasetyowira/shorter/infrastructure/qrcode"
+	"github.com/prasetyowira/shorter/infrastructure/security"
+	"github.com/prasetyowira/shorter/infrastructure/tracing"
+	"github.com/prasetyowira/shorter/infrastructure/useragent"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 )
 
 func main() {
-	// Load configuration from environment variables
-	cfg := config.LoadConfig()
+	migrateOnly := flag.Bool("migrate", false, "apply pending database migrations and exit")
+	flag.Parse()
+
+	// Load configuration from environment variables, merged over CONFIG_FILE when set
+	cfg, loadErr := config.Load()
 
 	// Initialize logger based on environment
 	isProduction := cfg.LogLevel == "INFO"
 	appLogger.Initialize(isProduction)
 	defer appLogger.Close()
+
+	if loadErr != nil {
+		appLogger.Fatal(constant.MsgInvalidConfig, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAppInvalidConfig,
+				Message: loadErr.Error(),
+				Type:    constant.ErrTypeApp,
+			},
+		})
+	}
+
+	// Refuse to start with an invalid configuration, reporting every problem
+	if err := cfg.Validate(); err != nil {
+		validationErrs := err.(interface{ Unwrap() []error }).Unwrap()
+		for _, validationErr := range validationErrs {
+			appLogger.Error(constant.MsgInvalidConfig, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAppInvalidConfig,
+					Message: validationErr.Error(),
+					Type:    constant.ErrTypeApp,
+				},
+			})
+		}
+		appLogger.Fatal(constant.MsgInvalidConfig, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Data: map[string]interface{}{
+				constant.DataErrors: len(validationErrs),
+			},
+		})
+	}
+	for _, warning := range cfg.Warnings() {
+		appLogger.Warn(constant.MsgRiskyConfig, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Data: map[string]interface{}{
+				constant.DataWarning: warning,
+			},
+		})
+	}
+
 	appLogger.Info(constant.MsgApplicationStarting, appLogger.LoggerInfo{
 		ContextFunction: constant.CtxMain,
 		Data: map[string]interface{}{
@@ -34,9 +92,54 @@ func main() {
 		},
 	})
 
-	cacheLRU := cache.NewNamespaceLRU(cfg.CacheSize)
-	//Create SQLite repository
-	repository, err := db.NewSQLiteRepository(cfg.DatabaseURL, cacheLRU)
+	// Initialize tracing; spans are only exported when OTEL_ENABLED is set
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Enabled:     cfg.OTelEnabled,
+		ServiceName: cfg.OTelServiceName,
+		Endpoint:    cfg.OTelEndpoint,
+	})
+	if err != nil {
+		appLogger.Fatal(constant.MsgFailedToInitTracing, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAppTracingInit,
+				Message: err.Error(),
+				Type:    constant.ErrTypeApp,
+			},
+		})
+	}
+
+	memCache, err := cache.New(cache.Options{Capacity: cfg.CacheSize, EvictionPolicy: cfg.CacheEvictionPolicy})
+	if err != nil {
+		appLogger.Fatal(constant.MsgInvalidConfig, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAppInvalidConfig,
+				Message: err.Error(),
+				Type:    constant.ErrTypeApp,
+			},
+		})
+	}
+	if cfg.CachePurge > 0 {
+		stopPurge := memCache.StartPurge(cfg.CachePurge)
+		defer stopPurge()
+	}
+	// Create the repository; mysql:// URLs select MySQL, anything else is a SQLite path
+	var repository db.Repository
+	dbInfo := map[string]interface{}{}
+	if strings.HasPrefix(cfg.DatabaseURL, db.MySQLScheme) {
+		// The URL carries the password, so it is not logged
+		repository, err = db.NewMySQLRepository(cfg.DatabaseURL, memCache)
+	} else {
+		dbInfo[constant.DataDBPath] = cfg.DatabaseURL
+		repository, err = db.NewSQLiteRepositoryWithOptions(cfg.DatabaseURL, memCache, db.SQLiteOptions{
+			WALMode:         cfg.SQLiteWALMode,
+			MaxOpenConns:    cfg.DBMaxOpenConns,
+			MaxIdleConns:    cfg.DBMaxIdleConns,
+			ConnMaxLifetime: cfg.DBConnMaxLifetime,
+			QueryTimeout:    cfg.DBQueryTimeout,
+		})
+	}
 	if err != nil {
 		appLogger.Fatal(constant.MsgFailedToInitDB, appLogger.LoggerInfo{
 			ContextFunction: constant.CtxMain,
@@ -45,24 +148,159 @@ func main() {
 				Message: err.Error(),
 				Type:    constant.ErrTypeApp,
 			},
-			Data: map[string]interface{}{
-				constant.DataDBPath: cfg.DatabaseURL,
-			},
+			Data: dbInfo,
 		})
 	}
 	defer repository.Close()
 
+	// Opening the repository applies pending migrations, so there is nothing left to do
+	if *migrateOnly {
+		appLogger.Info(constant.MsgMigrationsApplied, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Data:            dbInfo,
+		})
+		return
+	}
+
+	// Flush visit counts in the background instead of on every redirect
+	visitQueue := db.NewVisitQueue(repository, db.DefaultVisitBufferSize)
+
+	// Audit entries and webhook deliveries are made by handlers of URL lifecycle events
+	eventBus := events.NewEventBus()
+
+	serviceOpts := shortener.ServiceOptions{
+		ShortCodeLength:         cfg.ShortCodeLength,
+		VisitQueue:              visitQueue,
+		SSRFGuard:               security.NewGuard(),
+		Webhooks:                jobs.NewWebhookSender(),
+		CacheTTL:                cfg.CacheTTL,
+		BotDetector:             useragent.NewBotDetector(strings.Split(cfg.BotUserAgents, ",")...),
+		Events:                  eventBus,
+		OwnerTokenTTL:           cfg.OwnerTokenTTL,
+		CaseSensitive:           cfg.CaseSensitiveCodes,
+		ValidateURLReachability: cfg.ValidateURLReachability,
+	}
+	switch cfg.ShortCodeStyle {
+	case config.ShortCodeStyleWordPair:
+		serviceOpts.CodeGenerator = codegen.NewWordPair()
+	case config.ShortCodeStyleBase58:
+		length := cfg.ShortCodeLength
+		if length == 0 {
+			length = shortener.DefaultShortCodeLength
+		}
+		serviceOpts.CodeGenerator = codegen.NewLowercaseBase58(length)
+		if cfg.CaseSensitiveCodes {
+			serviceOpts.CodeGenerator = codegen.NewBase58(length)
+		}
+	}
+
+	// Load the domain blacklist when configured
+	if cfg.BlacklistPath != "" {
+		urlBlacklist, err := blacklist.NewFileBlacklist(cfg.BlacklistPath)
+		if err != nil {
+			appLogger.Fatal(constant.MsgFailedToLoadBlacklist, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAppBlacklistLoad,
+					Message: err.Error(),
+					Type:    constant.ErrTypeApp,
+				},
+				Data: map[string]interface{}{
+					constant.DataPath: cfg.BlacklistPath,
+				},
+			})
+		}
+		serviceOpts.Blacklist = urlBlacklist
+	}
+
 	// Create shortener service
-	service := shortener.NewService(repository, cacheLRU)
+	service := shortener.NewService(repository, memCache, serviceOpts)
+
+	// Load the most visited URLs so the first redirects after a restart skip the
+	// database; WarmCache logs failures, which only leave the cache to fill on demand
+	warmCtx, stopWarm := context.WithCancel(context.Background())
+	defer stopWarm()
+	if cfg.CacheWarmLimit > 0 {
+		go service.WarmCache(warmCtx, cfg.CacheWarmLimit)
+	}
+
+	// Purge expired URLs in the background
+	cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+	cleanupDone := make(chan struct{})
+	if cfg.CleanupInterval > 0 {
+		cleaner := jobs.NewExpiryCleanerJob(repository, cfg.CleanupInterval)
+		go func() {
+			defer close(cleanupDone)
+			cleaner.Run(cleanupCtx)
+		}()
+	} else {
+		close(cleanupDone)
+	}
 
 	// Create QR code generator
 	qrGenerator := qrcode.NewGenerator(cfg.BaseURL)
 
 	// Create API handler and router
 	handler := api.NewHandler(service, qrGenerator, cfg.BaseURL)
-	router := api.NewRouter(handler, cfg.AuthUser, cfg.AuthPass)
+	handler.SetCDNCacheMaxAge(cfg.CDNCacheMaxAge)
+	handler.SetPreventSelfRedirect(cfg.PreventSelfRedirect)
+
+	// Route visitors by country when a GeoIP database is configured
+	if cfg.GeoIPDBPath != "" {
+		geoResolver, err := geo.NewMaxMindResolver(cfg.GeoIPDBPath)
+		if err != nil {
+			appLogger.Fatal(constant.MsgFailedToLoadGeoIP, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAppGeoIPLoad,
+					Message: err.Error(),
+					Type:    constant.ErrTypeApp,
+				},
+				Data: map[string]interface{}{
+					constant.DataPath: cfg.GeoIPDBPath,
+				},
+			})
+		}
+		defer geoResolver.Close()
+		handler.SetGeoResolver(geoResolver)
+	}
+
+	router := api.NewRouter(handler, cfg)
 	router.SetupRoutes()
 
+	// Reload the configuration on SIGHUP. LOG_LEVEL and the settings applied by
+	// Router.Reload take effect immediately; the rest apply on the next restart.
+	reloader := config.NewReloader(cfg)
+	stopReload := reloader.Start(func(reloaded config.Config, err error) {
+		if err != nil {
+			appLogger.Error(constant.MsgConfigReloadRejected, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAppInvalidConfig,
+					Message: err.Error(),
+					Type:    constant.ErrTypeApp,
+				},
+			})
+			return
+		}
+
+		// Mirror Initialize: INFO is production, any other level logs debug entries
+		logLevel := "debug"
+		if reloaded.LogLevel == "INFO" {
+			logLevel = "info"
+		}
+		appLogger.SetLevel(logLevel)
+		router.Reload(reloader.Get())
+
+		appLogger.Info(constant.MsgConfigReloaded, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Data: map[string]interface{}{
+				constant.DataEnvironment: reloader.Get().LogLevel,
+			},
+		})
+	})
+	defer stopReload()
+
 	// Configure HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -78,10 +316,17 @@ func main() {
 			ContextFunction: constant.CtxMain,
 			Data: map[string]interface{}{
 				constant.DataPort: cfg.Port,
+				constant.DataTLS:  cfg.TLSEnabled,
 			},
 		})
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.TLSEnabled {
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			appLogger.Fatal(constant.MsgServerFailedToStart, appLogger.LoggerInfo{
 				ContextFunction: constant.CtxMain,
 				Error: &appLogger.CustomError{
@@ -96,6 +341,77 @@ func main() {
 		}
 	}()
 
+	// Send plain HTTP requests to the HTTPS server
+	var redirectServer *http.Server
+	if cfg.TLSEnabled && cfg.HTTPRedirectPort > 0 {
+		redirectServer = &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.HTTPRedirectPort),
+			Handler:      appMiddleware.HTTPSRedirect(cfg.Port),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+
+		go func() {
+			appLogger.Info(constant.MsgRedirectServerStarting, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Data: map[string]interface{}{
+					constant.DataPort: cfg.HTTPRedirectPort,
+				},
+			})
+
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Fatal(constant.MsgServerFailedToStart, appLogger.LoggerInfo{
+					ContextFunction: constant.CtxMain,
+					Error: &appLogger.CustomError{
+						Code:    constant.ErrCodeAppServerStart,
+						Message: err.Error(),
+						Type:    constant.ErrTypeApp,
+					},
+					Data: map[string]interface{}{
+						constant.DataPort: cfg.HTTPRedirectPort,
+					},
+				})
+			}
+		}()
+	}
+
+	// Serve pprof on its own listener in development mode
+	var debugServer *http.Server
+	if cfg.DebugServerEnabled() {
+		debugServer = &http.Server{
+			Addr:        net.JoinHostPort(cfg.DebugHost, strconv.Itoa(cfg.DebugPort)),
+			Handler:     debug.PprofHandler(),
+			ReadTimeout: 5 * time.Second,
+			// No write timeout: CPU profiles and traces run for as long as the caller asks
+			IdleTimeout: 60 * time.Second,
+		}
+
+		go func() {
+			appLogger.Info(constant.MsgDebugServerStarting, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Data: map[string]interface{}{
+					constant.DataHost: cfg.DebugHost,
+					constant.DataPort: cfg.DebugPort,
+				},
+			})
+
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Fatal(constant.MsgServerFailedToStart, appLogger.LoggerInfo{
+					ContextFunction: constant.CtxMain,
+					Error: &appLogger.CustomError{
+						Code:    constant.ErrCodeAppServerStart,
+						Message: err.Error(),
+						Type:    constant.ErrTypeApp,
+					},
+					Data: map[string]interface{}{
+						constant.DataPort: cfg.DebugPort,
+					},
+				})
+			}
+		}()
+	}
+
 	// Set up graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
@@ -120,6 +436,74 @@ func main() {
 		})
 	}
 
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			appLogger.Error(constant.MsgServerShutdownError, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAppServerShutdown,
+					Message: err.Error(),
+					Type:    constant.ErrTypeApp,
+				},
+				Data: map[string]interface{}{
+					constant.DataPort: cfg.HTTPRedirectPort,
+				},
+			})
+		}
+	}
+
+	if debugServer != nil {
+		if err := debugServer.Shutdown(ctx); err != nil {
+			appLogger.Error(constant.MsgServerShutdownError, appLogger.LoggerInfo{
+				ContextFunction: constant.CtxMain,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeAppServerShutdown,
+					Message: err.Error(),
+					Type:    constant.ErrTypeApp,
+				},
+				Data: map[string]interface{}{
+					constant.DataPort: cfg.DebugPort,
+				},
+			})
+		}
+	}
+
+	stopCleanup()
+	<-cleanupDone
+
+	if err := visitQueue.Shutdown(ctx); err != nil {
+		appLogger.Error(constant.MsgVisitQueueShutdownError, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAppServerShutdown,
+				Message: err.Error(),
+				Type:    constant.ErrTypeApp,
+			},
+		})
+	}
+
+	if err := eventBus.Shutdown(ctx); err != nil {
+		appLogger.Error(constant.MsgEventBusShutdownError, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAppServerShutdown,
+				Message: err.Error(),
+				Type:    constant.ErrTypeApp,
+			},
+		})
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		appLogger.Error(constant.MsgTracingShutdownError, appLogger.LoggerInfo{
+			ContextFunction: constant.CtxMain,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeAppServerShutdown,
+				Message: err.Error(),
+				Type:    constant.ErrTypeApp,
+			},
+		})
+	}
+
 	appLogger.Info(constant.MsgServerStopped, appLogger.LoggerInfo{
 		ContextFunction: constant.CtxMain,
 	})
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the versioned API the client talks to
+const apiPrefix = "/api/v1"
+
+// client calls the shorter HTTP API with Basic Auth credentials
+type client struct {
+	baseURL  string
+	user     string
+	password string
+	http     *http.Client
+}
+
+// newClient creates a client for the server at baseURL
+func newClient(baseURL, user, password string, transport http.RoundTripper) *client {
+	return &client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		user:     user,
+		password: password,
+		http:     &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}
+}
+
+// apiError is a non-2xx response from the API
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("server responded %d: %s", e.Status, e.Message)
+}
+
+// shortURL is the response of the create endpoint
+type shortURL struct {
+	FullURL   string `json:"full_url"`
+	ShortCode string `json:"short_code"`
+	LongURL   string `json:"long_url"`
+}
+
+// urlStats is the response of the stats endpoint
+type urlStats struct {
+	FullURL   string `json:"full_url"`
+	ShortCode string `json:"short_code"`
+	Visits    uint   `json:"visits"`
+}
+
+// urlList is the response of the search endpoint used to list URLs
+type urlList struct {
+	URLs []struct {
+		ShortCode string    `json:"short_code"`
+		LongURL   string    `json:"long_url"`
+		Visits    uint      `json:"visits"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"urls"`
+	Total int `json:"total"`
+}
+
+// bulkDeleteResult is the response of the bulk delete endpoint
+type bulkDeleteResult struct {
+	Deleted   int      `json:"deleted"`
+	NotFound  []string `json:"not_found"`
+	Forbidden []string `json:"forbidden"`
+}
+
+// create shortens longURL, using customCode as the short code when it is not empty
+func (c *client) create(longURL, customCode string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"long_url": longURL, "custom_short_url": customCode})
+	if err != nil {
+		return nil, err
+	}
+	return c.do(http.MethodPost, "/urls", body)
+}
+
+// list returns up to limit URLs
+func (c *client) list(limit int) ([]byte, error) {
+	return c.do(http.MethodGet, "/urls?limit="+strconv.Itoa(limit), nil)
+}
+
+// stats returns the visit count of code
+func (c *client) stats(code string) ([]byte, error) {
+	return c.do(http.MethodGet, "/urls/"+url.PathEscape(code)+"/stats", nil)
+}
+
+// delete soft-deletes code
+func (c *client) delete(code string) ([]byte, error) {
+	body, err := json.Marshal(map[string][]string{"short_codes": {code}})
+	if err != nil {
+		return nil, err
+	}
+	return c.do(http.MethodPost, "/urls/bulk-delete", body)
+}
+
+// do sends a request to path under the API prefix and returns the body of a 2xx response
+func (c *client) do(method, path string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &apiError{Status: resp.StatusCode, Message: errorMessage(respBody)}
+	}
+	return respBody, nil
+}
+
+// errorMessage extracts the message of an API error body, which is JSON for
+// API errors and plain text for others such as unmatched routes
+func errorMessage(body []byte) string {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
+		return errResp.Error
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,238 @@
+// Command shorter-cli manages short URLs through the shorter HTTP API.
+//
+// Usage:
+//
+//	shorter-cli create --url=https://example.com [--custom=xyz] [-json]
+//	shorter-cli list [--limit=20] [-json]
+//	shorter-cli stats --code=abc [-json]
+//	shorter-cli delete --code=abc [-json]
+//
+// The server and credentials come from SHORTER_BASE_URL (default
+// http://localhost:8080), SHORTER_USER and SHORTER_PASS.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"text/tabwriter"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// defaultBaseURL is the server used when SHORTER_BASE_URL is unset
+const defaultBaseURL = "http://localhost:8080"
+
+// usage lists the subcommands
+const usage = `usage: shorter-cli <command> [flags]
+
+commands:
+  create  --url=<long url> [--custom=<code>]  create a short URL
+  list    [--limit=20]                        list short URLs
+  stats   --code=<code>                       show the visits of a short URL
+  delete  --code=<code>                       delete a short URL
+
+Every command accepts -json to print the raw API response.
+The server and credentials come from SHORTER_BASE_URL, SHORTER_USER and SHORTER_PASS.
+`
+
+// errUsage reports a command line the CLI cannot run; flag has already printed the details
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, http.DefaultTransport, os.Stdout, os.Stderr))
+}
+
+// run executes the command in args and returns the process exit code
+func run(args []string, getenv func(string) string, transport http.RoundTripper, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	baseURL := getenv("SHORTER_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	c := newClient(baseURL, getenv("SHORTER_USER"), getenv("SHORTER_PASS"), transport)
+
+	var err error
+	switch args[0] {
+	case "create":
+		err = runCreate(c, args[1:], stdout, stderr)
+	case "list":
+		err = runList(c, args[1:], stdout, stderr)
+	case "stats":
+		err = runStats(c, args[1:], stdout, stderr)
+	case "delete":
+		err = runDelete(c, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		fmt.Fprintln(stderr, "error:", err)
+		return exitError
+	}
+}
+
+// newFlagSet creates the flag set of a subcommand with the shared -json flag
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *bool) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	jsonOutput := flags.Bool("json", false, "print the raw JSON response")
+	return flags, jsonOutput
+}
+
+// parseFlags parses args, turning parse failures and missing required string flags into errUsage
+func parseFlags(flags *flag.FlagSet, args []string, required map[string]*string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	for name, value := range required {
+		if *value == "" {
+			fmt.Fprintf(flags.Output(), "%s: --%s is required\n", flags.Name(), name)
+			flags.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+func runCreate(c *client, args []string, stdout, stderr io.Writer) error {
+	flags, jsonOutput := newFlagSet("create", stderr)
+	longURL := flags.String("url", "", "long URL to shorten (required)")
+	custom := flags.String("custom", "", "custom short code")
+	if err := parseFlags(flags, args, map[string]*string{"url": longURL}); err != nil {
+		return err
+	}
+
+	body, err := c.create(*longURL, *custom)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeRaw(stdout, body)
+	}
+
+	var created shortURL
+	if err := json.Unmarshal(body, &created); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, created.FullURL)
+	return nil
+}
+
+func runList(c *client, args []string, stdout, stderr io.Writer) error {
+	flags, jsonOutput := newFlagSet("list", stderr)
+	limit := flags.Int("limit", 20, "maximum number of URLs to list")
+	if err := parseFlags(flags, args, nil); err != nil {
+		return err
+	}
+
+	body, err := c.list(*limit)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeRaw(stdout, body)
+	}
+
+	var listed urlList
+	if err := json.Unmarshal(body, &listed); err != nil {
+		return err
+	}
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CODE\tVISITS\tCREATED\tLONG URL")
+	for _, u := range listed.URLs {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\n", u.ShortCode, u.Visits, u.CreatedAt.Format("2006-01-02"), u.LongURL)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%d of %d URLs\n", len(listed.URLs), listed.Total)
+	return nil
+}
+
+func runStats(c *client, args []string, stdout, stderr io.Writer) error {
+	flags, jsonOutput := newFlagSet("stats", stderr)
+	code := flags.String("code", "", "short code (required)")
+	if err := parseFlags(flags, args, map[string]*string{"code": code}); err != nil {
+		return err
+	}
+
+	body, err := c.stats(*code)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeRaw(stdout, body)
+	}
+
+	var stats urlStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s: %d visits (%s)\n", stats.ShortCode, stats.Visits, stats.FullURL)
+	return nil
+}
+
+func runDelete(c *client, args []string, stdout, stderr io.Writer) error {
+	flags, jsonOutput := newFlagSet("delete", stderr)
+	code := flags.String("code", "", "short code (required)")
+	if err := parseFlags(flags, args, map[string]*string{"code": code}); err != nil {
+		return err
+	}
+
+	body, err := c.delete(*code)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeRaw(stdout, body)
+	}
+
+	var result bulkDeleteResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	switch {
+	case slices.Contains(result.NotFound, *code):
+		return fmt.Errorf("short code %s not found", *code)
+	case slices.Contains(result.Forbidden, *code):
+		return fmt.Errorf("not allowed to delete %s", *code)
+	}
+	fmt.Fprintf(stdout, "deleted %s\n", *code)
+	return nil
+}
+
+// writeRaw prints an API response body followed by a newline
+func writeRaw(stdout io.Writer, body []byte) error {
+	if _, err := stdout.Write(body); err != nil {
+		return err
+	}
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		_, err := fmt.Fprintln(stdout)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTripFunc is an http.RoundTripper backed by a function
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// recordedRequest is what the fake transport saw of a request
+type recordedRequest struct {
+	Method string
+	URL    string
+	Body   string
+	User   string
+	Pass   string
+}
+
+// fakeTransport answers every request with status and body, recording the request
+func fakeTransport(status int, body string, recorded *recordedRequest) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorded.Method = req.Method
+		recorded.URL = req.URL.String()
+		if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			recorded.Body = string(data)
+		}
+		recorded.User, recorded.Pass, _ = req.BasicAuth()
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+// testEnv is the environment the CLI runs with in tests
+func testEnv(key string) string {
+	return map[string]string{
+		"SHORTER_BASE_URL": "https://sho.rt/",
+		"SHORTER_USER":     "shorter-admin",
+		"SHORTER_PASS":     "s3cret-password",
+	}[key]
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		status          int
+		responseBody    string
+		expectedExit    int
+		expectedRequest recordedRequest
+		expectedStdout  string
+		expectedStderr  string
+	}{
+		{
+			name:         "Create",
+			args:         []string{"create", "--url=https://example.com/long", "--custom=xyz"},
+			status:       http.StatusCreated,
+			responseBody: `{"full_url":"https://sho.rt/xyz","short_code":"xyz","long_url":"https://example.com/long","tags":[]}`,
+			expectedExit: exitOK,
+			expectedRequest: recordedRequest{
+				Method: "POST",
+				URL:    "https://sho.rt/api/v1/urls",
+				Body:   `{"custom_short_url":"xyz","long_url":"https://example.com/long"}`,
+			},
+			expectedStdout: "https://sho.rt/xyz\n",
+		},
+		{
+			name:            "Create JSON",
+			args:            []string{"create", "-json", "--url=https://example.com/long"},
+			status:          http.StatusCreated,
+			responseBody:    `{"full_url":"https://sho.rt/abc123","short_code":"abc123"}`,
+			expectedExit:    exitOK,
+			expectedRequest: recordedRequest{Method: "POST", URL: "https://sho.rt/api/v1/urls", Body: `{"custom_short_url":"","long_url":"https://example.com/long"}`},
+			expectedStdout:  `{"full_url":"https://sho.rt/abc123","short_code":"abc123"}` + "\n",
+		},
+		{
+			name:            "List",
+			args:            []string{"list", "--limit=2"},
+			status:          http.StatusOK,
+			responseBody:    `{"urls":[{"short_code":"abc123","long_url":"https://example.com/1","visits":42,"created_at":"2026-10-01T09:00:00Z"},{"short_code":"xyz","long_url":"https://example.com/2","visits":0,"created_at":"2026-10-02T09:00:00Z"}],"total":5}`,
+			expectedExit:    exitOK,
+			expectedRequest: recordedRequest{Method: "GET", URL: "https://sho.rt/api/v1/urls?limit=2"},
+			expectedStdout: "CODE    VISITS  CREATED     LONG URL\n" +
+				"abc123  42      2026-10-01  https://example.com/1\n" +
+				"xyz     0       2026-10-02  https://example.com/2\n" +
+				"2 of 5 URLs\n",
+		},
+		{
+			name:            "Stats",
+			args:            []string{"stats", "--code=abc123"},
+			status:          http.StatusOK,
+			responseBody:    `{"full_url":"https://sho.rt/abc123","short_code":"abc123","visits":42}`,
+			expectedExit:    exitOK,
+			expectedRequest: recordedRequest{Method: "GET", URL: "https://sho.rt/api/v1/urls/abc123/stats"},
+			expectedStdout:  "abc123: 42 visits (https://sho.rt/abc123)\n",
+		},
+		{
+			name:            "Stats not found",
+			args:            []string{"stats", "--code=missing"},
+			status:          http.StatusNotFound,
+			responseBody:    "404 page not found\n",
+			expectedExit:    exitError,
+			expectedRequest: recordedRequest{Method: "GET", URL: "https://sho.rt/api/v1/urls/missing/stats"},
+			expectedStderr:  "error: server responded 404: 404 page not found\n",
+		},
+		{
+			name:            "Delete",
+			args:            []string{"delete", "--code=abc123"},
+			status:          http.StatusOK,
+			responseBody:    `{"deleted":1,"not_found":null}`,
+			expectedExit:    exitOK,
+			expectedRequest: recordedRequest{Method: "POST", URL: "https://sho.rt/api/v1/urls/bulk-delete", Body: `{"short_codes":["abc123"]}`},
+			expectedStdout:  "deleted abc123\n",
+		},
+		{
+			name:            "Delete not found",
+			args:            []string{"delete", "--code=missing"},
+			status:          http.StatusOK,
+			responseBody:    `{"deleted":0,"not_found":["missing"]}`,
+			expectedExit:    exitError,
+			expectedRequest: recordedRequest{Method: "POST", URL: "https://sho.rt/api/v1/urls/bulk-delete", Body: `{"short_codes":["missing"]}`},
+			expectedStderr:  "error: short code missing not found\n",
+		},
+		{
+			name:            "API error",
+			args:            []string{"create", "--url=ftp://example.com"},
+			status:          http.StatusBadRequest,
+			responseBody:    `{"error":"invalid URL format","code":400,"error_code":"invalid_url"}`,
+			expectedExit:    exitError,
+			expectedRequest: recordedRequest{Method: "POST", URL: "https://sho.rt/api/v1/urls", Body: `{"custom_short_url":"","long_url":"ftp://example.com"}`},
+			expectedStderr:  "error: server responded 400: invalid URL format\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var recorded recordedRequest
+			transport := fakeTransport(tt.status, tt.responseBody, &recorded)
+			var stdout, stderr bytes.Buffer
+
+			// Act
+			exit := run(tt.args, testEnv, transport, &stdout, &stderr)
+
+			// Assert
+			assert.Equal(t, tt.expectedExit, exit)
+			assert.Equal(t, tt.expectedStdout, stdout.String())
+			assert.Equal(t, tt.expectedStderr, stderr.String())
+			tt.expectedRequest.User = "shorter-admin"
+			tt.expectedRequest.Pass = "s3cret-password"
+			assert.Equal(t, tt.expectedRequest, recorded)
+		})
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "No command", args: nil},
+		{name: "Unknown command", args: []string{"rename"}},
+		{name: "Missing required flag", args: []string{"create"}},
+		{name: "Unknown flag", args: []string{"stats", "--code=abc", "--verbose"}},
+		{name: "Malformed limit", args: []string{"list", "--limit=many"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				t.Errorf("unexpected request to %s", req.URL)
+				return nil, errors.New("unexpected request")
+			})
+			var stdout, stderr bytes.Buffer
+
+			// Act
+			exit := run(tt.args, testEnv, transport, &stdout, &stderr)
+
+			// Assert
+			assert.Equal(t, exitUsage, exit)
+			assert.NotEmpty(t, stderr.String())
+		})
+	}
+}
+
+func TestRun_DefaultBaseURL(t *testing.T) {
+	// Arrange
+	var recorded recordedRequest
+	transport := fakeTransport(http.StatusOK, `{"short_code":"abc123","visits":1}`, &recorded)
+	var stdout, stderr bytes.Buffer
+
+	// Act
+	exit := run([]string{"stats", "-code", "abc123"}, func(string) string { return "" }, transport, &stdout, &stderr)
+
+	// Assert
+	assert.Equal(t, exitOK, exit)
+	assert.Equal(t, "http://localhost:8080/api/v1/urls/abc123/stats", recorded.URL)
+	assert.Empty(t, recorded.User, "no credentials are sent when none are configured")
+}
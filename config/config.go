@@ -1,38 +1,331 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"time"
 )
 
+// Bounds for the generated short code length
+const (
+	MinShortCodeLength = 4
+	MaxShortCodeLength = 32
+)
+
+// Short code styles
+const (
+	ShortCodeStyleRandom   = "random"
+	ShortCodeStyleWordPair = "wordpair"
+	ShortCodeStyleBase58   = "base58"
+)
+
+// Cache eviction policies
+const (
+	CacheEvictionLRU = "lru"
+	CacheEvictionLFU = "lfu"
+)
+
+// MinCredentialLength is the minimum length of the Basic Auth username and password
+const MinCredentialLength = 8
+
 type Config struct {
-	Port        int
-	DatabaseURL string
-	AuthUser    string
-	AuthPass    string
-	BaseURL     string
-	CacheSize   int
-	LogLevel    string
+	Port        int    `yaml:"Port" env:"PORT"`
+	TLSEnabled  bool   `yaml:"TLSEnabled" env:"TLS_ENABLED"`
+	TLSCertFile string `yaml:"TLSCertFile" env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"TLSKeyFile" env:"TLS_KEY_FILE"`
+	// HTTPRedirectPort serves redirects from plain HTTP to HTTPS when TLS is enabled; 0 disables it
+	HTTPRedirectPort int `yaml:"HTTPRedirectPort" env:"HTTP_REDIRECT_PORT"`
+	// HSTSMaxAge is how long browsers should insist on HTTPS after a response
+	HSTSMaxAge time.Duration `yaml:"HSTSMaxAge" env:"HSTS_MAX_AGE"`
+	// DebugHost and DebugPort serve pprof in development mode; port 0 disables it
+	DebugHost     string `yaml:"DebugHost" env:"DEBUG_HOST"`
+	DebugPort     int    `yaml:"DebugPort" env:"DEBUG_PORT"`
+	DatabaseURL   string `yaml:"DatabaseURL" env:"DATABASE_URL"`
+	SQLiteWALMode bool   `yaml:"SQLiteWALMode" env:"SQLITE_WAL_MODE"`
+	// Connection pool limits; 0 keeps the repository's default
+	DBMaxOpenConns    int           `yaml:"DBMaxOpenConns" env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns    int           `yaml:"DBMaxIdleConns" env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime time.Duration `yaml:"DBConnMaxLifetime" env:"DB_CONN_MAX_LIFETIME"`
+	DBQueryTimeout    time.Duration `yaml:"DBQueryTimeout" env:"DB_QUERY_TIMEOUT"`
+	AuthUser          string        `yaml:"AuthUser" env:"AUTH_USER"`
+	AuthPass          string        `yaml:"AuthPass" env:"AUTH_PASS"`
+	BaseURL           string        `yaml:"BaseURL" env:"BASE_URL"`
+	CacheSize         int           `yaml:"CacheSize" env:"CACHE_SIZE"`
+	LogLevel          string        `yaml:"LogLevel" env:"LOG_LEVEL"`
+	RateLimitRPS      float64       `yaml:"RateLimitRPS" env:"RATE_LIMIT_RPS"`
+	RateLimitBurst    int           `yaml:"RateLimitBurst" env:"RATE_LIMIT_BURST"`
+	// MaxRequestBodyBytes caps request bodies; 0 disables the limit
+	MaxRequestBodyBytes int64         `yaml:"MaxRequestBodyBytes" env:"MAX_REQUEST_BODY_BYTES"`
+	ShortCodeLength     int           `yaml:"ShortCodeLength" env:"SHORT_CODE_LENGTH"`
+	ShortCodeStyle      string        `yaml:"ShortCodeStyle" env:"SHORT_CODE_STYLE"`
+	BlacklistPath       string        `yaml:"BlacklistPath" env:"BLACKLIST_PATH"`
+	CacheTTL            time.Duration `yaml:"CacheTTL" env:"CACHE_TTL"`
+	CachePurge          time.Duration `yaml:"CachePurge" env:"CACHE_PURGE_INTERVAL"`
+	CleanupInterval     time.Duration `yaml:"CleanupInterval" env:"CLEANUP_INTERVAL"`
+	OTelEnabled         bool          `yaml:"OTelEnabled" env:"OTEL_ENABLED"`
+	OTelServiceName     string        `yaml:"OTelServiceName" env:"OTEL_SERVICE_NAME"`
+	OTelEndpoint        string        `yaml:"OTelEndpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	// BotUserAgents is a comma-separated list of User-Agent substrings treated as
+	// bots in addition to the built-in ones
+	BotUserAgents string `yaml:"BotUserAgents" env:"BOT_USER_AGENTS"`
+	// GeoIPDBPath is a MaxMind GeoLite2 or GeoIP2 Country database enabling geo
+	// rules; empty disables them
+	GeoIPDBPath string `yaml:"GeoIPDBPath" env:"GEOIP_DB_PATH"`
+	// CDNCacheMaxAge is how long, in seconds, CDNs may keep a redirect (Surrogate-Control); 0 leaves it to Cache-Control
+	CDNCacheMaxAge int `yaml:"CDNCacheMaxAge" env:"CDN_CACHE_MAX_AGE"`
+	// AllowAnonymousCreate lets clients without credentials create URLs, which they
+	// update or delete with the owner token returned on creation for OwnerTokenTTL
+	AllowAnonymousCreate bool          `yaml:"AllowAnonymousCreate" env:"ALLOW_ANONYMOUS_CREATE"`
+	OwnerTokenTTL        time.Duration `yaml:"OwnerTokenTTL" env:"OWNER_TOKEN_TTL"`
+	// CacheWarmLimit is how many of the most visited URLs are loaded into the cache
+	// at startup; 0 leaves the cache to fill on demand
+	CacheWarmLimit int `yaml:"CacheWarmLimit" env:"CACHE_WARM_LIMIT"`
+	// CaseSensitiveCodes keeps short codes as typed; otherwise they are stored and
+	// looked up in lowercase
+	CaseSensitiveCodes bool `yaml:"CaseSensitiveCodes" env:"CASE_SENSITIVE_CODES"`
+	// PreventSelfRedirect refuses to redirect to a URL on the host of BaseURL, which
+	// would send visitors round in a loop
+	PreventSelfRedirect bool `yaml:"PreventSelfRedirect" env:"PREVENT_SELF_REDIRECT"`
+	// ValidateURLReachability rejects new URLs whose long URL does not answer a HEAD
+	// request with a success status
+	ValidateURLReachability bool `yaml:"ValidateURLReachability" env:"VALIDATE_URL_REACHABILITY"`
+	// CacheEvictionPolicy picks the cache entry evicted when the cache is full: the
+	// least recently used (lru) or the least frequently used (lfu)
+	CacheEvictionPolicy string `yaml:"CacheEvictionPolicy" env:"CACHE_EVICTION_POLICY"`
 }
 
+// LoadConfig reads the configuration from environment variables, using the defaults for unset ones
 func LoadConfig() Config {
-	port, _ := strconv.Atoi(getEnv("PORT", "8080"))
-	cacheSize, _ := strconv.Atoi(getEnv("CACHE_SIZE", "1000"))
+	return loadConfig(getEnv)
+}
+
+// defaultConfig returns the configuration used when no setting is given
+func defaultConfig() Config {
+	return loadConfig(func(key, defaultValue string) string { return defaultValue })
+}
+
+// loadConfig builds the configuration from setting, which returns the value of
+// an environment variable name or the given default
+func loadConfig(setting func(key, defaultValue string) string) Config {
+	port, _ := strconv.Atoi(setting("PORT", "8080"))
+	tlsEnabled, _ := strconv.ParseBool(setting("TLS_ENABLED", "false"))
+	httpRedirectPort, _ := strconv.Atoi(setting("HTTP_REDIRECT_PORT", "80"))
+	hstsMaxAge := parseDuration(setting("HSTS_MAX_AGE", "8760h"))
+	debugPort, _ := strconv.Atoi(setting("DEBUG_PORT", "6060"))
+	cacheSize, _ := strconv.Atoi(setting("CACHE_SIZE", "1000"))
+	rateLimitRPS, _ := strconv.ParseFloat(setting("RATE_LIMIT_RPS", "10"), 64)
+	rateLimitBurst, _ := strconv.Atoi(setting("RATE_LIMIT_BURST", "20"))
+	maxRequestBodyBytes, _ := strconv.ParseInt(setting("MAX_REQUEST_BODY_BYTES", "1048576"), 10, 64)
+	cacheTTL := parseDuration(setting("CACHE_TTL", "1h"))
+	cachePurge := parseDuration(setting("CACHE_PURGE_INTERVAL", "1m"))
+	cleanupInterval := parseDuration(setting("CLEANUP_INTERVAL", "1h"))
+	otelEnabled, _ := strconv.ParseBool(setting("OTEL_ENABLED", "false"))
+	sqliteWALMode, _ := strconv.ParseBool(setting("SQLITE_WAL_MODE", "true"))
+	dbMaxOpenConns, _ := strconv.Atoi(setting("DB_MAX_OPEN_CONNS", "0"))
+	dbMaxIdleConns, _ := strconv.Atoi(setting("DB_MAX_IDLE_CONNS", "0"))
+	dbConnMaxLifetime := parseDuration(setting("DB_CONN_MAX_LIFETIME", "0"))
+	dbQueryTimeout := parseDuration(setting("DB_QUERY_TIMEOUT", "5s"))
+	cdnCacheMaxAge, _ := strconv.Atoi(setting("CDN_CACHE_MAX_AGE", "3600"))
+	allowAnonymousCreate, _ := strconv.ParseBool(setting("ALLOW_ANONYMOUS_CREATE", "false"))
+	ownerTokenTTL := parseDuration(setting("OWNER_TOKEN_TTL", "720h"))
+	cacheWarmLimit, _ := strconv.Atoi(setting("CACHE_WARM_LIMIT", "0"))
+	caseSensitiveCodes, _ := strconv.ParseBool(setting("CASE_SENSITIVE_CODES", "false"))
+	preventSelfRedirect, _ := strconv.ParseBool(setting("PREVENT_SELF_REDIRECT", "false"))
+	validateURLReachability, _ := strconv.ParseBool(setting("VALIDATE_URL_REACHABILITY", "false"))
+	shortCodeLength, err := strconv.Atoi(setting("SHORT_CODE_LENGTH", "6"))
+	if err != nil {
+		// Not a number; keep it out of range so Validate reports it
+		shortCodeLength = -1
+	}
 
 	return Config{
-		Port:        port,
-		DatabaseURL: getEnv("DATABASE_URL", "shorter.db"),
-		AuthUser:    getEnv("AUTH_USER", "admin"),
-		AuthPass:    getEnv("AUTH_PASS", "password"),
-		BaseURL:     getEnv("BASE_URL", "http://localhost:8080"),
-		CacheSize:   cacheSize,
-		LogLevel:    getEnv("LOG_LEVEL", "INFO"),
+		Port:                    port,
+		TLSEnabled:              tlsEnabled,
+		TLSCertFile:             setting("TLS_CERT_FILE", ""),
+		TLSKeyFile:              setting("TLS_KEY_FILE", ""),
+		HTTPRedirectPort:        httpRedirectPort,
+		HSTSMaxAge:              hstsMaxAge,
+		DebugHost:               setting("DEBUG_HOST", "localhost"),
+		DebugPort:               debugPort,
+		DatabaseURL:             setting("DATABASE_URL", "shorter.db"),
+		SQLiteWALMode:           sqliteWALMode,
+		DBMaxOpenConns:          dbMaxOpenConns,
+		DBMaxIdleConns:          dbMaxIdleConns,
+		DBConnMaxLifetime:       dbConnMaxLifetime,
+		DBQueryTimeout:          dbQueryTimeout,
+		AuthUser:                setting("AUTH_USER", ""),
+		AuthPass:                setting("AUTH_PASS", ""),
+		BaseURL:                 setting("BASE_URL", "http://localhost:8080"),
+		CacheSize:               cacheSize,
+		LogLevel:                setting("LOG_LEVEL", "INFO"),
+		RateLimitRPS:            rateLimitRPS,
+		RateLimitBurst:          rateLimitBurst,
+		MaxRequestBodyBytes:     maxRequestBodyBytes,
+		ShortCodeLength:         shortCodeLength,
+		ShortCodeStyle:          setting("SHORT_CODE_STYLE", ShortCodeStyleRandom),
+		BlacklistPath:           setting("BLACKLIST_PATH", ""),
+		CacheTTL:                cacheTTL,
+		CachePurge:              cachePurge,
+		CleanupInterval:         cleanupInterval,
+		OTelEnabled:             otelEnabled,
+		OTelServiceName:         setting("OTEL_SERVICE_NAME", "shorter"),
+		OTelEndpoint:            setting("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		BotUserAgents:           setting("BOT_USER_AGENTS", ""),
+		GeoIPDBPath:             setting("GEOIP_DB_PATH", ""),
+		CDNCacheMaxAge:          cdnCacheMaxAge,
+		AllowAnonymousCreate:    allowAnonymousCreate,
+		OwnerTokenTTL:           ownerTokenTTL,
+		CacheWarmLimit:          cacheWarmLimit,
+		CaseSensitiveCodes:      caseSensitiveCodes,
+		PreventSelfRedirect:     preventSelfRedirect,
+		ValidateURLReachability: validateURLReachability,
+		CacheEvictionPolicy:     setting("CACHE_EVICTION_POLICY", CacheEvictionLRU),
 	}
 }
 
+// Validate checks the configuration and returns every problem found, joined
+// into a single error, or nil when the configuration is usable
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.TLSEnabled {
+		if c.TLSCertFile == "" {
+			errs = append(errs, errors.New("TLS_CERT_FILE must be set when TLS_ENABLED is true"))
+		}
+		if c.TLSKeyFile == "" {
+			errs = append(errs, errors.New("TLS_KEY_FILE must be set when TLS_ENABLED is true"))
+		}
+		if c.HTTPRedirectPort < 0 || c.HTTPRedirectPort > 65535 {
+			errs = append(errs, fmt.Errorf("HTTP_REDIRECT_PORT must be between 0 and 65535, got %d", c.HTTPRedirectPort))
+		} else if c.HTTPRedirectPort == c.Port {
+			errs = append(errs, fmt.Errorf("HTTP_REDIRECT_PORT must differ from PORT, both are %d", c.Port))
+		}
+	}
+	if c.DebugPort < 0 || c.DebugPort > 65535 {
+		errs = append(errs, fmt.Errorf("DEBUG_PORT must be between 0 and 65535, got %d", c.DebugPort))
+	} else if c.DebugPort != 0 && c.DebugPort == c.Port {
+		errs = append(errs, fmt.Errorf("DEBUG_PORT must differ from PORT, both are %d", c.Port))
+	}
+	if c.HSTSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("HSTS_MAX_AGE must be a non-negative duration, got %s", c.HSTSMaxAge))
+	}
+	if c.BaseURL == "" {
+		errs = append(errs, errors.New("BASE_URL must not be empty"))
+	}
+	if c.DatabaseURL == "" {
+		errs = append(errs, errors.New("DATABASE_URL must not be empty"))
+	}
+	if c.AuthUser == "" {
+		errs = append(errs, errors.New("AUTH_USER must not be empty"))
+	} else if len(c.AuthUser) < MinCredentialLength {
+		errs = append(errs, fmt.Errorf("AUTH_USER must be at least %d characters", MinCredentialLength))
+	}
+	if c.AuthPass == "" {
+		errs = append(errs, errors.New("AUTH_PASS must not be empty"))
+	} else if len(c.AuthPass) < MinCredentialLength {
+		errs = append(errs, fmt.Errorf("AUTH_PASS must be at least %d characters", MinCredentialLength))
+	}
+	if c.DBMaxOpenConns < 0 {
+		errs = append(errs, fmt.Errorf("DB_MAX_OPEN_CONNS must not be negative, got %d", c.DBMaxOpenConns))
+	}
+	if c.DBMaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("DB_MAX_IDLE_CONNS must not be negative, got %d", c.DBMaxIdleConns))
+	}
+	if c.DBConnMaxLifetime < 0 {
+		errs = append(errs, fmt.Errorf("DB_CONN_MAX_LIFETIME must be a non-negative duration, got %s", c.DBConnMaxLifetime))
+	}
+	if c.DBQueryTimeout < 0 {
+		errs = append(errs, fmt.Errorf("DB_QUERY_TIMEOUT must be a non-negative duration, got %s", c.DBQueryTimeout))
+	}
+	if c.MaxRequestBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("MAX_REQUEST_BODY_BYTES must not be negative, got %d", c.MaxRequestBodyBytes))
+	}
+	if c.CacheSize <= 0 {
+		errs = append(errs, fmt.Errorf("CACHE_SIZE must be greater than 0, got %d", c.CacheSize))
+	}
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("CACHE_TTL must be a non-negative duration, got %s", c.CacheTTL))
+	}
+	if c.CachePurge < 0 {
+		errs = append(errs, fmt.Errorf("CACHE_PURGE_INTERVAL must be a non-negative duration, got %s", c.CachePurge))
+	}
+	if c.CleanupInterval < 0 {
+		errs = append(errs, fmt.Errorf("CLEANUP_INTERVAL must be a non-negative duration, got %s", c.CleanupInterval))
+	}
+	if c.ShortCodeLength != 0 && (c.ShortCodeLength < MinShortCodeLength || c.ShortCodeLength > MaxShortCodeLength) {
+		errs = append(errs, fmt.Errorf("SHORT_CODE_LENGTH must be between %d and %d, got %d",
+			MinShortCodeLength, MaxShortCodeLength, c.ShortCodeLength))
+	}
+	if c.OwnerTokenTTL < 0 {
+		errs = append(errs, fmt.Errorf("OWNER_TOKEN_TTL must be a non-negative duration, got %s", c.OwnerTokenTTL))
+	}
+	if c.CDNCacheMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("CDN_CACHE_MAX_AGE must not be negative, got %d", c.CDNCacheMaxAge))
+	}
+	if c.CacheWarmLimit < 0 {
+		errs = append(errs, fmt.Errorf("CACHE_WARM_LIMIT must not be negative, got %d", c.CacheWarmLimit))
+	}
+	switch c.ShortCodeStyle {
+	case "", ShortCodeStyleRandom, ShortCodeStyleWordPair, ShortCodeStyleBase58:
+	default:
+		errs = append(errs, fmt.Errorf("SHORT_CODE_STYLE must be %s, %s or %s, got %q",
+			ShortCodeStyleRandom, ShortCodeStyleWordPair, ShortCodeStyleBase58, c.ShortCodeStyle))
+	}
+	switch c.CacheEvictionPolicy {
+	case "", CacheEvictionLRU, CacheEvictionLFU:
+	default:
+		errs = append(errs, fmt.Errorf("CACHE_EVICTION_POLICY must be %s or %s, got %q",
+			CacheEvictionLRU, CacheEvictionLFU, c.CacheEvictionPolicy))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Warnings returns settings that are valid but risky, for logging at startup
+func (c Config) Warnings() []string {
+	var warnings []string
+
+	if c.DebugServerEnabled() && !isLoopback(c.DebugHost) {
+		warnings = append(warnings, fmt.Sprintf("DEBUG_HOST %q is not a loopback address, so pprof on port %d is reachable from other hosts", c.DebugHost, c.DebugPort))
+	}
+
+	return warnings
+}
+
+// DebugServerEnabled reports whether the pprof server should run, which it does
+// in development mode (any LOG_LEVEL but INFO) unless DEBUG_PORT is 0
+func (c Config) DebugServerEnabled() bool {
+	return c.LogLevel != "INFO" && c.DebugPort > 0
+}
+
+// isLoopback reports whether host only accepts connections from this machine.
+// An empty host listens on every interface.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseDuration parses a duration setting, returning -1 for malformed values so Validate reports them
+func parseDuration(value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return -1
+	}
+	return d
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
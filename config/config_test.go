@@ -0,0 +1,136 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validConfig returns a configuration that passes Validate
+func validConfig() Config {
+	return Config{
+		Port:            8080,
+		DatabaseURL:     "shorter.db",
+		AuthUser:        "shorter-admin",
+		AuthPass:        "s3cret-password",
+		BaseURL:         "http://localhost:8080",
+		CacheSize:       1000,
+		ShortCodeLength: 6,
+		ShortCodeStyle:  ShortCodeStyleRandom,
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(c *Config)
+		expectedErr string
+	}{
+		{name: "Valid", modify: func(c *Config) {}},
+		{name: "Port zero", modify: func(c *Config) { c.Port = 0 }, expectedErr: "PORT must be between 1 and 65535, got 0"},
+		{name: "Port too large", modify: func(c *Config) { c.Port = 65536 }, expectedErr: "PORT must be between 1 and 65535, got 65536"},
+		{name: "Port upper bound", modify: func(c *Config) { c.Port = 65535 }},
+		{name: "TLS", modify: func(c *Config) { c.TLSEnabled = true; c.TLSCertFile = "cert.pem"; c.TLSKeyFile = "key.pem"; c.HTTPRedirectPort = 80 }},
+		{name: "TLS without redirect", modify: func(c *Config) { c.TLSEnabled = true; c.TLSCertFile = "cert.pem"; c.TLSKeyFile = "key.pem" }},
+		{name: "TLS without certificate", modify: func(c *Config) { c.TLSEnabled = true; c.TLSKeyFile = "key.pem" }, expectedErr: "TLS_CERT_FILE must be set when TLS_ENABLED is true"},
+		{name: "TLS without key", modify: func(c *Config) { c.TLSEnabled = true; c.TLSCertFile = "cert.pem" }, expectedErr: "TLS_KEY_FILE must be set when TLS_ENABLED is true"},
+		{name: "Redirect port out of range", modify: func(c *Config) { c.TLSEnabled = true; c.TLSCertFile = "cert.pem"; c.TLSKeyFile = "key.pem"; c.HTTPRedirectPort = 70000 }, expectedErr: "HTTP_REDIRECT_PORT must be between 0 and 65535, got 70000"},
+		{name: "Redirect port clashes", modify: func(c *Config) { c.TLSEnabled = true; c.TLSCertFile = "cert.pem"; c.TLSKeyFile = "key.pem"; c.HTTPRedirectPort = 8080 }, expectedErr: "HTTP_REDIRECT_PORT must differ from PORT, both are 8080"},
+		{name: "Debug port", modify: func(c *Config) { c.DebugPort = 6060 }},
+		{name: "Debug port out of range", modify: func(c *Config) { c.DebugPort = 70000 }, expectedErr: "DEBUG_PORT must be between 0 and 65535, got 70000"},
+		{name: "Debug port clashes", modify: func(c *Config) { c.DebugPort = 8080 }, expectedErr: "DEBUG_PORT must differ from PORT, both are 8080"},
+		{name: "Negative HSTS max age", modify: func(c *Config) { c.HSTSMaxAge = -1 }, expectedErr: "HSTS_MAX_AGE must be a non-negative duration, got -1ns"},
+		{name: "Empty base URL", modify: func(c *Config) { c.BaseURL = "" }, expectedErr: "BASE_URL must not be empty"},
+		{name: "Empty database URL", modify: func(c *Config) { c.DatabaseURL = "" }, expectedErr: "DATABASE_URL must not be empty"},
+		{name: "Empty auth user", modify: func(c *Config) { c.AuthUser = "" }, expectedErr: "AUTH_USER must not be empty"},
+		{name: "Short auth user", modify: func(c *Config) { c.AuthUser = "admin" }, expectedErr: "AUTH_USER must be at least 8 characters"},
+		{name: "Empty auth pass", modify: func(c *Config) { c.AuthPass = "" }, expectedErr: "AUTH_PASS must not be empty"},
+		{name: "Short auth pass", modify: func(c *Config) { c.AuthPass = "passwd" }, expectedErr: "AUTH_PASS must be at least 8 characters"},
+		{name: "Eight character credentials", modify: func(c *Config) { c.AuthUser = "abcdefgh"; c.AuthPass = "12345678" }},
+		{name: "Negative max open connections", modify: func(c *Config) { c.DBMaxOpenConns = -1 }, expectedErr: "DB_MAX_OPEN_CONNS must not be negative, got -1"},
+		{name: "Negative max idle connections", modify: func(c *Config) { c.DBMaxIdleConns = -1 }, expectedErr: "DB_MAX_IDLE_CONNS must not be negative, got -1"},
+		{name: "Negative connection lifetime", modify: func(c *Config) { c.DBConnMaxLifetime = -1 }, expectedErr: "DB_CONN_MAX_LIFETIME must be a non-negative duration, got -1ns"},
+		{name: "Negative body limit", modify: func(c *Config) { c.MaxRequestBodyBytes = -1 }, expectedErr: "MAX_REQUEST_BODY_BYTES must not be negative, got -1"},
+		{name: "Negative owner token TTL", modify: func(c *Config) { c.OwnerTokenTTL = -1 }, expectedErr: "OWNER_TOKEN_TTL must be a non-negative duration, got -1ns"},
+		{name: "Negative CDN cache max age", modify: func(c *Config) { c.CDNCacheMaxAge = -1 }, expectedErr: "CDN_CACHE_MAX_AGE must not be negative, got -1"},
+		{name: "Negative cache warm limit", modify: func(c *Config) { c.CacheWarmLimit = -1 }, expectedErr: "CACHE_WARM_LIMIT must not be negative, got -1"},
+		{name: "Negative query timeout", modify: func(c *Config) { c.DBQueryTimeout = -1 }, expectedErr: "DB_QUERY_TIMEOUT must be a non-negative duration, got -1ns"},
+		{name: "Zero cache size", modify: func(c *Config) { c.CacheSize = 0 }, expectedErr: "CACHE_SIZE must be greater than 0, got 0"},
+		{name: "Negative cache size", modify: func(c *Config) { c.CacheSize = -1 }, expectedErr: "CACHE_SIZE must be greater than 0, got -1"},
+		{name: "Cleanup disabled", modify: func(c *Config) { c.CleanupInterval = 0 }},
+		{name: "Negative cleanup interval", modify: func(c *Config) { c.CleanupInterval = -1 }, expectedErr: "CLEANUP_INTERVAL must be a non-negative duration, got -1ns"},
+		{name: "Short code length unset", modify: func(c *Config) { c.ShortCodeLength = 0 }},
+		{name: "Short code length too small", modify: func(c *Config) { c.ShortCodeLength = 3 }, expectedErr: "SHORT_CODE_LENGTH must be between 4 and 32, got 3"},
+		{name: "Short code length too large", modify: func(c *Config) { c.ShortCodeLength = 33 }, expectedErr: "SHORT_CODE_LENGTH must be between 4 and 32, got 33"},
+		{name: "Short code length not a number", modify: func(c *Config) { c.ShortCodeLength = -1 }, expectedErr: "SHORT_CODE_LENGTH must be between 4 and 32, got -1"},
+		{name: "Short code style unset", modify: func(c *Config) { c.ShortCodeStyle = "" }},
+		{name: "Word pair short code style", modify: func(c *Config) { c.ShortCodeStyle = ShortCodeStyleWordPair }},
+		{name: "Base58 short code style", modify: func(c *Config) { c.ShortCodeStyle = ShortCodeStyleBase58 }},
+		{name: "Unknown short code style", modify: func(c *Config) { c.ShortCodeStyle = "emoji" }, expectedErr: `SHORT_CODE_STYLE must be random, wordpair or base58, got "emoji"`},
+		{name: "Cache eviction policy unset", modify: func(c *Config) { c.CacheEvictionPolicy = "" }},
+		{name: "LFU cache eviction policy", modify: func(c *Config) { c.CacheEvictionPolicy = CacheEvictionLFU }},
+		{name: "Unknown cache eviction policy", modify: func(c *Config) { c.CacheEvictionPolicy = "fifo" }, expectedErr: `CACHE_EVICTION_POLICY must be lru or lfu, got "fifo"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			cfg := validConfig()
+			tt.modify(&cfg)
+
+			// Act
+			err := cfg.Validate()
+
+			// Assert
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestConfig_Validate_ReportsEveryFailure(t *testing.T) {
+	// Arrange
+	cfg := Config{}
+
+	// Act
+	err := cfg.Validate()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 6)
+}
+
+func TestConfig_Warnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		expected []string
+	}{
+		{name: "Production", modify: func(c *Config) { c.LogLevel = "INFO"; c.DebugHost = "0.0.0.0" }},
+		{name: "Debug on localhost", modify: func(c *Config) { c.DebugHost = "localhost" }},
+		{name: "Debug on loopback IP", modify: func(c *Config) { c.DebugHost = "127.0.0.1" }},
+		{name: "Debug on IPv6 loopback", modify: func(c *Config) { c.DebugHost = "::1" }},
+		{name: "Debug disabled", modify: func(c *Config) { c.DebugHost = "0.0.0.0"; c.DebugPort = 0 }},
+		{name: "Debug on all interfaces", modify: func(c *Config) { c.DebugHost = "" }, expected: []string{`DEBUG_HOST "" is not a loopback address, so pprof on port 6060 is reachable from other hosts`}},
+		{name: "Debug on public IP", modify: func(c *Config) { c.DebugHost = "10.0.0.5" }, expected: []string{`DEBUG_HOST "10.0.0.5" is not a loopback address, so pprof on port 6060 is reachable from other hosts`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			cfg := validConfig()
+			cfg.LogLevel = "DEBUG"
+			cfg.DebugPort = 6060
+			tt.modify(&cfg)
+
+			// Act
+			warnings := cfg.Warnings()
+
+			// Assert
+			assert.Equal(t, tt.expected, warnings)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Reloader holds the current configuration and replaces it with a freshly
+// loaded one when the process receives SIGHUP
+type Reloader struct {
+	mutex   sync.RWMutex
+	current Config
+	load    func() (Config, error)
+}
+
+// NewReloader creates a reloader serving initial until the first successful reload
+func NewReloader(initial Config) *Reloader {
+	return &Reloader{
+		current: initial,
+		load:    Load,
+	}
+}
+
+// Get returns the current configuration
+func (r *Reloader) Get() Config {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.current
+}
+
+// Reload loads the configuration as Load does and swaps it in when it passes
+// Validate. A configuration that cannot be loaded or is invalid is returned with
+// its error and leaves the current one in place.
+func (r *Reloader) Reload() (Config, error) {
+	cfg, err := r.load()
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+
+	r.mutex.Lock()
+	r.current = cfg
+	r.mutex.Unlock()
+	return cfg, nil
+}
+
+// Start reloads the configuration on every SIGHUP until stop is called,
+// reporting the outcome of each reload to onReload
+func (r *Reloader) Start(onReload func(cfg Config, err error)) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				onReload(r.Reload())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reloadResult is the outcome of one reload reported to Start's callback
+type reloadResult struct {
+	cfg Config
+	err error
+}
+
+// sendSIGHUP signals the test process and waits for the reloader to report the reload
+func sendSIGHUP(t *testing.T, results <-chan reloadResult) reloadResult {
+	process, err := os.FindProcess(os.Getpid())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, process.Signal(syscall.SIGHUP))
+
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after SIGHUP")
+		return reloadResult{}
+	}
+}
+
+// startReloader starts a reloader serving initial and returns the channel its reloads are reported on
+func startReloader(t *testing.T, initial Config) (*Reloader, <-chan reloadResult) {
+	reloader := NewReloader(initial)
+	results := make(chan reloadResult, 1)
+	stop := reloader.Start(func(cfg Config, err error) {
+		results <- reloadResult{cfg: cfg, err: err}
+	})
+	t.Cleanup(stop)
+	return reloader, results
+}
+
+func TestReloader_SIGHUP(t *testing.T) {
+	// Arrange
+	t.Setenv("AUTH_USER", "shorter-admin")
+	t.Setenv("AUTH_PASS", "s3cret-password")
+	t.Setenv("CACHE_SIZE", "2500")
+	reloader, results := startReloader(t, validConfig())
+
+	// Act
+	result := sendSIGHUP(t, results)
+
+	// Assert
+	assert.NoError(t, result.err)
+	assert.Equal(t, 2500, reloader.Get().CacheSize)
+	assert.Equal(t, "s3cret-password", reloader.Get().AuthPass)
+}
+
+func TestReloader_SIGHUP_InvalidConfig(t *testing.T) {
+	// Arrange
+	t.Setenv("AUTH_USER", "shorter-admin")
+	t.Setenv("AUTH_PASS", "s3cret-password")
+	t.Setenv("CACHE_SIZE", "2500")
+	t.Setenv("PORT", "70000")
+	initial := validConfig()
+	reloader, results := startReloader(t, initial)
+
+	// Act
+	result := sendSIGHUP(t, results)
+
+	// Assert
+	assert.ErrorContains(t, result.err, "PORT must be between 1 and 65535, got 70000")
+	assert.Equal(t, initial, reloader.Get(), "an invalid reload keeps the old configuration")
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LoadConfigFromTOML reads the TOML configuration file at path. It accepts the
+// same settings as LoadConfigFromYAML: keys are Config field names, matched
+// case-insensitively, and durations are strings in Go syntax such as "1h30m".
+// Settings missing from the file keep their defaults and unknown keys are rejected.
+func LoadConfigFromTOML(path string) (Config, error) {
+	cfg := defaultConfig()
+	meta, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return Config{}, fmt.Errorf("parsing config file %s: unknown keys %s", path, strings.Join(keys, ", "))
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTOMLFile writes content to a TOML file in a temporary directory and returns its path
+func writeTOMLFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "shorter.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFromTOML_RoundTrip(t *testing.T) {
+	// Arrange - the YAML fixture sets every field to a non-default value
+	known, err := LoadConfigFromYAML(writeConfigFile(t, fullYAML))
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "shorter.toml")
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, toml.NewEncoder(file).Encode(known))
+	assert.NoError(t, file.Close())
+
+	// Act
+	cfg, err := LoadConfigFromTOML(path)
+
+	// Assert
+	assert.NoError(t, err)
+	expected := reflect.ValueOf(known)
+	loaded := reflect.ValueOf(cfg)
+	for i := 0; i < loaded.NumField(); i++ {
+		name := loaded.Type().Field(i).Name
+		assert.Equal(t, expected.Field(i).Interface(), loaded.Field(i).Interface(), "field %s", name)
+	}
+}
+
+func TestLoadConfigFromTOML_Keys(t *testing.T) {
+	// Arrange - keys match field names in any case, as written by hand
+	path := writeTOMLFile(t, `
+Port = 9090
+authuser = "shorter-admin"
+cachettl = "2h"
+RateLimitRPS = 2.5
+`)
+	expected := defaultConfig()
+	expected.Port = 9090
+	expected.AuthUser = "shorter-admin"
+	expected.CacheTTL = 2 * time.Hour
+	expected.RateLimitRPS = 2.5
+
+	// Act
+	cfg, err := LoadConfigFromTOML(path)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, expected, cfg)
+}
+
+func TestLoadConfigFromTOML_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{name: "Unknown key", content: "Prot = 9090\n", expectedErr: "unknown keys Prot"},
+		{name: "Wrong type", content: "Port = \"eighty\"\n", expectedErr: "incompatible types"},
+		{name: "Malformed duration", content: "CacheTTL = \"soon\"\n", expectedErr: "invalid duration"},
+		{name: "Malformed file", content: "Port = \n", expectedErr: "parsing config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := LoadConfigFromTOML(writeTOMLFile(t, tt.content))
+
+			// Assert
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestLoad_TOMLConfigFile(t *testing.T) {
+	// Arrange
+	t.Setenv("CONFIG_FILE", writeTOMLFile(t, "Port = 9090\nCacheSize = 5000\n"))
+	t.Setenv("CACHE_SIZE", "250")
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, 250, cfg.CacheSize, "environment variables take precedence over the file")
+}
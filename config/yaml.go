@@ -0,0 +1,72 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfigFromYAML reads the configuration file at path. Keys are Config field
+// names and durations use Go syntax such as "1h30m"; settings missing from the
+// file keep their defaults. Unknown keys are rejected so typos do not go unnoticed.
+func LoadConfigFromYAML(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config file: %w", err)
+	}
+
+	cfg := defaultConfig()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// An empty file decodes to io.EOF and leaves the defaults in place
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// LoadConfigWithFile reads the configuration file at path, as TOML when its name
+// ends in .toml and as YAML otherwise, and overrides it with every setting that
+// is also set as an environment variable
+func LoadConfigWithFile(path string) (Config, error) {
+	load := LoadConfigFromYAML
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		load = LoadConfigFromTOML
+	}
+
+	cfg, err := load(path)
+	if err != nil {
+		return Config{}, err
+	}
+	overrideFromEnv(&cfg, LoadConfig())
+	return cfg, nil
+}
+
+// Load reads the configuration from the YAML or TOML file named by CONFIG_FILE
+// merged with the environment, or from the environment alone when CONFIG_FILE is unset
+func Load() (Config, error) {
+	if path := getEnv("CONFIG_FILE", ""); path != "" {
+		return LoadConfigWithFile(path)
+	}
+	return LoadConfig(), nil
+}
+
+// overrideFromEnv copies into cfg each field of env whose environment variable,
+// named by the field's env tag, is set
+func overrideFromEnv(cfg *Config, env Config) {
+	dst := reflect.ValueOf(cfg).Elem()
+	src := reflect.ValueOf(env)
+	for i := 0; i < dst.NumField(); i++ {
+		if _, set := os.LookupEnv(dst.Type().Field(i).Tag.Get("env")); set {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fullYAML sets every Config field to a value other than its default
+const fullYAML = `
+Port: 9090
+TLSEnabled: true
+TLSCertFile: /etc/shorter/cert.pem
+TLSKeyFile: /etc/shorter/key.pem
+HTTPRedirectPort: 8081
+HSTSMaxAge: 24h
+DebugHost: 127.0.0.1
+DebugPort: 6061
+DatabaseURL: /var/lib/shorter/shorter.db
+SQLiteWALMode: false
+DBMaxOpenConns: 20
+DBMaxIdleConns: 5
+DBConnMaxLifetime: 30m
+DBQueryTimeout: 2s
+AuthUser: shorter-admin
+AuthPass: s3cret-password
+BaseURL: https://sho.rt
+CacheSize: 5000
+LogLevel: DEBUG
+RateLimitRPS: 2.5
+RateLimitBurst: 7
+MaxRequestBodyBytes: 4096
+ShortCodeLength: 8
+ShortCodeStyle: base58
+BlacklistPath: /etc/shorter/blacklist.txt
+CacheTTL: 2h
+CachePurge: 5m
+CleanupInterval: 12h
+OTelEnabled: true
+OTelServiceName: shorter-prod
+OTelEndpoint: http://otel:4318
+BotUserAgents: UptimeMonitor,LinkChecker
+GeoIPDBPath: /var/lib/shorter/GeoLite2-Country.mmdb
+CDNCacheMaxAge: 600
+AllowAnonymousCreate: true
+OwnerTokenTTL: 48h
+CacheWarmLimit: 500
+CaseSensitiveCodes: true
+PreventSelfRedirect: true
+ValidateURLReachability: true
+CacheEvictionPolicy: lfu
+`
+
+// writeConfigFile writes content to a YAML file in a temporary directory and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "shorter.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFromYAML(t *testing.T) {
+	// Arrange
+	path := writeConfigFile(t, fullYAML)
+	expected := Config{
+		Port:                    9090,
+		TLSEnabled:              true,
+		TLSCertFile:             "/etc/shorter/cert.pem",
+		TLSKeyFile:              "/etc/shorter/key.pem",
+		HTTPRedirectPort:        8081,
+		HSTSMaxAge:              24 * time.Hour,
+		DebugHost:               "127.0.0.1",
+		DebugPort:               6061,
+		DatabaseURL:             "/var/lib/shorter/shorter.db",
+		SQLiteWALMode:           false,
+		DBMaxOpenConns:          20,
+		DBMaxIdleConns:          5,
+		DBConnMaxLifetime:       30 * time.Minute,
+		DBQueryTimeout:          2 * time.Second,
+		AuthUser:                "shorter-admin",
+		AuthPass:                "s3cret-password",
+		BaseURL:                 "https://sho.rt",
+		CacheSize:               5000,
+		LogLevel:                "DEBUG",
+		RateLimitRPS:            2.5,
+		RateLimitBurst:          7,
+		MaxRequestBodyBytes:     4096,
+		ShortCodeLength:         8,
+		ShortCodeStyle:          ShortCodeStyleBase58,
+		BlacklistPath:           "/etc/shorter/blacklist.txt",
+		CacheTTL:                2 * time.Hour,
+		CachePurge:              5 * time.Minute,
+		CleanupInterval:         12 * time.Hour,
+		OTelEnabled:             true,
+		OTelServiceName:         "shorter-prod",
+		OTelEndpoint:            "http://otel:4318",
+		BotUserAgents:           "UptimeMonitor,LinkChecker",
+		GeoIPDBPath:             "/var/lib/shorter/GeoLite2-Country.mmdb",
+		CDNCacheMaxAge:          600,
+		AllowAnonymousCreate:    true,
+		OwnerTokenTTL:           48 * time.Hour,
+		CacheWarmLimit:          500,
+		CaseSensitiveCodes:      true,
+		PreventSelfRedirect:     true,
+		ValidateURLReachability: true,
+		CacheEvictionPolicy:     CacheEvictionLFU,
+	}
+
+	// Act
+	cfg, err := LoadConfigFromYAML(path)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, expected, cfg)
+	assert.NoError(t, cfg.Validate())
+
+	// Every field must differ from its default, or the file would not prove it is read
+	defaults := reflect.ValueOf(defaultConfig())
+	loaded := reflect.ValueOf(cfg)
+	for i := 0; i < loaded.NumField(); i++ {
+		name := loaded.Type().Field(i).Name
+		assert.NotEqual(t, defaults.Field(i).Interface(), loaded.Field(i).Interface(), "fullYAML must set %s", name)
+	}
+}
+
+func TestLoadConfigFromYAML_Defaults(t *testing.T) {
+	// Arrange
+	path := writeConfigFile(t, "AuthUser: shorter-admin\n")
+	expected := defaultConfig()
+	expected.AuthUser = "shorter-admin"
+
+	// Act
+	cfg, err := LoadConfigFromYAML(path)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, expected, cfg)
+}
+
+func TestLoadConfigFromYAML_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{name: "Unknown key", content: "Prot: 9090\n", expectedErr: "field Prot not found"},
+		{name: "Wrong type", content: "Port: eighty\n", expectedErr: "cannot unmarshal"},
+		{name: "Malformed duration", content: "CacheTTL: soon\n", expectedErr: "cannot unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := LoadConfigFromYAML(writeConfigFile(t, tt.content))
+
+			// Assert
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestLoadConfigFromYAML_MissingFile(t *testing.T) {
+	// Act
+	_, err := LoadConfigFromYAML(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	// Assert
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLoadConfigWithFile_EnvTakesPrecedence(t *testing.T) {
+	// Arrange
+	path := writeConfigFile(t, fullYAML)
+	t.Setenv("PORT", "7070")
+	t.Setenv("CACHE_TTL", "10m")
+
+	// Act
+	cfg, err := LoadConfigWithFile(path)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 7070, cfg.Port)
+	assert.Equal(t, 10*time.Minute, cfg.CacheTTL)
+	assert.Equal(t, 5000, cfg.CacheSize, "settings without an environment variable come from the file")
+	assert.Equal(t, "https://sho.rt", cfg.BaseURL)
+}
+
+func TestLoad_ConfigFile(t *testing.T) {
+	// Arrange
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, fullYAML))
+
+	// Act
+	cfg, err := Load()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Port)
+}
+
+func TestConfig_FieldTags(t *testing.T) {
+	// Every field must be settable from both sources: the YAML key is the field
+	// name and the env tag names the variable LoadConfig reads
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		assert.Equal(t, field.Name, field.Tag.Get("yaml"), "yaml tag of %s", field.Name)
+		assert.NotEmpty(t, field.Tag.Get("env"), "env tag of %s", field.Name)
+	}
+}
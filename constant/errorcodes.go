@@ -3,20 +3,98 @@ package constant
 // Domain service error codes
 const (
 	// Shortener service - Validation errors (1xx)
-	ErrCodeEmptyLongURL   = "SVC001"
-	ErrCodeEmptyShortCode = "SVC003"
+	ErrCodeEmptyLongURL        = "SVC001"
+	ErrCodeEmptyShortCode      = "SVC003"
+	ErrCodeBlacklistedURL      = "SVC011"
+	ErrCodeReservedShortCode   = "SVC012"
+	ErrCodeInvalidPassword     = "SVC013"
+	ErrCodePasswordHash        = "SVC014"
+	ErrCodeInvalidRedirectCode = "SVC015"
+	ErrCodeSSRFBlocked         = "SVC018"
+	ErrCodeInvalidLongURL      = "SVC036"
+	ErrCodeInvalidExpiry       = "SVC038"
+	ErrCodeInvalidCanary       = "SVC040"
+	ErrCodeInvalidGeoRule      = "SVC041"
+	ErrCodeInvalidActiveFrom   = "SVC043"
+	ErrCodeURLUnreachable      = "SVC045"
+	ErrCodeMissingAlternateURL = "SVC046"
+	ErrCodeInvalidAlternateURL = "SVC047"
+	ErrCodeInvalidGeoTarget    = "SVC048"
 	
 	// Shortener service - Storage errors (2xx)
-	ErrCodeStorageFailure = "SVC002"
+	ErrCodeStorageFailure    = "SVC002"
+	ErrCodeTooManyCollisions = "SVC037"
+	ErrCodeShortCodeExists   = "SVC049"
 	
 	// Shortener service - Retrieval errors (3xx)
 	ErrCodeShortCodeNotFound = "SVC004"
+	ErrCodeURLNotYetActive   = "SVC042"
+	ErrCodeLongURLNotFound   = "SVC050"
 	
 	// Shortener service - Stats errors (4xx)
 	ErrCodeIncrementVisits = "SVC005"
 
 	// Shortener service - Update errors (5xx)
 	ErrCodeUpdateFailure = "SVC006"
+
+	// Shortener service - Analytics errors (6xx)
+	ErrCodeRecordClick        = "SVC007"
+	ErrCodeInvalidVisitsQuery = "SVC008"
+	ErrCodeFindClicks         = "SVC009"
+	ErrCodeInvalidGranularity = "SVC051"
+	ErrCodeInvalidTimeRange   = "SVC052"
+	ErrCodeInvalidPeriod      = "SVC053"
+
+	// Shortener service - Export errors (7xx)
+	ErrCodeExportFailure       = "SVC010"
+	ErrCodeInvalidSearch       = "SVC029"
+	ErrCodeSearchFailure       = "SVC030"
+	ErrCodeInvalidSearchLimit  = "SVC054"
+	ErrCodeInvalidSearchOffset = "SVC055"
+
+	// Shortener service - Expiry errors (8xx)
+	ErrCodeShortCodeExpired  = "SVC016"
+	ErrCodeDeleteFailure     = "SVC017"
+	ErrCodeInvalidBulkDelete = "SVC033"
+
+	// Shortener service - User errors (9xx)
+	ErrCodeInvalidUser        = "SVC019"
+	ErrCodeUserExists         = "SVC020"
+	ErrCodeCreateUser         = "SVC021"
+	ErrCodeInvalidCredentials = "SVC022"
+	ErrCodeForbidden          = "SVC023"
+	ErrCodeListUsers          = "SVC024"
+	ErrCodeEmptyUsername      = "SVC056"
+	ErrCodeEmptyUserPassword  = "SVC057"
+	ErrCodeInvalidRole        = "SVC058"
+	ErrCodeUserNotFound       = "SVC059"
+
+	// Shortener service - Webhook errors (10xx)
+	ErrCodeInvalidWebhook      = "SVC025"
+	ErrCodeCreateWebhook       = "SVC026"
+	ErrCodeWebhookDelivery     = "SVC027"
+	ErrCodeFindWebhooks        = "SVC028"
+	ErrCodeInvalidWebhookURL   = "SVC060"
+	ErrCodeEmptyWebhookSecret  = "SVC061"
+	ErrCodeInvalidWebhookEvent = "SVC062"
+	ErrCodeWebhookNotFound     = "SVC063"
+
+	// Shortener service - Tag errors (11xx)
+	ErrCodeInvalidTag  = "SVC031"
+	ErrCodeTagFailure  = "SVC032"
+	ErrCodeTagNotFound = "SVC064"
+
+	// Shortener service - Health errors (12xx)
+	ErrCodeHealthCheck = "SVC034"
+
+	// Shortener service - Audit errors (13xx)
+	ErrCodeAuditFailure = "SVC035"
+
+	// Shortener service - Event errors (14xx)
+	ErrCodeEventHandlerPanic = "SVC039"
+
+	// Shortener service - Cache errors (15xx)
+	ErrCodeCacheWarm = "SVC044"
 )
 
 // Database error codes
@@ -27,10 +105,12 @@ const (
 	// Connection errors (0xx)
 	ErrCodeDBOpen    = "DB001"
 	ErrCodeDBMigrate = "DB002"
+	ErrCodeDBTracing = "DB003"
 	
 	// Store operation errors (1xx)
 	ErrCodeDBCheckExists = "DB101"
 	ErrCodeDBInsert      = "DB102"
+	ErrCodeDBBulkInsert  = "DB103"
 	
 	// FindByShortCode operation errors (2xx)
 	ErrCodeDBLookup     = "DB201"
@@ -38,10 +118,50 @@ const (
 	ErrCodeDBRowIterate = "DB203"
 	
 	// IncrementVisits operation errors (3xx)
-	ErrCodeDBIncrement = "DB301"
+	ErrCodeDBIncrement         = "DB301"
+	ErrCodeDBUpdateLastAccessed = "DB302"
+	ErrCodeDBUpdateCanary       = "DB303"
 	
 	// Close operation errors (4xx)
 	ErrCodeDBClose = "DB401"
+	ErrCodeDBPing  = "DB402"
+
+	// Click event operation errors (6xx)
+	ErrCodeDBRecordClick = "DB601"
+	ErrCodeDBFindClicks  = "DB602"
+
+	// FindAll operation errors (7xx)
+	ErrCodeDBFindAll          = "DB701"
+	ErrCodeDBSearch           = "DB702"
+	ErrCodeDBFindExpired      = "DB703"
+	ErrCodeDBFindCreatedAfter = "DB704"
+	ErrCodeDBFindTopURLs      = "DB705"
+	ErrCodeDBFindTrending     = "DB706"
+
+	// Delete operation errors (8xx)
+	ErrCodeDBDelete     = "DB801"
+	ErrCodeDBBulkDelete = "DB802"
+
+	// User operation errors (9xx)
+	ErrCodeDBCreateUser = "DB901"
+	ErrCodeDBFindUser   = "DB902"
+
+	// Webhook operation errors (10xx)
+	ErrCodeDBCreateWebhook = "DB1001"
+	ErrCodeDBFindWebhooks  = "DB1002"
+	ErrCodeDBDeleteWebhook = "DB1003"
+
+	// Tag operation errors (11xx)
+	ErrCodeDBAddTag    = "DB1101"
+	ErrCodeDBRemoveTag = "DB1102"
+	ErrCodeDBFindTags  = "DB1103"
+
+	// Audit operation errors (12xx)
+	ErrCodeDBAppendAudit = "DB1201"
+	ErrCodeDBFindAudits  = "DB1202"
+
+	// Rename operation errors (13xx)
+	ErrCodeDBRename = "DB1301"
 )
 
 // Error types for categorization
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// EventHandler reacts to a published event
+type EventHandler func(ctx context.Context, event Event)
+
+// EventBus delivers published events to the handlers subscribed to their type.
+// Each handler runs in its own goroutine, so a slow or panicking handler
+// neither blocks the publisher nor affects other handlers.
+type EventBus struct {
+	mutex    sync.RWMutex
+	handlers map[string][]EventHandler
+	closed   bool
+	running  sync.WaitGroup
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[string][]EventHandler)}
+}
+
+// Subscribe registers handler for events of eventType
+func (b *EventBus) Subscribe(eventType string, handler EventHandler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish runs every handler subscribed to the event's type without waiting for
+// them. Handlers get a context that is not cancelled with ctx, since they usually
+// outlive the request that published the event. Events published after Shutdown
+// are dropped.
+func (b *EventBus) Publish(ctx context.Context, event Event) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.closed {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	for _, handler := range b.handlers[event.Type()] {
+		b.running.Add(1)
+		go b.run(ctx, handler, event)
+	}
+}
+
+// run calls handler, recovering from and logging any panic
+func (b *EventBus) run(ctx context.Context, handler EventHandler, event Event) {
+	defer b.running.Done()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.CtxError(ctx, "Event handler panicked", logger.LoggerInfo{
+				ContextFunction: constant.CtxEventBus,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeEventHandlerPanic,
+					Message: fmt.Sprint(recovered),
+					Type:    constant.ErrTypeDomain,
+				},
+				Data: map[string]interface{}{
+					constant.DataEvent: event.Type(),
+				},
+			})
+		}
+	}()
+
+	handler(ctx, event)
+}
+
+// Shutdown stops accepting events and waits for running handlers to return
+func (b *EventBus) Shutdown(ctx context.Context) error {
+	b.mutex.Lock()
+	b.closed = true
+	b.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
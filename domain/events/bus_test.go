@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a test subscriber that collects the events it handles
+type recorder struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (r *recorder) handle(ctx context.Context, event Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) received() []Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.events
+}
+
+func TestEventBus_Publish(t *testing.T) {
+	// Arrange
+	bus := NewEventBus()
+	created, deleted := &recorder{}, &recorder{}
+	bus.Subscribe(URLCreated, created.handle)
+	bus.Subscribe(URLDeleted, deleted.handle)
+	now := time.Now()
+
+	// Act
+	bus.Publish(context.Background(), URLCreatedEvent{ShortCode: "abc123", LongURL: "https://example.com", OccurredAt: now})
+	bus.Publish(context.Background(), URLAccessedEvent{ShortCode: "abc123", OccurredAt: now})
+	assert.NoError(t, bus.Shutdown(context.Background()))
+
+	// Assert - each handler only sees the type it subscribed to
+	assert.Equal(t, []Event{URLCreatedEvent{ShortCode: "abc123", LongURL: "https://example.com", OccurredAt: now}}, created.received())
+	assert.Empty(t, deleted.received())
+}
+
+func TestEventBus_Publish_MultipleHandlers(t *testing.T) {
+	// Arrange
+	bus := NewEventBus()
+	first, second := &recorder{}, &recorder{}
+	bus.Subscribe(URLUpdated, first.handle)
+	bus.Subscribe(URLUpdated, second.handle)
+
+	// Act
+	bus.Publish(context.Background(), URLUpdatedEvent{ShortCode: "fixed", PreviousShortCode: "typo"})
+	assert.NoError(t, bus.Shutdown(context.Background()))
+
+	// Assert
+	assert.Len(t, first.received(), 1)
+	assert.Len(t, second.received(), 1)
+}
+
+func TestEventBus_Publish_RecoversPanics(t *testing.T) {
+	// Arrange
+	bus := NewEventBus()
+	survivor := &recorder{}
+	bus.Subscribe(URLAccessed, func(ctx context.Context, event Event) { panic("handler failed") })
+	bus.Subscribe(URLAccessed, survivor.handle)
+
+	// Act
+	assert.NotPanics(t, func() {
+		bus.Publish(context.Background(), URLAccessedEvent{ShortCode: "abc123"})
+		assert.NoError(t, bus.Shutdown(context.Background()))
+	})
+
+	// Assert - a panicking handler does not stop the others
+	assert.Len(t, survivor.received(), 1)
+}
+
+func TestEventBus_Publish_OutlivesRequestContext(t *testing.T) {
+	// Arrange
+	bus := NewEventBus()
+	handlerErr := make(chan error, 1)
+	bus.Subscribe(URLAccessed, func(ctx context.Context, event Event) { handlerErr <- ctx.Err() })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	bus.Publish(ctx, URLAccessedEvent{ShortCode: "abc123"})
+
+	// Assert
+	select {
+	case err := <-handlerErr:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("handler did not run")
+	}
+}
+
+func TestEventBus_Shutdown(t *testing.T) {
+	// Arrange
+	bus := NewEventBus()
+	release := make(chan struct{})
+	late := &recorder{}
+	bus.Subscribe(URLCreated, func(ctx context.Context, event Event) { <-release })
+	bus.Subscribe(URLDeleted, late.handle)
+	bus.Publish(context.Background(), URLCreatedEvent{ShortCode: "abc123"})
+
+	// Act - the running handler is still blocked
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	timedOut := bus.Shutdown(ctx)
+	bus.Publish(context.Background(), URLDeletedEvent{ShortCode: "abc123"})
+	close(release)
+	drained := bus.Shutdown(context.Background())
+
+	// Assert - Shutdown waits for running handlers and drops later events
+	assert.ErrorIs(t, timedOut, context.DeadlineExceeded)
+	assert.NoError(t, drained)
+	assert.Empty(t, late.received())
+}
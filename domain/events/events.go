@@ -0,0 +1,67 @@
+package events
+
+import "time"
+
+// Event types
+const (
+	URLCreated  = "url.created"
+	URLAccessed = "url.accessed"
+	URLUpdated  = "url.updated"
+	URLDeleted  = "url.deleted"
+)
+
+// Event is something that happened to a short URL
+type Event interface {
+	// Type returns the event type handlers subscribe to
+	Type() string
+}
+
+// URLCreatedEvent is published after a short URL is stored
+type URLCreatedEvent struct {
+	ShortCode string
+	LongURL   string
+	// Snapshot is the JSON encoding of the created URL
+	Snapshot   string
+	OccurredAt time.Time
+}
+
+// Type implements Event
+func (URLCreatedEvent) Type() string { return URLCreated }
+
+// URLAccessedEvent is published every time a short URL is followed
+type URLAccessedEvent struct {
+	ShortCode  string
+	LongURL    string
+	Referer    string
+	OccurredAt time.Time
+}
+
+// Type implements Event
+func (URLAccessedEvent) Type() string { return URLAccessed }
+
+// URLUpdatedEvent is published after a short URL is changed
+type URLUpdatedEvent struct {
+	ShortCode string
+	// PreviousShortCode is set when the update renamed the short code
+	PreviousShortCode string
+	// Before and After are JSON encodings of the URL around the change
+	Before     string
+	After      string
+	OccurredAt time.Time
+	// Audited is set when the audit entry was written in the same transaction as the change
+	Audited bool
+}
+
+// Type implements Event
+func (URLUpdatedEvent) Type() string { return URLUpdated }
+
+// URLDeletedEvent is published after a short URL is deleted
+type URLDeletedEvent struct {
+	ShortCode string
+	// Snapshot is the JSON encoding of the deleted URL, empty when it was not loaded
+	Snapshot   string
+	OccurredAt time.Time
+}
+
+// Type implements Event
+func (URLDeletedEvent) Type() string { return URLDeleted }
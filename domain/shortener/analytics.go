@@ -0,0 +1,491 @@
+package shortener
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+	"github.com/prasetyowira/shorter/infrastructure/useragent"
+)
+
+// Visit aggregation granularities
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// maxVisitBuckets caps the number of periods returned by a single visits query
+const maxVisitBuckets = 1000
+
+// Sparkline window sizes, in days
+const (
+	DefaultSparklineDays = 7
+	MaxSparklineDays     = 30
+)
+
+// sparklineDateLayout formats the date of a daily click count
+const sparklineDateLayout = "2006-01-02"
+
+// ClickEvent represents a single visit to a short URL
+type ClickEvent struct {
+	ShortCode string    `json:"short_code"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referer   string    `json:"referer"`
+	// IPHash is the hex SHA-256 of the visitor's IP; the IP itself is never stored
+	IPHash    string `json:"ip_hash"`
+	UserAgent string `json:"user_agent"`
+	// DeviceType is the useragent.DeviceType of UserAgent
+	DeviceType string `json:"device_type"`
+}
+
+// RefererStat holds the number of clicks coming from a single referer
+type RefererStat struct {
+	URL   string `json:"url"`
+	Count uint   `json:"count"`
+}
+
+// refererContextKey is the context key carrying the HTTP Referer of a visit
+type refererContextKey struct{}
+
+// WithReferer attaches the visit's HTTP Referer to ctx so that it is stored with the click event
+func WithReferer(ctx context.Context, referer string) context.Context {
+	return context.WithValue(ctx, refererContextKey{}, referer)
+}
+
+// RefererFromContext returns the HTTP Referer attached by WithReferer, if any
+func RefererFromContext(ctx context.Context) string {
+	referer, _ := ctx.Value(refererContextKey{}).(string)
+	return referer
+}
+
+// DeviceStats holds the number of clicks from each kind of device
+type DeviceStats struct {
+	Mobile  uint `json:"mobile"`
+	Desktop uint `json:"desktop"`
+	Tablet  uint `json:"tablet"`
+	Bot     uint `json:"bot"`
+}
+
+// Add counts clicks from deviceType. Unknown device types, such as clicks recorded
+// before device detection, are ignored.
+func (d *DeviceStats) Add(deviceType string, clicks uint) {
+	switch useragent.DeviceType(deviceType) {
+	case useragent.Mobile:
+		d.Mobile += clicks
+	case useragent.Desktop:
+		d.Desktop += clicks
+	case useragent.Tablet:
+		d.Tablet += clicks
+	case useragent.Bot:
+		d.Bot += clicks
+	}
+}
+
+// visitorContextKey is the context key carrying the client of a visit
+type visitorContextKey struct{}
+
+// visitor is the client of a visit as attached by WithVisitor
+type visitor struct {
+	ip        string
+	userAgent string
+}
+
+// maxUserAgentLength caps the User-Agent stored with a click event
+const maxUserAgentLength = 512
+
+// WithVisitor attaches the visit's client IP and User-Agent to ctx so that they are stored
+// with the click event. The IP is hashed with HashIP before it is stored.
+func WithVisitor(ctx context.Context, ip, userAgent string) context.Context {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return context.WithValue(ctx, visitorContextKey{}, visitor{ip: ip, userAgent: userAgent})
+}
+
+// visitorFromContext returns the client attached by WithVisitor, if any
+func visitorFromContext(ctx context.Context) visitor {
+	v, _ := ctx.Value(visitorContextKey{}).(visitor)
+	return v
+}
+
+// HashIP returns the hex SHA-256 of ip, or an empty string when ip is empty
+func HashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:])
+}
+
+// VisitBucket holds the number of clicks within a single period
+type VisitBucket struct {
+	Period time.Time `json:"period"`
+	Count  uint      `json:"count"`
+}
+
+// TrendingURL is a URL together with the number of clicks it received within a recent period
+type TrendingURL struct {
+	URL
+	RecentVisits uint `json:"recent_visits"`
+}
+
+// Trending periods; longer periods are rejected
+const (
+	DefaultTrendingPeriod = 24 * time.Hour
+	MaxTrendingPeriod     = 30 * 24 * time.Hour
+)
+
+// DailyCount holds the number of clicks on a single UTC day
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count uint   `json:"count"`
+}
+
+// recordClickAsync stores a click event without blocking the redirect
+func (s *Service) recordClickAsync(ctx context.Context, event ClickEvent) {
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		if err := s.repo.RecordClick(ctx, event); err != nil {
+			logger.CtxWarn(ctx, "Failed to record click event", logger.LoggerInfo{
+				ContextFunction: constant.CtxRecordClick,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeRecordClick,
+					Message: err.Error(),
+					Type:    constant.ErrTypeStats,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: event.ShortCode,
+				},
+			})
+		}
+	}()
+}
+
+// getVisits implements GetVisits
+func (s *Service) getVisits(ctx context.Context, shortCode string, from, to time.Time, granularity string) ([]VisitBucket, error) {
+	logger.CtxDebug(ctx, "Retrieving visits", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetVisits,
+		Data: map[string]interface{}{
+			constant.DataShortCode:   shortCode,
+			constant.DataFrom:        from,
+			constant.DataTo:          to,
+			constant.DataGranularity: granularity,
+		},
+	})
+
+	if shortCode == "" {
+		return nil, ErrEmptyShortCode
+	}
+
+	var step time.Duration
+	switch granularity {
+	case GranularityHour:
+		step = time.Hour
+	case GranularityDay:
+		step = 24 * time.Hour
+	default:
+		logger.CtxWarn(ctx, "Invalid visits granularity", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetVisits,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidVisitsQuery,
+				Message: constant.ErrInvalidGranularity,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataGranularity: granularity,
+			},
+		})
+		return nil, ErrInvalidGranularity
+	}
+
+	from = from.UTC().Truncate(step)
+	to = to.UTC()
+	if !to.After(from) || to.Sub(from)/step >= maxVisitBuckets {
+		logger.CtxWarn(ctx, "Invalid visits time range", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetVisits,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidVisitsQuery,
+				Message: constant.ErrInvalidTimeRange,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataFrom: from,
+				constant.DataTo:   to,
+			},
+		})
+		return nil, ErrInvalidTimeRange
+	}
+
+	url, err := s.findShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	clicks, err := s.repo.FindClicks(ctx, url.ShortCode, from, to)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to find click events", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetVisits,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	buckets := make([]VisitBucket, 0, to.Sub(from)/step+1)
+	for period := from; period.Before(to); period = period.Add(step) {
+		buckets = append(buckets, VisitBucket{Period: period})
+	}
+	for _, click := range clicks {
+		index := int(click.ClickedAt.UTC().Sub(from) / step)
+		if index >= 0 && index < len(buckets) {
+			buckets[index].Count++
+		}
+	}
+
+	logger.CtxInfo(ctx, "Visits retrieved successfully", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetVisits,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataVisits:    len(clicks),
+		},
+	})
+
+	return buckets, nil
+}
+
+// getReferers implements GetReferers
+func (s *Service) getReferers(ctx context.Context, shortCode string) ([]RefererStat, error) {
+	logger.CtxDebug(ctx, "Retrieving referers", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetReferers,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	if shortCode == "" {
+		return nil, ErrEmptyShortCode
+	}
+
+	url, err := s.findShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	referers, err := s.repo.FindReferers(ctx, url.ShortCode)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to find referers", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetReferers,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	return referers, nil
+}
+
+// exportClicks implements ExportClicks
+func (s *Service) exportClicks(ctx context.Context, shortCode string) ([]ClickEvent, error) {
+	logger.CtxDebug(ctx, "Exporting click events", logger.LoggerInfo{
+		ContextFunction: constant.CtxExportClicks,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	if shortCode == "" {
+		return nil, ErrEmptyShortCode
+	}
+
+	url, err := s.LookupURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeOwner(ctx, url); err != nil {
+		return nil, err
+	}
+
+	clicks, err := s.repo.FindAllClicks(ctx, url.ShortCode)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to find click events", logger.LoggerInfo{
+			ContextFunction: constant.CtxExportClicks,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	return clicks, nil
+}
+
+// getDeviceStats implements GetDeviceStats
+func (s *Service) getDeviceStats(ctx context.Context, shortCode string) (DeviceStats, error) {
+	logger.CtxDebug(ctx, "Retrieving device stats", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetDeviceStats,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	if shortCode == "" {
+		return DeviceStats{}, ErrEmptyShortCode
+	}
+
+	url, err := s.findShortCode(ctx, shortCode)
+	if err != nil {
+		return DeviceStats{}, err
+	}
+
+	stats, err := s.repo.FindDeviceStats(ctx, url.ShortCode)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to find device stats", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetDeviceStats,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return DeviceStats{}, err
+	}
+
+	return stats, nil
+}
+
+// getSparkline implements GetSparkline
+func (s *Service) getSparkline(ctx context.Context, shortCode string, days int) ([]DailyCount, error) {
+	days = clampSparklineDays(days)
+
+	logger.CtxDebug(ctx, "Retrieving sparkline", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetSparkline,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataDays:      days,
+		},
+	})
+
+	if shortCode == "" {
+		return nil, ErrEmptyShortCode
+	}
+
+	url, err := s.findShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.repo.FindDailyClicks(ctx, url.ShortCode, days)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to find daily clicks", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetSparkline,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	byDate := make(map[string]uint, len(counts))
+	for _, count := range counts {
+		byDate[count.Date] = count.Count
+	}
+
+	// Days without clicks are missing from the repository result, so every day in the window is filled in
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	buckets := make([]DailyCount, days)
+	for i := range buckets {
+		date := today.AddDate(0, 0, i-days+1).Format(sparklineDateLayout)
+		buckets[i] = DailyCount{Date: date, Count: byDate[date]}
+	}
+
+	return buckets, nil
+}
+
+// clampSparklineDays keeps a sparkline window within [1, MaxSparklineDays], defaulting to DefaultSparklineDays
+func clampSparklineDays(days int) int {
+	switch {
+	case days <= 0:
+		return DefaultSparklineDays
+	case days > MaxSparklineDays:
+		return MaxSparklineDays
+	default:
+		return days
+	}
+}
+
+// getTrendingURLs implements GetTrendingURLs
+func (s *Service) getTrendingURLs(ctx context.Context, period time.Duration, limit int) ([]*TrendingURL, error) {
+	logger.CtxDebug(ctx, "Listing trending URLs", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetTrending,
+		Data: map[string]interface{}{
+			constant.DataPeriod: period.String(),
+			constant.DataLimit:  limit,
+		},
+	})
+
+	var validationErr error
+	switch {
+	case period <= 0 || period > MaxTrendingPeriod:
+		validationErr = ErrInvalidPeriod
+	case limit < 1:
+		validationErr = ErrInvalidSearchLimit
+	}
+	if validationErr != nil {
+		logger.CtxWarn(ctx, "Invalid trending URLs query", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetTrending,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidVisitsQuery,
+				Message: validationErr.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+		return nil, validationErr
+	}
+	if limit > MaxTopURLsLimit {
+		limit = MaxTopURLsLimit
+	}
+
+	urls, err := s.repo.FindTrending(ctx, period, limit)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to list trending URLs", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetTrending,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+		})
+		return nil, err
+	}
+
+	return urls, nil
+}
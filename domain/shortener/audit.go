@@ -0,0 +1,145 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionRename = "rename"
+)
+
+// AuditActorSystem is recorded as the actor of changes made without an authenticated user,
+// such as URLs deactivated after reaching their visit limit
+const AuditActorSystem = "system"
+
+// AuditEntry records who changed a short URL, how and when
+type AuditEntry struct {
+	ID            uint   `json:"id"`
+	ActorUsername string `json:"actor_username"`
+	Action        string `json:"action"`
+	ShortCode     string `json:"short_code"`
+	// Before and After are JSON snapshots of the URL; Before is empty for creates and After for deletes.
+	// Renames are recorded against the new short code.
+	Before     string    `json:"before,omitempty"`
+	After      string    `json:"after,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// auditEvent records a URL lifecycle event in the audit log
+func (s *Service) auditEvent(ctx context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.URLCreatedEvent:
+		s.recordAudit(ctx, AuditActionCreate, e.ShortCode, "", e.Snapshot, e.OccurredAt)
+	case events.URLUpdatedEvent:
+		if e.Audited {
+			return
+		}
+		action := AuditActionUpdate
+		if e.PreviousShortCode != "" {
+			action = AuditActionRename
+		}
+		s.recordAudit(ctx, action, e.ShortCode, e.Before, e.After, e.OccurredAt)
+	case events.URLDeletedEvent:
+		s.recordAudit(ctx, AuditActionDelete, e.ShortCode, e.Snapshot, "", e.OccurredAt)
+	}
+}
+
+// recordAudit appends an audit entry for a change to shortCode. The change has
+// already been made, so a failure is logged rather than returned.
+func (s *Service) recordAudit(ctx context.Context, action, shortCode, before, after string, occurredAt time.Time) {
+	entry := newAuditEntry(ctx, action, shortCode, before, after, occurredAt)
+	if err := s.repo.AppendAudit(ctx, entry); err != nil {
+		logAuditFailure(ctx, shortCode, action, err)
+	}
+}
+
+// newAuditEntry builds the audit entry for a change to shortCode made by the user in ctx
+func newAuditEntry(ctx context.Context, action, shortCode, before, after string, occurredAt time.Time) AuditEntry {
+	entry := AuditEntry{
+		ActorUsername: AuditActorSystem,
+		Action:        action,
+		ShortCode:     shortCode,
+		Before:        before,
+		After:         after,
+		OccurredAt:    occurredAt,
+	}
+	if user, ok := UserFromContext(ctx); ok {
+		entry.ActorUsername = user.Username
+	}
+	return entry
+}
+
+// logAuditFailure logs that the audit entry for a change to shortCode could not be appended
+func logAuditFailure(ctx context.Context, shortCode, action string, err error) {
+	logger.CtxError(ctx, "Failed to append audit entry", logger.LoggerInfo{
+		ContextFunction: constant.CtxAppendAudit,
+		Error: &logger.CustomError{
+			Code:    constant.ErrCodeAuditFailure,
+			Message: err.Error(),
+			Type:    constant.ErrTypeStorage,
+		},
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataAction:    action,
+		},
+	})
+}
+
+// snapshot returns url as JSON, or an empty string when url is nil.
+// The password hash is left out by the URL's JSON tags.
+func snapshot(url *URL) string {
+	if url == nil {
+		return ""
+	}
+	snapshot, err := json.Marshal(url)
+	if err != nil {
+		return ""
+	}
+	return string(snapshot)
+}
+
+// getAuditLog implements GetAuditLog
+func (s *Service) getAuditLog(ctx context.Context, shortCode string) ([]AuditEntry, error) {
+	if shortCode == "" {
+		return nil, ErrEmptyShortCode
+	}
+
+	// Entries are recorded under the stored code. A deleted URL keeps its entries, so a
+	// code that is no longer found is looked up as normalized.
+	code := s.normalizeShortCode(shortCode)
+	url, err := s.findShortCode(ctx, shortCode)
+	switch {
+	case err == nil:
+		code = url.ShortCode
+	case !errors.Is(err, ErrShortCodeNotFound):
+		return nil, err
+	}
+
+	entries, err := s.repo.FindAudits(ctx, code)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to look up audit entries", logger.LoggerInfo{
+			ContextFunction: constant.CtxFindAudits,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeAuditFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+	return entries, nil
+}
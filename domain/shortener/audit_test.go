@@ -0,0 +1,134 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_RecordAudit_Actor(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *User
+		expected string
+	}{
+		{name: "Authenticated user", user: &User{ID: 7, Username: "alice", Role: RoleUser}, expected: "alice"},
+		{name: "Internal call", user: nil, expected: AuditActorSystem},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = WithUser(ctx, tt.user)
+			}
+
+			// Act
+			_, err := service.CreateShortURL(ctx, 0, "https://example.com", "custom")
+			waitForEvents(t, service)
+
+			// Assert
+			assert.NoError(t, err)
+			mockRepo.AssertCalled(t, "AppendAudit", mock.Anything, mock.MatchedBy(func(entry AuditEntry) bool {
+				return entry.ActorUsername == tt.expected && entry.Action == AuditActionCreate &&
+					entry.ShortCode == "custom" && entry.Before == "" && entry.After != ""
+			}))
+		})
+	}
+}
+
+func TestService_UpdateLongURL_Audit(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").
+		Return(&URL{ShortCode: "abc123", LongURL: "https://example.com/old", Password: "secret-hash"}, nil)
+	mockRepo.On("UpdateLongURL", mock.Anything, "abc123", "https://example.com/new").Return(nil)
+	var recorded AuditEntry
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { recorded = args.Get(1).(AuditEntry) }).
+		Return(nil)
+
+	// Act
+	_, err := service.UpdateLongURL(context.Background(), "abc123", "https://example.com/new")
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, AuditActionUpdate, recorded.Action)
+	assert.Contains(t, recorded.Before, "https://example.com/old")
+	assert.Contains(t, recorded.After, "https://example.com/new")
+	assert.NotContains(t, recorded.Before, "secret-hash")
+	assert.NotContains(t, recorded.After, "secret-hash")
+}
+
+func TestService_DeleteURL_AuditFailure(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123"}, nil)
+	mockRepo.On("Delete", mock.Anything, "abc123").Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(errors.New("database is locked"))
+
+	// Act
+	err := service.DeleteURL(context.Background(), "abc123")
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err, "a failed audit append must not fail the delete")
+	mockRepo.AssertCalled(t, "AppendAudit", mock.Anything, mock.MatchedBy(func(entry AuditEntry) bool {
+		return entry.Action == AuditActionDelete && entry.Before != "" && entry.After == ""
+	}))
+}
+
+func TestService_GetAuditLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		shortCode string
+		stored    *URL
+		auditCode string
+		err       string
+	}{
+		{name: "Valid", shortCode: "abc123", stored: &URL{ShortCode: "abc123"}, auditCode: "abc123"},
+		{name: "Stored in another case", shortCode: "ABC123", stored: &URL{ShortCode: "AbC123"}, auditCode: "AbC123"},
+		{name: "Deleted", shortCode: "ABC123", auditCode: "abc123"},
+		{name: "Empty short code", shortCode: "", err: constant.ErrEmptyShortCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			entries := []AuditEntry{{ID: 1, Action: AuditActionCreate, ShortCode: tt.auditCode}}
+			if tt.stored != nil {
+				mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(tt.stored, nil)
+			} else {
+				mockRepo.On("FindByShortCode", mock.Anything, mock.Anything).Return((*URL)(nil), ErrShortCodeNotFound)
+			}
+			mockRepo.On("FindAudits", mock.Anything, tt.auditCode).Return(entries, nil)
+
+			// Act
+			result, err := service.GetAuditLog(context.Background(), tt.shortCode)
+
+			// Assert
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				mockRepo.AssertNotCalled(t, "FindAudits", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, entries, result)
+		})
+	}
+}
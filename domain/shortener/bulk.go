@@ -0,0 +1,260 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// MaxBulkDelete is the largest number of short codes accepted by one bulk delete
+const MaxBulkDelete = 1000
+
+// BulkDeleteError explains why a short code in a bulk delete was not deleted
+type BulkDeleteError struct {
+	ShortCode string `json:"short_code"`
+	Reason    string `json:"reason"`
+}
+
+// BulkDeleteResult is the outcome of a bulk delete
+type BulkDeleteResult struct {
+	Deleted   int      `json:"deleted"`
+	NotFound  []string `json:"not_found"`
+	Forbidden []string `json:"forbidden,omitempty"`
+}
+
+// BulkURL is one URL of a bulk creation. An empty CustomShort gets a generated code.
+type BulkURL struct {
+	LongURL     string
+	CustomShort string
+}
+
+// BulkStoreError explains why a URL in a bulk store was not stored. Index is the
+// position of the URL in the batch.
+type BulkStoreError struct {
+	Index     int    `json:"index"`
+	ShortCode string `json:"short_code"`
+	Reason    string `json:"reason"`
+}
+
+// BulkStoreResult is the outcome of a bulk store
+type BulkStoreResult struct {
+	Stored  int              `json:"stored"`
+	Skipped int              `json:"skipped"`
+	Errors  []BulkStoreError `json:"errors"`
+}
+
+// bulkDeleteURLs implements BulkDeleteURLs
+func (s *Service) bulkDeleteURLs(ctx context.Context, shortCodes []string) (*BulkDeleteResult, error) {
+	codes := uniqueShortCodes(shortCodes)
+	if len(codes) == 0 || len(codes) > MaxBulkDelete {
+		logger.CtxWarn(ctx, "Invalid bulk delete", logger.LoggerInfo{
+			ContextFunction: constant.CtxBulkDeleteURLs,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidBulkDelete,
+				Message: constant.ErrInvalidBulkDelete,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataCount: len(codes),
+			},
+		})
+		return nil, ErrInvalidBulkDelete
+	}
+
+	result := &BulkDeleteResult{NotFound: []string{}}
+
+	// Codes are deleted as stored, which may differ in case from the requested codes, and
+	// non-admin users may only delete the URLs they own
+	user, ok := UserFromContext(ctx)
+	ownedOnly := ok && !user.IsAdmin()
+	resolved := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		url, err := s.findShortCode(ctx, code)
+		switch {
+		case errors.Is(err, ErrShortCodeNotFound):
+			result.NotFound = append(result.NotFound, code)
+		case err != nil:
+			return nil, err
+		case ownedOnly && url.OwnerID != user.ID:
+			result.Forbidden = append(result.Forbidden, code)
+		case !seen[url.ShortCode]:
+			seen[url.ShortCode] = true
+			resolved = append(resolved, url.ShortCode)
+		}
+	}
+	codes = resolved
+	if len(codes) == 0 {
+		return result, nil
+	}
+
+	deleted, failures, err := s.repo.BulkDelete(ctx, codes)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to bulk delete URLs", logger.LoggerInfo{
+			ContextFunction: constant.CtxBulkDeleteURLs,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeDeleteFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+			Data: map[string]interface{}{
+				constant.DataCount: len(codes),
+			},
+		})
+		return nil, err
+	}
+
+	notFound := make(map[string]bool, len(failures))
+	for _, failure := range failures {
+		notFound[failure.ShortCode] = true
+		result.NotFound = append(result.NotFound, failure.ShortCode)
+	}
+	for _, code := range codes {
+		s.cache.Invalidate(constant.ShortURLNamespace, code)
+		s.invalidateQRCodes(code)
+		if !notFound[code] {
+			s.opts.Events.Publish(ctx, events.URLDeletedEvent{ShortCode: code, OccurredAt: time.Now()})
+		}
+	}
+	result.Deleted = deleted
+
+	logger.CtxInfo(ctx, "URLs bulk deleted", logger.LoggerInfo{
+		ContextFunction: constant.CtxBulkDeleteURLs,
+		Data: map[string]interface{}{
+			constant.DataCount: deleted,
+		},
+	})
+
+	return result, nil
+}
+
+// createBulkShortURLs implements CreateBulkShortURLs
+func (s *Service) createBulkShortURLs(ctx context.Context, items []BulkURL) (*BulkStoreResult, error) {
+	logger.CtxDebug(ctx, "Creating short URLs in bulk", logger.LoggerInfo{
+		ContextFunction: constant.CtxBulkCreateURLs,
+		Data: map[string]interface{}{
+			constant.DataCount: len(items),
+		},
+	})
+
+	result := &BulkStoreResult{Errors: []BulkStoreError{}}
+	ownerID := UserIDFromContext(ctx)
+	now := time.Now()
+
+	// positions maps each URL handed to the repository back to its item
+	urls := make([]*URL, 0, len(items))
+	positions := make([]int, 0, len(items))
+	for i, item := range items {
+		shortCode := s.normalizeShortCode(item.CustomShort)
+		longURL, err := s.checkLongURL(ctx, constant.CtxBulkCreateURLs, item.LongURL)
+		if err == nil && shortCode != "" && s.isReserved(shortCode) {
+			err = ErrReservedShortCode
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, BulkStoreError{Index: i, ShortCode: shortCode, Reason: err.Error()})
+			continue
+		}
+
+		// Unlike CreateShortURL, a long URL shortened before is not reused, so every
+		// imported row keeps a code of its own
+		if shortCode == "" {
+			shortCode = s.generateShortCode()
+		}
+		urls = append(urls, &URL{
+			LongURL:      longURL,
+			ShortCode:    shortCode,
+			CreatedAt:    now,
+			RedirectCode: DefaultRedirectCode,
+			OwnerID:      ownerID,
+		})
+		positions = append(positions, i)
+	}
+
+	// Generated codes that turn out to be taken are regenerated and stored again
+	for attempt := 1; len(urls) > 0; attempt++ {
+		stored, err := s.repo.BulkStore(ctx, urls)
+		if err != nil {
+			logger.CtxError(ctx, "Failed to bulk store URLs", logger.LoggerInfo{
+				ContextFunction: constant.CtxBulkCreateURLs,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeStorageFailure,
+					Message: err.Error(),
+					Type:    constant.ErrTypeStorage,
+				},
+				Data: map[string]interface{}{
+					constant.DataCount: len(urls),
+				},
+			})
+			return nil, err
+		}
+		result.Stored += stored.Stored
+
+		var retryURLs []*URL
+		var retryPositions []int
+		failed := make(map[int]bool, len(stored.Errors))
+		for _, failure := range stored.Errors {
+			failed[failure.Index] = true
+			position := positions[failure.Index]
+			if items[position].CustomShort == "" && failure.Reason == constant.ErrShortCodeExists {
+				if attempt < s.opts.MaxCodeGenRetries {
+					url := urls[failure.Index]
+					url.ShortCode = s.generateShortCode()
+					retryURLs = append(retryURLs, url)
+					retryPositions = append(retryPositions, position)
+					continue
+				}
+				failure.Reason = constant.ErrTooManyCollisions
+			}
+			result.Errors = append(result.Errors, BulkStoreError{Index: position, ShortCode: failure.ShortCode, Reason: failure.Reason})
+		}
+
+		// The stored URLs are left out of the cache so a large import does not evict
+		// the URLs that are actually being visited
+		for i, url := range urls {
+			if failed[i] {
+				continue
+			}
+			s.opts.Events.Publish(ctx, events.URLCreatedEvent{
+				ShortCode:  url.ShortCode,
+				LongURL:    url.LongURL,
+				Snapshot:   snapshot(url),
+				OccurredAt: time.Now(),
+			})
+		}
+		urls, positions = retryURLs, retryPositions
+	}
+
+	result.Skipped = len(items) - result.Stored
+	sort.Slice(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Index < result.Errors[j].Index
+	})
+
+	logger.CtxInfo(ctx, "URLs bulk created", logger.LoggerInfo{
+		ContextFunction: constant.CtxBulkCreateURLs,
+		Data: map[string]interface{}{
+			constant.DataCount:  result.Stored,
+			constant.DataErrors: len(result.Errors),
+		},
+	})
+
+	return result, nil
+}
+
+// uniqueShortCodes drops empty and repeated short codes, keeping the first occurrence order
+func uniqueShortCodes(shortCodes []string) []string {
+	seen := make(map[string]bool, len(shortCodes))
+	codes := make([]string, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes
+}
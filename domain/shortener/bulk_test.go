@@ -0,0 +1,187 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_BulkDeleteURLs_Invalid(t *testing.T) {
+	tooMany := make([]string, MaxBulkDelete+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("code%d", i)
+	}
+
+	tests := []struct {
+		name       string
+		shortCodes []string
+	}{
+		{name: "Nil", shortCodes: nil},
+		{name: "Only empty codes", shortCodes: []string{"", ""}},
+		{name: "Too many", shortCodes: tooMany},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+			// Act
+			result, err := service.BulkDeleteURLs(context.Background(), tt.shortCodes)
+
+			// Assert
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, ErrInvalidBulkDelete)
+			mockRepo.AssertNotCalled(t, "BulkDelete", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_BulkDeleteURLs(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	lru := cache.NewNamespaceLRU(100)
+	service := NewService(mockRepo, lru, ServiceOptions{})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	lru.Set(constant.ShortURLNamespace, "abc123", &URL{ShortCode: "abc123"})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123"}, nil)
+	mockRepo.On("FindByShortCode", mock.Anything, "missing").Return((*URL)(nil), ErrShortCodeNotFound)
+	mockRepo.On("BulkDelete", mock.Anything, []string{"abc123"}).Return(1, []BulkDeleteError(nil), nil)
+
+	// Act
+	result, err := service.BulkDeleteURLs(context.Background(), []string{"abc123", "missing", "abc123", "ABC123", ""})
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &BulkDeleteResult{Deleted: 1, NotFound: []string{"missing"}}, result)
+	_, cached := lru.Get(constant.ShortURLNamespace, "abc123")
+	assert.False(t, cached)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_BulkDeleteURLs_Owner(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("FindByShortCode", mock.Anything, "mine").Return(&URL{ShortCode: "mine", OwnerID: 7}, nil)
+	mockRepo.On("FindByShortCode", mock.Anything, "theirs").Return(&URL{ShortCode: "theirs", OwnerID: 8}, nil)
+	mockRepo.On("FindByShortCode", mock.Anything, "missing").Return((*URL)(nil), ErrShortCodeNotFound)
+	mockRepo.On("BulkDelete", mock.Anything, []string{"mine"}).Return(1, []BulkDeleteError(nil), nil)
+	ctx := WithUser(context.Background(), &User{ID: 7, Role: RoleUser})
+
+	// Act
+	result, err := service.BulkDeleteURLs(ctx, []string{"mine", "theirs", "missing"})
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &BulkDeleteResult{Deleted: 1, NotFound: []string{"missing"}, Forbidden: []string{"theirs"}}, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_BulkDeleteURLs_StoredShortCode(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	lru := cache.NewNamespaceLRU(100)
+	service := NewService(mockRepo, lru, ServiceOptions{})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	lru.Set(constant.ShortURLNamespace, "AbC123", &URL{ShortCode: "AbC123"})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "AbC123"}, nil)
+	mockRepo.On("BulkDelete", mock.Anything, []string{"AbC123"}).Return(1, []BulkDeleteError(nil), nil)
+
+	// Act
+	result, err := service.BulkDeleteURLs(context.Background(), []string{"ABC123"})
+	waitForEvents(t, service)
+
+	// Assert - the URL is deleted under the code it is stored with
+	assert.NoError(t, err)
+	assert.Equal(t, &BulkDeleteResult{Deleted: 1, NotFound: []string{}}, result)
+	_, cached := lru.Get(constant.ShortURLNamespace, "AbC123")
+	assert.False(t, cached)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateBulkShortURLs(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	generator := &sequenceGenerator{codes: []string{"gen1", "gen2"}}
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{CodeGenerator: generator})
+	var audited []string
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { audited = append(audited, args.Get(1).(AuditEntry).ShortCode) }).
+		Return(nil)
+	var batches [][]*URL
+	mockRepo.On("BulkStore", mock.Anything, mock.MatchedBy(func(urls []*URL) bool { return len(urls) == 2 })).
+		Run(func(args mock.Arguments) { batches = append(batches, args.Get(1).([]*URL)) }).
+		Return(&BulkStoreResult{Stored: 1, Skipped: 1, Errors: []BulkStoreError{{Index: 1, ShortCode: "gen1", Reason: constant.ErrShortCodeExists}}}, nil).Once()
+	mockRepo.On("BulkStore", mock.Anything, mock.MatchedBy(func(urls []*URL) bool { return len(urls) == 1 })).
+		Run(func(args mock.Arguments) { batches = append(batches, args.Get(1).([]*URL)) }).
+		Return(&BulkStoreResult{Stored: 1, Errors: []BulkStoreError{}}, nil).Once()
+	ctx := WithUser(context.Background(), &User{ID: 7, Role: RoleUser})
+
+	// Act
+	result, err := service.CreateBulkShortURLs(ctx, []BulkURL{
+		{LongURL: "https://example.com/a", CustomShort: "first"},
+		{LongURL: "", CustomShort: "empty"},
+		{LongURL: "https://example.com/c", CustomShort: "api"},
+		{LongURL: "https://example.com/d"},
+	})
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &BulkStoreResult{Stored: 2, Skipped: 2, Errors: []BulkStoreError{
+		{Index: 1, ShortCode: "empty", Reason: ErrEmptyLongURL.Error()},
+		{Index: 2, ShortCode: "api", Reason: ErrReservedShortCode.Error()},
+	}}, result)
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, "first", batches[0][0].ShortCode)
+		assert.Equal(t, uint(7), batches[0][0].OwnerID)
+		assert.Equal(t, DefaultRedirectCode, batches[0][0].RedirectCode)
+		assert.Equal(t, "gen2", batches[1][0].ShortCode, "the taken generated code is replaced")
+	}
+	assert.ElementsMatch(t, []string{"first", "gen2"}, audited, "only stored URLs are audited")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateBulkShortURLs_TooManyCollisions(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	generator := &sequenceGenerator{codes: []string{"gen1", "gen2"}}
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{CodeGenerator: generator, MaxCodeGenRetries: 2})
+	mockRepo.On("BulkStore", mock.Anything, mock.Anything).
+		Return(&BulkStoreResult{Skipped: 1, Errors: []BulkStoreError{{Index: 0, ShortCode: "taken", Reason: constant.ErrShortCodeExists}}}, nil)
+
+	// Act
+	result, err := service.CreateBulkShortURLs(context.Background(), []BulkURL{{LongURL: "https://example.com"}})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &BulkStoreResult{Skipped: 1, Errors: []BulkStoreError{
+		{Index: 0, ShortCode: "taken", Reason: constant.ErrTooManyCollisions},
+	}}, result)
+	mockRepo.AssertNumberOfCalls(t, "BulkStore", 2)
+}
+
+func TestService_CreateBulkShortURLs_RepositoryFailure(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("BulkStore", mock.Anything, mock.Anything).Return((*BulkStoreResult)(nil), errors.New("database is closed"))
+
+	// Act
+	result, err := service.CreateBulkShortURLs(context.Background(), []BulkURL{{LongURL: "https://example.com", CustomShort: "abc123"}})
+
+	// Assert
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "database is closed")
+}
@@ -0,0 +1,152 @@
+package shortener
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+	"github.com/prasetyowira/shorter/infrastructure/urlnorm"
+)
+
+// MaxCanaryPercent sends every redirect to the alternate URL
+const MaxCanaryPercent = 100
+
+// checkCanary validates a traffic split and returns alternateURL normalized. The
+// alternate URL is checked by checkDestination; it may be set without a percentage,
+// which keeps it ready but unused.
+func (s *Service) checkCanary(ctx context.Context, function, alternateURL string, percent uint8) (string, error) {
+	reject := func(code, message string, err error) (string, error) {
+		logger.CtxWarn(ctx, "Invalid canary split", logger.LoggerInfo{
+			ContextFunction: function,
+			Error: &logger.CustomError{
+				Code:    code,
+				Message: message,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataAlternateURL: alternateURL,
+				constant.DataCanary:       percent,
+			},
+		})
+		return "", err
+	}
+
+	if percent > MaxCanaryPercent {
+		return reject(constant.ErrCodeInvalidCanary, constant.ErrInvalidCanary, ErrInvalidCanary)
+	}
+	if alternateURL == "" {
+		if percent > 0 {
+			return reject(constant.ErrCodeInvalidCanary, constant.ErrMissingAlternateURL, ErrMissingAlternateURL)
+		}
+		return "", nil
+	}
+
+	return s.checkDestination(ctx, function, alternateURL, constant.ErrCodeInvalidCanary, ErrInvalidAlternateURL)
+}
+
+// checkDestination returns rawURL, a redirect target other than the long URL,
+// normalized. It must be an absolute http or https URL, or invalid is returned and
+// logged with invalidCode, and it passes the blacklist and SSRF checks a long URL does.
+func (s *Service) checkDestination(ctx context.Context, function, rawURL, invalidCode string, invalid error) (string, error) {
+	reject := func(code, message string, err error) (string, error) {
+		logger.CtxWarn(ctx, "Invalid redirect destination", logger.LoggerInfo{
+			ContextFunction: function,
+			Error: &logger.CustomError{
+				Code:    code,
+				Message: message,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataTargetURL: rawURL,
+			},
+		})
+		return "", err
+	}
+
+	normalized, err := urlnorm.Normalize(rawURL)
+	if err != nil {
+		return reject(invalidCode, err.Error(), invalid)
+	}
+	parsedURL, err := url.Parse(normalized)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return reject(invalidCode, invalid.Error(), invalid)
+	}
+	if s.opts.Blacklist != nil && s.opts.Blacklist.IsBlocked(parsedURL.Hostname()) {
+		return reject(constant.ErrCodeBlacklistedURL, constant.ErrBlacklistedURL, ErrBlacklistedURL)
+	}
+	if s.opts.SSRFGuard != nil {
+		if private, err := s.opts.SSRFGuard.IsPrivateURL(normalized); err != nil || private {
+			return reject(constant.ErrCodeSSRFBlocked, constant.ErrSSRFBlocked, ErrSSRFBlocked)
+		}
+	}
+	return normalized, nil
+}
+
+// updateCanary implements UpdateCanary
+func (s *Service) updateCanary(ctx context.Context, shortCode, alternateURL string, percent uint8) (*URL, error) {
+	logger.CtxDebug(ctx, "Updating canary split", logger.LoggerInfo{
+		ContextFunction: constant.CtxUpdateCanary,
+		Data: map[string]interface{}{
+			constant.DataShortCode:    shortCode,
+			constant.DataAlternateURL: alternateURL,
+			constant.DataCanary:       percent,
+		},
+	})
+
+	if shortCode == "" {
+		return nil, ErrEmptyShortCode
+	}
+
+	alternateURL, err := s.checkCanary(ctx, constant.CtxUpdateCanary, alternateURL, percent)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.findShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeOwner(ctx, url); err != nil {
+		return nil, err
+	}
+	shortCode = url.ShortCode
+
+	if err := s.repo.UpdateCanary(ctx, shortCode, alternateURL, percent); err != nil {
+		logger.CtxError(ctx, "Failed to update canary split", logger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateCanary,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeUpdateFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	before := *url
+	url.AlternateURL = alternateURL
+	url.CanaryPercent = percent
+	s.opts.Events.Publish(ctx, events.URLUpdatedEvent{
+		ShortCode:  shortCode,
+		Before:     snapshot(&before),
+		After:      snapshot(url),
+		OccurredAt: time.Now(),
+	})
+	s.cacheURL(url)
+
+	logger.CtxInfo(ctx, "Canary split updated", logger.LoggerInfo{
+		ContextFunction: constant.CtxUpdateCanary,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataCanary:    percent,
+		},
+	})
+
+	return url, nil
+}
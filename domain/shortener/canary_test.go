@@ -0,0 +1,193 @@
+package shortener
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestURL_CanaryRedirectURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      URL
+		roll     int
+		expected string
+	}{
+		{name: "Roll below percent", url: URL{LongURL: "https://example.com/a", AlternateURL: "https://example.com/b", CanaryPercent: 30}, roll: 29, expected: "https://example.com/b"},
+		{name: "Roll at percent", url: URL{LongURL: "https://example.com/a", AlternateURL: "https://example.com/b", CanaryPercent: 30}, roll: 30, expected: "https://example.com/a"},
+		{name: "Full canary", url: URL{LongURL: "https://example.com/a", AlternateURL: "https://example.com/b", CanaryPercent: 100}, roll: 99, expected: "https://example.com/b"},
+		{name: "No split", url: URL{LongURL: "https://example.com/a", AlternateURL: "https://example.com/b"}, roll: 0, expected: "https://example.com/a"},
+		{name: "No alternate URL", url: URL{LongURL: "https://example.com/a", CanaryPercent: 50}, roll: 0, expected: "https://example.com/a"},
+		{name: "UTM parameters on alternate URL", url: URL{LongURL: "https://example.com/a", AlternateURL: "https://example.com/b", CanaryPercent: 50, UTMSource: "newsletter"}, roll: 0, expected: "https://example.com/b?utm_source=newsletter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act & Assert
+			assert.Equal(t, tt.expected, tt.url.CanaryRedirectURL(tt.roll))
+		})
+	}
+}
+
+func TestURL_CanaryRedirectURL_SplitRatio(t *testing.T) {
+	// Arrange - a seeded source makes the split reproducible
+	rolls := rand.New(rand.NewSource(42))
+	url := URL{LongURL: "https://example.com/a", AlternateURL: "https://example.com/b", CanaryPercent: 25}
+
+	// Act
+	alternate := 0
+	for i := 0; i < 1000; i++ {
+		if url.CanaryRedirectURL(rolls.Intn(MaxCanaryPercent)) == "https://example.com/b" {
+			alternate++
+		}
+	}
+
+	// Assert
+	assert.InDelta(t, 250, alternate, 40)
+}
+
+func TestService_CreateShortURL_Canary(t *testing.T) {
+	tests := []struct {
+		name          string
+		alternateURL  string
+		percent       uint8
+		expectedURL   string
+		expectedError error
+	}{
+		{name: "Valid", alternateURL: "HTTPS://Example.com/b", percent: 20, expectedURL: "https://example.com/b"},
+		{name: "Alternate URL without split", alternateURL: "https://example.com/b", expectedURL: "https://example.com/b"},
+		{name: "Percent above 100", alternateURL: "https://example.com/b", percent: 101, expectedError: ErrInvalidCanary},
+		{name: "Percent without alternate URL", percent: 20, expectedError: ErrMissingAlternateURL},
+		{name: "Invalid alternate URL", alternateURL: "not a url", percent: 20, expectedError: ErrInvalidAlternateURL},
+		{name: "Blacklisted alternate URL", alternateURL: "https://evil.com/b", percent: 20, expectedError: ErrBlacklistedURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{Blacklist: stubBlacklist{"evil.com": true}})
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			// Act
+			url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com/a", "custom", CreateURLParams{
+				AlternateURL:  tt.alternateURL,
+				CanaryPercent: tt.percent,
+			})
+
+			// Assert
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedURL, url.AlternateURL)
+			assert.Equal(t, tt.percent, url.CanaryPercent)
+		})
+	}
+}
+
+func TestService_UpdateCanary(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	lru := cache.NewNamespaceLRU(100)
+	service := NewService(mockRepo, lru, ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com/a", OwnerID: 7}, nil)
+	mockRepo.On("UpdateCanary", mock.Anything, "abc123", "https://example.com/b", uint8(40)).Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	ctx := WithUser(context.Background(), &User{ID: 7, Username: "alice", Role: RoleUser})
+
+	// Act
+	url, err := service.UpdateCanary(ctx, "abc123", "https://example.com/b", 40)
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", url.AlternateURL)
+	assert.Equal(t, uint8(40), url.CanaryPercent)
+	cached, found := lru.Get(constant.ShortURLNamespace, "abc123")
+	assert.True(t, found)
+	assert.Equal(t, url, cached)
+	mockRepo.AssertCalled(t, "AppendAudit", mock.Anything, mock.MatchedBy(func(entry AuditEntry) bool {
+		return entry.Action == AuditActionUpdate && entry.ShortCode == "abc123"
+	}))
+}
+
+func TestService_UpdateCanary_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		user          *User
+		alternateURL  string
+		percent       uint8
+		expectedError error
+	}{
+		{name: "Invalid split", alternateURL: "", percent: 10, expectedError: ErrMissingAlternateURL},
+		{name: "Not owner", user: &User{ID: 8, Role: RoleUser}, alternateURL: "https://example.com/b", percent: 10, expectedError: ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", OwnerID: 7}, nil)
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = WithUser(ctx, tt.user)
+			}
+
+			// Act
+			_, err := service.UpdateCanary(ctx, "abc123", tt.alternateURL, tt.percent)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.expectedError)
+			mockRepo.AssertNotCalled(t, "UpdateCanary", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateShortURL_CanaryNotReused(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *URL
+		params   CreateURLParams
+	}{
+		{
+			name:     "Existing splits traffic",
+			existing: &URL{ShortCode: "abc123", LongURL: "https://example.com/a", AlternateURL: "https://example.com/b", CanaryPercent: 20},
+		},
+		{
+			name:     "New splits traffic",
+			existing: &URL{ShortCode: "abc123", LongURL: "https://example.com/a"},
+			params:   CreateURLParams{AlternateURL: "https://example.com/b", CanaryPercent: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByLongURL", mock.Anything, "https://example.com/a").Return(tt.existing, nil)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			// Act
+			url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com/a", "", tt.params)
+			waitForEvents(t, service)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.NotEqual(t, "abc123", url.ShortCode)
+			assert.Equal(t, tt.params.AlternateURL, url.AlternateURL)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
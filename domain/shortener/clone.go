@@ -0,0 +1,68 @@
+package shortener
+
+import (
+	"context"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// cloneURL implements CloneURL
+func (s *Service) cloneURL(ctx context.Context, shortCode, newShortCode string) (*URL, error) {
+	logger.CtxDebug(ctx, "Cloning short URL", logger.LoggerInfo{
+		ContextFunction: constant.CtxCloneURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode:    shortCode,
+			constant.DataNewShortCode: newShortCode,
+		},
+	})
+
+	// Looked up rather than resolved so that cloning does not count as a visit
+	source, err := s.lookupURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeOwner(ctx, source); err != nil {
+		return nil, err
+	}
+
+	// The source may be cached and shared, so nothing it points to is reused
+	params := CreateURLParams{
+		RedirectCode:  source.RedirectCode,
+		OwnerID:       UserIDFromContext(ctx),
+		UTMSource:     source.UTMSource,
+		UTMCampaign:   source.UTMCampaign,
+		UTMMedium:     source.UTMMedium,
+		AlternateURL:  source.AlternateURL,
+		CanaryPercent: source.CanaryPercent,
+		GeoRules:      source.GeoRules,
+		passwordHash:  source.Password,
+		fresh:         true,
+	}
+	if source.MaxVisits != nil {
+		maxVisits := *source.MaxVisits
+		params.MaxVisits = &maxVisits
+	}
+	if source.ExpiresAt != nil {
+		expiresAt := *source.ExpiresAt
+		params.ExpiresAt = &expiresAt
+	}
+	if source.ActiveFrom != nil {
+		activeFrom := *source.ActiveFrom
+		params.ActiveFrom = &activeFrom
+	}
+
+	clone, err := s.createShortURLWithParams(ctx, source.LongURL, newShortCode, params)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.CtxInfo(ctx, "Short URL cloned", logger.LoggerInfo{
+		ContextFunction: constant.CtxCloneURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode:    shortCode,
+			constant.DataNewShortCode: clone.ShortCode,
+		},
+	})
+	return clone, nil
+}
@@ -0,0 +1,99 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_CloneURL(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	maxVisits := uint(10)
+	expiresAt := time.Now().Add(time.Hour)
+	source := &URL{
+		ShortCode:     "abc123",
+		LongURL:       "https://example.com/a",
+		Visits:        5,
+		RedirectCode:  301,
+		OwnerID:       7,
+		MaxVisits:     &maxVisits,
+		ExpiresAt:     &expiresAt,
+		UTMSource:     "newsletter",
+		AlternateURL:  "https://example.com/b",
+		CanaryPercent: 20,
+		GeoRules:      []GeoRule{{CountryCodes: []string{"ID"}, TargetURL: "https://example.com/id"}},
+		Password:      "$2a$10$hash",
+		IsProtected:   true,
+	}
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(source, nil)
+	// The source shares the long URL but must not be handed back as the clone
+	mockRepo.On("FindByLongURL", mock.Anything, "https://example.com/a").Return(source, nil).Maybe()
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+	ctx := WithUser(context.Background(), &User{ID: 7, Role: RoleUser})
+
+	// Act
+	clone, err := service.CloneURL(ctx, "abc123", "")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotEqual(t, "abc123", clone.ShortCode)
+	assert.Equal(t, "https://example.com/a", clone.LongURL)
+	assert.Zero(t, clone.Visits)
+	assert.Equal(t, 301, clone.RedirectCode)
+	assert.Equal(t, uint(7), clone.OwnerID)
+	assert.Equal(t, "newsletter", clone.UTMSource)
+	assert.Equal(t, "https://example.com/b", clone.AlternateURL)
+	assert.Equal(t, uint8(20), clone.CanaryPercent)
+	assert.Equal(t, source.GeoRules, clone.GeoRules)
+	assert.Equal(t, "$2a$10$hash", clone.Password)
+	assert.True(t, clone.IsProtected)
+	if assert.NotNil(t, clone.MaxVisits) && assert.NotNil(t, clone.ExpiresAt) {
+		assert.Equal(t, maxVisits, *clone.MaxVisits)
+		assert.True(t, expiresAt.Equal(*clone.ExpiresAt))
+		assert.NotSame(t, source.MaxVisits, clone.MaxVisits)
+		assert.NotSame(t, source.ExpiresAt, clone.ExpiresAt)
+	}
+	mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+}
+
+func TestService_CloneURL_Errors(t *testing.T) {
+	source := &URL{ShortCode: "abc123", LongURL: "https://example.com/a", OwnerID: 7}
+
+	tests := []struct {
+		name          string
+		source        *URL
+		findErr       error
+		newShortCode  string
+		user          *User
+		expectedError error
+	}{
+		{name: "Source not found", findErr: ErrShortCodeNotFound, user: &User{ID: 7, Role: RoleUser}, expectedError: ErrShortCodeNotFound},
+		{name: "Not the owner", source: source, user: &User{ID: 8, Role: RoleUser}, expectedError: ErrForbidden},
+		{name: "Reserved new code", source: source, newShortCode: "api", user: &User{ID: 7, Role: RoleUser}, expectedError: ErrReservedShortCode},
+		{name: "New code taken", source: source, newShortCode: "taken", user: &User{ID: 7, Role: RoleUser}, expectedError: ErrShortCodeExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(tt.source, tt.findErr)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(ErrShortCodeExists).Maybe()
+
+			// Act
+			clone, err := service.CloneURL(WithUser(context.Background(), tt.user), "abc123", tt.newShortCode)
+
+			// Assert
+			assert.Nil(t, clone)
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
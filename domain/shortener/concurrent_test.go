@@ -0,0 +1,225 @@
+package shortener_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/prasetyowira/shorter/infrastructure/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrentCreators is the number of goroutines creating short URLs at once
+const concurrentCreators = 50
+
+// concurrentBackends open each repository the concurrency tests run against
+var concurrentBackends = []struct {
+	name string
+	open func(t *testing.T) shortener.Repository
+}{
+	{name: "Memory", open: func(t *testing.T) shortener.Repository {
+		return db.NewMemoryRepository()
+	}},
+	{name: "SQLite", open: func(t *testing.T) shortener.Repository {
+		repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), "concurrent.db"), cache.NewNamespaceLRU(100))
+		if err != nil {
+			t.Fatalf("Failed to create test repository: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	}},
+}
+
+// pairedGenerator hands out every code twice, so that one of each pair collides
+type pairedGenerator struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (g *pairedGenerator) Generate() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	code := fmt.Sprintf("pair%d", g.calls/2)
+	g.calls++
+	return code
+}
+
+// createConcurrently calls CreateShortURL from concurrentCreators goroutines at once,
+// each with its own long URL, and returns their errors
+func createConcurrently(service *shortener.Service, customShort string) []error {
+	errs := make([]error, concurrentCreators)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentCreators; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = service.CreateShortURL(context.Background(), 0, fmt.Sprintf("https://example.com/%d", i), customShort)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func TestConcurrent_CreateShortURL_SameCustomCode(t *testing.T) {
+	for _, backend := range concurrentBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			repo := backend.open(t)
+			service := shortener.NewService(repo, cache.NewNamespaceLRU(100), shortener.ServiceOptions{})
+
+			// Act
+			errs := createConcurrently(service, "launch")
+
+			// Assert
+			succeeded := 0
+			for _, err := range errs {
+				if err == nil {
+					succeeded++
+					continue
+				}
+				assert.True(t, errors.Is(err, shortener.ErrShortCodeExists), "unexpected error: %v", err)
+			}
+			assert.Equal(t, 1, succeeded)
+		})
+	}
+}
+
+func TestConcurrent_CreateShortURL_GeneratedCodes(t *testing.T) {
+	for _, backend := range concurrentBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			repo := backend.open(t)
+			generator := &pairedGenerator{}
+			service := shortener.NewService(repo, cache.NewNamespaceLRU(100), shortener.ServiceOptions{
+				CodeGenerator: generator,
+				// A goroutine may draw the losing half of several pairs in a row
+				MaxCodeGenRetries: concurrentCreators,
+			})
+
+			// Act
+			errs := createConcurrently(service, "")
+
+			// Assert
+			for _, err := range errs {
+				assert.NoError(t, err)
+			}
+			// Each stored code was handed out twice, so the retries did resolve collisions
+			assert.GreaterOrEqual(t, generator.calls, 2*concurrentCreators-1)
+		})
+	}
+}
+
+// racingRepository hands transactions a repository that lets hook run as soon as the
+// transaction has read a URL, and that fails audit writes when failAudit is set
+type racingRepository struct {
+	shortener.Repository
+	hook      func(ctx context.Context, shortCode string)
+	failAudit bool
+}
+
+func (r *racingRepository) WithTransaction(ctx context.Context, fn func(tx shortener.Repository) error) error {
+	return r.Repository.WithTransaction(ctx, func(tx shortener.Repository) error {
+		return fn(&racingTx{Repository: tx, outer: r})
+	})
+}
+
+// racingTx is the transaction side of a racingRepository
+type racingTx struct {
+	shortener.Repository
+	outer *racingRepository
+}
+
+func (tx *racingTx) FindByShortCode(ctx context.Context, shortCode string) (*shortener.URL, error) {
+	url, err := tx.Repository.FindByShortCode(ctx, shortCode)
+	if err == nil && tx.outer.hook != nil {
+		tx.outer.hook(ctx, shortCode)
+	}
+	return url, err
+}
+
+func (tx *racingTx) AppendAudit(ctx context.Context, entry shortener.AuditEntry) error {
+	if tx.outer.failAudit {
+		return errors.New("audit log is unavailable")
+	}
+	return tx.Repository.AppendAudit(ctx, entry)
+}
+
+// updateAudits returns the update entries in the audit log of shortCode
+func updateAudits(t *testing.T, repo shortener.Repository, shortCode string) []shortener.AuditEntry {
+	entries, err := repo.FindAudits(context.Background(), shortCode)
+	assert.NoError(t, err)
+	var updates []shortener.AuditEntry
+	for _, entry := range entries {
+		if entry.Action == shortener.AuditActionUpdate {
+			updates = append(updates, entry)
+		}
+	}
+	return updates
+}
+
+func TestConcurrent_UpdateLongURL_DeletedDuringUpdate(t *testing.T) {
+	// Arrange - WAL mode lets the delete commit on a second connection while the update's
+	// transaction is open, as a delete from another request would
+	ctx := context.Background()
+	repo, err := db.NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "race.db"), cache.NewNamespaceLRU(100), db.SQLiteOptions{WALMode: true, MaxOpenConns: 2})
+	if err != nil {
+		t.Fatalf("Failed to create test repository: %v", err)
+	}
+	defer repo.Close()
+	var deleteErr error
+	racing := &racingRepository{Repository: repo, hook: func(ctx context.Context, shortCode string) {
+		deleteErr = repo.Delete(ctx, shortCode)
+	}}
+	urlCache := cache.NewNamespaceLRU(100)
+	service := shortener.NewService(racing, urlCache, shortener.ServiceOptions{})
+	_, err = service.CreateShortURL(ctx, 0, "https://example.com/old", "racing")
+	assert.NoError(t, err)
+
+	// Act
+	url, err := service.UpdateLongURL(ctx, "racing", "https://example.com/new")
+
+	// Assert - the update rolled back and the delete stands
+	assert.NoError(t, deleteErr)
+	assert.Error(t, err)
+	assert.Nil(t, url)
+	_, err = repo.FindByShortCode(ctx, "racing")
+	assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
+	assert.Empty(t, updateAudits(t, repo, "racing"))
+	if cached, ok := urlCache.Get(constant.ShortURLNamespace, "racing"); ok {
+		assert.Equal(t, "https://example.com/old", cached.(*shortener.URL).LongURL)
+	}
+}
+
+func TestConcurrent_UpdateLongURL_AuditFailureRollsBack(t *testing.T) {
+	for _, backend := range concurrentBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			repo := backend.open(t)
+			racing := &racingRepository{Repository: repo, failAudit: true}
+			service := shortener.NewService(racing, cache.NewNamespaceLRU(100), shortener.ServiceOptions{})
+			_, err := service.CreateShortURL(ctx, 0, "https://example.com/old", "audited")
+			assert.NoError(t, err)
+
+			// Act
+			url, err := service.UpdateLongURL(ctx, "audited", "https://example.com/new")
+
+			// Assert
+			assert.EqualError(t, err, "audit log is unavailable")
+			assert.Nil(t, url)
+			stored, err := repo.FindByShortCode(ctx, "audited")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com/old", stored.LongURL)
+			assert.Empty(t, updateAudits(t, repo, "audited"))
+		})
+	}
+}
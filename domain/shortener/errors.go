@@ -0,0 +1,82 @@
+package shortener
+
+import (
+	"errors"
+
+	"github.com/prasetyowira/shorter/constant"
+)
+
+// DomainError is an error of the shortener domain. Code tells errors apart for
+// callers, who find it with errors.As or CodeOf; Message is meant for people.
+type DomainError struct {
+	Code    string
+	Message string
+}
+
+// Error implements error
+func (e *DomainError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a DomainError with the same code, so errors.Is
+// matches a sentinel below by code as well as by identity
+func (e *DomainError) Is(target error) bool {
+	var domainErr *DomainError
+	return errors.As(target, &domainErr) && domainErr.Code == e.Code
+}
+
+// CodeOf returns the code of the first DomainError in err's chain, or an empty
+// string when there is none
+func CodeOf(err error) string {
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ""
+}
+
+// Errors returned by the service and by Repository implementations. Each has a code
+// of its own, and matching keeps working when an error is wrapped with more context.
+var (
+	ErrEmptyLongURL        = &DomainError{Code: constant.ErrCodeEmptyLongURL, Message: constant.ErrEmptyLongURL}
+	ErrEmptyShortCode      = &DomainError{Code: constant.ErrCodeEmptyShortCode, Message: constant.ErrEmptyShortCode}
+	ErrShortCodeExists     = &DomainError{Code: constant.ErrCodeShortCodeExists, Message: constant.ErrShortCodeExists}
+	ErrTooManyCollisions   = &DomainError{Code: constant.ErrCodeTooManyCollisions, Message: constant.ErrTooManyCollisions}
+	ErrShortCodeNotFound   = &DomainError{Code: constant.ErrCodeShortCodeNotFound, Message: constant.ErrShortCodeNotFound}
+	ErrLongURLNotFound     = &DomainError{Code: constant.ErrCodeLongURLNotFound, Message: constant.ErrLongURLNotFound}
+	ErrBlacklistedURL      = &DomainError{Code: constant.ErrCodeBlacklistedURL, Message: constant.ErrBlacklistedURL}
+	ErrInvalidLongURL      = &DomainError{Code: constant.ErrCodeInvalidLongURL, Message: constant.ErrInvalidLongURL}
+	ErrReservedShortCode   = &DomainError{Code: constant.ErrCodeReservedShortCode, Message: constant.ErrReservedShortCode}
+	ErrInvalidPassword     = &DomainError{Code: constant.ErrCodeInvalidPassword, Message: constant.ErrInvalidPassword}
+	ErrInvalidRedirectCode = &DomainError{Code: constant.ErrCodeInvalidRedirectCode, Message: constant.ErrInvalidRedirectCode}
+	ErrShortCodeExpired    = &DomainError{Code: constant.ErrCodeShortCodeExpired, Message: constant.ErrShortCodeExpired}
+	ErrInvalidExpiry       = &DomainError{Code: constant.ErrCodeInvalidExpiry, Message: constant.ErrInvalidExpiry}
+	ErrSSRFBlocked         = &DomainError{Code: constant.ErrCodeSSRFBlocked, Message: constant.ErrSSRFBlocked}
+	ErrURLUnreachable      = &DomainError{Code: constant.ErrCodeURLUnreachable, Message: constant.ErrURLUnreachable}
+	ErrEmptyUsername       = &DomainError{Code: constant.ErrCodeEmptyUsername, Message: constant.ErrEmptyUsername}
+	ErrEmptyUserPassword   = &DomainError{Code: constant.ErrCodeEmptyUserPassword, Message: constant.ErrEmptyUserPassword}
+	ErrInvalidRole         = &DomainError{Code: constant.ErrCodeInvalidRole, Message: constant.ErrInvalidRole}
+	ErrUserExists          = &DomainError{Code: constant.ErrCodeUserExists, Message: constant.ErrUserExists}
+	ErrUserNotFound        = &DomainError{Code: constant.ErrCodeUserNotFound, Message: constant.ErrUserNotFound}
+	ErrInvalidCredentials  = &DomainError{Code: constant.ErrCodeInvalidCredentials, Message: constant.ErrInvalidCredentials}
+	ErrForbidden           = &DomainError{Code: constant.ErrCodeForbidden, Message: constant.ErrForbidden}
+	ErrInvalidTag          = &DomainError{Code: constant.ErrCodeInvalidTag, Message: constant.ErrInvalidTag}
+	ErrTagNotFound         = &DomainError{Code: constant.ErrCodeTagNotFound, Message: constant.ErrTagNotFound}
+	ErrInvalidBulkDelete   = &DomainError{Code: constant.ErrCodeInvalidBulkDelete, Message: constant.ErrInvalidBulkDelete}
+	ErrInvalidSearchLimit  = &DomainError{Code: constant.ErrCodeInvalidSearchLimit, Message: constant.ErrInvalidSearchLimit}
+	ErrInvalidSearchOffset = &DomainError{Code: constant.ErrCodeInvalidSearchOffset, Message: constant.ErrInvalidSearchOffset}
+	ErrInvalidWebhookURL   = &DomainError{Code: constant.ErrCodeInvalidWebhookURL, Message: constant.ErrInvalidWebhookURL}
+	ErrEmptyWebhookSecret  = &DomainError{Code: constant.ErrCodeEmptyWebhookSecret, Message: constant.ErrEmptyWebhookSecret}
+	ErrInvalidWebhookEvent = &DomainError{Code: constant.ErrCodeInvalidWebhookEvent, Message: constant.ErrInvalidWebhookEvent}
+	ErrWebhookNotFound     = &DomainError{Code: constant.ErrCodeWebhookNotFound, Message: constant.ErrWebhookNotFound}
+	ErrInvalidGranularity  = &DomainError{Code: constant.ErrCodeInvalidGranularity, Message: constant.ErrInvalidGranularity}
+	ErrInvalidTimeRange    = &DomainError{Code: constant.ErrCodeInvalidTimeRange, Message: constant.ErrInvalidTimeRange}
+	ErrInvalidPeriod       = &DomainError{Code: constant.ErrCodeInvalidPeriod, Message: constant.ErrInvalidPeriod}
+	ErrInvalidCanary       = &DomainError{Code: constant.ErrCodeInvalidCanary, Message: constant.ErrInvalidCanary}
+	ErrMissingAlternateURL = &DomainError{Code: constant.ErrCodeMissingAlternateURL, Message: constant.ErrMissingAlternateURL}
+	ErrInvalidAlternateURL = &DomainError{Code: constant.ErrCodeInvalidAlternateURL, Message: constant.ErrInvalidAlternateURL}
+	ErrInvalidGeoRule      = &DomainError{Code: constant.ErrCodeInvalidGeoRule, Message: constant.ErrInvalidGeoRule}
+	ErrInvalidGeoTarget    = &DomainError{Code: constant.ErrCodeInvalidGeoTarget, Message: constant.ErrInvalidGeoTarget}
+	ErrURLNotYetActive     = &DomainError{Code: constant.ErrCodeURLNotYetActive, Message: constant.ErrURLNotYetActive}
+	ErrInvalidActiveFrom   = &DomainError{Code: constant.ErrCodeInvalidActiveFrom, Message: constant.ErrInvalidActiveFrom}
+)
@@ -0,0 +1,105 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// allDomainErrors lists every sentinel of errors.go
+var allDomainErrors = []*DomainError{
+	ErrEmptyLongURL, ErrEmptyShortCode, ErrShortCodeExists, ErrTooManyCollisions, ErrShortCodeNotFound,
+	ErrLongURLNotFound, ErrBlacklistedURL, ErrInvalidLongURL, ErrReservedShortCode, ErrInvalidPassword,
+	ErrInvalidRedirectCode, ErrShortCodeExpired, ErrInvalidExpiry, ErrSSRFBlocked, ErrURLUnreachable,
+	ErrEmptyUsername, ErrEmptyUserPassword, ErrInvalidRole, ErrUserExists, ErrUserNotFound,
+	ErrInvalidCredentials, ErrForbidden, ErrInvalidTag, ErrTagNotFound, ErrInvalidBulkDelete,
+	ErrInvalidSearchLimit, ErrInvalidSearchOffset, ErrInvalidWebhookURL, ErrEmptyWebhookSecret, ErrInvalidWebhookEvent,
+	ErrWebhookNotFound, ErrInvalidGranularity, ErrInvalidTimeRange, ErrInvalidPeriod, ErrInvalidCanary,
+	ErrMissingAlternateURL, ErrInvalidAlternateURL, ErrInvalidGeoRule, ErrInvalidGeoTarget, ErrURLNotYetActive,
+	ErrInvalidActiveFrom,
+}
+
+func TestDomainError_CodesAreUnique(t *testing.T) {
+	// Callers tell domain errors apart by code, so no two may share one
+	seen := make(map[string]string, len(allDomainErrors))
+	for _, domainErr := range allDomainErrors {
+		require.NotEmpty(t, domainErr.Code, domainErr.Message)
+		require.NotEmpty(t, domainErr.Message, domainErr.Code)
+		if other, found := seen[domainErr.Code]; found {
+			t.Errorf("%q and %q share code %s", other, domainErr.Message, domainErr.Code)
+		}
+		seen[domainErr.Code] = domainErr.Message
+	}
+}
+
+func TestDomainError_Error(t *testing.T) {
+	assert.EqualError(t, ErrShortCodeNotFound, constant.ErrShortCodeNotFound)
+}
+
+func TestDomainError_As(t *testing.T) {
+	// Arrange
+	err := fmt.Errorf("renaming abc123: %w", ErrShortCodeExists)
+
+	// Act
+	var domainErr *DomainError
+	found := errors.As(err, &domainErr)
+
+	// Assert
+	require.True(t, found)
+	assert.Equal(t, constant.ErrCodeShortCodeExists, domainErr.Code)
+	assert.Equal(t, constant.ErrShortCodeExists, domainErr.Message)
+}
+
+func TestDomainError_Is(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		target   error
+		expected bool
+	}{
+		{name: "Same sentinel", err: ErrForbidden, target: ErrForbidden, expected: true},
+		{name: "Wrapped sentinel", err: fmt.Errorf("deleting abc123: %w", ErrForbidden), target: ErrForbidden, expected: true},
+		{name: "Same code", err: &DomainError{Code: constant.ErrCodeForbidden, Message: "not yours"}, target: ErrForbidden, expected: true},
+		{name: "Other code", err: ErrShortCodeNotFound, target: ErrForbidden, expected: false},
+		{name: "Same message, other code", err: &DomainError{Code: "SVC999", Message: constant.ErrForbidden}, target: ErrForbidden, expected: false},
+		{name: "Plain error", err: errors.New(constant.ErrForbidden), target: ErrForbidden, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := errors.Is(tt.err, tt.target)
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "Nil", err: nil, expected: ""},
+		{name: "Plain error", err: errors.New("disk full"), expected: ""},
+		{name: "Domain error", err: ErrTagNotFound, expected: constant.ErrCodeTagNotFound},
+		{name: "Wrapped domain error", err: fmt.Errorf("removing tag: %w", ErrTagNotFound), expected: constant.ErrCodeTagNotFound},
+		{name: "Joined errors", err: errors.Join(errors.New("disk full"), ErrUserExists), expected: constant.ErrCodeUserExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			code := CodeOf(tt.err)
+
+			// Assert
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+}
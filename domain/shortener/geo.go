@@ -0,0 +1,89 @@
+package shortener
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// GeoRule sends visitors from any of CountryCodes to TargetURL instead of LongURL
+type GeoRule struct {
+	// CountryCodes are upper-case ISO 3166-1 alpha-2 codes such as US or ID
+	CountryCodes []string `json:"country_codes"`
+	TargetURL    string   `json:"target_url"`
+}
+
+// matches reports whether country is one of the rule's countries
+func (r GeoRule) matches(country string) bool {
+	for _, code := range r.CountryCodes {
+		if code == country {
+			return true
+		}
+	}
+	return false
+}
+
+// GeoRedirectURL returns the target of the first geo rule matching country, the
+// visitor's ISO 3166-1 alpha-2 code, with UTM parameters appended as by RedirectURL.
+// It reports false when the country is unknown or no rule matches.
+func (u *URL) GeoRedirectURL(country string) (string, bool) {
+	country = strings.ToUpper(country)
+	if country == "" {
+		return "", false
+	}
+	for _, rule := range u.GeoRules {
+		if rule.matches(country) {
+			return u.withUTM(rule.TargetURL), true
+		}
+	}
+	return "", false
+}
+
+// checkGeoRules validates rules and returns them with country codes upper-cased
+// and target URLs normalized. Targets are checked by checkDestination.
+func (s *Service) checkGeoRules(ctx context.Context, function string, rules []GeoRule) ([]GeoRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	checked := make([]GeoRule, 0, len(rules))
+	for _, rule := range rules {
+		codes := make([]string, 0, len(rule.CountryCodes))
+		for _, code := range rule.CountryCodes {
+			code = strings.ToUpper(strings.TrimSpace(code))
+			if !isCountryCode(code) {
+				codes = nil
+				break
+			}
+			codes = append(codes, code)
+		}
+		if len(codes) == 0 {
+			logger.CtxWarn(ctx, "Invalid geo rule", logger.LoggerInfo{
+				ContextFunction: function,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeInvalidGeoRule,
+					Message: constant.ErrInvalidGeoRule,
+					Type:    constant.ErrTypeValidation,
+				},
+				Data: map[string]interface{}{
+					constant.DataGeoRules: rule.CountryCodes,
+				},
+			})
+			return nil, ErrInvalidGeoRule
+		}
+
+		target, err := s.checkDestination(ctx, function, rule.TargetURL, constant.ErrCodeInvalidGeoRule, ErrInvalidGeoTarget)
+		if err != nil {
+			return nil, err
+		}
+		checked = append(checked, GeoRule{CountryCodes: codes, TargetURL: target})
+	}
+	return checked, nil
+}
+
+// isCountryCode reports whether code has the shape of an upper-case ISO 3166-1 alpha-2 code
+func isCountryCode(code string) bool {
+	return len(code) == 2 && code[0] >= 'A' && code[0] <= 'Z' && code[1] >= 'A' && code[1] <= 'Z'
+}
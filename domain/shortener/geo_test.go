@@ -0,0 +1,125 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestURL_GeoRedirectURL(t *testing.T) {
+	url := URL{
+		LongURL: "https://example.com/a",
+		GeoRules: []GeoRule{
+			{CountryCodes: []string{"ID", "MY"}, TargetURL: "https://example.com/asia"},
+			{CountryCodes: []string{"DE"}, TargetURL: "https://example.de/a"},
+			{CountryCodes: []string{"MY"}, TargetURL: "https://example.com/unreachable"},
+		},
+		UTMSource: "newsletter",
+	}
+
+	tests := []struct {
+		name     string
+		country  string
+		expected string
+		matched  bool
+	}{
+		{name: "First rule", country: "ID", expected: "https://example.com/asia?utm_source=newsletter", matched: true},
+		{name: "First matching rule wins", country: "MY", expected: "https://example.com/asia?utm_source=newsletter", matched: true},
+		{name: "Later rule", country: "DE", expected: "https://example.de/a?utm_source=newsletter", matched: true},
+		{name: "Lower-case country", country: "de", expected: "https://example.de/a?utm_source=newsletter", matched: true},
+		{name: "No matching rule", country: "US"},
+		{name: "Unknown country", country: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			target, matched := url.GeoRedirectURL(tt.country)
+
+			// Assert
+			assert.Equal(t, tt.matched, matched)
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+}
+
+func TestService_CreateShortURL_GeoRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		rules         []GeoRule
+		expected      []GeoRule
+		expectedError error
+	}{
+		{name: "No rules"},
+		{name: "Valid", rules: []GeoRule{{CountryCodes: []string{" id", "MY"}, TargetURL: "HTTPS://Example.com/asia"}}, expected: []GeoRule{{CountryCodes: []string{"ID", "MY"}, TargetURL: "https://example.com/asia"}}},
+		{name: "No country codes", rules: []GeoRule{{TargetURL: "https://example.com/asia"}}, expectedError: ErrInvalidGeoRule},
+		{name: "Invalid country code", rules: []GeoRule{{CountryCodes: []string{"IDN"}, TargetURL: "https://example.com/asia"}}, expectedError: ErrInvalidGeoRule},
+		{name: "Invalid target URL", rules: []GeoRule{{CountryCodes: []string{"ID"}, TargetURL: "not a url"}}, expectedError: ErrInvalidGeoTarget},
+		{name: "Blacklisted target URL", rules: []GeoRule{{CountryCodes: []string{"ID"}, TargetURL: "https://evil.com/asia"}}, expectedError: ErrBlacklistedURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{Blacklist: stubBlacklist{"evil.com": true}})
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			// Act
+			url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com/a", "custom", CreateURLParams{GeoRules: tt.rules})
+
+			// Assert
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, url.GeoRules)
+		})
+	}
+}
+
+func TestService_CreateShortURL_GeoRulesNotReused(t *testing.T) {
+	rules := []GeoRule{{CountryCodes: []string{"ID"}, TargetURL: "https://example.com/id"}}
+	tests := []struct {
+		name     string
+		existing *URL
+		params   CreateURLParams
+	}{
+		{
+			name:     "Existing has geo rules",
+			existing: &URL{ShortCode: "abc123", LongURL: "https://example.com", GeoRules: rules},
+		},
+		{
+			name:     "New has geo rules",
+			existing: &URL{ShortCode: "abc123", LongURL: "https://example.com"},
+			params:   CreateURLParams{GeoRules: rules},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return(tt.existing, nil)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			// Act
+			url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com", "", tt.params)
+			waitForEvents(t, service)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.NotEqual(t, "abc123", url.ShortCode)
+			assert.Len(t, url.GeoRules, len(tt.params.GeoRules))
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
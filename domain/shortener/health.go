@@ -0,0 +1,68 @@
+package shortener
+
+import (
+	"context"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// Component health states
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// healthCheckTimeout bounds how long a single component check may take
+const healthCheckTimeout = 2 * time.Second
+
+// HealthReport is the state of each component the service depends on
+type HealthReport struct {
+	DB    string `json:"db"`
+	Cache string `json:"cache"`
+}
+
+// Healthy reports whether every component is ok
+func (h HealthReport) Healthy() bool {
+	return h.DB == HealthOK && h.Cache == HealthOK
+}
+
+// Health checks that the database and cache are reachable
+func (s *Service) Health(ctx context.Context) HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	return HealthReport{
+		DB:    componentHealth(ctx, "db", s.repo.Ping(ctx)),
+		Cache: componentHealth(ctx, "cache", s.cache.Ping()),
+	}
+}
+
+// IsReady reports whether the service can serve requests, which requires a reachable database
+func (s *Service) IsReady(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	return componentHealth(ctx, "db", s.repo.Ping(ctx)) == HealthOK
+}
+
+// componentHealth maps the result of a component check to its state, logging failures
+func componentHealth(ctx context.Context, component string, err error) string {
+	if err == nil {
+		return HealthOK
+	}
+
+	logger.CtxWarn(ctx, "Health check failed", logger.LoggerInfo{
+		ContextFunction: constant.CtxHealth,
+		Error: &logger.CustomError{
+			Code:    constant.ErrCodeHealthCheck,
+			Message: err.Error(),
+			Type:    constant.ErrTypeRetrieval,
+		},
+		Data: map[string]interface{}{
+			constant.DataComponent: component,
+		},
+	})
+	return HealthDegraded
+}
@@ -0,0 +1,50 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// unreachableCache is a cache whose backend cannot be reached
+type unreachableCache struct {
+	cache.NoopCache
+}
+
+func (unreachableCache) Ping() error {
+	return errors.New("connection refused")
+}
+
+func TestService_Health(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbErr    error
+		cache    cache.Cache
+		expected HealthReport
+		healthy  bool
+	}{
+		{name: "Healthy", cache: cache.NewNoopCache(), expected: HealthReport{DB: HealthOK, Cache: HealthOK}, healthy: true},
+		{name: "Database down", dbErr: errors.New("database is closed"), cache: cache.NewNoopCache(), expected: HealthReport{DB: HealthDegraded, Cache: HealthOK}},
+		{name: "Cache down", cache: unreachableCache{}, expected: HealthReport{DB: HealthOK, Cache: HealthDegraded}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, tt.cache, ServiceOptions{})
+			mockRepo.On("Ping", mock.Anything).Return(tt.dbErr)
+
+			// Act
+			report := service.Health(context.Background())
+
+			// Assert
+			assert.Equal(t, tt.expected, report)
+			assert.Equal(t, tt.healthy, report.Healthy())
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package shortener
+
+import (
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+)
+
+// IdempotencyTTL is how long the result of an idempotent request is remembered
+const IdempotencyTTL = 24 * time.Hour
+
+// RememberIdempotent stores the result of an idempotent request under key for IdempotencyTTL
+func (s *Service) RememberIdempotent(key string, result interface{}) {
+	s.cache.SetWithTTL(constant.IdempotencyNamespace, key, result, IdempotencyTTL)
+}
+
+// RecallIdempotent returns the result stored under key by RememberIdempotent, if it has not expired
+func (s *Service) RecallIdempotent(key string) (interface{}, bool) {
+	return s.cache.Get(constant.IdempotencyNamespace, key)
+}
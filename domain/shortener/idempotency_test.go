@@ -0,0 +1,27 @@
+package shortener
+
+import (
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_Idempotency(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	service := NewService(new(MockRepository), lru, ServiceOptions{})
+
+	// Act
+	_, foundBefore := service.RecallIdempotent("key")
+	service.RememberIdempotent("key", "result")
+	result, found := service.RecallIdempotent("key")
+	_, shortURLFound := lru.Get(constant.ShortURLNamespace, "key")
+
+	// Assert
+	assert.False(t, foundBefore)
+	assert.True(t, found)
+	assert.Equal(t, "result", result)
+	assert.False(t, shortURLFound, "idempotent results live in their own namespace")
+}
@@ -3,7 +3,9 @@ package shortener_test
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/prasetyowira/shorter/constant"
 	"github.com/prasetyowira/shorter/domain/shortener"
@@ -26,13 +28,16 @@ func cleanupIntegrationTestDB(t *testing.T) {
 func createIntegrationTestService(t *testing.T) *shortener.Service {
 	cleanupIntegrationTestDB(t)
 	
+	// Each test gets its own database file so background click writes from
+	// a previous test cannot leave a journal behind for the next one
 	cacheLRU := cache.NewNamespaceLRU(100)
-	repo, err := db.NewSQLiteRepository(testDBPath, cacheLRU)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), testDBPath), cacheLRU)
 	if err != nil {
 		t.Fatalf("Failed to create test repository: %v", err)
 	}
+	t.Cleanup(func() { repo.Close() })
 	
-	return shortener.NewService(repo, cacheLRU)
+	return shortener.NewService(repo, cacheLRU, shortener.ServiceOptions{})
 }
 
 func TestIntegration_UpdateLongURL(t *testing.T) {
@@ -51,7 +56,7 @@ func TestIntegration_UpdateLongURL(t *testing.T) {
 	shortCode := "abc123"
 	
 	// Creating a URL with defined short code for testing
-	url, err := service.CreateShortURL(ctx, originalURL, shortCode)
+	url, err := service.CreateShortURL(ctx, 0, originalURL, shortCode)
 	assert.NoError(t, err)
 	assert.Equal(t, shortCode, url.ShortCode)
 	assert.Equal(t, originalURL, url.LongURL)
@@ -75,7 +80,9 @@ func TestIntegration_UpdateLongURL(t *testing.T) {
 	assert.Equal(t, shortCode, retrievedURL.ShortCode)
 	
 	// GetLongURL increments the visit counter, so it should now be 1
-	assert.Equal(t, uint(1), retrievedURL.Visits)
+	visitedURL, err := service.LookupURL(ctx, shortCode)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), visitedURL.Visits)
 }
 
 func TestIntegration_UpdateLongURL_NotFound(t *testing.T) {
@@ -113,7 +120,7 @@ func TestIntegration_UpdateLongURL_EmptyShortCode(t *testing.T) {
 	
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, constant.ErrEmptyShortCode, err.Error())
+	assert.ErrorIs(t, err, shortener.ErrEmptyShortCode)
 	assert.Nil(t, updatedURL)
 }
 
@@ -133,7 +140,7 @@ func TestIntegration_UpdateLongURL_EmptyLongURL(t *testing.T) {
 	shortCode := "abc123"
 	
 	// Creating a URL with defined short code for testing
-	_, err := service.CreateShortURL(ctx, originalURL, shortCode)
+	_, err := service.CreateShortURL(ctx, 0, originalURL, shortCode)
 	assert.NoError(t, err)
 	
 	// Act - Try to update with empty long URL
@@ -141,7 +148,7 @@ func TestIntegration_UpdateLongURL_EmptyLongURL(t *testing.T) {
 	
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, constant.ErrEmptyLongURL, err.Error())
+	assert.ErrorIs(t, err, shortener.ErrEmptyLongURL)
 	assert.Nil(t, updatedURL)
 	
 	// Verify the original URL is still intact
@@ -163,8 +170,9 @@ func TestIntegration_UpdateLongURL_Cache(t *testing.T) {
 		t.Fatalf("Failed to create test repository: %v", err)
 	}
 	defer cleanupIntegrationTestDB(t)
+	defer repo.Close()
 	
-	service := shortener.NewService(repo, cacheLRU)
+	service := shortener.NewService(repo, cacheLRU, shortener.ServiceOptions{})
 	ctx := context.Background()
 	
 	// First create a URL
@@ -172,7 +180,7 @@ func TestIntegration_UpdateLongURL_Cache(t *testing.T) {
 	shortCode := "abc123"
 	
 	// Creating a URL with defined short code for testing
-	_, err = service.CreateShortURL(ctx, originalURL, shortCode)
+	_, err = service.CreateShortURL(ctx, 0, originalURL, shortCode)
 	assert.NoError(t, err)
 	
 	// Get the URL to populate cache
@@ -193,4 +201,130 @@ func TestIntegration_UpdateLongURL_Cache(t *testing.T) {
 	updatedCachedURL, found := cacheLRU.Get(constant.ShortURLNamespace, shortCode)
 	assert.True(t, found, "URL should still be in cache after update")
 	assert.Equal(t, newLongURL, updatedCachedURL.(*shortener.URL).LongURL)
-} 
\ No newline at end of file
+} 
+func TestIntegration_MaxVisits(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	// Arrange
+	service := createIntegrationTestService(t)
+	defer cleanupIntegrationTestDB(t)
+	ctx := context.Background()
+
+	maxVisits := uint(2)
+	_, err := service.CreateShortURLWithParams(ctx, "https://example.com", "limited", shortener.CreateURLParams{MaxVisits: &maxVisits})
+	assert.NoError(t, err)
+
+	// Act & Assert - exactly maxVisits redirects are served
+	for i := 0; i < int(maxVisits); i++ {
+		_, err := service.GetLongURL(ctx, "limited")
+		assert.NoError(t, err)
+	}
+
+	_, err = service.GetLongURL(ctx, "limited")
+	assert.ErrorIs(t, err, shortener.ErrShortCodeExpired)
+
+	_, err = service.GetLongURL(ctx, "limited")
+	assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
+}
+
+func TestIntegration_MaxVisits_WithVisitQueue(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	// Arrange
+	cacheLRU := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), testDBPath), cacheLRU)
+	if err != nil {
+		t.Fatalf("Failed to create test repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	queue := db.NewVisitQueue(repo, db.DefaultVisitBufferSize)
+	t.Cleanup(func() { queue.Shutdown(context.Background()) })
+	service := shortener.NewService(repo, cacheLRU, shortener.ServiceOptions{VisitQueue: queue})
+	ctx := context.Background()
+
+	maxVisits := uint(2)
+	_, err = service.CreateShortURLWithParams(ctx, "https://example.com", "limited", shortener.CreateURLParams{MaxVisits: &maxVisits})
+	assert.NoError(t, err)
+
+	// Act & Assert - the queue flushes later, yet only maxVisits redirects are served
+	for i := 0; i < int(maxVisits); i++ {
+		_, err := service.GetLongURL(ctx, "limited")
+		assert.NoError(t, err)
+	}
+
+	_, err = service.GetLongURL(ctx, "limited")
+	assert.ErrorIs(t, err, shortener.ErrShortCodeExpired)
+}
+
+func TestIntegration_CacheKeys(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	// Arrange
+	cacheLRU := cache.NewNamespaceLRU(100)
+	repo, err := db.NewSQLiteRepository(filepath.Join(t.TempDir(), testDBPath), cacheLRU)
+	if err != nil {
+		t.Fatalf("Failed to create test repository: %v", err)
+	}
+	defer repo.Close()
+
+	service := shortener.NewService(repo, cacheLRU, shortener.ServiceOptions{})
+	ctx := context.Background()
+
+	// Act & Assert - creating a URL caches it under its short code
+	_, err = service.CreateShortURL(ctx, 0, "https://example.com", "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc123"}, cacheLRU.Keys(constant.ShortURLNamespace))
+	cached, found := cacheLRU.Entries(constant.ShortURLNamespace)["abc123"]
+	if assert.True(t, found) {
+		assert.Equal(t, "https://example.com", cached.(*shortener.URL).LongURL)
+	}
+
+	// Act & Assert - deleting the URL drops it from the cache
+	assert.NoError(t, service.DeleteURL(ctx, "abc123"))
+	assert.Empty(t, cacheLRU.Keys(constant.ShortURLNamespace))
+}
+
+func TestIntegration_GetLongURL_RecordsClicksOnCacheHits(t *testing.T) {
+	// Skip in CI environment
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+	
+	// Arrange
+	service := createIntegrationTestService(t)
+	defer cleanupIntegrationTestDB(t)
+	ctx := context.Background()
+	
+	url, err := service.CreateShortURL(ctx, 0, "https://example.com", "clicks")
+	assert.NoError(t, err)
+	
+	// Act - the first lookup fills the cache, the second is served from it
+	_, err = service.GetLongURL(ctx, url.ShortCode)
+	assert.NoError(t, err)
+	_, err = service.GetLongURL(ctx, url.ShortCode)
+	assert.NoError(t, err)
+	
+	// Assert - clicks are recorded in the background, cache hits included
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+	assert.Eventually(t, func() bool {
+		buckets, err := service.GetVisits(ctx, url.ShortCode, from, to, shortener.GranularityDay)
+		if err != nil {
+			return false
+		}
+		var clicks uint
+		for _, bucket := range buckets {
+			clicks += bucket.Count
+		}
+		return clicks == 2
+	}, time.Second, 10*time.Millisecond)
+}
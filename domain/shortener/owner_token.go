@@ -0,0 +1,67 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ownerTokenBytes is the number of random bytes in an owner token
+const ownerTokenBytes = 32
+
+// DefaultOwnerTokenTTL is how long an owner token is accepted unless ServiceOptions.OwnerTokenTTL says otherwise
+const DefaultOwnerTokenTTL = 30 * 24 * time.Hour
+
+// NewOwnerToken returns a random token letting an anonymous caller change the URL
+// it creates, to be passed in CreateURLParams.OwnerToken
+func NewOwnerToken() (string, error) {
+	token := make([]byte, ownerTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// ownerTokenContextKey is the context key carrying the owner token of an anonymous caller
+type ownerTokenContextKey struct{}
+
+// WithOwnerToken marks ctx as coming from an anonymous caller presenting token, which
+// may be empty. Such callers may only change URLs whose unexpired owner token matches.
+func WithOwnerToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, ownerTokenContextKey{}, token)
+}
+
+// OwnerTokenFromContext returns the token attached by WithOwnerToken and whether ctx
+// comes from an anonymous caller
+func OwnerTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(ownerTokenContextKey{}).(string)
+	return token, ok
+}
+
+// checkOwnerToken rejects token unless it matches url's unexpired owner token
+func (s *Service) checkOwnerToken(ctx context.Context, url *URL, token string) error {
+	valid := token != "" && url.OwnerTokenHash != "" &&
+		(url.OwnerTokenExpiresAt == nil || time.Now().Before(*url.OwnerTokenExpiresAt)) &&
+		bcrypt.CompareHashAndPassword([]byte(url.OwnerTokenHash), []byte(token)) == nil
+	if valid {
+		return nil
+	}
+
+	logger.CtxWarn(ctx, "Invalid or expired owner token", logger.LoggerInfo{
+		ContextFunction: constant.CtxDomain,
+		Error: &logger.CustomError{
+			Code:    constant.ErrCodeForbidden,
+			Message: constant.ErrForbidden,
+			Type:    constant.ErrTypeValidation,
+		},
+		Data: map[string]interface{}{
+			constant.DataShortCode: url.ShortCode,
+		},
+	})
+	return ErrForbidden
+}
@@ -0,0 +1,81 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewOwnerToken(t *testing.T) {
+	// Act
+	first, err := NewOwnerToken()
+	assert.NoError(t, err)
+	second, err := NewOwnerToken()
+	assert.NoError(t, err)
+
+	// Assert - 32 random bytes in unpadded base64
+	assert.Len(t, first, 43)
+	assert.NotEqual(t, first, second)
+}
+
+func TestService_CreateShortURL_OwnerToken(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{OwnerTokenTTL: time.Hour})
+	// The same long URL was shortened before, but the new URL needs a token of its own
+	mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	// Act
+	url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com", "", CreateURLParams{OwnerToken: "token"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotEqual(t, "abc123", url.ShortCode)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(url.OwnerTokenHash), []byte("token")))
+	if assert.NotNil(t, url.OwnerTokenExpiresAt) {
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *url.OwnerTokenExpiresAt, time.Minute)
+	}
+	mockRepo.AssertCalled(t, "Store", mock.Anything, url)
+}
+
+func TestService_AuthorizeOwner_OwnerToken(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("token"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		url           URL
+		ctx           context.Context
+		expectedError error
+	}{
+		{name: "Matching token", url: URL{OwnerTokenHash: string(hash), OwnerTokenExpiresAt: &future}, ctx: WithOwnerToken(context.Background(), "token")},
+		{name: "Wrong token", url: URL{OwnerTokenHash: string(hash), OwnerTokenExpiresAt: &future}, ctx: WithOwnerToken(context.Background(), "other"), expectedError: ErrForbidden},
+		{name: "Missing token", url: URL{OwnerTokenHash: string(hash), OwnerTokenExpiresAt: &future}, ctx: WithOwnerToken(context.Background(), ""), expectedError: ErrForbidden},
+		{name: "Expired token", url: URL{OwnerTokenHash: string(hash), OwnerTokenExpiresAt: &past}, ctx: WithOwnerToken(context.Background(), "token"), expectedError: ErrForbidden},
+		{name: "URL without token", url: URL{}, ctx: WithOwnerToken(context.Background(), "token"), expectedError: ErrForbidden},
+		{name: "Authenticated user", url: URL{OwnerID: 7}, ctx: WithUser(context.Background(), &User{ID: 7, Role: RoleUser})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			service := NewService(new(MockRepository), cache.NewNoopCache(), ServiceOptions{})
+			tt.url.ShortCode = "abc123"
+
+			// Act
+			err := service.authorizeOwner(tt.ctx, &tt.url)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
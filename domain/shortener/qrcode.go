@@ -0,0 +1,44 @@
+package shortener
+
+import (
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+)
+
+// QRCodeTTL is how long a rendered QR code is cached
+const QRCodeTTL = 24 * time.Hour
+
+// qrCodeVariants holds the rendered QR codes of one short code keyed by variant, so a
+// single invalidation drops every format and size
+type qrCodeVariants map[string][]byte
+
+// RememberQRCode caches image, the QR code of shortCode rendered as variant, for QRCodeTTL.
+// The variant tells formats and sizes apart.
+func (s *Service) RememberQRCode(shortCode, variant string, image []byte) {
+	variants := qrCodeVariants{variant: image}
+	if cached, found := s.cache.Get(constant.QRCodeNamespace, shortCode); found {
+		// Copy rather than mutate, the cached map may be read concurrently
+		for v, img := range cached.(qrCodeVariants) {
+			if v != variant {
+				variants[v] = img
+			}
+		}
+	}
+	s.cache.SetWithTTL(constant.QRCodeNamespace, shortCode, variants, QRCodeTTL)
+}
+
+// RecallQRCode returns the QR code of shortCode stored as variant by RememberQRCode, if it has not expired
+func (s *Service) RecallQRCode(shortCode, variant string) ([]byte, bool) {
+	cached, found := s.cache.Get(constant.QRCodeNamespace, shortCode)
+	if !found {
+		return nil, false
+	}
+	image, found := cached.(qrCodeVariants)[variant]
+	return image, found
+}
+
+// invalidateQRCodes drops every cached QR code of shortCode
+func (s *Service) invalidateQRCodes(shortCode string) {
+	s.cache.Invalidate(constant.QRCodeNamespace, shortCode)
+}
@@ -0,0 +1,77 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_QRCodeCache(t *testing.T) {
+	// Arrange
+	lru := cache.NewNamespaceLRU(100)
+	service := NewService(new(MockRepository), lru, ServiceOptions{})
+
+	// Act
+	_, foundBefore := service.RecallQRCode("abc123", "png:256")
+	service.RememberQRCode("abc123", "png:256", []byte("png"))
+	service.RememberQRCode("abc123", "svg:256", []byte("svg"))
+	png, pngFound := service.RecallQRCode("abc123", "png:256")
+	svg, svgFound := service.RecallQRCode("abc123", "svg:256")
+	_, otherSizeFound := service.RecallQRCode("abc123", "png:512")
+	_, shortURLFound := lru.Get(constant.ShortURLNamespace, "abc123")
+
+	// Assert
+	assert.False(t, foundBefore)
+	assert.True(t, pngFound)
+	assert.Equal(t, []byte("png"), png)
+	assert.True(t, svgFound, "variants of one short code are kept side by side")
+	assert.Equal(t, []byte("svg"), svg)
+	assert.False(t, otherSizeFound)
+	assert.False(t, shortURLFound, "QR codes live in their own namespace")
+}
+
+func TestService_QRCodeCache_Invalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(service *Service) error
+	}{
+		{
+			name: "UpdateLongURL",
+			mutate: func(service *Service) error {
+				_, err := service.UpdateLongURL(context.Background(), "abc123", "https://example.com/updated")
+				return err
+			},
+		},
+		{
+			name: "DeleteURL",
+			mutate: func(service *Service) error {
+				return service.DeleteURL(context.Background(), "abc123")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ID: 1, ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+			mockRepo.On("UpdateLongURL", mock.Anything, "abc123", "https://example.com/updated").Return(nil)
+			mockRepo.On("Delete", mock.Anything, "abc123").Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+			service.RememberQRCode("abc123", "png:256", []byte("png"))
+
+			// Act
+			err := tt.mutate(service)
+			_, found := service.RecallQRCode("abc123", "png:256")
+
+			// Assert
+			assert.NoError(t, err)
+			assert.False(t, found)
+		})
+	}
+}
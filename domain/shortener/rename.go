@@ -0,0 +1,100 @@
+package shortener
+
+import (
+	"context"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// renameShortCode implements RenameShortCode
+func (s *Service) renameShortCode(ctx context.Context, oldCode, newCode string) (*URL, error) {
+	logger.CtxDebug(ctx, "Renaming short code", logger.LoggerInfo{
+		ContextFunction: constant.CtxRenameShortCode,
+		Data: map[string]interface{}{
+			constant.DataShortCode:    oldCode,
+			constant.DataNewShortCode: newCode,
+		},
+	})
+
+	if oldCode == "" || newCode == "" {
+		logger.CtxWarn(ctx, "Short code cannot be empty", logger.LoggerInfo{
+			ContextFunction: constant.CtxRenameShortCode,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeEmptyShortCode,
+				Message: constant.ErrEmptyShortCode,
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+		return nil, ErrEmptyShortCode
+	}
+
+	newCode = s.normalizeShortCode(newCode)
+	if s.isReserved(newCode) {
+		logger.CtxWarn(ctx, "New short code is reserved", logger.LoggerInfo{
+			ContextFunction: constant.CtxRenameShortCode,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeReservedShortCode,
+				Message: constant.ErrReservedShortCode,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataNewShortCode: newCode,
+			},
+		})
+		return nil, ErrReservedShortCode
+	}
+
+	url, err := s.findShortCode(ctx, oldCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeOwner(ctx, url); err != nil {
+		return nil, err
+	}
+	oldCode = url.ShortCode
+
+	if err := s.repo.RenameShortCode(ctx, oldCode, newCode); err != nil {
+		logger.CtxError(ctx, "Failed to rename short code", logger.LoggerInfo{
+			ContextFunction: constant.CtxRenameShortCode,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeUpdateFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode:    oldCode,
+				constant.DataNewShortCode: newCode,
+			},
+		})
+		return nil, err
+	}
+
+	s.cache.Invalidate(constant.ShortURLNamespace, oldCode)
+	s.invalidateQRCodes(oldCode)
+
+	renamed, err := s.repo.FindByShortCode(ctx, newCode)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheURL(renamed)
+	s.opts.Events.Publish(ctx, events.URLUpdatedEvent{
+		ShortCode:         newCode,
+		PreviousShortCode: oldCode,
+		Before:            snapshot(url),
+		After:             snapshot(renamed),
+		OccurredAt:        time.Now(),
+	})
+
+	logger.CtxInfo(ctx, "Short code renamed", logger.LoggerInfo{
+		ContextFunction: constant.CtxRenameShortCode,
+		Data: map[string]interface{}{
+			constant.DataShortCode:    oldCode,
+			constant.DataNewShortCode: newCode,
+		},
+	})
+
+	return renamed, nil
+}
@@ -0,0 +1,101 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_RenameShortCode(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	lru := cache.NewNamespaceLRU(100)
+	service := NewService(mockRepo, lru, ServiceOptions{})
+	lru.Set(constant.ShortURLNamespace, "typo", &URL{ShortCode: "typo"})
+	mockRepo.On("FindByShortCode", mock.Anything, "typo").Return(&URL{ID: 1, ShortCode: "typo", LongURL: "https://example.com", OwnerID: 7}, nil)
+	mockRepo.On("RenameShortCode", mock.Anything, "typo", "fixed").Return(nil)
+	mockRepo.On("FindByShortCode", mock.Anything, "fixed").Return(&URL{ID: 2, ShortCode: "fixed", LongURL: "https://example.com", OwnerID: 7}, nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	ctx := WithUser(context.Background(), &User{ID: 7, Username: "alice", Role: RoleUser})
+
+	// Act
+	url, err := service.RenameShortCode(ctx, "typo", "fixed")
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed", url.ShortCode)
+	_, oldCached := lru.Get(constant.ShortURLNamespace, "typo")
+	assert.False(t, oldCached)
+	newCached, found := lru.Get(constant.ShortURLNamespace, "fixed")
+	assert.True(t, found)
+	assert.Equal(t, url, newCached)
+	mockRepo.AssertCalled(t, "AppendAudit", mock.Anything, mock.MatchedBy(func(entry AuditEntry) bool {
+		return entry.Action == AuditActionRename && entry.ShortCode == "fixed" && entry.ActorUsername == "alice"
+	}))
+}
+
+func TestService_RenameShortCode_StoredShortCode(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	lru := cache.NewNamespaceLRU(100)
+	service := NewService(mockRepo, lru, ServiceOptions{})
+	lru.Set(constant.ShortURLNamespace, "TyPo", &URL{ShortCode: "TyPo"})
+	mockRepo.On("FindByShortCode", mock.Anything, "typo").Return(&URL{ID: 1, ShortCode: "TyPo", LongURL: "https://example.com"}, nil)
+	mockRepo.On("RenameShortCode", mock.Anything, "TyPo", "fixed").Return(nil)
+	mockRepo.On("FindByShortCode", mock.Anything, "fixed").Return(&URL{ID: 2, ShortCode: "fixed", LongURL: "https://example.com"}, nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	url, err := service.RenameShortCode(context.Background(), "TYPO", "fixed")
+	waitForEvents(t, service)
+
+	// Assert - the URL is renamed from the code it is stored with
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed", url.ShortCode)
+	_, oldCached := lru.Get(constant.ShortURLNamespace, "TyPo")
+	assert.False(t, oldCached)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_RenameShortCode_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldCode   string
+		newCode   string
+		user      *User
+		renameErr error
+		wantErr   string
+	}{
+		{name: "Empty new code", oldCode: "typo", newCode: "", wantErr: constant.ErrEmptyShortCode},
+		{name: "Reserved new code", oldCode: "typo", newCode: "api", wantErr: constant.ErrReservedShortCode},
+		{name: "Not owner", oldCode: "typo", newCode: "fixed", user: &User{ID: 8, Role: RoleUser}, wantErr: constant.ErrForbidden},
+		{name: "New code taken", oldCode: "typo", newCode: "taken", renameErr: ErrShortCodeExists, wantErr: constant.ErrShortCodeExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByShortCode", mock.Anything, "typo").Return(&URL{ShortCode: "typo", OwnerID: 7}, nil)
+			mockRepo.On("RenameShortCode", mock.Anything, tt.oldCode, tt.newCode).Return(tt.renameErr)
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = WithUser(ctx, tt.user)
+			}
+
+			// Act
+			url, err := service.RenameShortCode(ctx, tt.oldCode, tt.newCode)
+
+			// Assert
+			assert.Nil(t, url)
+			assert.EqualError(t, err, tt.wantErr)
+			mockRepo.AssertNotCalled(t, "AppendAudit", mock.Anything, mock.Anything)
+		})
+	}
+}
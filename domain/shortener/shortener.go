@@ -4,10 +4,22 @@ import (
 	"context"
 	"errors"
 	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
+	"github.com/prasetyowira/shorter/infrastructure/codegen"
 	"github.com/prasetyowira/shorter/infrastructure/logger"
+	"github.com/prasetyowira/shorter/infrastructure/tracing"
+	"github.com/prasetyowira/shorter/infrastructure/urlcheck"
+	"github.com/prasetyowira/shorter/infrastructure/urlnorm"
+	"github.com/prasetyowira/shorter/infrastructure/useragent"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // URL represents the core domain model for a shortened URL
@@ -17,173 +29,1082 @@ type URL struct {
 	ShortCode string    `json:"short_code"`
 	CreatedAt time.Time `json:"created_at"`
 	Visits    uint      `json:"visits"`
+	// Password is the bcrypt hash guarding a protected URL
+	Password    string `json:"-"`
+	IsProtected bool   `json:"is_protected"`
+	// RedirectCode is the HTTP status used when redirecting to LongURL
+	RedirectCode int `json:"redirect_code"`
+	// MaxVisits deactivates the URL once its visits reach the limit; nil or zero means unlimited
+	MaxVisits *uint `json:"max_visits,omitempty"`
+	// OwnerID is the ID of the user that created the URL; zero is the built-in admin
+	OwnerID uint `json:"owner_id"`
+	// ExpiresAt deactivates the URL once it has passed; nil means it never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// UTMSource, UTMCampaign and UTMMedium are appended to LongURL on redirect when set
+	UTMSource   string `json:"utm_source,omitempty"`
+	UTMCampaign string `json:"utm_campaign,omitempty"`
+	UTMMedium   string `json:"utm_medium,omitempty"`
+	// LastAccessedAt is the time of the latest redirect; nil means the URL was never visited
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	// AlternateURL receives CanaryPercent percent of redirects instead of LongURL,
+	// splitting traffic for A/B tests; zero sends every visitor to LongURL
+	AlternateURL  string `json:"alternate_url,omitempty"`
+	CanaryPercent uint8  `json:"canary_percent,omitempty"`
+	// GeoRules send visitors from listed countries to their own target, checked in
+	// order before the canary split; visitors matching none get the usual destination
+	GeoRules []GeoRule `json:"geo_rules,omitempty"`
+	// OwnerTokenHash is the bcrypt hash of the token letting the anonymous creator
+	// change the URL until OwnerTokenExpiresAt; empty when the URL has an account owner
+	OwnerTokenHash      string     `json:"-"`
+	OwnerTokenExpiresAt *time.Time `json:"-"`
+	// ActiveFrom keeps the URL from resolving until it is reached; nil means it is active at once
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+}
+
+// ActiveAt reports whether t has reached the URL's ActiveFrom. Expiry and visit
+// limits are not considered.
+func (u *URL) ActiveAt(t time.Time) bool {
+	return u.ActiveFrom == nil || !t.Before(*u.ActiveFrom)
+}
+
+// Lifecycle states of a URL reported by StatusAt
+const (
+	URLStatusActive    = "active"
+	URLStatusScheduled = "scheduled"
+	URLStatusExpired   = "expired"
+)
+
+// StatusAt returns the state of the URL at t: scheduled before ActiveFrom, expired
+// once ExpiresAt has passed or its visits have reached MaxVisits, and active otherwise
+func (u *URL) StatusAt(t time.Time) string {
+	switch {
+	case !u.ActiveAt(t):
+		return URLStatusScheduled
+	case u.ExpiresAt != nil && !t.Before(*u.ExpiresAt), u.MaxVisits != nil && *u.MaxVisits > 0 && u.Visits >= *u.MaxVisits:
+		return URLStatusExpired
+	default:
+		return URLStatusActive
+	}
+}
+
+// RedirectStatus returns RedirectCode, falling back to DefaultRedirectCode when unset
+func (u *URL) RedirectStatus() int {
+	if u.RedirectCode == 0 {
+		return DefaultRedirectCode
+	}
+	return u.RedirectCode
+}
+
+// RedirectURL returns LongURL with the URL's UTM parameters appended to its query.
+// Existing query parameters are kept as they are, including UTM parameters the
+// long URL already sets, which are not overridden.
+func (u *URL) RedirectURL() string {
+	return u.withUTM(u.LongURL)
+}
+
+// CanaryRedirectURL returns the destination of a visitor who rolled roll, a number
+// in [0, 100): AlternateURL when roll is below CanaryPercent and LongURL otherwise.
+// UTM parameters are appended to either destination as by RedirectURL.
+func (u *URL) CanaryRedirectURL(roll int) string {
+	if u.AlternateURL != "" && roll < int(u.CanaryPercent) {
+		return u.withUTM(u.AlternateURL)
+	}
+	return u.RedirectURL()
+}
+
+// withUTM returns destination with the URL's UTM parameters appended to its query
+func (u *URL) withUTM(destination string) string {
+	target, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	existing := target.Query()
+	utm := url.Values{}
+	for _, param := range []struct{ key, value string }{
+		{"utm_source", u.UTMSource},
+		{"utm_medium", u.UTMMedium},
+		{"utm_campaign", u.UTMCampaign},
+	} {
+		if param.value != "" && !existing.Has(param.key) {
+			utm.Set(param.key, param.value)
+		}
+	}
+	if len(utm) == 0 {
+		return destination
+	}
+
+	// Append to the raw query rather than re-encoding it, so existing parameters keep their order and escaping
+	if target.RawQuery == "" {
+		target.RawQuery = utm.Encode()
+	} else {
+		target.RawQuery += "&" + utm.Encode()
+	}
+	return target.String()
+}
+
+// CreateURLParams holds optional settings for a new short URL
+type CreateURLParams struct {
+	// Password, when set, protects the URL; it is stored as a bcrypt hash
+	Password string
+	// RedirectCode is the redirect status; zero means DefaultRedirectCode
+	RedirectCode int
+	// MaxVisits limits how many times the URL may be followed; nil or zero means unlimited
+	MaxVisits *uint
+	// OwnerID is the ID of the user creating the URL
+	OwnerID uint
+	// ExpiresAt, when set, must be in the future; the URL stops resolving after it
+	ExpiresAt *time.Time
+	// UTM parameters appended to the long URL on every redirect; empty ones are left out
+	UTMSource   string
+	UTMCampaign string
+	UTMMedium   string
+	// AlternateURL receives CanaryPercent percent of redirects, at most MaxCanaryPercent
+	AlternateURL  string
+	CanaryPercent uint8
+	// GeoRules redirect visitors by country; see URL.GeoRules
+	GeoRules []GeoRule
+	// OwnerToken, from NewOwnerToken, lets an anonymous creator change the URL for
+	// ServiceOptions.OwnerTokenTTL; only its hash is stored
+	OwnerToken string
+	// ActiveFrom, when set, delays the URL's first redirect until then; it must be before ExpiresAt
+	ActiveFrom *time.Time
+
+	// passwordHash protects the URL with an existing bcrypt hash when Password is empty
+	passwordHash string
+	// fresh stores a new URL even when the long URL was shortened before
+	fresh bool
+}
+
+// DefaultRedirectCode is the redirect status used when none is requested
+const DefaultRedirectCode = http.StatusFound
+
+// validRedirectCodes lists the redirect statuses a URL may use
+var validRedirectCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
 }
 
 // Repository defines the interface for data persistence operations
 type Repository interface {
 	Store(ctx context.Context, url *URL) error
 	FindByShortCode(ctx context.Context, shortCode string) (*URL, error)
+	FindByLongURL(ctx context.Context, longURL string) (*URL, error)
 	IncrementVisits(ctx context.Context, shortCode string) error
+	// IncrementVisitsWithinLimit counts a visit only while the URL's visits are below its
+	// MaxVisits, reporting whether it did; URLs without a limit are never counted
+	IncrementVisitsWithinLimit(ctx context.Context, shortCode string) (bool, error)
+	// UpdateLastAccessed moves the URL's LastAccessedAt forward to at; an earlier at is ignored
+	UpdateLastAccessed(ctx context.Context, shortCode string, at time.Time) error
 	UpdateLongURL(ctx context.Context, shortCode string, newLongURL string) error
+	// UpdateCanary sets the alternate destination of a short URL and the percentage of redirects sent to it
+	UpdateCanary(ctx context.Context, shortCode, alternateURL string, percent uint8) error
+	// FindExpired returns the live URLs whose expiry is at or before now
+	FindExpired(ctx context.Context, now time.Time) ([]*URL, error)
+	// RenameShortCode moves the URL stored under oldCode to newCode in one transaction
+	RenameShortCode(ctx context.Context, oldCode, newCode string) error
+	Delete(ctx context.Context, shortCode string) error
+	BulkDelete(ctx context.Context, shortCodes []string) (int, []BulkDeleteError, error)
+	// BulkStore inserts urls in one transaction, skipping those whose short code is
+	// taken rather than failing the batch
+	BulkStore(ctx context.Context, urls []*URL) (*BulkStoreResult, error)
+	// WithTransaction runs fn against a repository bound to one transaction, committing
+	// when fn returns nil and rolling back when it returns an error
+	WithTransaction(ctx context.Context, fn func(tx Repository) error) error
+	Ping(ctx context.Context) error
+	AppendAudit(ctx context.Context, entry AuditEntry) error
+	FindAudits(ctx context.Context, shortCode string) ([]AuditEntry, error)
+	RecordClick(ctx context.Context, event ClickEvent) error
+	FindClicks(ctx context.Context, shortCode string, from, to time.Time) ([]ClickEvent, error)
+	// FindAllClicks returns every click event of a short code, oldest first
+	FindAllClicks(ctx context.Context, shortCode string) ([]ClickEvent, error)
+	// FindDeviceStats counts the click events of a short code by device type
+	FindDeviceStats(ctx context.Context, shortCode string) (DeviceStats, error)
+	FindReferers(ctx context.Context, shortCode string) ([]RefererStat, error)
+	FindDailyClicks(ctx context.Context, shortCode string, days int) ([]DailyCount, error)
+	FindAll(ctx context.Context, fn func(url *URL) error) error
+	Search(ctx context.Context, query string, limit, offset int) ([]*URL, int, error)
+	AddTag(ctx context.Context, shortCode, tag string) error
+	RemoveTag(ctx context.Context, shortCode, tag string) error
+	FindTags(ctx context.Context, shortCode string) ([]string, error)
+	ListByTag(ctx context.Context, tag string, limit, offset int) ([]*URL, int, error)
+	// FindCreatedAfter returns a page of the live URLs created after since, newest first
+	FindCreatedAfter(ctx context.Context, since time.Time, limit, offset int) ([]*URL, error)
+	// FindTopURLs returns up to limit live URLs with the most visits, most visited first
+	FindTopURLs(ctx context.Context, limit int) ([]*URL, error)
+	// FindTrending returns up to limit live URLs clicked within the past period,
+	// most recent clicks first. URLs without clicks in the period are left out.
+	FindTrending(ctx context.Context, period time.Duration, limit int) ([]*TrendingURL, error)
+	FindUserByUsername(ctx context.Context, username string) (*User, error)
+	CreateUser(ctx context.Context, user *User) error
+	ListUsers(ctx context.Context) ([]User, error)
+	CreateWebhook(ctx context.Context, hook *Webhook) error
+	FindWebhooks(ctx context.Context, shortCode string) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint) error
+}
+
+// DefaultShortCodeLength is used when ServiceOptions does not specify a length
+const DefaultShortCodeLength = 6
+
+// DefaultMaxCodeGenRetries is used when ServiceOptions does not specify a retry limit
+const DefaultMaxCodeGenRetries = 5
+
+// VisitQueue defers visit count increments, and the last access time, off the redirect path
+type VisitQueue interface {
+	Submit(shortCode string)
+}
+
+// Blacklist decides whether a host may be shortened
+type Blacklist interface {
+	IsBlocked(host string) bool
+}
+
+// SSRFGuard decides whether a long URL points at a private network address
+type SSRFGuard interface {
+	IsPrivateURL(rawURL string) (bool, error)
+}
+
+// CodeGenerator produces candidate short codes for URLs created without a custom code
+type CodeGenerator interface {
+	Generate() string
+}
+
+// ReachabilityChecker decides whether a long URL answers requests
+type ReachabilityChecker interface {
+	// Check returns nil when rawURL is reachable and an error saying why not otherwise
+	Check(ctx context.Context, rawURL string) error
+}
+
+// BotDetector decides whether a User-Agent belongs to a bot
+type BotDetector interface {
+	IsBot(ua string) bool
+}
+
+// ServiceOptions holds tunable settings for the shortener service
+type ServiceOptions struct {
+	ShortCodeLength int
+	// CodeGenerator produces short codes; nil means random alphanumeric codes of ShortCodeLength.
+	// Unless CaseSensitive is set its codes must be lowercase, as they are stored as generated.
+	CodeGenerator CodeGenerator
+	// MaxCodeGenRetries caps how many generated codes are tried when they collide; zero means DefaultMaxCodeGenRetries
+	MaxCodeGenRetries int
+	// VisitQueue, when set, receives visits instead of incrementing them synchronously.
+	// Visits to URLs with MaxVisits are always counted synchronously.
+	VisitQueue VisitQueue
+	// Blacklist, when set, rejects long URLs whose host it blocks
+	Blacklist Blacklist
+	// SSRFGuard, when set, rejects long URLs resolving to private or loopback addresses
+	SSRFGuard SSRFGuard
+	// ReservedCodes cannot be used as short codes; nil means DefaultReservedCodes
+	ReservedCodes []string
+	// Webhooks, when set, delivers events to the webhooks registered for a short URL
+	Webhooks WebhookSender
+	// CacheTTL expires cached URLs after this long; zero keeps them until evicted
+	CacheTTL time.Duration
+	// Tracer records spans for service calls; nil means the global application tracer
+	Tracer trace.Tracer
+	// BotDetector recognises bot visits, which are not counted; nil means useragent.DefaultBotSignatures
+	BotDetector BotDetector
+	// Events receives URL lifecycle events; nil means a bus private to the service
+	Events *events.EventBus
+	// OwnerTokenTTL is how long owner tokens of anonymously created URLs are accepted; zero means DefaultOwnerTokenTTL
+	OwnerTokenTTL time.Duration
+	// CaseSensitive keeps short codes as given; when false they are stored and looked up in lowercase
+	CaseSensitive bool
+	// ValidateURLReachability rejects new URLs whose long URL does not answer a HEAD request with a 2xx status
+	ValidateURLReachability bool
+	// ReachabilityChecker checks long URLs when ValidateURLReachability is set; nil means urlcheck.NewChecker()
+	ReachabilityChecker ReachabilityChecker
+}
+
+// DefaultReservedCodes returns the first path segment of every top-level route
+// together with prefixes kept free for future routes
+func DefaultReservedCodes() []string {
+	routes := []string{
+		constant.RouteAPIPrefix,
+		constant.RouteHealthcheck,
+		constant.RouteLive,
+		constant.RouteReady,
+		constant.RouteRobotsTxt,
+		constant.RouteProtectedURL,
+		constant.RoutePreviewURL,
+	}
+
+	codes := []string{constant.ReservedCodeAdmin, constant.ReservedCodeStatic}
+	for _, route := range routes {
+		codes = append(codes, strings.SplitN(strings.TrimPrefix(route, "/"), "/", 2)[0])
+	}
+	return codes
+}
+
+// Service represents the domain service for URL shortening
+type Service struct {
+	repo     Repository
+	cache    cache.Cache
+	opts     ServiceOptions
+	reserved map[string]struct{}
+}
+
+// NewService creates a new shortener service
+func NewService(repo Repository, urlCache cache.Cache, opts ServiceOptions) *Service {
+	ctx := logger.NewRequestContext()
+
+	logger.CtxDebug(ctx, "Creating shortener service", logger.LoggerInfo{
+		ContextFunction: constant.CtxDomain,
+		Data: map[string]interface{}{
+			constant.DataService: "shortener",
+		},
+	})
+
+	if opts.ShortCodeLength <= 0 {
+		opts.ShortCodeLength = DefaultShortCodeLength
+	}
+
+	if opts.MaxCodeGenRetries <= 0 {
+		opts.MaxCodeGenRetries = DefaultMaxCodeGenRetries
+	}
+
+	if opts.CodeGenerator == nil && opts.CaseSensitive {
+		opts.CodeGenerator = codegen.NewRandom(opts.ShortCodeLength)
+	} else if opts.CodeGenerator == nil {
+		opts.CodeGenerator = codegen.NewLowercaseRandom(opts.ShortCodeLength)
+	}
+
+	if opts.Tracer == nil {
+		opts.Tracer = tracing.Tracer()
+	}
+
+	if opts.BotDetector == nil {
+		opts.BotDetector = useragent.NewBotDetector()
+	}
+
+	if opts.Events == nil {
+		opts.Events = events.NewEventBus()
+	}
+
+	if opts.ValidateURLReachability && opts.ReachabilityChecker == nil {
+		opts.ReachabilityChecker = urlcheck.NewChecker()
+	}
+
+	if opts.OwnerTokenTTL <= 0 {
+		opts.OwnerTokenTTL = DefaultOwnerTokenTTL
+	}
+
+	if opts.ReservedCodes == nil {
+		opts.ReservedCodes = DefaultReservedCodes()
+	}
+	reserved := make(map[string]struct{}, len(opts.ReservedCodes))
+	for _, code := range opts.ReservedCodes {
+		reserved[strings.ToLower(code)] = struct{}{}
+	}
+
+	s := &Service{
+		repo:     repo,
+		cache:    urlCache,
+		opts:     opts,
+		reserved: reserved,
+	}
+	s.subscribe()
+	return s
+}
+
+// subscribe registers the service's own consumers of URL lifecycle events
+func (s *Service) subscribe() {
+	for _, eventType := range []string{events.URLCreated, events.URLUpdated, events.URLDeleted} {
+		s.opts.Events.Subscribe(eventType, s.auditEvent)
+	}
+	if s.opts.Webhooks != nil {
+		s.opts.Events.Subscribe(events.URLAccessed, s.notifyWebhooks)
+	}
+}
+
+// cacheURL stores url in the cache, expiring it after CacheTTL when set
+func (s *Service) cacheURL(url *URL) {
+	if s.opts.CacheTTL > 0 {
+		s.cache.SetWithTTL(constant.ShortURLNamespace, url.ShortCode, url, s.opts.CacheTTL)
+		return
+	}
+	s.cache.Set(constant.ShortURLNamespace, url.ShortCode, url)
+}
+
+// CacheStats returns the hit, miss and eviction counters and the contents of the URL cache
+func (s *Service) CacheStats() cache.CacheStats {
+	return s.cache.Stats()
+}
+
+// normalizeShortCode returns shortCode as it is stored: lowercased unless short codes
+// are case sensitive
+func (s *Service) normalizeShortCode(shortCode string) string {
+	if s.opts.CaseSensitive {
+		return shortCode
+	}
+	return strings.ToLower(shortCode)
 }
 
-// Service represents the domain service for URL shortening
-type Service struct {
-	repo  Repository
-	cache *cache.NamespaceLRU
+// findShortCode finds the URL of shortCode in the repository. URLs created while short
+// codes were case sensitive keep their case, so a code that is not found in lowercase
+// is looked up again as given. Changes to the URL must use the returned URL's ShortCode.
+func (s *Service) findShortCode(ctx context.Context, shortCode string) (*URL, error) {
+	return s.findShortCodeIn(ctx, s.repo, shortCode)
+}
+
+// findShortCodeIn is findShortCode against repo, such as a transaction
+func (s *Service) findShortCodeIn(ctx context.Context, repo Repository, shortCode string) (*URL, error) {
+	normalized := s.normalizeShortCode(shortCode)
+	url, err := repo.FindByShortCode(ctx, normalized)
+	if errors.Is(err, ErrShortCodeNotFound) && normalized != shortCode {
+		url, err = repo.FindByShortCode(ctx, shortCode)
+	}
+	return url, err
+}
+
+// isReserved reports whether shortCode clashes with a reserved code, ignoring case
+func (s *Service) isReserved(shortCode string) bool {
+	_, found := s.reserved[strings.ToLower(shortCode)]
+	return found
+}
+
+// reusable reports whether existing, a URL of the same long URL, can be returned in place
+// of a new URL created with params and redirectCode. It cannot when it belongs to someone
+// else, either side is password protected, limited in visits or time, splits traffic,
+// routes by country or redirects differently, or the new URL needs its own owner token.
+func reusable(existing *URL, params CreateURLParams, redirectCode int) bool {
+	if params.fresh || existing.OwnerID != params.OwnerID || params.OwnerToken != "" {
+		return false
+	}
+	if existing.IsProtected || params.Password != "" || existing.RedirectStatus() != redirectCode {
+		return false
+	}
+	if existing.MaxVisits != nil || (params.MaxVisits != nil && *params.MaxVisits > 0) {
+		return false
+	}
+	if existing.ExpiresAt != nil || params.ExpiresAt != nil || existing.ActiveFrom != nil || params.ActiveFrom != nil {
+		return false
+	}
+	if existing.AlternateURL != "" || params.AlternateURL != "" || len(existing.GeoRules) > 0 || len(params.GeoRules) > 0 {
+		return false
+	}
+	// UTM parameters are part of the destination
+	return existing.UTMSource == params.UTMSource && existing.UTMCampaign == params.UTMCampaign && existing.UTMMedium == params.UTMMedium
+}
+
+// CreateShortURL creates a new shortened URL owned by userID
+func (s *Service) CreateShortURL(ctx context.Context, userID uint, longURL, customShort string) (*URL, error) {
+	return s.CreateShortURLWithParams(ctx, longURL, customShort, CreateURLParams{OwnerID: userID})
+}
+
+// createShortURLWithParams implements CreateShortURLWithParams
+func (s *Service) createShortURLWithParams(ctx context.Context, longURL, customShort string, params CreateURLParams) (*URL, error) {
+	logger.CtxDebug(ctx, "Creating short URL", logger.LoggerInfo{
+		ContextFunction: constant.CtxCreateShortURL,
+		Data: map[string]interface{}{
+			constant.DataLongURL:     longURL,
+			constant.DataCustomShort: customShort != "",
+			constant.DataProtected:   params.Password != "",
+		},
+	})
+
+	customShort = s.normalizeShortCode(customShort)
+	longURL, err := s.checkLongURL(ctx, constant.CtxCreateShortURL, longURL)
+	if err != nil {
+		return nil, err
+	}
+
+	alternateURL, err := s.checkCanary(ctx, constant.CtxCreateShortURL, params.AlternateURL, params.CanaryPercent)
+	if err != nil {
+		return nil, err
+	}
+	geoRules, err := s.checkGeoRules(ctx, constant.CtxCreateShortURL, params.GeoRules)
+	if err != nil {
+		return nil, err
+	}
+
+	if customShort != "" && s.isReserved(customShort) {
+		logger.CtxWarn(ctx, "Custom short code is reserved", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateShortURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeReservedShortCode,
+				Message: constant.ErrReservedShortCode,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: customShort,
+			},
+		})
+		return nil, ErrReservedShortCode
+	}
+
+	redirectCode := params.RedirectCode
+	if redirectCode == 0 {
+		redirectCode = DefaultRedirectCode
+	}
+	if !validRedirectCodes[redirectCode] {
+		logger.CtxWarn(ctx, "Unsupported redirect code", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateShortURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidRedirectCode,
+				Message: constant.ErrInvalidRedirectCode,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataRedirectCode: redirectCode,
+			},
+		})
+		return nil, ErrInvalidRedirectCode
+	}
+
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		logger.CtxWarn(ctx, "Expiry is not in the future", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateShortURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidExpiry,
+				Message: constant.ErrInvalidExpiry,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataExpiresAt: *params.ExpiresAt,
+			},
+		})
+		return nil, ErrInvalidExpiry
+	}
+
+	if params.ActiveFrom != nil && params.ExpiresAt != nil && !params.ExpiresAt.After(*params.ActiveFrom) {
+		logger.CtxWarn(ctx, "Expiry is not after activation", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateShortURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidActiveFrom,
+				Message: constant.ErrInvalidActiveFrom,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataActiveFrom: *params.ActiveFrom,
+				constant.DataExpiresAt:  *params.ExpiresAt,
+			},
+		})
+		return nil, ErrInvalidActiveFrom
+	}
+
+	if err := s.checkReachable(ctx, longURL); err != nil {
+		return nil, err
+	}
+
+	shortCode := customShort
+	if shortCode == "" {
+		// Reuse the existing short code rather than creating a duplicate when it behaves
+		// the same as the new URL would
+		existing, err := s.repo.FindByLongURL(ctx, longURL)
+		if err == nil && reusable(existing, params, redirectCode) {
+			logger.CtxInfo(ctx, "Long URL already shortened", logger.LoggerInfo{
+				ContextFunction: constant.CtxCreateShortURL,
+				Data: map[string]interface{}{
+					constant.DataShortCode: existing.ShortCode,
+					constant.DataLongURL:   existing.LongURL,
+				},
+			})
+			return existing, nil
+		}
+		if err != nil && !errors.Is(err, ErrLongURLNotFound) {
+			logger.CtxError(ctx, "Failed to look up existing long URL", logger.LoggerInfo{
+				ContextFunction: constant.CtxCreateShortURL,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeStorageFailure,
+					Message: err.Error(),
+					Type:    constant.ErrTypeRetrieval,
+				},
+				Data: map[string]interface{}{
+					constant.DataLongURL: longURL,
+				},
+			})
+			return nil, err
+		}
+
+		shortCode = s.generateShortCode()
+		logger.CtxDebug(ctx, "Generated random short code", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateShortURL,
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+	}
+
+	url := &URL{
+		LongURL:       longURL,
+		ShortCode:     shortCode,
+		CreatedAt:     time.Now(),
+		Visits:        0,
+		RedirectCode:  redirectCode,
+		OwnerID:       params.OwnerID,
+		UTMSource:     params.UTMSource,
+		UTMCampaign:   params.UTMCampaign,
+		UTMMedium:     params.UTMMedium,
+		AlternateURL:  alternateURL,
+		CanaryPercent: params.CanaryPercent,
+		GeoRules:      geoRules,
+	}
+	if params.MaxVisits != nil && *params.MaxVisits > 0 {
+		url.MaxVisits = params.MaxVisits
+	}
+	url.ExpiresAt = params.ExpiresAt
+	url.ActiveFrom = params.ActiveFrom
+
+	if params.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+		if err != nil {
+			logger.CtxError(ctx, "Failed to hash URL password", logger.LoggerInfo{
+				ContextFunction: constant.CtxCreateShortURL,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodePasswordHash,
+					Message: err.Error(),
+					Type:    constant.ErrTypeValidation,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			return nil, err
+		}
+		url.Password = string(hash)
+		url.IsProtected = true
+	} else if params.passwordHash != "" {
+		url.Password = params.passwordHash
+		url.IsProtected = true
+	}
+
+	if params.OwnerToken != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(params.OwnerToken), bcrypt.DefaultCost)
+		if err != nil {
+			logger.CtxError(ctx, "Failed to hash owner token", logger.LoggerInfo{
+				ContextFunction: constant.CtxCreateShortURL,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodePasswordHash,
+					Message: err.Error(),
+					Type:    constant.ErrTypeValidation,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			return nil, err
+		}
+		expiresAt := time.Now().Add(s.opts.OwnerTokenTTL)
+		url.OwnerTokenHash = string(hash)
+		url.OwnerTokenExpiresAt = &expiresAt
+	}
+
+	err = s.repo.Store(ctx, url)
+	// A generated code may already be taken; try fresh codes before giving up
+	for attempt := 1; customShort == "" && errors.Is(err, ErrShortCodeExists); attempt++ {
+		if attempt >= s.opts.MaxCodeGenRetries {
+			logger.CtxError(ctx, "Generated short codes kept colliding", logger.LoggerInfo{
+				ContextFunction: constant.CtxCreateShortURL,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeTooManyCollisions,
+					Message: constant.ErrTooManyCollisions,
+					Type:    constant.ErrTypeStorage,
+				},
+				Data: map[string]interface{}{
+					constant.DataLongURL: longURL,
+					constant.DataCount:   attempt,
+				},
+			})
+			return nil, ErrTooManyCollisions
+		}
+		url.ShortCode = s.generateShortCode()
+		err = s.repo.Store(ctx, url)
+	}
+	if err != nil {
+		logger.CtxError(ctx, "Failed to store URL", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateShortURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeStorageFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+			Data: map[string]interface{}{
+				constant.DataLongURL:   longURL,
+				constant.DataShortCode: url.ShortCode,
+			},
+		})
+		return nil, err
+	}
+
+	// ShortURLNamespace
+	s.cacheURL(url)
+	s.opts.Events.Publish(ctx, events.URLCreatedEvent{
+		ShortCode:  url.ShortCode,
+		LongURL:    url.LongURL,
+		Snapshot:   snapshot(url),
+		OccurredAt: time.Now(),
+	})
+
+	logger.CtxInfo(ctx, "URL successfully shortened", logger.LoggerInfo{
+		ContextFunction: constant.CtxCreateShortURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: url.ShortCode,
+			constant.DataLongURL:   url.LongURL,
+			constant.DataCustom:    customShort != "",
+		},
+	})
+
+	return url, nil
+}
+
+// checkLongURL normalizes longURL and rejects it when it is empty, malformed,
+// blacklisted or points at a private address
+func (s *Service) checkLongURL(ctx context.Context, function, longURL string) (string, error) {
+	if longURL == "" {
+		logger.CtxWarn(ctx, "Long URL cannot be empty", logger.LoggerInfo{
+			ContextFunction: function,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeEmptyLongURL,
+				Message: constant.ErrEmptyLongURL,
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+		return "", ErrEmptyLongURL
+	}
+
+	// Normalize first so equivalent spellings of a URL share a short code
+	normalized, err := urlnorm.Normalize(longURL)
+	if err != nil {
+		logger.CtxWarn(ctx, "Long URL is not a valid URL", logger.LoggerInfo{
+			ContextFunction: function,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidLongURL,
+				Message: err.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataLongURL: longURL,
+			},
+		})
+		return "", ErrInvalidLongURL
+	}
+	longURL = normalized
+
+	if s.opts.Blacklist != nil {
+		if parsedURL, err := url.Parse(longURL); err == nil && s.opts.Blacklist.IsBlocked(parsedURL.Hostname()) {
+			logger.CtxWarn(ctx, "Long URL host is blacklisted", logger.LoggerInfo{
+				ContextFunction: function,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeBlacklistedURL,
+					Message: constant.ErrBlacklistedURL,
+					Type:    constant.ErrTypeValidation,
+				},
+				Data: map[string]interface{}{
+					constant.DataLongURL: longURL,
+				},
+			})
+			return "", ErrBlacklistedURL
+		}
+	}
+
+	if s.opts.SSRFGuard != nil {
+		private, err := s.opts.SSRFGuard.IsPrivateURL(longURL)
+		if err != nil || private {
+			reason := constant.ErrSSRFBlocked
+			if err != nil {
+				reason = err.Error()
+			}
+			logger.CtxWarn(ctx, "Long URL points at a private address", logger.LoggerInfo{
+				ContextFunction: function,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeSSRFBlocked,
+					Message: reason,
+					Type:    constant.ErrTypeValidation,
+				},
+				Data: map[string]interface{}{
+					constant.DataLongURL: longURL,
+				},
+			})
+			return "", ErrSSRFBlocked
+		}
+	}
+
+	return longURL, nil
+}
+
+// checkReachable rejects longURL when reachability is validated and it does not answer
+func (s *Service) checkReachable(ctx context.Context, longURL string) error {
+	if !s.opts.ValidateURLReachability {
+		return nil
+	}
+	if err := s.opts.ReachabilityChecker.Check(ctx, longURL); err != nil {
+		logger.CtxWarn(ctx, "Long URL is unreachable", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateShortURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeURLUnreachable,
+				Message: err.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataLongURL: longURL,
+			},
+		})
+		return ErrURLUnreachable
+	}
+	return nil
+}
+
+// generateShortCode returns a generated short code that is not reserved
+func (s *Service) generateShortCode() string {
+	shortCode := s.opts.CodeGenerator.Generate()
+	for s.isReserved(shortCode) {
+		shortCode = s.opts.CodeGenerator.Generate()
+	}
+	return shortCode
+}
+
+// getLongURL implements GetLongURL
+func (s *Service) getLongURL(ctx context.Context, shortCode string) (*URL, error) {
+
+	logger.CtxDebug(ctx, "Retrieving long URL", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetLongURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	if shortCode == "" {
+		logger.CtxWarn(ctx, "Short code cannot be empty", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetLongURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeEmptyShortCode,
+				Message: constant.ErrEmptyShortCode,
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+		return nil, ErrEmptyShortCode
+	}
+
+	val, found := s.cache.Get(constant.ShortURLNamespace, s.normalizeShortCode(shortCode))
+	if found {
+		if urlObj, ok := val.(*URL); ok {
+			// Cache hit, log and return
+			logger.CtxInfo(ctx, "Long URL retrieved from cache", logger.LoggerInfo{
+				ContextFunction: constant.CtxGetLongURL,
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+					constant.DataLongURL:   urlObj.LongURL,
+					constant.DataVisits:    urlObj.Visits,
+				},
+			})
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Bool(constant.AttrCacheHit, true))
+			if err := s.enforceLimits(ctx, urlObj); err != nil {
+				return nil, err
+			}
+			if err := s.countAccess(ctx, urlObj); err != nil {
+				return nil, err
+			}
+			return urlObj, nil
+		}
+	}
+
+	url, err := s.findShortCode(ctx, shortCode)
+	if err != nil {
+		logger.CtxWarn(ctx, "Failed to find URL by short code", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetLongURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeShortCodeNotFound,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool(constant.AttrCacheHit, false))
+	if err := s.enforceLimits(ctx, url); err != nil {
+		return nil, err
+	}
+	if err := s.countAccess(ctx, url); err != nil {
+		return nil, err
+	}
+
+	logger.CtxInfo(ctx, "Long URL retrieved successfully", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetLongURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataLongURL:   url.LongURL,
+			constant.DataVisits:    url.Visits,
+		},
+	})
+
+	return url, nil
+}
+
+// countAccess tracks a visit to url and publishes its access. A protected URL is
+// left to VerifyPassword, which counts the visit once the password is accepted.
+func (s *Service) countAccess(ctx context.Context, url *URL) error {
+	if url.IsProtected {
+		return nil
+	}
+	if err := s.trackVisit(ctx, url); err != nil {
+		return err
+	}
+	s.publishAccess(ctx, url)
+	return nil
+}
+
+// enforceLimits returns ErrURLNotYetActive before url's ActiveFrom, and deactivates
+// url and returns ErrShortCodeExpired once it has passed ExpiresAt or its visits
+// have reached MaxVisits, so exactly MaxVisits redirects are served
+func (s *Service) enforceLimits(ctx context.Context, url *URL) error {
+	if err := s.checkActive(ctx, constant.CtxGetLongURL, url); err != nil {
+		return err
+	}
+
+	if url.ExpiresAt != nil && !time.Now().Before(*url.ExpiresAt) {
+		logger.CtxInfo(ctx, "URL has expired, deactivating it", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetLongURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeShortCodeExpired,
+				Message: constant.ErrShortCodeExpired,
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: url.ShortCode,
+				constant.DataExpiresAt: *url.ExpiresAt,
+			},
+		})
+
+		_ = s.DeleteURL(ctx, url.ShortCode)
+		return ErrShortCodeExpired
+	}
+
+	if url.MaxVisits == nil || *url.MaxVisits == 0 || url.Visits < *url.MaxVisits {
+		return nil
+	}
+	return s.expireAtVisitLimit(ctx, url)
 }
 
-// NewService creates a new shortener service
-func NewService(repo Repository, lru *cache.NamespaceLRU) *Service {
-	ctx := logger.NewRequestContext()
-
-	logger.CtxDebug(ctx, "Creating shortener service", logger.LoggerInfo{
-		ContextFunction: constant.CtxDomain,
+// expireAtVisitLimit deactivates url, whose visits have reached MaxVisits, and returns ErrShortCodeExpired
+func (s *Service) expireAtVisitLimit(ctx context.Context, url *URL) error {
+	logger.CtxInfo(ctx, "Visit limit reached, deactivating URL", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetLongURL,
+		Error: &logger.CustomError{
+			Code:    constant.ErrCodeShortCodeExpired,
+			Message: constant.ErrShortCodeExpired,
+			Type:    constant.ErrTypeRetrieval,
+		},
 		Data: map[string]interface{}{
-			constant.DataService: "shortener",
+			constant.DataShortCode: url.ShortCode,
+			constant.DataVisits:    url.Visits,
+			constant.DataMaxVisits: *url.MaxVisits,
 		},
 	})
 
-	return &Service{
-		repo:  repo,
-		cache: lru,
-	}
+	// A failed delete is logged by DeleteURL; the URL is expired either way
+	_ = s.DeleteURL(ctx, url.ShortCode)
+	return ErrShortCodeExpired
 }
 
-// CreateShortURL creates a new shortened URL
-func (s *Service) CreateShortURL(ctx context.Context, longURL, customShort string) (*URL, error) {
-	logger.CtxDebug(ctx, "Creating short URL", logger.LoggerInfo{
-		ContextFunction: constant.CtxCreateShortURL,
+// checkActive returns ErrURLNotYetActive while url has not reached its ActiveFrom
+func (s *Service) checkActive(ctx context.Context, function string, url *URL) error {
+	if url.ActiveAt(time.Now()) {
+		return nil
+	}
+
+	logger.CtxInfo(ctx, "URL is not active yet", logger.LoggerInfo{
+		ContextFunction: function,
+		Error: &logger.CustomError{
+			Code:    constant.ErrCodeURLNotYetActive,
+			Message: constant.ErrURLNotYetActive,
+			Type:    constant.ErrTypeRetrieval,
+		},
 		Data: map[string]interface{}{
-			constant.DataLongURL:     longURL,
-			constant.DataCustomShort: customShort != "",
+			constant.DataShortCode:  url.ShortCode,
+			constant.DataActiveFrom: *url.ActiveFrom,
 		},
 	})
+	return ErrURLNotYetActive
+}
 
-	if longURL == "" {
-		logger.CtxWarn(ctx, "Long URL cannot be empty", logger.LoggerInfo{
-			ContextFunction: constant.CtxCreateShortURL,
+// deleteURL implements DeleteURL
+func (s *Service) deleteURL(ctx context.Context, shortCode string) error {
+	if shortCode == "" {
+		logger.CtxWarn(ctx, "Short code cannot be empty", logger.LoggerInfo{
+			ContextFunction: constant.CtxDeleteURL,
 			Error: &logger.CustomError{
-				Code:    constant.ErrCodeEmptyLongURL,
-				Message: constant.ErrEmptyLongURL,
+				Code:    constant.ErrCodeEmptyShortCode,
+				Message: constant.ErrEmptyShortCode,
 				Type:    constant.ErrTypeValidation,
 			},
 		})
-		return nil, errors.New(constant.ErrEmptyLongURL)
+		return ErrEmptyShortCode
 	}
 
-	shortCode := customShort
-	if shortCode == "" {
-		shortCode = generateShortCode(6)
-		logger.CtxDebug(ctx, "Generated random short code", logger.LoggerInfo{
-			ContextFunction: constant.CtxCreateShortURL,
-			Data: map[string]interface{}{
-				constant.DataShortCode: shortCode,
-			},
-		})
+	url, err := s.findShortCode(ctx, shortCode)
+	if err != nil {
+		return err
 	}
-
-	url := &URL{
-		LongURL:   longURL,
-		ShortCode: shortCode,
-		CreatedAt: time.Now(),
-		Visits:    0,
+	if err := s.authorizeOwner(ctx, url); err != nil {
+		return err
 	}
+	shortCode = url.ShortCode
 
-	if err := s.repo.Store(ctx, url); err != nil {
-		logger.CtxError(ctx, "Failed to store URL", logger.LoggerInfo{
-			ContextFunction: constant.CtxCreateShortURL,
+	if err := s.repo.Delete(ctx, shortCode); err != nil {
+		logger.CtxError(ctx, "Failed to delete URL", logger.LoggerInfo{
+			ContextFunction: constant.CtxDeleteURL,
 			Error: &logger.CustomError{
-				Code:    constant.ErrCodeStorageFailure,
+				Code:    constant.ErrCodeDeleteFailure,
 				Message: err.Error(),
 				Type:    constant.ErrTypeStorage,
 			},
 			Data: map[string]interface{}{
-				constant.DataLongURL:   longURL,
 				constant.DataShortCode: shortCode,
 			},
 		})
-		return nil, err
+		return err
 	}
 
-	// ShortURLNamespace
-	s.cache.Set(constant.ShortURLNamespace, shortCode, url)
-
-	logger.CtxInfo(ctx, "URL successfully shortened", logger.LoggerInfo{
-		ContextFunction: constant.CtxCreateShortURL,
-		Data: map[string]interface{}{
-			constant.DataShortCode: url.ShortCode,
-			constant.DataLongURL:   url.LongURL,
-			constant.DataCustom:    customShort != "",
-		},
+	s.cache.Invalidate(constant.ShortURLNamespace, shortCode)
+	s.invalidateQRCodes(shortCode)
+	s.opts.Events.Publish(ctx, events.URLDeletedEvent{
+		ShortCode:  shortCode,
+		Snapshot:   snapshot(url),
+		OccurredAt: time.Now(),
 	})
 
-	return url, nil
-}
-
-// GetLongURL retrieves the original URL from a short code
-func (s *Service) GetLongURL(ctx context.Context, shortCode string) (*URL, error) {
-
-	logger.CtxDebug(ctx, "Retrieving long URL", logger.LoggerInfo{
-		ContextFunction: constant.CtxGetLongURL,
+	logger.CtxInfo(ctx, "URL deleted", logger.LoggerInfo{
+		ContextFunction: constant.CtxDeleteURL,
 		Data: map[string]interface{}{
 			constant.DataShortCode: shortCode,
 		},
 	})
 
+	return nil
+}
+
+// lookupURL implements LookupURL
+func (s *Service) lookupURL(ctx context.Context, shortCode string) (*URL, error) {
 	if shortCode == "" {
 		logger.CtxWarn(ctx, "Short code cannot be empty", logger.LoggerInfo{
-			ContextFunction: constant.CtxGetLongURL,
+			ContextFunction: constant.CtxLookupURL,
 			Error: &logger.CustomError{
 				Code:    constant.ErrCodeEmptyShortCode,
 				Message: constant.ErrEmptyShortCode,
 				Type:    constant.ErrTypeValidation,
 			},
 		})
-		return nil, errors.New(constant.ErrEmptyShortCode)
+		return nil, ErrEmptyShortCode
 	}
 
-	val, found := s.cache.Get(constant.ShortURLNamespace, shortCode)
-	if found {
+	if val, found := s.cache.Get(constant.ShortURLNamespace, s.normalizeShortCode(shortCode)); found {
 		if urlObj, ok := val.(*URL); ok {
-			// Cache hit, log and return
-			logger.CtxInfo(ctx, "Long URL retrieved from cache", logger.LoggerInfo{
-				ContextFunction: constant.CtxGetLongURL,
-				Data: map[string]interface{}{
-					constant.DataShortCode: shortCode,
-					constant.DataLongURL:   urlObj.LongURL,
-					constant.DataVisits:    urlObj.Visits,
-				},
-			})
-			if err := s.repo.IncrementVisits(ctx, shortCode); err != nil {
-				// Log error but continue with the redirect
-				logger.CtxWarn(ctx, "Failed to increment visit count", logger.LoggerInfo{
-					ContextFunction: constant.CtxGetLongURL,
-					Error: &logger.CustomError{
-						Code:    constant.ErrCodeIncrementVisits,
-						Message: err.Error(),
-						Type:    constant.ErrTypeStats,
-					},
-					Data: map[string]interface{}{
-						constant.DataShortCode: shortCode,
-					},
-				})
-			} else {
-				logger.CtxDebug(ctx, "Visit count incremented", logger.LoggerInfo{
-					ContextFunction: constant.CtxGetLongURL,
-					Data: map[string]interface{}{
-						constant.DataShortCode: shortCode,
-					},
-				})
-			}
 			return urlObj, nil
 		}
 	}
 
-	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	url, err := s.findShortCode(ctx, shortCode)
 	if err != nil {
 		logger.CtxWarn(ctx, "Failed to find URL by short code", logger.LoggerInfo{
-			ContextFunction: constant.CtxGetLongURL,
+			ContextFunction: constant.CtxLookupURL,
 			Error: &logger.CustomError{
 				Code:    constant.ErrCodeShortCodeNotFound,
 				Message: err.Error(),
@@ -196,7 +1117,115 @@ func (s *Service) GetLongURL(ctx context.Context, shortCode string) (*URL, error
 		return nil, err
 	}
 
-	if err := s.repo.IncrementVisits(ctx, shortCode); err != nil {
+	return url, nil
+}
+
+// verifyPassword implements VerifyPassword
+func (s *Service) verifyPassword(ctx context.Context, shortCode, password string) (*URL, error) {
+	logger.CtxDebug(ctx, "Verifying URL password", logger.LoggerInfo{
+		ContextFunction: constant.CtxVerifyPassword,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	url, err := s.LookupURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	// A correct password must not reveal where a scheduled URL will lead
+	if err := s.checkActive(ctx, constant.CtxVerifyPassword, url); err != nil {
+		return nil, err
+	}
+
+	if url.IsProtected {
+		if err := bcrypt.CompareHashAndPassword([]byte(url.Password), []byte(password)); err != nil {
+			logger.CtxWarn(ctx, "Invalid password for protected URL", logger.LoggerInfo{
+				ContextFunction: constant.CtxVerifyPassword,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeInvalidPassword,
+					Message: constant.ErrInvalidPassword,
+					Type:    constant.ErrTypeValidation,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			return nil, ErrInvalidPassword
+		}
+	}
+
+	// The visit is counted once the password is accepted, not on the way to the form
+	if err := s.enforceLimits(ctx, url); err != nil {
+		return nil, err
+	}
+	if err := s.trackVisit(ctx, url); err != nil {
+		return nil, err
+	}
+	s.publishAccess(ctx, url)
+	return url, nil
+}
+
+// publishAccess publishes a URLAccessedEvent for url
+func (s *Service) publishAccess(ctx context.Context, url *URL) {
+	s.opts.Events.Publish(ctx, events.URLAccessedEvent{
+		ShortCode:  url.ShortCode,
+		LongURL:    url.LongURL,
+		Referer:    RefererFromContext(ctx),
+		OccurredAt: time.Now(),
+	})
+}
+
+// trackVisit counts a visit and records its click event. Bot clicks are
+// recorded but do not count as visits. It returns ErrShortCodeExpired when the
+// visit would go over url's MaxVisits.
+func (s *Service) trackVisit(ctx context.Context, url *URL) error {
+	now := time.Now()
+	client := visitorFromContext(ctx)
+	deviceType := useragent.Classify(client.userAgent)
+	if s.opts.BotDetector.IsBot(client.userAgent) {
+		deviceType = useragent.Bot
+	}
+	if deviceType != useragent.Bot {
+		if err := s.incrementVisits(ctx, url, now); err != nil {
+			return err
+		}
+	}
+	s.recordClickAsync(ctx, ClickEvent{
+		ShortCode:  url.ShortCode,
+		ClickedAt:  now,
+		Referer:    RefererFromContext(ctx),
+		IPHash:     HashIP(client.ip),
+		UserAgent:  client.userAgent,
+		DeviceType: string(deviceType),
+	})
+	return nil
+}
+
+// incrementVisits counts a visit made at and updates the last access time, deferring
+// to the visit queue when one is configured; the queue writes both in a single batch.
+// A URL with MaxVisits bypasses the queue: its visit is only counted while the stored
+// visits are below the limit, so queued or concurrent visits cannot go over it, and
+// ErrShortCodeExpired is returned once they have reached it.
+func (s *Service) incrementVisits(ctx context.Context, url *URL, at time.Time) error {
+	shortCode := url.ShortCode
+	limited := url.MaxVisits != nil && *url.MaxVisits > 0
+	if s.opts.VisitQueue != nil && !limited {
+		s.opts.VisitQueue.Submit(shortCode)
+		return nil
+	}
+
+	var err error
+	if limited {
+		var counted bool
+		counted, err = s.repo.IncrementVisitsWithinLimit(ctx, shortCode)
+		if err == nil && !counted {
+			return s.expireAtVisitLimit(ctx, url)
+		}
+	} else {
+		err = s.repo.IncrementVisits(ctx, shortCode)
+	}
+	if err != nil {
 		// Log error but continue with the redirect
 		logger.CtxWarn(ctx, "Failed to increment visit count", logger.LoggerInfo{
 			ContextFunction: constant.CtxGetLongURL,
@@ -218,20 +1247,24 @@ func (s *Service) GetLongURL(ctx context.Context, shortCode string) (*URL, error
 		})
 	}
 
-	logger.CtxInfo(ctx, "Long URL retrieved successfully", logger.LoggerInfo{
-		ContextFunction: constant.CtxGetLongURL,
-		Data: map[string]interface{}{
-			constant.DataShortCode: shortCode,
-			constant.DataLongURL:   url.LongURL,
-			constant.DataVisits:    url.Visits,
-		},
-	})
-
-	return url, nil
+	if err := s.repo.UpdateLastAccessed(ctx, shortCode, at); err != nil {
+		logger.CtxWarn(ctx, "Failed to update last access time", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetLongURL,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeIncrementVisits,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStats,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+	}
+	return nil
 }
 
-// UpdateLongURL updates the long URL for an existing short code
-func (s *Service) UpdateLongURL(ctx context.Context, shortCode, newLongURL string) (*URL, error) {
+// updateLongURL implements UpdateLongURL
+func (s *Service) updateLongURL(ctx context.Context, shortCode, newLongURL string) (*URL, error) {
 	logger.CtxDebug(ctx, "Updating long URL", logger.LoggerInfo{
 		ContextFunction: constant.CtxUpdateLongURL,
 		Data: map[string]interface{}{
@@ -249,80 +1282,255 @@ func (s *Service) UpdateLongURL(ctx context.Context, shortCode, newLongURL strin
 				Type:    constant.ErrTypeValidation,
 			},
 		})
-		return nil, errors.New(constant.ErrEmptyShortCode)
+		return nil, ErrEmptyShortCode
 	}
 
-	if newLongURL == "" {
-		logger.CtxWarn(ctx, "Long URL cannot be empty", logger.LoggerInfo{
-			ContextFunction: constant.CtxUpdateLongURL,
+	// The new destination gets the same validation as one given at creation
+	newLongURL, err := s.checkLongURL(ctx, constant.CtxUpdateLongURL, newLongURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The lookup, the update and its audit entry commit together, so a URL deleted
+	// in between is neither updated nor audited
+	var url, before *URL
+	err = s.repo.WithTransaction(ctx, func(tx Repository) error {
+		found, err := s.findShortCodeIn(ctx, tx, shortCode)
+		if err != nil {
+			logger.CtxWarn(ctx, "Failed to find URL by short code", logger.LoggerInfo{
+				ContextFunction: constant.CtxUpdateLongURL,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeShortCodeNotFound,
+					Message: err.Error(),
+					Type:    constant.ErrTypeRetrieval,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+				},
+			})
+			return err
+		}
+
+		if err := s.authorizeOwner(ctx, found); err != nil {
+			return err
+		}
+		shortCode = found.ShortCode
+
+		if err := tx.UpdateLongURL(ctx, shortCode, newLongURL); err != nil {
+			logger.CtxError(ctx, "Failed to update long URL", logger.LoggerInfo{
+				ContextFunction: constant.CtxUpdateLongURL,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeUpdateFailure,
+					Message: err.Error(),
+					Type:    constant.ErrTypeStorage,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+					constant.DataLongURL:   newLongURL,
+				},
+			})
+			return err
+		}
+
+		previous := *found
+		found.LongURL = newLongURL
+		entry := newAuditEntry(ctx, AuditActionUpdate, shortCode, snapshot(&previous), snapshot(found), time.Now())
+		if err := tx.AppendAudit(ctx, entry); err != nil {
+			logAuditFailure(ctx, shortCode, AuditActionUpdate, err)
+			return err
+		}
+
+		url, before = found, &previous
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.opts.Events.Publish(ctx, events.URLUpdatedEvent{
+		ShortCode:  shortCode,
+		Before:     snapshot(before),
+		After:      snapshot(url),
+		OccurredAt: time.Now(),
+		Audited:    true,
+	})
+
+	// Update the cache
+	s.cacheURL(url)
+	s.invalidateQRCodes(shortCode)
+
+	logger.CtxInfo(ctx, "URL successfully updated", logger.LoggerInfo{
+		ContextFunction: constant.CtxUpdateLongURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataLongURL:   newLongURL,
+		},
+	})
+
+	return url, nil
+}
+
+// exportURLs implements ExportURLs
+func (s *Service) exportURLs(ctx context.Context, fn func(url *URL) error) error {
+	logger.CtxDebug(ctx, "Exporting URLs", logger.LoggerInfo{
+		ContextFunction: constant.CtxExportURLs,
+	})
+
+	if err := s.repo.FindAll(ctx, fn); err != nil {
+		logger.CtxError(ctx, "Failed to export URLs", logger.LoggerInfo{
+			ContextFunction: constant.CtxExportURLs,
 			Error: &logger.CustomError{
-				Code:    constant.ErrCodeEmptyLongURL,
-				Message: constant.ErrEmptyLongURL,
+				Code:    constant.ErrCodeExportFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+		})
+		return err
+	}
+
+	return nil
+}
+
+// Search result page sizes
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// Sizes of the most visited URLs list; larger limits are capped
+const (
+	DefaultTopURLsLimit = 10
+	MaxTopURLsLimit     = 100
+)
+
+// searchURLs implements SearchURLs
+func (s *Service) searchURLs(ctx context.Context, query string, limit, offset int) ([]*URL, int, error) {
+	logger.CtxDebug(ctx, "Searching URLs", logger.LoggerInfo{
+		ContextFunction: constant.CtxSearchURLs,
+		Data: map[string]interface{}{
+			constant.DataQuery:  query,
+			constant.DataLimit:  limit,
+			constant.DataOffset: offset,
+		},
+	})
+
+	var validationErr error
+	switch {
+	case limit < 1 || limit > MaxSearchLimit:
+		validationErr = ErrInvalidSearchLimit
+	case offset < 0:
+		validationErr = ErrInvalidSearchOffset
+	}
+	if validationErr != nil {
+		logger.CtxWarn(ctx, "Invalid search parameters", logger.LoggerInfo{
+			ContextFunction: constant.CtxSearchURLs,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidSearch,
+				Message: validationErr.Error(),
 				Type:    constant.ErrTypeValidation,
 			},
 		})
-		return nil, errors.New(constant.ErrEmptyLongURL)
+		return nil, 0, validationErr
 	}
 
-	// First check if the short code exists
-	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	urls, total, err := s.repo.Search(ctx, query, limit, offset)
 	if err != nil {
-		logger.CtxWarn(ctx, "Failed to find URL by short code", logger.LoggerInfo{
-			ContextFunction: constant.CtxUpdateLongURL,
+		logger.CtxError(ctx, "Failed to search URLs", logger.LoggerInfo{
+			ContextFunction: constant.CtxSearchURLs,
 			Error: &logger.CustomError{
-				Code:    constant.ErrCodeShortCodeNotFound,
+				Code:    constant.ErrCodeSearchFailure,
 				Message: err.Error(),
 				Type:    constant.ErrTypeRetrieval,
 			},
-			Data: map[string]interface{}{
-				constant.DataShortCode: shortCode,
+		})
+		return nil, 0, err
+	}
+
+	return urls, total, nil
+}
+
+// listRecentURLs implements ListRecentURLs
+func (s *Service) listRecentURLs(ctx context.Context, since time.Time, limit, offset int) ([]*URL, error) {
+	logger.CtxDebug(ctx, "Listing recent URLs", logger.LoggerInfo{
+		ContextFunction: constant.CtxListRecentURLs,
+		Data: map[string]interface{}{
+			constant.DataSince:  since,
+			constant.DataLimit:  limit,
+			constant.DataOffset: offset,
+		},
+	})
+
+	var validationErr error
+	switch {
+	case limit < 1 || limit > MaxSearchLimit:
+		validationErr = ErrInvalidSearchLimit
+	case offset < 0:
+		validationErr = ErrInvalidSearchOffset
+	}
+	if validationErr != nil {
+		logger.CtxWarn(ctx, "Invalid recent URL parameters", logger.LoggerInfo{
+			ContextFunction: constant.CtxListRecentURLs,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidSearch,
+				Message: validationErr.Error(),
+				Type:    constant.ErrTypeValidation,
 			},
 		})
-		return nil, err
+		return nil, validationErr
 	}
 
-	// Update the long URL
-	err = s.repo.UpdateLongURL(ctx, shortCode, newLongURL)
+	urls, err := s.repo.FindCreatedAfter(ctx, since, limit, offset)
 	if err != nil {
-		logger.CtxError(ctx, "Failed to update long URL", logger.LoggerInfo{
-			ContextFunction: constant.CtxUpdateLongURL,
+		logger.CtxError(ctx, "Failed to list recent URLs", logger.LoggerInfo{
+			ContextFunction: constant.CtxListRecentURLs,
 			Error: &logger.CustomError{
-				Code:    constant.ErrCodeUpdateFailure,
+				Code:    constant.ErrCodeSearchFailure,
 				Message: err.Error(),
-				Type:    constant.ErrTypeStorage,
-			},
-			Data: map[string]interface{}{
-				constant.DataShortCode: shortCode,
-				constant.DataLongURL:   newLongURL,
+				Type:    constant.ErrTypeRetrieval,
 			},
 		})
 		return nil, err
 	}
 
-	// Update the URL object with the new long URL
-	url.LongURL = newLongURL
-
-	// Update the cache
-	s.cache.Set(constant.ShortURLNamespace, shortCode, url)
+	return urls, nil
+}
 
-	logger.CtxInfo(ctx, "URL successfully updated", logger.LoggerInfo{
-		ContextFunction: constant.CtxUpdateLongURL,
+// getTopURLs implements GetTopURLs
+func (s *Service) getTopURLs(ctx context.Context, limit int) ([]*URL, error) {
+	logger.CtxDebug(ctx, "Listing most visited URLs", logger.LoggerInfo{
+		ContextFunction: constant.CtxGetTopURLs,
 		Data: map[string]interface{}{
-			constant.DataShortCode: shortCode,
-			constant.DataLongURL:   newLongURL,
+			constant.DataLimit: limit,
 		},
 	})
 
-	return url, nil
-}
+	if limit < 1 {
+		logger.CtxWarn(ctx, "Invalid top URLs limit", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetTopURLs,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidSearch,
+				Message: ErrInvalidSearchLimit.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+		return nil, ErrInvalidSearchLimit
+	}
+	if limit > MaxTopURLsLimit {
+		limit = MaxTopURLsLimit
+	}
 
-// generateShortCode generates a random short code of specified length
-func generateShortCode(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	result := make([]byte, length)
-	for i := range result {
-		result[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-		time.Sleep(1 * time.Nanosecond) // Ensure uniqueness
+	urls, err := s.repo.FindTopURLs(ctx, limit)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to list most visited URLs", logger.LoggerInfo{
+			ContextFunction: constant.CtxGetTopURLs,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeSearchFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+		})
+		return nil, err
 	}
-	return string(result)
+
+	return urls, nil
 }
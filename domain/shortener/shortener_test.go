@@ -3,12 +3,17 @@ package shortener
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/prasetyowira/shorter/constant"
 	"github.com/prasetyowira/shorter/infrastructure/cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // MockRepository is a mock implementation of the Repository interface
@@ -26,28 +31,221 @@ func (m *MockRepository) FindByShortCode(ctx context.Context, shortCode string)
 	return args.Get(0).(*URL), args.Error(1)
 }
 
+func (m *MockRepository) FindByLongURL(ctx context.Context, longURL string) (*URL, error) {
+	args := m.Called(ctx, longURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*URL), args.Error(1)
+}
+
 func (m *MockRepository) IncrementVisits(ctx context.Context, shortCode string) error {
 	args := m.Called(ctx, shortCode)
 	return args.Error(0)
 }
 
+func (m *MockRepository) IncrementVisitsWithinLimit(ctx context.Context, shortCode string) (bool, error) {
+	args := m.Called(ctx, shortCode)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) UpdateLastAccessed(ctx context.Context, shortCode string, at time.Time) error {
+	args := m.Called(ctx, shortCode, at)
+	return args.Error(0)
+}
+
 func (m *MockRepository) UpdateLongURL(ctx context.Context, shortCode string, newLongURL string) error {
 	args := m.Called(ctx, shortCode, newLongURL)
 	return args.Error(0)
 }
 
+func (m *MockRepository) UpdateCanary(ctx context.Context, shortCode, alternateURL string, percent uint8) error {
+	args := m.Called(ctx, shortCode, alternateURL, percent)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindExpired(ctx context.Context, now time.Time) ([]*URL, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]*URL), args.Error(1)
+}
+
+func (m *MockRepository) RenameShortCode(ctx context.Context, oldCode, newCode string) error {
+	args := m.Called(ctx, oldCode, newCode)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindUserByUsername(ctx context.Context, username string) (*User, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(*User), args.Error(1)
+}
+
+func (m *MockRepository) CreateUser(ctx context.Context, user *User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListUsers(ctx context.Context) ([]User, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]User), args.Error(1)
+}
+
+func (m *MockRepository) Search(ctx context.Context, query string, limit, offset int) ([]*URL, int, error) {
+	args := m.Called(ctx, query, limit, offset)
+	return args.Get(0).([]*URL), args.Int(1), args.Error(2)
+}
+
+func (m *MockRepository) FindCreatedAfter(ctx context.Context, since time.Time, limit, offset int) ([]*URL, error) {
+	args := m.Called(ctx, since, limit, offset)
+	return args.Get(0).([]*URL), args.Error(1)
+}
+
+func (m *MockRepository) FindTopURLs(ctx context.Context, limit int) ([]*URL, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]*URL), args.Error(1)
+}
+
+func (m *MockRepository) FindTrending(ctx context.Context, period time.Duration, limit int) ([]*TrendingURL, error) {
+	args := m.Called(ctx, period, limit)
+	return args.Get(0).([]*TrendingURL), args.Error(1)
+}
+
+func (m *MockRepository) BulkDelete(ctx context.Context, shortCodes []string) (int, []BulkDeleteError, error) {
+	args := m.Called(ctx, shortCodes)
+	return args.Int(0), args.Get(1).([]BulkDeleteError), args.Error(2)
+}
+
+func (m *MockRepository) BulkStore(ctx context.Context, urls []*URL) (*BulkStoreResult, error) {
+	args := m.Called(ctx, urls)
+	return args.Get(0).(*BulkStoreResult), args.Error(1)
+}
+
+// WithTransaction runs fn against the mock itself, so expectations set on the mock
+// apply inside the transaction too
+func (m *MockRepository) WithTransaction(ctx context.Context, fn func(tx Repository) error) error {
+	return fn(m)
+}
+
+func (m *MockRepository) AppendAudit(ctx context.Context, entry AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindAudits(ctx context.Context, shortCode string) ([]AuditEntry, error) {
+	args := m.Called(ctx, shortCode)
+	return args.Get(0).([]AuditEntry), args.Error(1)
+}
+
+func (m *MockRepository) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddTag(ctx context.Context, shortCode, tag string) error {
+	args := m.Called(ctx, shortCode, tag)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RemoveTag(ctx context.Context, shortCode, tag string) error {
+	args := m.Called(ctx, shortCode, tag)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindTags(ctx context.Context, shortCode string) ([]string, error) {
+	args := m.Called(ctx, shortCode)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepository) ListByTag(ctx context.Context, tag string, limit, offset int) ([]*URL, int, error) {
+	args := m.Called(ctx, tag, limit, offset)
+	return args.Get(0).([]*URL), args.Int(1), args.Error(2)
+}
+
+func (m *MockRepository) CreateWebhook(ctx context.Context, hook *Webhook) error {
+	args := m.Called(ctx, hook)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindWebhooks(ctx context.Context, shortCode string) ([]Webhook, error) {
+	args := m.Called(ctx, shortCode)
+	return args.Get(0).([]Webhook), args.Error(1)
+}
+
+func (m *MockRepository) DeleteWebhook(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RecordClick(ctx context.Context, event ClickEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindDeviceStats(ctx context.Context, shortCode string) (DeviceStats, error) {
+	args := m.Called(ctx, shortCode)
+	return args.Get(0).(DeviceStats), args.Error(1)
+}
+
+func (m *MockRepository) FindAllClicks(ctx context.Context, shortCode string) ([]ClickEvent, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ClickEvent), args.Error(1)
+}
+
+func (m *MockRepository) FindClicks(ctx context.Context, shortCode string, from, to time.Time) ([]ClickEvent, error) {
+	args := m.Called(ctx, shortCode, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ClickEvent), args.Error(1)
+}
+
+func (m *MockRepository) FindReferers(ctx context.Context, shortCode string) ([]RefererStat, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]RefererStat), args.Error(1)
+}
+
+func (m *MockRepository) FindDailyClicks(ctx context.Context, shortCode string, days int) ([]DailyCount, error) {
+	args := m.Called(ctx, shortCode, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]DailyCount), args.Error(1)
+}
+
+func (m *MockRepository) FindAll(ctx context.Context, fn func(url *URL) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Close() error {
 	args := m.Called()
 	return args.Error(0)
 }
 
+// waitForEvents shuts down the service's event bus once its handlers have run, so
+// the repository calls they make, such as appending audit entries, can be asserted
+func waitForEvents(t *testing.T, service *Service) {
+	t.Helper()
+	assert.NoError(t, service.opts.Events.Shutdown(context.Background()))
+}
+
 func TestService_UpdateLongURL(t *testing.T) {
 	// Create cache and mock repository
 	cacheLRU := cache.NewNamespaceLRU(100)
 	mockRepo := new(MockRepository)
 	
 	// Create service with mock repository
-	service := NewService(mockRepo, cacheLRU)
+	service := NewService(mockRepo, cacheLRU, ServiceOptions{})
 	
 	// Test cases
 	tests := []struct {
@@ -71,6 +269,7 @@ func TestService_UpdateLongURL(t *testing.T) {
 				}
 				mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(existingURL, nil)
 				mockRepo.On("UpdateLongURL", mock.Anything, "abc123", "https://example.com/updated").Return(nil)
+				mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
 			},
 			expectedURL: &URL{
 				ID:        1,
@@ -86,7 +285,7 @@ func TestService_UpdateLongURL(t *testing.T) {
 			newLongURL: "https://example.com/updated",
 			setupMock:  func() {},
 			expectedURL: nil,
-			expectedErr: errors.New(constant.ErrEmptyShortCode),
+			expectedErr: ErrEmptyShortCode,
 		},
 		{
 			name:       "Empty LongURL",
@@ -94,17 +293,17 @@ func TestService_UpdateLongURL(t *testing.T) {
 			newLongURL: "",
 			setupMock:  func() {},
 			expectedURL: nil,
-			expectedErr: errors.New(constant.ErrEmptyLongURL),
+			expectedErr: ErrEmptyLongURL,
 		},
 		{
 			name:       "ShortCode Not Found",
 			shortCode:  "nonexistent",
 			newLongURL: "https://example.com/updated",
 			setupMock: func() {
-				mockRepo.On("FindByShortCode", mock.Anything, "nonexistent").Return((*URL)(nil), errors.New(constant.ErrShortCodeNotFound))
+				mockRepo.On("FindByShortCode", mock.Anything, "nonexistent").Return((*URL)(nil), ErrShortCodeNotFound)
 			},
 			expectedURL: nil,
-			expectedErr: errors.New(constant.ErrShortCodeNotFound),
+			expectedErr: ErrShortCodeNotFound,
 		},
 		{
 			name:       "Update Error",
@@ -136,6 +335,7 @@ func TestService_UpdateLongURL(t *testing.T) {
 			// Call the function
 			ctx := context.Background()
 			url, err := service.UpdateLongURL(ctx, tt.shortCode, tt.newLongURL)
+			waitForEvents(t, service)
 			
 			// Verify results
 			if tt.expectedErr != nil {
@@ -162,7 +362,8 @@ func TestService_UpdateLongURL_Cache(t *testing.T) {
 	mockRepo := new(MockRepository)
 	
 	// Create service with mock repository
-	service := NewService(mockRepo, cacheLRU)
+	service := NewService(mockRepo, cacheLRU, ServiceOptions{})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
 	
 	// Create test URL
 	existingURL := &URL{
@@ -182,6 +383,7 @@ func TestService_UpdateLongURL_Cache(t *testing.T) {
 	// Call the function
 	ctx := context.Background()
 	url, err := service.UpdateLongURL(ctx, "abc123", "https://example.com/updated")
+	waitForEvents(t, service)
 	
 	// Verify results
 	assert.NoError(t, err)
@@ -194,4 +396,1457 @@ func TestService_UpdateLongURL_Cache(t *testing.T) {
 	
 	// Verify all mock expectations were met
 	mockRepo.AssertExpectations(t)
-} 
\ No newline at end of file
+} 
+func TestService_GetVisits(t *testing.T) {
+	// Arrange
+	cacheLRU := cache.NewNamespaceLRU(100)
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cacheLRU, ServiceOptions{})
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	clicks := []ClickEvent{
+		{ShortCode: "abc123", ClickedAt: from.Add(2 * time.Hour)},
+		{ShortCode: "abc123", ClickedAt: from.Add(5 * time.Hour)},
+		{ShortCode: "abc123", ClickedAt: from.Add(50 * time.Hour)},
+	}
+
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123"}, nil)
+	mockRepo.On("FindClicks", mock.Anything, "abc123", from, to).Return(clicks, nil)
+
+	// Act
+	buckets, err := service.GetVisits(context.Background(), "abc123", from, to, GranularityDay)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, buckets, 3)
+	assert.Equal(t, from, buckets[0].Period)
+	assert.Equal(t, uint(2), buckets[0].Count)
+	assert.Equal(t, uint(0), buckets[1].Count)
+	assert.Equal(t, uint(1), buckets[2].Count)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetVisits_InvalidQuery(t *testing.T) {
+	// Arrange
+	cacheLRU := cache.NewNamespaceLRU(100)
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cacheLRU, ServiceOptions{})
+	ctx := context.Background()
+	now := time.Now()
+
+	// Act & Assert
+	_, err := service.GetVisits(ctx, "abc123", now.Add(-time.Hour), now, "week")
+	assert.ErrorIs(t, err, ErrInvalidGranularity)
+
+	_, err = service.GetVisits(ctx, "abc123", now, now.Add(-time.Hour), GranularityHour)
+	assert.ErrorIs(t, err, ErrInvalidTimeRange)
+
+	_, err = service.GetVisits(ctx, "abc123", now.AddDate(-1, 0, 0), now, GranularityHour)
+	assert.ErrorIs(t, err, ErrInvalidTimeRange)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetSparkline(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+
+	tests := []struct {
+		name         string
+		days         int
+		expectedDays int
+	}{
+		{name: "Requested window", days: 3, expectedDays: 3},
+		{name: "Zero uses default", days: 0, expectedDays: DefaultSparklineDays},
+		{name: "Negative uses default", days: -5, expectedDays: DefaultSparklineDays},
+		{name: "At maximum", days: MaxSparklineDays, expectedDays: MaxSparklineDays},
+		{name: "Clamped to maximum", days: MaxSparklineDays + 1, expectedDays: MaxSparklineDays},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123"}, nil)
+			mockRepo.On("FindDailyClicks", mock.Anything, "abc123", tt.expectedDays).
+				Return([]DailyCount{{Date: day(-2), Count: 4}, {Date: day(0), Count: 1}}, nil)
+
+			// Act
+			buckets, err := service.GetSparkline(context.Background(), "abc123", tt.days)
+
+			// Assert
+			assert.NoError(t, err)
+			if assert.Len(t, buckets, tt.expectedDays) {
+				last := len(buckets) - 1
+				assert.Equal(t, DailyCount{Date: day(0), Count: 1}, buckets[last])
+				assert.Equal(t, DailyCount{Date: day(-1), Count: 0}, buckets[last-1])
+				assert.Equal(t, DailyCount{Date: day(-2), Count: 4}, buckets[last-2])
+				assert.Equal(t, day(1-tt.expectedDays), buckets[0].Date)
+			}
+		})
+	}
+}
+
+func TestService_GetSparkline_NotFound(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "missing").Return((*URL)(nil), ErrShortCodeNotFound)
+
+	// Act
+	buckets, err := service.GetSparkline(context.Background(), "missing", 7)
+
+	// Assert
+	assert.Nil(t, buckets)
+	assert.ErrorIs(t, err, ErrShortCodeNotFound)
+	mockRepo.AssertNotCalled(t, "FindDailyClicks", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_CreateShortURL_ShortCodeLength(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           ServiceOptions
+		expectedLength int
+	}{
+		{name: "Default", opts: ServiceOptions{}, expectedLength: DefaultShortCodeLength},
+		{name: "Configured short", opts: ServiceOptions{ShortCodeLength: 4}, expectedLength: 4},
+		{name: "Configured long", opts: ServiceOptions{ShortCodeLength: 12}, expectedLength: 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return(nil, ErrLongURLNotFound)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), tt.opts)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "")
+			waitForEvents(t, service)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Len(t, url.ShortCode, tt.expectedLength)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_CreateShortURL_ReturnsExisting(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	existingURL := &URL{ID: 1, LongURL: "https://example.com", ShortCode: "abc123", Visits: 4}
+	mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return(existingURL, nil)
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, existingURL, url)
+	mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateShortURL_ReusesOnlyMatchingUTM(t *testing.T) {
+	tests := []struct {
+		name        string
+		existingUTM string
+		newUTM      string
+		expectReuse bool
+	}{
+		{name: "Same UTM", existingUTM: "newsletter", newUTM: "newsletter", expectReuse: true},
+		{name: "Different UTM", existingUTM: "newsletter", newUTM: "twitter"},
+		{name: "UTM on existing only", existingUTM: "newsletter"},
+		{name: "UTM on new only", newUTM: "twitter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			existing := &URL{ShortCode: "abc123", LongURL: "https://example.com", UTMSource: tt.existingUTM}
+			mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return(existing, nil)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil).Maybe()
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			// Act
+			url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com", "", CreateURLParams{UTMSource: tt.newUTM})
+			waitForEvents(t, service)
+
+			// Assert
+			assert.NoError(t, err)
+			if tt.expectReuse {
+				assert.Equal(t, existing, url)
+				mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NotEqual(t, "abc123", url.ShortCode)
+			assert.Equal(t, tt.newUTM, url.UTMSource)
+			mockRepo.AssertCalled(t, "Store", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateShortURL_CustomAlwaysCreates(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "custom")
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "custom", url.ShortCode)
+	mockRepo.AssertNotCalled(t, "FindByLongURL", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+// stubBlacklist blocks a fixed set of hosts
+type stubBlacklist map[string]bool
+
+func (b stubBlacklist) IsBlocked(host string) bool {
+	return b[host]
+}
+
+func TestService_CreateShortURL_Blacklisted(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{
+		Blacklist: stubBlacklist{"evil.com": true},
+	})
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 0, "https://evil.com:8443/login", "")
+
+	// Assert
+	assert.Nil(t, url)
+	assert.ErrorIs(t, err, ErrBlacklistedURL)
+	mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateShortURL_NotBlacklisted(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{
+		Blacklist: stubBlacklist{"evil.com": true},
+	})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "custom")
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "custom", url.ShortCode)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateShortURL_ReservedCode(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	for _, code := range []string{"api", "API", "Health", "admin", "static"} {
+		// Act
+		url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", code)
+
+		// Assert
+		assert.Nil(t, url, code)
+		assert.ErrorIs(t, err, ErrReservedShortCode, code)
+	}
+	mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateShortURL_CustomReservedCodes(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{
+		ReservedCodes: []string{"promo"},
+	})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	_, reservedErr := service.CreateShortURL(context.Background(), 0, "https://example.com", "PROMO")
+	url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "api")
+
+	// Assert
+	assert.ErrorIs(t, reservedErr, ErrReservedShortCode)
+	assert.NoError(t, err)
+	assert.Equal(t, "api", url.ShortCode)
+}
+
+func TestDefaultReservedCodes(t *testing.T) {
+	codes := DefaultReservedCodes()
+
+	assert.Contains(t, codes, "api")
+	assert.Contains(t, codes, "health")
+	assert.Contains(t, codes, constant.ReservedCodeAdmin)
+	assert.Contains(t, codes, constant.ReservedCodeStatic)
+}
+
+func TestService_GetLongURL_RecordsReferer(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	recorded := make(chan ClickEvent, 1)
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		recorded <- args.Get(1).(ClickEvent)
+	})
+
+	// Act
+	ctx := WithReferer(context.Background(), "https://referrer.example")
+	ctx = WithVisitor(ctx, "203.0.113.7", "curl/8.0")
+	_, err := service.GetLongURL(ctx, "abc123")
+
+	// Assert
+	assert.NoError(t, err)
+	select {
+	case event := <-recorded:
+		assert.Equal(t, "abc123", event.ShortCode)
+		assert.Equal(t, "https://referrer.example", event.Referer)
+		assert.Equal(t, HashIP("203.0.113.7"), event.IPHash)
+		assert.NotContains(t, event.IPHash, "203.0.113.7")
+		assert.Equal(t, "curl/8.0", event.UserAgent)
+		assert.Equal(t, "desktop", event.DeviceType)
+	case <-time.After(time.Second):
+		t.Fatal("click event was not recorded")
+	}
+}
+
+func TestService_GetLongURL_SkipsBotVisits(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	recorded := make(chan ClickEvent, 1)
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		recorded <- args.Get(1).(ClickEvent)
+	})
+
+	// Act
+	ctx := WithVisitor(context.Background(), "66.249.66.1", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+	url, err := service.GetLongURL(ctx, "abc123")
+
+	// Assert - the bot is redirected and its click recorded, but it is not a visit
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.LongURL)
+	select {
+	case event := <-recorded:
+		assert.Equal(t, "bot", event.DeviceType)
+	case <-time.After(time.Second):
+		t.Fatal("click event was not recorded")
+	}
+	mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateLastAccessed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// stubBotDetector treats exactly one User-Agent as a bot
+type stubBotDetector struct {
+	botUserAgent string
+}
+
+func (d stubBotDetector) IsBot(ua string) bool {
+	return ua == d.botUserAgent
+}
+
+func TestService_GetLongURL_CustomBotDetector(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{
+		BotDetector: stubBotDetector{botUserAgent: "UptimeMonitor/1.0"},
+	})
+
+	recorded := make(chan ClickEvent, 1)
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		recorded <- args.Get(1).(ClickEvent)
+	})
+
+	// Act
+	ctx := WithVisitor(context.Background(), "203.0.113.7", "UptimeMonitor/1.0")
+	_, err := service.GetLongURL(ctx, "abc123")
+
+	// Assert - a User-Agent the default signatures miss is still treated as a bot
+	assert.NoError(t, err)
+	select {
+	case event := <-recorded:
+		assert.Equal(t, "bot", event.DeviceType)
+	case <-time.After(time.Second):
+		t.Fatal("click event was not recorded")
+	}
+	mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+}
+
+func TestService_GetLongURL_UpdatesLastAccessed(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	var accessTimes []time.Time
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		accessTimes = append(accessTimes, args.Get(2).(time.Time))
+	})
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	// Act
+	before := time.Now()
+	_, err := service.GetLongURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = service.GetLongURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+
+	// Assert
+	if assert.Len(t, accessTimes, 2) {
+		assert.False(t, accessTimes[0].Before(before))
+		assert.True(t, accessTimes[1].After(accessTimes[0]), "each access moves the time forward")
+	}
+	mockRepo.AssertNumberOfCalls(t, "IncrementVisits", 2)
+}
+
+func TestService_GetLongURL_VisitQueueTracksLastAccessed(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	queue := &recordingVisitQueue{}
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{VisitQueue: queue})
+
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	// Act
+	_, err := service.GetLongURL(context.Background(), "abc123")
+
+	// Assert - the queue writes the visit and the access time together
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc123"}, queue.submitted)
+	mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateLastAccessed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// recordingVisitQueue is a VisitQueue that records the submitted short codes
+type recordingVisitQueue struct {
+	submitted []string
+}
+
+func (q *recordingVisitQueue) Submit(shortCode string) {
+	q.submitted = append(q.submitted, shortCode)
+}
+
+func TestService_CreateShortURL_HashesPassword(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+	mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return(&URL{ShortCode: "abc123"}, nil)
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com", "", CreateURLParams{Password: "s3cret"})
+	waitForEvents(t, service)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, url.IsProtected)
+	assert.NotEqual(t, "abc123", url.ShortCode)
+	assert.NotEmpty(t, url.Password)
+	assert.NotEqual(t, "s3cret", url.Password)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_VerifyPassword(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+	ctx := context.Background()
+
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("IncrementVisits", mock.Anything, "secret").Return(nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "secret", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Maybe()
+	_, err := service.CreateShortURLWithParams(ctx, "https://example.com", "secret", CreateURLParams{Password: "s3cret"})
+	assert.NoError(t, err)
+
+	// Act
+	wrong, wrongErr := service.VerifyPassword(ctx, "secret", "guess")
+	correct, correctErr := service.VerifyPassword(ctx, "secret", "s3cret")
+
+	// Assert
+	assert.Nil(t, wrong)
+	assert.ErrorIs(t, wrongErr, ErrInvalidPassword)
+	assert.NoError(t, correctErr)
+	assert.Equal(t, "https://example.com", correct.LongURL)
+	mockRepo.AssertNumberOfCalls(t, "IncrementVisits", 1)
+}
+
+func TestService_ProtectedURL_CountsVisitOncePasswordAccepted(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	limit := uint(1)
+
+	mockURL := &URL{ShortCode: "secret", LongURL: "https://example.com", IsProtected: true, Password: string(hash), MaxVisits: &limit}
+	mockRepo.On("FindByShortCode", mock.Anything, "secret").Return(mockURL, nil)
+	mockRepo.On("IncrementVisitsWithinLimit", mock.Anything, "secret").Return(true, nil).Once()
+	mockRepo.On("IncrementVisitsWithinLimit", mock.Anything, "secret").Return(false, nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "secret", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("Delete", mock.Anything, "secret").Return(nil)
+
+	// Act - the redirect to the form does not count, the accepted password does
+	_, formErr := service.GetLongURL(ctx, "secret")
+	_, wrongErr := service.VerifyPassword(ctx, "secret", "guess")
+	_, firstErr := service.VerifyPassword(ctx, "secret", "s3cret")
+	_, secondErr := service.VerifyPassword(ctx, "secret", "s3cret")
+
+	// Assert
+	assert.NoError(t, formErr)
+	assert.ErrorIs(t, wrongErr, ErrInvalidPassword)
+	assert.NoError(t, firstErr)
+	assert.ErrorIs(t, secondErr, ErrShortCodeExpired, "the visit limit applies to password-protected URLs")
+	mockRepo.AssertNumberOfCalls(t, "IncrementVisitsWithinLimit", 2)
+	mockRepo.AssertCalled(t, "Delete", mock.Anything, "secret")
+}
+
+func TestService_LookupURL_DoesNotCountVisit(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	mockURL := &URL{ID: 1, LongURL: "https://example.com", ShortCode: "abc123", Visits: 3}
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(mockURL, nil)
+
+	// Act
+	url, err := service.LookupURL(context.Background(), "abc123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, mockURL, url)
+	mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateLastAccessed", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "RecordClick", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateShortURL_RedirectCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		redirectCode int
+		expectedCode int
+		expectedErr  string
+	}{
+		{name: "Default", redirectCode: 0, expectedCode: http.StatusFound},
+		{name: "MovedPermanently", redirectCode: http.StatusMovedPermanently, expectedCode: http.StatusMovedPermanently},
+		{name: "Found", redirectCode: http.StatusFound, expectedCode: http.StatusFound},
+		{name: "TemporaryRedirect", redirectCode: http.StatusTemporaryRedirect, expectedCode: http.StatusTemporaryRedirect},
+		{name: "PermanentRedirect", redirectCode: http.StatusPermanentRedirect, expectedCode: http.StatusPermanentRedirect},
+		{name: "OK rejected", redirectCode: http.StatusOK, expectedErr: constant.ErrInvalidRedirectCode},
+		{name: "SeeOther rejected", redirectCode: http.StatusSeeOther, expectedErr: constant.ErrInvalidRedirectCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com", "custom", CreateURLParams{RedirectCode: tt.redirectCode})
+
+			// Assert
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				assert.Nil(t, url)
+				mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, url.RedirectCode)
+		})
+	}
+}
+
+func TestService_GetLongURL_MaxVisits(t *testing.T) {
+	limit := uint(3)
+	zero := uint(0)
+
+	tests := []struct {
+		name         string
+		visits       uint
+		maxVisits    *uint
+		limitReached bool // the database refuses to count the visit, as other redirects used up the limit
+		expectedErr  string
+	}{
+		{name: "Below limit", visits: 2, maxVisits: &limit},
+		{name: "At limit", visits: 3, maxVisits: &limit, expectedErr: constant.ErrShortCodeExpired},
+		{name: "Limit reached since read", visits: 2, maxVisits: &limit, limitReached: true, expectedErr: constant.ErrShortCodeExpired},
+		{name: "Zero means unlimited", visits: 100, maxVisits: &zero},
+		{name: "Nil means unlimited", visits: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			lru := cache.NewNamespaceLRU(100)
+			service := NewService(mockRepo, lru, ServiceOptions{})
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+			mockURL := &URL{ID: 1, LongURL: "https://example.com", ShortCode: "abc123", Visits: tt.visits, MaxVisits: tt.maxVisits}
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(mockURL, nil)
+			mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+			mockRepo.On("IncrementVisitsWithinLimit", mock.Anything, "abc123").Return(!tt.limitReached, nil)
+			mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+			mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("Delete", mock.Anything, "abc123").Return(nil)
+
+			// Act
+			url, err := service.GetLongURL(context.Background(), "abc123")
+
+			// Assert
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				assert.Nil(t, url)
+				mockRepo.AssertCalled(t, "Delete", mock.Anything, "abc123")
+				mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+				mockRepo.AssertNotCalled(t, "UpdateLastAccessed", mock.Anything, mock.Anything, mock.Anything)
+				_, cached := lru.Get(constant.ShortURLNamespace, "abc123")
+				assert.False(t, cached)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, mockURL, url)
+			mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_GetLongURL_MaxVisitsBypassesVisitQueue(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	queue := &recordingVisitQueue{}
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{VisitQueue: queue})
+	limit := uint(3)
+
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com", MaxVisits: &limit}, nil)
+	mockRepo.On("IncrementVisitsWithinLimit", mock.Anything, "abc123").Return(true, nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	// Act
+	_, err := service.GetLongURL(context.Background(), "abc123")
+
+	// Assert - the limit is checked by the database as the visit is counted
+	assert.NoError(t, err)
+	assert.Empty(t, queue.submitted)
+	mockRepo.AssertCalled(t, "IncrementVisitsWithinLimit", mock.Anything, "abc123")
+	mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+}
+
+func TestService_GetLongURL_ExpiresAt(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		expiresAt   *time.Time
+		expectedErr string
+	}{
+		{name: "Not yet expired", expiresAt: &future},
+		{name: "Expired", expiresAt: &past, expectedErr: constant.ErrShortCodeExpired},
+		{name: "Nil never expires"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+			mockURL := &URL{ID: 1, LongURL: "https://example.com", ShortCode: "abc123", ExpiresAt: tt.expiresAt}
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(mockURL, nil)
+			mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+			mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+			mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("Delete", mock.Anything, "abc123").Return(nil)
+
+			// Act
+			url, err := service.GetLongURL(context.Background(), "abc123")
+
+			// Assert
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				assert.Nil(t, url)
+				mockRepo.AssertCalled(t, "Delete", mock.Anything, "abc123")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, mockURL, url)
+			mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateShortURL_InvalidExpiry(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	past := time.Now().Add(-time.Minute)
+
+	// Act
+	url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com", "", CreateURLParams{ExpiresAt: &past})
+
+	// Assert
+	assert.Nil(t, url)
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+	mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+}
+
+// stubSSRFGuard reports a fixed result for every URL
+type stubSSRFGuard struct {
+	private bool
+	err     error
+}
+
+func (g stubSSRFGuard) IsPrivateURL(rawURL string) (bool, error) {
+	return g.private, g.err
+}
+
+func TestService_CreateShortURL_SSRFGuard(t *testing.T) {
+	tests := []struct {
+		name        string
+		guard       stubSSRFGuard
+		expectedErr string
+	}{
+		{name: "Public", guard: stubSSRFGuard{}},
+		{name: "Private", guard: stubSSRFGuard{private: true}, expectedErr: constant.ErrSSRFBlocked},
+		{name: "Unresolvable", guard: stubSSRFGuard{err: errors.New("no such host")}, expectedErr: constant.ErrSSRFBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{SSRFGuard: tt.guard})
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			url, err := service.CreateShortURL(context.Background(), 0, "http://internal.example.com", "custom")
+
+			// Assert
+			if tt.expectedErr != "" {
+				assert.Nil(t, url)
+				assert.EqualError(t, err, tt.expectedErr)
+				mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, url)
+		})
+	}
+}
+
+func TestService_SearchURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		offset  int
+		wantErr string
+	}{
+		{name: "Valid", limit: DefaultSearchLimit, offset: 0},
+		{name: "Max limit", limit: MaxSearchLimit, offset: 40},
+		{name: "Zero limit", limit: 0, wantErr: constant.ErrInvalidSearchLimit},
+		{name: "Limit too large", limit: MaxSearchLimit + 1, wantErr: constant.ErrInvalidSearchLimit},
+		{name: "Negative offset", limit: 10, offset: -1, wantErr: constant.ErrInvalidSearchOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			found := []*URL{{ShortCode: "abc123", LongURL: "https://example.com"}}
+			mockRepo.On("Search", mock.Anything, "example", tt.limit, tt.offset).Return(found, 1, nil)
+
+			// Act
+			urls, total, err := service.SearchURLs(context.Background(), "example", tt.limit, tt.offset)
+
+			// Assert
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, found, urls)
+			assert.Equal(t, 1, total)
+		})
+	}
+}
+
+func TestService_ListRecentURLs(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		limit   int
+		offset  int
+		wantErr string
+	}{
+		{name: "Valid", limit: DefaultSearchLimit, offset: 0},
+		{name: "Max limit", limit: MaxSearchLimit, offset: 40},
+		{name: "Zero limit", limit: 0, wantErr: constant.ErrInvalidSearchLimit},
+		{name: "Limit too large", limit: MaxSearchLimit + 1, wantErr: constant.ErrInvalidSearchLimit},
+		{name: "Negative offset", limit: 10, offset: -1, wantErr: constant.ErrInvalidSearchOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			found := []*URL{{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: since.Add(time.Hour)}}
+			mockRepo.On("FindCreatedAfter", mock.Anything, since, tt.limit, tt.offset).Return(found, nil)
+
+			// Act
+			urls, err := service.ListRecentURLs(context.Background(), since, tt.limit, tt.offset)
+
+			// Assert
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "FindCreatedAfter", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, found, urls)
+		})
+	}
+}
+
+func TestService_GetTopURLs(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		expectedLimit int
+		wantErr       error
+	}{
+		{name: "Default", limit: DefaultTopURLsLimit, expectedLimit: DefaultTopURLsLimit},
+		{name: "Max limit", limit: MaxTopURLsLimit, expectedLimit: MaxTopURLsLimit},
+		{name: "Limit capped", limit: MaxTopURLsLimit + 1, expectedLimit: MaxTopURLsLimit},
+		{name: "Zero limit", limit: 0, wantErr: ErrInvalidSearchLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			found := []*URL{{ShortCode: "abc123", LongURL: "https://example.com", Visits: 42}}
+			mockRepo.On("FindTopURLs", mock.Anything, tt.expectedLimit).Return(found, nil)
+
+			// Act
+			urls, err := service.GetTopURLs(context.Background(), tt.limit)
+
+			// Assert
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "FindTopURLs", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, found, urls)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_GetTrendingURLs(t *testing.T) {
+	tests := []struct {
+		name          string
+		period        time.Duration
+		limit         int
+		expectedLimit int
+		wantErr       error
+	}{
+		{name: "Default", period: DefaultTrendingPeriod, limit: DefaultTopURLsLimit, expectedLimit: DefaultTopURLsLimit},
+		{name: "Max period", period: MaxTrendingPeriod, limit: DefaultTopURLsLimit, expectedLimit: DefaultTopURLsLimit},
+		{name: "Limit capped", period: time.Hour, limit: MaxTopURLsLimit + 1, expectedLimit: MaxTopURLsLimit},
+		{name: "Zero period", period: 0, limit: DefaultTopURLsLimit, wantErr: ErrInvalidPeriod},
+		{name: "Negative period", period: -time.Hour, limit: DefaultTopURLsLimit, wantErr: ErrInvalidPeriod},
+		{name: "Period too long", period: MaxTrendingPeriod + time.Hour, limit: DefaultTopURLsLimit, wantErr: ErrInvalidPeriod},
+		{name: "Zero limit", period: time.Hour, limit: 0, wantErr: ErrInvalidSearchLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			found := []*TrendingURL{{URL: URL{ShortCode: "abc123", LongURL: "https://example.com", Visits: 42}, RecentVisits: 7}}
+			mockRepo.On("FindTrending", mock.Anything, tt.period, tt.expectedLimit).Return(found, nil)
+
+			// Act
+			urls, err := service.GetTrendingURLs(context.Background(), tt.period, tt.limit)
+
+			// Assert
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "FindTrending", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, found, urls)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_LookupURL_NoopCache(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNoopCache(), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+
+	// Act
+	first, firstErr := service.LookupURL(context.Background(), "abc123")
+	second, secondErr := service.LookupURL(context.Background(), "abc123")
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, first, second)
+	mockRepo.AssertNumberOfCalls(t, "FindByShortCode", 2)
+}
+
+func TestService_CreateShortURL_NormalizesLongURL(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	existing := &URL{ShortCode: "abc123", LongURL: "http://example.com/path", RedirectCode: DefaultRedirectCode}
+	mockRepo.On("FindByLongURL", mock.Anything, "http://example.com/path").Return(existing, nil)
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 0, "HTTP://EXAMPLE.COM:80/path", "")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, existing, url)
+	mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+}
+
+func TestService_CreateShortURL_InvalidLongURL(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 0, "http://example.com/%zz", "")
+
+	// Assert
+	assert.Nil(t, url)
+	assert.ErrorIs(t, err, ErrInvalidLongURL)
+	mockRepo.AssertNotCalled(t, "FindByLongURL", mock.Anything, mock.Anything)
+}
+
+// sequenceGenerator returns the given codes in order
+type sequenceGenerator struct {
+	codes []string
+}
+
+func (g *sequenceGenerator) Generate() string {
+	code := g.codes[0]
+	g.codes = g.codes[1:]
+	return code
+}
+
+func TestService_CreateShortURL_CodeGenerator(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	generator := &sequenceGenerator{codes: []string{"api", "swift-dog"}}
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{CodeGenerator: generator})
+	mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return((*URL)(nil), ErrLongURLNotFound)
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "swift-dog", url.ShortCode, "reserved codes from the generator are skipped")
+	assert.Empty(t, generator.codes)
+}
+
+func TestService_CreateShortURL_RetriesCollisions(t *testing.T) {
+	tests := []struct {
+		name       string
+		collisions int
+		storeErr   error
+		customCode string
+		storeCalls int
+		wantErr    string
+	}{
+		{name: "Fifth code accepted", collisions: 4, storeCalls: 5},
+		{name: "Wrapped collision retried", collisions: 1, storeErr: fmt.Errorf("store url: %w", ErrShortCodeExists), storeCalls: 2},
+		{name: "Retries exhausted", collisions: 5, storeCalls: 5, wantErr: constant.ErrTooManyCollisions},
+		{name: "Custom code not retried", collisions: 1, customCode: "taken", storeCalls: 1, wantErr: constant.ErrShortCodeExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return((*URL)(nil), ErrLongURLNotFound)
+			storeErr := tt.storeErr
+			if storeErr == nil {
+				storeErr = ErrShortCodeExists
+			}
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(storeErr).Times(tt.collisions)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			url, err := service.CreateShortURL(context.Background(), 0, "https://example.com", tt.customCode)
+
+			// Assert
+			mockRepo.AssertNumberOfCalls(t, "Store", tt.storeCalls)
+			if tt.wantErr != "" {
+				assert.Nil(t, url)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, url.ShortCode)
+		})
+	}
+}
+
+func TestURL_RedirectURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      URL
+		expected string
+	}{
+		{
+			name:     "No UTM parameters",
+			url:      URL{LongURL: "https://example.com/page?ref=blog"},
+			expected: "https://example.com/page?ref=blog",
+		},
+		{
+			name:     "Without existing query",
+			url:      URL{LongURL: "https://example.com/page", UTMSource: "newsletter", UTMCampaign: "spring", UTMMedium: "email"},
+			expected: "https://example.com/page?utm_campaign=spring&utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name:     "With existing query",
+			url:      URL{LongURL: "https://example.com/page?z=1&a=2", UTMSource: "newsletter"},
+			expected: "https://example.com/page?z=1&a=2&utm_source=newsletter",
+		},
+		{
+			name:     "Keeps fragment",
+			url:      URL{LongURL: "https://example.com/page#section", UTMMedium: "email"},
+			expected: "https://example.com/page?utm_medium=email#section",
+		},
+		{
+			name:     "Does not override existing UTM parameter",
+			url:      URL{LongURL: "https://example.com/page?utm_source=twitter", UTMSource: "newsletter", UTMCampaign: "spring"},
+			expected: "https://example.com/page?utm_source=twitter&utm_campaign=spring",
+		},
+		{
+			name:     "All UTM parameters already present",
+			url:      URL{LongURL: "https://example.com/page?utm_source=twitter", UTMSource: "newsletter"},
+			expected: "https://example.com/page?utm_source=twitter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := tt.url.RedirectURL()
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestHashIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{name: "IPv4", ip: "203.0.113.7", expected: "fec52565aa0cf18f57d7cf5b3ac728503b8992d2d6f7d46da1d1201090902b02"},
+		{name: "Empty", ip: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			hash := HashIP(tt.ip)
+
+			// Assert
+			assert.Equal(t, tt.expected, hash)
+		})
+	}
+}
+
+func TestWithVisitor_TruncatesUserAgent(t *testing.T) {
+	// Act
+	ctx := WithVisitor(context.Background(), "203.0.113.7", strings.Repeat("a", maxUserAgentLength+10))
+
+	// Assert
+	assert.Len(t, visitorFromContext(ctx).userAgent, maxUserAgentLength)
+}
+
+func TestService_ExportClicks(t *testing.T) {
+	clicks := []ClickEvent{{ShortCode: "abc123", ClickedAt: time.Now(), IPHash: HashIP("203.0.113.7")}}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		found   error
+		wantErr error
+	}{
+		{name: "Owner", ctx: WithUser(context.Background(), &User{ID: 7, Role: RoleUser})},
+		{name: "Admin", ctx: WithUser(context.Background(), &User{ID: 1, Role: RoleAdmin})},
+		{name: "Other user", ctx: WithUser(context.Background(), &User{ID: 8, Role: RoleUser}), wantErr: ErrForbidden},
+		{name: "Not found", ctx: context.Background(), found: ErrShortCodeNotFound, wantErr: ErrShortCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNoopCache(), ServiceOptions{})
+			if tt.found != nil {
+				mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return((*URL)(nil), tt.found)
+			} else {
+				mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com", OwnerID: 7}, nil)
+			}
+			mockRepo.On("FindAllClicks", mock.Anything, "abc123").Return(clicks, nil)
+
+			// Act
+			result, err := service.ExportClicks(tt.ctx, "abc123")
+
+			// Assert
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "FindAllClicks", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, clicks, result)
+		})
+	}
+}
+
+func TestService_GetLongURL_ActiveFrom(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		activeFrom  *time.Time
+		expectedErr error
+	}{
+		{name: "Not active yet", activeFrom: &future, expectedErr: ErrURLNotYetActive},
+		{name: "Active", activeFrom: &past},
+		{name: "Nil is active at once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			mockURL := &URL{ID: 1, LongURL: "https://example.com", ShortCode: "abc123", ActiveFrom: tt.activeFrom}
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(mockURL, nil)
+			mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+			mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+			mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			url, err := service.GetLongURL(context.Background(), "abc123")
+
+			// Assert
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, url)
+				// A scheduled URL is neither counted nor deactivated
+				mockRepo.AssertNotCalled(t, "IncrementVisits", mock.Anything, mock.Anything)
+				mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, mockURL, url)
+		})
+	}
+}
+
+func TestService_CreateShortURL_ActiveFromAfterExpiry(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	activeFrom := time.Now().Add(2 * time.Hour)
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Act
+	url, err := service.CreateShortURLWithParams(context.Background(), "https://example.com", "", CreateURLParams{ActiveFrom: &activeFrom, ExpiresAt: &expiresAt})
+
+	// Assert
+	assert.Nil(t, url)
+	assert.ErrorIs(t, err, ErrInvalidActiveFrom)
+	mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+}
+
+func TestService_CaseSensitivity(t *testing.T) {
+	tests := []struct {
+		name          string
+		caseSensitive bool
+		customShort   string
+		lookup        string
+		expectedCode  string
+	}{
+		{name: "Insensitive stores lowercase", customShort: "SpringSale", lookup: "SPRINGSALE", expectedCode: "springsale"},
+		{name: "Sensitive keeps case", caseSensitive: true, customShort: "SpringSale", lookup: "SpringSale", expectedCode: "SpringSale"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNoopCache(), ServiceOptions{CaseSensitive: tt.caseSensitive})
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+			mockRepo.On("FindByShortCode", mock.Anything, tt.expectedCode).Return(&URL{ShortCode: tt.expectedCode, LongURL: "https://example.com"}, nil)
+			mockRepo.On("IncrementVisits", mock.Anything, tt.expectedCode).Return(nil)
+			mockRepo.On("UpdateLastAccessed", mock.Anything, tt.expectedCode, mock.Anything).Return(nil)
+			mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			created, createErr := service.CreateShortURL(context.Background(), 0, "https://example.com", tt.customShort)
+			found, findErr := service.GetLongURL(context.Background(), tt.lookup)
+
+			// Assert
+			assert.NoError(t, createErr)
+			assert.NoError(t, findErr)
+			assert.Equal(t, tt.expectedCode, created.ShortCode)
+			assert.Equal(t, "https://example.com", found.LongURL)
+		})
+	}
+}
+
+func TestService_GetLongURL_LegacyMixedCaseCode(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNoopCache(), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return((*URL)(nil), ErrShortCodeNotFound)
+	mockRepo.On("FindByShortCode", mock.Anything, "AbC123").Return(&URL{ShortCode: "AbC123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("IncrementVisits", mock.Anything, "AbC123").Return(nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "AbC123", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	url, err := service.GetLongURL(context.Background(), "AbC123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.LongURL)
+	mockRepo.AssertCalled(t, "IncrementVisits", mock.Anything, "AbC123")
+}
+
+func TestService_ChangesLegacyMixedCaseCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(mockRepo *MockRepository)
+		act    func(service *Service) error
+	}{
+		{
+			name:   "Delete",
+			expect: func(mockRepo *MockRepository) { mockRepo.On("Delete", mock.Anything, "AbC123").Return(nil) },
+			act:    func(service *Service) error { return service.DeleteURL(context.Background(), "AbC123") },
+		},
+		{
+			name: "Update long URL",
+			expect: func(mockRepo *MockRepository) {
+				mockRepo.On("UpdateLongURL", mock.Anything, "AbC123", "https://example.com/new").Return(nil)
+			},
+			act: func(service *Service) error {
+				_, err := service.UpdateLongURL(context.Background(), "AbC123", "https://example.com/new")
+				return err
+			},
+		},
+		{
+			name: "Update canary",
+			expect: func(mockRepo *MockRepository) {
+				mockRepo.On("UpdateCanary", mock.Anything, "AbC123", "https://example.com/b", uint8(10)).Return(nil)
+			},
+			act: func(service *Service) error {
+				_, err := service.UpdateCanary(context.Background(), "AbC123", "https://example.com/b", 10)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNoopCache(), ServiceOptions{})
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return((*URL)(nil), ErrShortCodeNotFound)
+			mockRepo.On("FindByShortCode", mock.Anything, "AbC123").Return(&URL{ShortCode: "AbC123", LongURL: "https://example.com"}, nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil).Maybe()
+			tt.expect(mockRepo)
+
+			// Act
+			err := tt.act(service)
+			waitForEvents(t, service)
+
+			// Assert - the URL created while codes were case sensitive is changed under its stored code
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_GeneratedCodesAreLowercase(t *testing.T) {
+	tests := []struct {
+		name          string
+		caseSensitive bool
+		expectMixed   bool
+	}{
+		{name: "Case insensitive", caseSensitive: false},
+		{name: "Case sensitive", caseSensitive: true, expectMixed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			service := NewService(new(MockRepository), cache.NewNoopCache(), ServiceOptions{CaseSensitive: tt.caseSensitive})
+			mixed := false
+
+			// Act
+			for i := 0; i < 200; i++ {
+				code := service.generateShortCode()
+				mixed = mixed || code != strings.ToLower(code)
+			}
+
+			// Assert - the default generator draws from a lowercase alphabet unless codes are case sensitive
+			assert.Equal(t, tt.expectMixed, mixed)
+		})
+	}
+}
+
+// stubReachabilityChecker returns a fixed result for every URL and records the URLs checked
+type stubReachabilityChecker struct {
+	err     error
+	checked []string
+}
+
+func (c *stubReachabilityChecker) Check(ctx context.Context, rawURL string) error {
+	c.checked = append(c.checked, rawURL)
+	return c.err
+}
+
+func TestService_CreateShortURL_Reachability(t *testing.T) {
+	tests := []struct {
+		name            string
+		validate        bool
+		checkErr        error
+		expectedErr     error
+		expectedChecked []string
+	}{
+		{name: "Not validated", validate: false, checkErr: errors.New("connection refused")},
+		{name: "Reachable", validate: true, expectedChecked: []string{"https://example.com/page"}},
+		{name: "Unreachable", validate: true, checkErr: errors.New("HEAD https://example.com/page answered 404 Not Found"), expectedErr: ErrURLUnreachable, expectedChecked: []string{"https://example.com/page"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			checker := &stubReachabilityChecker{err: tt.checkErr}
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{ValidateURLReachability: tt.validate, ReachabilityChecker: checker})
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+
+			// Act
+			url, err := service.CreateShortURL(context.Background(), 0, "https://example.com/page", "custom")
+
+			// Assert
+			assert.Equal(t, tt.expectedChecked, checker.checked)
+			if tt.expectedErr != nil {
+				assert.Nil(t, url)
+				assert.ErrorIs(t, err, tt.expectedErr)
+				mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, url)
+		})
+	}
+}
+
+func TestURL_StatusAt(t *testing.T) {
+	now := time.Now()
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	limit := uint(3)
+	zero := uint(0)
+
+	tests := []struct {
+		name     string
+		url      URL
+		expected string
+	}{
+		{name: "No limits", url: URL{}, expected: URLStatusActive},
+		{name: "Before expiry", url: URL{ExpiresAt: &after}, expected: URLStatusActive},
+		{name: "Expired", url: URL{ExpiresAt: &before}, expected: URLStatusExpired},
+		{name: "Below visit limit", url: URL{MaxVisits: &limit, Visits: 2}, expected: URLStatusActive},
+		{name: "Visit limit reached", url: URL{MaxVisits: &limit, Visits: 3}, expected: URLStatusExpired},
+		{name: "Zero visit limit is unlimited", url: URL{MaxVisits: &zero, Visits: 10}, expected: URLStatusActive},
+		{name: "Scheduled", url: URL{ActiveFrom: &after}, expected: URLStatusScheduled},
+		{name: "Activated", url: URL{ActiveFrom: &before}, expected: URLStatusActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			status := tt.url.StatusAt(now)
+
+			// Assert
+			assert.Equal(t, tt.expected, status)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// MaxTagLength is the longest tag name accepted, in characters
+const MaxTagLength = 50
+
+// Tag is a label used to group short URLs, for example by campaign or project
+type Tag struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// normalizeTag trims and lower-cases tag so that "Campaign " and "campaign" are the same tag
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// addTag implements AddTag
+func (s *Service) addTag(ctx context.Context, shortCode, tag string) ([]string, error) {
+	name, err := normalizeTag(tag)
+	if err != nil {
+		logger.CtxWarn(ctx, "Invalid tag", logger.LoggerInfo{
+			ContextFunction: constant.CtxAddTag,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidTag,
+				Message: err.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	url, err := s.authorizeTagChange(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddTag(ctx, url.ShortCode, name); err != nil {
+		logger.CtxError(ctx, "Failed to add tag", logger.LoggerInfo{
+			ContextFunction: constant.CtxAddTag,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeTagFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataTag:       name,
+			},
+		})
+		return nil, err
+	}
+
+	logger.CtxInfo(ctx, "Tag added", logger.LoggerInfo{
+		ContextFunction: constant.CtxAddTag,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataTag:       name,
+		},
+	})
+
+	return s.repo.FindTags(ctx, url.ShortCode)
+}
+
+// removeTag implements RemoveTag
+func (s *Service) removeTag(ctx context.Context, shortCode, tag string) ([]string, error) {
+	name, err := normalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.authorizeTagChange(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RemoveTag(ctx, url.ShortCode, name); err != nil {
+		if !errors.Is(err, ErrTagNotFound) {
+			logger.CtxError(ctx, "Failed to remove tag", logger.LoggerInfo{
+				ContextFunction: constant.CtxRemoveTag,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeTagFailure,
+					Message: err.Error(),
+					Type:    constant.ErrTypeStorage,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: shortCode,
+					constant.DataTag:       name,
+				},
+			})
+		}
+		return nil, err
+	}
+
+	logger.CtxInfo(ctx, "Tag removed", logger.LoggerInfo{
+		ContextFunction: constant.CtxRemoveTag,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataTag:       name,
+		},
+	})
+
+	return s.repo.FindTags(ctx, url.ShortCode)
+}
+
+// authorizeTagChange checks that the short code exists and the user in ctx may modify it,
+// and returns its URL, whose ShortCode the tags are stored under
+func (s *Service) authorizeTagChange(ctx context.Context, shortCode string) (*URL, error) {
+	if shortCode == "" {
+		return nil, ErrEmptyShortCode
+	}
+
+	url, err := s.LookupURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeOwner(ctx, url); err != nil {
+		return nil, err
+	}
+	return url, nil
+}
+
+// getTags implements GetTags
+func (s *Service) getTags(ctx context.Context, shortCode string) ([]string, error) {
+	url, err := s.LookupURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.repo.FindTags(ctx, url.ShortCode)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to look up tags", logger.LoggerInfo{
+			ContextFunction: constant.CtxFindTags,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeTagFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+	return tags, nil
+}
+
+// listByTag implements ListByTag
+func (s *Service) listByTag(ctx context.Context, tag string, limit, offset int) ([]*URL, int, error) {
+	name, err := normalizeTag(tag)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if limit < 1 || limit > MaxSearchLimit {
+		return nil, 0, ErrInvalidSearchLimit
+	}
+	if offset < 0 {
+		return nil, 0, ErrInvalidSearchOffset
+	}
+
+	urls, total, err := s.repo.ListByTag(ctx, name, limit, offset)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to list URLs by tag", logger.LoggerInfo{
+			ContextFunction: constant.CtxListByTag,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeTagFailure,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataTag: name,
+			},
+		})
+		return nil, 0, err
+	}
+	return urls, total, nil
+}
@@ -0,0 +1,194 @@
+package shortener
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		expected string
+		err      string
+	}{
+		{name: "Lower-cased and trimmed", tag: "  Campaign ", expected: "campaign"},
+		{name: "Max length", tag: strings.Repeat("a", MaxTagLength), expected: strings.Repeat("a", MaxTagLength)},
+		{name: "Empty", tag: "   ", err: constant.ErrInvalidTag},
+		{name: "Too long", tag: strings.Repeat("a", MaxTagLength+1), err: constant.ErrInvalidTag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			tag, err := normalizeTag(tt.tag)
+
+			// Assert
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tag)
+		})
+	}
+}
+
+func TestService_AddTag(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", OwnerID: 7}, nil)
+	mockRepo.On("AddTag", mock.Anything, "abc123", "spring-sale").Return(nil)
+	mockRepo.On("FindTags", mock.Anything, "abc123").Return([]string{"spring-sale"}, nil)
+	ctx := WithUser(context.Background(), &User{ID: 7, Role: RoleUser})
+
+	// Act
+	tags, err := service.AddTag(ctx, "abc123", " Spring-Sale ")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spring-sale"}, tags)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_AddTag_Forbidden(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", OwnerID: 7}, nil)
+	ctx := WithUser(context.Background(), &User{ID: 8, Role: RoleUser})
+
+	// Act
+	tags, err := service.AddTag(ctx, "abc123", "campaign")
+
+	// Assert
+	assert.Nil(t, tags)
+	assert.ErrorIs(t, err, ErrForbidden)
+	mockRepo.AssertNotCalled(t, "AddTag", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_AddTag_Invalid(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	// Act
+	tags, err := service.AddTag(context.Background(), "abc123", "")
+
+	// Assert
+	assert.Nil(t, tags)
+	assert.ErrorIs(t, err, ErrInvalidTag)
+	mockRepo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
+
+func TestService_RemoveTag_NotFound(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123"}, nil)
+	mockRepo.On("RemoveTag", mock.Anything, "abc123", "campaign").Return(ErrTagNotFound)
+
+	// Act
+	tags, err := service.RemoveTag(context.Background(), "abc123", "Campaign")
+
+	// Assert
+	assert.Nil(t, tags)
+	assert.ErrorIs(t, err, ErrTagNotFound)
+	mockRepo.AssertNotCalled(t, "FindTags", mock.Anything, mock.Anything)
+}
+
+func TestService_TagsUseStoredShortCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(mockRepo *MockRepository)
+		act    func(service *Service) ([]string, error)
+	}{
+		{
+			name:   "Add",
+			expect: func(mockRepo *MockRepository) { mockRepo.On("AddTag", mock.Anything, "AbC123", "campaign").Return(nil) },
+			act: func(service *Service) ([]string, error) {
+				return service.AddTag(context.Background(), "ABC123", "campaign")
+			},
+		},
+		{
+			name: "Remove",
+			expect: func(mockRepo *MockRepository) {
+				mockRepo.On("RemoveTag", mock.Anything, "AbC123", "campaign").Return(nil)
+			},
+			act: func(service *Service) ([]string, error) {
+				return service.RemoveTag(context.Background(), "ABC123", "campaign")
+			},
+		},
+		{
+			name:   "Get",
+			expect: func(mockRepo *MockRepository) {},
+			act: func(service *Service) ([]string, error) {
+				return service.GetTags(context.Background(), "ABC123")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNoopCache(), ServiceOptions{})
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "AbC123"}, nil)
+			mockRepo.On("FindTags", mock.Anything, "AbC123").Return([]string{"campaign"}, nil)
+			tt.expect(mockRepo)
+
+			// Act
+			tags, err := tt.act(service)
+
+			// Assert - the tags are changed and read under the code the URL is stored with
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"campaign"}, tags)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_ListByTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		tag    string
+		limit  int
+		offset int
+		err    string
+	}{
+		{name: "Valid", tag: "Campaign", limit: 10, offset: 0},
+		{name: "Invalid tag", tag: "", limit: 10, offset: 0, err: constant.ErrInvalidTag},
+		{name: "Limit too large", tag: "campaign", limit: MaxSearchLimit + 1, offset: 0, err: constant.ErrInvalidSearchLimit},
+		{name: "Negative offset", tag: "campaign", limit: 10, offset: -1, err: constant.ErrInvalidSearchOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			urls := []*URL{{ShortCode: "abc123"}}
+			mockRepo.On("ListByTag", mock.Anything, "campaign", tt.limit, tt.offset).Return(urls, 1, nil)
+
+			// Act
+			result, total, err := service.ListByTag(context.Background(), tt.tag, tt.limit, tt.offset)
+
+			// Assert
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				mockRepo.AssertNotCalled(t, "ListByTag", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, urls, result)
+			assert.Equal(t, 1, total)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package shortener
+
+import (
+	"context"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan opens a span named after the Service method
+func (s *Service) startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.opts.Tracer.Start(ctx, "shortener.Service."+method, trace.WithAttributes(attrs...))
+}
+
+// endSpan marks the span as failed when err is set, then ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// CreateShortURLWithParams creates a new shortened URL with optional settings
+func (s *Service) CreateShortURLWithParams(ctx context.Context, longURL, customShort string, params CreateURLParams) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "CreateShortURL",
+		attribute.Bool(constant.AttrCustomShort, customShort != ""),
+		attribute.Bool(constant.AttrProtected, params.Password != ""),
+	)
+	url, err := s.createShortURLWithParams(ctx, longURL, customShort, params)
+	if err == nil {
+		span.SetAttributes(attribute.String(constant.AttrShortCode, url.ShortCode))
+	}
+	endSpan(span, err)
+	return url, err
+}
+
+// GetLongURL retrieves the original URL from a short code
+func (s *Service) GetLongURL(ctx context.Context, shortCode string) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "GetLongURL", attribute.String(constant.AttrShortCode, shortCode))
+	url, err := s.getLongURL(ctx, shortCode)
+	endSpan(span, err)
+	return url, err
+}
+
+// LookupURL retrieves the URL for a short code without counting a visit
+func (s *Service) LookupURL(ctx context.Context, shortCode string) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "LookupURL", attribute.String(constant.AttrShortCode, shortCode))
+	url, err := s.lookupURL(ctx, shortCode)
+	endSpan(span, err)
+	return url, err
+}
+
+// VerifyPassword checks password against a protected URL's hash and, once it is accepted,
+// enforces the URL's limits and counts the visit
+func (s *Service) VerifyPassword(ctx context.Context, shortCode, password string) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "VerifyPassword", attribute.String(constant.AttrShortCode, shortCode))
+	url, err := s.verifyPassword(ctx, shortCode, password)
+	endSpan(span, err)
+	return url, err
+}
+
+// UpdateLongURL updates the long URL for an existing short code
+func (s *Service) UpdateLongURL(ctx context.Context, shortCode, newLongURL string) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "UpdateLongURL", attribute.String(constant.AttrShortCode, shortCode))
+	url, err := s.updateLongURL(ctx, shortCode, newLongURL)
+	endSpan(span, err)
+	return url, err
+}
+
+// UpdateCanary sends percent percent of a short URL's redirects to alternateURL
+func (s *Service) UpdateCanary(ctx context.Context, shortCode, alternateURL string, percent uint8) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "UpdateCanary", attribute.String(constant.AttrShortCode, shortCode))
+	url, err := s.updateCanary(ctx, shortCode, alternateURL, percent)
+	endSpan(span, err)
+	return url, err
+}
+
+// RenameShortCode moves a short URL to a new short code, keeping its long URL, visits and tags
+func (s *Service) RenameShortCode(ctx context.Context, oldCode, newCode string) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "RenameShortCode", attribute.String(constant.AttrShortCode, oldCode))
+	url, err := s.renameShortCode(ctx, oldCode, newCode)
+	endSpan(span, err)
+	return url, err
+}
+
+// CloneURL creates an independent copy of a short URL's settings under newShortCode,
+// or a generated code when it is empty
+func (s *Service) CloneURL(ctx context.Context, shortCode, newShortCode string) (*URL, error) {
+	ctx, span := s.startSpan(ctx, "CloneURL",
+		attribute.String(constant.AttrShortCode, shortCode),
+		attribute.Bool(constant.AttrCustomShort, newShortCode != ""),
+	)
+	url, err := s.cloneURL(ctx, shortCode, newShortCode)
+	endSpan(span, err)
+	return url, err
+}
+
+// WarmCache loads up to limit of the most visited URLs into the cache and returns how many it loaded
+func (s *Service) WarmCache(ctx context.Context, limit int) (int, error) {
+	ctx, span := s.startSpan(ctx, "WarmCache")
+	count, err := s.warmCache(ctx, limit)
+	span.SetAttributes(attribute.Int(constant.AttrCount, count))
+	endSpan(span, err)
+	return count, err
+}
+
+// DeleteURL soft-deletes a short URL so it no longer resolves
+func (s *Service) DeleteURL(ctx context.Context, shortCode string) error {
+	ctx, span := s.startSpan(ctx, "DeleteURL", attribute.String(constant.AttrShortCode, shortCode))
+	err := s.deleteURL(ctx, shortCode)
+	endSpan(span, err)
+	return err
+}
+
+// BulkDeleteURLs soft-deletes the given short URLs and reports which could not be deleted
+func (s *Service) BulkDeleteURLs(ctx context.Context, shortCodes []string) (*BulkDeleteResult, error) {
+	ctx, span := s.startSpan(ctx, "BulkDeleteURLs", attribute.Int(constant.AttrCount, len(shortCodes)))
+	result, err := s.bulkDeleteURLs(ctx, shortCodes)
+	endSpan(span, err)
+	return result, err
+}
+
+// CreateBulkShortURLs creates many short URLs at once and reports which could not be created
+func (s *Service) CreateBulkShortURLs(ctx context.Context, items []BulkURL) (*BulkStoreResult, error) {
+	ctx, span := s.startSpan(ctx, "CreateBulkShortURLs", attribute.Int(constant.AttrCount, len(items)))
+	result, err := s.createBulkShortURLs(ctx, items)
+	endSpan(span, err)
+	return result, err
+}
+
+// GetAuditLog returns the recorded changes to a short code, oldest first
+func (s *Service) GetAuditLog(ctx context.Context, shortCode string) ([]AuditEntry, error) {
+	ctx, span := s.startSpan(ctx, "GetAuditLog", attribute.String(constant.AttrShortCode, shortCode))
+	entries, err := s.getAuditLog(ctx, shortCode)
+	endSpan(span, err)
+	return entries, err
+}
+
+// ExportURLs streams every stored URL to fn, stopping at the first error
+func (s *Service) ExportURLs(ctx context.Context, fn func(url *URL) error) error {
+	ctx, span := s.startSpan(ctx, "ExportURLs")
+	err := s.exportURLs(ctx, fn)
+	endSpan(span, err)
+	return err
+}
+
+// GetVisits returns click counts for a short code aggregated per hour or day within [from, to)
+func (s *Service) GetVisits(ctx context.Context, shortCode string, from, to time.Time, granularity string) ([]VisitBucket, error) {
+	ctx, span := s.startSpan(ctx, "GetVisits",
+		attribute.String(constant.AttrShortCode, shortCode),
+		attribute.String(constant.AttrGranularity, granularity),
+	)
+	buckets, err := s.getVisits(ctx, shortCode, from, to, granularity)
+	endSpan(span, err)
+	return buckets, err
+}
+
+// GetReferers returns click counts for a short code grouped by referer, most frequent first
+func (s *Service) GetReferers(ctx context.Context, shortCode string) ([]RefererStat, error) {
+	ctx, span := s.startSpan(ctx, "GetReferers", attribute.String(constant.AttrShortCode, shortCode))
+	referers, err := s.getReferers(ctx, shortCode)
+	endSpan(span, err)
+	return referers, err
+}
+
+// ExportClicks returns every click event of a short URL the caller may modify, oldest first
+func (s *Service) ExportClicks(ctx context.Context, shortCode string) ([]ClickEvent, error) {
+	ctx, span := s.startSpan(ctx, "ExportClicks", attribute.String(constant.AttrShortCode, shortCode))
+	clicks, err := s.exportClicks(ctx, shortCode)
+	endSpan(span, err)
+	return clicks, err
+}
+
+// GetDeviceStats returns click counts for a short code grouped by device type
+func (s *Service) GetDeviceStats(ctx context.Context, shortCode string) (DeviceStats, error) {
+	ctx, span := s.startSpan(ctx, "GetDeviceStats", attribute.String(constant.AttrShortCode, shortCode))
+	stats, err := s.getDeviceStats(ctx, shortCode)
+	endSpan(span, err)
+	return stats, err
+}
+
+// GetSparkline returns one click count per UTC day over the last given number of days, oldest first
+func (s *Service) GetSparkline(ctx context.Context, shortCode string, days int) ([]DailyCount, error) {
+	ctx, span := s.startSpan(ctx, "GetSparkline", attribute.String(constant.AttrShortCode, shortCode))
+	buckets, err := s.getSparkline(ctx, shortCode, days)
+	endSpan(span, err)
+	return buckets, err
+}
+
+// SearchURLs returns a page of URLs whose long URL contains query, with the total number of matches
+func (s *Service) SearchURLs(ctx context.Context, query string, limit, offset int) ([]*URL, int, error) {
+	ctx, span := s.startSpan(ctx, "SearchURLs")
+	urls, total, err := s.searchURLs(ctx, query, limit, offset)
+	endSpan(span, err)
+	return urls, total, err
+}
+
+// ListRecentURLs returns a page of the URLs created after since, newest first
+func (s *Service) ListRecentURLs(ctx context.Context, since time.Time, limit, offset int) ([]*URL, error) {
+	ctx, span := s.startSpan(ctx, "ListRecentURLs")
+	urls, err := s.listRecentURLs(ctx, since, limit, offset)
+	endSpan(span, err)
+	return urls, err
+}
+
+// GetTopURLs returns up to limit URLs with the most visits, most visited first.
+// Limits above MaxTopURLsLimit are capped.
+func (s *Service) GetTopURLs(ctx context.Context, limit int) ([]*URL, error) {
+	ctx, span := s.startSpan(ctx, "GetTopURLs")
+	urls, err := s.getTopURLs(ctx, limit)
+	endSpan(span, err)
+	return urls, err
+}
+
+// GetTrendingURLs returns up to limit URLs with the most clicks within the past period,
+// most clicks first. Limits above MaxTopURLsLimit are capped.
+func (s *Service) GetTrendingURLs(ctx context.Context, period time.Duration, limit int) ([]*TrendingURL, error) {
+	ctx, span := s.startSpan(ctx, "GetTrendingURLs", attribute.String(constant.AttrPeriod, period.String()))
+	urls, err := s.getTrendingURLs(ctx, period, limit)
+	endSpan(span, err)
+	return urls, err
+}
+
+// AddTag attaches tag to a short URL the caller may modify and returns the URL's tags
+func (s *Service) AddTag(ctx context.Context, shortCode, tag string) ([]string, error) {
+	ctx, span := s.startSpan(ctx, "AddTag", attribute.String(constant.AttrShortCode, shortCode))
+	tags, err := s.addTag(ctx, shortCode, tag)
+	endSpan(span, err)
+	return tags, err
+}
+
+// RemoveTag detaches tag from a short URL the caller may modify and returns the URL's remaining tags
+func (s *Service) RemoveTag(ctx context.Context, shortCode, tag string) ([]string, error) {
+	ctx, span := s.startSpan(ctx, "RemoveTag", attribute.String(constant.AttrShortCode, shortCode))
+	tags, err := s.removeTag(ctx, shortCode, tag)
+	endSpan(span, err)
+	return tags, err
+}
+
+// GetTags returns the tags of a short URL in alphabetical order
+func (s *Service) GetTags(ctx context.Context, shortCode string) ([]string, error) {
+	ctx, span := s.startSpan(ctx, "GetTags", attribute.String(constant.AttrShortCode, shortCode))
+	tags, err := s.getTags(ctx, shortCode)
+	endSpan(span, err)
+	return tags, err
+}
+
+// ListByTag returns a page of URLs carrying tag, newest first, with the total number of matches
+func (s *Service) ListByTag(ctx context.Context, tag string, limit, offset int) ([]*URL, int, error) {
+	ctx, span := s.startSpan(ctx, "ListByTag")
+	urls, total, err := s.listByTag(ctx, tag, limit, offset)
+	endSpan(span, err)
+	return urls, total, err
+}
+
+// CreateWebhook registers a webhook for events on a short URL the caller may modify
+func (s *Service) CreateWebhook(ctx context.Context, hook *Webhook) (*Webhook, error) {
+	ctx, span := s.startSpan(ctx, "CreateWebhook", attribute.String(constant.AttrShortCode, hook.ShortCode))
+	created, err := s.createWebhook(ctx, hook)
+	endSpan(span, err)
+	return created, err
+}
+
+// CreateUser creates a user account with a bcrypt-hashed password
+func (s *Service) CreateUser(ctx context.Context, username, password string, role Role) (*User, error) {
+	ctx, span := s.startSpan(ctx, "CreateUser", attribute.String(constant.AttrRole, string(role)))
+	user, err := s.createUser(ctx, username, password, role)
+	endSpan(span, err)
+	return user, err
+}
+
+// ListUsers returns every user account
+func (s *Service) ListUsers(ctx context.Context) ([]User, error) {
+	ctx, span := s.startSpan(ctx, "ListUsers")
+	users, err := s.listUsers(ctx)
+	endSpan(span, err)
+	return users, err
+}
+
+// Authenticate returns the user matching username and password
+func (s *Service) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	ctx, span := s.startSpan(ctx, "Authenticate")
+	user, err := s.authenticate(ctx, username, password)
+	endSpan(span, err)
+	return user, err
+}
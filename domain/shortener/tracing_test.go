@@ -0,0 +1,98 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTracedService creates a service whose spans are captured by the returned recorder
+func newTracedService(repo Repository) (*Service, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	service := NewService(repo, cache.NewNamespaceLRU(100), ServiceOptions{
+		Tracer: provider.Tracer("test"),
+	})
+	return service, recorder
+}
+
+// spanAttributes flattens span attributes into a map keyed by attribute name
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestService_GetLongURL_Span(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service, recorder := newTracedService(mockRepo)
+
+	mockURL := &URL{ID: 1, LongURL: "https://example.com", ShortCode: "abc123"}
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(mockURL, nil)
+	mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	_, err := service.GetLongURL(context.Background(), "abc123")
+
+	// Assert
+	assert.NoError(t, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "shortener.Service.GetLongURL", spans[0].Name())
+	attrs := spanAttributes(spans[0])
+	assert.Equal(t, "abc123", attrs[constant.AttrShortCode].AsString())
+	assert.False(t, attrs[constant.AttrCacheHit].AsBool())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestService_GetLongURL_SpanError(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service, recorder := newTracedService(mockRepo)
+
+	mockRepo.On("FindByShortCode", mock.Anything, "missing").Return((*URL)(nil), ErrShortCodeNotFound)
+
+	// Act
+	_, err := service.GetLongURL(context.Background(), "missing")
+
+	// Assert
+	assert.Error(t, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, constant.ErrShortCodeNotFound, spans[0].Status().Description)
+}
+
+func TestService_CreateShortURL_Span(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service, recorder := newTracedService(mockRepo)
+
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	_, err := service.CreateShortURL(context.Background(), 0, "https://example.com", "custom")
+
+	// Assert
+	assert.NoError(t, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	attrs := spanAttributes(spans[0])
+	assert.Equal(t, "custom", attrs[constant.AttrShortCode].AsString())
+	assert.True(t, attrs[constant.AttrCustomShort].AsBool())
+	assert.False(t, attrs[constant.AttrProtected].AsBool())
+}
@@ -0,0 +1,232 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role controls what a user may do
+type Role string
+
+// User roles
+const (
+	// RoleAdmin may manage users and every URL
+	RoleAdmin Role = "admin"
+	// RoleUser may only modify the URLs it owns
+	RoleUser Role = "user"
+)
+
+// User represents an account that owns short URLs
+type User struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	// PasswordHash is the bcrypt hash of the user's password
+	PasswordHash string    `json:"-"`
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// roleRanks orders roles so that a higher rank grants everything a lower one does
+var roleRanks = map[Role]int{
+	RoleUser:  1,
+	RoleAdmin: 2,
+}
+
+// Satisfies reports whether r grants at least the access of required
+func (r Role) Satisfies(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// userContextKey is the context key carrying the authenticated user
+type userContextKey struct{}
+
+// WithUser attaches the authenticated user to ctx so that ownership is enforced on changes
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the user attached by WithUser, if any
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*User)
+	return user, ok && user != nil
+}
+
+// UserIDFromContext returns the ID of the user attached by WithUser, or zero when there is none
+func UserIDFromContext(ctx context.Context) uint {
+	if user, ok := UserFromContext(ctx); ok {
+		return user.ID
+	}
+	return 0
+}
+
+// authorizeOwner rejects changes to url by a non-admin user in ctx who does not own it,
+// and by an anonymous caller whose owner token does not match url's.
+// Calls without a user in ctx are internal and always allowed.
+func (s *Service) authorizeOwner(ctx context.Context, url *URL) error {
+	if token, anonymous := OwnerTokenFromContext(ctx); anonymous {
+		return s.checkOwnerToken(ctx, url, token)
+	}
+
+	user, ok := UserFromContext(ctx)
+	if !ok || user.IsAdmin() || url.OwnerID == user.ID {
+		return nil
+	}
+
+	logger.CtxWarn(ctx, "User does not own URL", logger.LoggerInfo{
+		ContextFunction: constant.CtxDomain,
+		Error: &logger.CustomError{
+			Code:    constant.ErrCodeForbidden,
+			Message: constant.ErrForbidden,
+			Type:    constant.ErrTypeValidation,
+		},
+		Data: map[string]interface{}{
+			constant.DataShortCode: url.ShortCode,
+			constant.DataUsername:  user.Username,
+		},
+	})
+	return ErrForbidden
+}
+
+// createUser implements CreateUser
+func (s *Service) createUser(ctx context.Context, username, password string, role Role) (*User, error) {
+	logger.CtxDebug(ctx, "Creating user", logger.LoggerInfo{
+		ContextFunction: constant.CtxCreateUser,
+		Data: map[string]interface{}{
+			constant.DataUsername: username,
+			constant.DataRole:     role,
+		},
+	})
+
+	var validationErr error
+	switch {
+	case username == "":
+		validationErr = ErrEmptyUsername
+	case password == "":
+		validationErr = ErrEmptyUserPassword
+	case role != RoleAdmin && role != RoleUser:
+		validationErr = ErrInvalidRole
+	}
+	if validationErr != nil {
+		logger.CtxWarn(ctx, "Invalid user", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateUser,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidUser,
+				Message: validationErr.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+		return nil, validationErr
+	}
+
+	if _, err := s.repo.FindUserByUsername(ctx, username); err == nil {
+		logger.CtxWarn(ctx, "Username already exists", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateUser,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeUserExists,
+				Message: constant.ErrUserExists,
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataUsername: username,
+			},
+		})
+		return nil, ErrUserExists
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to hash user password", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateUser,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodePasswordHash,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+		})
+		return nil, err
+	}
+
+	user := &User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         role,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		logger.CtxError(ctx, "Failed to store user", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateUser,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeCreateUser,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+			Data: map[string]interface{}{
+				constant.DataUsername: username,
+			},
+		})
+		return nil, err
+	}
+
+	logger.CtxInfo(ctx, "User created", logger.LoggerInfo{
+		ContextFunction: constant.CtxCreateUser,
+		Data: map[string]interface{}{
+			constant.DataUsername: username,
+			constant.DataRole:     role,
+		},
+	})
+
+	return user, nil
+}
+
+// listUsers implements ListUsers
+func (s *Service) listUsers(ctx context.Context) ([]User, error) {
+	users, err := s.repo.ListUsers(ctx)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to list users", logger.LoggerInfo{
+			ContextFunction: constant.CtxFindUser,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeListUsers,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+		})
+		return nil, err
+	}
+	return users, nil
+}
+
+// authenticate implements Authenticate
+func (s *Service) authenticate(ctx context.Context, username, password string) (*User, error) {
+	user, err := s.repo.FindUserByUsername(ctx, username)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	}
+	if err != nil {
+		logger.CtxWarn(ctx, "Authentication failed", logger.LoggerInfo{
+			ContextFunction: constant.CtxAuthenticate,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidCredentials,
+				Message: err.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataUsername: username,
+			},
+		})
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
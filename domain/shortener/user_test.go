@@ -0,0 +1,197 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestService_CreateUser(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+	mockRepo.On("FindUserByUsername", mock.Anything, "alice").Return((*User)(nil), ErrUserNotFound)
+	mockRepo.On("CreateUser", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*User).ID = 7
+	}).Return(nil)
+
+	// Act
+	user, err := service.CreateUser(context.Background(), "alice", "s3cret-pass", RoleUser)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), user.ID)
+	assert.Equal(t, RoleUser, user.Role)
+	assert.NotEqual(t, "s3cret-pass", user.PasswordHash)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("s3cret-pass")))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateUser_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		role     Role
+		err      string
+	}{
+		{name: "Empty username", username: "", password: "pass", role: RoleUser, err: constant.ErrEmptyUsername},
+		{name: "Empty password", username: "alice", password: "", role: RoleUser, err: constant.ErrEmptyUserPassword},
+		{name: "Unknown role", username: "alice", password: "pass", role: "owner", err: constant.ErrInvalidRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+			// Act
+			user, err := service.CreateUser(context.Background(), tt.username, tt.password, tt.role)
+
+			// Assert
+			assert.Nil(t, user)
+			assert.EqualError(t, err, tt.err)
+			mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateUser_Exists(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindUserByUsername", mock.Anything, "alice").Return(&User{ID: 1, Username: "alice"}, nil)
+
+	// Act
+	user, err := service.CreateUser(context.Background(), "alice", "pass", RoleUser)
+
+	// Assert
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, ErrUserExists)
+	mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+func TestService_Authenticate(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	mockRepo.On("FindUserByUsername", mock.Anything, "alice").Return(&User{ID: 7, Username: "alice", PasswordHash: string(hash), Role: RoleUser}, nil)
+	mockRepo.On("FindUserByUsername", mock.Anything, "bob").Return((*User)(nil), ErrUserNotFound)
+
+	// Act
+	user, err := service.Authenticate(context.Background(), "alice", "s3cret-pass")
+	_, wrongPassErr := service.Authenticate(context.Background(), "alice", "guess")
+	_, unknownErr := service.Authenticate(context.Background(), "bob", "s3cret-pass")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), user.ID)
+	assert.ErrorIs(t, wrongPassErr, ErrInvalidCredentials)
+	assert.ErrorIs(t, unknownErr, ErrInvalidCredentials)
+}
+
+func TestService_UpdateLongURL_Ownership(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    *User
+		wantErr string
+	}{
+		{name: "Owner", user: &User{ID: 7, Role: RoleUser}},
+		{name: "Other user", user: &User{ID: 8, Role: RoleUser}, wantErr: constant.ErrForbidden},
+		{name: "Admin", user: &User{ID: 8, Role: RoleAdmin}},
+		{name: "Internal call", user: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = WithUser(ctx, tt.user)
+			}
+
+			mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com", OwnerID: 7}, nil)
+			mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("UpdateLongURL", mock.Anything, "abc123", "https://example.com/new").Return(nil)
+
+			// Act
+			_, err := service.UpdateLongURL(ctx, "abc123", "https://example.com/new")
+
+			// Assert
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "UpdateLongURL", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestService_DeleteURL_Ownership(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", OwnerID: 7}, nil)
+	mockRepo.On("Delete", mock.Anything, "abc123").Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	forbiddenErr := service.DeleteURL(WithUser(context.Background(), &User{ID: 8, Role: RoleUser}), "abc123")
+	ownerErr := service.DeleteURL(WithUser(context.Background(), &User{ID: 7, Role: RoleUser}), "abc123")
+
+	// Assert
+	assert.ErrorIs(t, forbiddenErr, ErrForbidden)
+	assert.NoError(t, ownerErr)
+	mockRepo.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestService_CreateShortURL_SetsOwner(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+	mockRepo.On("FindByLongURL", mock.Anything, "https://example.com").Return(&URL{ShortCode: "theirs", LongURL: "https://example.com", OwnerID: 8}, nil)
+	mockRepo.On("FindByShortCode", mock.Anything, mock.Anything).Return((*URL)(nil), ErrShortCodeNotFound)
+	mockRepo.On("Store", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("AppendAudit", mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	url, err := service.CreateShortURL(context.Background(), 7, "https://example.com", "")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), url.OwnerID)
+	assert.NotEqual(t, "theirs", url.ShortCode, "another user's URL must not be reused")
+}
+
+func TestRole_Satisfies(t *testing.T) {
+	tests := []struct {
+		role     Role
+		required Role
+		want     bool
+	}{
+		{role: RoleAdmin, required: RoleAdmin, want: true},
+		{role: RoleAdmin, required: RoleUser, want: true},
+		{role: RoleUser, required: RoleUser, want: true},
+		{role: RoleUser, required: RoleAdmin, want: false},
+		{role: "", required: RoleUser, want: false},
+		{role: "owner", required: RoleUser, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.required), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.role.Satisfies(tt.required))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package shortener
+
+import (
+	"context"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// warmCache implements WarmCache
+func (s *Service) warmCache(ctx context.Context, limit int) (int, error) {
+	if limit < 1 {
+		logger.CtxWarn(ctx, "Invalid cache warm limit", logger.LoggerInfo{
+			ContextFunction: constant.CtxWarmCache,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidSearch,
+				Message: ErrInvalidSearchLimit.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+		})
+		return 0, ErrInvalidSearchLimit
+	}
+
+	urls, err := s.repo.FindTopURLs(ctx, limit)
+	if err != nil {
+		logger.CtxError(ctx, "Failed to list URLs to warm the cache with", logger.LoggerInfo{
+			ContextFunction: constant.CtxWarmCache,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeCacheWarm,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataLimit: limit,
+			},
+		})
+		return 0, err
+	}
+
+	// Most visited first, so the busiest URLs are the last to be evicted
+	var expiresAt time.Time
+	if s.opts.CacheTTL > 0 {
+		expiresAt = time.Now().Add(s.opts.CacheTTL)
+	}
+	entries := make([]cache.CacheEntry, 0, len(urls))
+	for _, url := range urls {
+		entries = append(entries, cache.CacheEntry{Key: url.ShortCode, Value: url, ExpiresAt: expiresAt})
+	}
+	if err := s.cache.Warm(ctx, constant.ShortURLNamespace, entries); err != nil {
+		logger.CtxWarn(ctx, "Cache warming stopped early", logger.LoggerInfo{
+			ContextFunction: constant.CtxWarmCache,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeCacheWarm,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+		})
+		return 0, err
+	}
+
+	logger.CtxInfo(ctx, "Cache warmed with most visited URLs", logger.LoggerInfo{
+		ContextFunction: constant.CtxWarmCache,
+		Data: map[string]interface{}{
+			constant.DataCount: len(entries),
+		},
+	})
+	return len(entries), nil
+}
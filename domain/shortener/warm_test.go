@@ -0,0 +1,77 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_WarmCache(t *testing.T) {
+	top := []*URL{
+		{ShortCode: "busiest", LongURL: "https://example.com/a", Visits: 100},
+		{ShortCode: "busy", LongURL: "https://example.com/b", Visits: 10},
+	}
+
+	tests := []struct {
+		name          string
+		limit         int
+		urls          []*URL
+		repoErr       error
+		expectedCount int
+		expectedKeys  []string
+		expectedError error
+	}{
+		{name: "Most visited first", limit: 2, urls: top, expectedCount: 2, expectedKeys: []string{"busiest", "busy"}},
+		{name: "Nothing to warm", limit: 2, urls: []*URL{}, expectedKeys: []string{}},
+		{name: "Invalid limit", limit: 0, expectedKeys: []string{}, expectedError: ErrInvalidSearchLimit},
+		{name: "Repository failure", limit: 2, urls: []*URL{}, repoErr: errors.New("database is closed"), expectedKeys: []string{}, expectedError: errors.New("database is closed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			urlCache := cache.NewNamespaceLRU(10)
+			service := NewService(mockRepo, urlCache, ServiceOptions{CacheTTL: time.Hour})
+			mockRepo.On("FindTopURLs", mock.Anything, tt.limit).Return(tt.urls, tt.repoErr).Maybe()
+
+			// Act
+			count, err := service.WarmCache(context.Background(), tt.limit)
+
+			// Assert
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCount, count)
+			assert.Equal(t, tt.expectedKeys, urlCache.Keys(constant.ShortURLNamespace))
+		})
+	}
+}
+
+func TestService_WarmCache_ServesFromCache(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(10), ServiceOptions{})
+	mockRepo.On("FindTopURLs", mock.Anything, 1).Return([]*URL{{ShortCode: "abc123", LongURL: "https://example.com"}}, nil)
+	mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+	_, err := service.WarmCache(context.Background(), 1)
+	assert.NoError(t, err)
+
+	// Act
+	url, err := service.GetLongURL(context.Background(), "abc123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.LongURL)
+	mockRepo.AssertNotCalled(t, "FindByShortCode", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,209 @@
+package shortener
+
+import (
+	"context"
+	neturl "net/url"
+	"sync"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/events"
+	"github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// Webhook events
+const (
+	// WebhookEventVisit fires every time a short URL is followed
+	WebhookEventVisit = "visit"
+)
+
+// validWebhookEvents lists the events a webhook may subscribe to
+var validWebhookEvents = map[string]bool{
+	WebhookEventVisit: true,
+}
+
+// Webhook is an HTTP endpoint notified about events on a short URL
+type Webhook struct {
+	ID        uint   `json:"id"`
+	URL       string `json:"url"`
+	ShortCode string `json:"short_code"`
+	// Secret is the HMAC-SHA256 key used to sign deliveries
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	OwnerID   uint      `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook wants to be notified about event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the JSON body delivered to a webhook
+type WebhookPayload struct {
+	Event      string    `json:"event"`
+	ShortCode  string    `json:"short_code"`
+	LongURL    string    `json:"long_url"`
+	Referer    string    `json:"referer,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// WebhookSender delivers a payload to a webhook endpoint
+type WebhookSender interface {
+	Send(ctx context.Context, hook Webhook, payload WebhookPayload) error
+}
+
+// createWebhook implements CreateWebhook
+func (s *Service) createWebhook(ctx context.Context, hook *Webhook) (*Webhook, error) {
+	logger.CtxDebug(ctx, "Creating webhook", logger.LoggerInfo{
+		ContextFunction: constant.CtxCreateWebhook,
+		Data: map[string]interface{}{
+			constant.DataShortCode:  hook.ShortCode,
+			constant.DataWebhookURL: hook.URL,
+		},
+	})
+
+	if len(hook.Events) == 0 {
+		hook.Events = []string{WebhookEventVisit}
+	}
+
+	if validationErr := validateWebhook(hook); validationErr != nil {
+		logger.CtxWarn(ctx, "Invalid webhook", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateWebhook,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeInvalidWebhook,
+				Message: validationErr.Error(),
+				Type:    constant.ErrTypeValidation,
+			},
+			Data: map[string]interface{}{
+				constant.DataWebhookURL: hook.URL,
+			},
+		})
+		return nil, validationErr
+	}
+
+	if s.opts.SSRFGuard != nil {
+		if private, err := s.opts.SSRFGuard.IsPrivateURL(hook.URL); err != nil || private {
+			logger.CtxWarn(ctx, "Webhook URL points to a private address", logger.LoggerInfo{
+				ContextFunction: constant.CtxCreateWebhook,
+				Error: &logger.CustomError{
+					Code:    constant.ErrCodeSSRFBlocked,
+					Message: constant.ErrSSRFBlocked,
+					Type:    constant.ErrTypeValidation,
+				},
+				Data: map[string]interface{}{
+					constant.DataWebhookURL: hook.URL,
+				},
+			})
+			return nil, ErrSSRFBlocked
+		}
+	}
+
+	target, err := s.LookupURL(ctx, hook.ShortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeOwner(ctx, target); err != nil {
+		return nil, err
+	}
+
+	// Webhooks are matched against the stored code when events are dispatched
+	hook.ShortCode = target.ShortCode
+	hook.OwnerID = UserIDFromContext(ctx)
+	hook.CreatedAt = time.Now()
+	if err := s.repo.CreateWebhook(ctx, hook); err != nil {
+		logger.CtxError(ctx, "Failed to store webhook", logger.LoggerInfo{
+			ContextFunction: constant.CtxCreateWebhook,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeCreateWebhook,
+				Message: err.Error(),
+				Type:    constant.ErrTypeStorage,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: hook.ShortCode,
+			},
+		})
+		return nil, err
+	}
+
+	logger.CtxInfo(ctx, "Webhook created", logger.LoggerInfo{
+		ContextFunction: constant.CtxCreateWebhook,
+		Data: map[string]interface{}{
+			constant.DataShortCode:  hook.ShortCode,
+			constant.DataWebhookURL: hook.URL,
+		},
+	})
+
+	return hook, nil
+}
+
+// validateWebhook returns the validation error for hook, or nil when it is valid
+func validateWebhook(hook *Webhook) error {
+	parsed, err := neturl.Parse(hook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if hook.ShortCode == "" {
+		return ErrEmptyShortCode
+	}
+	if hook.Secret == "" {
+		return ErrEmptyWebhookSecret
+	}
+	for _, event := range hook.Events {
+		if !validWebhookEvents[event] {
+			return ErrInvalidWebhookEvent
+		}
+	}
+	return nil
+}
+
+// notifyWebhooks delivers a URLAccessedEvent to every webhook subscribed to visits
+func (s *Service) notifyWebhooks(ctx context.Context, event events.Event) {
+	accessed, ok := event.(events.URLAccessedEvent)
+	if !ok {
+		return
+	}
+
+	payload := WebhookPayload{
+		Event:      WebhookEventVisit,
+		ShortCode:  accessed.ShortCode,
+		LongURL:    accessed.LongURL,
+		Referer:    accessed.Referer,
+		OccurredAt: accessed.OccurredAt,
+	}
+
+	hooks, err := s.repo.FindWebhooks(ctx, accessed.ShortCode)
+	if err != nil {
+		logger.CtxWarn(ctx, "Failed to look up webhooks", logger.LoggerInfo{
+			ContextFunction: constant.CtxWebhookDelivery,
+			Error: &logger.CustomError{
+				Code:    constant.ErrCodeFindWebhooks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeRetrieval,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: accessed.ShortCode,
+			},
+		})
+		return
+	}
+
+	// Deliver in parallel, but return only when done so the event bus can wait for deliveries on shutdown
+	var deliveries sync.WaitGroup
+	for _, hook := range hooks {
+		if !hook.Subscribes(WebhookEventVisit) {
+			continue
+		}
+		deliveries.Add(1)
+		go func(hook Webhook) {
+			defer deliveries.Done()
+			s.opts.Webhooks.Send(ctx, hook, payload)
+		}(hook)
+	}
+	deliveries.Wait()
+}
@@ -0,0 +1,129 @@
+package shortener
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// recordingSender captures webhook deliveries
+type recordingSender struct {
+	mu        sync.Mutex
+	delivered []Webhook
+	payloads  []WebhookPayload
+}
+
+func (r *recordingSender) Send(ctx context.Context, hook Webhook, payload WebhookPayload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delivered = append(r.delivered, hook)
+	r.payloads = append(r.payloads, payload)
+	return nil
+}
+
+func (r *recordingSender) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.delivered)
+}
+
+func TestService_CreateWebhook_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		hook Webhook
+		err  string
+	}{
+		{name: "Missing URL", hook: Webhook{ShortCode: "abc123", Secret: "s"}, err: constant.ErrInvalidWebhookURL},
+		{name: "Relative URL", hook: Webhook{URL: "/hook", ShortCode: "abc123", Secret: "s"}, err: constant.ErrInvalidWebhookURL},
+		{name: "Unsupported scheme", hook: Webhook{URL: "ftp://example.com/hook", ShortCode: "abc123", Secret: "s"}, err: constant.ErrInvalidWebhookURL},
+		{name: "Missing short code", hook: Webhook{URL: "https://example.com/hook", Secret: "s"}, err: constant.ErrEmptyShortCode},
+		{name: "Missing secret", hook: Webhook{URL: "https://example.com/hook", ShortCode: "abc123"}, err: constant.ErrEmptyWebhookSecret},
+		{name: "Unknown event", hook: Webhook{URL: "https://example.com/hook", ShortCode: "abc123", Secret: "s", Events: []string{"create"}}, err: constant.ErrInvalidWebhookEvent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{})
+
+			// Act
+			hook, err := service.CreateWebhook(context.Background(), &tt.hook)
+
+			// Assert
+			assert.Nil(t, hook)
+			assert.EqualError(t, err, tt.err)
+			mockRepo.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateWebhook(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{SSRFGuard: stubSSRFGuard{}})
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", OwnerID: 7}, nil)
+	mockRepo.On("CreateWebhook", mock.Anything, mock.Anything).Return(nil)
+	ctx := WithUser(context.Background(), &User{ID: 7, Role: RoleUser})
+
+	// Act
+	hook, err := service.CreateWebhook(ctx, &Webhook{URL: "https://example.com/hook", ShortCode: "ABC123", Secret: "s"})
+	_, otherErr := service.CreateWebhook(WithUser(context.Background(), &User{ID: 8, Role: RoleUser}),
+		&Webhook{URL: "https://example.com/hook", ShortCode: "abc123", Secret: "s"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{WebhookEventVisit}, hook.Events)
+	assert.Equal(t, uint(7), hook.OwnerID)
+	assert.Equal(t, "abc123", hook.ShortCode)
+	assert.ErrorIs(t, otherErr, ErrForbidden)
+	mockRepo.AssertNumberOfCalls(t, "CreateWebhook", 1)
+}
+
+func TestService_CreateWebhook_PrivateURL(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{SSRFGuard: stubSSRFGuard{private: true}})
+
+	// Act
+	hook, err := service.CreateWebhook(context.Background(), &Webhook{URL: "http://127.0.0.1/hook", ShortCode: "abc123", Secret: "s"})
+
+	// Assert
+	assert.Nil(t, hook)
+	assert.ErrorIs(t, err, ErrSSRFBlocked)
+}
+
+func TestService_GetLongURL_NotifiesWebhooks(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	sender := &recordingSender{}
+	service := NewService(mockRepo, cache.NewNamespaceLRU(100), ServiceOptions{Webhooks: sender})
+
+	mockRepo.On("FindByShortCode", mock.Anything, "abc123").Return(&URL{ShortCode: "abc123", LongURL: "https://example.com"}, nil)
+	mockRepo.On("IncrementVisits", mock.Anything, "abc123").Return(nil)
+	mockRepo.On("UpdateLastAccessed", mock.Anything, "abc123", mock.Anything).Return(nil)
+	mockRepo.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("FindWebhooks", mock.Anything, "abc123").Return([]Webhook{
+		{ID: 1, URL: "https://example.com/a", Events: []string{WebhookEventVisit}},
+		{ID: 2, URL: "https://example.com/b", Events: []string{"other"}},
+	}, nil)
+
+	// Act
+	_, err := service.GetLongURL(WithReferer(context.Background(), "https://news.example.com"), "abc123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return sender.count() == 1 }, time.Second, 5*time.Millisecond)
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	assert.Equal(t, uint(1), sender.delivered[0].ID)
+	assert.Equal(t, WebhookEventVisit, sender.payloads[0].Event)
+	assert.Equal(t, "https://example.com", sender.payloads[0].LongURL)
+	assert.Equal(t, "https://news.example.com", sender.payloads[0].Referer)
+}
@@ -0,0 +1,65 @@
+package blacklist
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// FileBlacklist blocks hosts listed in a newline-separated domain file.
+// Entries of the form *.example.com block every subdomain of example.com.
+type FileBlacklist struct {
+	exact     map[string]struct{}
+	wildcards []string
+}
+
+// NewFileBlacklist loads a blacklist from path, ignoring blank lines and # comments
+func NewFileBlacklist(path string) (*FileBlacklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	b := &FileBlacklist{exact: make(map[string]struct{})}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		b.add(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// add registers a single blacklist entry
+func (b *FileBlacklist) add(entry string) {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if entry == "" || strings.HasPrefix(entry, "#") {
+		return
+	}
+
+	if strings.HasPrefix(entry, "*.") {
+		// Keep the leading dot so that *.evil.com does not match notevil.com
+		b.wildcards = append(b.wildcards, entry[1:])
+		return
+	}
+	b.exact[entry] = struct{}{}
+}
+
+// IsBlocked reports whether host matches an exact or wildcard entry
+func (b *FileBlacklist) IsBlocked(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if _, found := b.exact[host]; found {
+		return true
+	}
+	for _, suffix := range b.wildcards {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package blacklist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeBlacklist writes content to a temporary blacklist file and returns its path
+func writeBlacklist(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "blacklist.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write blacklist file: %v", err)
+	}
+	return path
+}
+
+func TestFileBlacklist_IsBlocked(t *testing.T) {
+	// Arrange
+	path := writeBlacklist(t, "# phishing domains\nphish.example\n\n*.evil.com\n  Spam.Example  \n")
+	b, err := NewFileBlacklist(path)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		host    string
+		blocked bool
+	}{
+		{name: "Exact match", host: "phish.example", blocked: true},
+		{name: "Exact match is case-insensitive", host: "SPAM.example", blocked: true},
+		{name: "Wildcard subdomain", host: "login.evil.com", blocked: true},
+		{name: "Wildcard nested subdomain", host: "a.b.evil.com", blocked: true},
+		{name: "Wildcard does not match apex", host: "evil.com", blocked: false},
+		{name: "Wildcard does not match suffix lookalike", host: "notevil.com", blocked: false},
+		{name: "Exact does not match subdomain", host: "www.phish.example", blocked: false},
+		{name: "Not blocked", host: "example.com", blocked: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.blocked, b.IsBlocked(tt.host))
+		})
+	}
+}
+
+func TestNewFileBlacklist_MissingFile(t *testing.T) {
+	// Act
+	b, err := NewFileBlacklist(filepath.Join(t.TempDir(), "missing.txt"))
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, b)
+}
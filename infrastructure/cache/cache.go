@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache is a namespaced key-value cache used to avoid repeated lookups
+type Cache interface {
+	// Get returns the value for key in namespace and whether it was found
+	Get(namespace, key string) (interface{}, bool)
+	// Set adds or updates a value that does not expire
+	Set(namespace, key string, value interface{})
+	// SetWithTTL adds or updates a value that expires after ttl
+	SetWithTTL(namespace, key string, value interface{}, ttl time.Duration)
+	// Invalidate removes key from namespace
+	Invalidate(namespace, key string)
+	// InvalidateNamespace removes every key in namespace
+	InvalidateNamespace(namespace string)
+	// Keys returns a snapshot of the keys in namespace, for inspection and tests
+	Keys(namespace string) []string
+	// Entries returns a snapshot of the values in namespace keyed by key
+	Entries(namespace string) map[string]interface{}
+	// Drain removes every entry in namespace and returns the unexpired ones, the
+	// last to be evicted first, so they can be persisted or moved to another cache
+	Drain(namespace string) []CacheEntry
+	// Warm adds entries to namespace, the first ending up the last to be evicted as
+	// after Drain. Expired entries are skipped; it stops early when ctx is done.
+	Warm(ctx context.Context, namespace string, entries []CacheEntry) error
+	// Clear removes every entry
+	Clear()
+	// Size returns the number of entries
+	Size() int
+	// Stats returns a snapshot of the hit, miss and eviction counters
+	Stats() CacheStats
+	// Ping reports whether the cache backend is reachable
+	Ping() error
+}
+
+// CacheEntry is a cached value and its key, as taken out of a cache by Drain and
+// loaded by Warm
+type CacheEntry struct {
+	Key   string
+	Value interface{}
+	// ExpiresAt is the zero time for entries that never expire
+	ExpiresAt time.Time
+}
+
+// Eviction policies accepted by New
+const (
+	// EvictionLRU evicts the least recently used entry
+	EvictionLRU = "lru"
+	// EvictionLFU evicts the least frequently used entry
+	EvictionLFU = "lfu"
+)
+
+var (
+	_ PurgingCache = (*NamespaceLRU)(nil)
+	_ PurgingCache = (*NamespaceLFU)(nil)
+	_ Cache        = NoopCache{}
+)
+
+// PurgingCache is an in-memory Cache that can remove expired entries in the background
+type PurgingCache interface {
+	Cache
+	// StartPurge removes expired entries every interval until the returned stop
+	// function is called
+	StartPurge(interval time.Duration) (stop func())
+}
+
+// Options configures the cache created by New
+type Options struct {
+	// Capacity is the maximum number of entries
+	Capacity int
+	// EvictionPolicy picks the entry to evict when the cache is full, EvictionLRU
+	// when empty
+	EvictionPolicy string
+}
+
+// New creates an in-memory cache evicting entries with the policy in opts
+func New(opts Options) (PurgingCache, error) {
+	switch opts.EvictionPolicy {
+	case "", EvictionLRU:
+		return NewNamespaceLRU(opts.Capacity), nil
+	case EvictionLFU:
+		return NewNamespaceLFU(opts.Capacity), nil
+	default:
+		return nil, fmt.Errorf("unknown cache eviction policy %q", opts.EvictionPolicy)
+	}
+}
+
+// startPurge calls purge every interval in a background goroutine until the
+// returned stop function is called
+func startPurge(interval time.Duration, purge func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				purge()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// NoopCache is a Cache that stores nothing, so every Get is a miss
+type NoopCache struct{}
+
+// NewNoopCache creates a cache that stores nothing, for tests and for running without a cache
+func NewNoopCache() NoopCache {
+	return NoopCache{}
+}
+
+// Get always reports a miss
+func (NoopCache) Get(namespace, key string) (interface{}, bool) {
+	return nil, false
+}
+
+// Set discards the value
+func (NoopCache) Set(namespace, key string, value interface{}) {}
+
+// SetWithTTL discards the value
+func (NoopCache) SetWithTTL(namespace, key string, value interface{}, ttl time.Duration) {}
+
+// Invalidate does nothing
+func (NoopCache) Invalidate(namespace, key string) {}
+
+// InvalidateNamespace does nothing
+func (NoopCache) InvalidateNamespace(namespace string) {}
+
+// Keys always returns no keys
+func (NoopCache) Keys(namespace string) []string {
+	return []string{}
+}
+
+// Entries always returns no entries
+func (NoopCache) Entries(namespace string) map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// Drain always returns no entries
+func (NoopCache) Drain(namespace string) []CacheEntry {
+	return []CacheEntry{}
+}
+
+// Warm discards the entries
+func (NoopCache) Warm(ctx context.Context, namespace string, entries []CacheEntry) error {
+	return nil
+}
+
+// Clear does nothing
+func (NoopCache) Clear() {}
+
+// Size always returns zero
+func (NoopCache) Size() int {
+	return 0
+}
+
+// Stats always returns zero counters and no namespaces
+func (NoopCache) Stats() CacheStats {
+	return CacheStats{Namespaces: map[string]int{}}
+}
+
+// Ping always succeeds
+func (NoopCache) Ping() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoopCache(t *testing.T) {
+	// Arrange
+	var c Cache = NewNoopCache()
+
+	// Act
+	c.Set("ns", "key", "value")
+	c.SetWithTTL("ns", "ttl", "value", time.Minute)
+	_, found := c.Get("ns", "key")
+
+	// Assert
+	assert.False(t, found)
+	assert.Equal(t, 0, c.Size())
+	assert.Equal(t, CacheStats{Namespaces: map[string]int{}}, c.Stats())
+	assert.Empty(t, c.Keys("ns"))
+	assert.Empty(t, c.Entries("ns"))
+	assert.NoError(t, c.Warm(context.Background(), "ns", []CacheEntry{{Key: "key", Value: "value"}}))
+	assert.Empty(t, c.Drain("ns"))
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		expected    PurgingCache
+		expectedErr string
+	}{
+		{name: "Unset", policy: "", expected: &NamespaceLRU{}},
+		{name: "LRU", policy: EvictionLRU, expected: &NamespaceLRU{}},
+		{name: "LFU", policy: EvictionLFU, expected: &NamespaceLFU{}},
+		{name: "Unknown", policy: "fifo", expectedErr: `unknown cache eviction policy "fifo"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			c, err := New(Options{Capacity: 10, EvictionPolicy: tt.policy})
+
+			// Assert
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.IsType(t, tt.expected, c)
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NamespaceLFU is a namespace-based cache evicting the least frequently used entry,
+// and among those the least recently used. Unlike NamespaceLRU, a burst of one-off
+// lookups cannot push out entries that are used again and again.
+type NamespaceLFU struct {
+	capacity int
+	items    map[string]*lfuEntry
+	// freqMap holds the entries used each number of times, most recently used first
+	freqMap map[int]*list.List
+	// minFreq is the lowest frequency in freqMap; it may be stale after a removal
+	// until the next eviction looks it up again
+	minFreq int
+	mutex   sync.Mutex
+	// now is the clock used for expiry and entry ages
+	now func() time.Time
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+type lfuEntry struct {
+	entry
+	// frequency counts the sets and hits of the entry
+	frequency int
+	// element is the entry's element in freqMap[frequency]
+	element *list.Element
+}
+
+// NewNamespaceLFU creates a new namespace-based LFU cache with specified capacity
+func NewNamespaceLFU(capacity int) *NamespaceLFU {
+	return &NamespaceLFU{
+		capacity: capacity,
+		items:    make(map[string]*lfuEntry),
+		freqMap:  make(map[int]*list.List),
+		now:      time.Now,
+	}
+}
+
+// Set adds or updates a key-value pair in the cache with a namespace.
+// New entries never expire; updating an existing entry keeps its expiry.
+func (c *NamespaceLFU) Set(namespace, key string, value interface{}) {
+	c.set(namespace, key, value, nil)
+}
+
+// SetWithTTL adds or updates a key-value pair that expires after ttl
+func (c *NamespaceLFU) SetWithTTL(namespace, key string, value interface{}, ttl time.Duration) {
+	expiresAt := c.now().Add(ttl)
+	c.set(namespace, key, value, &expiresAt)
+}
+
+// set stores value, replacing the entry's expiry when expiresAt is non-nil. Updating
+// an entry counts as a use of it.
+func (c *NamespaceLFU) set(namespace, key string, value interface{}, expiresAt *time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, exists := c.items[namespace+":"+key]; exists {
+		e.value = value
+		e.storedAt = c.now()
+		if expiresAt != nil {
+			e.expiresAt = *expiresAt
+		}
+		c.touch(e)
+		return
+	}
+
+	if c.capacity <= 0 {
+		return
+	}
+	// Make room first, or the new entry would be the least frequently used
+	if len(c.items) >= c.capacity {
+		c.evict()
+	}
+
+	e := &lfuEntry{
+		entry: entry{
+			namespace: namespace,
+			key:       key,
+			value:     value,
+			storedAt:  c.now(),
+		},
+		frequency: 1,
+	}
+	if expiresAt != nil {
+		e.expiresAt = *expiresAt
+	}
+	e.element = c.frequencyList(1).PushFront(e)
+	c.items[namespace+":"+key] = e
+	c.minFreq = 1
+}
+
+// Get retrieves a value from the cache by namespace and key.
+// Expired entries are removed and reported as misses.
+func (c *NamespaceLFU) Get(namespace, key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, exists := c.items[namespace+":"+key]
+	if !exists {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	if e.expired(c.now()) {
+		c.remove(e)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	c.touch(e)
+	return e.value, true
+}
+
+// Peek retrieves a value like Get but leaves the frequencies and the hit and miss
+// counters untouched, so inspecting the cache does not change what gets evicted.
+// Expired entries are reported as missing but left for Get or the purge to remove.
+func (c *NamespaceLFU) Peek(namespace, key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, exists := c.items[namespace+":"+key]
+	if !exists || e.expired(c.now()) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Keys returns a snapshot of the unexpired keys in namespace, the last to be evicted first
+func (c *NamespaceLFU) Keys(namespace string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	keys := []string{}
+	for _, e := range c.retentionOrder() {
+		if e.namespace == namespace && !e.expired(now) {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+// Entries returns a snapshot of the unexpired entries in namespace keyed by key.
+// The map is the caller's, but the values are shared with the cache.
+func (c *NamespaceLFU) Entries(namespace string) map[string]interface{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	entries := make(map[string]interface{})
+	for _, e := range c.items {
+		if e.namespace == namespace && !e.expired(now) {
+			entries[e.key] = e.value
+		}
+	}
+	return entries
+}
+
+// Drain removes every entry in namespace and returns the unexpired ones, the last
+// to be evicted first. Frequencies are not carried over.
+func (c *NamespaceLFU) Drain(namespace string) []CacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	entries := []CacheEntry{}
+	for _, e := range c.retentionOrder() {
+		if e.namespace != namespace {
+			continue
+		}
+		if !e.expired(now) {
+			entries = append(entries, CacheEntry{Key: e.key, Value: e.value, ExpiresAt: e.expiresAt})
+		}
+		c.remove(e)
+	}
+	return entries
+}
+
+// Warm adds entries to namespace, replacing existing values. They are added from
+// last to first as new entries used once, so the first is the last of them to be
+// evicted and, when entries outnumber the capacity, the last ones are evicted.
+func (c *NamespaceLFU) Warm(ctx context.Context, namespace string, entries []CacheEntry) error {
+	now := c.now()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		e := entries[i]
+		if !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt) {
+			continue
+		}
+		c.set(namespace, e.Key, e.Value, &e.ExpiresAt)
+	}
+	return nil
+}
+
+// Invalidate removes an item from the cache by namespace and key
+func (c *NamespaceLFU) Invalidate(namespace, key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, exists := c.items[namespace+":"+key]; exists {
+		c.remove(e)
+	}
+}
+
+// InvalidateNamespace removes all items from the specified namespace
+func (c *NamespaceLFU) InvalidateNamespace(namespace string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, e := range c.items {
+		if e.namespace == namespace {
+			c.remove(e)
+		}
+	}
+}
+
+// Clear empties the cache
+func (c *NamespaceLFU) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.items = make(map[string]*lfuEntry)
+	c.freqMap = make(map[int]*list.List)
+	c.minFreq = 0
+}
+
+// Size returns the current number of items in the cache
+func (c *NamespaceLFU) Size() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.items)
+}
+
+// Stats returns a snapshot of the hit, miss and eviction counters and of the
+// entries held, expired ones included until they are removed
+func (c *NamespaceLFU) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := CacheStats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Evictions:  c.evictions.Load(),
+		Size:       len(c.items),
+		Namespaces: make(map[string]int),
+	}
+
+	var oldest time.Time
+	for _, e := range c.items {
+		stats.Namespaces[e.namespace]++
+		if oldest.IsZero() || e.storedAt.Before(oldest) {
+			oldest = e.storedAt
+		}
+	}
+	if !oldest.IsZero() {
+		stats.OldestEntryAge = c.now().Sub(oldest)
+	}
+	return stats
+}
+
+// Ping always succeeds because the cache lives in memory
+func (c *NamespaceLFU) Ping() error {
+	return nil
+}
+
+// StartPurge removes expired entries every interval in a background goroutine
+// until the returned stop function is called
+func (c *NamespaceLFU) StartPurge(interval time.Duration) (stop func()) {
+	return startPurge(interval, c.purgeExpired)
+}
+
+// purgeExpired removes every entry whose TTL has elapsed
+func (c *NamespaceLFU) purgeExpired() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	for _, e := range c.items {
+		if e.expired(now) {
+			c.remove(e)
+		}
+	}
+}
+
+// frequencyList returns the list of entries used frequency times, creating it when missing
+func (c *NamespaceLFU) frequencyList(frequency int) *list.List {
+	entries, exists := c.freqMap[frequency]
+	if !exists {
+		entries = list.New()
+		c.freqMap[frequency] = entries
+	}
+	return entries
+}
+
+// touch counts a use of e, moving it to the front of the next frequency's list
+func (c *NamespaceLFU) touch(e *lfuEntry) {
+	c.unlink(e)
+	if e.frequency == c.minFreq && c.freqMap[e.frequency] == nil {
+		c.minFreq++
+	}
+	e.frequency++
+	e.element = c.frequencyList(e.frequency).PushFront(e)
+}
+
+// remove takes e out of the cache
+func (c *NamespaceLFU) remove(e *lfuEntry) {
+	c.unlink(e)
+	delete(c.items, e.namespace+":"+e.key)
+}
+
+// unlink takes e out of its frequency's list, dropping the list once it is empty
+func (c *NamespaceLFU) unlink(e *lfuEntry) {
+	entries := c.freqMap[e.frequency]
+	entries.Remove(e.element)
+	if entries.Len() == 0 {
+		delete(c.freqMap, e.frequency)
+	}
+}
+
+// evict removes the least recently used of the least frequently used entries
+func (c *NamespaceLFU) evict() {
+	if len(c.items) == 0 {
+		return
+	}
+	// Removals other than touch do not move minFreq, so it may name an empty frequency
+	if c.freqMap[c.minFreq] == nil {
+		c.minFreq = 0
+		for frequency := range c.freqMap {
+			if c.minFreq == 0 || frequency < c.minFreq {
+				c.minFreq = frequency
+			}
+		}
+	}
+
+	victim := c.freqMap[c.minFreq].Back().Value.(*lfuEntry)
+	c.remove(victim)
+	c.evictions.Add(1)
+}
+
+// retentionOrder returns the entries from the last to the first to be evicted: the
+// most frequently used first and, among equally used ones, the most recently used first
+func (c *NamespaceLFU) retentionOrder() []*lfuEntry {
+	frequencies := make([]int, 0, len(c.freqMap))
+	for frequency := range c.freqMap {
+		frequencies = append(frequencies, frequency)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(frequencies)))
+
+	entries := make([]*lfuEntry, 0, len(c.items))
+	for _, frequency := range frequencies {
+		for element := c.freqMap[frequency].Front(); element != nil; element = element.Next() {
+			entries = append(entries, element.Value.(*lfuEntry))
+		}
+	}
+	return entries
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceLFU_EvictsLeastFrequentlyUsed(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLFU(3)
+	c.Set("ns", "popular", 1)
+	c.Set("ns", "used", 2)
+	c.Set("ns", "once", 3)
+	c.Get("ns", "popular")
+	c.Get("ns", "popular")
+	c.Get("ns", "used")
+
+	// Act
+	c.Set("ns", "new", 4)
+
+	// Assert
+	_, found := c.Get("ns", "once")
+	assert.False(t, found, "the least frequently used entry is evicted")
+	assert.Equal(t, []string{"popular", "used", "new"}, c.Keys("ns"))
+	assert.Equal(t, int64(1), c.Stats().Evictions)
+}
+
+func TestNamespaceLFU_EvictsLeastRecentlyUsedOnTie(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLFU(3)
+	c.Set("ns", "a", 1)
+	c.Set("ns", "b", 2)
+	c.Set("ns", "c", 3)
+	c.Get("ns", "a")
+	c.Get("ns", "b")
+
+	// Act
+	c.Set("ns", "d", 4) // evicts "c", the only entry used once
+	c.Set("ns", "e", 5) // evicts "d"; "a" and "b" were used twice
+
+	// Assert
+	assert.Equal(t, []string{"b", "a", "e"}, c.Keys("ns"))
+}
+
+func TestNamespaceLFU_SetCountsAsUse(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLFU(2)
+	c.Set("ns", "a", 1)
+	c.Set("ns", "b", 2)
+	c.Set("ns", "a", 10)
+
+	// Act
+	c.Set("ns", "c", 3)
+
+	// Assert
+	value, found := c.Get("ns", "a")
+	assert.True(t, found)
+	assert.Equal(t, 10, value)
+	_, found = c.Get("ns", "b")
+	assert.False(t, found)
+}
+
+func TestNamespaceLFU_Peek_KeepsFrequencies(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLFU(2)
+	c.Set("ns", "a", 1)
+	c.Set("ns", "b", 2)
+	c.Get("ns", "b")
+	for i := 0; i < 3; i++ {
+		c.Peek("ns", "a")
+	}
+
+	// Act
+	c.Set("ns", "c", 3)
+
+	// Assert
+	assert.Equal(t, []string{"b", "c"}, c.Keys("ns"), "peeking does not save an entry from eviction")
+	stats := c.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(0), stats.Misses)
+}
+
+func TestNamespaceLFU_RemovalsKeepEvictionWorking(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(c *NamespaceLFU)
+	}{
+		{name: "Invalidate", remove: func(c *NamespaceLFU) { c.Invalidate("ns", "once") }},
+		{name: "InvalidateNamespace", remove: func(c *NamespaceLFU) { c.InvalidateNamespace("ns") }},
+		{name: "Clear", remove: func(c *NamespaceLFU) { c.Clear() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			c := NewNamespaceLFU(2)
+			c.Set("ns", "once", 1)
+			c.Set("other", "twice", 2)
+			c.Get("other", "twice")
+			tt.remove(c)
+
+			// Act
+			c.Set("ns", "a", 3)
+			c.Set("ns", "b", 4)
+			c.Set("ns", "c", 5)
+
+			// Assert
+			assert.Equal(t, 2, c.Size())
+			_, found := c.Peek("ns", "c")
+			assert.True(t, found, "the newest entry is kept")
+		})
+	}
+}
+
+func TestNamespaceLFU_SetWithTTL_ExpiresOnGet(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	c := NewNamespaceLFU(10)
+	c.now = func() time.Time { return now }
+	c.SetWithTTL("ns", "short", "value", time.Minute)
+	c.Set("ns", "forever", "value")
+
+	// Act
+	now = now.Add(2 * time.Minute)
+	_, shortFound := c.Get("ns", "short")
+	_, foreverFound := c.Get("ns", "forever")
+
+	// Assert
+	assert.False(t, shortFound)
+	assert.True(t, foreverFound)
+	assert.Equal(t, 1, c.Size(), "the expired entry is removed")
+}
+
+func TestNamespaceLFU_PurgeExpired(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	c := NewNamespaceLFU(10)
+	c.now = func() time.Time { return now }
+	c.SetWithTTL("ns", "a", 1, time.Minute)
+	c.SetWithTTL("ns", "b", 2, time.Hour)
+	c.Set("other", "c", 3)
+
+	// Act
+	now = now.Add(2 * time.Minute)
+	c.purgeExpired()
+
+	// Assert
+	assert.Equal(t, 2, c.Size())
+	assert.Equal(t, []string{"b"}, c.Keys("ns"))
+}
+
+func TestNamespaceLFU_DrainWarmRoundTrip(t *testing.T) {
+	// Arrange
+	source := NewNamespaceLFU(10)
+	source.Set("ns", "a", 1)
+	source.Set("ns", "b", 2)
+	source.Set("ns", "c", 3)
+	source.Set("other", "x", 4)
+	source.Get("ns", "a")
+	source.SetWithTTL("ns", "expired", 5, -time.Second)
+	target := NewNamespaceLFU(2)
+
+	// Act
+	drained := source.Drain("ns")
+	err := target.Warm(context.Background(), "ns", drained)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c", "b"}, keysOf(drained), "Drain returns the last to be evicted first")
+	assert.Empty(t, source.Keys("ns"), "Drain removes the namespace, expired entries included")
+	assert.Equal(t, []string{"x"}, source.Keys("other"))
+	assert.Equal(t, []string{"a", "c"}, target.Keys("ns"), "the last entries are evicted when they do not fit")
+}
+
+func TestNamespaceLFU_Stats_Contents(t *testing.T) {
+	// Arrange
+	start := time.Now()
+	now := start
+	c := NewNamespaceLFU(3)
+	c.now = func() time.Time { return now }
+	c.Set("urls", "evicted", 0)
+	now = start.Add(time.Minute)
+	c.Set("urls", "oldest", 1)
+	c.Get("urls", "oldest")  // hit
+	c.Get("urls", "missing") // miss
+	now = start.Add(2 * time.Minute)
+	c.Set("tokens", "a", 2)
+	c.Get("tokens", "a") // hit
+	now = start.Add(3 * time.Minute)
+	c.Set("urls", "newest", 3) // evicts "evicted", the only entry used once
+	now = start.Add(5 * time.Minute)
+
+	// Act
+	stats := c.Stats()
+
+	// Assert
+	assert.Equal(t, CacheStats{
+		Hits:           2,
+		Misses:         1,
+		Evictions:      1,
+		Size:           3,
+		Namespaces:     map[string]int{"urls": 2, "tokens": 1},
+		OldestEntryAge: 4 * time.Minute,
+	}, stats)
+}
+
+// keysOf returns the keys of entries in order
+func keysOf(entries []CacheEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
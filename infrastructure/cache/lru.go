@@ -2,7 +2,10 @@ package cache
 
 import (
 	"container/list"
+	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // NamespaceLRU is a namespace-based LRU cache implementation
@@ -11,12 +14,40 @@ type NamespaceLRU struct {
 	items    map[string]*list.Element
 	queue    *list.List
 	mutex    sync.RWMutex
+	// now is the clock used for expiry and entry ages
+	now func() time.Time
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+// CacheStats is a point-in-time snapshot of cache effectiveness counters and contents
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Size      int   `json:"size"`
+	// Namespaces counts the entries of each namespace, adding up to Size
+	Namespaces map[string]int `json:"namespaces"`
+	// OldestEntryAge is how long ago the entry stored longest ago was set; zero when
+	// the cache is empty
+	OldestEntryAge time.Duration `json:"-"`
 }
 
 type entry struct {
 	namespace string
 	key       string
 	value     interface{}
+	// expiresAt is the zero time for entries that never expire
+	expiresAt time.Time
+	// storedAt is when the value was last set
+	storedAt time.Time
+}
+
+// expired reports whether the entry's TTL has elapsed at now
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
 }
 
 // NewNamespaceLRU creates a new namespace-based LRU cache with specified capacity
@@ -25,11 +56,24 @@ func NewNamespaceLRU(capacity int) *NamespaceLRU {
 		capacity: capacity,
 		items:    make(map[string]*list.Element),
 		queue:    list.New(),
+		now:      time.Now,
 	}
 }
 
-// Set adds or updates a key-value pair in the cache with a namespace
+// Set adds or updates a key-value pair in the cache with a namespace.
+// New entries never expire; updating an existing entry keeps its expiry.
 func (c *NamespaceLRU) Set(namespace, key string, value interface{}) {
+	c.set(namespace, key, value, nil)
+}
+
+// SetWithTTL adds or updates a key-value pair that expires after ttl
+func (c *NamespaceLRU) SetWithTTL(namespace, key string, value interface{}, ttl time.Duration) {
+	expiresAt := c.now().Add(ttl)
+	c.set(namespace, key, value, &expiresAt)
+}
+
+// set stores value, replacing the entry's expiry when expiresAt is non-nil
+func (c *NamespaceLRU) set(namespace, key string, value interface{}, expiresAt *time.Time) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -39,16 +83,27 @@ func (c *NamespaceLRU) Set(namespace, key string, value interface{}) {
 	// Check if key exists
 	if element, exists := c.items[compositeKey]; exists {
 		c.queue.MoveToFront(element)
-		element.Value.(*entry).value = value
+		e := element.Value.(*entry)
+		e.value = value
+		e.storedAt = c.now()
+		if expiresAt != nil {
+			e.expiresAt = *expiresAt
+		}
 		return
 	}
 
-	// Add new item to the front
-	element := c.queue.PushFront(&entry{
+	newEntry := &entry{
 		namespace: namespace,
 		key:       key,
 		value:     value,
-	})
+		storedAt:  c.now(),
+	}
+	if expiresAt != nil {
+		newEntry.expiresAt = *expiresAt
+	}
+
+	// Add new item to the front
+	element := c.queue.PushFront(newEntry)
 	c.items[compositeKey] = element
 
 	// Evict items if over capacity
@@ -57,22 +112,121 @@ func (c *NamespaceLRU) Set(namespace, key string, value interface{}) {
 	}
 }
 
-// Get retrieves a value from the cache by namespace and key
+// Get retrieves a value from the cache by namespace and key.
+// Expired entries are removed and reported as misses.
 func (c *NamespaceLRU) Get(namespace, key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	// Get reorders the queue and may remove entries, so it needs the write lock
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	compositeKey := namespace + ":" + key
 	element, exists := c.items[compositeKey]
 	if !exists {
+		c.misses.Add(1)
 		return nil, false
 	}
 
+	if element.Value.(*entry).expired(c.now()) {
+		c.queue.Remove(element)
+		delete(c.items, compositeKey)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
 	// Move to front (mark as recently used)
 	c.queue.MoveToFront(element)
 	return element.Value.(*entry).value, true
 }
 
+// Peek retrieves a value like Get but leaves the LRU order and the hit and miss
+// counters untouched, so inspecting the cache does not change what gets evicted.
+// Expired entries are reported as missing but left for Get or the purge to remove.
+func (c *NamespaceLRU) Peek(namespace, key string) (interface{}, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	element, exists := c.items[namespace+":"+key]
+	if !exists {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if e.expired(c.now()) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Keys returns a snapshot of the unexpired keys in namespace, most recently used first
+func (c *NamespaceLRU) Keys(namespace string) []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	now := c.now()
+	keys := []string{}
+	for element := c.queue.Front(); element != nil; element = element.Next() {
+		if e := element.Value.(*entry); e.namespace == namespace && !e.expired(now) {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+// Entries returns a snapshot of the unexpired entries in namespace keyed by key.
+// The map is the caller's, but the values are shared with the cache.
+func (c *NamespaceLRU) Entries(namespace string) map[string]interface{} {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	now := c.now()
+	entries := make(map[string]interface{})
+	for _, element := range c.items {
+		if e := element.Value.(*entry); e.namespace == namespace && !e.expired(now) {
+			entries[e.key] = e.value
+		}
+	}
+	return entries
+}
+
+// Drain removes every entry in namespace and returns the unexpired ones, most recently used first
+func (c *NamespaceLRU) Drain(namespace string) []CacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	entries := []CacheEntry{}
+	for element := c.queue.Front(); element != nil; {
+		next := element.Next()
+		if e := element.Value.(*entry); e.namespace == namespace {
+			if !e.expired(now) {
+				entries = append(entries, CacheEntry{Key: e.key, Value: e.value, ExpiresAt: e.expiresAt})
+			}
+			c.queue.Remove(element)
+			delete(c.items, e.namespace+":"+e.key)
+		}
+		element = next
+	}
+	return entries
+}
+
+// Warm adds entries to namespace, replacing existing values. They are added from
+// last to first, so the first is the most recently used and, when entries outnumber
+// the capacity, the last ones are evicted.
+func (c *NamespaceLRU) Warm(ctx context.Context, namespace string, entries []CacheEntry) error {
+	now := c.now()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		e := entries[i]
+		if !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt) {
+			continue
+		}
+		c.set(namespace, e.Key, e.Value, &e.ExpiresAt)
+	}
+	return nil
+}
+
 // Invalidate removes an item from the cache by namespace and key
 func (c *NamespaceLRU) Invalidate(namespace, key string) {
 	c.mutex.Lock()
@@ -126,6 +280,62 @@ func (c *NamespaceLRU) Size() int {
 	return c.queue.Len()
 }
 
+// Stats returns a snapshot of the hit, miss and eviction counters and of the
+// entries held, expired ones included until they are removed
+func (c *NamespaceLRU) Stats() CacheStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	stats := CacheStats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Evictions:  c.evictions.Load(),
+		Size:       c.queue.Len(),
+		Namespaces: make(map[string]int),
+	}
+
+	// The queue is in order of use, not of storage, so every entry is looked at
+	var oldest time.Time
+	for element := c.queue.Front(); element != nil; element = element.Next() {
+		e := element.Value.(*entry)
+		stats.Namespaces[e.namespace]++
+		if oldest.IsZero() || e.storedAt.Before(oldest) {
+			oldest = e.storedAt
+		}
+	}
+	if !oldest.IsZero() {
+		stats.OldestEntryAge = c.now().Sub(oldest)
+	}
+	return stats
+}
+
+// Ping always succeeds because the cache lives in memory
+func (c *NamespaceLRU) Ping() error {
+	return nil
+}
+
+// StartPurge removes expired entries every interval in a background goroutine
+// until the returned stop function is called
+func (c *NamespaceLRU) StartPurge(interval time.Duration) (stop func()) {
+	return startPurge(interval, c.purgeExpired)
+}
+
+// purgeExpired removes every entry whose TTL has elapsed
+func (c *NamespaceLRU) purgeExpired() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	for element := c.queue.Back(); element != nil; {
+		prev := element.Prev()
+		if e := element.Value.(*entry); e.expired(now) {
+			c.queue.Remove(element)
+			delete(c.items, e.namespace+":"+e.key)
+		}
+		element = prev
+	}
+}
+
 // evict removes the least recently used item from the cache
 func (c *NamespaceLRU) evict() {
 	// Get the oldest element (from the back of the queue)
@@ -136,6 +346,7 @@ func (c *NamespaceLRU) evict() {
 
 	// Remove it from the queue
 	c.queue.Remove(element)
+	c.evictions.Add(1)
 
 	// Get the entry and remove it from the map
 	entry := element.Value.(*entry)
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Benchmark sizes: the key space is ten times the capacity so that misses and evictions happen
+const (
+	benchCapacity   = 1000
+	benchKeyCount   = 10000
+	benchGoroutines = 8
+	benchNamespace  = "BENCH"
+)
+
+// benchKeys are the keys every benchmark draws from
+var benchKeys = func() []string {
+	keys := make([]string, benchKeyCount)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	return keys
+}()
+
+// newBenchCache returns a cache filled to capacity with the first benchCapacity keys
+func newBenchCache() *NamespaceLRU {
+	c := NewNamespaceLRU(benchCapacity)
+	for _, key := range benchKeys[:benchCapacity] {
+		c.Set(benchNamespace, key, key)
+	}
+	return c
+}
+
+func BenchmarkGet_NoContention(b *testing.B) {
+	c := newBenchCache()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		c.Get(benchNamespace, benchKeys[i%benchKeyCount])
+	}
+}
+
+func BenchmarkSet_NoContention(b *testing.B) {
+	c := newBenchCache()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		key := benchKeys[i%benchKeyCount]
+		c.Set(benchNamespace, key, key)
+	}
+}
+
+func BenchmarkGetSet_Concurrent(b *testing.B) {
+	c := newBenchCache()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+	for g := 0; g < benchGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// Each goroutine takes every benchGoroutines-th operation; one in five is a set
+			for i := g; i < b.N; i += benchGoroutines {
+				key := benchKeys[(i*7919)%benchKeyCount]
+				if i%5 == 0 {
+					c.Set(benchNamespace, key, key)
+				} else {
+					c.Get(benchNamespace, key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func BenchmarkEviction(b *testing.B) {
+	c := newBenchCache()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	// Keys are never repeated within a capacity's worth of sets, so every set evicts
+	for i := 0; i < b.N; i++ {
+		key := benchKeys[(benchCapacity+i)%benchKeyCount]
+		c.Set(benchNamespace, key, key)
+	}
+	b.StopTimer()
+
+	if c.Size() > benchCapacity {
+		b.Fatalf("cache grew past its capacity: %d", c.Size())
+	}
+}
@@ -0,0 +1,395 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceLRU_SetWithTTL_ExpiresOnGet(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(10)
+	c.SetWithTTL("ns", "short", "value", 10*time.Millisecond)
+	c.Set("ns", "forever", "value")
+
+	// Act
+	time.Sleep(20 * time.Millisecond)
+	_, expiredFound := c.Get("ns", "short")
+	_, foreverFound := c.Get("ns", "forever")
+
+	// Assert
+	assert.False(t, expiredFound)
+	assert.True(t, foreverFound)
+	assert.Equal(t, 1, c.Size(), "expired entry should be removed on Get")
+}
+
+func TestNamespaceLRU_Set_KeepsExpiry(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(10)
+	c.SetWithTTL("ns", "key", "old", 10*time.Millisecond)
+
+	// Act
+	c.Set("ns", "key", "new")
+	time.Sleep(20 * time.Millisecond)
+	_, found := c.Get("ns", "key")
+
+	// Assert
+	assert.False(t, found)
+}
+
+func TestNamespaceLRU_PurgeExpired(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(10)
+	c.SetWithTTL("ns", "a", 1, 10*time.Millisecond)
+	c.SetWithTTL("ns", "b", 2, time.Hour)
+	c.SetWithTTL("other", "c", 3, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	// Act
+	c.purgeExpired()
+
+	// Assert
+	assert.Equal(t, 1, c.Size())
+	value, found := c.Get("ns", "b")
+	assert.True(t, found)
+	assert.Equal(t, 2, value)
+}
+
+func TestNamespaceLRU_StartPurge(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(10)
+	c.SetWithTTL("ns", "key", "value", 5*time.Millisecond)
+
+	// Act
+	stop := c.StartPurge(5 * time.Millisecond)
+	defer stop()
+
+	// Assert
+	assert.Eventually(t, func() bool { return c.Size() == 0 }, time.Second, 5*time.Millisecond)
+	stop() // stopping twice is safe
+}
+
+func TestNamespaceLRU_ConcurrentTTL(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(50)
+	stop := c.StartPurge(time.Millisecond)
+	defer stop()
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("%d-%d", worker, j%20)
+				c.SetWithTTL("ns", key, j, time.Millisecond)
+				c.Set("ns", key+"-plain", j)
+				c.Get("ns", key)
+				c.Invalidate("ns", key+"-plain")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Assert
+	assert.LessOrEqual(t, c.Size(), 50)
+}
+
+// withClock makes c read the time from now, so tests control expiry and entry ages
+func withClock(c *NamespaceLRU, now *time.Time) *NamespaceLRU {
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestNamespaceLRU_Stats(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	c := withClock(NewNamespaceLRU(2), &now)
+	c.Set("ns", "a", 1)
+	c.Set("ns", "b", 2)
+	c.SetWithTTL("ns", "short", 3, time.Millisecond) // evicts "a"
+	now = now.Add(5 * time.Millisecond)
+
+	// Act
+	c.Get("ns", "b")     // hit
+	c.Get("ns", "b")     // hit
+	c.Get("ns", "a")     // miss, evicted
+	c.Get("ns", "short") // miss, expired
+	c.Get("other", "b")  // miss, wrong namespace
+	c.Set("ns", "c", 4)
+	c.Set("ns", "d", 5) // evicts "b"
+
+	// Assert
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 3, Evictions: 2, Size: 2, Namespaces: map[string]int{"ns": 2}}, c.Stats())
+}
+
+func TestNamespaceLRU_Stats_Contents(t *testing.T) {
+	// Arrange
+	start := time.Now()
+	now := start
+	c := withClock(NewNamespaceLRU(4), &now)
+	c.Set("urls", "evicted", 0)
+	now = start.Add(time.Minute)
+	c.Set("urls", "oldest", 1)
+	now = start.Add(2 * time.Minute)
+	c.SetWithTTL("urls", "refreshed", 2, time.Hour)
+	now = start.Add(3 * time.Minute)
+	c.Set("tokens", "a", 3)
+	c.Get("urls", "evicted")   // hit
+	c.Get("urls", "oldest")    // hit
+	c.Get("tokens", "missing") // miss
+	now = start.Add(4 * time.Minute)
+	c.Set("urls", "refreshed", 20) // a new value is stored now
+	c.Set("tokens", "b", 4)        // evicts "a", the least recently used
+	c.Set("urls", "newest", 5)     // evicts "evicted"
+	c.Get("urls", "oldest")        // hit; using an entry does not make it younger
+	now = start.Add(10 * time.Minute)
+
+	// Act
+	stats := c.Stats()
+
+	// Assert
+	assert.Equal(t, CacheStats{
+		Hits:           3,
+		Misses:         1,
+		Evictions:      2,
+		Size:           4,
+		Namespaces:     map[string]int{"urls": 3, "tokens": 1},
+		OldestEntryAge: 9 * time.Minute,
+	}, stats)
+}
+
+func TestNamespaceLRU_Stats_Empty(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(4)
+	c.Set("urls", "a", 1)
+	c.Invalidate("urls", "a")
+
+	// Act
+	stats := c.Stats()
+
+	// Assert
+	assert.Equal(t, CacheStats{Namespaces: map[string]int{}}, stats)
+}
+
+func TestNamespaceLRU_StatsConcurrent(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(100)
+	c.Set("ns", "present", 1)
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Get("ns", "present")
+				c.Get("ns", "absent")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	stats := c.Stats()
+	assert.Equal(t, int64(1000), stats.Hits)
+	assert.Equal(t, int64(1000), stats.Misses)
+}
+
+// TestNamespaceLRU_ConcurrentGet guards against Get moving entries to the front of
+// the queue under a shared lock; run it with -race. Every goroutine alternates between the
+// same two keys because moving the entry that is already at the front changes nothing.
+func TestNamespaceLRU_ConcurrentGet(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(10)
+	keys := []string{"hot", "warm"}
+	for _, key := range keys {
+		c.Set("ns", key, "value")
+	}
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				value, found := c.Get("ns", keys[j%len(keys)])
+				assert.True(t, found)
+				assert.Equal(t, "value", value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, int64(10000), c.Stats().Hits)
+	assert.Equal(t, len(keys), c.Size())
+}
+
+func TestNamespaceLRU_Peek_KeepsEvictionOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		read        func(c *NamespaceLRU, namespace, key string) (interface{}, bool)
+		evictedKey  string
+		retainedKey string
+	}{
+		// Get promotes "a", so "b" becomes the least recently used
+		{name: "Get promotes", read: (*NamespaceLRU).Get, evictedKey: "b", retainedKey: "a"},
+		// Peek leaves "a" as the least recently used
+		{name: "Peek does not promote", read: (*NamespaceLRU).Peek, evictedKey: "a", retainedKey: "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			c := NewNamespaceLRU(2)
+			c.Set("ns", "a", 1)
+			c.Set("ns", "b", 2)
+
+			// Act
+			value, found := tt.read(c, "ns", "a")
+			c.Set("ns", "c", 3)
+
+			// Assert
+			assert.True(t, found)
+			assert.Equal(t, 1, value)
+			_, evicted := c.Peek("ns", tt.evictedKey)
+			assert.False(t, evicted)
+			_, retained := c.Peek("ns", tt.retainedKey)
+			assert.True(t, retained)
+		})
+	}
+}
+
+func TestNamespaceLRU_Peek(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	c := withClock(NewNamespaceLRU(10), &now)
+	c.Set("ns", "plain", 1)
+	c.SetWithTTL("ns", "expired", 2, -time.Second)
+
+	// Act
+	value, found := c.Peek("ns", "plain")
+	_, expiredFound := c.Peek("ns", "expired")
+	_, missingFound := c.Peek("ns", "missing")
+	_, otherNamespaceFound := c.Peek("other", "plain")
+
+	// Assert
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+	assert.False(t, expiredFound)
+	assert.False(t, missingFound)
+	assert.False(t, otherNamespaceFound)
+	assert.Equal(t, CacheStats{Size: 2, Namespaces: map[string]int{"ns": 2}}, c.Stats(), "Peek counts neither hits nor misses")
+}
+
+func TestNamespaceLRU_Keys(t *testing.T) {
+	// Arrange
+	c := NewNamespaceLRU(10)
+	c.Set("ns", "a", 1)
+	c.Set("ns", "b", 2)
+	c.Set("other", "c", 3)
+	c.SetWithTTL("ns", "expired", 4, -time.Second)
+	c.Get("ns", "a")
+
+	// Act
+	keys := c.Keys("ns")
+	empty := c.Keys("missing")
+
+	// Assert
+	assert.Equal(t, []string{"a", "b"}, keys)
+	assert.Empty(t, empty)
+	assert.NotNil(t, empty)
+}
+
+func TestNamespaceLRU_Entries(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	c := withClock(NewNamespaceLRU(10), &now)
+	c.Set("ns", "a", 1)
+	c.Set("ns", "b", 2)
+	c.Set("other", "c", 3)
+	c.SetWithTTL("ns", "expired", 4, -time.Second)
+
+	// Act
+	entries := c.Entries("ns")
+	entries["a"] = 10
+	empty := c.Entries("missing")
+
+	// Assert
+	assert.Equal(t, map[string]interface{}{"a": 10, "b": 2}, entries)
+	value, _ := c.Peek("ns", "a")
+	assert.Equal(t, 1, value, "the snapshot is not the cache")
+	assert.Empty(t, empty)
+	assert.NotNil(t, empty)
+	assert.Equal(t, CacheStats{Size: 4, Namespaces: map[string]int{"ns": 3, "other": 1}}, c.Stats(), "Entries counts neither hits nor misses")
+}
+
+func TestNamespaceLRU_DrainWarmRoundTrip(t *testing.T) {
+	// Arrange
+	source := NewNamespaceLRU(10)
+	source.Set("ns", "a", 1)
+	source.SetWithTTL("ns", "b", 2, time.Hour)
+	source.Set("other", "c", 3)
+	source.SetWithTTL("ns", "expired", 4, -time.Second)
+	target := NewNamespaceLRU(10)
+
+	// Act
+	drained := source.Drain("ns")
+	err := target.Warm(context.Background(), "ns", drained)
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.Len(t, drained, 2) {
+		assert.Equal(t, "b", drained[0].Key)
+		assert.Equal(t, 2, drained[0].Value)
+		assert.False(t, drained[0].ExpiresAt.IsZero())
+		assert.Equal(t, CacheEntry{Key: "a", Value: 1}, drained[1])
+	}
+	assert.Empty(t, source.Keys("ns"), "Drain removes the namespace, expired entries included")
+	assert.Equal(t, 1, source.Size())
+	assert.Equal(t, []string{"b", "a"}, target.Keys("ns"), "Warm keeps the recency order")
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": 2}, target.Entries("ns"))
+}
+
+func TestNamespaceLRU_Warm(t *testing.T) {
+	entries := []CacheEntry{
+		{Key: "a", Value: 1},
+		{Key: "b", Value: 2},
+		{Key: "expired", Value: 3, ExpiresAt: time.Now().Add(-time.Second)},
+		{Key: "c", Value: 4},
+	}
+
+	t.Run("Over capacity keeps the first entries", func(t *testing.T) {
+		// Arrange
+		c := NewNamespaceLRU(2)
+
+		// Act
+		err := c.Warm(context.Background(), "ns", entries)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, c.Keys("ns"))
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		// Arrange
+		c := NewNamespaceLRU(10)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		err := c.Warm(ctx, "ns", entries)
+
+		// Assert
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, c.Size())
+	})
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// accessPattern returns a function drawing the index of the next key to look up
+type accessPattern func(r *rand.Rand) func() int
+
+var accessPatterns = []struct {
+	name    string
+	pattern accessPattern
+}{
+	{
+		// Every key is as likely as any other, so no policy can beat capacity/keys
+		name: "Uniform",
+		pattern: func(r *rand.Rand) func() int {
+			return func() int { return r.Intn(benchKeyCount) }
+		},
+	},
+	{
+		// A few keys take most lookups, as popular short URLs do
+		name: "Zipf",
+		pattern: func(r *rand.Rand) func() int {
+			zipf := rand.NewZipf(r, 1.1, 1, benchKeyCount-1)
+			return func() int { return int(zipf.Uint64()) }
+		},
+	},
+	{
+		// Zipf lookups mixed with a crawler visiting every key once in turn
+		name: "ZipfWithScan",
+		pattern: func(r *rand.Rand) func() int {
+			zipf := rand.NewZipf(r, 1.1, 1, benchKeyCount-1)
+			next := 0
+			return func() int {
+				if r.Intn(2) == 0 {
+					return int(zipf.Uint64())
+				}
+				next = (next + 1) % benchKeyCount
+				return next
+			}
+		},
+	},
+}
+
+// BenchmarkHitRate compares the share of lookups each eviction policy answers
+// from the cache, reported as the hit-rate metric. A miss stores the key, as the
+// repository does after reading a short URL from the database.
+func BenchmarkHitRate(b *testing.B) {
+	for _, policy := range []string{EvictionLRU, EvictionLFU} {
+		for _, access := range accessPatterns {
+			b.Run(policy+"/"+access.name, func(b *testing.B) {
+				c, err := New(Options{Capacity: benchCapacity, EvictionPolicy: policy})
+				if err != nil {
+					b.Fatal(err)
+				}
+				next := access.pattern(rand.New(rand.NewSource(1)))
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					key := benchKeys[next()]
+					if _, found := c.Get(benchNamespace, key); !found {
+						c.Set(benchNamespace, key, key)
+					}
+				}
+
+				b.StopTimer()
+				stats := c.Stats()
+				b.ReportMetric(float64(stats.Hits)/float64(stats.Hits+stats.Misses), "hit-rate")
+			})
+		}
+	}
+}
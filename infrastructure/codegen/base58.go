@@ -0,0 +1,53 @@
+package codegen
+
+import "math/rand/v2"
+
+// base58Alphabet is the Bitcoin base58 alphabet, which leaves out the
+// look-alike characters 0, O, I and l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// lowercaseBase58Alphabet is the lowercase letters and digits of base58Alphabet
+const lowercaseBase58Alphabet = "123456789abcdefghijkmnopqrstuvwxyz"
+
+// EncodeBase58 returns n in base58, most significant digit first
+func EncodeBase58(n uint64) string {
+	if n == 0 {
+		return base58Alphabet[:1]
+	}
+
+	var digits [11]byte // 58^11 > 2^64
+	i := len(digits)
+	for n > 0 {
+		i--
+		digits[i] = base58Alphabet[n%58]
+		n /= 58
+	}
+	return string(digits[i:])
+}
+
+// Base58 generates short codes of random base58 characters, such as "3yQhTm"
+type Base58 struct {
+	length   int
+	alphabet string
+}
+
+// NewBase58 creates a Base58 generator producing codes of length characters
+func NewBase58(length int) *Base58 {
+	return &Base58{length: length, alphabet: base58Alphabet}
+}
+
+// NewLowercaseBase58 creates a Base58 generator producing codes of length lowercase base58
+// characters, such as "3yqhtm", for short codes that are not case sensitive
+func NewLowercaseBase58(length int) *Base58 {
+	return &Base58{length: length, alphabet: lowercaseBase58Alphabet}
+}
+
+// Generate returns a new base58 short code. Each character is drawn on its own,
+// as Random does, so every character of the alphabet is equally likely in every position.
+func (g *Base58) Generate() string {
+	code := make([]byte, g.length)
+	for i := range code {
+		code[i] = g.alphabet[rand.IntN(len(g.alphabet))]
+	}
+	return string(code)
+}
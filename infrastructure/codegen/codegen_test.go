@@ -0,0 +1,216 @@
+package codegen
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandom_Generate(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+	}{
+		{name: "Default length", length: 6},
+		{name: "Minimum length", length: 4},
+		{name: "Maximum length", length: 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			generator := NewRandom(tt.length)
+
+			// Act
+			code := generator.Generate()
+
+			// Assert
+			assert.Len(t, code, tt.length)
+			assert.Regexp(t, "^[a-zA-Z0-9]+$", code)
+		})
+	}
+}
+
+func TestRandom_Generate_NoCollisions(t *testing.T) {
+	// Arrange
+	generator := NewRandom(8)
+	seen := make(map[string]bool)
+
+	// Act
+	for i := 0; i < 10000; i++ {
+		seen[generator.Generate()] = true
+	}
+
+	// Assert
+	assert.Len(t, seen, 10000, "62^8 codes should not collide within 10,000 draws")
+}
+
+func TestWordPair_WordLists(t *testing.T) {
+	// Arrange
+	generator := NewWordPair()
+	word := regexp.MustCompile("^[a-z]+$")
+
+	// Assert
+	assert.Equal(t, 10000, generator.Combinations())
+	for _, list := range [][]string{generator.adjectives, generator.nouns} {
+		unique := make(map[string]bool, len(list))
+		for _, w := range list {
+			assert.Regexp(t, word, w)
+			unique[w] = true
+		}
+		assert.Len(t, unique, len(list), "word lists must not repeat words")
+	}
+}
+
+func TestWordPair_Generate(t *testing.T) {
+	// Arrange
+	generator := NewWordPair()
+
+	// Act
+	code := generator.Generate()
+
+	// Assert
+	parts := strings.Split(code, "-")
+	if assert.Len(t, parts, 2) {
+		assert.Contains(t, generator.adjectives, parts[0])
+		assert.Contains(t, generator.nouns, parts[1])
+	}
+}
+
+func TestWordPair_Generate_Spread(t *testing.T) {
+	// Arrange
+	generator := NewWordPair()
+	seen := make(map[string]bool)
+
+	// Act
+	for i := 0; i < 2000; i++ {
+		seen[generator.Generate()] = true
+	}
+
+	// Assert
+	// 2,000 uniform draws from 10,000 combinations give about 1,813 distinct codes
+	assert.Greater(t, len(seen), 1700)
+}
+
+func TestEncodeBase58(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        uint64
+		expected string
+	}{
+		{name: "Zero", n: 0, expected: "1"},
+		{name: "One", n: 1, expected: "2"},
+		{name: "Last single digit", n: 57, expected: "z"},
+		{name: "First two digit", n: 58, expected: "21"},
+		{name: "Max uint64", n: math.MaxUint64, expected: "jpXCZedGfVQ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			encoded := EncodeBase58(tt.n)
+
+			// Assert
+			assert.Equal(t, tt.expected, encoded)
+		})
+	}
+}
+
+func TestBase58_Generate(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+	}{
+		{name: "Default length", length: 6},
+		{name: "Maximum length", length: 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			generator := NewBase58(tt.length)
+
+			// Act
+			code := generator.Generate()
+
+			// Assert
+			assert.Len(t, code, tt.length)
+		})
+	}
+}
+
+func TestBase58_Generate_NoAmbiguousCharacters(t *testing.T) {
+	// Arrange
+	generator := NewBase58(8)
+	seen := make(map[string]bool)
+
+	// Act
+	for i := 0; i < 10000; i++ {
+		code := generator.Generate()
+		seen[code] = true
+
+		// Assert
+		if strings.ContainsAny(code, "0OIl") {
+			t.Fatalf("code %q contains an ambiguous character", code)
+		}
+	}
+	assert.Len(t, seen, 10000, "58^8 codes should not collide within 10,000 draws")
+}
+
+func TestBase58_Generate_Uniform(t *testing.T) {
+	// Arrange
+	generator := NewBase58(4)
+	counts := make(map[byte]int)
+	const draws = 20000
+
+	// Act
+	for i := 0; i < draws; i++ {
+		code := generator.Generate()
+		for j := range code {
+			counts[code[j]]++
+		}
+	}
+
+	// Assert - every character is expected 4*20,000/58, about 1,379 times
+	assert.Len(t, counts, len(base58Alphabet))
+	for c, n := range counts {
+		assert.InDelta(t, 4*draws/len(base58Alphabet), n, 250, "character %q", c)
+	}
+}
+
+func TestLowercase_Generate(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator interface{ Generate() string }
+		alphabet  string
+	}{
+		{name: "Random", generator: NewLowercaseRandom(6), alphabet: lowercaseAlphanumeric},
+		{name: "Base58", generator: NewLowercaseBase58(6), alphabet: lowercaseBase58Alphabet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			seen := make(map[rune]bool)
+
+			// Act
+			for i := 0; i < 2000; i++ {
+				code := tt.generator.Generate()
+				assert.Len(t, code, 6)
+				assert.Equal(t, strings.ToLower(code), code)
+				for _, c := range code {
+					seen[c] = true
+				}
+			}
+
+			// Assert - every character of the alphabet is drawn, and nothing else
+			assert.Len(t, seen, len(tt.alphabet))
+			for c := range seen {
+				assert.Contains(t, tt.alphabet, string(c))
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package codegen
+
+import "math/rand/v2"
+
+// alphanumeric is the alphabet of random short codes
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// lowercaseAlphanumeric is the alphabet of random short codes that are not case sensitive
+const lowercaseAlphanumeric = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// Random generates short codes of random letters and digits, such as "aZ3k9Q"
+type Random struct {
+	length   int
+	alphabet string
+}
+
+// NewRandom creates a Random generator producing codes of length characters
+func NewRandom(length int) *Random {
+	return &Random{length: length, alphabet: alphanumeric}
+}
+
+// NewLowercaseRandom creates a Random generator producing codes of length lowercase
+// letters and digits, such as "az3k9q", for short codes that are not case sensitive
+func NewLowercaseRandom(length int) *Random {
+	return &Random{length: length, alphabet: lowercaseAlphanumeric}
+}
+
+// Generate returns a new random short code
+func (g *Random) Generate() string {
+	code := make([]byte, g.length)
+	for i := range code {
+		code[i] = g.alphabet[rand.IntN(len(g.alphabet))]
+	}
+	return string(code)
+}
@@ -0,0 +1,38 @@
+package codegen
+
+import (
+	_ "embed"
+	"math/rand/v2"
+	"strings"
+)
+
+var (
+	//go:embed adjectives.txt
+	adjectiveList string
+	//go:embed nouns.txt
+	nounList string
+)
+
+// WordPair generates memorable adjective-noun short codes, such as "swift-dog"
+type WordPair struct {
+	adjectives []string
+	nouns      []string
+}
+
+// NewWordPair creates a WordPair generator using the embedded word lists
+func NewWordPair() *WordPair {
+	return &WordPair{
+		adjectives: strings.Fields(adjectiveList),
+		nouns:      strings.Fields(nounList),
+	}
+}
+
+// Combinations returns the number of distinct codes the generator can produce
+func (g *WordPair) Combinations() int {
+	return len(g.adjectives) * len(g.nouns)
+}
+
+// Generate returns a new adjective-noun short code
+func (g *WordPair) Generate() string {
+	return g.adjectives[rand.IntN(len(g.adjectives))] + "-" + g.nouns[rand.IntN(len(g.nouns))]
+}
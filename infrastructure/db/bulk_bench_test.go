@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+)
+
+// benchURLCount is the number of URLs each benchmark iteration stores
+const benchURLCount = 1000
+
+// newBenchURLs returns benchURLCount URLs whose short codes are unique to iteration
+func newBenchURLs(iteration int) []*shortener.URL {
+	urls := make([]*shortener.URL, benchURLCount)
+	for i := range urls {
+		code := fmt.Sprintf("b%d-%d", iteration, i)
+		urls[i] = &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now(), RedirectCode: 302}
+	}
+	return urls
+}
+
+// newBenchRepository opens an empty SQLite repository that is closed when the benchmark ends
+func newBenchRepository(b *testing.B) Repository {
+	repo, err := NewSQLiteRepository(filepath.Join(b.TempDir(), "bench.db"), cache.NewNoopCache())
+	if err != nil {
+		b.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	b.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func BenchmarkStore_Individual(b *testing.B) {
+	repo := newBenchRepository(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		urls := newBenchURLs(i)
+		b.StartTimer()
+
+		for _, url := range urls {
+			if err := repo.Store(ctx, url); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkStore_Bulk(b *testing.B) {
+	repo := newBenchRepository(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		urls := newBenchURLs(i)
+		b.StartTimer()
+
+		result, err := repo.BulkStore(ctx, urls)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.Stored != benchURLCount {
+			b.Fatalf("stored %d of %d URLs", result.Stored, benchURLCount)
+		}
+	}
+}
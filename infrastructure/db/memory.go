@@ -0,0 +1,774 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+)
+
+// errMemoryClosed is reported by Ping once a MemoryRepository has been closed
+var errMemoryClosed = errors.New("memory repository is closed")
+
+// memoryURL is a stored URL with the state the domain type does not carry
+type memoryURL struct {
+	url     shortener.URL
+	deleted bool
+	tags    map[string]bool
+}
+
+// MemoryRepository implements shortener.Repository in process memory. It behaves like
+// the SQL repositories, including soft deletes, and is meant for tests that do not need
+// a real database. Nothing survives the process.
+type MemoryRepository struct {
+	mu sync.RWMutex
+	// txMu runs transactions one at a time
+	txMu sync.Mutex
+	// urls holds every stored URL in ID order, deleted ones included
+	urls        []*memoryURL
+	byShortCode map[string]*memoryURL
+	// byFoldedCode holds the lowercased short codes of byShortCode for taken
+	byFoldedCode map[string]bool
+	clicks       []shortener.ClickEvent
+	users        []shortener.User
+	webhooks     []shortener.Webhook
+	audits       []shortener.AuditEntry
+	lastID       uint
+	closed       bool
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{byShortCode: make(map[string]*memoryURL), byFoldedCode: make(map[string]bool)}
+}
+
+// index makes stored findable by its short code and keeps the code taken
+func (r *MemoryRepository) index(stored *memoryURL) {
+	r.byShortCode[stored.url.ShortCode] = stored
+	r.byFoldedCode[strings.ToLower(stored.url.ShortCode)] = true
+}
+
+// nextID returns a new ID; IDs are unique across every kind of record
+func (r *MemoryRepository) nextID() uint {
+	r.lastID++
+	return r.lastID
+}
+
+// live returns the stored URL for a short code unless it does not exist or is deleted
+func (r *MemoryRepository) live(shortCode string) (*memoryURL, bool) {
+	stored, ok := r.byShortCode[shortCode]
+	if !ok || stored.deleted {
+		return nil, false
+	}
+	return stored, true
+}
+
+// Store persists a URL and sets its ID. Deleted URLs keep their short code taken.
+func (r *MemoryRepository) Store(ctx context.Context, url *shortener.URL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.taken(url.ShortCode) {
+		return shortener.ErrShortCodeExists
+	}
+
+	url.ID = r.nextID()
+	stored := &memoryURL{url: *url, tags: make(map[string]bool)}
+	r.urls = append(r.urls, stored)
+	r.index(stored)
+	return nil
+}
+
+// memoryState is a copy of everything a MemoryRepository stores
+type memoryState struct {
+	urls     []*memoryURL
+	clicks   []shortener.ClickEvent
+	users    []shortener.User
+	webhooks []shortener.Webhook
+	audits   []shortener.AuditEntry
+	lastID   uint
+}
+
+// WithTransaction runs fn against the repository and restores what it stored before fn
+// when fn returns an error. Transactions run one at a time, but other callers are not
+// isolated from them: a write made outside the transaction while a failing one runs is
+// rolled back with it.
+func (r *MemoryRepository) WithTransaction(ctx context.Context, fn func(tx shortener.Repository) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+
+	saved := r.save()
+	if err := fn(r); err != nil {
+		r.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// save copies the stored state deeply enough that later writes do not change the copy
+func (r *MemoryRepository) save() memoryState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	state := memoryState{
+		urls:     make([]*memoryURL, len(r.urls)),
+		clicks:   append([]shortener.ClickEvent(nil), r.clicks...),
+		users:    append([]shortener.User(nil), r.users...),
+		webhooks: append([]shortener.Webhook(nil), r.webhooks...),
+		audits:   append([]shortener.AuditEntry(nil), r.audits...),
+		lastID:   r.lastID,
+	}
+	for i, stored := range r.urls {
+		copied := &memoryURL{url: stored.url, deleted: stored.deleted, tags: make(map[string]bool, len(stored.tags))}
+		for tag := range stored.tags {
+			copied.tags[tag] = true
+		}
+		state.urls[i] = copied
+	}
+	return state
+}
+
+// restore replaces the stored state with one taken by save
+func (r *MemoryRepository) restore(state memoryState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.urls = state.urls
+	r.byShortCode = make(map[string]*memoryURL, len(state.urls))
+	r.byFoldedCode = make(map[string]bool, len(state.urls))
+	for _, stored := range state.urls {
+		r.index(stored)
+	}
+	r.clicks = state.clicks
+	r.users = state.users
+	r.webhooks = state.webhooks
+	r.audits = state.audits
+	r.lastID = state.lastID
+}
+
+// BulkStore stores every URL whose short code is free, skipping the others
+func (r *MemoryRepository) BulkStore(ctx context.Context, urls []*shortener.URL) (*shortener.BulkStoreResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &shortener.BulkStoreResult{Errors: []shortener.BulkStoreError{}}
+	for i, url := range urls {
+		if r.taken(url.ShortCode) {
+			result.Errors = append(result.Errors, shortener.BulkStoreError{Index: i, ShortCode: url.ShortCode, Reason: constant.ErrShortCodeExists})
+			continue
+		}
+
+		url.ID = r.nextID()
+		stored := &memoryURL{url: *url, tags: make(map[string]bool)}
+		r.urls = append(r.urls, stored)
+		r.index(stored)
+		result.Stored++
+	}
+	result.Skipped = len(urls) - result.Stored
+	return result, nil
+}
+
+// taken reports whether a stored URL, deleted or not, holds shortCode in any case, as
+// the unique short code index of the SQL repositories does
+func (r *MemoryRepository) taken(shortCode string) bool {
+	return r.byFoldedCode[strings.ToLower(shortCode)]
+}
+
+// FindByShortCode retrieves a URL by its short code
+func (r *MemoryRepository) FindByShortCode(ctx context.Context, shortCode string) (*shortener.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.live(shortCode)
+	if !ok {
+		return nil, shortener.ErrShortCodeNotFound
+	}
+	url := stored.url
+	return &url, nil
+}
+
+// FindByLongURL retrieves the first URL stored for a long URL
+func (r *MemoryRepository) FindByLongURL(ctx context.Context, longURL string) (*shortener.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, stored := range r.urls {
+		if !stored.deleted && stored.url.LongURL == longURL {
+			url := stored.url
+			return &url, nil
+		}
+	}
+	return nil, shortener.ErrLongURLNotFound
+}
+
+// IncrementVisits increments the visit count for a URL. Unknown short codes are ignored.
+func (r *MemoryRepository) IncrementVisits(ctx context.Context, shortCode string) error {
+	return r.incrementVisitsBatch(ctx, map[string]visitBatch{shortCode: {count: 1}})
+}
+
+// IncrementVisitsWithinLimit increments the visit count for a URL while it is below its MaxVisits
+func (r *MemoryRepository) IncrementVisitsWithinLimit(ctx context.Context, shortCode string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.byShortCode[shortCode]
+	if !ok || stored.url.MaxVisits == nil || stored.url.Visits >= *stored.url.MaxVisits {
+		return false, nil
+	}
+	stored.url.Visits++
+	return true, nil
+}
+
+// UpdateLastAccessed moves the last access time of a URL forward to at. Unknown short codes are ignored.
+func (r *MemoryRepository) UpdateLastAccessed(ctx context.Context, shortCode string, at time.Time) error {
+	return r.incrementVisitsBatch(ctx, map[string]visitBatch{shortCode: {lastAccessed: at}})
+}
+
+// incrementVisitsBatch adds each count to its short code's visits and moves its last access
+// time forward; a zero lastAccessed leaves the time unchanged
+func (r *MemoryRepository) incrementVisitsBatch(ctx context.Context, visits map[string]visitBatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for shortCode, batch := range visits {
+		stored, ok := r.byShortCode[shortCode]
+		if !ok {
+			continue
+		}
+		stored.url.Visits += batch.count
+		if at := batch.lastAccessed.UTC(); !batch.lastAccessed.IsZero() && (stored.url.LastAccessedAt == nil || stored.url.LastAccessedAt.Before(at)) {
+			stored.url.LastAccessedAt = &at
+		}
+	}
+	return nil
+}
+
+// UpdateLongURL updates the long URL for an existing short code
+func (r *MemoryRepository) UpdateLongURL(ctx context.Context, shortCode string, newLongURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.live(shortCode)
+	if !ok {
+		return shortener.ErrShortCodeNotFound
+	}
+	stored.url.LongURL = newLongURL
+	return nil
+}
+
+// UpdateCanary sets the alternate destination of a short URL and the percentage of redirects sent to it
+func (r *MemoryRepository) UpdateCanary(ctx context.Context, shortCode, alternateURL string, percent uint8) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.live(shortCode)
+	if !ok {
+		return shortener.ErrShortCodeNotFound
+	}
+	stored.url.AlternateURL = alternateURL
+	stored.url.CanaryPercent = percent
+	return nil
+}
+
+// FindExpired returns the live URLs whose expiry is at or before now, oldest expiry first
+func (r *MemoryRepository) FindExpired(ctx context.Context, now time.Time) ([]*shortener.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := []*shortener.URL{}
+	for _, stored := range r.urls {
+		if !stored.deleted && stored.url.ExpiresAt != nil && !stored.url.ExpiresAt.After(now) {
+			url := stored.url
+			urls = append(urls, &url)
+		}
+	}
+	sort.SliceStable(urls, func(i, j int) bool {
+		return urls[i].ExpiresAt.Before(*urls[j].ExpiresAt)
+	})
+	return urls, nil
+}
+
+// RenameShortCode moves the URL stored under oldCode to newCode. As in the SQL
+// repositories the old code stays taken, and tags, webhooks and clicks follow the URL.
+func (r *MemoryRepository) RenameShortCode(ctx context.Context, oldCode, newCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.live(oldCode)
+	if !ok {
+		return shortener.ErrShortCodeNotFound
+	}
+	if r.taken(newCode) {
+		return shortener.ErrShortCodeExists
+	}
+
+	renamed := &memoryURL{url: stored.url, tags: make(map[string]bool, len(stored.tags))}
+	renamed.url.ID = r.nextID()
+	renamed.url.ShortCode = newCode
+	for tag := range stored.tags {
+		renamed.tags[tag] = true
+	}
+	stored.deleted = true
+	r.urls = append(r.urls, renamed)
+	r.index(renamed)
+
+	for i := range r.webhooks {
+		if r.webhooks[i].ShortCode == oldCode {
+			r.webhooks[i].ShortCode = newCode
+		}
+	}
+	for i := range r.clicks {
+		if r.clicks[i].ShortCode == oldCode {
+			r.clicks[i].ShortCode = newCode
+		}
+	}
+	return nil
+}
+
+// Delete soft-deletes the URL for a short code so it can no longer be resolved
+func (r *MemoryRepository) Delete(ctx context.Context, shortCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.live(shortCode)
+	if !ok {
+		return shortener.ErrShortCodeNotFound
+	}
+	stored.deleted = true
+	return nil
+}
+
+// BulkDelete soft-deletes every short code. Codes that do not exist or are already
+// deleted are returned as errors rather than failing the batch.
+func (r *MemoryRepository) BulkDelete(ctx context.Context, shortCodes []string) (int, []shortener.BulkDeleteError, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int
+	var failures []shortener.BulkDeleteError
+	for _, code := range shortCodes {
+		stored, ok := r.live(code)
+		if !ok {
+			failures = append(failures, shortener.BulkDeleteError{ShortCode: code, Reason: constant.ErrShortCodeNotFound})
+			continue
+		}
+		stored.deleted = true
+		deleted++
+	}
+	return deleted, failures, nil
+}
+
+// Ping reports whether the repository is still open
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return errMemoryClosed
+	}
+	return nil
+}
+
+// AppendAudit stores an audit entry
+func (r *MemoryRepository) AppendAudit(ctx context.Context, entry shortener.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = r.nextID()
+	r.audits = append(r.audits, entry)
+	return nil
+}
+
+// FindAudits retrieves the audit entries of a short code, oldest first
+func (r *MemoryRepository) FindAudits(ctx context.Context, shortCode string) ([]shortener.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []shortener.AuditEntry{}
+	for _, entry := range r.audits {
+		if entry.ShortCode == shortCode {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].OccurredAt.Before(entries[j].OccurredAt)
+	})
+	return entries, nil
+}
+
+// RecordClick stores a single click event for a short code
+func (r *MemoryRepository) RecordClick(ctx context.Context, event shortener.ClickEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ClickedAt = event.ClickedAt.UTC()
+	r.clicks = append(r.clicks, event)
+	return nil
+}
+
+// FindClicks retrieves the click events for a short code within [from, to), oldest first
+func (r *MemoryRepository) FindClicks(ctx context.Context, shortCode string, from, to time.Time) ([]shortener.ClickEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clicks := []shortener.ClickEvent{}
+	for _, click := range r.clicks {
+		if click.ShortCode == shortCode && !click.ClickedAt.Before(from) && click.ClickedAt.Before(to) {
+			clicks = append(clicks, click)
+		}
+	}
+	sort.SliceStable(clicks, func(i, j int) bool {
+		return clicks[i].ClickedAt.Before(clicks[j].ClickedAt)
+	})
+	return clicks, nil
+}
+
+// FindAllClicks retrieves every click event for a short code, oldest first
+func (r *MemoryRepository) FindAllClicks(ctx context.Context, shortCode string) ([]shortener.ClickEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clicks := []shortener.ClickEvent{}
+	for _, click := range r.clicks {
+		if click.ShortCode == shortCode {
+			clicks = append(clicks, click)
+		}
+	}
+	sort.SliceStable(clicks, func(i, j int) bool {
+		return clicks[i].ClickedAt.Before(clicks[j].ClickedAt)
+	})
+	return clicks, nil
+}
+
+// FindDeviceStats counts the click events for a short code by device type
+func (r *MemoryRepository) FindDeviceStats(ctx context.Context, shortCode string) (shortener.DeviceStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stats shortener.DeviceStats
+	for _, click := range r.clicks {
+		if click.ShortCode == shortCode {
+			stats.Add(click.DeviceType, 1)
+		}
+	}
+	return stats, nil
+}
+
+// FindReferers counts the click events for a short code grouped by referer, most frequent first
+func (r *MemoryRepository) FindReferers(ctx context.Context, shortCode string) ([]shortener.RefererStat, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]uint)
+	for _, click := range r.clicks {
+		if click.ShortCode == shortCode {
+			counts[click.Referer]++
+		}
+	}
+
+	referers := make([]shortener.RefererStat, 0, len(counts))
+	for referer, count := range counts {
+		referers = append(referers, shortener.RefererStat{URL: referer, Count: count})
+	}
+	sort.Slice(referers, func(i, j int) bool {
+		if referers[i].Count != referers[j].Count {
+			return referers[i].Count > referers[j].Count
+		}
+		return referers[i].URL < referers[j].URL
+	})
+	return referers, nil
+}
+
+// FindDailyClicks counts the click events for a short code per UTC day over the last given number of days.
+// Days without clicks are omitted.
+func (r *MemoryRepository) FindDailyClicks(ctx context.Context, shortCode string, days int) ([]shortener.DailyCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	byDate := make(map[string]uint)
+	for _, click := range r.clicks {
+		if click.ShortCode == shortCode && !click.ClickedAt.Before(since) {
+			byDate[click.ClickedAt.Format("2006-01-02")]++
+		}
+	}
+
+	counts := make([]shortener.DailyCount, 0, len(byDate))
+	for date, count := range byDate {
+		counts = append(counts, shortener.DailyCount{Date: date, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Date < counts[j].Date
+	})
+	return counts, nil
+}
+
+// FindAll streams every live URL to fn in ID order, stopping at the first error
+func (r *MemoryRepository) FindAll(ctx context.Context, fn func(url *shortener.URL) error) error {
+	// Copy first so that fn may call back into the repository
+	var urls []shortener.URL
+	r.mu.RLock()
+	for _, stored := range r.urls {
+		if !stored.deleted {
+			urls = append(urls, stored.url)
+		}
+	}
+	r.mu.RUnlock()
+
+	for i := range urls {
+		if err := fn(&urls[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search retrieves a page of URLs whose long URL contains query, newest first, with the total number of matches
+func (r *MemoryRepository) Search(ctx context.Context, query string, limit, offset int) ([]*shortener.URL, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls, total := r.page(func(stored *memoryURL) bool {
+		return strings.Contains(stored.url.LongURL, query)
+	}, limit, offset)
+	return urls, total, nil
+}
+
+// FindCreatedAfter retrieves a page of the live URLs created after since, newest first
+func (r *MemoryRepository) FindCreatedAfter(ctx context.Context, since time.Time, limit, offset int) ([]*shortener.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matches []*shortener.URL
+	for i := len(r.urls) - 1; i >= 0; i-- {
+		stored := r.urls[i]
+		if !stored.deleted && stored.url.CreatedAt.After(since) {
+			url := stored.url
+			matches = append(matches, &url)
+		}
+	}
+	// Stable, so URLs created at the same time stay newest ID first
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+
+	urls := []*shortener.URL{}
+	for i := offset; i < len(matches) && len(urls) < limit; i++ {
+		urls = append(urls, matches[i])
+	}
+	return urls, nil
+}
+
+// FindTopURLs retrieves up to limit live URLs with the most visits, most visited first
+func (r *MemoryRepository) FindTopURLs(ctx context.Context, limit int) ([]*shortener.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matches []*shortener.URL
+	for _, stored := range r.urls {
+		if !stored.deleted {
+			url := stored.url
+			matches = append(matches, &url)
+		}
+	}
+	// Stable, so URLs with the same visits stay oldest ID first
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Visits > matches[j].Visits
+	})
+
+	urls := []*shortener.URL{}
+	for i := 0; i < len(matches) && len(urls) < limit; i++ {
+		urls = append(urls, matches[i])
+	}
+	return urls, nil
+}
+
+// FindTrending retrieves up to limit live URLs clicked within the past period, most recent clicks first
+func (r *MemoryRepository) FindTrending(ctx context.Context, period time.Duration, limit int) ([]*shortener.TrendingURL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	since := time.Now().UTC().Add(-period)
+	counts := make(map[string]uint)
+	for _, click := range r.clicks {
+		if !click.ClickedAt.Before(since) {
+			counts[click.ShortCode]++
+		}
+	}
+
+	matches := []*shortener.TrendingURL{}
+	for _, stored := range r.urls {
+		if count := counts[stored.url.ShortCode]; count > 0 && !stored.deleted {
+			matches = append(matches, &shortener.TrendingURL{URL: stored.url, RecentVisits: count})
+		}
+	}
+	// Stable, so URLs with the same clicks stay oldest ID first
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].RecentVisits > matches[j].RecentVisits
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// AddTag attaches tag to a short code. Adding a tag twice, or to a missing short code, is a no-op.
+func (r *MemoryRepository) AddTag(ctx context.Context, shortCode, tag string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.live(shortCode); ok {
+		stored.tags[tag] = true
+	}
+	return nil
+}
+
+// RemoveTag detaches tag from a short code
+func (r *MemoryRepository) RemoveTag(ctx context.Context, shortCode, tag string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.live(shortCode)
+	if !ok || !stored.tags[tag] {
+		return shortener.ErrTagNotFound
+	}
+	delete(stored.tags, tag)
+	return nil
+}
+
+// FindTags retrieves the tag names of a short code in alphabetical order
+func (r *MemoryRepository) FindTags(ctx context.Context, shortCode string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := []string{}
+	if stored, ok := r.live(shortCode); ok {
+		for tag := range stored.tags {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// ListByTag retrieves a page of URLs carrying tag, newest first, with the total number of matches
+func (r *MemoryRepository) ListByTag(ctx context.Context, tag string, limit, offset int) ([]*shortener.URL, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls, total := r.page(func(stored *memoryURL) bool {
+		return stored.tags[tag]
+	}, limit, offset)
+	return urls, total, nil
+}
+
+// page returns the live URLs matching match, newest first, limited to [offset, offset+limit),
+// with the total number of matches. The caller must hold the lock.
+func (r *MemoryRepository) page(match func(stored *memoryURL) bool, limit, offset int) ([]*shortener.URL, int) {
+	urls := []*shortener.URL{}
+	total := 0
+	for i := len(r.urls) - 1; i >= 0; i-- {
+		stored := r.urls[i]
+		if stored.deleted || !match(stored) {
+			continue
+		}
+		if total >= offset && len(urls) < limit {
+			url := stored.url
+			urls = append(urls, &url)
+		}
+		total++
+	}
+	return urls, total
+}
+
+// FindUserByUsername retrieves a user account by its username
+func (r *MemoryRepository) FindUserByUsername(ctx context.Context, username string) (*shortener.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			found := user
+			return &found, nil
+		}
+	}
+	return nil, shortener.ErrUserNotFound
+}
+
+// CreateUser stores a new user account and sets its ID
+func (r *MemoryRepository) CreateUser(ctx context.Context, user *shortener.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == user.Username {
+			return shortener.ErrUserExists
+		}
+	}
+	user.ID = r.nextID()
+	r.users = append(r.users, *user)
+	return nil
+}
+
+// ListUsers retrieves every user account ordered by ID
+func (r *MemoryRepository) ListUsers(ctx context.Context) ([]shortener.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]shortener.User{}, r.users...), nil
+}
+
+// CreateWebhook stores a new webhook and sets its ID
+func (r *MemoryRepository) CreateWebhook(ctx context.Context, hook *shortener.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hook.ID = r.nextID()
+	stored := *hook
+	stored.Events = append([]string{}, hook.Events...)
+	r.webhooks = append(r.webhooks, stored)
+	return nil
+}
+
+// FindWebhooks retrieves the webhooks registered for a short code
+func (r *MemoryRepository) FindWebhooks(ctx context.Context, shortCode string) ([]shortener.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hooks := []shortener.Webhook{}
+	for _, hook := range r.webhooks {
+		if hook.ShortCode == shortCode {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook removes a webhook by ID
+func (r *MemoryRepository) DeleteWebhook(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, hook := range r.webhooks {
+		if hook.ID == id {
+			r.webhooks = append(r.webhooks[:i], r.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return shortener.ErrWebhookNotFound
+}
+
+// Close marks the repository closed so that Ping fails; the data stays readable
+func (r *MemoryRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	return nil
+}
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+	"gorm.io/gorm"
+)
+
+// Migration is a numbered schema change. Applied migrations are recorded in the
+// migrations table and never run again, so a released migration must not be edited;
+// add a new one instead.
+type Migration struct {
+	Version int
+	// Name describes the change in logs
+	Name string
+	Up   func(tx *gorm.DB) error
+}
+
+// MigrationModel records a migration that has been applied
+type MigrationModel struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
+
+// TableName overrides the table name used by MigrationModel
+func (MigrationModel) TableName() string {
+	return "migrations"
+}
+
+// schemaMigrations returns the migrations that build the schema for dialect d, oldest first
+func schemaMigrations(d dialect) []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create tables",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&URLModel{}, &ClickModel{}, &UserModel{}, &WebhookModel{}, &TagModel{}, &URLTagModel{}, &AuditModel{})
+			},
+		},
+		{
+			Version: 2,
+			Name:    "index long URLs",
+			Up: func(tx *gorm.DB) error {
+				// Created by hand because MySQL can only index a prefix of the column.
+				// Databases that predate versioning may already have it.
+				if tx.Migrator().HasIndex(&URLModel{}, longURLIndexName) {
+					return nil
+				}
+				return tx.Exec(d.longURLIndex).Error
+			},
+		},
+		{
+			Version: 3,
+			Name:    "index creation times and live short codes",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.Exec(`CREATE INDEX ` + createdAtIndexName + ` ON url_models (created_at)`).Error; err != nil {
+					return err
+				}
+				if d.activeShortCodeIndex == "" {
+					return nil
+				}
+				return tx.Exec(d.activeShortCodeIndex).Error
+			},
+		},
+		{
+			Version: 4,
+			Name:    "add UTM parameters",
+			Up: func(tx *gorm.DB) error {
+				// Databases created after the model gained the columns already have them
+				for _, column := range []string{"UTMSource", "UTMCampaign", "UTMMedium"} {
+					if tx.Migrator().HasColumn(&URLModel{}, column) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&URLModel{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version: 5,
+			Name:    "add last access time",
+			Up: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&URLModel{}, "LastAccessedAt") {
+					return nil
+				}
+				return tx.Migrator().AddColumn(&URLModel{}, "LastAccessedAt")
+			},
+		},
+		{
+			Version: 6,
+			Name:    "add click visitor details",
+			Up: func(tx *gorm.DB) error {
+				for _, column := range []string{"IPHash", "UserAgent"} {
+					if tx.Migrator().HasColumn(&ClickModel{}, column) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&ClickModel{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version: 7,
+			Name:    "add click device types",
+			Up: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&ClickModel{}, "DeviceType") {
+					return nil
+				}
+				return tx.Migrator().AddColumn(&ClickModel{}, "DeviceType")
+			},
+		},
+		{
+			Version: 8,
+			Name:    "add canary splits",
+			Up: func(tx *gorm.DB) error {
+				for _, column := range []string{"AlternateURL", "CanaryPercent"} {
+					if tx.Migrator().HasColumn(&URLModel{}, column) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&URLModel{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version: 9,
+			Name:    "add geo rules",
+			Up: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&URLModel{}, "GeoRules") {
+					return nil
+				}
+				return tx.Migrator().AddColumn(&URLModel{}, "GeoRules")
+			},
+		},
+		{
+			Version: 10,
+			Name:    "add owner tokens",
+			Up: func(tx *gorm.DB) error {
+				for _, column := range []string{"OwnerTokenHash", "OwnerTokenExpiresAt"} {
+					if tx.Migrator().HasColumn(&URLModel{}, column) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&URLModel{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version: 11,
+			Name:    "add scheduled activation",
+			Up: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&URLModel{}, "ActiveFrom") {
+					return nil
+				}
+				return tx.Migrator().AddColumn(&URLModel{}, "ActiveFrom")
+			},
+		},
+		{
+			Version: 12,
+			Name:    "make short codes unique regardless of case",
+			Up: func(tx *gorm.DB) error {
+				// Fails on databases holding codes that differ only in case; one of each
+				// pair must be renamed before upgrading
+				if d.foldedShortCodeIndex == "" {
+					return nil
+				}
+				return tx.Exec(d.foldedShortCodeIndex).Error
+			},
+		},
+	}
+}
+
+// RunMigrations applies the migrations that have not been applied yet, in version order.
+// Each one runs in a transaction together with its migrations row, so a failed migration
+// is not recorded; MySQL commits DDL implicitly, so there it may leave a partial change.
+func RunMigrations(db *gorm.DB, migrations []Migration) error {
+	if err := db.AutoMigrate(&MigrationModel{}); err != nil {
+		return err
+	}
+
+	var versions []int
+	if err := db.Model(&MigrationModel{}).Pluck("version", &versions).Error; err != nil {
+		return err
+	}
+	applied := make(map[int]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	pending := make([]Migration, 0, len(migrations))
+	seen := make(map[int]bool, len(migrations))
+	for _, migration := range migrations {
+		if seen[migration.Version] {
+			return fmt.Errorf("duplicate migration version %d", migration.Version)
+		}
+		seen[migration.Version] = true
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Version < pending[j].Version
+	})
+
+	for _, migration := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&MigrationModel{Version: migration.Version, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		appLogger.Info("Applied database migration", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxDB,
+			Data: map[string]interface{}{
+				constant.DataVersion: migration.Version,
+				constant.DataName:    migration.Name,
+			},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openMigrationTestDB opens an empty SQLite database without running any migrations
+func openMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test_migrations.db")), &gorm.Config{Logger: &GormLogger{}})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// appliedVersions returns the versions recorded in the migrations table
+func appliedVersions(t *testing.T, db *gorm.DB) []int {
+	var versions []int
+	assert.NoError(t, db.Model(&MigrationModel{}).Order("version").Pluck("version", &versions).Error)
+	return versions
+}
+
+func TestRunMigrations_Idempotent(t *testing.T) {
+	// Arrange
+	db := openMigrationTestDB(t)
+	var order []int
+	migrations := []Migration{
+		{Version: 2, Name: "second", Up: func(tx *gorm.DB) error {
+			order = append(order, 2)
+			return tx.Exec(`CREATE TABLE second (id INTEGER)`).Error
+		}},
+		{Version: 1, Name: "first", Up: func(tx *gorm.DB) error {
+			order = append(order, 1)
+			return tx.Exec(`CREATE TABLE first (id INTEGER)`).Error
+		}},
+	}
+
+	// Act
+	firstErr := RunMigrations(db, migrations)
+	secondErr := RunMigrations(db, migrations)
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr, "applied migrations are skipped rather than run again")
+	assert.Equal(t, []int{1, 2}, order)
+	assert.Equal(t, []int{1, 2}, appliedVersions(t, db))
+}
+
+func TestRunMigrations_AppliesOnlyNewMigrations(t *testing.T) {
+	// Arrange
+	db := openMigrationTestDB(t)
+	first := Migration{Version: 1, Name: "first", Up: func(tx *gorm.DB) error { return nil }}
+	assert.NoError(t, RunMigrations(db, []Migration{first}))
+	ran := 0
+	second := Migration{Version: 2, Name: "second", Up: func(tx *gorm.DB) error {
+		ran++
+		return nil
+	}}
+
+	// Act
+	err := RunMigrations(db, []Migration{first, second})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, []int{1, 2}, appliedVersions(t, db))
+}
+
+func TestRunMigrations_FailureRollsBack(t *testing.T) {
+	// Arrange
+	db := openMigrationTestDB(t)
+	failure := errors.New("boom")
+	migrations := []Migration{
+		{Version: 1, Name: "first", Up: func(tx *gorm.DB) error { return nil }},
+		{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(`CREATE TABLE partial (id INTEGER)`).Error; err != nil {
+				return err
+			}
+			return failure
+		}},
+		{Version: 3, Name: "after", Up: func(tx *gorm.DB) error { return nil }},
+	}
+
+	// Act
+	err := RunMigrations(db, migrations)
+
+	// Assert
+	assert.ErrorIs(t, err, failure)
+	assert.EqualError(t, err, "migration 2 (broken): boom")
+	assert.Equal(t, []int{1}, appliedVersions(t, db))
+	assert.False(t, db.Migrator().HasTable("partial"), "the failed migration's changes are rolled back")
+}
+
+func TestRunMigrations_DuplicateVersion(t *testing.T) {
+	// Arrange
+	db := openMigrationTestDB(t)
+	noop := func(tx *gorm.DB) error { return nil }
+
+	// Act
+	err := RunMigrations(db, []Migration{{Version: 1, Up: noop}, {Version: 1, Up: noop}})
+
+	// Assert
+	assert.EqualError(t, err, "duplicate migration version 1")
+}
+
+func TestNewSQLiteRepository_RecordsMigrations(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "test_schema.db")
+	repo, err := NewSQLiteRepository(path, cache.NewNamespaceLRU(100))
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close())
+
+	// Act
+	reopened, err := NewSQLiteRepository(path, cache.NewNamespaceLRU(100))
+
+	// Assert
+	assert.NoError(t, err)
+	defer reopened.Close()
+	var versions []int
+	for _, migration := range schemaMigrations(sqliteDialect) {
+		versions = append(versions, migration.Version)
+	}
+	assert.Equal(t, versions, appliedVersions(t, reopened.db))
+}
@@ -0,0 +1,2030 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// Repository is a shortener.Repository backed by a relational database
+type Repository interface {
+	shortener.Repository
+	// Close releases the database connection pool
+	Close() error
+
+	incrementVisitsBatch(ctx context.Context, visits map[string]visitBatch) error
+}
+
+// visitBatch is the number of visits queued for a short code and the time of the latest one
+type visitBatch struct {
+	count        uint
+	lastAccessed time.Time
+}
+
+// gormRepository implements shortener.Repository with GORM. The SQLite and MySQL
+// repositories share it and differ only in how the database is opened.
+type gormRepository struct {
+	db      *gorm.DB
+	cache   cache.Cache
+	dialect dialect
+	// queryTimeout bounds each operation so a slow query cannot hold a request forever
+	queryTimeout time.Duration
+}
+
+// DefaultQueryTimeout is how long a repository operation may run before it is cancelled
+const DefaultQueryTimeout = 5 * time.Second
+
+// withQueryTimeout derives a context that expires after the repository's query timeout.
+// FindAll is not bounded, since it streams every URL and its duration grows with the table.
+func (r *gormRepository) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+// dialect holds the SQL that differs between the supported databases
+type dialect struct {
+	// system identifies the database in trace spans
+	system attribute.KeyValue
+	// insertIgnore begins an INSERT that skips rows violating a unique constraint
+	insertIgnore string
+	// likeEscape declares backslash as the escape character of a LIKE pattern
+	likeEscape string
+	// longURLIndex creates the index used to deduplicate long URLs
+	longURLIndex string
+	// activeShortCodeIndex creates an index on the short codes of URLs that are not deleted,
+	// or is empty when the database has no partial indexes
+	activeShortCodeIndex string
+	// clickDay formats clicked_at as a YYYY-MM-DD date
+	clickDay string
+	// foldedShortCode is the short_code column as compared by the unique index that keeps
+	// short codes unique regardless of case; it is compared against lowercase codes
+	foldedShortCode string
+	// foldedShortCodeIndex creates that index, or is empty when the column's collation
+	// already makes the unique short code index ignore case
+	foldedShortCodeIndex string
+	// isUniqueViolation reports whether err comes from a write violating a unique index
+	isUniqueViolation func(err error) bool
+}
+
+// Names of the indexes created by hand in schemaMigrations
+const (
+	longURLIndexName         = "idx_url_models_long_url"
+	createdAtIndexName       = "idx_url_models_created_at"
+	activeShortCodeIndexName = "idx_url_models_active_short_code"
+	foldedShortCodeIndexName = "idx_url_models_folded_short_code"
+)
+
+// URLModel is the GORM model for URL entity
+type URLModel struct {
+	ID             uint   `gorm:"primaryKey"`
+	LongURL        string `gorm:"size:2048;not null"`
+	ShortCode      string `gorm:"size:191;uniqueIndex;not null"`
+	CreatedAt      time.Time
+	Visits         uint
+	Password       string
+	IsProtected    bool
+	RedirectCode   int `gorm:"not null;default:302"`
+	MaxVisits      *uint
+	OwnerID        uint       `gorm:"not null;default:0;index"`
+	ExpiresAt      *time.Time `gorm:"index"`
+	UTMSource      string     `gorm:"column:utm_source;size:255;not null;default:''"`
+	UTMCampaign    string     `gorm:"column:utm_campaign;size:255;not null;default:''"`
+	UTMMedium      string     `gorm:"column:utm_medium;size:255;not null;default:''"`
+	LastAccessedAt *time.Time
+	AlternateURL   string `gorm:"size:2048;not null;default:''"`
+	CanaryPercent  uint8  `gorm:"not null;default:0"`
+	// GeoRules holds the URL's geo rules encoded as JSON, or is empty when it has none
+	GeoRules            string `gorm:"size:4096;not null;default:''"`
+	OwnerTokenHash      string `gorm:"size:255;not null;default:''"`
+	OwnerTokenExpiresAt *time.Time
+	ActiveFrom          *time.Time
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
+}
+
+// urlColumns is the column list selected for URL lookups, matching URLModel
+const urlColumns = `id, long_url, short_code, created_at, visits, password, is_protected, redirect_code, max_visits, owner_id, expires_at, utm_source, utm_campaign, utm_medium, last_accessed_at, alternate_url, canary_percent, geo_rules, owner_token_hash, owner_token_expires_at, active_from`
+
+// urlInsertColumns are the columns written when a URL is stored
+const urlInsertColumns = `long_url, short_code, created_at, visits, password, is_protected, redirect_code, max_visits, owner_id, expires_at, utm_source, utm_campaign, utm_medium, last_accessed_at, alternate_url, canary_percent, geo_rules, owner_token_hash, owner_token_expires_at, active_from`
+
+// urlInsertRow holds one placeholder per column of urlInsertColumns
+const urlInsertRow = `(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// bulkStoreChunkSize is the number of rows inserted by each statement of a bulk store,
+// keeping the placeholders well below the limits of SQLite and MySQL
+const bulkStoreChunkSize = 500
+
+// bulkStoreSavePoint marks the start of a chunk insert in BulkStore
+const bulkStoreSavePoint = "bulk_store_chunk"
+
+// Lookups of a single live URL; kept as constants so tests can check their query plans
+const (
+	findByShortCodeQuery = `SELECT ` + urlColumns + ` FROM url_models WHERE short_code = ? AND deleted_at IS NULL LIMIT 1`
+	findByLongURLQuery   = `SELECT ` + urlColumns + ` FROM url_models WHERE long_url = ? AND deleted_at IS NULL LIMIT 1`
+)
+
+// toDomain converts a URLModel into the shortener domain model
+func (m URLModel) toDomain() *shortener.URL {
+	return &shortener.URL{
+		ID:                  m.ID,
+		LongURL:             m.LongURL,
+		ShortCode:           m.ShortCode,
+		CreatedAt:           m.CreatedAt,
+		Visits:              m.Visits,
+		Password:            m.Password,
+		IsProtected:         m.IsProtected,
+		RedirectCode:        m.RedirectCode,
+		MaxVisits:           m.MaxVisits,
+		OwnerID:             m.OwnerID,
+		ExpiresAt:           m.ExpiresAt,
+		UTMSource:           m.UTMSource,
+		UTMCampaign:         m.UTMCampaign,
+		UTMMedium:           m.UTMMedium,
+		LastAccessedAt:      m.LastAccessedAt,
+		AlternateURL:        m.AlternateURL,
+		CanaryPercent:       m.CanaryPercent,
+		GeoRules:            decodeGeoRules(m.GeoRules),
+		OwnerTokenHash:      m.OwnerTokenHash,
+		OwnerTokenExpiresAt: m.OwnerTokenExpiresAt,
+		ActiveFrom:          m.ActiveFrom,
+	}
+}
+
+// encodeGeoRules encodes rules for URLModel.GeoRules
+func encodeGeoRules(rules []shortener.GeoRule) string {
+	if len(rules) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// decodeGeoRules decodes URLModel.GeoRules. Rules that cannot be decoded are
+// dropped, so the URL falls back to its usual destination.
+func decodeGeoRules(encoded string) []shortener.GeoRule {
+	if encoded == "" {
+		return nil
+	}
+	var rules []shortener.GeoRule
+	if err := json.Unmarshal([]byte(encoded), &rules); err != nil {
+		return nil
+	}
+	return rules
+}
+
+// UserModel is the GORM model for User entity
+type UserModel struct {
+	ID           uint   `gorm:"primaryKey"`
+	Username     string `gorm:"size:191;uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+	Role         string `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+// toDomain converts a UserModel into the shortener domain model
+func (m UserModel) toDomain() *shortener.User {
+	return &shortener.User{
+		ID:           m.ID,
+		Username:     m.Username,
+		PasswordHash: m.PasswordHash,
+		Role:         shortener.Role(m.Role),
+		CreatedAt:    m.CreatedAt,
+	}
+}
+
+// WebhookModel is the GORM model for Webhook entity
+type WebhookModel struct {
+	ID        uint   `gorm:"primaryKey"`
+	URL       string `gorm:"not null"`
+	ShortCode string `gorm:"size:191;index;not null"`
+	Secret    string `gorm:"not null"`
+	// Events holds the subscribed events separated by commas
+	Events    string `gorm:"not null"`
+	OwnerID   uint   `gorm:"not null;default:0"`
+	CreatedAt time.Time
+}
+
+// toDomain converts a WebhookModel into the shortener domain model
+func (m WebhookModel) toDomain() shortener.Webhook {
+	return shortener.Webhook{
+		ID:        m.ID,
+		URL:       m.URL,
+		ShortCode: m.ShortCode,
+		Secret:    m.Secret,
+		Events:    strings.Split(m.Events, ","),
+		OwnerID:   m.OwnerID,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// TagModel is the GORM model for Tag entity
+type TagModel struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:64;uniqueIndex;not null"`
+}
+
+// URLTagModel is the join table linking URLs to their tags
+type URLTagModel struct {
+	URLID uint `gorm:"primaryKey"`
+	TagID uint `gorm:"primaryKey;index"`
+}
+
+// TableName stores URL tags in url_tags
+func (URLTagModel) TableName() string {
+	return "url_tags"
+}
+
+// AuditModel is the GORM model for AuditEntry entity
+type AuditModel struct {
+	ID            uint   `gorm:"primaryKey"`
+	ActorUsername string `gorm:"not null"`
+	Action        string `gorm:"not null"`
+	ShortCode     string `gorm:"size:191;index;not null"`
+	Before        string
+	After         string
+	OccurredAt    time.Time `gorm:"not null"`
+}
+
+// toDomain converts an AuditModel into the shortener domain model
+func (m AuditModel) toDomain() shortener.AuditEntry {
+	return shortener.AuditEntry{
+		ID:            m.ID,
+		ActorUsername: m.ActorUsername,
+		Action:        m.Action,
+		ShortCode:     m.ShortCode,
+		Before:        m.Before,
+		After:         m.After,
+		OccurredAt:    m.OccurredAt,
+	}
+}
+
+// ClickModel is the GORM model for a click event
+type ClickModel struct {
+	ID         uint      `gorm:"primaryKey"`
+	ShortCode  string    `gorm:"size:191;index;not null"`
+	ClickedAt  time.Time `gorm:"index"`
+	Referer    string
+	IPHash     string `gorm:"column:ip_hash;size:64;not null;default:''"`
+	UserAgent  string `gorm:"size:512;not null;default:''"`
+	DeviceType string `gorm:"size:16;not null;default:''"`
+}
+
+// clickColumns is the column list selected for click event lookups, matching ClickModel
+const clickColumns = `id, short_code, clicked_at, referer, ip_hash, user_agent, device_type`
+
+// toDomain converts the model to a click event
+func (m ClickModel) toDomain() shortener.ClickEvent {
+	return shortener.ClickEvent{
+		ShortCode:  m.ShortCode,
+		ClickedAt:  m.ClickedAt,
+		Referer:    m.Referer,
+		IPHash:     m.IPHash,
+		UserAgent:  m.UserAgent,
+		DeviceType: m.DeviceType,
+	}
+}
+
+// GormLogger implements GORM's logger.Interface
+type GormLogger struct{}
+
+// LogMode implements the log.Interface method
+func (l *GormLogger) LogMode(level gormLogger.LogLevel) gormLogger.Interface {
+	return l
+}
+
+// Info logs info messages
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	appLogger.CtxInfo(ctx, msg, appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDB,
+		Data: map[string]interface{}{
+			constant.DataData: data,
+		},
+	})
+}
+
+// Warn logs warn messages
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	appLogger.CtxWarn(ctx, msg, appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDB,
+		Data: map[string]interface{}{
+			constant.DataData: data,
+		},
+	})
+}
+
+// Error logs error messages
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	appLogger.CtxError(ctx, msg, appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDB,
+		Error: &appLogger.CustomError{
+			Code:    constant.ErrCodeDBGeneral,
+			Message: msg,
+			Type:    constant.ErrTypeDB,
+		},
+		Data: map[string]interface{}{
+			constant.DataData: data,
+		},
+	})
+}
+
+// Trace logs SQL operations
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+
+	if err != nil {
+		appLogger.CtxError(ctx, "SQL error", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxDB,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBGeneral,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataElapsed: elapsed.String(),
+				constant.DataRows:    rows,
+				constant.DataSQL:     sql,
+			},
+		})
+		return
+	}
+
+	// Only log SQL queries if in debug mode
+	appLogger.CtxDebug(ctx, "SQL query", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDB,
+		Data: map[string]interface{}{
+			constant.DataElapsed: elapsed.String(),
+			constant.DataRows:    rows,
+			constant.DataSQL:     sql,
+		},
+	})
+}
+
+// openRepository opens a database through dialector, migrates the schema and registers tracing.
+// logData describes the database in log entries and must not contain credentials.
+func openRepository(dialector gorm.Dialector, d dialect, cacheObj cache.Cache, logData map[string]interface{}) (*gormRepository, error) {
+	ctx := appLogger.NewRequestContext()
+
+	appLogger.CtxDebug(ctx, "Opening database", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDB,
+		Data:            logData,
+	})
+
+	dbLogger := &GormLogger{}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: dbLogger,
+	})
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to open database", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxDB,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBOpen,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: logData,
+		})
+		return nil, err
+	}
+
+	// Apply the schema migrations that have not run yet
+	if err := RunMigrations(db, schemaMigrations(d)); err != nil {
+		appLogger.CtxError(ctx, "Failed to migrate database schema", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxDB,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBMigrate,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return nil, err
+	}
+
+	if err := registerTracing(db, d.system); err != nil {
+		appLogger.CtxError(ctx, "Failed to register database tracing", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxDB,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBTracing,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return nil, err
+	}
+
+	appLogger.CtxInfo(ctx, "Database initialized successfully", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDB,
+		Data:            logData,
+	})
+
+	return &gormRepository{db: db, cache: cacheObj, dialect: d, queryTimeout: DefaultQueryTimeout}, nil
+}
+
+// Store persists a URL to the database
+func (r *gormRepository) Store(ctx context.Context, url *shortener.URL) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	// Check if shortcode already exists
+	var count int64
+	err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM url_models WHERE `+r.dialect.foldedShortCode+` = ?`, strings.ToLower(url.ShortCode)).Count(&count).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Error checking for existing short code", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxStore,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBCheckExists,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: url.ShortCode,
+			},
+		})
+		return err
+	}
+
+	if count > 0 {
+		appLogger.CtxWarn(ctx, "Short code already exists", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxStore,
+			Data: map[string]interface{}{
+				constant.DataShortCode: url.ShortCode,
+			},
+		})
+		return shortener.ErrShortCodeExists
+	}
+
+	model := URLModel{
+		LongURL:             url.LongURL,
+		ShortCode:           url.ShortCode,
+		CreatedAt:           url.CreatedAt,
+		Visits:              url.Visits,
+		Password:            url.Password,
+		IsProtected:         url.IsProtected,
+		RedirectCode:        url.RedirectCode,
+		MaxVisits:           url.MaxVisits,
+		OwnerID:             url.OwnerID,
+		ExpiresAt:           url.ExpiresAt,
+		UTMSource:           url.UTMSource,
+		UTMCampaign:         url.UTMCampaign,
+		UTMMedium:           url.UTMMedium,
+		LastAccessedAt:      url.LastAccessedAt,
+		AlternateURL:        url.AlternateURL,
+		CanaryPercent:       url.CanaryPercent,
+		GeoRules:            encodeGeoRules(url.GeoRules),
+		OwnerTokenHash:      url.OwnerTokenHash,
+		OwnerTokenExpiresAt: url.OwnerTokenExpiresAt,
+		ActiveFrom:          url.ActiveFrom,
+	}
+
+	result := r.db.WithContext(ctx).Exec(`INSERT INTO url_models (`+urlInsertColumns+`) VALUES `+urlInsertRow,
+		model.LongURL, model.ShortCode, model.CreatedAt, model.Visits, model.Password, model.IsProtected, model.RedirectCode, model.MaxVisits, model.OwnerID, model.ExpiresAt, model.UTMSource, model.UTMCampaign, model.UTMMedium, model.LastAccessedAt, model.AlternateURL, model.CanaryPercent, model.GeoRules, model.OwnerTokenHash, model.OwnerTokenExpiresAt, model.ActiveFrom)
+
+	// Another writer may take the code between the check above and the insert
+	if result.Error != nil && r.dialect.isUniqueViolation(result.Error) {
+		appLogger.CtxWarn(ctx, "Short code already exists", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxStore,
+			Data: map[string]interface{}{
+				constant.DataShortCode: url.ShortCode,
+			},
+		})
+		return shortener.ErrShortCodeExists
+	}
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to insert URL", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxStore,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBInsert,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: url.ShortCode,
+				constant.DataLongURL:   url.LongURL,
+			},
+		})
+		return result.Error
+	}
+
+	url.ID = model.ID
+
+	appLogger.CtxInfo(ctx, "URL stored successfully", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxStore,
+		Data: map[string]interface{}{
+			constant.DataShortCode: url.ShortCode,
+			constant.DataLongURL:   url.LongURL,
+		},
+	})
+
+	return nil
+}
+
+// WithTransaction runs fn against a repository whose queries all go through one database
+// transaction, committing when fn returns nil and rolling back when it returns an error.
+// fn must not use the outer repository, whose queries may wait for the connection the
+// transaction holds.
+func (r *gormRepository) WithTransaction(ctx context.Context, fn func(tx shortener.Repository) error) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormRepository{db: tx, cache: r.cache, dialect: r.dialect, queryTimeout: r.queryTimeout})
+	})
+}
+
+// BulkStore inserts urls in a single transaction, one multi-row statement per chunk of
+// bulkStoreChunkSize. URLs whose short code is already taken in any case, including by
+// an earlier URL of the batch, are skipped and returned as errors. A code taken by a concurrent
+// writer between the check and the insert is counted in Skipped but not itemized.
+func (r *gormRepository) BulkStore(ctx context.Context, urls []*shortener.URL) (*shortener.BulkStoreResult, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := &shortener.BulkStoreResult{Errors: []shortener.BulkStoreError{}}
+	seen := make(map[string]bool, len(urls))
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(urls); start += bulkStoreChunkSize {
+			chunk := urls[start:min(start+bulkStoreChunkSize, len(urls))]
+
+			codes := make([]string, len(chunk))
+			for i, url := range chunk {
+				codes[i] = strings.ToLower(url.ShortCode)
+			}
+			var existing []string
+			if err := tx.Raw(`SELECT short_code FROM url_models WHERE `+r.dialect.foldedShortCode+` IN ?`, codes).Scan(&existing).Error; err != nil {
+				return err
+			}
+			for _, code := range existing {
+				seen[strings.ToLower(code)] = true
+			}
+
+			// indexes holds the chunk positions of the rows to insert
+			indexes := make([]int, 0, len(chunk))
+			for i, url := range chunk {
+				if seen[codes[i]] {
+					result.Errors = append(result.Errors, shortener.BulkStoreError{Index: start + i, ShortCode: url.ShortCode, Reason: constant.ErrShortCodeExists})
+					continue
+				}
+				seen[codes[i]] = true
+				indexes = append(indexes, i)
+			}
+			if len(indexes) == 0 {
+				continue
+			}
+
+			// A code taken since the lookup is skipped by the insert without saying which
+			// row it was, so the chunk is then inserted again a row at a time to find out
+			if err := tx.SavePoint(bulkStoreSavePoint).Error; err != nil {
+				return err
+			}
+			inserted, err := insertURLRows(tx, r.dialect, chunk, indexes)
+			if err != nil {
+				return err
+			}
+			if inserted == int64(len(indexes)) {
+				result.Stored += len(indexes)
+				continue
+			}
+			if err := tx.RollbackTo(bulkStoreSavePoint).Error; err != nil {
+				return err
+			}
+			for _, i := range indexes {
+				inserted, err := insertURLRows(tx, r.dialect, chunk, []int{i})
+				if err != nil {
+					return err
+				}
+				if inserted == 0 {
+					result.Errors = append(result.Errors, shortener.BulkStoreError{Index: start + i, ShortCode: chunk[i].ShortCode, Reason: constant.ErrShortCodeExists})
+					continue
+				}
+				result.Stored++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to bulk insert URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxBulkStore,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBBulkInsert,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataCount: len(urls),
+			},
+		})
+		return nil, err
+	}
+	result.Skipped = len(urls) - result.Stored
+
+	appLogger.CtxInfo(ctx, "URLs bulk stored", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxBulkStore,
+		Data: map[string]interface{}{
+			constant.DataCount:  result.Stored,
+			constant.DataErrors: len(result.Errors),
+		},
+	})
+
+	return result, nil
+}
+
+// insertURLRows inserts the urls at indexes, skipping those whose short code is taken,
+// and returns how many were inserted
+func insertURLRows(tx *gorm.DB, dialect dialect, urls []*shortener.URL, indexes []int) (int64, error) {
+	rows := make([]string, 0, len(indexes))
+	args := make([]interface{}, 0, len(indexes)*strings.Count(urlInsertRow, "?"))
+	for _, i := range indexes {
+		url := urls[i]
+		rows = append(rows, urlInsertRow)
+		args = append(args, url.LongURL, url.ShortCode, url.CreatedAt, url.Visits, url.Password, url.IsProtected, url.RedirectCode, url.MaxVisits, url.OwnerID, url.ExpiresAt, url.UTMSource, url.UTMCampaign, url.UTMMedium, url.LastAccessedAt, url.AlternateURL, url.CanaryPercent, encodeGeoRules(url.GeoRules), url.OwnerTokenHash, url.OwnerTokenExpiresAt, url.ActiveFrom)
+	}
+
+	inserted := tx.Exec(dialect.insertIgnore+` INTO url_models (`+urlInsertColumns+`) VALUES `+strings.Join(rows, ", "), args...)
+	return inserted.RowsAffected, inserted.Error
+}
+
+// FindByShortCode retrieves a URL by its short code
+func (r *gormRepository) FindByShortCode(ctx context.Context, shortCode string) (*shortener.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var model URLModel
+
+	appLogger.CtxDebug(ctx, "Looking up short code", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxFindByShortCode,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	rows, err := r.db.WithContext(ctx).Raw(findByShortCodeQuery, shortCode).Rows()
+	if err != nil {
+		appLogger.CtxError(ctx, "Database error while looking up short code", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindByShortCode,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBLookup,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		appLogger.CtxInfo(ctx, "Short code not found", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindByShortCode,
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, shortener.ErrShortCodeNotFound
+	}
+
+	if err := r.db.ScanRows(rows, &model); err != nil {
+		appLogger.CtxError(ctx, "Failed to scan database rows", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindByShortCode,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBScanRows,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		appLogger.CtxError(ctx, "Row iteration error", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindByShortCode,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBRowIterate,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	appLogger.CtxDebug(ctx, "Short code found", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxFindByShortCode,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataLongURL:   model.LongURL,
+			constant.DataVisits:    model.Visits,
+		},
+	})
+
+	return model.toDomain(), nil
+}
+
+// FindByLongURL retrieves the first URL stored for a long URL
+func (r *gormRepository) FindByLongURL(ctx context.Context, longURL string) (*shortener.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []URLModel
+
+	err := r.db.WithContext(ctx).Raw(findByLongURLQuery, longURL).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Database error while looking up long URL", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindByLongURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBLookup,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataLongURL: longURL,
+			},
+		})
+		return nil, err
+	}
+
+	if len(models) == 0 {
+		appLogger.CtxDebug(ctx, "Long URL not found", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindByLongURL,
+			Data: map[string]interface{}{
+				constant.DataLongURL: longURL,
+			},
+		})
+		return nil, shortener.ErrLongURLNotFound
+	}
+
+	return models[0].toDomain(), nil
+}
+
+// IncrementVisits increments the visit count for a URL
+func (r *gormRepository) IncrementVisits(ctx context.Context, shortCode string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Exec(`UPDATE url_models SET visits = visits + 1 WHERE short_code = ?`, shortCode)
+
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to increment visit count", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxIncrementVisits,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBIncrement,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		appLogger.CtxWarn(ctx, "No rows affected when incrementing visits", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxIncrementVisits,
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+	} else {
+		appLogger.CtxDebug(ctx, "Visit count incremented", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxIncrementVisits,
+			Data: map[string]interface{}{
+				constant.DataShortCode:    shortCode,
+				constant.DataRowsAffected: result.RowsAffected,
+			},
+		})
+		r.touchCachedURL(shortCode, 1, time.Time{})
+	}
+
+	return nil
+}
+
+// IncrementVisitsWithinLimit increments the visit count for a URL while it is below max_visits.
+// The limit is checked by the UPDATE itself, so concurrent visits cannot go over it.
+func (r *gormRepository) IncrementVisitsWithinLimit(ctx context.Context, shortCode string) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Exec(`UPDATE url_models SET visits = visits + 1 WHERE short_code = ? AND visits < max_visits`, shortCode)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to increment visit count", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxIncrementVisits,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBIncrement,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		appLogger.CtxDebug(ctx, "Visit limit reached, visit not counted", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxIncrementVisits,
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return false, nil
+	}
+
+	r.touchCachedURL(shortCode, 1, time.Time{})
+	return true, nil
+}
+
+// UpdateLastAccessed moves the last access time of a URL forward to at
+func (r *gormRepository) UpdateLastAccessed(ctx context.Context, shortCode string, at time.Time) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	at = at.UTC()
+	result := r.db.WithContext(ctx).Exec(`UPDATE url_models SET last_accessed_at = ? WHERE short_code = ? AND (last_accessed_at IS NULL OR last_accessed_at < ?)`,
+		at, shortCode, at)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to update last access time", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLastAccess,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBUpdateLastAccessed,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return result.Error
+	}
+
+	r.touchCachedURL(shortCode, 0, at)
+	return nil
+}
+
+// touchCachedURL adds visits to the cached copy of a URL and moves its last access time forward to at.
+// Callers may still hold the cached URL, so a copy is updated and cached in its place.
+func (r *gormRepository) touchCachedURL(shortCode string, visits uint, at time.Time) {
+	urlObj, found := r.cache.Get(constant.ShortURLNamespace, shortCode)
+	if !found {
+		return
+	}
+	cached, ok := urlObj.(*shortener.URL)
+	if !ok {
+		return
+	}
+	url := *cached
+	url.Visits += visits
+	if !at.IsZero() && (url.LastAccessedAt == nil || url.LastAccessedAt.Before(at)) {
+		url.LastAccessedAt = &at
+	}
+	r.cache.Set(constant.ShortURLNamespace, shortCode, &url)
+}
+
+// UpdateLongURL updates the long URL for an existing short code
+func (r *gormRepository) UpdateLongURL(ctx context.Context, shortCode string, newLongURL string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	appLogger.CtxDebug(ctx, "Updating long URL in database", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxUpdateLongURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataLongURL:   newLongURL,
+		},
+	})
+
+	// Check if shortcode exists
+	var count int64
+	err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM url_models WHERE short_code = ? AND deleted_at IS NULL`, shortCode).Count(&count).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Error checking for existing short code", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLongURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBCheckExists,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return err
+	}
+
+	if count == 0 {
+		appLogger.CtxWarn(ctx, "Short code not found", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLongURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeShortCodeNotFound,
+				Message: constant.ErrShortCodeNotFound,
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return shortener.ErrShortCodeNotFound
+	}
+
+	// Update the long URL
+	result := r.db.WithContext(ctx).Exec(`UPDATE url_models SET long_url = ? WHERE short_code = ? AND deleted_at IS NULL`, newLongURL, shortCode)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to update long URL in database", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLongURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeUpdateFailure,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataLongURL:   newLongURL,
+			},
+		})
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		appLogger.CtxWarn(ctx, "No rows updated", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateLongURL,
+			Data: map[string]interface{}{
+				constant.DataShortCode:    shortCode,
+				constant.DataRowsAffected: 0,
+			},
+		})
+		return shortener.ErrShortCodeNotFound
+	}
+
+	appLogger.CtxInfo(ctx, "Long URL updated successfully in database", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxUpdateLongURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode:    shortCode,
+			constant.DataLongURL:      newLongURL,
+			constant.DataRowsAffected: result.RowsAffected,
+		},
+	})
+
+	return nil
+}
+
+// UpdateCanary sets the alternate destination of a short URL and the percentage of redirects sent to it
+func (r *gormRepository) UpdateCanary(ctx context.Context, shortCode, alternateURL string, percent uint8) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Exec(`UPDATE url_models SET alternate_url = ?, canary_percent = ? WHERE short_code = ? AND deleted_at IS NULL`,
+		alternateURL, percent, shortCode)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to update canary split", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxUpdateCanary,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBUpdateCanary,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return shortener.ErrShortCodeNotFound
+	}
+	return nil
+}
+
+// RenameShortCode moves the URL stored under oldCode to newCode in one transaction.
+// The URL is copied to a new row and the old row soft-deleted, so the old code stays
+// taken; tags, webhooks and click history follow the URL to its new code.
+func (r *gormRepository) RenameShortCode(ctx context.Context, oldCode, newCode string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var model URLModel
+		if err := tx.Raw(`SELECT `+urlColumns+` FROM url_models WHERE short_code = ? AND deleted_at IS NULL`, oldCode).Scan(&model).Error; err != nil {
+			return err
+		}
+		if model.ID == 0 {
+			return shortener.ErrShortCodeNotFound
+		}
+
+		// Soft-deleted rows still hold their code in the unique index
+		var count int64
+		if err := tx.Raw(`SELECT COUNT(*) FROM url_models WHERE `+r.dialect.foldedShortCode+` = ?`, strings.ToLower(newCode)).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return shortener.ErrShortCodeExists
+		}
+
+		if err := tx.Exec(`INSERT INTO url_models (`+urlInsertColumns+`) VALUES `+urlInsertRow,
+			model.LongURL, newCode, model.CreatedAt, model.Visits, model.Password, model.IsProtected, model.RedirectCode, model.MaxVisits, model.OwnerID, model.ExpiresAt, model.UTMSource, model.UTMCampaign, model.UTMMedium, model.LastAccessedAt, model.AlternateURL, model.CanaryPercent, model.GeoRules, model.OwnerTokenHash, model.OwnerTokenExpiresAt, model.ActiveFrom).Error; err != nil {
+			if r.dialect.isUniqueViolation(err) {
+				return shortener.ErrShortCodeExists
+			}
+			return err
+		}
+		var newID uint
+		if err := tx.Raw(`SELECT id FROM url_models WHERE short_code = ?`, newCode).Scan(&newID).Error; err != nil {
+			return err
+		}
+
+		statements := []struct {
+			sql  string
+			args []interface{}
+		}{
+			{`UPDATE url_models SET deleted_at = ? WHERE id = ?`, []interface{}{time.Now(), model.ID}},
+			{`INSERT INTO url_tags (url_id, tag_id) SELECT ?, tag_id FROM url_tags WHERE url_id = ?`, []interface{}{newID, model.ID}},
+			{`UPDATE webhook_models SET short_code = ? WHERE short_code = ?`, []interface{}{newCode, oldCode}},
+			{`UPDATE click_models SET short_code = ? WHERE short_code = ?`, []interface{}{newCode, oldCode}},
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement.sql, statement.args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, shortener.ErrShortCodeNotFound) || errors.Is(err, shortener.ErrShortCodeExists) {
+			return err
+		}
+		appLogger.CtxError(ctx, "Failed to rename short code in database", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxRenameShortCode,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBRename,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode:    oldCode,
+				constant.DataNewShortCode: newCode,
+			},
+		})
+		return err
+	}
+
+	appLogger.CtxInfo(ctx, "Short code renamed in database", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxRenameShortCode,
+		Data: map[string]interface{}{
+			constant.DataShortCode:    oldCode,
+			constant.DataNewShortCode: newCode,
+		},
+	})
+
+	return nil
+}
+
+// Delete soft-deletes the URL for a short code so it can no longer be resolved
+func (r *gormRepository) Delete(ctx context.Context, shortCode string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	appLogger.CtxDebug(ctx, "Deleting URL from database", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDeleteURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	result := r.db.WithContext(ctx).Exec(`UPDATE url_models SET deleted_at = ? WHERE short_code = ? AND deleted_at IS NULL`, time.Now(), shortCode)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to delete URL from database", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxDeleteURL,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBDelete,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return shortener.ErrShortCodeNotFound
+	}
+
+	appLogger.CtxInfo(ctx, "URL deleted from database", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxDeleteURL,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+		},
+	})
+
+	return nil
+}
+
+// BulkDelete soft-deletes every short code in a single statement. Codes that do not
+// exist or are already deleted are returned as errors rather than failing the batch.
+func (r *gormRepository) BulkDelete(ctx context.Context, shortCodes []string) (int, []shortener.BulkDeleteError, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var deleted int
+	var failures []shortener.BulkDeleteError
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []string
+		if err := tx.Raw(`SELECT short_code FROM url_models WHERE short_code IN ? AND deleted_at IS NULL`, shortCodes).Scan(&existing).Error; err != nil {
+			return err
+		}
+
+		result := tx.Exec(`UPDATE url_models SET deleted_at = ? WHERE short_code IN ? AND deleted_at IS NULL`, time.Now(), shortCodes)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = int(result.RowsAffected)
+
+		found := make(map[string]bool, len(existing))
+		for _, code := range existing {
+			found[code] = true
+		}
+		for _, code := range shortCodes {
+			if !found[code] {
+				failures = append(failures, shortener.BulkDeleteError{ShortCode: code, Reason: constant.ErrShortCodeNotFound})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to bulk delete URLs from database", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxBulkDeleteURLs,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBBulkDelete,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataCount: len(shortCodes),
+			},
+		})
+		return 0, nil, err
+	}
+
+	return deleted, failures, nil
+}
+
+// FindExpired returns the live URLs whose expiry is at or before now, oldest expiry first
+func (r *gormRepository) FindExpired(ctx context.Context, now time.Time) ([]*shortener.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []URLModel
+	err := r.db.WithContext(ctx).Raw(`SELECT `+urlColumns+` FROM url_models WHERE expires_at IS NOT NULL AND expires_at <= ? AND deleted_at IS NULL ORDER BY expires_at`, now).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to find expired URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindExpired,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindExpired,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return nil, err
+	}
+
+	urls := make([]*shortener.URL, len(models))
+	for i, model := range models {
+		urls[i] = model.toDomain()
+	}
+	return urls, nil
+}
+
+// FindAll streams every stored URL to fn in id order, stopping at the first error
+func (r *gormRepository) FindAll(ctx context.Context, fn func(url *shortener.URL) error) error {
+	rows, err := r.db.WithContext(ctx).Raw(`SELECT ` + urlColumns + ` FROM url_models WHERE deleted_at IS NULL ORDER BY id`).Rows()
+	if err != nil {
+		appLogger.CtxError(ctx, "Database error while listing URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindAll,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindAll,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var model URLModel
+		if err := r.db.ScanRows(rows, &model); err != nil {
+			appLogger.CtxError(ctx, "Failed to scan database rows", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxFindAll,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeDBScanRows,
+					Message: err.Error(),
+					Type:    constant.ErrTypeDB,
+				},
+			})
+			return err
+		}
+
+		if err := fn(model.toDomain()); err != nil {
+			return err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		appLogger.CtxError(ctx, "Row iteration error", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindAll,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBRowIterate,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return err
+	}
+
+	appLogger.CtxDebug(ctx, "Listed URLs", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxFindAll,
+		Data: map[string]interface{}{
+			constant.DataRows: count,
+		},
+	})
+
+	return nil
+}
+
+// likeEscaper escapes the LIKE wildcards so that a search matches them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search retrieves a page of URLs whose long URL contains query, newest first, with the total number of matches
+func (r *gormRepository) Search(ctx context.Context, query string, limit, offset int) ([]*shortener.URL, int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	logError := func(err error) {
+		appLogger.CtxError(ctx, "Database error while searching URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxSearch,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBSearch,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataQuery: query,
+			},
+		})
+	}
+
+	var total int64
+	err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM url_models WHERE long_url LIKE ? `+r.dialect.likeEscape+` AND deleted_at IS NULL`, pattern).Count(&total).Error
+	if err != nil {
+		logError(err)
+		return nil, 0, err
+	}
+
+	var models []URLModel
+	err = r.db.WithContext(ctx).Raw(`SELECT `+urlColumns+` FROM url_models WHERE long_url LIKE ? `+r.dialect.likeEscape+` AND deleted_at IS NULL ORDER BY id DESC LIMIT ? OFFSET ?`,
+		pattern, limit, offset).Scan(&models).Error
+	if err != nil {
+		logError(err)
+		return nil, 0, err
+	}
+
+	urls := make([]*shortener.URL, 0, len(models))
+	for _, model := range models {
+		urls = append(urls, model.toDomain())
+	}
+	return urls, int(total), nil
+}
+
+// FindCreatedAfter retrieves a page of the live URLs created after since, newest first
+func (r *gormRepository) FindCreatedAfter(ctx context.Context, since time.Time, limit, offset int) ([]*shortener.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []URLModel
+	err := r.db.WithContext(ctx).Raw(`SELECT `+urlColumns+` FROM url_models WHERE created_at > ? AND deleted_at IS NULL ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`,
+		since, limit, offset).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Database error while listing recent URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindCreatedAfter,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindCreatedAfter,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataSince: since,
+			},
+		})
+		return nil, err
+	}
+
+	urls := make([]*shortener.URL, 0, len(models))
+	for _, model := range models {
+		urls = append(urls, model.toDomain())
+	}
+	return urls, nil
+}
+
+// FindTopURLs retrieves up to limit live URLs with the most visits, most visited first
+func (r *gormRepository) FindTopURLs(ctx context.Context, limit int) ([]*shortener.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []URLModel
+	err := r.db.WithContext(ctx).Raw(`SELECT `+urlColumns+` FROM url_models WHERE deleted_at IS NULL ORDER BY visits DESC, id ASC LIMIT ?`,
+		limit).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Database error while listing most visited URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindTopURLs,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindTopURLs,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataLimit: limit,
+			},
+		})
+		return nil, err
+	}
+
+	urls := make([]*shortener.URL, 0, len(models))
+	for _, model := range models {
+		urls = append(urls, model.toDomain())
+	}
+	return urls, nil
+}
+
+// trendingModel is a URL row together with its click count from FindTrending
+type trendingModel struct {
+	URLModel
+	RecentVisits uint
+}
+
+// FindTrending retrieves up to limit live URLs clicked within the past period, most recent clicks first
+func (r *gormRepository) FindTrending(ctx context.Context, period time.Duration, limit int) ([]*shortener.TrendingURL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	since := time.Now().UTC().Add(-period)
+
+	// The subquery aliases short_code so the URL columns stay unambiguous
+	var models []trendingModel
+	err := r.db.WithContext(ctx).Raw(`SELECT `+urlColumns+`, recent.recent_visits FROM url_models
+		JOIN (SELECT short_code AS click_code, COUNT(*) AS recent_visits FROM click_models WHERE clicked_at >= ? GROUP BY short_code) recent
+		ON recent.click_code = url_models.short_code
+		WHERE deleted_at IS NULL ORDER BY recent.recent_visits DESC, id ASC LIMIT ?`,
+		since, limit).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Database error while listing trending URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindTrending,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindTrending,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataSince: since,
+				constant.DataLimit: limit,
+			},
+		})
+		return nil, err
+	}
+
+	urls := make([]*shortener.TrendingURL, 0, len(models))
+	for _, model := range models {
+		urls = append(urls, &shortener.TrendingURL{URL: *model.toDomain(), RecentVisits: model.RecentVisits})
+	}
+	return urls, nil
+}
+
+// AddTag attaches tag to a short code, creating the tag when it is new. Adding a tag twice is a no-op.
+func (r *gormRepository) AddTag(ctx context.Context, shortCode, tag string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(r.dialect.insertIgnore+` INTO tag_models (name) VALUES (?)`, tag).Error; err != nil {
+			return err
+		}
+
+		result := tx.Exec(r.dialect.insertIgnore+` INTO url_tags (url_id, tag_id)
+			SELECT u.id, t.id FROM url_models u JOIN tag_models t ON t.name = ?
+			WHERE u.short_code = ? AND u.deleted_at IS NULL`, tag, shortCode)
+		return result.Error
+	})
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to add tag", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxAddTag,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBAddTag,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataTag:       tag,
+			},
+		})
+		return err
+	}
+	return nil
+}
+
+// RemoveTag detaches tag from a short code
+func (r *gormRepository) RemoveTag(ctx context.Context, shortCode, tag string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Exec(`DELETE FROM url_tags
+		WHERE url_id = (SELECT id FROM url_models WHERE short_code = ? AND deleted_at IS NULL)
+		AND tag_id = (SELECT id FROM tag_models WHERE name = ?)`, shortCode, tag)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to remove tag", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxRemoveTag,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBRemoveTag,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataTag:       tag,
+			},
+		})
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return shortener.ErrTagNotFound
+	}
+	return nil
+}
+
+// FindTags retrieves the tag names of a short code in alphabetical order
+func (r *gormRepository) FindTags(ctx context.Context, shortCode string) ([]string, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	tags := []string{}
+
+	err := r.db.WithContext(ctx).Raw(`SELECT t.name FROM tag_models t
+		JOIN url_tags ut ON ut.tag_id = t.id
+		JOIN url_models u ON u.id = ut.url_id
+		WHERE u.short_code = ? AND u.deleted_at IS NULL
+		ORDER BY t.name`, shortCode).Scan(&tags).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to look up tags", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindTags,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindTags,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListByTag retrieves a page of URLs carrying tag, newest first, with the total number of matches
+func (r *gormRepository) ListByTag(ctx context.Context, tag string, limit, offset int) ([]*shortener.URL, int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	const tagged = `FROM url_models WHERE deleted_at IS NULL AND id IN (
+		SELECT ut.url_id FROM url_tags ut JOIN tag_models t ON t.id = ut.tag_id WHERE t.name = ?)`
+	logError := func(err error) {
+		appLogger.CtxError(ctx, "Database error while listing URLs by tag", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxListByTag,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindTags,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataTag: tag,
+			},
+		})
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) `+tagged, tag).Count(&total).Error; err != nil {
+		logError(err)
+		return nil, 0, err
+	}
+
+	var models []URLModel
+	err := r.db.WithContext(ctx).Raw(`SELECT `+urlColumns+` `+tagged+` ORDER BY id DESC LIMIT ? OFFSET ?`, tag, limit, offset).Scan(&models).Error
+	if err != nil {
+		logError(err)
+		return nil, 0, err
+	}
+
+	urls := make([]*shortener.URL, 0, len(models))
+	for _, model := range models {
+		urls = append(urls, model.toDomain())
+	}
+	return urls, int(total), nil
+}
+
+// RecordClick stores a single click event for a short code
+func (r *gormRepository) RecordClick(ctx context.Context, event shortener.ClickEvent) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Exec(`INSERT INTO click_models (short_code, clicked_at, referer, ip_hash, user_agent, device_type) VALUES (?, ?, ?, ?, ?, ?)`,
+		event.ShortCode, event.ClickedAt.UTC(), event.Referer, event.IPHash, event.UserAgent, event.DeviceType)
+
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to insert click event", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxRecordClick,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBRecordClick,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: event.ShortCode,
+			},
+		})
+		return result.Error
+	}
+
+	appLogger.CtxDebug(ctx, "Click event recorded", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxRecordClick,
+		Data: map[string]interface{}{
+			constant.DataShortCode: event.ShortCode,
+		},
+	})
+
+	return nil
+}
+
+// FindClicks retrieves the click events for a short code within [from, to)
+func (r *gormRepository) FindClicks(ctx context.Context, shortCode string, from, to time.Time) ([]shortener.ClickEvent, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []ClickModel
+
+	err := r.db.WithContext(ctx).Raw(`SELECT `+clickColumns+` FROM click_models WHERE short_code = ? AND clicked_at >= ? AND clicked_at < ? ORDER BY clicked_at`,
+		shortCode, from.UTC(), to.UTC()).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to look up click events", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindClicks,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	clicks := make([]shortener.ClickEvent, 0, len(models))
+	for _, model := range models {
+		clicks = append(clicks, model.toDomain())
+	}
+
+	appLogger.CtxDebug(ctx, "Click events found", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxFindClicks,
+		Data: map[string]interface{}{
+			constant.DataShortCode: shortCode,
+			constant.DataRows:      len(clicks),
+		},
+	})
+
+	return clicks, nil
+}
+
+// FindAllClicks retrieves every click event for a short code, oldest first
+func (r *gormRepository) FindAllClicks(ctx context.Context, shortCode string) ([]shortener.ClickEvent, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []ClickModel
+	err := r.db.WithContext(ctx).Raw(`SELECT `+clickColumns+` FROM click_models WHERE short_code = ? ORDER BY clicked_at, id`, shortCode).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to look up click events", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindAllClicks,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	clicks := make([]shortener.ClickEvent, 0, len(models))
+	for _, model := range models {
+		clicks = append(clicks, model.toDomain())
+	}
+	return clicks, nil
+}
+
+// FindReferers counts the click events for a short code grouped by referer, most frequent first
+func (r *gormRepository) FindReferers(ctx context.Context, shortCode string) ([]shortener.RefererStat, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var referers []shortener.RefererStat
+
+	err := r.db.WithContext(ctx).Raw(`SELECT referer AS url, COUNT(*) AS count FROM click_models WHERE short_code = ? GROUP BY referer ORDER BY count DESC, referer`,
+		shortCode).Scan(&referers).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to group click events by referer", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindReferers,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	if referers == nil {
+		referers = []shortener.RefererStat{}
+	}
+	return referers, nil
+}
+
+// FindDeviceStats counts the click events for a short code by device type
+func (r *gormRepository) FindDeviceStats(ctx context.Context, shortCode string) (shortener.DeviceStats, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var rows []struct {
+		DeviceType string
+		Count      uint
+	}
+	err := r.db.WithContext(ctx).Raw(`SELECT device_type, COUNT(*) AS count FROM click_models WHERE short_code = ? GROUP BY device_type`,
+		shortCode).Scan(&rows).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to group click events by device type", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindDeviceStats,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return shortener.DeviceStats{}, err
+	}
+
+	var stats shortener.DeviceStats
+	for _, row := range rows {
+		stats.Add(row.DeviceType, row.Count)
+	}
+	return stats, nil
+}
+
+// FindDailyClicks counts the click events for a short code per UTC day over the last given number of days.
+// Days without clicks are omitted.
+func (r *gormRepository) FindDailyClicks(ctx context.Context, shortCode string, days int) ([]shortener.DailyCount, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var counts []shortener.DailyCount
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	err := r.db.WithContext(ctx).Raw(`SELECT `+r.dialect.clickDay+` AS date, COUNT(*) AS count FROM click_models WHERE short_code = ? AND clicked_at >= ? GROUP BY date ORDER BY date`,
+		shortCode, since).Scan(&counts).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to group click events by day", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindDailyClicks,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindClicks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+				constant.DataDays:      days,
+			},
+		})
+		return nil, err
+	}
+
+	if counts == nil {
+		counts = []shortener.DailyCount{}
+	}
+	return counts, nil
+}
+
+// CreateUser stores a new user account and sets its ID
+func (r *gormRepository) CreateUser(ctx context.Context, user *shortener.User) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	model := UserModel{
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		Role:         string(user.Role),
+		CreatedAt:    user.CreatedAt,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&model).Error; err != nil {
+		appLogger.CtxError(ctx, "Failed to insert user", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxCreateUser,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBCreateUser,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataUsername: user.Username,
+			},
+		})
+		return err
+	}
+
+	user.ID = model.ID
+	return nil
+}
+
+// FindUserByUsername retrieves a user account by its username
+func (r *gormRepository) FindUserByUsername(ctx context.Context, username string) (*shortener.User, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []UserModel
+
+	err := r.db.WithContext(ctx).Raw(`SELECT id, username, password_hash, role, created_at FROM user_models WHERE username = ? LIMIT 1`, username).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Database error while looking up user", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindUser,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindUser,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataUsername: username,
+			},
+		})
+		return nil, err
+	}
+
+	if len(models) == 0 {
+		return nil, shortener.ErrUserNotFound
+	}
+
+	return models[0].toDomain(), nil
+}
+
+// ListUsers retrieves every user account ordered by ID
+func (r *gormRepository) ListUsers(ctx context.Context) ([]shortener.User, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []UserModel
+
+	err := r.db.WithContext(ctx).Raw(`SELECT id, username, password_hash, role, created_at FROM user_models ORDER BY id`).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to list users", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindUser,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindUser,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return nil, err
+	}
+
+	users := make([]shortener.User, 0, len(models))
+	for _, model := range models {
+		users = append(users, *model.toDomain())
+	}
+	return users, nil
+}
+
+// CreateWebhook stores a new webhook and sets its ID
+func (r *gormRepository) CreateWebhook(ctx context.Context, hook *shortener.Webhook) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	model := WebhookModel{
+		URL:       hook.URL,
+		ShortCode: hook.ShortCode,
+		Secret:    hook.Secret,
+		Events:    strings.Join(hook.Events, ","),
+		OwnerID:   hook.OwnerID,
+		CreatedAt: hook.CreatedAt,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&model).Error; err != nil {
+		appLogger.CtxError(ctx, "Failed to insert webhook", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxCreateWebhook,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBCreateWebhook,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: hook.ShortCode,
+			},
+		})
+		return err
+	}
+
+	hook.ID = model.ID
+	return nil
+}
+
+// FindWebhooks retrieves the webhooks registered for a short code
+func (r *gormRepository) FindWebhooks(ctx context.Context, shortCode string) ([]shortener.Webhook, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []WebhookModel
+
+	err := r.db.WithContext(ctx).Raw(`SELECT id, url, short_code, secret, events, owner_id, created_at FROM webhook_models WHERE short_code = ? ORDER BY id`, shortCode).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to look up webhooks", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindWebhooks,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindWebhooks,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	hooks := make([]shortener.Webhook, 0, len(models))
+	for _, model := range models {
+		hooks = append(hooks, model.toDomain())
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook removes a webhook by ID
+func (r *gormRepository) DeleteWebhook(ctx context.Context, id uint) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Exec(`DELETE FROM webhook_models WHERE id = ?`, id)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to delete webhook", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxDeleteWebhook,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBDeleteWebhook,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return shortener.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// AppendAudit stores an audit entry
+func (r *gormRepository) AppendAudit(ctx context.Context, entry shortener.AuditEntry) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Exec(`INSERT INTO audit_models (actor_username, action, short_code, `+"`before`, `after`"+`, occurred_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.ActorUsername, entry.Action, entry.ShortCode, entry.Before, entry.After, entry.OccurredAt)
+	if result.Error != nil {
+		appLogger.CtxError(ctx, "Failed to insert audit entry", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxAppendAudit,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBAppendAudit,
+				Message: result.Error.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: entry.ShortCode,
+				constant.DataAction:    entry.Action,
+			},
+		})
+		return result.Error
+	}
+	return nil
+}
+
+// FindAudits retrieves the audit entries of a short code, oldest first
+func (r *gormRepository) FindAudits(ctx context.Context, shortCode string) ([]shortener.AuditEntry, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var models []AuditModel
+	err := r.db.WithContext(ctx).Raw(`SELECT id, actor_username, action, short_code, `+"`before`, `after`"+`, occurred_at FROM audit_models WHERE short_code = ? ORDER BY occurred_at, id`, shortCode).Scan(&models).Error
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to look up audit entries", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxFindAudits,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBFindAudits,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataShortCode: shortCode,
+			},
+		})
+		return nil, err
+	}
+
+	entries := make([]shortener.AuditEntry, len(models))
+	for i, model := range models {
+		entries[i] = model.toDomain()
+	}
+	return entries, nil
+}
+
+// Ping checks that the database is reachable
+func (r *gormRepository) Ping(ctx context.Context) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	sqlDB, err := r.db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		appLogger.CtxError(ctx, "Database ping failed", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxPing,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBPing,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return err
+	}
+	return nil
+}
+
+// Close closes the database connection
+func (r *gormRepository) Close() error {
+	ctx := context.Background()
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		appLogger.CtxError(ctx, "Failed to get database connection", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxClose,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBClose,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+		})
+		return err
+	}
+
+	appLogger.CtxInfo(ctx, "Closing database connection", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxClose,
+	})
+
+	return sqlDB.Close()
+}
@@ -0,0 +1,595 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// repositoryBackends opens an empty repository of each kind that must pass the shared suite
+var repositoryBackends = []struct {
+	name string
+	open func(t *testing.T) Repository
+}{
+	{name: "Memory", open: func(t *testing.T) Repository {
+		return NewMemoryRepository()
+	}},
+	{name: "SQLite", open: func(t *testing.T) Repository {
+		repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "test_suite.db"), cache.NewNoopCache())
+		if err != nil {
+			t.Fatalf("Failed to create SQLite repository: %v", err)
+		}
+		return repo
+	}},
+}
+
+// repositoryCases is the behaviour every Repository implementation shares
+var repositoryCases = []struct {
+	name string
+	run  func(t *testing.T, ctx context.Context, repo Repository)
+}{
+	{name: "Store and find", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		maxVisits := uint(5)
+		url := &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now(), RedirectCode: 301, MaxVisits: &maxVisits, OwnerID: 7,
+			UTMSource: "newsletter", UTMCampaign: "spring", UTMMedium: "email"}
+		assert.NoError(t, repo.Store(ctx, url))
+
+		found, err := repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", found.LongURL)
+		assert.Equal(t, 301, found.RedirectCode)
+		assert.Equal(t, &maxVisits, found.MaxVisits)
+		assert.Equal(t, uint(7), found.OwnerID)
+		assert.Equal(t, "newsletter", found.UTMSource)
+		assert.Equal(t, "spring", found.UTMCampaign)
+		assert.Equal(t, "email", found.UTMMedium)
+
+		byLong, err := repo.FindByLongURL(ctx, "https://example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", byLong.ShortCode)
+
+		_, err = repo.FindByShortCode(ctx, "missing")
+		assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
+		_, err = repo.FindByLongURL(ctx, "https://missing.example.com")
+		assert.ErrorIs(t, err, shortener.ErrLongURLNotFound)
+		assert.ErrorIs(t, repo.Store(ctx, &shortener.URL{LongURL: "https://other.example.com", ShortCode: "abc123"}), shortener.ErrShortCodeExists)
+	}},
+	{name: "Increment visits", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now()}))
+
+		assert.NoError(t, repo.IncrementVisits(ctx, "abc123"))
+		assert.NoError(t, repo.IncrementVisits(ctx, "abc123"))
+		assert.NoError(t, repo.incrementVisitsBatch(ctx, map[string]visitBatch{"abc123": {count: 3}}))
+		assert.NoError(t, repo.IncrementVisits(ctx, "missing"))
+
+		found, err := repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(5), found.Visits)
+		assert.Nil(t, found.LastAccessedAt, "counting visits alone does not set the access time")
+	}},
+	{name: "Increment visits within limit", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		maxVisits := uint(2)
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "limited", CreatedAt: time.Now(), MaxVisits: &maxVisits}))
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/other", ShortCode: "unlimited", CreatedAt: time.Now()}))
+
+		var counted []bool
+		for i := 0; i < 3; i++ {
+			ok, err := repo.IncrementVisitsWithinLimit(ctx, "limited")
+			assert.NoError(t, err)
+			counted = append(counted, ok)
+		}
+		assert.Equal(t, []bool{true, true, false}, counted)
+
+		found, err := repo.FindByShortCode(ctx, "limited")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), found.Visits)
+
+		ok, err := repo.IncrementVisitsWithinLimit(ctx, "unlimited")
+		assert.NoError(t, err)
+		assert.False(t, ok, "a URL without a limit is not counted")
+		ok, err = repo.IncrementVisitsWithinLimit(ctx, "missing")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	}},
+	{name: "Update last accessed", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now()}))
+		lastAccessed := func() *time.Time {
+			found, err := repo.FindByShortCode(ctx, "abc123")
+			assert.NoError(t, err)
+			return found.LastAccessedAt
+		}
+		assert.Nil(t, lastAccessed())
+
+		first := time.Now().Add(-time.Hour)
+		assert.NoError(t, repo.UpdateLastAccessed(ctx, "abc123", first))
+		if at := lastAccessed(); assert.NotNil(t, at) {
+			assert.WithinDuration(t, first, *at, time.Millisecond)
+		}
+
+		assert.NoError(t, repo.UpdateLastAccessed(ctx, "abc123", first.Add(-time.Minute)))
+		if at := lastAccessed(); assert.NotNil(t, at) {
+			assert.WithinDuration(t, first, *at, time.Millisecond, "an earlier access does not move the time back")
+		}
+
+		second := first.Add(30 * time.Minute)
+		assert.NoError(t, repo.UpdateLastAccessed(ctx, "abc123", second))
+		if at := lastAccessed(); assert.NotNil(t, at) {
+			assert.WithinDuration(t, second, *at, time.Millisecond)
+		}
+
+		third := second.Add(time.Minute)
+		assert.NoError(t, repo.incrementVisitsBatch(ctx, map[string]visitBatch{"abc123": {count: 2, lastAccessed: third}}))
+		found, err := repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), found.Visits)
+		if assert.NotNil(t, found.LastAccessedAt) {
+			assert.WithinDuration(t, third, *found.LastAccessedAt, time.Millisecond)
+		}
+
+		assert.NoError(t, repo.UpdateLastAccessed(ctx, "missing", third))
+	}},
+	{name: "Update long URL", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/old", ShortCode: "abc123", CreatedAt: time.Now()}))
+
+		assert.NoError(t, repo.UpdateLongURL(ctx, "abc123", "https://example.com/new"))
+		assert.ErrorIs(t, repo.UpdateLongURL(ctx, "missing", "https://example.com/new"), shortener.ErrShortCodeNotFound)
+
+		found, err := repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/new", found.LongURL)
+	}},
+	{name: "Delete", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now()}))
+
+		assert.NoError(t, repo.Delete(ctx, "abc123"))
+		assert.ErrorIs(t, repo.Delete(ctx, "abc123"), shortener.ErrShortCodeNotFound)
+
+		_, err := repo.FindByShortCode(ctx, "abc123")
+		assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
+		assert.ErrorIs(t, repo.UpdateLongURL(ctx, "abc123", "https://example.com/new"), shortener.ErrShortCodeNotFound)
+		assert.ErrorIs(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123"}), shortener.ErrShortCodeExists,
+			"deleted short codes stay taken")
+	}},
+	{name: "Bulk delete", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: fmt.Sprintf("https://example.com/%d", i), ShortCode: fmt.Sprintf("code%d", i), CreatedAt: time.Now()}))
+		}
+
+		deleted, failures, err := repo.BulkDelete(ctx, []string{"code0", "code1", "missing"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+		assert.Equal(t, []shortener.BulkDeleteError{{ShortCode: "missing", Reason: constant.ErrShortCodeNotFound}}, failures)
+	}},
+	{name: "Bulk store", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/taken", ShortCode: "taken", CreatedAt: time.Now()}))
+		urls := []*shortener.URL{
+			{LongURL: "https://example.com/a", ShortCode: "first", CreatedAt: time.Now(), RedirectCode: 301, OwnerID: 7},
+			{LongURL: "https://example.com/b", ShortCode: "taken", CreatedAt: time.Now()},
+			{LongURL: "https://example.com/c", ShortCode: "first", CreatedAt: time.Now()},
+		}
+
+		result, err := repo.BulkStore(ctx, urls)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &shortener.BulkStoreResult{Stored: 1, Skipped: 2, Errors: []shortener.BulkStoreError{
+			{Index: 1, ShortCode: "taken", Reason: constant.ErrShortCodeExists},
+			{Index: 2, ShortCode: "first", Reason: constant.ErrShortCodeExists},
+		}}, result)
+		stored, err := repo.FindByShortCode(ctx, "first")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/a", stored.LongURL)
+		assert.Equal(t, 301, stored.RedirectCode)
+		assert.Equal(t, uint(7), stored.OwnerID)
+	}},
+	{name: "Bulk store across chunks", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		urls := make([]*shortener.URL, 2*bulkStoreChunkSize+1)
+		for i := range urls {
+			urls[i] = &shortener.URL{LongURL: fmt.Sprintf("https://example.com/%d", i), ShortCode: fmt.Sprintf("code%d", i), CreatedAt: time.Now()}
+		}
+
+		result, err := repo.BulkStore(ctx, urls)
+
+		assert.NoError(t, err)
+		assert.Equal(t, len(urls), result.Stored)
+		assert.Zero(t, result.Skipped)
+		last, err := repo.FindByShortCode(ctx, fmt.Sprintf("code%d", len(urls)-1))
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("https://example.com/%d", len(urls)-1), last.LongURL)
+	}},
+	{name: "Short codes unique regardless of case", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/a", ShortCode: "abc123", CreatedAt: time.Now()}))
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/b", ShortCode: "other", CreatedAt: time.Now()}))
+
+		assert.ErrorIs(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/c", ShortCode: "ABC123", CreatedAt: time.Now()}), shortener.ErrShortCodeExists)
+		assert.ErrorIs(t, repo.RenameShortCode(ctx, "other", "Abc123"), shortener.ErrShortCodeExists)
+		result, err := repo.BulkStore(ctx, []*shortener.URL{
+			{LongURL: "https://example.com/d", ShortCode: "aBc123", CreatedAt: time.Now()},
+			{LongURL: "https://example.com/e", ShortCode: "new", CreatedAt: time.Now()},
+			{LongURL: "https://example.com/f", ShortCode: "NEW", CreatedAt: time.Now()},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, &shortener.BulkStoreResult{Stored: 1, Skipped: 2, Errors: []shortener.BulkStoreError{
+			{Index: 0, ShortCode: "aBc123", Reason: constant.ErrShortCodeExists},
+			{Index: 2, ShortCode: "NEW", Reason: constant.ErrShortCodeExists},
+		}}, result)
+	}},
+	{name: "Rolled back short codes are free", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		rollback := errors.New("rollback")
+		err := repo.WithTransaction(ctx, func(tx shortener.Repository) error {
+			assert.NoError(t, tx.Store(ctx, &shortener.URL{LongURL: "https://example.com/a", ShortCode: "undone", CreatedAt: time.Now()}))
+			return rollback
+		})
+
+		assert.ErrorIs(t, err, rollback)
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/b", ShortCode: "UNDONE", CreatedAt: time.Now()}))
+	}},
+	{name: "Rename", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		for _, code := range []string{"typo", "taken"} {
+			assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now(), UTMSource: "social"}))
+		}
+		assert.NoError(t, repo.AddTag(ctx, "typo", "sale"))
+		assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "typo", ClickedAt: time.Now()}))
+
+		assert.NoError(t, repo.RenameShortCode(ctx, "typo", "fixed"))
+		assert.ErrorIs(t, repo.RenameShortCode(ctx, "fixed", "taken"), shortener.ErrShortCodeExists)
+		assert.ErrorIs(t, repo.RenameShortCode(ctx, "fixed", "typo"), shortener.ErrShortCodeExists)
+		assert.ErrorIs(t, repo.RenameShortCode(ctx, "typo", "other"), shortener.ErrShortCodeNotFound)
+
+		renamed, err := repo.FindByShortCode(ctx, "fixed")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/typo", renamed.LongURL)
+		assert.Equal(t, "social", renamed.UTMSource, "settings move with the code")
+		tags, _ := repo.FindTags(ctx, "fixed")
+		assert.Equal(t, []string{"sale"}, tags)
+		clicks, _ := repo.FindClicks(ctx, "fixed", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+		assert.Len(t, clicks, 1)
+	}},
+	{name: "Find expired", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		now := time.Now()
+		earlier, past, future := now.Add(-2*time.Hour), now.Add(-time.Hour), now.Add(time.Hour)
+		for code, expiresAt := range map[string]*time.Time{"past": &past, "earlier": &earlier, "future": &future, "never": nil} {
+			assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: now, ExpiresAt: expiresAt}))
+		}
+
+		expired, err := repo.FindExpired(ctx, now)
+
+		assert.NoError(t, err)
+		if assert.Len(t, expired, 2) {
+			assert.Equal(t, "earlier", expired[0].ShortCode)
+			assert.Equal(t, "past", expired[1].ShortCode)
+		}
+	}},
+	{name: "Search and list", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		for i := 0; i < 3; i++ {
+			code := fmt.Sprintf("code%d", i)
+			assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/match_" + code, ShortCode: code, CreatedAt: time.Now()}))
+			assert.NoError(t, repo.AddTag(ctx, code, "campaign"))
+		}
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/matchXcode", ShortCode: "other", CreatedAt: time.Now()}))
+		assert.NoError(t, repo.Delete(ctx, "code0"))
+
+		found, total, err := repo.Search(ctx, "match_", 1, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total, "the underscore is matched literally and deleted URLs are skipped")
+		if assert.Len(t, found, 1) {
+			assert.Equal(t, "code2", found[0].ShortCode)
+		}
+
+		tagged, total, err := repo.ListByTag(ctx, "campaign", 10, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		if assert.Len(t, tagged, 1) {
+			assert.Equal(t, "code1", tagged[0].ShortCode)
+		}
+
+		var all []string
+		assert.NoError(t, repo.FindAll(ctx, func(url *shortener.URL) error {
+			all = append(all, url.ShortCode)
+			return nil
+		}))
+		assert.Equal(t, []string{"code1", "code2", "other"}, all)
+		stop := errors.New("stop")
+		assert.Equal(t, stop, repo.FindAll(ctx, func(url *shortener.URL) error { return stop }))
+	}},
+	{name: "Find created after", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i, code := range []string{"older", "boundary", "newer", "newest", "deleted"} {
+			createdAt := base.Add(time.Duration(i-1) * time.Hour)
+			assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: createdAt}))
+		}
+		assert.NoError(t, repo.Delete(ctx, "deleted"))
+
+		recent, err := repo.FindCreatedAfter(ctx, base, 10, 0)
+		assert.NoError(t, err)
+		var codes []string
+		for _, url := range recent {
+			codes = append(codes, url.ShortCode)
+		}
+		assert.Equal(t, []string{"newest", "newer"}, codes, "URLs created exactly at since are excluded")
+
+		paged, err := repo.FindCreatedAfter(ctx, base.Add(-2*time.Hour), 2, 1)
+		assert.NoError(t, err)
+		if assert.Len(t, paged, 2) {
+			assert.Equal(t, "newer", paged[0].ShortCode)
+			assert.Equal(t, "boundary", paged[1].ShortCode)
+		}
+	}},
+	{name: "Find top URLs", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		visits := map[string]uint{"quiet": 1, "popular": 50, "tied-first": 7, "tied-second": 7, "deleted": 99}
+		for _, code := range []string{"quiet", "popular", "tied-first", "tied-second", "deleted"} {
+			assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now(), Visits: visits[code]}))
+		}
+		assert.NoError(t, repo.Delete(ctx, "deleted"))
+
+		top, err := repo.FindTopURLs(ctx, 10)
+		assert.NoError(t, err)
+		var codes []string
+		for _, url := range top {
+			codes = append(codes, url.ShortCode)
+		}
+		assert.Equal(t, []string{"popular", "tied-first", "tied-second", "quiet"}, codes, "ties keep creation order")
+
+		limited, err := repo.FindTopURLs(ctx, 2)
+		assert.NoError(t, err)
+		if assert.Len(t, limited, 2) {
+			assert.Equal(t, "popular", limited[0].ShortCode)
+			assert.Equal(t, uint(50), limited[0].Visits)
+		}
+	}},
+	{name: "Find trending URLs", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		for _, code := range []string{"steady", "rising", "tied-first", "tied-second", "stale", "deleted"} {
+			assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now(), Visits: 100}))
+		}
+		assert.NoError(t, repo.Delete(ctx, "deleted"))
+
+		now := time.Now()
+		recent := map[string]int{"steady": 1, "rising": 5, "tied-first": 2, "tied-second": 2, "deleted": 9}
+		for code, count := range recent {
+			for i := 0; i < count; i++ {
+				assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: code, ClickedAt: now.Add(-time.Duration(i+1) * time.Minute)}))
+			}
+		}
+		// Clicks older than the period do not count
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "stale", ClickedAt: now.Add(-48 * time.Hour)}))
+			assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "steady", ClickedAt: now.Add(-48 * time.Hour)}))
+		}
+
+		trending, err := repo.FindTrending(ctx, 24*time.Hour, 10)
+		assert.NoError(t, err)
+		var codes []string
+		var counts []uint
+		for _, url := range trending {
+			codes = append(codes, url.ShortCode)
+			counts = append(counts, url.RecentVisits)
+		}
+		assert.Equal(t, []string{"rising", "tied-first", "tied-second", "steady"}, codes, "ties keep creation order")
+		assert.Equal(t, []uint{5, 2, 2, 1}, counts)
+		if assert.NotEmpty(t, trending) {
+			assert.Equal(t, "https://example.com/rising", trending[0].LongURL)
+			assert.Equal(t, uint(100), trending[0].Visits)
+		}
+
+		wider, err := repo.FindTrending(ctx, 72*time.Hour, 1)
+		assert.NoError(t, err)
+		if assert.Len(t, wider, 1) {
+			assert.Equal(t, "steady", wider[0].ShortCode)
+			assert.Equal(t, uint(21), wider[0].RecentVisits)
+		}
+
+		none, err := repo.FindTrending(ctx, time.Second, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, none)
+	}},
+	{name: "Tags", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now()}))
+
+		assert.NoError(t, repo.AddTag(ctx, "abc123", "sale"))
+		assert.NoError(t, repo.AddTag(ctx, "abc123", "sale"))
+		assert.NoError(t, repo.AddTag(ctx, "abc123", "autumn"))
+		assert.NoError(t, repo.RemoveTag(ctx, "abc123", "sale"))
+		assert.ErrorIs(t, repo.RemoveTag(ctx, "abc123", "sale"), shortener.ErrTagNotFound)
+
+		tags, err := repo.FindTags(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"autumn"}, tags)
+		none, err := repo.FindTags(ctx, "missing")
+		assert.NoError(t, err)
+		assert.Empty(t, none)
+	}},
+	{name: "Clicks", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		now := time.Now().UTC()
+		for _, referer := range []string{"https://a.example.com", "https://b.example.com", "https://b.example.com"} {
+			assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "abc123", ClickedAt: now, Referer: referer}))
+		}
+		assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "abc123", ClickedAt: now.Add(-48 * time.Hour)}))
+
+		clicks, err := repo.FindClicks(ctx, "abc123", now.Add(-time.Hour), now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Len(t, clicks, 3)
+
+		referers, err := repo.FindReferers(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, []shortener.RefererStat{
+			{URL: "https://b.example.com", Count: 2},
+			{URL: "", Count: 1},
+			{URL: "https://a.example.com", Count: 1},
+		}, referers)
+
+		daily, err := repo.FindDailyClicks(ctx, "abc123", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []shortener.DailyCount{{Date: now.Format("2006-01-02"), Count: 3}}, daily)
+	}},
+	{name: "Device stats", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		now := time.Now()
+		for _, deviceType := range []string{"mobile", "mobile", "desktop", "tablet", "bot", "bot", "bot", ""} {
+			assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "abc123", ClickedAt: now, DeviceType: deviceType}))
+		}
+		assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "other", ClickedAt: now, DeviceType: "mobile"}))
+
+		stats, err := repo.FindDeviceStats(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, shortener.DeviceStats{Mobile: 2, Desktop: 1, Tablet: 1, Bot: 3}, stats, "clicks without a device type are left out")
+
+		none, err := repo.FindDeviceStats(ctx, "missing")
+		assert.NoError(t, err)
+		assert.Equal(t, shortener.DeviceStats{}, none)
+
+		clicks, err := repo.FindAllClicks(ctx, "other")
+		assert.NoError(t, err)
+		if assert.Len(t, clicks, 1) {
+			assert.Equal(t, "mobile", clicks[0].DeviceType)
+		}
+	}},
+	{name: "Find all clicks", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		now := time.Now().UTC().Truncate(time.Second)
+		assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "abc123", ClickedAt: now, Referer: "https://a.example.com", IPHash: shortener.HashIP("203.0.113.7"), UserAgent: "curl/8.0"}))
+		assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "abc123", ClickedAt: now.Add(-72 * time.Hour)}))
+		assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "other", ClickedAt: now}))
+
+		clicks, err := repo.FindAllClicks(ctx, "abc123")
+		assert.NoError(t, err)
+		if assert.Len(t, clicks, 2) {
+			assert.True(t, clicks[0].ClickedAt.Equal(now.Add(-72*time.Hour)), "oldest first")
+			assert.Empty(t, clicks[0].IPHash)
+			assert.True(t, clicks[1].ClickedAt.Equal(now))
+			assert.Equal(t, "https://a.example.com", clicks[1].Referer)
+			assert.Equal(t, shortener.HashIP("203.0.113.7"), clicks[1].IPHash)
+			assert.Equal(t, "curl/8.0", clicks[1].UserAgent)
+		}
+
+		none, err := repo.FindAllClicks(ctx, "missing")
+		assert.NoError(t, err)
+		assert.Empty(t, none)
+	}},
+	{name: "Users", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		alice := &shortener.User{Username: "alice", PasswordHash: "hash", Role: shortener.RoleUser, CreatedAt: time.Now()}
+		assert.NoError(t, repo.CreateUser(ctx, alice))
+		assert.NotZero(t, alice.ID)
+		assert.Error(t, repo.CreateUser(ctx, &shortener.User{Username: "alice", Role: shortener.RoleUser}))
+
+		found, err := repo.FindUserByUsername(ctx, "alice")
+		assert.NoError(t, err)
+		assert.Equal(t, alice.ID, found.ID)
+		assert.Equal(t, shortener.RoleUser, found.Role)
+		_, err = repo.FindUserByUsername(ctx, "bob")
+		assert.ErrorIs(t, err, shortener.ErrUserNotFound)
+
+		users, err := repo.ListUsers(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+	}},
+	{name: "Webhooks", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		hook := &shortener.Webhook{URL: "https://hooks.example.com", ShortCode: "abc123", Secret: "secret", Events: []string{"click"}, CreatedAt: time.Now()}
+		assert.NoError(t, repo.CreateWebhook(ctx, hook))
+
+		hooks, err := repo.FindWebhooks(ctx, "abc123")
+		assert.NoError(t, err)
+		if assert.Len(t, hooks, 1) {
+			assert.Equal(t, hook.ID, hooks[0].ID)
+			assert.Equal(t, []string{"click"}, hooks[0].Events)
+		}
+
+		assert.NoError(t, repo.DeleteWebhook(ctx, hook.ID))
+		assert.ErrorIs(t, repo.DeleteWebhook(ctx, hook.ID), shortener.ErrWebhookNotFound)
+	}},
+	{name: "Audits", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		now := time.Now()
+		assert.NoError(t, repo.AppendAudit(ctx, shortener.AuditEntry{Action: shortener.AuditActionUpdate, ShortCode: "abc123", OccurredAt: now}))
+		assert.NoError(t, repo.AppendAudit(ctx, shortener.AuditEntry{Action: shortener.AuditActionCreate, ShortCode: "abc123", OccurredAt: now.Add(-time.Minute)}))
+
+		entries, err := repo.FindAudits(ctx, "abc123")
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, shortener.AuditActionCreate, entries[0].Action)
+			assert.Equal(t, shortener.AuditActionUpdate, entries[1].Action)
+		}
+	}},
+	{name: "Canary split", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		url := &shortener.URL{LongURL: "https://example.com/old", ShortCode: "abc123", CreatedAt: time.Now(), AlternateURL: "https://example.com/new", CanaryPercent: 10}
+		assert.NoError(t, repo.Store(ctx, url))
+
+		found, err := repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/new", found.AlternateURL)
+		assert.Equal(t, uint8(10), found.CanaryPercent)
+
+		assert.NoError(t, repo.UpdateCanary(ctx, "abc123", "https://example.com/next", 50))
+		found, err = repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/next", found.AlternateURL)
+		assert.Equal(t, uint8(50), found.CanaryPercent)
+
+		assert.ErrorIs(t, repo.UpdateCanary(ctx, "missing", "", 0), shortener.ErrShortCodeNotFound)
+	}},
+	{name: "Geo rules", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		rules := []shortener.GeoRule{
+			{CountryCodes: []string{"ID", "MY"}, TargetURL: "https://example.com/asia"},
+			{CountryCodes: []string{"DE"}, TargetURL: "https://example.de"},
+		}
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now(), GeoRules: rules}))
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/plain", ShortCode: "plain", CreatedAt: time.Now()}))
+
+		found, err := repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, rules, found.GeoRules)
+
+		plain, err := repo.FindByShortCode(ctx, "plain")
+		assert.NoError(t, err)
+		assert.Empty(t, plain.GeoRules)
+	}},
+	{name: "Owner token", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now(), OwnerTokenHash: "hash", OwnerTokenExpiresAt: &expiresAt}))
+
+		found, err := repo.FindByShortCode(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "hash", found.OwnerTokenHash)
+		if assert.NotNil(t, found.OwnerTokenExpiresAt) {
+			assert.True(t, expiresAt.Equal(*found.OwnerTokenExpiresAt))
+		}
+	}},
+	{name: "Scheduled activation", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		activeFrom := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "launch", CreatedAt: time.Now(), ActiveFrom: &activeFrom}))
+		assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/now", ShortCode: "now", CreatedAt: time.Now()}))
+
+		found, err := repo.FindByShortCode(ctx, "launch")
+		assert.NoError(t, err)
+		if assert.NotNil(t, found.ActiveFrom) {
+			assert.True(t, activeFrom.Equal(*found.ActiveFrom))
+		}
+		immediate, err := repo.FindByShortCode(ctx, "now")
+		assert.NoError(t, err)
+		assert.Nil(t, immediate.ActiveFrom)
+	}},
+	{name: "Ping and close", run: func(t *testing.T, ctx context.Context, repo Repository) {
+		assert.NoError(t, repo.Ping(ctx))
+		assert.NoError(t, repo.Close())
+		assert.Error(t, repo.Ping(ctx))
+	}},
+}
+
+func TestRepositories(t *testing.T) {
+	for _, backend := range repositoryBackends {
+		for _, tc := range repositoryCases {
+			t.Run(backend.name+"/"+tc.name, func(t *testing.T) {
+				// Arrange
+				repo := backend.open(t)
+				defer repo.Close()
+
+				// Act & Assert
+				tc.run(t, context.Background(), repo)
+			})
+		}
+	}
+}
@@ -2,7 +2,10 @@ package db
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -10,6 +13,12 @@ import (
 	"github.com/prasetyowira/shorter/domain/shortener"
 	"github.com/prasetyowira/shorter/infrastructure/cache"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
 )
 
 // testDBPath is the path to the test database file
@@ -121,7 +130,7 @@ func TestSQLiteRepository_Store_DuplicateShortCode(t *testing.T) {
 	// Assert
 	assert.NoError(t, err1)
 	assert.Error(t, err2)
-	assert.Equal(t, constant.ErrShortCodeExists, err2.Error())
+	assert.ErrorIs(t, err2, shortener.ErrShortCodeExists)
 }
 
 func TestSQLiteRepository_FindByShortCode(t *testing.T) {
@@ -165,7 +174,7 @@ func TestSQLiteRepository_FindByShortCode_NotFound(t *testing.T) {
 	
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, constant.ErrShortCodeNotFound, err.Error())
+	assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
 	assert.Nil(t, foundURL)
 }
 
@@ -278,7 +287,7 @@ func TestSQLiteRepository_UpdateLongURL_NonexistentShortCode(t *testing.T) {
 	
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, constant.ErrShortCodeNotFound, err.Error())
+	assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
 }
 
 func TestGormLogger_LogMode(t *testing.T) {
@@ -294,4 +303,758 @@ func TestGormLogger_LogMode(t *testing.T) {
 
 // Note: The remaining GormLogger methods (Info, Warn, Error, Trace)
 // primarily call the application logger and don't need extensive testing.
-// They rely on appLogger, which would need to be mocked for thorough testing. 
\ No newline at end of file
+// They rely on appLogger, which would need to be mocked for thorough testing. 
+func TestSQLiteRepository_RecordClick_FindClicks(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, offset := range []time.Duration{0, time.Hour, 48 * time.Hour} {
+		err := repo.RecordClick(ctx, shortener.ClickEvent{
+			ShortCode: "abc123",
+			ClickedAt: base.Add(offset),
+			Referer:   "https://referrer.example",
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "other", ClickedAt: base}))
+
+	// Act
+	clicks, err := repo.FindClicks(ctx, "abc123", base, base.Add(24*time.Hour))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 2)
+	assert.Equal(t, "abc123", clicks[0].ShortCode)
+	assert.Equal(t, "https://referrer.example", clicks[0].Referer)
+	assert.True(t, base.Equal(clicks[0].ClickedAt))
+}
+
+func TestSQLiteRepository_FindAll(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	for _, shortCode := range []string{"abc123", "def456", "ghi789"} {
+		err := repo.Store(ctx, &shortener.URL{
+			LongURL:   "https://example.com/" + shortCode,
+			ShortCode: shortCode,
+			CreatedAt: time.Now(),
+		})
+		assert.NoError(t, err)
+	}
+
+	// Act
+	var found []string
+	err := repo.FindAll(ctx, func(url *shortener.URL) error {
+		found = append(found, url.ShortCode)
+		return nil
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc123", "def456", "ghi789"}, found)
+}
+
+func TestSQLiteRepository_FindByLongURL(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	err := repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now()})
+	assert.NoError(t, err)
+
+	// Act
+	found, err := repo.FindByLongURL(ctx, "https://example.com")
+	missing, missingErr := repo.FindByLongURL(ctx, "https://missing.example.com")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", found.ShortCode)
+	assert.Nil(t, missing)
+	assert.ErrorIs(t, missingErr, shortener.ErrLongURLNotFound)
+}
+
+func TestSQLiteRepository_Store_Protected(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	url := &shortener.URL{
+		LongURL:     "https://example.com/private",
+		ShortCode:   "secret",
+		CreatedAt:   time.Now(),
+		Password:    "hashed-password",
+		IsProtected: true,
+	}
+
+	// Act
+	err := repo.Store(ctx, url)
+	found, findErr := repo.FindByShortCode(ctx, "secret")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, findErr)
+	assert.True(t, found.IsProtected)
+	assert.Equal(t, "hashed-password", found.Password)
+}
+
+func TestSQLiteRepository_Delete(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	maxVisits := uint(5)
+	err := repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now(), MaxVisits: &maxVisits})
+	assert.NoError(t, err)
+	stored, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+
+	// Act
+	err = repo.Delete(ctx, "abc123")
+	found, findErr := repo.FindByShortCode(ctx, "abc123")
+	againErr := repo.Delete(ctx, "abc123")
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.NotNil(t, stored.MaxVisits) {
+		assert.Equal(t, uint(5), *stored.MaxVisits)
+	}
+	assert.Nil(t, found)
+	assert.ErrorIs(t, findErr, shortener.ErrShortCodeNotFound)
+	assert.ErrorIs(t, againErr, shortener.ErrShortCodeNotFound)
+}
+
+func TestSQLiteRepository_Store_RedirectCode(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	url := &shortener.URL{
+		LongURL:      "https://example.com/landing",
+		ShortCode:    "landing",
+		CreatedAt:    time.Now(),
+		RedirectCode: http.StatusMovedPermanently,
+	}
+
+	// Act
+	err := repo.Store(ctx, url)
+	found, findErr := repo.FindByShortCode(ctx, "landing")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, findErr)
+	assert.Equal(t, http.StatusMovedPermanently, found.RedirectCode)
+}
+
+func TestSQLiteRepository_Users(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	alice := &shortener.User{Username: "alice", PasswordHash: "hash", Role: shortener.RoleUser, CreatedAt: time.Now()}
+	admin := &shortener.User{Username: "root", PasswordHash: "hash", Role: shortener.RoleAdmin, CreatedAt: time.Now()}
+
+	// Act
+	aliceErr := repo.CreateUser(ctx, alice)
+	adminErr := repo.CreateUser(ctx, admin)
+	duplicateErr := repo.CreateUser(ctx, &shortener.User{Username: "alice", PasswordHash: "hash", Role: shortener.RoleUser})
+	found, findErr := repo.FindUserByUsername(ctx, "alice")
+	_, missingErr := repo.FindUserByUsername(ctx, "bob")
+	users, listErr := repo.ListUsers(ctx)
+
+	// Assert
+	assert.NoError(t, aliceErr)
+	assert.NoError(t, adminErr)
+	assert.Error(t, duplicateErr)
+	assert.NotZero(t, alice.ID)
+	assert.NoError(t, findErr)
+	assert.Equal(t, alice.ID, found.ID)
+	assert.Equal(t, shortener.RoleUser, found.Role)
+	assert.Equal(t, "hash", found.PasswordHash)
+	assert.ErrorIs(t, missingErr, shortener.ErrUserNotFound)
+	assert.NoError(t, listErr)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "alice", users[0].Username)
+		assert.Equal(t, shortener.RoleAdmin, users[1].Role)
+	}
+}
+
+func TestSQLiteRepository_Store_Owner(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	// Act
+	err := repo.Store(ctx, &shortener.URL{LongURL: "https://example.com", ShortCode: "owned", CreatedAt: time.Now(), OwnerID: 7})
+	found, findErr := repo.FindByShortCode(ctx, "owned")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, findErr)
+	assert.Equal(t, uint(7), found.OwnerID)
+}
+
+func TestSQLiteRepository_Webhooks(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	hook := &shortener.Webhook{URL: "https://example.com/hook", ShortCode: "abc123", Secret: "s", Events: []string{"visit", "other"}, OwnerID: 7, CreatedAt: time.Now()}
+
+	// Act
+	createErr := repo.CreateWebhook(ctx, hook)
+	found, findErr := repo.FindWebhooks(ctx, "abc123")
+	none, noneErr := repo.FindWebhooks(ctx, "other")
+	deleteErr := repo.DeleteWebhook(ctx, hook.ID)
+	afterDelete, _ := repo.FindWebhooks(ctx, "abc123")
+	againErr := repo.DeleteWebhook(ctx, hook.ID)
+
+	// Assert
+	assert.NoError(t, createErr)
+	assert.NotZero(t, hook.ID)
+	assert.NoError(t, findErr)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, hook.URL, found[0].URL)
+		assert.Equal(t, "s", found[0].Secret)
+		assert.Equal(t, []string{"visit", "other"}, found[0].Events)
+		assert.Equal(t, uint(7), found[0].OwnerID)
+	}
+	assert.NoError(t, noneErr)
+	assert.Empty(t, none)
+	assert.NoError(t, deleteErr)
+	assert.Empty(t, afterDelete)
+	assert.ErrorIs(t, againErr, shortener.ErrWebhookNotFound)
+}
+
+func TestSQLiteRepository_Search(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	for i, longURL := range []string{
+		"https://example.com/a",
+		"https://example.com/b",
+		"https://other.org/100%_off",
+		"https://other.org/deleted",
+	} {
+		err := repo.Store(ctx, &shortener.URL{LongURL: longURL, ShortCode: fmt.Sprintf("code%d", i), CreatedAt: time.Now()})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.Delete(ctx, "code3"))
+
+	tests := []struct {
+		name      string
+		query     string
+		limit     int
+		offset    int
+		wantCodes []string
+		wantTotal int
+	}{
+		{name: "Empty query returns all", query: "", limit: 20, wantCodes: []string{"code2", "code1", "code0"}, wantTotal: 3},
+		{name: "Filters by substring", query: "example", limit: 20, wantCodes: []string{"code1", "code0"}, wantTotal: 2},
+		{name: "Paginates", query: "", limit: 1, offset: 1, wantCodes: []string{"code1"}, wantTotal: 3},
+		{name: "Percent is literal", query: "%", limit: 20, wantCodes: []string{"code2"}, wantTotal: 1},
+		{name: "Underscore is literal", query: "_", limit: 20, wantCodes: []string{"code2"}, wantTotal: 1},
+		{name: "Quote injection", query: "' OR '1'='1", limit: 20, wantCodes: []string{}, wantTotal: 0},
+		{name: "Statement injection", query: "x'; DROP TABLE url_models; --", limit: 20, wantCodes: []string{}, wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			urls, total, err := repo.Search(ctx, tt.query, tt.limit, tt.offset)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTotal, total)
+			codes := []string{}
+			for _, url := range urls {
+				codes = append(codes, url.ShortCode)
+			}
+			assert.Equal(t, tt.wantCodes, codes)
+		})
+	}
+
+	_, err := repo.FindByShortCode(ctx, "code0")
+	assert.NoError(t, err, "table must survive injection attempts")
+}
+
+func TestSQLiteRepository_FindReferers(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	for _, referer := range []string{"https://a.example", "https://b.example", "https://a.example", "", "https://a.example"} {
+		err := repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "abc123", ClickedAt: time.Now(), Referer: referer})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "other", ClickedAt: time.Now(), Referer: "https://b.example"}))
+
+	// Act
+	referers, err := repo.FindReferers(ctx, "abc123")
+	empty, emptyErr := repo.FindReferers(ctx, "nothing")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []shortener.RefererStat{
+		{URL: "https://a.example", Count: 3},
+		{URL: "", Count: 1},
+		{URL: "https://b.example", Count: 1},
+	}, referers)
+	assert.NoError(t, emptyErr)
+	assert.Empty(t, empty)
+}
+
+func TestSQLiteRepository_TracesQueries(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	// Act
+	_, err := repo.FindByShortCode(ctx, "missing")
+	parent.End()
+
+	// Assert
+	assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
+	var querySpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "gorm.row" {
+			querySpan = span
+		}
+	}
+	if assert.NotNil(t, querySpan) {
+		assert.Equal(t, parent.SpanContext().SpanID(), querySpan.Parent().SpanID())
+		assert.Contains(t, querySpan.Attributes(), semconv.DBSystemSqlite)
+	}
+}
+
+func TestSQLiteRepository_Tags(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := repo.Store(ctx, &shortener.URL{LongURL: fmt.Sprintf("https://example.com/%d", i), ShortCode: fmt.Sprintf("code%d", i), CreatedAt: time.Now()})
+		assert.NoError(t, err)
+	}
+
+	// Act
+	assert.NoError(t, repo.AddTag(ctx, "code0", "sale"))
+	assert.NoError(t, repo.AddTag(ctx, "code0", "sale"))
+	assert.NoError(t, repo.AddTag(ctx, "code0", "blog"))
+	assert.NoError(t, repo.AddTag(ctx, "code2", "sale"))
+	tags, findErr := repo.FindTags(ctx, "code0")
+	untagged, untaggedErr := repo.FindTags(ctx, "code1")
+	urls, total, listErr := repo.ListByTag(ctx, "sale", 10, 0)
+	removeErr := repo.RemoveTag(ctx, "code0", "sale")
+	againErr := repo.RemoveTag(ctx, "code0", "sale")
+	afterRemove, _ := repo.FindTags(ctx, "code0")
+
+	// Assert
+	assert.NoError(t, findErr)
+	assert.Equal(t, []string{"blog", "sale"}, tags)
+	assert.NoError(t, untaggedErr)
+	assert.Equal(t, []string{}, untagged)
+	assert.NoError(t, listErr)
+	assert.Equal(t, 2, total)
+	if assert.Len(t, urls, 2) {
+		assert.Equal(t, "code2", urls[0].ShortCode)
+		assert.Equal(t, "code0", urls[1].ShortCode)
+	}
+	assert.NoError(t, removeErr)
+	assert.ErrorIs(t, againErr, shortener.ErrTagNotFound)
+	assert.Equal(t, []string{"blog"}, afterRemove)
+}
+
+func TestSQLiteRepository_Audits(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+	now := time.Now()
+
+	// Act
+	createErr := repo.AppendAudit(ctx, shortener.AuditEntry{ActorUsername: "alice", Action: shortener.AuditActionCreate, ShortCode: "abc123", After: `{"long_url":"https://example.com"}`, OccurredAt: now})
+	deleteErr := repo.AppendAudit(ctx, shortener.AuditEntry{ActorUsername: "bob", Action: shortener.AuditActionDelete, ShortCode: "abc123", OccurredAt: now.Add(time.Second)})
+	otherErr := repo.AppendAudit(ctx, shortener.AuditEntry{ActorUsername: "alice", Action: shortener.AuditActionCreate, ShortCode: "other", OccurredAt: now})
+	entries, findErr := repo.FindAudits(ctx, "abc123")
+	none, noneErr := repo.FindAudits(ctx, "missing")
+
+	// Assert
+	assert.NoError(t, createErr)
+	assert.NoError(t, deleteErr)
+	assert.NoError(t, otherErr)
+	assert.NoError(t, findErr)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "alice", entries[0].ActorUsername)
+		assert.Equal(t, shortener.AuditActionCreate, entries[0].Action)
+		assert.Equal(t, `{"long_url":"https://example.com"}`, entries[0].After)
+		assert.Empty(t, entries[0].Before)
+		assert.Equal(t, shortener.AuditActionDelete, entries[1].Action)
+		assert.NotZero(t, entries[1].ID)
+	}
+	assert.NoError(t, noneErr)
+	assert.Equal(t, []shortener.AuditEntry{}, none)
+}
+
+func TestSQLiteRepository_RenameShortCode(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	for _, code := range []string{"typo", "taken", "gone"} {
+		err := repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: time.Now(), Visits: 3, OwnerID: 7})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.Delete(ctx, "gone"))
+	assert.NoError(t, repo.AddTag(ctx, "typo", "sale"))
+	assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "typo", ClickedAt: time.Now()}))
+
+	// Act
+	renameErr := repo.RenameShortCode(ctx, "typo", "fixed")
+	renamed, findErr := repo.FindByShortCode(ctx, "fixed")
+	_, oldErr := repo.FindByShortCode(ctx, "typo")
+	tags, _ := repo.FindTags(ctx, "fixed")
+	clicks, _ := repo.FindClicks(ctx, "fixed", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	takenErr := repo.RenameShortCode(ctx, "fixed", "taken")
+	deletedErr := repo.RenameShortCode(ctx, "fixed", "gone")
+	missingErr := repo.RenameShortCode(ctx, "typo", "other")
+	reuseErr := repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/new", ShortCode: "typo", CreatedAt: time.Now()})
+
+	// Assert
+	assert.NoError(t, renameErr)
+	assert.NoError(t, findErr)
+	assert.Equal(t, "https://example.com/typo", renamed.LongURL)
+	assert.Equal(t, uint(3), renamed.Visits)
+	assert.Equal(t, uint(7), renamed.OwnerID)
+	assert.ErrorIs(t, oldErr, shortener.ErrShortCodeNotFound)
+	assert.Equal(t, []string{"sale"}, tags)
+	assert.Len(t, clicks, 1)
+	assert.ErrorIs(t, takenErr, shortener.ErrShortCodeExists)
+	assert.ErrorIs(t, deletedErr, shortener.ErrShortCodeExists)
+	assert.ErrorIs(t, missingErr, shortener.ErrShortCodeNotFound)
+	assert.ErrorIs(t, reuseErr, shortener.ErrShortCodeExists, "the old code stays taken")
+}
+
+func TestSQLiteRepository_BulkDelete(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := repo.Store(ctx, &shortener.URL{LongURL: fmt.Sprintf("https://example.com/%d", i), ShortCode: fmt.Sprintf("code%d", i), CreatedAt: time.Now()})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.Delete(ctx, "code2"))
+
+	// Act
+	deleted, failures, err := repo.BulkDelete(ctx, []string{"code0", "code1", "code2", "missing"})
+	_, findErr := repo.FindByShortCode(ctx, "code0")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, []shortener.BulkDeleteError{
+		{ShortCode: "code2", Reason: constant.ErrShortCodeNotFound},
+		{ShortCode: "missing", Reason: constant.ErrShortCodeNotFound},
+	}, failures)
+	assert.ErrorIs(t, findErr, shortener.ErrShortCodeNotFound)
+}
+
+func TestNewSQLiteRepository_Reopen(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	assert.NoError(t, repo.Close())
+
+	// Act
+	reopened, err := NewSQLiteRepository(testDBPath, cache.NewNamespaceLRU(100))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, reopened.db.Migrator().HasIndex(&URLModel{}, longURLIndexName))
+	assert.NoError(t, reopened.Close())
+}
+
+func TestSQLiteRepository_FindExpired(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	for code, expiresAt := range map[string]*time.Time{"past": &past, "future": &future, "never": nil} {
+		err := repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/" + code, ShortCode: code, CreatedAt: now, ExpiresAt: expiresAt})
+		assert.NoError(t, err)
+	}
+
+	// Act
+	expired, err := repo.FindExpired(ctx, now)
+	assert.NoError(t, repo.Delete(ctx, "past"))
+	afterDelete, afterErr := repo.FindExpired(ctx, now)
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.Len(t, expired, 1) {
+		assert.Equal(t, "past", expired[0].ShortCode)
+		assert.WithinDuration(t, past, *expired[0].ExpiresAt, time.Second)
+	}
+	assert.NoError(t, afterErr)
+	assert.Empty(t, afterDelete)
+}
+
+func TestSQLiteRepository_FindDailyClicks(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, clickedAt := range []time.Time{
+		today.Add(time.Minute),
+		today.Add(2 * time.Minute),
+		today.AddDate(0, 0, -2).Add(time.Hour),
+		today.AddDate(0, 0, -3).Add(time.Hour),
+	} {
+		assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "abc123", ClickedAt: clickedAt}))
+	}
+	assert.NoError(t, repo.RecordClick(ctx, shortener.ClickEvent{ShortCode: "other", ClickedAt: today}))
+
+	// Act
+	counts, err := repo.FindDailyClicks(ctx, "abc123", 3)
+	none, noneErr := repo.FindDailyClicks(ctx, "missing", 3)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []shortener.DailyCount{
+		{Date: today.AddDate(0, 0, -2).Format("2006-01-02"), Count: 1},
+		{Date: today.Format("2006-01-02"), Count: 2},
+	}, counts)
+	assert.NoError(t, noneErr)
+	assert.Empty(t, none)
+}
+
+func TestNewSQLiteRepositoryWithOptions_WALMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        SQLiteOptions
+		journalMode string
+	}{
+		{name: "WAL", opts: SQLiteOptions{WALMode: true}, journalMode: "wal"},
+		// The test pins a second connection, which the default single-connection pool would not allow
+		{name: "Default", opts: SQLiteOptions{MaxOpenConns: 2}, journalMode: "delete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test_wal.db"), cache.NewNamespaceLRU(100), tt.opts)
+			assert.NoError(t, err)
+			defer repo.Close()
+			sqlDB, err := repo.db.DB()
+			assert.NoError(t, err)
+			// Pin a second pooled connection to check the per-connection pragmas apply to it too
+			conn, err := sqlDB.Conn(context.Background())
+			assert.NoError(t, err)
+			defer conn.Close()
+
+			// Act
+			var journalMode string
+			journalErr := repo.db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error
+			var busyTimeout int
+			busyErr := conn.QueryRowContext(context.Background(), "PRAGMA busy_timeout").Scan(&busyTimeout)
+
+			// Assert
+			assert.NoError(t, journalErr)
+			assert.Equal(t, tt.journalMode, journalMode)
+			assert.NoError(t, busyErr)
+			if tt.opts.WALMode {
+				assert.Equal(t, 5000, busyTimeout)
+			}
+		})
+	}
+}
+
+func TestSQLiteRepository_FindByLongURL_UsesIndex(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 1000; i++ {
+		err := repo.Store(ctx, &shortener.URL{LongURL: fmt.Sprintf("https://example.com/%d", i), ShortCode: fmt.Sprintf("code%d", i), CreatedAt: time.Now()})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.db.Exec(`ANALYZE`).Error)
+
+	// Act
+	var plan []struct {
+		Detail string
+	}
+	err := repo.db.Raw(`EXPLAIN QUERY PLAN `+findByLongURLQuery, "https://example.com/500").Scan(&plan).Error
+
+	// Assert
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, plan) {
+		assert.Contains(t, plan[0].Detail, "USING INDEX "+longURLIndexName)
+	}
+	for _, index := range []string{longURLIndexName, createdAtIndexName, activeShortCodeIndexName} {
+		assert.True(t, repo.db.Migrator().HasIndex(&URLModel{}, index), index)
+	}
+}
+
+func TestNewSQLiteRepositoryWithOptions_ConnectionPool(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         SQLiteOptions
+		maxOpenConns int
+	}{
+		{name: "Default", opts: SQLiteOptions{}, maxOpenConns: DefaultSQLiteMaxOpenConns},
+		{name: "WAL unlimited", opts: SQLiteOptions{WALMode: true}, maxOpenConns: 0},
+		{name: "Configured", opts: SQLiteOptions{MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: time.Minute}, maxOpenConns: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test_pool.db"), cache.NewNamespaceLRU(100), tt.opts)
+			assert.NoError(t, err)
+			defer repo.Close()
+			sqlDB, err := repo.db.DB()
+			assert.NoError(t, err)
+
+			// Act
+			stats := sqlDB.Stats()
+
+			// Assert
+			assert.Equal(t, tt.maxOpenConns, stats.MaxOpenConnections)
+		})
+	}
+}
+
+func TestSQLiteRepository_QueryTimeout(t *testing.T) {
+	// Arrange
+	repo, err := NewSQLiteRepositoryWithOptions(filepath.Join(t.TempDir(), "test_timeout.db"), cache.NewNamespaceLRU(100), SQLiteOptions{QueryTimeout: 50 * time.Millisecond})
+	assert.NoError(t, err)
+	defer repo.Close()
+	// Simulate a slow query: the statement only gives up once its context is done
+	err = repo.db.Callback().Row().Before("gorm:row").Register("test:slow_query", func(tx *gorm.DB) {
+		<-tx.Statement.Context.Done()
+		tx.AddError(tx.Statement.Context.Err())
+	})
+	assert.NoError(t, err)
+
+	// Act
+	start := time.Now()
+	found, err := repo.FindByLongURL(context.Background(), "https://example.com")
+	elapsed := time.Since(start)
+
+	// Assert
+	assert.Nil(t, found)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, elapsed, time.Second)
+}
+
+func TestSQLiteRepository_UpdateLastAccessed_CopiesCachedURL(t *testing.T) {
+	// Arrange
+	cleanupTestDB(t)
+	defer cleanupTestDB(t)
+	cacheLRU := cache.NewNamespaceLRU(100)
+	repo, err := NewSQLiteRepository(testDBPath, cacheLRU)
+	assert.NoError(t, err)
+	defer repo.Close()
+	ctx := context.Background()
+	held := &shortener.URL{LongURL: "https://example.com", ShortCode: "abc123", CreatedAt: time.Now()}
+	assert.NoError(t, repo.Store(ctx, held))
+	cacheLRU.Set(constant.ShortURLNamespace, "abc123", held)
+	at := time.Now().UTC()
+
+	// Act
+	assert.NoError(t, repo.IncrementVisits(ctx, "abc123"))
+	assert.NoError(t, repo.UpdateLastAccessed(ctx, "abc123", at))
+
+	// Assert - the URL callers already hold is left alone, the cache gets an updated copy
+	assert.Equal(t, uint(0), held.Visits)
+	assert.Nil(t, held.LastAccessedAt)
+	cached, found := cacheLRU.Get(constant.ShortURLNamespace, "abc123")
+	assert.True(t, found)
+	assert.NotSame(t, held, cached)
+	assert.Equal(t, uint(1), cached.(*shortener.URL).Visits)
+	assert.Equal(t, &at, cached.(*shortener.URL).LastAccessedAt)
+}
+
+func TestSQLiteRepository_BulkStore_ReportsRowsSkippedByInsert(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+	assert.NoError(t, repo.Store(ctx, &shortener.URL{LongURL: "https://example.com/taken", ShortCode: "taken1", CreatedAt: time.Now()}))
+	// Stands in for a short code taken between the lookup and the insert: the insert
+	// skips the row although its short code is free
+	assert.NoError(t, repo.db.Exec(`CREATE UNIQUE INDEX idx_test_long_url ON url_models (long_url)`).Error)
+	urls := []*shortener.URL{
+		{LongURL: "https://example.com/a", ShortCode: "new1", CreatedAt: time.Now()},
+		{LongURL: "https://example.com/taken", ShortCode: "new2", CreatedAt: time.Now()},
+		{LongURL: "https://example.com/b", ShortCode: "new3", CreatedAt: time.Now()},
+	}
+
+	// Act
+	result, err := repo.BulkStore(ctx, urls)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &shortener.BulkStoreResult{Stored: 2, Skipped: 1, Errors: []shortener.BulkStoreError{
+		{Index: 1, ShortCode: "new2", Reason: constant.ErrShortCodeExists},
+	}}, result)
+	for _, code := range []string{"new1", "new3"} {
+		_, err := repo.FindByShortCode(ctx, code)
+		assert.NoError(t, err, code)
+	}
+	_, err = repo.FindByShortCode(ctx, "new2")
+	assert.ErrorIs(t, err, shortener.ErrShortCodeNotFound)
+}
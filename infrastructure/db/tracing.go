@@ -0,0 +1,67 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/prasetyowira/shorter/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanInstanceKey stores the active span on a gorm statement between callbacks
+const spanInstanceKey = "tracing:span"
+
+// registerTracing adds gorm callbacks that wrap every statement in a client
+// span parented to the span carried by the statement's context. system is the
+// db.system attribute identifying the database.
+func registerTracing(db *gorm.DB, system attribute.KeyValue) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startSpan("create", system)),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endSpan),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startSpan("query", system)),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endSpan),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startSpan("update", system)),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endSpan),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startSpan("delete", system)),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startSpan("row", system)),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endSpan),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startSpan("raw", system)),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan),
+	)
+}
+
+// startSpan returns a callback that opens a span for the statement
+func startSpan(operation string, system attribute.KeyValue) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx, span := tracing.Tracer().Start(tx.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(system),
+		)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanInstanceKey, span)
+	}
+}
+
+// endSpan records the executed SQL and any error, then closes the span
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanInstanceKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(semconv.DBQueryText(tx.Statement.SQL.String()))
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// Visit queue defaults
+const (
+	DefaultVisitBufferSize    = 1000
+	DefaultVisitBatchSize     = 50
+	DefaultVisitFlushInterval = 500 * time.Millisecond
+)
+
+// VisitQueue buffers visit increments and writes them to the database in batches
+type VisitQueue struct {
+	repo          Repository
+	visits        chan queuedVisit
+	batchSize     int
+	flushInterval time.Duration
+	mutex         sync.RWMutex
+	closed        bool
+	done          chan struct{}
+}
+
+// NewVisitQueue creates a visit queue with the given channel buffer size and starts its worker
+func NewVisitQueue(repo Repository, bufferSize int) *VisitQueue {
+	return newVisitQueue(repo, bufferSize, DefaultVisitBatchSize, DefaultVisitFlushInterval)
+}
+
+func newVisitQueue(repo Repository, bufferSize, batchSize int, flushInterval time.Duration) *VisitQueue {
+	q := &VisitQueue{
+		repo:          repo,
+		visits:        make(chan queuedVisit, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// queuedVisit is a single visit waiting in the queue
+type queuedVisit struct {
+	shortCode string
+	at        time.Time
+}
+
+// Submit queues a visit for shortCode made now. When the queue is full or shut down
+// the visit is written synchronously so that no visit is lost.
+func (q *VisitQueue) Submit(shortCode string) {
+	visit := queuedVisit{shortCode: shortCode, at: time.Now()}
+
+	q.mutex.RLock()
+	if !q.closed {
+		select {
+		case q.visits <- visit:
+			q.mutex.RUnlock()
+			return
+		default:
+		}
+	}
+	q.mutex.RUnlock()
+
+	_ = q.repo.incrementVisitsBatch(context.Background(), map[string]visitBatch{
+		shortCode: {count: 1, lastAccessed: visit.at},
+	})
+}
+
+// Shutdown stops accepting visits and waits for queued visits to be flushed
+func (q *VisitQueue) Shutdown(ctx context.Context) error {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.visits)
+	}
+	q.mutex.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects visits and flushes them when the batch is full or the interval elapses
+func (q *VisitQueue) run() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.flushInterval)
+	defer ticker.Stop()
+
+	batch := make(map[string]visitBatch)
+	pending := 0
+	flush := func() {
+		if pending == 0 {
+			return
+		}
+		q.flush(batch)
+		batch = make(map[string]visitBatch)
+		pending = 0
+	}
+
+	for {
+		select {
+		case visit, ok := <-q.visits:
+			if !ok {
+				flush()
+				return
+			}
+			entry := batch[visit.shortCode]
+			entry.count++
+			if visit.at.After(entry.lastAccessed) {
+				entry.lastAccessed = visit.at
+			}
+			batch[visit.shortCode] = entry
+			pending++
+			if pending >= q.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// flush writes a batch of visit counts to the database
+func (q *VisitQueue) flush(visits map[string]visitBatch) {
+	ctx := context.Background()
+
+	if err := q.repo.incrementVisitsBatch(ctx, visits); err != nil {
+		appLogger.CtxError(ctx, "Failed to flush visit batch", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxVisitQueue,
+			Error: &appLogger.CustomError{
+				Code:    constant.ErrCodeDBIncrement,
+				Message: err.Error(),
+				Type:    constant.ErrTypeDB,
+			},
+			Data: map[string]interface{}{
+				constant.DataCount: len(visits),
+			},
+		})
+		return
+	}
+
+	appLogger.CtxDebug(ctx, "Visit batch flushed", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxVisitQueue,
+		Data: map[string]interface{}{
+			constant.DataCount: len(visits),
+		},
+	})
+}
+
+// incrementVisitsBatch adds each count to its short code's visits and moves its last
+// access time forward, in a single transaction; a zero lastAccessed leaves the time unchanged
+func (r *gormRepository) incrementVisitsBatch(ctx context.Context, visits map[string]visitBatch) error {
+	tx := r.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	for shortCode, batch := range visits {
+		var err error
+		if batch.lastAccessed.IsZero() {
+			err = tx.Exec(`UPDATE url_models SET visits = visits + ? WHERE short_code = ?`, batch.count, shortCode).Error
+		} else {
+			at := batch.lastAccessed.UTC()
+			err = tx.Exec(`UPDATE url_models SET visits = visits + ?,
+				last_accessed_at = CASE WHEN last_accessed_at IS NULL OR last_accessed_at < ? THEN ? ELSE last_accessed_at END
+				WHERE short_code = ?`, batch.count, at, at, shortCode).Error
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	for shortCode, batch := range visits {
+		r.touchCachedURL(shortCode, batch.count, batch.lastAccessed.UTC())
+	}
+
+	return nil
+}
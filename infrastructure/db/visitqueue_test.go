@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/stretchr/testify/assert"
+)
+
+// storeTestURL stores a URL with the given short code for visit queue tests
+func storeTestURL(t *testing.T, repo *SQLiteRepository, shortCode string) {
+	err := repo.Store(context.Background(), &shortener.URL{
+		LongURL:   "https://example.com/" + shortCode,
+		ShortCode: shortCode,
+		CreatedAt: time.Now(),
+	})
+	assert.NoError(t, err)
+}
+
+// visitsFor reads the persisted visit count for a short code
+func visitsFor(t *testing.T, repo *SQLiteRepository, shortCode string) uint {
+	url, err := repo.FindByShortCode(context.Background(), shortCode)
+	assert.NoError(t, err)
+	return url.Visits
+}
+
+func TestVisitQueue_FlushesFullBatch(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	storeTestURL(t, repo, "abc123")
+	storeTestURL(t, repo, "def456")
+
+	queue := newVisitQueue(repo, 100, 5, time.Hour)
+	defer queue.Shutdown(context.Background())
+
+	// Act
+	for i := 0; i < 3; i++ {
+		queue.Submit("abc123")
+	}
+	queue.Submit("def456")
+	queue.Submit("def456")
+
+	// Assert - the batch size is reached long before the flush interval
+	assert.Eventually(t, func() bool {
+		return visitsFor(t, repo, "abc123") == 3 && visitsFor(t, repo, "def456") == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestVisitQueue_UpdatesLastAccessed(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	storeTestURL(t, repo, "abc123")
+
+	queue := newVisitQueue(repo, 100, 50, time.Hour)
+	lastAccessed := func() *time.Time {
+		url, err := repo.FindByShortCode(context.Background(), "abc123")
+		assert.NoError(t, err)
+		return url.LastAccessedAt
+	}
+
+	// Act
+	before := time.Now()
+	queue.Submit("abc123")
+	queue.Submit("abc123")
+	assert.NoError(t, queue.Shutdown(context.Background()))
+	first := lastAccessed()
+
+	time.Sleep(5 * time.Millisecond)
+	queue.Submit("abc123")
+	second := lastAccessed()
+
+	// Assert
+	if assert.NotNil(t, first) && assert.NotNil(t, second) {
+		assert.False(t, first.Before(before.Truncate(time.Microsecond)))
+		assert.True(t, second.After(*first), "each access moves the time forward")
+	}
+	assert.Equal(t, uint(3), visitsFor(t, repo, "abc123"))
+}
+
+func TestVisitQueue_FlushesOnInterval(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	storeTestURL(t, repo, "abc123")
+
+	queue := newVisitQueue(repo, 100, 50, 20*time.Millisecond)
+	defer queue.Shutdown(context.Background())
+
+	// Act
+	queue.Submit("abc123")
+	queue.Submit("abc123")
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		return visitsFor(t, repo, "abc123") == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestVisitQueue_ShutdownDrains(t *testing.T) {
+	// Arrange
+	repo := createTestRepository(t)
+	defer cleanupTestDB(t)
+	defer repo.Close()
+	storeTestURL(t, repo, "abc123")
+
+	queue := newVisitQueue(repo, 100, 50, time.Hour)
+	for i := 0; i < 4; i++ {
+		queue.Submit("abc123")
+	}
+
+	// Act
+	err := queue.Shutdown(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint(4), visitsFor(t, repo, "abc123"))
+
+	// Visits submitted after shutdown are still counted synchronously
+	queue.Submit("abc123")
+	assert.Equal(t, uint(5), visitsFor(t, repo, "abc123"))
+}
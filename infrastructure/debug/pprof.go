@@ -0,0 +1,20 @@
+// Package debug serves runtime profiling endpoints for diagnosing a running process
+package debug
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// PprofHandler returns a handler serving the standard net/http/pprof endpoints
+// under /debug/pprof/. It exposes process internals, so serve it on a separate
+// listener that only operators can reach.
+func PprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
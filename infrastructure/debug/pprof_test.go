@@ -0,0 +1,36 @@
+package debug
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPprofHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+	}{
+		{name: "Index", target: "/debug/pprof/", expectedStatus: http.StatusOK},
+		{name: "Named profile", target: "/debug/pprof/goroutine?debug=1", expectedStatus: http.StatusOK},
+		{name: "Command line", target: "/debug/pprof/cmdline", expectedStatus: http.StatusOK},
+		{name: "Symbol", target: "/debug/pprof/symbol", expectedStatus: http.StatusOK},
+		{name: "Outside pprof", target: "/api/v1/urls", expectedStatus: http.StatusNotFound},
+	}
+
+	handler := PprofHandler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
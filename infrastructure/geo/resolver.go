@@ -0,0 +1,52 @@
+package geo
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// ErrInvalidIP is returned when the address to resolve is not an IP address
+var ErrInvalidIP = errors.New("geo: invalid IP address")
+
+// Resolver looks up the country of a visitor
+type Resolver interface {
+	// Country returns the upper-case ISO 3166-1 alpha-2 code of the country ip is
+	// located in, or "" when the database does not know it
+	Country(ip string) (string, error)
+}
+
+// MaxMindResolver resolves countries from a MaxMind GeoLite2 or GeoIP2 Country database
+type MaxMindResolver struct {
+	reader *geoip2.Reader
+}
+
+// NewMaxMindResolver opens the .mmdb database at path
+func NewMaxMindResolver(path string) (*MaxMindResolver, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MaxMindResolver{reader: reader}, nil
+}
+
+// Country implements Resolver
+func (r *MaxMindResolver) Country(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", ErrInvalidIP
+	}
+
+	record, err := r.reader.Country(addr)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(record.Country.IsoCode), nil
+}
+
+// Close releases the database
+func (r *MaxMindResolver) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,35 @@
+package geo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMaxMindResolver_Errors(t *testing.T) {
+	notDatabase := filepath.Join(t.TempDir(), "countries.mmdb")
+	if err := os.WriteFile(notDatabase, []byte("not a maxmind database"), 0o600); err != nil {
+		t.Fatalf("Failed to write database file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "Missing file", path: filepath.Join(t.TempDir(), "missing.mmdb")},
+		{name: "Not a database", path: notDatabase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			resolver, err := NewMaxMindResolver(tt.path)
+
+			// Assert
+			assert.Error(t, err)
+			assert.Nil(t, resolver)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+)
+
+// DefaultCleanupInterval is how often expired URLs are purged when no interval is configured
+const DefaultCleanupInterval = time.Hour
+
+// ExpiredURLStore finds expired URLs and soft-deletes them
+type ExpiredURLStore interface {
+	FindExpired(ctx context.Context, now time.Time) ([]*shortener.URL, error)
+	Delete(ctx context.Context, shortCode string) error
+}
+
+// ExpiryCleanerJob periodically soft-deletes URLs that have passed their expiry,
+// so URLs nobody visits after they expire do not linger as live rows
+type ExpiryCleanerJob struct {
+	store    ExpiredURLStore
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewExpiryCleanerJob creates a job purging expired URLs every interval;
+// a non-positive interval means DefaultCleanupInterval
+func NewExpiryCleanerJob(store ExpiredURLStore, interval time.Duration) *ExpiryCleanerJob {
+	if interval <= 0 {
+		interval = DefaultCleanupInterval
+	}
+	return &ExpiryCleanerJob{store: store, interval: interval, now: time.Now}
+}
+
+// Run purges expired URLs every interval until ctx is cancelled, logging the runs that fail
+func (j *ExpiryCleanerJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				appLogger.CtxError(ctx, "Failed to find expired URLs", appLogger.LoggerInfo{
+					ContextFunction: constant.CtxExpiryCleaner,
+					Error: &appLogger.CustomError{
+						Code:    constant.ErrCodeDBFindExpired,
+						Message: err.Error(),
+						Type:    constant.ErrTypeDB,
+					},
+				})
+			}
+		}
+	}
+}
+
+// RunOnce soft-deletes every URL expired by now and returns how many were deleted.
+// A failed delete is logged and skipped so one bad row does not block the rest.
+func (j *ExpiryCleanerJob) RunOnce(ctx context.Context) (int, error) {
+	urls, err := j.store.FindExpired(ctx, j.now())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, url := range urls {
+		if err := j.store.Delete(ctx, url.ShortCode); err != nil {
+			appLogger.CtxError(ctx, "Failed to delete expired URL", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxExpiryCleaner,
+				Error: &appLogger.CustomError{
+					Code:    constant.ErrCodeDBDelete,
+					Message: err.Error(),
+					Type:    constant.ErrTypeDB,
+				},
+				Data: map[string]interface{}{
+					constant.DataShortCode: url.ShortCode,
+				},
+			})
+			continue
+		}
+		deleted++
+	}
+
+	if deleted > 0 {
+		appLogger.CtxInfo(ctx, "Purged expired URLs", appLogger.LoggerInfo{
+			ContextFunction: constant.CtxExpiryCleaner,
+			Data: map[string]interface{}{
+				constant.DataCount: deleted,
+			},
+		})
+	}
+	return deleted, nil
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// mockExpiredURLStore is a mock implementation of ExpiredURLStore
+type mockExpiredURLStore struct {
+	mock.Mock
+}
+
+func (m *mockExpiredURLStore) FindExpired(ctx context.Context, now time.Time) ([]*shortener.URL, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]*shortener.URL), args.Error(1)
+}
+
+func (m *mockExpiredURLStore) Delete(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func TestExpiryCleanerJob_RunOnce(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	store := new(mockExpiredURLStore)
+	store.On("FindExpired", mock.Anything, now).Return([]*shortener.URL{{ShortCode: "old1"}, {ShortCode: "broken"}, {ShortCode: "old2"}}, nil)
+	store.On("Delete", mock.Anything, "old1").Return(nil)
+	store.On("Delete", mock.Anything, "broken").Return(errors.New("database is locked"))
+	store.On("Delete", mock.Anything, "old2").Return(nil)
+	job := NewExpiryCleanerJob(store, time.Minute)
+	job.now = func() time.Time { return now }
+
+	// Act
+	deleted, err := job.RunOnce(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted, "a failed delete is skipped")
+	store.AssertExpectations(t)
+}
+
+func TestExpiryCleanerJob_RunOnce_FindError(t *testing.T) {
+	// Arrange
+	store := new(mockExpiredURLStore)
+	store.On("FindExpired", mock.Anything, mock.Anything).Return([]*shortener.URL(nil), errors.New("database is closed"))
+	job := NewExpiryCleanerJob(store, time.Minute)
+
+	// Act
+	deleted, err := job.RunOnce(context.Background())
+
+	// Assert
+	assert.EqualError(t, err, "database is closed")
+	assert.Zero(t, deleted)
+	store.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestExpiryCleanerJob_Run(t *testing.T) {
+	// Arrange
+	store := new(mockExpiredURLStore)
+	found := make(chan struct{}, 10)
+	store.On("FindExpired", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { found <- struct{}{} }).
+		Return([]*shortener.URL{}, nil)
+	job := NewExpiryCleanerJob(store, 5*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		job.Run(ctx)
+		close(done)
+	}()
+	<-found
+	cancel()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
+
+func TestExpiryCleanerJob_Run_LogsFindError(t *testing.T) {
+	// Arrange
+	core, logs := observer.New(zapcore.ErrorLevel)
+	t.Cleanup(appLogger.ReplaceLogger(zap.New(core)))
+	store := new(mockExpiredURLStore)
+	store.On("FindExpired", mock.Anything, mock.Anything).Return([]*shortener.URL(nil), errors.New("database is closed"))
+	job := NewExpiryCleanerJob(store, 5*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		job.Run(ctx)
+		close(done)
+	}()
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		return logs.FilterMessage("Failed to find expired URLs").Len() > 0
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	entry := logs.FilterMessage("Failed to find expired URLs").All()[0]
+	assert.Equal(t, constant.ErrCodeDBFindExpired, entry.ContextMap()[constant.LogErrorCodeKey])
+}
+
+func TestNewExpiryCleanerJob_DefaultInterval(t *testing.T) {
+	// Act
+	job := NewExpiryCleanerJob(new(mockExpiredURLStore), 0)
+
+	// Assert
+	assert.Equal(t, DefaultCleanupInterval, job.interval)
+}
@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/prasetyowira/shorter/constant"
+	"github.com/prasetyowira/shorter/domain/shortener"
+	appLogger "github.com/prasetyowira/shorter/infrastructure/logger"
+	"github.com/prasetyowira/shorter/infrastructure/security"
+)
+
+// Webhook delivery defaults
+const (
+	DefaultWebhookAttempts = 3
+	DefaultWebhookBackoff  = 500 * time.Millisecond
+	DefaultWebhookTimeout  = 5 * time.Second
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of a webhook body
+const SignatureHeader = "X-Shorter-Signature"
+
+// WebhookSender POSTs signed JSON payloads to webhooks, retrying failed deliveries
+// with exponential backoff
+type WebhookSender struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// errPrivateAddress is returned when a webhook host resolves to a private address at delivery
+var errPrivateAddress = errors.New("webhook address is private")
+
+// NewWebhookSender creates a sender with the default attempts, backoff and timeout. The
+// URL is only checked against private hosts when the webhook is created, so the client
+// follows no redirects and refuses to dial private addresses the host resolves to later.
+// It connects directly rather than through an environment proxy, so the dialed address
+// is always the webhook host.
+func NewWebhookSender() *WebhookSender {
+	dialer := &net.Dialer{Timeout: DefaultWebhookTimeout, Control: refusePrivateAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	client := &http.Client{
+		Timeout:       DefaultWebhookTimeout,
+		Transport:     transport,
+		CheckRedirect: refuseRedirects,
+	}
+	return newWebhookSender(client, DefaultWebhookAttempts, DefaultWebhookBackoff)
+}
+
+func newWebhookSender(client *http.Client, attempts int, backoff time.Duration) *WebhookSender {
+	return &WebhookSender{
+		client:   client,
+		attempts: attempts,
+		backoff:  backoff,
+	}
+}
+
+// refuseRedirects returns redirect responses as they are, so a 3xx fails the delivery
+func refuseRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// refusePrivateAddress rejects connections to private IPs after DNS resolution
+func refusePrivateAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || security.IsPrivateIP(ip) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
+
+// Send delivers payload to hook, retrying until a 2xx response or the attempts run out
+func (s *WebhookSender) Send(ctx context.Context, hook shortener.Webhook, payload shortener.WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	signature := security.Sign(body, hook.Secret)
+
+	delay := s.backoff
+	for attempt := 1; ; attempt++ {
+		err = s.deliver(ctx, hook.URL, body, signature)
+		if err == nil {
+			appLogger.CtxDebug(ctx, "Webhook delivered", appLogger.LoggerInfo{
+				ContextFunction: constant.CtxWebhookDelivery,
+				Data: map[string]interface{}{
+					constant.DataWebhookURL: hook.URL,
+					constant.DataEvent:      payload.Event,
+					constant.DataAttempt:    attempt,
+				},
+			})
+			return nil
+		}
+
+		if attempt >= s.attempts {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+
+	appLogger.CtxWarn(ctx, "Webhook delivery failed", appLogger.LoggerInfo{
+		ContextFunction: constant.CtxWebhookDelivery,
+		Error: &appLogger.CustomError{
+			Code:    constant.ErrCodeWebhookDelivery,
+			Message: err.Error(),
+			Type:    constant.ErrTypeStats,
+		},
+		Data: map[string]interface{}{
+			constant.DataWebhookURL: hook.URL,
+			constant.DataEvent:      payload.Event,
+			constant.DataAttempt:    s.attempts,
+		},
+	})
+	return err
+}
+
+// deliver makes a single delivery attempt
+func (s *WebhookSender) deliver(ctx context.Context, target string, body []byte, signature string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
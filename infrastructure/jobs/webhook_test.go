@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prasetyowira/shorter/domain/shortener"
+	"github.com/prasetyowira/shorter/infrastructure/security"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSender_Send(t *testing.T) {
+	// Arrange
+	var received shortener.WebhookPayload
+	var signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := newWebhookSender(server.Client(), 3, time.Millisecond)
+	hook := shortener.Webhook{URL: server.URL, ShortCode: "abc123", Secret: "topsecret"}
+	payload := shortener.WebhookPayload{Event: shortener.WebhookEventVisit, ShortCode: "abc123", LongURL: "https://example.com"}
+
+	// Act
+	err := sender.Send(context.Background(), hook, payload)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, payload.ShortCode, received.ShortCode)
+	assert.Equal(t, payload.Event, received.Event)
+	assert.True(t, security.VerifySignature(body, signature, "topsecret"))
+}
+
+func TestWebhookSender_Send_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{name: "Succeeds first time", failures: 0, wantAttempts: 1},
+		{name: "Succeeds on last attempt", failures: 2, wantAttempts: 3},
+		{name: "Gives up after three attempts", failures: 5, wantErr: true, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			sender := newWebhookSender(server.Client(), 3, time.Millisecond)
+
+			// Act
+			err := sender.Send(context.Background(), shortener.Webhook{URL: server.URL, Secret: "s"}, shortener.WebhookPayload{Event: shortener.WebhookEventVisit})
+
+			// Assert
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantAttempts, attempts.Load())
+		})
+	}
+}
+
+func TestWebhookSender_Send_Backoff(t *testing.T) {
+	// Arrange
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sender := newWebhookSender(server.Client(), 3, 20*time.Millisecond)
+
+	// Act
+	err := sender.Send(context.Background(), shortener.Webhook{URL: server.URL, Secret: "s"}, shortener.WebhookPayload{})
+
+	// Assert
+	assert.Error(t, err)
+	if assert.Len(t, times, 3) {
+		assert.GreaterOrEqual(t, times[1].Sub(times[0]), 20*time.Millisecond)
+		assert.GreaterOrEqual(t, times[2].Sub(times[1]), 40*time.Millisecond)
+	}
+}
+
+func TestWebhookSender_Send_RefusesRedirects(t *testing.T) {
+	// Arrange
+	var redirected int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&redirected, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer internal.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.CheckRedirect = refuseRedirects
+	sender := newWebhookSender(client, 1, time.Millisecond)
+	hook := shortener.Webhook{URL: server.URL, ShortCode: "abc123", Secret: "topsecret"}
+
+	// Act
+	err := sender.Send(context.Background(), hook, shortener.WebhookPayload{Event: shortener.WebhookEventVisit})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&redirected))
+}
+
+func TestWebhookSender_Send_RefusesPrivateAddress(t *testing.T) {
+	// Arrange
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := NewWebhookSender()
+	sender.backoff = time.Millisecond
+	hook := shortener.Webhook{URL: server.URL, ShortCode: "abc123", Secret: "topsecret"}
+
+	// Act
+	err := sender.Send(context.Background(), hook, shortener.WebhookPayload{Event: shortener.WebhookEventVisit})
+
+	// Assert
+	assert.ErrorIs(t, err, errPrivateAddress)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/prasetyowira/shorter/constant"
@@ -15,6 +16,9 @@ import (
 
 var logger *zap.Logger
 
+// Level is the logger's minimum enabled level; changing it takes effect immediately
+var Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
 // LoggerContext represents the context for log entries
 type LoggerContext struct {
 	RequestID string
@@ -39,10 +43,11 @@ type CustomError struct {
 // Initialize sets up the logger
 func Initialize(isProduction bool) {
 	// Default level
-	logLevel := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	Level.SetLevel(zapcore.DebugLevel)
 	if isProduction {
-		logLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+		Level.SetLevel(zapcore.InfoLevel)
 	}
+	logLevel := Level
 
 	// Create encoder config
 	encoderConfig := zapcore.EncoderConfig{
@@ -101,6 +106,40 @@ func Initialize(isProduction bool) {
 	// The application should call Close() on shutdown
 }
 
+// ErrInvalidLogLevel is returned by SetLevel for a name it does not accept
+var ErrInvalidLogLevel = errors.New(constant.ErrInvalidLogLevel)
+
+// SetLevel changes the minimum enabled level at runtime. Accepted names are
+// debug, info, warn and error, in any case.
+func SetLevel(level string) error {
+	switch strings.ToLower(level) {
+	case "debug":
+		Level.SetLevel(zapcore.DebugLevel)
+	case "info":
+		Level.SetLevel(zapcore.InfoLevel)
+	case "warn":
+		Level.SetLevel(zapcore.WarnLevel)
+	case "error":
+		Level.SetLevel(zapcore.ErrorLevel)
+	default:
+		return ErrInvalidLogLevel
+	}
+	return nil
+}
+
+// DebugEnabled reports whether debug entries are currently logged
+func DebugEnabled() bool {
+	return Level.Enabled(zapcore.DebugLevel)
+}
+
+// ReplaceLogger swaps the package logger for l and returns a function that
+// restores the previous one. Tests use it to capture log output.
+func ReplaceLogger(l *zap.Logger) (restore func()) {
+	previous := logger
+	logger = l
+	return func() { logger = previous }
+}
+
 // Close ensures logger syncs before shutdown
 func Close() {
 	if logger != nil {
@@ -1,9 +1,64 @@
 package qrcode
 
 import (
+	"bytes"
+	"fmt"
+	"image/color"
+
 	"github.com/skip2/go-qrcode"
 )
 
+// RecoveryLevel is the error correction level of a QR code
+type RecoveryLevel = qrcode.RecoveryLevel
+
+// Error correction levels, named after the QR code L, M, Q and H levels. Higher
+// levels survive more damage at the cost of denser codes.
+const (
+	ECLow      = qrcode.Low
+	ECMedium   = qrcode.Medium
+	ECQuartile = qrcode.High
+	ECHigh     = qrcode.Highest
+)
+
+// QROptions controls the colors and error correction of a rendered QR code
+type QROptions struct {
+	// FGColor paints the dark modules; nil means black
+	FGColor color.Color
+	// BGColor paints the light modules and the quiet zone; nil means white
+	BGColor color.Color
+	// ECLevel is the error correction level; the zero value is ECLow
+	ECLevel RecoveryLevel
+}
+
+// DefaultQROptions renders black on white with medium error correction
+func DefaultQROptions() QROptions {
+	return QROptions{
+		FGColor: color.RGBA{A: 0xff},
+		BGColor: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+		ECLevel: ECMedium,
+	}
+}
+
+// colors returns the foreground and background colors, filling in the defaults for nil ones
+func (o QROptions) colors() (fg, bg color.Color) {
+	fg, bg = o.FGColor, o.BGColor
+	if fg == nil {
+		fg = color.Black
+	}
+	if bg == nil {
+		bg = color.White
+	}
+	return fg, bg
+}
+
+// QRGenerator renders the QR code that points at a short URL
+type QRGenerator interface {
+	// GenerateQRCode renders a size x size pixel PNG
+	GenerateQRCode(shortCode string, size int, opts QROptions) ([]byte, error)
+	// GenerateQRCodeSVG renders an SVG document size units wide
+	GenerateQRCodeSVG(shortCode string, size int, opts QROptions) ([]byte, error)
+}
+
 // Generator handles QR code generation
 type Generator struct {
 	baseURL string
@@ -17,16 +72,59 @@ func NewGenerator(baseURL string) *Generator {
 }
 
 // GenerateQRCode generates a QR code for a short URL
-func (g *Generator) GenerateQRCode(shortCode string, size int) ([]byte, error) {
+func (g *Generator) GenerateQRCode(shortCode string, size int, opts QROptions) ([]byte, error) {
 	// Combine base URL with short code
 	targetURL := g.baseURL + "/" + shortCode
-	
+
+	code, err := qrcode.New(targetURL, opts.ECLevel)
+	if err != nil {
+		return nil, err
+	}
+	code.ForegroundColor, code.BackgroundColor = opts.colors()
+
 	// Generate QR code as PNG
-	var png []byte
-	png, err := qrcode.Encode(targetURL, qrcode.Medium, size)
+	png, err := code.PNG(size)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	return png, nil
-} 
\ No newline at end of file
+}
+
+// GenerateQRCodeSVG generates a QR code for a short URL as an SVG document
+func (g *Generator) GenerateQRCodeSVG(shortCode string, size int, opts QROptions) ([]byte, error) {
+	targetURL := g.baseURL + "/" + shortCode
+
+	code, err := qrcode.New(targetURL, opts.ECLevel)
+	if err != nil {
+		return nil, err
+	}
+	fg, bg := opts.colors()
+
+	// The bitmap includes the quiet zone, so one SVG unit maps to one module
+	bitmap := code.Bitmap()
+	modules := len(bitmap)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, modules, modules, hexColor(bg))
+
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(fg))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+// hexColor formats c as an opaque "#rrggbb" SVG color
+func hexColor(c color.Color) string {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
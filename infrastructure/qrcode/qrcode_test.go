@@ -0,0 +1,96 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_GenerateQRCode(t *testing.T) {
+	// Arrange
+	generator := NewGenerator("http://localhost:8080")
+
+	// Act
+	png, err := generator.GenerateQRCode("abc123", 256, DefaultQROptions())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(png, []byte("\x89PNG")))
+}
+
+func TestGenerator_GenerateQRCode_Colors(t *testing.T) {
+	// Arrange
+	generator := NewGenerator("http://localhost:8080")
+	fg := color.RGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}
+	bg := color.RGBA{R: 0xff, G: 0xeb, B: 0x3b, A: 0xff}
+	size := 256
+
+	// Act
+	data, err := generator.GenerateQRCode("abc123", size, QROptions{FGColor: fg, BGColor: bg, ECLevel: ECHigh})
+
+	// Assert
+	assert.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	colorAt := func(x, y int) color.RGBA {
+		return color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+	}
+
+	// The corners fall in the quiet zone, which takes the background color
+	bounds := img.Bounds()
+	assert.Equal(t, bg, colorAt(bounds.Min.X, bounds.Min.Y))
+	assert.Equal(t, bg, colorAt(bounds.Max.X-1, bounds.Min.Y))
+	assert.Equal(t, bg, colorAt(bounds.Min.X, bounds.Max.Y-1))
+	assert.Equal(t, bg, colorAt(bounds.Max.X-1, bounds.Max.Y-1))
+
+	// Moving diagonally in from the top-left corner reaches the dark outer ring of a finder pattern
+	var finder color.RGBA
+	for i := 0; i < size/2; i++ {
+		if c := colorAt(i, i); c != bg {
+			finder = c
+			break
+		}
+	}
+	assert.Equal(t, fg, finder)
+}
+
+func TestGenerator_GenerateQRCodeSVG(t *testing.T) {
+	// Arrange
+	generator := NewGenerator("http://localhost:8080")
+
+	// Act
+	svg, err := generator.GenerateQRCodeSVG("abc123", 512, DefaultQROptions())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(svg, []byte("<svg")))
+	assert.Contains(t, string(svg), `width="512" height="512"`)
+	assert.Contains(t, string(svg), `fill="#ffffff"`)
+	assert.Contains(t, string(svg), `fill="#000000"`)
+	assert.True(t, bytes.HasSuffix(svg, []byte("</svg>")))
+}
+
+func TestGenerator_GenerateQRCodeSVG_Colors(t *testing.T) {
+	// Arrange
+	generator := NewGenerator("http://localhost:8080")
+	opts := QROptions{
+		FGColor: color.RGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff},
+		BGColor: color.RGBA{R: 0xff, G: 0xeb, B: 0x3b, A: 0xff},
+		ECLevel: ECMedium,
+	}
+
+	// Act
+	svg, err := generator.GenerateQRCodeSVG("abc123", 256, opts)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Contains(t, string(svg), `<rect width=`)
+	assert.Contains(t, string(svg), `fill="#ffeb3b"`)
+	assert.Contains(t, string(svg), `<path fill="#1a237e"`)
+}
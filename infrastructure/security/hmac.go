@@ -0,0 +1,37 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// signaturePrefix names the hash in a signature header value
+const signaturePrefix = "sha256="
+
+// Sign returns the signature header value for payload: "sha256=" followed by
+// the hex HMAC-SHA256 of payload keyed with secret
+func Sign(payload []byte, secret string) string {
+	return signaturePrefix + hex.EncodeToString(computeMAC(payload, secret))
+}
+
+// VerifySignature reports whether signature, in the "sha256=<hex>" form produced
+// by Sign, matches payload under secret. The comparison is constant-time.
+func VerifySignature(payload []byte, signature, secret string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, computeMAC(payload, secret))
+}
+
+// computeMAC returns the raw HMAC-SHA256 of payload keyed with secret
+func computeMAC(payload []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
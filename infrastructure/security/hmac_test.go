@@ -0,0 +1,41 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Known vector: HMAC-SHA256("key", "The quick brown fox jumps over the lazy dog")
+const (
+	vectorPayload   = "The quick brown fox jumps over the lazy dog"
+	vectorSecret    = "key"
+	vectorSignature = "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+)
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, vectorSignature, Sign([]byte(vectorPayload), vectorSecret))
+}
+
+func TestVerifySignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		signature string
+		secret    string
+		expected  bool
+	}{
+		{name: "Known vector", payload: vectorPayload, signature: vectorSignature, secret: vectorSecret, expected: true},
+		{name: "Tampered payload", payload: vectorPayload + ".", signature: vectorSignature, secret: vectorSecret, expected: false},
+		{name: "Wrong secret", payload: vectorPayload, signature: vectorSignature, secret: "other", expected: false},
+		{name: "Missing prefix", payload: vectorPayload, signature: vectorSignature[len("sha256="):], secret: vectorSecret, expected: false},
+		{name: "Invalid hex", payload: vectorPayload, signature: "sha256=zz", secret: vectorSecret, expected: false},
+		{name: "Empty signature", payload: vectorPayload, signature: "", secret: vectorSecret, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, VerifySignature([]byte(tt.payload), tt.signature, tt.secret))
+		})
+	}
+}
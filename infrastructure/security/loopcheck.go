@@ -0,0 +1,24 @@
+package security
+
+import (
+	"net/url"
+	"strings"
+)
+
+// IsSelfRedirect reports whether target points at the host of baseURL, so that
+// redirecting to it sends the visitor back to this service. Hosts are compared
+// without case, port or trailing dot. Subdomains are other sites and do not match,
+// nor does a URL without a host.
+func IsSelfRedirect(target, baseURL string) bool {
+	targetHost := hostName(target)
+	return targetHost != "" && targetHost == hostName(baseURL)
+}
+
+// hostName returns the lowercase host name of rawURL, or an empty string when it has none
+func hostName(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsedURL.Hostname()), ".")
+}
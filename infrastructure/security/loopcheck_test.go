@@ -0,0 +1,37 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSelfRedirect(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		baseURL  string
+		expected bool
+	}{
+		{name: "Same host", target: "https://sho.rt/abc123", baseURL: "https://sho.rt", expected: true},
+		{name: "Same host in another case", target: "https://SHO.RT/abc123", baseURL: "https://sho.rt", expected: true},
+		{name: "Same host on another port and scheme", target: "http://sho.rt:8080/abc123", baseURL: "https://sho.rt", expected: true},
+		{name: "Same host with trailing dot", target: "https://sho.rt./abc123", baseURL: "https://sho.rt", expected: true},
+		{name: "Different host", target: "https://example.com/page", baseURL: "https://sho.rt", expected: false},
+		{name: "Subdomain of base host", target: "https://blog.sho.rt/post", baseURL: "https://sho.rt", expected: false},
+		{name: "Parent of base host", target: "https://sho.rt/page", baseURL: "https://go.sho.rt", expected: false},
+		{name: "Host as path", target: "https://example.com/sho.rt", baseURL: "https://sho.rt", expected: false},
+		{name: "No host", target: "/abc123", baseURL: "https://sho.rt", expected: false},
+		{name: "Invalid target", target: "http://[::1", baseURL: "https://sho.rt", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := IsSelfRedirect(tt.target, tt.baseURL)
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"errors"
+	"net"
+	"net/url"
+)
+
+// errMissingHost is returned for URLs without a host to resolve
+var errMissingHost = errors.New("url has no host")
+
+// Guard rejects URLs whose host resolves to a private or loopback address
+type Guard struct {
+	lookupIP func(host string) ([]net.IP, error)
+}
+
+// NewGuard creates a Guard using the system resolver
+func NewGuard() *Guard {
+	return &Guard{lookupIP: net.LookupIP}
+}
+
+// defaultGuard backs the package-level IsPrivateURL
+var defaultGuard = NewGuard()
+
+// IsPrivateURL reports whether rawURL points at a private address using the system resolver
+func IsPrivateURL(rawURL string) (bool, error) {
+	return defaultGuard.IsPrivateURL(rawURL)
+}
+
+// IsPrivateURL reports whether any address of rawURL's host is loopback,
+// RFC 1918 / RFC 4193 private, link-local or unspecified. IP literals are
+// checked directly; hostnames are resolved first.
+func (g *Guard) IsPrivateURL(rawURL string) (bool, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+
+	host := parsedURL.Hostname()
+	if host == "" {
+		return false, errMissingHost
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return IsPrivateIP(ip), nil
+	}
+
+	ips, err := g.lookupIP(host)
+	if err != nil {
+		return false, err
+	}
+	for _, ip := range ips {
+		if IsPrivateIP(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsPrivateIP reports whether ip is not routable on the public internet
+func IsPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
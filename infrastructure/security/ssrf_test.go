@@ -0,0 +1,76 @@
+package security
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestGuard creates a Guard resolving hostnames from a fixed table
+func newTestGuard(hosts map[string][]net.IP) *Guard {
+	return &Guard{lookupIP: func(host string) ([]net.IP, error) {
+		ips, found := hosts[host]
+		if !found {
+			return nil, errors.New("no such host")
+		}
+		return ips, nil
+	}}
+}
+
+func TestGuard_IsPrivateURL(t *testing.T) {
+	guard := newTestGuard(map[string][]net.IP{
+		"public.example.com":   {net.ParseIP("93.184.216.34")},
+		"internal.example.com": {net.ParseIP("10.1.2.3")},
+		"mixed.example.com":    {net.ParseIP("93.184.216.34"), net.ParseIP("127.0.0.1")},
+	})
+
+	tests := []struct {
+		name        string
+		rawURL      string
+		expected    bool
+		expectError bool
+	}{
+		{name: "Loopback", rawURL: "http://127.0.0.1/admin", expected: true},
+		{name: "RFC 1918 10/8", rawURL: "http://10.0.0.1", expected: true},
+		{name: "RFC 1918 172.16/12", rawURL: "http://172.16.5.4:8080", expected: true},
+		{name: "RFC 1918 192.168/16", rawURL: "https://192.168.1.1/router", expected: true},
+		{name: "Link-local", rawURL: "http://169.254.169.254/latest/meta-data", expected: true},
+		{name: "IPv6 loopback", rawURL: "http://[::1]:8080", expected: true},
+		{name: "Unspecified", rawURL: "http://0.0.0.0", expected: true},
+		{name: "Public IP", rawURL: "https://8.8.8.8", expected: false},
+		{name: "Public hostname", rawURL: "https://public.example.com/page", expected: false},
+		{name: "Private hostname", rawURL: "https://internal.example.com", expected: true},
+		{name: "Any private address", rawURL: "https://mixed.example.com", expected: true},
+		{name: "Invalid hostname", rawURL: "https://nonexistent.invalid", expectError: true},
+		{name: "Missing host", rawURL: "not-a-url", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			private, err := guard.IsPrivateURL(tt.rawURL)
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, private)
+		})
+	}
+}
+
+func TestIsPrivateURL_IPLiterals(t *testing.T) {
+	// Act
+	loopback, loopbackErr := IsPrivateURL("http://127.0.0.1")
+	public, publicErr := IsPrivateURL("http://8.8.8.8")
+
+	// Assert
+	assert.NoError(t, loopbackErr)
+	assert.True(t, loopback)
+	assert.NoError(t, publicErr)
+	assert.False(t, public)
+}
@@ -0,0 +1,164 @@
+package urlcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prasetyowira/shorter/infrastructure/cache"
+	"github.com/prasetyowira/shorter/infrastructure/security"
+)
+
+// Settings of a Checker created with NewChecker
+const (
+	// DefaultTimeout bounds each HEAD request, redirects included
+	DefaultTimeout = 5 * time.Second
+	// DefaultCacheTTL is how long a URL found reachable is not checked again
+	DefaultCacheTTL = time.Hour
+	// DefaultFailureThreshold is how many requests in a row may fail to get a
+	// response before the circuit breaker opens
+	DefaultFailureThreshold = 5
+	// DefaultBreakerCooldown is how long an open circuit breaker lets URLs through unchecked
+	DefaultBreakerCooldown = time.Minute
+)
+
+// maxRedirects is how many redirects a check follows, as many as http.Client does by default
+const maxRedirects = 10
+
+// ErrPrivateRedirect is returned for URLs that redirect to a private or loopback address
+var ErrPrivateRedirect = errors.New("redirect to a private address")
+
+// errTooManyRedirects is returned for URLs that redirect more than maxRedirects times
+var errTooManyRedirects = fmt.Errorf("stopped after %d redirects", maxRedirects)
+
+// PrivateURLGuard decides whether a URL points at a private network address
+type PrivateURLGuard interface {
+	IsPrivateURL(rawURL string) (bool, error)
+}
+
+// reachableCacheSize caps how many reachable URLs are remembered
+const reachableCacheSize = 10000
+
+// reachableNamespace is the cache namespace of reachable URLs
+const reachableNamespace = "reachable"
+
+// Checker tells whether long URLs answer requests. A circuit breaker stops it from
+// rejecting every URL when this host, rather than the URLs, cannot reach the internet:
+// after DefaultFailureThreshold requests in a row to hosts that resolved get no response
+// at all, URLs are let through unchecked for DefaultBreakerCooldown. Error statuses are
+// answers and hosts that do not resolve are the URL's fault, so neither opens the
+// breaker and a run of mistyped URLs cannot switch the check off.
+type Checker struct {
+	client *http.Client
+	// guard vets every redirect, so a public URL cannot point the check at internal hosts
+	guard            PrivateURLGuard
+	reachable        cache.Cache
+	cacheTTL         time.Duration
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// NewChecker creates a Checker with its own HTTP client and the default settings,
+// refusing redirects to addresses security.Guard finds private
+func NewChecker() *Checker {
+	c := &Checker{
+		guard:            security.NewGuard(),
+		reachable:        cache.NewNamespaceLRU(reachableCacheSize),
+		cacheTTL:         DefaultCacheTTL,
+		failureThreshold: DefaultFailureThreshold,
+		cooldown:         DefaultBreakerCooldown,
+		now:              time.Now,
+	}
+	c.client = &http.Client{Timeout: DefaultTimeout, CheckRedirect: c.checkRedirect}
+	return c
+}
+
+// checkRedirect lets the client follow a redirect unless its destination is private or
+// it is one too many
+func (c *Checker) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+	private, err := c.guard.IsPrivateURL(req.URL.String())
+	if err != nil {
+		return err
+	}
+	if private {
+		return fmt.Errorf("%w: %s", ErrPrivateRedirect, req.URL.Redacted())
+	}
+	return nil
+}
+
+// Check returns nil when rawURL answers a HEAD request with a 2xx status, following
+// redirects to public addresses, and an error saying why it is unreachable otherwise.
+// It also returns nil, without a request, for URLs found reachable within the cache
+// TTL and while the circuit breaker is open.
+func (c *Checker) Check(ctx context.Context, rawURL string) error {
+	if _, found := c.reachable.Get(reachableNamespace, rawURL); found {
+		return nil
+	}
+	if c.isOpen() {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, ErrPrivateRedirect) || errors.Is(err, errTooManyRedirects):
+		// The redirect refused was an answer
+		c.recordResponse()
+		return err
+	case errors.As(err, &dnsErr):
+		return err
+	case err != nil:
+		c.recordFailure()
+		return err
+	}
+	resp.Body.Close()
+	c.recordResponse()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HEAD %s answered %s", rawURL, resp.Status)
+	}
+	c.reachable.SetWithTTL(reachableNamespace, rawURL, struct{}{}, c.cacheTTL)
+	return nil
+}
+
+// isOpen reports whether the circuit breaker lets URLs through unchecked
+func (c *Checker) isOpen() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now().Before(c.openUntil)
+}
+
+// recordFailure counts a request that got no response, opening the circuit breaker
+// at the threshold. Once a cooldown is over the count stays at the threshold, so the
+// first request to fail again reopens it.
+func (c *Checker) recordFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	if c.failures >= c.failureThreshold {
+		c.openUntil = c.now().Add(c.cooldown)
+	}
+}
+
+// recordResponse closes the circuit breaker after a request got a response
+func (c *Checker) recordResponse() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+}
@@ -0,0 +1,223 @@
+package urlcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingServer starts a server answering every request with status and counting them
+func newCountingServer(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// URLs whose connections fail without touching the network once useFailingTransport is applied
+const (
+	downURL    = "http://down.test/"
+	unknownURL = "http://unknown.test/"
+)
+
+// useFailingTransport makes checker's connections to down.test fail as if refused and
+// those to unknown.test as if its name did not resolve; other hosts are dialed as usual
+func useFailingTransport(checker *Checker) {
+	dialer := &net.Dialer{}
+	checker.client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			switch addr {
+			case "down.test:80":
+				return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+			case "unknown.test:80":
+				return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: "unknown.test", IsNotFound: true}}
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// allowAllGuard lets redirects reach the loopback test servers
+type allowAllGuard struct{}
+
+func (allowAllGuard) IsPrivateURL(rawURL string) (bool, error) {
+	return false, nil
+}
+
+func TestChecker_Check(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		expectError bool
+	}{
+		{name: "OK", status: http.StatusOK},
+		{name: "No content", status: http.StatusNoContent},
+		{name: "Not found", status: http.StatusNotFound, expectError: true},
+		{name: "Server error", status: http.StatusInternalServerError, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			server, _ := newCountingServer(t, tt.status)
+			checker := NewChecker()
+
+			// Act
+			err := checker.Check(context.Background(), server.URL+"/page")
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_FollowsRedirects(t *testing.T) {
+	// Arrange
+	target, targetRequests := newCountingServer(t, http.StatusNotFound)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusMovedPermanently))
+	defer redirect.Close()
+	checker := NewChecker()
+	checker.guard = allowAllGuard{}
+
+	// Act
+	err := checker.Check(context.Background(), redirect.URL)
+
+	// Assert
+	assert.Error(t, err, "the status of the final destination counts")
+	assert.Equal(t, int32(1), targetRequests.Load())
+}
+
+func TestChecker_Check_RefusesPrivateRedirects(t *testing.T) {
+	// Arrange
+	target, targetRequests := newCountingServer(t, http.StatusOK)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+	checker := NewChecker()
+
+	// Act
+	err := checker.Check(context.Background(), redirect.URL)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrPrivateRedirect)
+	assert.Equal(t, int32(0), targetRequests.Load(), "the private address is never requested")
+}
+
+func TestChecker_Check_ConnectionFailure(t *testing.T) {
+	// Arrange
+	checker := NewChecker()
+	useFailingTransport(checker)
+
+	// Act
+	err := checker.Check(context.Background(), downURL)
+
+	// Assert
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+}
+
+func TestChecker_Check_Timeout(t *testing.T) {
+	// Arrange
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	checker := NewChecker()
+	checker.client.Timeout = 50 * time.Millisecond
+
+	// Act
+	err := checker.Check(context.Background(), server.URL)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestChecker_Check_CachesReachableURLs(t *testing.T) {
+	// Arrange
+	reachable, reachableRequests := newCountingServer(t, http.StatusOK)
+	missing, missingRequests := newCountingServer(t, http.StatusNotFound)
+	checker := NewChecker()
+
+	// Act
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, checker.Check(context.Background(), reachable.URL))
+		assert.Error(t, checker.Check(context.Background(), missing.URL))
+	}
+
+	// Assert
+	assert.Equal(t, int32(1), reachableRequests.Load(), "reachable URLs are remembered")
+	assert.Equal(t, int32(3), missingRequests.Load(), "unreachable URLs are checked again")
+}
+
+func TestChecker_Check_CircuitBreaker(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	checker := NewChecker()
+	checker.now = func() time.Time { return now }
+	useFailingTransport(checker)
+	down := downURL
+	missing, missingRequests := newCountingServer(t, http.StatusNotFound)
+
+	// Act & Assert
+	for i := 0; i < DefaultFailureThreshold; i++ {
+		require.Error(t, checker.Check(context.Background(), down), "failure %d", i+1)
+	}
+	assert.NoError(t, checker.Check(context.Background(), down), "open breaker lets URLs through")
+	assert.NoError(t, checker.Check(context.Background(), missing.URL), "open breaker lets URLs through")
+	assert.Equal(t, int32(0), missingRequests.Load(), "open breaker sends no requests")
+
+	now = now.Add(DefaultBreakerCooldown)
+	assert.Error(t, checker.Check(context.Background(), down), "checks resume after the cooldown")
+	assert.NoError(t, checker.Check(context.Background(), down), "one more failure reopens the breaker")
+
+	now = now.Add(DefaultBreakerCooldown)
+	assert.Error(t, checker.Check(context.Background(), missing.URL), "checks resume after the cooldown")
+	assert.Error(t, checker.Check(context.Background(), down), "a response closed the breaker")
+	assert.Error(t, checker.Check(context.Background(), missing.URL), "breaker stays closed below the threshold")
+}
+
+func TestChecker_Check_ErrorStatusesDoNotOpenBreaker(t *testing.T) {
+	// Arrange
+	missing, missingRequests := newCountingServer(t, http.StatusNotFound)
+	checker := NewChecker()
+
+	// Act
+	for i := 0; i < DefaultFailureThreshold+1; i++ {
+		assert.Error(t, checker.Check(context.Background(), missing.URL))
+	}
+
+	// Assert
+	assert.Equal(t, int32(DefaultFailureThreshold+1), missingRequests.Load())
+}
+
+func TestChecker_Check_DNSFailuresDoNotOpenBreaker(t *testing.T) {
+	// Arrange
+	missing, missingRequests := newCountingServer(t, http.StatusNotFound)
+	checker := NewChecker()
+	useFailingTransport(checker)
+
+	// Act
+	for i := 0; i < DefaultFailureThreshold+1; i++ {
+		assert.Error(t, checker.Check(context.Background(), unknownURL))
+	}
+
+	// Assert
+	assert.Error(t, checker.Check(context.Background(), missing.URL), "the breaker stays closed")
+	assert.Equal(t, int32(1), missingRequests.Load())
+}
@@ -0,0 +1,75 @@
+package urlnorm
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// defaultPorts maps a scheme to the port it implies when none is given
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize rewrites rawURL so equivalent URLs compare equal: the scheme and
+// host are lower-cased, internationalized hostnames are converted to their
+// ASCII (punycode) form, the scheme's default port is dropped and a bare "/"
+// path is removed. URLs without a host are returned unchanged.
+func Normalize(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if parsedURL.Host == "" {
+		return rawURL, nil
+	}
+
+	parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
+
+	host, err := normalizeHost(parsedURL.Hostname())
+	if err != nil {
+		return "", err
+	}
+	port := parsedURL.Port()
+	if port == defaultPorts[parsedURL.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		parsedURL.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		// IPv6 literals keep their brackets
+		parsedURL.Host = "[" + host + "]"
+	default:
+		parsedURL.Host = host
+	}
+
+	if parsedURL.Path == "/" {
+		parsedURL.Path = ""
+		parsedURL.RawPath = ""
+	}
+
+	return parsedURL.String(), nil
+}
+
+// normalizeHost lower-cases host, converting internationalized names to punycode
+func normalizeHost(host string) (string, error) {
+	if !isASCII(host) {
+		return idna.Lookup.ToASCII(host)
+	}
+	return strings.ToLower(host), nil
+}
+
+// isASCII reports whether s contains only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
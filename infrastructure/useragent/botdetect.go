@@ -0,0 +1,42 @@
+package useragent
+
+import "strings"
+
+// DefaultBotSignatures are lowercase User-Agent substrings of well-known crawlers,
+// followed by generic markers that most other bots include
+var DefaultBotSignatures = []string{
+	"googlebot", "bingbot", "slurp", "duckduckbot", "baiduspider", "yandexbot",
+	"applebot", "facebookexternalhit", "twitterbot", "linkedinbot", "slackbot",
+	"ahrefsbot", "semrushbot", "headlesschrome",
+	"bot", "crawl", "spider",
+}
+
+// BotDetector recognises bots by case-insensitive User-Agent substrings
+type BotDetector struct {
+	signatures []string
+}
+
+// NewBotDetector creates a detector matching DefaultBotSignatures and any extra
+// signatures. Blank extra signatures are ignored.
+func NewBotDetector(extra ...string) *BotDetector {
+	signatures := append([]string{}, DefaultBotSignatures...)
+	for _, signature := range extra {
+		if signature = strings.ToLower(strings.TrimSpace(signature)); signature != "" {
+			signatures = append(signatures, signature)
+		}
+	}
+	return &BotDetector{signatures: signatures}
+}
+
+// IsBot reports whether ua contains one of the detector's signatures
+func (d *BotDetector) IsBot(ua string) bool {
+	return containsAny(strings.ToLower(ua), d.signatures)
+}
+
+// defaultBotDetector matches DefaultBotSignatures
+var defaultBotDetector = NewBotDetector()
+
+// IsBot reports whether ua contains one of DefaultBotSignatures
+func IsBot(ua string) bool {
+	return defaultBotDetector.IsBot(ua)
+}
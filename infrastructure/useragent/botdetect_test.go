@@ -0,0 +1,46 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		name     string
+		ua       string
+		expected bool
+	}{
+		{name: "Googlebot", ua: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", expected: true},
+		{name: "Bingbot", ua: "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", expected: true},
+		{name: "DuckDuckBot", ua: "DuckDuckBot/1.1; (+http://duckduckgo.com/duckduckbot.html)", expected: true},
+		{name: "Baiduspider", ua: "Mozilla/5.0 (compatible; Baiduspider/2.0; +http://www.baidu.com/search/spider.html)", expected: true},
+		{name: "YandexBot", ua: "Mozilla/5.0 (compatible; YandexBot/3.0; +http://yandex.com/bots)", expected: true},
+		{name: "Twitterbot", ua: "Twitterbot/1.0", expected: true},
+		{name: "Slackbot", ua: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", expected: true},
+		{name: "Facebook crawler", ua: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", expected: true},
+		{name: "Headless Chrome", ua: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/124.0.0.0 Safari/537.36", expected: true},
+		{name: "Chrome", ua: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", expected: false},
+		{name: "iPhone", ua: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", expected: false},
+		{name: "Empty", ua: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act & Assert
+			assert.Equal(t, tt.expected, IsBot(tt.ua))
+		})
+	}
+}
+
+func TestNewBotDetector_ExtraSignatures(t *testing.T) {
+	// Arrange
+	detector := NewBotDetector(" UptimeMonitor ", "")
+
+	// Act & Assert
+	assert.True(t, detector.IsBot("uptimemonitor/2.3"))
+	assert.True(t, detector.IsBot("Mozilla/5.0 (compatible; Googlebot/2.1)"))
+	assert.False(t, detector.IsBot("Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"))
+	assert.False(t, IsBot("uptimemonitor/2.3"))
+}
@@ -0,0 +1,51 @@
+package useragent
+
+import "strings"
+
+// DeviceType is the kind of device a User-Agent belongs to
+type DeviceType string
+
+// Device types returned by Classify
+const (
+	Mobile  DeviceType = "mobile"
+	Desktop DeviceType = "desktop"
+	Tablet  DeviceType = "tablet"
+	Bot     DeviceType = "bot"
+)
+
+// Lowercase User-Agent substrings, checked after IsBot: tablet, then mobile
+var (
+	tabletMarkers = []string{"ipad", "tablet", "kindle", "silk/", "playbook"}
+	mobileMarkers = []string{"mobi", "iphone", "ipod", "android", "windows phone", "blackberry", "opera mini"}
+)
+
+// Classify returns the device type of ua using substring matching, with bots
+// recognised by IsBot. Android devices without "Mobile" in their User-Agent are
+// tablets, and anything that is not recognised, including an empty User-Agent,
+// is a desktop.
+func Classify(ua string) DeviceType {
+	if IsBot(ua) {
+		return Bot
+	}
+	ua = strings.ToLower(ua)
+
+	switch {
+	case containsAny(ua, tabletMarkers),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return Tablet
+	case containsAny(ua, mobileMarkers):
+		return Mobile
+	default:
+		return Desktop
+	}
+}
+
+// containsAny reports whether s contains any of substrings
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}